@@ -1,13 +1,130 @@
 package authz
 
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
 const (
 	// Access token scopes.
-	ScopeUserAll       = "user:all"        // Full control of all resources accessible to the user account.
-	ScopeSiteAdminSudo = "site-admin:sudo" // Ability to perform any action as any other user.
+	ScopeUserAll          = "user:all"          // Full control of all resources accessible to the user account.
+	ScopeSiteAdminSudo    = "site-admin:sudo"   // Ability to perform any action as any other user.
+	ScopeSearchRead       = "search:read"       // Ability to run searches and read saved searches.
+	ScopeRepoRead         = "repo:read"         // Ability to read repositories and their contents.
+	ScopeCampaignsRead    = "campaigns:read"    // Ability to read campaigns and their changesets.
+	ScopeCampaignsWrite   = "campaigns:write"   // Ability to create, update and delete campaigns. Implies campaigns:read.
+	ScopeSettingsWrite    = "settings:write"    // Ability to read and change settings and saved searches.
+	ScopePermissionsWrite = "permissions:write" // Ability to read and set explicit repository permissions (site admins only).
 )
 
 // AllScopes is a list of all known access token scopes.
 var AllScopes = []string{
 	ScopeUserAll,
 	ScopeSiteAdminSudo,
+	ScopeSearchRead,
+	ScopeRepoRead,
+	ScopeCampaignsRead,
+	ScopeCampaignsWrite,
+	ScopeSettingsWrite,
+	ScopePermissionsWrite,
+}
+
+// UserScopes is a list of the access token scopes that grant access to (a subset of) the resources
+// accessible to the token's subject user. It contains all scopes except for ScopeSiteAdminSudo.
+var UserScopes = []string{
+	ScopeUserAll,
+	ScopeSearchRead,
+	ScopeRepoRead,
+	ScopeCampaignsRead,
+	ScopeCampaignsWrite,
+	ScopeSettingsWrite,
+	ScopePermissionsWrite,
+}
+
+// impliedScopes maps a scope to the other scopes that are granted along with it.
+var impliedScopes = map[string][]string{
+	ScopeCampaignsWrite: {ScopeCampaignsRead},
+}
+
+// HasScope reports whether the list of granted scopes contains scope, either directly or by
+// implication (ScopeUserAll implies all user scopes).
+func HasScope(granted []string, scope string) bool {
+	for _, s := range granted {
+		if s == scope || s == ScopeUserAll {
+			return true
+		}
+		for _, implied := range impliedScopes[s] {
+			if implied == scope {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type scopesKey struct{}
+
+// WithScopes returns a copy of ctx in which the current request is restricted to the given access
+// token scopes. Use CheckScope to check them.
+func WithScopes(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, scopesKey{}, scopes)
+}
+
+// ScopesFromContext returns the access token scopes that the current request is restricted to, and
+// whether it is restricted at all.
+func ScopesFromContext(ctx context.Context) (scopes []string, ok bool) {
+	scopes, ok = ctx.Value(scopesKey{}).([]string)
+	return scopes, ok
+}
+
+// ScopeError occurs when the access token that authenticated the current request was not granted a
+// scope that is required to perform an action.
+type ScopeError struct {
+	Scope string
+}
+
+func (e *ScopeError) Error() string {
+	return fmt.Sprintf("access token is missing the required scope %q", e.Scope)
+}
+
+// CheckScope returns a *ScopeError if the current request was authenticated with an access token
+// that was not granted scope. Requests that are not restricted to access token scopes (for example
+// requests authenticated with a session cookie or a sudo access token) are always allowed.
+func CheckScope(ctx context.Context, scope string) error {
+	granted, ok := ScopesFromContext(ctx)
+	if !ok || HasScope(granted, scope) {
+		return nil
+	}
+	return &ScopeError{Scope: scope}
+}
+
+// ScopeMiddleware returns a router middleware that rejects requests that are restricted to access
+// token scopes that don't include the scope required by the matched route. routeScopes maps route
+// names to required scopes. Routes that are not listed require ScopeUserAll, and an empty scope
+// allows any access token.
+func ScopeMiddleware(routeScopes map[string]string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scope := ScopeUserAll
+			if route := mux.CurrentRoute(r); route != nil {
+				if s, ok := routeScopes[route.GetName()]; ok {
+					scope = s
+				}
+			}
+
+			// 🚨 SECURITY: Restricted access tokens may only be used for the routes that they were
+			// granted the scope for.
+			if scope != "" {
+				if err := CheckScope(r.Context(), scope); err != nil {
+					http.Error(w, err.Error(), http.StatusForbidden)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,43 @@
+package authz
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHasScope(t *testing.T) {
+	for _, tc := range []struct {
+		granted []string
+		scope   string
+		want    bool
+	}{
+		{nil, ScopeSearchRead, false},
+		{[]string{ScopeSearchRead}, ScopeSearchRead, true},
+		{[]string{ScopeSearchRead}, ScopeRepoRead, false},
+		{[]string{ScopeUserAll}, ScopeSettingsWrite, true},
+		{[]string{ScopeCampaignsWrite}, ScopeCampaignsRead, true},
+		{[]string{ScopeCampaignsRead}, ScopeCampaignsWrite, false},
+		{[]string{ScopeRepoRead, ScopeSearchRead}, ScopeSearchRead, true},
+	} {
+		if have := HasScope(tc.granted, tc.scope); have != tc.want {
+			t.Errorf("HasScope(%q, %q): want %t, have %t", tc.granted, tc.scope, tc.want, have)
+		}
+	}
+}
+
+func TestCheckScope(t *testing.T) {
+	ctx := context.Background()
+	if err := CheckScope(ctx, ScopeSettingsWrite); err != nil {
+		t.Errorf("unrestricted request: want no error, have %v", err)
+	}
+
+	ctx = WithScopes(ctx, []string{ScopeSearchRead})
+	if err := CheckScope(ctx, ScopeSearchRead); err != nil {
+		t.Errorf("granted scope: want no error, have %v", err)
+	}
+
+	err := CheckScope(ctx, ScopeSettingsWrite)
+	if e, ok := err.(*ScopeError); !ok || e.Scope != ScopeSettingsWrite {
+		t.Errorf("missing scope: want *ScopeError for %q, have %v", ScopeSettingsWrite, err)
+	}
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/keegancsmith/sqlf"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbconn"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbutil"
+)
+
+// AccessTokenUsage describes a single request that was authenticated with an access token.
+type AccessTokenUsage struct {
+	ID            int64
+	AccessTokenID int64
+	RemoteAddr    string // the address of the peer that made the request
+	ForwardedFor  string // the raw X-Forwarded-For header of the request, if any
+	Route         string // the name of the matched route, or the request path if no route is known
+	OperationName string // the name of the GraphQL request, if any
+	StatusCode    int
+	CreatedAt     time.Time
+}
+
+// accessTokenUsageLog records the requests that were authenticated with access tokens.
+type accessTokenUsageLog struct{}
+
+// Insert records a request that was authenticated with an access token.
+func (*accessTokenUsageLog) Insert(ctx context.Context, u *AccessTokenUsage) error {
+	if Mocks.AccessTokenUsageLog.Insert != nil {
+		return Mocks.AccessTokenUsageLog.Insert(u)
+	}
+
+	createdAt := u.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	var operationName, forwardedFor *string
+	if u.OperationName != "" {
+		operationName = &u.OperationName
+	}
+	if u.ForwardedFor != "" {
+		forwardedFor = &u.ForwardedFor
+	}
+	_, err := dbconn.Global.ExecContext(ctx,
+		"INSERT INTO access_token_usage(access_token_id, remote_addr, forwarded_for, route, operation_name, status_code, created_at) VALUES($1, $2, $3, $4, $5, $6, $7)",
+		u.AccessTokenID,
+		u.RemoteAddr,
+		forwardedFor,
+		u.Route,
+		operationName,
+		u.StatusCode,
+		createdAt.UTC(),
+	)
+	if err != nil {
+		return errors.Wrap(err, "INSERT")
+	}
+	return nil
+}
+
+// AccessTokenUsageListOptions contains options for listing access token usage.
+type AccessTokenUsageListOptions struct {
+	AccessTokenID int64 // only list usage of this access token
+	*LimitOffset
+}
+
+func (o AccessTokenUsageListOptions) sqlConditions() []*sqlf.Query {
+	conds := []*sqlf.Query{sqlf.Sprintf("TRUE")}
+	if o.AccessTokenID != 0 {
+		conds = append(conds, sqlf.Sprintf("access_token_id=%d", o.AccessTokenID))
+	}
+	return conds
+}
+
+// List lists the recorded requests that satisfy the options, most recent first.
+//
+// 🚨 SECURITY: The caller must ensure that the actor is permitted to view the usage of the access
+// token (i.e., that the actor is either the token's subject user or a site admin).
+func (*accessTokenUsageLog) List(ctx context.Context, opt AccessTokenUsageListOptions) ([]*AccessTokenUsage, error) {
+	q := sqlf.Sprintf(`
+SELECT id, access_token_id, remote_addr, forwarded_for, route, operation_name, status_code, created_at FROM access_token_usage
+WHERE (%s)
+ORDER BY created_at DESC, id DESC
+%s`,
+		sqlf.Join(opt.sqlConditions(), ") AND ("),
+		opt.LimitOffset.SQL(),
+	)
+
+	rows, err := dbconn.Global.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*AccessTokenUsage
+	for rows.Next() {
+		var u AccessTokenUsage
+		if err := rows.Scan(&u.ID, &u.AccessTokenID, &u.RemoteAddr, &dbutil.NullString{S: &u.ForwardedFor}, &u.Route, &dbutil.NullString{S: &u.OperationName}, &u.StatusCode, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, &u)
+	}
+	return results, rows.Err()
+}
+
+// Count counts the recorded requests that satisfy the options (ignoring limit and offset).
+//
+// 🚨 SECURITY: The caller must ensure that the actor is permitted to view the usage of the access
+// token.
+func (*accessTokenUsageLog) Count(ctx context.Context, opt AccessTokenUsageListOptions) (int, error) {
+	q := sqlf.Sprintf("SELECT COUNT(*) FROM access_token_usage WHERE (%s)", sqlf.Join(opt.sqlConditions(), ") AND ("))
+	var count int
+	if err := dbconn.Global.QueryRowContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// DeleteOld deletes recorded requests that are older than maxAge, and all but the most recent
+// maxPerToken recorded requests of each access token.
+func (*accessTokenUsageLog) DeleteOld(ctx context.Context, maxAge time.Duration, maxPerToken int) error {
+	if _, err := dbconn.Global.ExecContext(ctx,
+		"DELETE FROM access_token_usage WHERE created_at < $1",
+		time.Now().Add(-maxAge).UTC(),
+	); err != nil {
+		return errors.Wrap(err, "deleting expired access token usage")
+	}
+
+	if _, err := dbconn.Global.ExecContext(ctx, `
+DELETE FROM access_token_usage WHERE id IN (
+  SELECT id FROM (
+    SELECT id, row_number() OVER (PARTITION BY access_token_id ORDER BY created_at DESC, id DESC) AS n
+    FROM access_token_usage
+  ) ranked
+  WHERE n > $1
+)`,
+		maxPerToken,
+	); err != nil {
+		return errors.Wrap(err, "deleting excess access token usage")
+	}
+	return nil
+}
+
+type MockAccessTokenUsageLog struct {
+	Insert func(u *AccessTokenUsage) error
+}
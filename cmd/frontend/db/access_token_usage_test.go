@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/db/dbtesting"
+)
+
+func TestAccessTokenUsageLog(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	dbtesting.SetupGlobalTestDB(t)
+	ctx := context.Background()
+
+	user, err := Users.Create(ctx, NewUser{
+		Email:                 "a@example.com",
+		Username:              "u1",
+		Password:              "p1",
+		EmailVerificationCode: "c1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tid0, _, err := AccessTokens.Create(ctx, user.ID, []string{"a"}, "n0", user.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tid1, _, err := AccessTokens.Create(ctx, user.ID, []string{"a"}, "n1", user.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	for i, u := range []*AccessTokenUsage{
+		{AccessTokenID: tid0, RemoteAddr: "10.0.0.1", Route: "graphql", OperationName: "Search", StatusCode: 200, CreatedAt: now.Add(-40 * 24 * time.Hour)},
+		{AccessTokenID: tid0, RemoteAddr: "10.0.0.1", Route: "graphql", OperationName: "Search", StatusCode: 200, CreatedAt: now.Add(-2 * time.Hour)},
+		{AccessTokenID: tid0, RemoteAddr: "10.0.0.1", ForwardedFor: "192.168.0.1", Route: "repo.shield", StatusCode: 403, CreatedAt: now.Add(-time.Hour)},
+		{AccessTokenID: tid1, RemoteAddr: "10.0.0.2", Route: "/foo", StatusCode: 404, CreatedAt: now},
+	} {
+		if err := AccessTokenUsageLog.Insert(ctx, u); err != nil {
+			t.Fatalf("Insert %d: %s", i, err)
+		}
+	}
+
+	usage, err := AccessTokenUsageLog.List(ctx, AccessTokenUsageListOptions{AccessTokenID: tid0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 3; len(usage) != want {
+		t.Fatalf("got %d usage records, want %d", len(usage), want)
+	}
+	if want := "repo.shield"; usage[0].Route != want {
+		t.Errorf("got most recent route %q, want %q", usage[0].Route, want)
+	}
+	if want := ""; usage[0].OperationName != want {
+		t.Errorf("got operation name %q, want %q", usage[0].OperationName, want)
+	}
+	if want := "Search"; usage[1].OperationName != want {
+		t.Errorf("got operation name %q, want %q", usage[1].OperationName, want)
+	}
+	if want := "192.168.0.1"; usage[0].ForwardedFor != want {
+		t.Errorf("got forwarded for %q, want %q", usage[0].ForwardedFor, want)
+	}
+	if want := ""; usage[1].ForwardedFor != want {
+		t.Errorf("got forwarded for %q, want %q", usage[1].ForwardedFor, want)
+	}
+
+	// Keep at most 1 record per token that is at most 30 days old.
+	if err := AccessTokenUsageLog.DeleteOld(ctx, 30*24*time.Hour, 1); err != nil {
+		t.Fatal(err)
+	}
+	for tid, want := range map[int64]int{tid0: 1, tid1: 1} {
+		count, err := AccessTokenUsageLog.Count(ctx, AccessTokenUsageListOptions{AccessTokenID: tid})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != want {
+			t.Errorf("token %d: got %d usage records, want %d", tid, count, want)
+		}
+	}
+}
@@ -4,7 +4,8 @@ var Mocks MockStores
 
 // MockStores has a field for each store interface with the concrete mock type (to obviate the need for tedious type assertions in test code).
 type MockStores struct {
-	AccessTokens MockAccessTokens
+	AccessTokens        MockAccessTokens
+	AccessTokenUsageLog MockAccessTokenUsageLog
 
 	DiscussionThreads         MockDiscussionThreads
 	DiscussionComments        MockDiscussionComments
@@ -17,6 +18,7 @@ type MockStores struct {
 	Settings      MockSettings
 	Users         MockUsers
 	UserEmails    MockUserEmails
+	UserGroups    MockUserGroups
 
 	Phabricator MockPhabricator
 
@@ -40,7 +40,9 @@ var MockAuthzFilter func(ctx context.Context, repos []*types.Repo, p authz.Perms
 //
 // The enforcement policy:
 //
-// - If permissions user mapping is enabled, directly check permissions against local Postgres.
+// - If permissions user mapping is enabled, directly check permissions against local Postgres for
+//   repositories that are not managed by any authz provider. Other repositories are subject to the
+//   rest of the policy.
 //
 // - If there are no authz providers and `authzAllowByDefault` is true, then the repository is
 //   accessible to everyone.
@@ -110,23 +112,87 @@ func authzFilter(ctx context.Context, repos []*types.Repo, p authz.Perms) (filte
 		otlog.Int("authzProviders.count", len(authzProviders)),
 	)
 
-	// 🚨 SECURITY: Blocking access to all repositories if both code host authz provider(s) and permissions user mapping
-	// are configured.
+	// 🚨 SECURITY: When permissions user mapping is enabled, repositories that are not managed by any
+	// code host authz provider are only accessible with explicit permissions.
 	if globals.PermissionsUserMapping().Enabled {
-		if len(authzProviders) > 0 {
-			return nil, errors.New("The permissions user mapping (site configuration `permissions.userMapping`) cannot be enabled when other authorization providers are in use, please contact site admin to resolve it.")
-		} else if currentUser == nil {
+		if currentUser == nil {
 			return nil, errors.New("Anonymous access is not allow when permissions user mapping is enabled.")
 		}
 
-		return Authz.AuthorizedRepos(ctx, &AuthorizedReposArgs{
-			Repos:  repos,
+		if len(authzProviders) == 0 {
+			return Authz.AuthorizedRepos(ctx, &AuthorizedReposArgs{
+				Repos:  repos,
+				UserID: currentUser.ID,
+				Perm:   p,
+				Type:   authz.PermRepos,
+			})
+		}
+
+		explicit, managed := partitionByAuthzProviders(repos, authzProviders)
+		verified, err := Authz.AuthorizedRepos(ctx, &AuthorizedReposArgs{
+			Repos:  explicit,
 			UserID: currentUser.ID,
 			Perm:   p,
 			Type:   authz.PermRepos,
 		})
+		if err != nil {
+			return nil, errors.Wrap(err, "authorize repositories with explicit permissions")
+		}
+
+		managed, err = authzFilterByProviders(ctx, tr, currentUser, managed, p, authzAllowByDefault, authzProviders)
+		if err != nil {
+			return nil, err
+		}
+
+		allowed := make(map[*types.Repo]bool, len(verified)+len(managed))
+		for _, r := range append(verified, managed...) {
+			allowed[r] = true
+		}
+
+		filtered = repos[:0]
+		for _, r := range repos {
+			if allowed[r] {
+				filtered = append(filtered, r) // In-place filtering
+			}
+		}
+
+		clear(repos[len(filtered):])
+
+		return filtered, nil
 	}
 
+	return authzFilterByProviders(ctx, tr, currentUser, repos, p, authzAllowByDefault, authzProviders)
+}
+
+// partitionByAuthzProviders splits repos into those that are not managed by any of the given
+// authz providers and those that are. The given repos slice is left untouched.
+func partitionByAuthzProviders(repos []*types.Repo, providers []authz.Provider) (unmanaged, managed []*types.Repo) {
+	serviceIDs := make(map[string]bool, len(providers))
+	for _, p := range providers {
+		serviceIDs[p.ServiceID()] = true
+	}
+
+	for _, r := range repos {
+		if serviceIDs[r.ExternalRepo.ServiceID] {
+			managed = append(managed, r)
+		} else {
+			unmanaged = append(unmanaged, r)
+		}
+	}
+	return unmanaged, managed
+}
+
+// authzFilterByProviders enforces the repository permissions of the given authz providers. See
+// authzFilter for the enforcement policy.
+func authzFilterByProviders(
+	ctx context.Context,
+	tr *trace.Trace,
+	currentUser *types.User,
+	repos []*types.Repo,
+	p authz.Perms,
+	authzAllowByDefault bool,
+	authzProviders []authz.Provider,
+) (filtered []*types.Repo, err error) {
 	// In case there is no repos to be checked, return here to avoid more expensive calls.
 	// 🚨 SECURITY: This "smart" check must happen after checking globals.PermissionsUserMapping().Enabled.
 	// Otherwise, we could leak the existence of repositories that a user has no access to by returning an
@@ -142,11 +208,24 @@ func authzFilter(ctx context.Context, repos []*types.Repo, p authz.Perms) (filte
 
 	// Perform authorization against permissions tables.
 	if globals.PermissionsBackgroundSync().Enabled {
+		// 🚨 SECURITY: Repositories of external services of kind OTHER are never private, and their
+		// group authz providers don't sync permissions in the background. Hide them instead of
+		// showing them to everyone.
+		hidden := make(map[string]bool)
+		for _, p := range authzProviders {
+			if p.ServiceType() == "other" {
+				hidden[p.ServiceID()] = true
+			}
+		}
+
 		toVerify := repos[:0]
 		filtered := make([]*types.Repo, 0, len(repos))
 
 		// Add public repositories to filtered, others to toVerify.
 		for _, r := range repos {
+			if hidden[r.ExternalRepo.ServiceID] {
+				continue
+			}
 			if r.Private {
 				toVerify = append(toVerify, r)
 				continue
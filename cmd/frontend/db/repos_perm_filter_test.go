@@ -793,31 +793,6 @@ func Test_authzFilter_permissionsUserMapping(t *testing.T) {
 		repos     []*types.Repo
 		expectErr string
 	}{
-		// 🚨 SECURITY: We need to make sure the behavior is the same for both "has repos" and "no repos".
-		// This is to ensure we always check conflict as the first step.
-		{
-			name: "site configuration conflict with code host authz providers: has repos",
-			providers: []authz.Provider{
-				&MockAuthzProvider{
-					serviceID:   "https://gitlab.mine/",
-					serviceType: "gitlab",
-				},
-			},
-			repos:     makeRepos("gitlab.mine/u1/r0"),
-			expectErr: "The permissions user mapping (site configuration `permissions.userMapping`) cannot be enabled when other authorization providers are in use, please contact site admin to resolve it.",
-		},
-		{
-			name: "site configuration conflict with code host authz providers: no repos",
-			providers: []authz.Provider{
-				&MockAuthzProvider{
-					serviceID:   "https://gitlab.mine/",
-					serviceType: "gitlab",
-				},
-			},
-			repos:     []*types.Repo{},
-			expectErr: "The permissions user mapping (site configuration `permissions.userMapping`) cannot be enabled when other authorization providers are in use, please contact site admin to resolve it.",
-		},
-
 		{
 			name:      "does not allow anonymous access when permissions user mapping is enabled",
 			repos:     []*types.Repo{},
@@ -862,6 +837,48 @@ func Test_authzFilter_permissionsUserMapping(t *testing.T) {
 			t.Fatal("!calledAuthorizedRepos")
 		}
 	})
+
+	t.Run("explicit permissions only apply to repositories not managed by authz providers", func(t *testing.T) {
+		authz.SetProviders(false, []authz.Provider{
+			&MockAuthzProvider{
+				serviceID:   "https://gitlab.mine/",
+				serviceType: "gitlab",
+				perms: map[extsvc.ExternalAccount]map[api.RepoName]authz.Perms{
+					{}: {"gitlab.mine/u1/r0": authz.Read},
+				},
+			},
+		})
+		defer authz.SetProviders(true, nil)
+
+		user := &types.User{ID: 1}
+		Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+			return user, nil
+		}
+		Mocks.ExternalAccounts.List = func(ExternalAccountsListOptions) ([]*extsvc.ExternalAccount, error) {
+			return nil, nil
+		}
+		defer func() { Mocks.ExternalAccounts.List = nil }()
+
+		repos := makeRepos("gitlab.mine/u1/r0", "other.mine/r1", "gitlab.mine/u1/r2", "other.mine/r3")
+		r0, r1, r3 := repos[0], repos[1], repos[3]
+
+		Mocks.Authz.AuthorizedRepos = func(_ context.Context, args *AuthorizedReposArgs) ([]*types.Repo, error) {
+			if diff := cmp.Diff([]*types.Repo{r1, r3}, args.Repos); diff != "" {
+				return nil, fmt.Errorf("args.Repos: %s", diff)
+			}
+			return []*types.Repo{r3}, nil
+		}
+		defer func() { Mocks.Authz.AuthorizedRepos = nil }()
+
+		ctx := actor.WithActor(context.Background(), &actor.Actor{UID: user.ID})
+		filtered, err := authzFilter(ctx, repos, authz.Read)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]*types.Repo{r0, r3}, filtered); diff != "" {
+			t.Fatal(diff)
+		}
+	})
 }
 
 func Test_authzFilter_permissionsBackgroudSync(t *testing.T) {
@@ -1055,6 +1072,51 @@ func Test_authzFilter_permissionsBackgroudSync(t *testing.T) {
 			t.Fatal("!callGrantPendingPermissions")
 		}
 	})
+
+	t.Run("repos of group authz providers should be hidden", func(t *testing.T) {
+		otherRepo := makeRepo("git.example.com/engineering/app", 3, false)
+		otherRepo.ExternalRepo.ServiceType = "other"
+		authz.SetProviders(false,
+			[]authz.Provider{
+				&MockAuthzProvider{
+					serviceID:   "https://git.example.com/",
+					serviceType: "other",
+				},
+			},
+		)
+		defer authz.SetProviders(true, nil)
+
+		user := &types.User{ID: 1}
+		Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+			return user, nil
+		}
+		Mocks.ExternalAccounts.List = func(ExternalAccountsListOptions) ([]*extsvc.ExternalAccount, error) {
+			return nil, nil
+		}
+		Mocks.Authz.AuthorizedRepos = func(_ context.Context, args *AuthorizedReposArgs) ([]*types.Repo, error) {
+			return args.Repos, nil
+		}
+		defer func() {
+			Mocks.Users = MockUsers{}
+			Mocks.ExternalAccounts = MockExternalAccounts{}
+			Mocks.Authz = MockAuthz{}
+		}()
+
+		for name, ctx := range map[string]context.Context{
+			"unauthenticated": context.Background(),
+			"authenticated":   actor.WithActor(context.Background(), &actor.Actor{UID: user.ID}),
+		} {
+			repos, err := authzFilter(ctx, []*types.Repo{publicRepo, otherRepo}, authz.Read)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expRepos := []*types.Repo{publicRepo}
+			if diff := cmp.Diff(expRepos, repos); diff != "" {
+				t.Fatalf("%s: %s", name, diff)
+			}
+		}
+	})
 }
 
 func acct(userID int32, serviceType, serviceID, accountID string) *extsvc.ExternalAccount {
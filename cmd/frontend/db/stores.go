@@ -2,6 +2,7 @@ package db
 
 var (
 	AccessTokens              = &accessTokens{}
+	AccessTokenUsageLog       = &accessTokenUsageLog{}
 	ExternalServices          = &ExternalServicesStore{}
 	DefaultRepos              = &defaultRepos{}
 	DiscussionThreads         = &discussionThreads{}
@@ -16,6 +17,7 @@ var (
 	Settings                  = &settings{}
 	Users                     = &users{}
 	UserEmails                = &userEmails{}
+	UserGroups                = &userGroups{}
 	EventLogs                 = &eventLogs{}
 
 	SurveyResponses = &surveyResponses{}
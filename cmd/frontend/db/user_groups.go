@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"sort"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbconn"
+)
+
+// userGroups records which groups users are members of according to external authentication
+// providers (such as the group claims of SAML and OpenID Connect identity providers).
+type userGroups struct{}
+
+// Set replaces the groups that the user is a member of according to the authentication provider
+// identified by serviceType and serviceID.
+func (*userGroups) Set(ctx context.Context, userID int32, serviceType, serviceID string, names []string) (err error) {
+	if Mocks.UserGroups.Set != nil {
+		return Mocks.UserGroups.Set(userID, serviceType, serviceID, names)
+	}
+
+	tx, err := dbconn.Global.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			rollErr := tx.Rollback()
+			if rollErr != nil {
+				err = multierror.Append(err, rollErr)
+			}
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if _, err = tx.ExecContext(ctx,
+		"DELETE FROM user_groups WHERE user_id=$1 AND service_type=$2 AND service_id=$3",
+		userID, serviceType, serviceID,
+	); err != nil {
+		return errors.Wrap(err, "DELETE")
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+	if _, err = tx.ExecContext(ctx,
+		"INSERT INTO user_groups(user_id, service_type, service_id, name) SELECT DISTINCT $1::integer, $2, $3, name FROM unnest($4::text[]) AS name",
+		userID, serviceType, serviceID, pq.Array(names),
+	); err != nil {
+		return errors.Wrap(err, "INSERT")
+	}
+	return nil
+}
+
+// ListNames returns the sorted names of the groups that the user is a member of according to any
+// authentication provider.
+func (*userGroups) ListNames(ctx context.Context, userID int32) ([]string, error) {
+	if Mocks.UserGroups.ListNames != nil {
+		return Mocks.UserGroups.ListNames(userID)
+	}
+
+	rows, err := dbconn.Global.QueryContext(ctx, "SELECT DISTINCT name FROM user_groups WHERE user_id=$1", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+type MockUserGroups struct {
+	Set       func(userID int32, serviceType, serviceID string, names []string) error
+	ListNames func(userID int32) ([]string, error)
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/db/dbtesting"
+)
+
+func TestUserGroups(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	dbtesting.SetupGlobalTestDB(t)
+	ctx := context.Background()
+
+	user, err := Users.Create(ctx, NewUser{Username: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertNames := func(t *testing.T, want []string) {
+		t.Helper()
+		names, err := UserGroups.ListNames(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(names, want) {
+			t.Errorf("got %q, want %q", names, want)
+		}
+	}
+
+	assertNames(t, nil)
+
+	if err := UserGroups.Set(ctx, user.ID, "saml", "https://idp.example.com", []string{"security", "engineering", "security"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := UserGroups.Set(ctx, user.ID, "openidconnect", "https://oidc.example.com", []string{"engineering", "ops"}); err != nil {
+		t.Fatal(err)
+	}
+	assertNames(t, []string{"engineering", "ops", "security"})
+
+	// Setting the groups of one provider leaves those of other providers untouched.
+	if err := UserGroups.Set(ctx, user.ID, "saml", "https://idp.example.com", nil); err != nil {
+		t.Fatal(err)
+	}
+	assertNames(t, []string{"engineering", "ops"})
+}
@@ -0,0 +1,107 @@
+package graphqlbackend
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
+)
+
+func (r *accessTokenResolver) Usage(ctx context.Context, args *struct {
+	graphqlutil.ConnectionArgs
+}) (*accessTokenUsageConnectionResolver, error) {
+	// 🚨 SECURITY: Only site admins and the user can view the usage of a user's access token.
+	if err := backend.CheckSiteAdminOrSameUser(ctx, r.accessToken.SubjectUserID); err != nil {
+		return nil, err
+	}
+
+	opt := db.AccessTokenUsageListOptions{AccessTokenID: r.accessToken.ID}
+	args.ConnectionArgs.Set(&opt.LimitOffset)
+	return &accessTokenUsageConnectionResolver{opt: opt}, nil
+}
+
+// accessTokenUsageConnectionResolver resolves a list of requests that were authenticated with an
+// access token.
+//
+// 🚨 SECURITY: When instantiating an accessTokenUsageConnectionResolver value, the caller MUST
+// check permissions.
+type accessTokenUsageConnectionResolver struct {
+	opt db.AccessTokenUsageListOptions
+
+	// cache results because they are used by multiple fields
+	once  sync.Once
+	usage []*db.AccessTokenUsage
+	err   error
+}
+
+func (r *accessTokenUsageConnectionResolver) compute(ctx context.Context) ([]*db.AccessTokenUsage, error) {
+	r.once.Do(func() {
+		opt2 := r.opt
+		if opt2.LimitOffset != nil {
+			tmp := *opt2.LimitOffset
+			opt2.LimitOffset = &tmp
+			opt2.Limit++ // so we can detect if there is a next page
+		}
+
+		r.usage, r.err = db.AccessTokenUsageLog.List(ctx, opt2)
+	})
+	return r.usage, r.err
+}
+
+func (r *accessTokenUsageConnectionResolver) Nodes(ctx context.Context) ([]*accessTokenUsageResolver, error) {
+	usage, err := r.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if r.opt.LimitOffset != nil && len(usage) > r.opt.LimitOffset.Limit {
+		usage = usage[:r.opt.LimitOffset.Limit]
+	}
+
+	l := make([]*accessTokenUsageResolver, 0, len(usage))
+	for _, u := range usage {
+		l = append(l, &accessTokenUsageResolver{usage: u})
+	}
+	return l, nil
+}
+
+func (r *accessTokenUsageConnectionResolver) TotalCount(ctx context.Context) (int32, error) {
+	count, err := db.AccessTokenUsageLog.Count(ctx, r.opt)
+	return int32(count), err
+}
+
+func (r *accessTokenUsageConnectionResolver) PageInfo(ctx context.Context) (*graphqlutil.PageInfo, error) {
+	usage, err := r.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return graphqlutil.HasNextPage(r.opt.LimitOffset != nil && len(usage) > r.opt.Limit), nil
+}
+
+// accessTokenUsageResolver resolves a single request that was authenticated with an access token.
+type accessTokenUsageResolver struct {
+	usage *db.AccessTokenUsage
+}
+
+func (r *accessTokenUsageResolver) Timestamp() DateTime { return DateTime{Time: r.usage.CreatedAt} }
+
+func (r *accessTokenUsageResolver) RemoteAddress() string { return r.usage.RemoteAddr }
+
+func (r *accessTokenUsageResolver) ForwardedFor() *string {
+	if r.usage.ForwardedFor == "" {
+		return nil
+	}
+	return &r.usage.ForwardedFor
+}
+
+func (r *accessTokenUsageResolver) Route() string { return r.usage.Route }
+
+func (r *accessTokenUsageResolver) OperationName() *string {
+	if r.usage.OperationName == "" {
+		return nil
+	}
+	return &r.usage.OperationName
+}
+
+func (r *accessTokenUsageResolver) StatusCode() int32 { return int32(r.usage.StatusCode) }
@@ -0,0 +1,34 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+)
+
+// 🚨 SECURITY: This tests that users can't view the usage of other users' access tokens.
+func TestAccessToken_Usage(t *testing.T) {
+	resetMocks()
+	const differentNonSiteAdminUID = 456
+	db.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+		return &types.User{ID: differentNonSiteAdminUID}, nil
+	}
+	db.Mocks.Users.GetByID = func(_ context.Context, userID int32) (*types.User, error) {
+		return &types.User{ID: userID, Username: "username"}, nil
+	}
+	defer resetMocks()
+
+	ctx := actor.WithActor(context.Background(), &actor.Actor{UID: differentNonSiteAdminUID})
+	r := &accessTokenResolver{accessToken: db.AccessToken{ID: 1, SubjectUserID: 1}}
+	result, err := r.Usage(ctx, &struct{ graphqlutil.ConnectionArgs }{})
+	if err == nil {
+		t.Error("Expected error, but there was none")
+	}
+	if result != nil {
+		t.Errorf("got result %v, want nil", result)
+	}
+}
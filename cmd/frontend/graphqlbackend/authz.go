@@ -12,6 +12,7 @@ var NewAuthzResolver func() AuthzResolver
 
 type AuthzResolver interface {
 	SetRepositoryPermissionsForUsers(ctx context.Context, args *RepoPermsArgs) (*EmptyResponse, error)
+	SetRepositoryPermissionsInBulk(ctx context.Context, args *RepoPermsInBulkArgs) (*EmptyResponse, error)
 	AuthorizedUserRepositories(ctx context.Context, args *AuthorizedRepoArgs) (RepositoryConnectionResolver, error)
 	UsersWithPendingPermissions(ctx context.Context) ([]string, error)
 	AuthorizedUsers(ctx context.Context, args *RepoAuthorizedUserArgs) (UserConnectionResolver, error)
@@ -25,6 +26,10 @@ func (defaultAuthzResolver) SetRepositoryPermissionsForUsers(ctx context.Context
 	return nil, authzInEnterprise
 }
 
+func (defaultAuthzResolver) SetRepositoryPermissionsInBulk(ctx context.Context, args *RepoPermsInBulkArgs) (*EmptyResponse, error) {
+	return nil, authzInEnterprise
+}
+
 func (defaultAuthzResolver) AuthorizedUserRepositories(ctx context.Context, args *AuthorizedRepoArgs) (RepositoryConnectionResolver, error) {
 	return nil, authzInEnterprise
 }
@@ -43,6 +48,18 @@ type RepoPermsArgs struct {
 	Perm       string
 }
 
+type RepoPermsInBulkArgs struct {
+	Permissions []*RepoPermsInput
+}
+
+type RepoPermsInput struct {
+	Repository     *graphql.ID
+	RepositoryName *string
+	BindIDs        *[]string
+	Organizations  *[]graphql.ID
+	Perm           string
+}
+
 type AuthorizedRepoArgs struct {
 	Username *string
 	Email    *string
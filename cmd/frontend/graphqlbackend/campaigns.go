@@ -28,6 +28,16 @@ type CreateCampaignArgs struct {
 		Branch      *string
 		PatchSet    *graphql.ID
 		Draft       *bool
+		AutoMerge   *bool
+		MergeMethod *campaigns.ChangesetMergeMethod
+
+		DraftOnCodeHost *bool
+
+		CommitMessage     *string
+		CommitAuthorName  *string
+		CommitAuthorEmail *string
+		CommitSignOff     *bool
+		CommitSign        *bool
 	}
 }
 
@@ -38,6 +48,14 @@ type UpdateCampaignArgs struct {
 		Description *string
 		Branch      *string
 		PatchSet    *graphql.ID
+		AutoMerge   *bool
+		MergeMethod *campaigns.ChangesetMergeMethod
+
+		CommitMessage     *string
+		CommitAuthorName  *string
+		CommitAuthorEmail *string
+		CommitSignOff     *bool
+		CommitSign        *bool
 	}
 }
 
@@ -45,6 +63,19 @@ type CreatePatchSetFromPatchesArgs struct {
 	Patches []PatchInput
 }
 
+type CreatePatchSetFromSpecArgs struct {
+	Input struct {
+		Query            string
+		MatchTemplate    string
+		RewriteTemplate  string
+		IsRegExp         bool
+		IsLiteral        bool
+		IsCaseSensitive  bool
+		FileExtension    *string
+		DirectoryExclude *string
+	}
+}
+
 type PatchInput struct {
 	Repository   graphql.ID
 	BaseRevision api.CommitID
@@ -91,6 +122,34 @@ type SyncChangesetArgs struct {
 	Changeset graphql.ID
 }
 
+// CampaignChangesetsFilter selects the changesets of a campaign that a bulk
+// action is performed on.
+type CampaignChangesetsFilter struct {
+	Changesets  *[]graphql.ID
+	State       *campaigns.ChangesetState
+	ReviewState *campaigns.ChangesetReviewState
+	CheckState  *campaigns.ChangesetCheckState
+}
+
+type CommentOnCampaignChangesetsArgs struct {
+	Campaign graphql.ID
+	Body     string
+	Filter   *CampaignChangesetsFilter
+}
+
+type LabelCampaignChangesetsArgs struct {
+	Campaign     graphql.ID
+	AddLabels    *[]string
+	RemoveLabels *[]string
+	Filter       *CampaignChangesetsFilter
+}
+
+type RequestCampaignChangesetReviewersArgs struct {
+	Campaign  graphql.ID
+	Reviewers []string
+	Filter    *CampaignChangesetsFilter
+}
+
 type CampaignsResolver interface {
 	CreateCampaign(ctx context.Context, args *CreateCampaignArgs) (CampaignResolver, error)
 	UpdateCampaign(ctx context.Context, args *UpdateCampaignArgs) (CampaignResolver, error)
@@ -102,6 +161,9 @@ type CampaignsResolver interface {
 	PublishCampaign(ctx context.Context, args *PublishCampaignArgs) (CampaignResolver, error)
 	PublishChangeset(ctx context.Context, args *PublishChangesetArgs) (*EmptyResponse, error)
 	SyncChangeset(ctx context.Context, args *SyncChangesetArgs) (*EmptyResponse, error)
+	CommentOnCampaignChangesets(ctx context.Context, args *CommentOnCampaignChangesetsArgs) ([]ChangesetActionResolver, error)
+	LabelCampaignChangesets(ctx context.Context, args *LabelCampaignChangesetsArgs) ([]ChangesetActionResolver, error)
+	RequestCampaignChangesetReviewers(ctx context.Context, args *RequestCampaignChangesetReviewersArgs) ([]ChangesetActionResolver, error)
 
 	CreateChangesets(ctx context.Context, args *CreateChangesetsArgs) ([]ExternalChangesetResolver, error)
 	ChangesetByID(ctx context.Context, id graphql.ID) (ExternalChangesetResolver, error)
@@ -110,6 +172,7 @@ type CampaignsResolver interface {
 	AddChangesetsToCampaign(ctx context.Context, args *AddChangesetsToCampaignArgs) (CampaignResolver, error)
 
 	CreatePatchSetFromPatches(ctx context.Context, args CreatePatchSetFromPatchesArgs) (PatchSetResolver, error)
+	CreatePatchSetFromSpec(ctx context.Context, args *CreatePatchSetFromSpecArgs) (PatchSetResolver, error)
 	PatchSetByID(ctx context.Context, id graphql.ID) (PatchSetResolver, error)
 
 	PatchByID(ctx context.Context, id graphql.ID) (PatchResolver, error)
@@ -159,6 +222,18 @@ func (defaultCampaignsResolver) SyncChangeset(ctx context.Context, args *SyncCha
 	return nil, campaignsOnlyInEnterprise
 }
 
+func (defaultCampaignsResolver) CommentOnCampaignChangesets(ctx context.Context, args *CommentOnCampaignChangesetsArgs) ([]ChangesetActionResolver, error) {
+	return nil, campaignsOnlyInEnterprise
+}
+
+func (defaultCampaignsResolver) LabelCampaignChangesets(ctx context.Context, args *LabelCampaignChangesetsArgs) ([]ChangesetActionResolver, error) {
+	return nil, campaignsOnlyInEnterprise
+}
+
+func (defaultCampaignsResolver) RequestCampaignChangesetReviewers(ctx context.Context, args *RequestCampaignChangesetReviewersArgs) ([]ChangesetActionResolver, error) {
+	return nil, campaignsOnlyInEnterprise
+}
+
 func (defaultCampaignsResolver) CreateChangesets(ctx context.Context, args *CreateChangesetsArgs) ([]ExternalChangesetResolver, error) {
 	return nil, campaignsOnlyInEnterprise
 }
@@ -179,6 +254,10 @@ func (defaultCampaignsResolver) CreatePatchSetFromPatches(ctx context.Context, a
 	return nil, campaignsOnlyInEnterprise
 }
 
+func (defaultCampaignsResolver) CreatePatchSetFromSpec(ctx context.Context, args *CreatePatchSetFromSpecArgs) (PatchSetResolver, error) {
+	return nil, campaignsOnlyInEnterprise
+}
+
 func (defaultCampaignsResolver) PatchSetByID(ctx context.Context, id graphql.ID) (PatchSetResolver, error) {
 	return nil, campaignsOnlyInEnterprise
 }
@@ -216,8 +295,17 @@ type CampaignResolver interface {
 	PatchSet(ctx context.Context) (PatchSetResolver, error)
 	Status(context.Context) (BackgroundProcessStatus, error)
 	ClosedAt() *DateTime
+	AutoMerge() bool
+	MergeMethod() campaigns.ChangesetMergeMethod
+	CommitMessage() *string
+	CommitAuthorName() *string
+	CommitAuthorEmail() *string
+	CommitSignOff() bool
+	CommitSign() bool
+	DraftOnCodeHost() bool
 	PublishedAt(ctx context.Context) (*DateTime, error)
 	Patches(ctx context.Context, args *graphqlutil.ConnectionArgs) PatchConnectionResolver
+	ChangesetActions(ctx context.Context, args *graphqlutil.ConnectionArgs) ChangesetActionConnectionResolver
 }
 
 type CampaignsConnectionResolver interface {
@@ -249,6 +337,7 @@ type ExternalChangesetResolver interface {
 	ExternalURL() (*externallink.Resolver, error)
 	ReviewState(context.Context) campaigns.ChangesetReviewState
 	CheckState(context.Context) (*campaigns.ChangesetCheckState, error)
+	Conflicted() bool
 	Repository(ctx context.Context) (*RepositoryResolver, error)
 	Campaigns(ctx context.Context, args *ListCampaignArgs) (CampaignsConnectionResolver, error)
 	Events(ctx context.Context, args *struct{ graphqlutil.ConnectionArgs }) (ChangesetEventsConnectionResolver, error)
@@ -285,6 +374,28 @@ type ChangesetEventResolver interface {
 	CreatedAt() DateTime
 }
 
+type ChangesetActionConnectionResolver interface {
+	Nodes(ctx context.Context) ([]ChangesetActionResolver, error)
+	TotalCount(ctx context.Context) (int32, error)
+	PageInfo(ctx context.Context) (*graphqlutil.PageInfo, error)
+}
+
+type ChangesetActionResolver interface {
+	ID() graphql.ID
+	Kind() campaigns.ChangesetActionKind
+	Campaign(ctx context.Context) (CampaignResolver, error)
+	Changeset(ctx context.Context) (ExternalChangesetResolver, error)
+	RequestedBy(ctx context.Context) (*UserResolver, error)
+	Body() *string
+	AddLabels() []string
+	RemoveLabels() []string
+	Reviewers() []string
+	State() campaigns.BackgroundProcessState
+	Error() *string
+	CreatedAt() DateTime
+	FinishedAt() *DateTime
+}
+
 type ChangesetCountsResolver interface {
 	Date() DateTime
 	Total() int32
@@ -311,6 +422,8 @@ type PatchSetResolver interface {
 	Patches(ctx context.Context, args *graphqlutil.ConnectionArgs) PatchConnectionResolver
 
 	PreviewURL() string
+
+	Status(ctx context.Context) (BackgroundProcessStatus, error)
 }
 
 type PreviewFileDiff interface {
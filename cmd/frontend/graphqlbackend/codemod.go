@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -38,6 +39,13 @@ type args struct {
 	rewriteTemplate   string
 	includeFileFilter string
 	excludeFileFilter string
+
+	// isRegExp and isLiteral are set for regexp and literal searches, which
+	// replacer rewrites without comby. isCaseSensitive only applies to
+	// those.
+	isRegExp        bool
+	isLiteral       bool
+	isCaseSensitive bool
 }
 
 // codemodResultResolver is a resolver for the GraphQL type `CodemodResult`
@@ -101,51 +109,72 @@ func (r *codemodResultResolver) Commit() *GitCommitResolver { return r.commit }
 
 func (r *codemodResultResolver) RawDiff() string { return r.diff }
 
-func validateQuery(q query.QueryInfo) (*args, error) {
+func validateQuery(q query.QueryInfo, p *search.TextPatternInfo, patternType query.SearchType) (*args, error) {
+	var a args
 	matchValues := q.Values(query.FieldDefault)
-	var matchTemplates []string
-	for _, v := range matchValues {
-		if v.String != nil && *v.String != "" {
-			matchTemplates = append(matchTemplates, *v.String)
+	switch patternType {
+	case query.SearchTypeRegex:
+		// The pattern has already been combined into a single regexp.
+		a.matchTemplate = p.Pattern
+		a.isRegExp = true
+		a.isCaseSensitive = p.IsCaseSensitive
+
+	case query.SearchTypeLiteral:
+		var literals []string
+		for _, v := range matchValues {
+			if v.String != nil && *v.String != "" {
+				literals = append(literals, *v.String)
+			}
 		}
-		if v.Regexp != nil || v.Bool != nil {
-			return nil, errors.New("this looks like a regex search pattern. Structural search is active because 'replace:' was specified. Please enclose your search string with quotes when using 'replace:'.")
+		a.matchTemplate = strings.Join(literals, " ")
+		a.isLiteral = true
+		a.isCaseSensitive = p.IsCaseSensitive
+
+	default:
+		var matchTemplates []string
+		for _, v := range matchValues {
+			if v.String != nil && *v.String != "" {
+				matchTemplates = append(matchTemplates, *v.String)
+			}
+			if v.Regexp != nil || v.Bool != nil {
+				return nil, errors.New("this looks like a regex search pattern. Structural search is active because 'replace:' was specified. Please enclose your search string with quotes when using 'replace:'.")
+			}
 		}
+		a.matchTemplate = strings.Join(matchTemplates, " ")
+	}
+	if a.matchTemplate == "" {
+		return nil, errors.New("a search pattern is required when using 'replace:'")
 	}
-	matchTemplate := strings.Join(matchTemplates, " ")
 
 	replacementValues, _ := q.StringValues(query.FieldReplace)
-	var rewriteTemplate string
 	if len(replacementValues) > 0 {
-		rewriteTemplate = replacementValues[0]
+		a.rewriteTemplate = replacementValues[0]
 	}
 
 	includeFileFilter, excludeFileFilter := q.RegexpPatterns(query.FieldFile)
-	var includeFileFilterText string
 	if len(includeFileFilter) > 0 {
-		includeFileFilterText = includeFileFilter[0]
+		a.includeFileFilter = includeFileFilter[0]
 		// only file names or files with extensions in the following characterset are allowed
 		IsAlphanumericWithPeriod := lazyregexp.New(`^[a-zA-Z0-9_.]+$`).MatchString
-		if !IsAlphanumericWithPeriod(includeFileFilterText) {
+		if !IsAlphanumericWithPeriod(a.includeFileFilter) {
 			return nil, errors.New("the 'file:' filter cannot contain regex when using the 'replace:' filter currently. Only alphanumeric characters or '.'")
 		}
 	}
 
-	var excludeFileFilterText string
 	if len(excludeFileFilter) > 0 {
-		excludeFileFilterText = excludeFileFilter[0]
+		a.excludeFileFilter = excludeFileFilter[0]
 		IsAlphanumericWithPeriod := lazyregexp.New(`^[a-zA-Z_.]+$`).MatchString
-		if !IsAlphanumericWithPeriod(includeFileFilterText) {
+		if !IsAlphanumericWithPeriod(a.includeFileFilter) {
 			return nil, errors.New("the '-file:' filter cannot contain regex when using the 'replace:' filter currently. Only alphanumeric characters or '.'")
 		}
 	}
 
-	return &args{matchTemplate, rewriteTemplate, includeFileFilterText, excludeFileFilterText}, nil
+	return &a, nil
 }
 
 // Calls the codemod backend replacer service for a set of repository revisions.
-func performCodemod(ctx context.Context, args *search.TextParameters) ([]SearchResultResolver, *searchResultsCommon, error) {
-	cmodArgs, err := validateQuery(args.Query)
+func performCodemod(ctx context.Context, args *search.TextParameters, patternType query.SearchType) ([]SearchResultResolver, *searchResultsCommon, error) {
+	cmodArgs, err := validateQuery(args.Query, args.PatternInfo, patternType)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -251,6 +280,9 @@ func callCodemodInRepo(ctx context.Context, repoRevs *search.RepositoryRevisions
 	q.Set("rewritetemplate", args.rewriteTemplate)
 	q.Set("fileextension", args.includeFileFilter)
 	q.Set("directoryexclude", args.excludeFileFilter)
+	q.Set("isregexp", strconv.FormatBool(args.isRegExp))
+	q.Set("isliteral", strconv.FormatBool(args.isLiteral))
+	q.Set("iscasesensitive", strconv.FormatBool(args.isCaseSensitive))
 	u.RawQuery = q.Encode()
 
 	req, err := http.NewRequest("GET", u.String(), nil)
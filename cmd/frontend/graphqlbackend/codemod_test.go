@@ -1,15 +1,17 @@
 package graphqlbackend
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/sourcegraph/sourcegraph/internal/search"
 	"github.com/sourcegraph/sourcegraph/internal/search/query"
 )
 
 func TestCodemod_validateArgsNoRegex(t *testing.T) {
 	q, _ := query.ParseAndCheck("re.*gex")
-	_, err := validateQuery(q)
+	_, err := validateQuery(q, &search.TextPatternInfo{}, query.SearchTypeStructural)
 	if err == nil {
 		t.Fatalf("Expected query %v to fail", q)
 	}
@@ -20,12 +22,40 @@ func TestCodemod_validateArgsNoRegex(t *testing.T) {
 
 func TestCodemod_validateArgsOk(t *testing.T) {
 	q, _ := query.ParseAndCheck(`"not regex"`)
-	_, err := validateQuery(q)
+	_, err := validateQuery(q, &search.TextPatternInfo{}, query.SearchTypeStructural)
 	if err != nil {
 		t.Fatalf("Expected query %v to to be OK", q)
 	}
 }
 
+func TestCodemod_validateArgsRegexpAndLiteral(t *testing.T) {
+	q, err := query.ParseAndCheck(`foo(\w+) replace:"bar$1"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := validateQuery(q, &search.TextPatternInfo{Pattern: `foo(\w+)`, IsRegExp: true, IsCaseSensitive: true}, query.SearchTypeRegex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &args{matchTemplate: `foo(\w+)`, rewriteTemplate: "bar$1", isRegExp: true, isCaseSensitive: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	q, err = query.ParseAndCheck(query.ConvertToLiteral(`foo(x) replace:bar`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = validateQuery(q, &search.TextPatternInfo{Pattern: `foo\(x\)`, IsRegExp: true}, query.SearchTypeLiteral)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = &args{matchTemplate: "foo(x)", rewriteTemplate: "bar", isLiteral: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
 func TestCodemod_resolver(t *testing.T) {
 	raw := &rawCodemodResult{
 		URI:  "",
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"time"
 
 	graphql "github.com/graph-gophers/graphql-go"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
@@ -172,6 +173,56 @@ func (r *updateQueueResolver) Total() int32 {
 	return int32(r.queue.Total)
 }
 
+func (r *repositoryMirrorInfoResolver) History(ctx context.Context) ([]*mirrorRepositoryOperationResolver, error) {
+	// 🚨 SECURITY: The output of git commands may reveal details about the
+	// code host, so only allow site admins to see it.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	ops, err := gitserver.DefaultClient.RepoHistory(ctx, r.repository.repo.Name)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*mirrorRepositoryOperationResolver, 0, len(ops))
+	for _, op := range ops {
+		resolvers = append(resolvers, &mirrorRepositoryOperationResolver{op: op})
+	}
+	return resolvers, nil
+}
+
+type mirrorRepositoryOperationResolver struct {
+	op protocol.RepoOperation
+}
+
+func (r *mirrorRepositoryOperationResolver) Kind() string {
+	return r.op.Kind
+}
+
+func (r *mirrorRepositoryOperationResolver) StartedAt() DateTime {
+	return DateTime{Time: r.op.Start}
+}
+
+func (r *mirrorRepositoryOperationResolver) FinishedAt() DateTime {
+	return DateTime{Time: r.op.End}
+}
+
+func (r *mirrorRepositoryOperationResolver) DurationMilliseconds() int32 {
+	return int32(r.op.End.Sub(r.op.Start) / time.Millisecond)
+}
+
+func (r *mirrorRepositoryOperationResolver) Bytes() float64 {
+	return float64(r.op.Bytes)
+}
+
+func (r *mirrorRepositoryOperationResolver) ExitCode() int32 {
+	return int32(r.op.ExitCode)
+}
+
+func (r *mirrorRepositoryOperationResolver) Stderr() string {
+	return r.op.Stderr
+}
+
 func (r *schemaResolver) CheckMirrorRepositoryConnection(ctx context.Context, args *struct {
 	Repository *graphql.ID
 	Name       *string
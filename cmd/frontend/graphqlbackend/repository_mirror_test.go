@@ -4,6 +4,7 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/graph-gophers/graphql-go/gqltesting"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
@@ -11,6 +12,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	gitserverprotocol "github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/repoupdater"
 	"github.com/sourcegraph/sourcegraph/internal/repoupdater/protocol"
 )
@@ -135,3 +137,67 @@ func TestCheckMirrorRepositoryConnection(t *testing.T) {
 		}
 	})
 }
+
+func TestRepositoryMirrorInfo_History(t *testing.T) {
+	resetMocks()
+	db.Mocks.Repos.MockGetByName(t, "github.com/gorilla/mux", 2)
+	db.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{SiteAdmin: true}, nil
+	}
+
+	start := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	gitserver.MockRepoHistory = func(repo api.RepoName) ([]gitserverprotocol.RepoOperation, error) {
+		if repo != "github.com/gorilla/mux" {
+			t.Errorf("got repo %q, want %q", repo, "github.com/gorilla/mux")
+		}
+		return []gitserverprotocol.RepoOperation{{
+			Kind:     "fetch",
+			Start:    start,
+			End:      start.Add(1500 * time.Millisecond),
+			Bytes:    4096,
+			ExitCode: 128,
+			Stderr:   "fatal: could not read Username",
+		}}, nil
+	}
+	defer func() { gitserver.MockRepoHistory = nil }()
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: mustParseGraphQLSchema(t),
+			Query: `
+				{
+					repository(name: "github.com/gorilla/mux") {
+						mirrorInfo {
+							history {
+								kind
+								startedAt
+								finishedAt
+								durationMilliseconds
+								bytes
+								exitCode
+								stderr
+							}
+						}
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"repository": {
+						"mirrorInfo": {
+							"history": [{
+								"kind": "fetch",
+								"startedAt": "2020-04-01T12:00:00Z",
+								"finishedAt": "2020-04-01T12:00:01Z",
+								"durationMilliseconds": 1500,
+								"bytes": 4096,
+								"exitCode": 128,
+								"stderr": "fatal: could not read Username"
+							}]
+						}
+					}
+				}
+			`,
+		},
+	})
+}
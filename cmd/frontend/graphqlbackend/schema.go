@@ -48,6 +48,12 @@ type Mutation {
         # created from this PatchSet.
         patches: [PatchInput!]!
     ): PatchSet!
+    # Create a patch set whose patches are computed by Sourcegraph: the repositories matching
+    # the search query are rewritten according to the given rewrite specification.
+    #
+    # The patches are computed in the background. Poll PatchSet.status until it is no longer
+    # PROCESSING before calling createCampaign with the returned PatchSet.id.
+    createPatchSetFromSpec(input: CreatePatchSetFromSpecInput!): PatchSet!
     # Updates a campaign.
     updateCampaign(input: UpdateCampaignInput!): Campaign!
     # Retries creating changesets from the patches in the PatchSet that could not be successfully created on the code host.
@@ -77,6 +83,8 @@ type Mutation {
     # The Campaign.draft field will be set to false and Campaign.status will
     # update according to the progress of turning the patches into
     # changesets.
+    # If Campaign.draftOnCodeHost is true, the existing draft changesets are
+    # marked as ready for review on the codehost instead.
     publishCampaign(campaign: ID!): Campaign!
     # Creates an ExternalChangeset on the codehost asynchronously.
     # The Patch has to belong to a PatchSet that has been attached
@@ -86,6 +94,47 @@ type Mutation {
     publishChangeset(patch: ID!): EmptyResponse!
     # Enqueues the given changeset for high-priority syncing.
     syncChangeset(changeset: ID!): EmptyResponse!
+    # Posts a comment with the given body on the changesets of the campaign
+    # that match the filter.
+    # The comments are posted on the codehosts asynchronously. The returned
+    # changeset actions, also available in Campaign.changesetActions, report
+    # the result for each changeset.
+    #
+    # Only site admins may perform this mutation.
+    commentOnCampaignChangesets(
+        campaign: ID!
+        # The body of the comment as Markdown.
+        body: String!
+        filter: CampaignChangesetsFilter
+    ): [ChangesetAction!]!
+    # Adds labels to and removes labels from the changesets of the campaign
+    # that match the filter.
+    # The labels are changed on the codehosts asynchronously. The returned
+    # changeset actions, also available in Campaign.changesetActions, report
+    # the result for each changeset.
+    #
+    # Only site admins may perform this mutation.
+    labelCampaignChangesets(
+        campaign: ID!
+        # The names of the labels to add.
+        addLabels: [String!]
+        # The names of the labels to remove.
+        removeLabels: [String!]
+        filter: CampaignChangesetsFilter
+    ): [ChangesetAction!]!
+    # Requests reviews from the given users on the changesets of the campaign
+    # that match the filter.
+    # The reviews are requested on the codehosts asynchronously. The returned
+    # changeset actions, also available in Campaign.changesetActions, report
+    # the result for each changeset.
+    #
+    # Only site admins may perform this mutation.
+    requestCampaignChangesetReviewers(
+        campaign: ID!
+        # The usernames of the reviewers on the codehosts.
+        reviewers: [String!]!
+        filter: CampaignChangesetsFilter
+    ): [ChangesetAction!]!
 
     # Updates the user profile information for the user with the given ID.
     #
@@ -197,11 +246,21 @@ type Mutation {
     # The supported scopes are:
     #
     # - "user:all": Full control of all resources accessible to the user account.
+    # - "search:read": Ability to run searches and read saved searches.
+    # - "repo:read": Ability to read repositories and their contents.
+    # - "campaigns:read": Ability to read campaigns and their changesets.
+    # - "campaigns:write": Ability to create, update and delete campaigns. Implies "campaigns:read".
+    # - "settings:write": Ability to read and change settings and saved searches.
+    # - "permissions:write": Ability to read and set explicit repository permissions. (Only useful for site
+    #   admins.)
     # - "site-admin:sudo": Ability to perform any action as any other user. (Only site admins may create tokens
     #   with this scope.)
     #
+    # Every access token must have at least one scope other than "site-admin:sudo". If expiresAt is given, the
+    # access token is no longer valid after that date.
+    #
     # Only the user or site admins may perform this mutation.
-    createAccessToken(user: ID!, scopes: [String!]!, note: String!): CreateAccessTokenResult!
+    createAccessToken(user: ID!, scopes: [String!]!, note: String!, expiresAt: DateTime): CreateAccessTokenResult!
     # Deletes and immediately revokes the specified access token, specified by either its ID or by the token
     # itself.
     #
@@ -405,6 +464,29 @@ type Mutation {
         # The level of repository permission.
         perm: RepositoryPermission = READ
     ): EmptyResponse!
+    # Set permissions of multiple repositories at once, each with a full set of users by their usernames
+    # or emails and by the organizations they are members of. Either all or none of the permissions are
+    # set. Only repositories whose code host has no authorization provider configured can be given
+    # permissions this way. Organizations apply to their current members, so users who join or leave an
+    # organization later gain or lose the permissions right away.
+    setRepositoryPermissionsInBulk(permissions: [RepositoryPermissionsInput!]!): EmptyResponse!
+}
+
+# The full set of users that have permissions to a repository.
+input RepositoryPermissionsInput {
+    # The ID of the repository. Exactly one of repository and repositoryName must be given.
+    repository: ID
+    # The name of the repository. Exactly one of repository and repositoryName must be given.
+    repositoryName: String
+    # A list of usernames or email addresses according to site configuration. Users who have not
+    # signed up yet are granted the permissions once they do.
+    bindIDs: [String!]
+    # A list of organizations whose members are granted the permissions. Membership is checked whenever
+    # the permissions are, so later members are granted them and former members lose them. Not giving
+    # any removes the organizations the repository was given before.
+    organizations: [ID!]
+    # The level of repository permission.
+    perm: RepositoryPermission = READ
 }
 
 # A patch to apply to a repository (in a new branch) when a campaign is created
@@ -428,6 +510,42 @@ input PatchInput {
     patch: String!
 }
 
+# Input arguments for creating a patch set from a search query and a rewrite specification.
+input CreatePatchSetFromSpecInput {
+    # The search query whose results determine the repositories (and revisions) that are
+    # rewritten. Only the repositories and revisions of the results are used, so the query
+    # doesn't need to match the code that is rewritten.
+    # Example: "repo:^github\.com/myorg/ lang:go"
+    query: String!
+
+    # The comby template matching the code to rewrite, or a regular expression if isRegExp is
+    # true.
+    # Example: "fmt.Sprintf(\"%d\", :[v])"
+    matchTemplate: String!
+
+    # The comby template the matched code is rewritten to, or the replacement of the regular
+    # expression (which may reference capture groups as $1) if isRegExp is true.
+    # Example: "strconv.Itoa(:[v])"
+    rewriteTemplate: String!
+
+    # Whether matchTemplate is a regular expression.
+    isRegExp: Boolean = false
+
+    # Whether matchTemplate is matched literally.
+    isLiteral: Boolean = false
+
+    # Whether matchTemplate is matched case sensitively.
+    isCaseSensitive: Boolean = false
+
+    # Only rewrite files with this extension.
+    # Example: "go"
+    fileExtension: String
+
+    # Don't rewrite files in directories with this name.
+    # Example: "vendor"
+    directoryExclude: String
+}
+
 # Input arguments for creating a campaign.
 input CreateCampaignInput {
     # The ID of the namespace where this campaign is defined.
@@ -456,6 +574,45 @@ input CreateCampaignInput {
     # When a Campaign is created in draft mode, its patches are not
     # created on the codehost, but only when publishing the Campaign.
     draft: Boolean
+
+    # Whether or not to create the changesets of a Campaign in draft mode as
+    # drafts on the codehost, e.g. as GitHub draft pull requests or GitLab WIP
+    # merge requests, so that CI runs on them before the Campaign is
+    # published. Publishing the Campaign marks them as ready for review.
+    # Requires draft to be true and codehosts that support drafts. Default is
+    # false.
+    draftOnCodeHost: Boolean
+
+    # Whether or not to merge the campaign's changesets automatically as soon
+    # as they are approved and their checks passed. Default is false.
+    autoMerge: Boolean
+
+    # The method used to merge changesets if autoMerge is enabled. Default is MERGE.
+    mergeMethod: ChangesetMergeMethod
+
+    # The Go text/template of the message of the commits created for the
+    # campaign's changesets. It can refer to {{.CampaignName}},
+    # {{.CampaignDescription}}, {{.Branch}} and {{.Repository}}. Default is the
+    # campaign's name.
+    commitMessage: String
+
+    # The name of the author of the commits created for the campaign's
+    # changesets. Must be set together with commitAuthorEmail. Default is the
+    # name of the campaign's author.
+    commitAuthorName: String
+
+    # The email of the author of the commits created for the campaign's
+    # changesets. Must be set together with commitAuthorName. Default is the
+    # verified primary email of the campaign's author.
+    commitAuthorEmail: String
+
+    # Whether or not to add a Signed-off-by trailer for the commit author to the
+    # commit messages. Default is false.
+    commitSignOff: Boolean
+
+    # Whether or not to sign the commits with the GPG key configured on
+    # gitserver. Default is false.
+    commitSign: Boolean
 }
 
 # Input arguments for updating a campaign.
@@ -478,6 +635,33 @@ input UpdateCampaignInput {
     # The Campaign's status will be updated accordingly while possibly
     # new ExternalChangesets are created/updated/closed on the codehosts.
     patchSet: ID
+
+    # Whether or not to merge the campaign's changesets automatically as soon
+    # as they are approved and their checks passed (if non-null).
+    autoMerge: Boolean
+
+    # The updated method used to merge changesets if autoMerge is enabled (if non-null).
+    mergeMethod: ChangesetMergeMethod
+
+    # The updated template of the commit messages (if non-null). An empty string
+    # resets it to the default. Only applies to commits created after the update.
+    commitMessage: String
+
+    # The updated name of the commit author (if non-null). An empty string resets
+    # it to the default. Only applies to commits created after the update.
+    commitAuthorName: String
+
+    # The updated email of the commit author (if non-null). An empty string
+    # resets it to the default. Only applies to commits created after the update.
+    commitAuthorEmail: String
+
+    # Whether or not to add a Signed-off-by trailer to the commit messages (if
+    # non-null). Only applies to commits created after the update.
+    commitSignOff: Boolean
+
+    # Whether or not to sign the commits (if non-null). Only applies to commits
+    # created after the update.
+    commitSign: Boolean
 }
 
 # A set of Patches that will be turned into changesets by a campaign.
@@ -491,6 +675,10 @@ type PatchSet implements Node {
 
     # The URL where the PatchSet can be previewed and a campaign can be created from it.
     previewURL: String!
+
+    # The progress of computing the patches of a PatchSet created with createPatchSetFromSpec.
+    # PatchSets created from patches computed by the caller are always COMPLETED.
+    status: BackgroundProcessStatus!
 }
 
 # A paginated list of repository diffs committed to git.
@@ -599,6 +787,36 @@ type Campaign implements Node {
     # The date and time when the campaign was closed.
     closedAt: DateTime
 
+    # Whether the campaign's changesets are merged automatically as soon as
+    # they are approved and their checks passed.
+    autoMerge: Boolean!
+
+    # The method used to merge changesets if autoMerge is enabled.
+    mergeMethod: ChangesetMergeMethod!
+
+    # The template of the message of the commits created for the campaign's
+    # changesets, or null if the campaign's name is used.
+    commitMessage: String
+
+    # The name of the author of the commits created for the campaign's
+    # changesets, or null if the name of the campaign's author is used.
+    commitAuthorName: String
+
+    # The email of the author of the commits created for the campaign's
+    # changesets, or null if the verified primary email of the campaign's
+    # author is used.
+    commitAuthorEmail: String
+
+    # Whether a Signed-off-by trailer is added to the commit messages.
+    commitSignOff: Boolean!
+
+    # Whether the commits are signed with the GPG key configured on gitserver.
+    commitSign: Boolean!
+
+    # Whether the Campaign is in draft mode with its changesets created as
+    # drafts on the codehost.
+    draftOnCodeHost: Boolean!
+
     # The date and time when the Campaign changed from draft mode to published.
     # If the Campaign has not been published yet (is still in draft mode) this
     # is null.
@@ -614,6 +832,88 @@ type Campaign implements Node {
     # Campaign.status increments with every Patch turned into an
     # ExternalChangeset.
     patches(first: Int): PatchConnection!
+
+    # The bulk actions, such as comments, performed on the changesets of the
+    # campaign, oldest first.
+    changesetActions(first: Int): ChangesetActionConnection!
+}
+
+# Selects the changesets of a campaign that a bulk action is performed on.
+# Changesets that have been deleted on the codehost are never selected.
+input CampaignChangesetsFilter {
+    # Only include the changesets with the given IDs.
+    changesets: [ID!]
+    # Only include changesets with the given state
+    state: ChangesetState
+    # Only include changesets with the given review state
+    reviewState: ChangesetReviewState
+    # Only include changesets with the given check state
+    checkState: ChangesetCheckState
+}
+
+# The kind of a bulk action performed on a changeset.
+enum ChangesetActionKind {
+    # Posts a comment on the changeset.
+    COMMENT
+    # Adds labels to and removes labels from the changeset.
+    LABEL
+    # Requests reviews of the changeset.
+    REQUEST_REVIEWERS
+}
+
+# A bulk action performed on a single changeset of a campaign on its codehost.
+type ChangesetAction implements Node {
+    # The unique ID for the changeset action.
+    id: ID!
+
+    # The kind of the action.
+    kind: ChangesetActionKind!
+
+    # The campaign the action was requested for.
+    campaign: Campaign!
+
+    # The changeset the action is performed on.
+    changeset: ExternalChangeset!
+
+    # The user who requested the action, or null if the user has been deleted.
+    requestedBy: User
+
+    # The body of the comment posted by COMMENT actions.
+    body: String
+
+    # The names of the labels added by LABEL actions.
+    addLabels: [String!]!
+
+    # The names of the labels removed by LABEL actions.
+    removeLabels: [String!]!
+
+    # The usernames of the reviewers requested by REQUEST_REVIEWERS actions.
+    reviewers: [String!]!
+
+    # Whether the action is still being processed, failed or has completed.
+    state: BackgroundProcessState!
+
+    # The error returned by the codehost if the action failed.
+    error: String
+
+    # The date and time when the action was requested.
+    createdAt: DateTime!
+
+    # The date and time when the action was performed on the codehost, or
+    # null if it's still being processed.
+    finishedAt: DateTime
+}
+
+# A list of changeset actions.
+type ChangesetActionConnection {
+    # A list of changeset actions.
+    nodes: [ChangesetAction!]!
+
+    # The total number of changeset actions in the connection.
+    totalCount: Int!
+
+    # Pagination information.
+    pageInfo: PageInfo!
 }
 
 # The counts of changesets in certain states at a specific point in time.
@@ -648,6 +948,17 @@ type CampaignConnection {
     pageInfo: PageInfo!
 }
 
+# The method used to merge a Changeset on the code host. Code hosts that
+# don't support a method fall back to the closest equivalent.
+enum ChangesetMergeMethod {
+    # Merge with a merge commit.
+    MERGE
+    # Squash all commits into one.
+    SQUASH
+    # Rebase the commits onto the base branch.
+    REBASE
+}
+
 # A Changeset's state
 enum ChangesetState {
     OPEN
@@ -771,6 +1082,10 @@ type ExternalChangeset implements Node {
     # The state of the continuous integration checks on this changeset.
     # It can be null if no checks have been configured.
     checkState: ChangesetCheckState
+
+    # Whether Sourcegraph failed to rebase the changeset onto the latest
+    # commit of its base branch, because its patch no longer applies.
+    conflicted: Boolean!
 }
 
 # A list of changesets.
@@ -1979,6 +2294,30 @@ type MirrorRepositoryInfo {
     updateSchedule: UpdateSchedule
     # The state of this repository in the update queue.
     updateQueue: UpdateQueue
+    # The most recent clones and fetches of the repository, most recent first. Only a bounded number of
+    # operations is kept, and the history is lost when gitserver restarts.
+    #
+    # Only site admins may access this field.
+    history: [MirrorRepositoryOperation!]!
+}
+
+# A clone or fetch of a mirror repository.
+type MirrorRepositoryOperation {
+    # The kind of operation, either "clone" or "fetch".
+    kind: String!
+    # When the operation started.
+    startedAt: DateTime!
+    # When the operation finished.
+    finishedAt: DateTime!
+    # How long the operation took, in milliseconds.
+    durationMilliseconds: Int!
+    # How much the repository grew on disk, in bytes. This approximates the amount of data that was
+    # fetched.
+    bytes: Float!
+    # The exit code of the git command, or -1 if it could not be run.
+    exitCode: Int!
+    # The end of the output of the git command, with credentials redacted.
+    stderr: String!
 }
 
 # The state of a repository in the update schedule.
@@ -3065,6 +3404,45 @@ type AccessToken implements Node {
     createdAt: DateTime!
     # The date when the access token was last used to authenticate a request.
     lastUsedAt: DateTime
+    # The date after which the access token is no longer valid, if any.
+    expiresAt: DateTime
+    # The most recent requests that were authenticated with the access token. Requests are kept for 30 days, and at
+    # most 1,000 requests are kept per access token.
+    #
+    # Only the access token's subject user and site admins may view this.
+    usage(
+        # Returns the first n requests from the list.
+        first: Int
+    ): AccessTokenUsageConnection!
+}
+
+# A request that was authenticated with an access token.
+type AccessTokenUsage {
+    # The date when the request was made.
+    timestamp: DateTime!
+    # The address of the peer that made the request. If the frontend is behind a reverse proxy, this is the
+    # address of the proxy.
+    remoteAddress: String!
+    # The raw X-Forwarded-For header of the request, if any. Only the entries added by trusted reverse proxies
+    # (the rightmost ones) can be relied on, the others are controlled by the client.
+    forwardedFor: String
+    # The name of the route that served the request, or the request path if it wasn't served by a named route.
+    route: String!
+    # The name of the GraphQL request, if the request was made to the GraphQL API.
+    operationName: String
+    # The HTTP status code of the response.
+    statusCode: Int!
+}
+
+# A list of requests that were authenticated with an access token.
+type AccessTokenUsageConnection {
+    # A list of requests, most recent first.
+    nodes: [AccessTokenUsage!]!
+    # The total count of recorded requests in the connection. This total count may be larger than the number of
+    # nodes in this object when the result is paginated.
+    totalCount: Int!
+    # Pagination information.
+    pageInfo: PageInfo!
 }
 
 # A list of access tokens.
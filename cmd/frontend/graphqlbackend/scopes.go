@@ -0,0 +1,138 @@
+package graphqlbackend
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/graph-gophers/graphql-go/trace"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+)
+
+// fieldScopes maps root fields of the Query and Mutation types (as "Type.field") to the access
+// token scope that is required to resolve them. Root fields that are not listed require
+// authz.ScopeUserAll. Fields below the root are only checked if their type is listed in
+// typeScopes: otherwise a scope grants access to everything that is reachable from the root
+// fields it covers.
+var fieldScopes = map[string]string{
+	"Query.search":                  authz.ScopeSearchRead,
+	"Query.searchFilterSuggestions": authz.ScopeSearchRead,
+	"Query.repoGroups":              authz.ScopeSearchRead,
+	"Query.savedSearches":           authz.ScopeSearchRead,
+
+	"Query.repository":         authz.ScopeRepoRead,
+	"Query.repositoryRedirect": authz.ScopeRepoRead,
+	"Query.repositories":       authz.ScopeRepoRead,
+	"Query.highlightCode":      authz.ScopeRepoRead,
+
+	"Query.campaigns": authz.ScopeCampaignsRead,
+
+	"Mutation.createChangesets":                  authz.ScopeCampaignsWrite,
+	"Mutation.addChangesetsToCampaign":           authz.ScopeCampaignsWrite,
+	"Mutation.createCampaign":                    authz.ScopeCampaignsWrite,
+	"Mutation.createPatchSetFromPatches":         authz.ScopeCampaignsWrite,
+	"Mutation.createPatchSetFromSpec":            authz.ScopeCampaignsWrite,
+	"Mutation.updateCampaign":                    authz.ScopeCampaignsWrite,
+	"Mutation.retryCampaign":                     authz.ScopeCampaignsWrite,
+	"Mutation.deleteCampaign":                    authz.ScopeCampaignsWrite,
+	"Mutation.closeCampaign":                     authz.ScopeCampaignsWrite,
+	"Mutation.publishCampaign":                   authz.ScopeCampaignsWrite,
+	"Mutation.publishChangeset":                  authz.ScopeCampaignsWrite,
+	"Mutation.syncChangeset":                     authz.ScopeCampaignsWrite,
+	"Mutation.commentOnCampaignChangesets":       authz.ScopeCampaignsWrite,
+	"Mutation.labelCampaignChangesets":           authz.ScopeCampaignsWrite,
+	"Mutation.requestCampaignChangesetReviewers": authz.ScopeCampaignsWrite,
+
+	"Query.viewerSettings":       authz.ScopeSettingsWrite,
+	"Mutation.settingsMutation":  authz.ScopeSettingsWrite,
+	"Mutation.createSavedSearch": authz.ScopeSettingsWrite,
+	"Mutation.updateSavedSearch": authz.ScopeSettingsWrite,
+	"Mutation.deleteSavedSearch": authz.ScopeSettingsWrite,
+
+	"Query.authorizedUserRepositories":          authz.ScopePermissionsWrite,
+	"Query.usersWithPendingPermissions":         authz.ScopePermissionsWrite,
+	"Mutation.setRepositoryPermissionsForUsers": authz.ScopePermissionsWrite,
+	"Mutation.setRepositoryPermissionsInBulk":   authz.ScopePermissionsWrite,
+}
+
+// typeScopes maps types whose fields are sensitive to the access token scope that is required to
+// resolve their fields, wherever they are reached from. For example, the users that authored
+// commits are reachable with a repo:read access token, but only their public profile
+// (publicFields) is.
+var typeScopes = map[string]string{
+	"User": authz.ScopeUserAll,
+	"Org":  authz.ScopeUserAll,
+
+	"SettingsCascade":      authz.ScopeSettingsWrite,
+	"Settings":             authz.ScopeSettingsWrite,
+	"ConfigurationCascade": authz.ScopeSettingsWrite,
+	"Configuration":        authz.ScopeSettingsWrite,
+}
+
+// publicFields are the fields of types in typeScopes that any access token may resolve.
+var publicFields = map[string]bool{
+	"User.id":          true,
+	"User.username":    true,
+	"User.displayName": true,
+	"User.avatarURL":   true,
+	"User.url":         true,
+
+	"Org.id":          true,
+	"Org.name":        true,
+	"Org.displayName": true,
+	"Org.url":         true,
+}
+
+// checkFieldScope returns an error if the current request is restricted to access token scopes
+// that don't include the scope required to resolve the given field.
+func checkFieldScope(ctx context.Context, typeName, fieldName string) error {
+	if strings.HasPrefix(fieldName, "__") {
+		return nil
+	}
+	if typeName != "Query" && typeName != "Mutation" {
+		scope, ok := typeScopes[typeName]
+		if !ok || publicFields[typeName+"."+fieldName] {
+			return nil
+		}
+		return authz.CheckScope(ctx, scope)
+	}
+	scope, ok := fieldScopes[typeName+"."+fieldName]
+	if !ok {
+		scope = authz.ScopeUserAll
+	}
+	return authz.CheckScope(ctx, scope)
+}
+
+// scopeTracer is a prometheusTracer that also enforces access token scopes on root fields and
+// sensitive types (see fieldScopes and typeScopes).
+type scopeTracer struct {
+	prometheusTracer
+}
+
+func (t scopeTracer) TraceField(ctx context.Context, label, typeName, fieldName string, trivial bool, args map[string]interface{}) (context.Context, trace.TraceFieldFinishFunc) {
+	traceCtx, finish := t.prometheusTracer.TraceField(ctx, label, typeName, fieldName, trivial, args)
+
+	// 🚨 SECURITY: graphql-go doesn't call the resolver of a field if the field's context is done,
+	// and reports the context's error for the field instead. This is the only hook it provides to
+	// deny access to a field before it is resolved.
+	if err := checkFieldScope(ctx, typeName, fieldName); err != nil {
+		return &deniedContext{Context: traceCtx, err: err}, finish
+	}
+	return traceCtx, finish
+}
+
+// deniedContext is a context that is done with the given error.
+type deniedContext struct {
+	context.Context
+	err error
+}
+
+var closedChan = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+func (c *deniedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (c *deniedContext) Done() <-chan struct{}       { return closedChan }
+func (c *deniedContext) Err() error                  { return c.err }
@@ -0,0 +1,65 @@
+package graphqlbackend
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+)
+
+func TestCheckFieldScope(t *testing.T) {
+	restricted := authz.WithScopes(context.Background(), []string{authz.ScopeSearchRead})
+	for _, tc := range []struct {
+		ctx       context.Context
+		typeName  string
+		fieldName string
+		wantErr   bool
+	}{
+		{context.Background(), "Mutation", "deleteUser", false},
+		{restricted, "Query", "search", false},
+		{restricted, "Query", "__schema", false},
+		{restricted, "Repository", "name", false},
+		{restricted, "Query", "repository", true},
+		{restricted, "Query", "currentUser", true},
+		{restricted, "Mutation", "deleteUser", true},
+		{restricted, "User", "username", false},
+		{restricted, "User", "emails", true},
+		{restricted, "User", "accessTokens", true},
+		{restricted, "User", "settingsCascade", true},
+		{restricted, "Org", "members", true},
+		{restricted, "SettingsCascade", "final", true},
+		{authz.WithScopes(context.Background(), []string{authz.ScopeSettingsWrite}), "SettingsCascade", "final", false},
+		{authz.WithScopes(context.Background(), []string{authz.ScopeUserAll}), "User", "emails", false},
+	} {
+		err := checkFieldScope(tc.ctx, tc.typeName, tc.fieldName)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s.%s: got error %v, want error: %t", tc.typeName, tc.fieldName, err, tc.wantErr)
+		}
+	}
+}
+
+// 🚨 SECURITY: This tests that the resolvers of root fields that an access token lacks the scope
+// for are never called.
+func TestSchema_FieldScopes(t *testing.T) {
+	resetMocks()
+	var called bool
+	db.Mocks.AccessTokens.Create = func(subjectUserID int32, scopes []string, note string, creatorUserID int32, expiresAt *time.Time) (int64, string, error) {
+		called = true
+		return 1, "t", nil
+	}
+	defer resetMocks()
+
+	ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 1})
+	ctx = authz.WithScopes(ctx, []string{authz.ScopeSearchRead})
+	res := mustParseGraphQLSchema(t).Exec(ctx, `mutation { createAccessToken(user: "VXNlcjox", scopes: ["user:all"], note: "n") { token } }`, "", nil)
+	if called {
+		t.Error("createAccessToken was resolved with a search:read access token")
+	}
+	if len(res.Errors) != 1 || !strings.Contains(res.Errors[0].Message, authz.ScopeUserAll) {
+		t.Errorf("got errors %v, want missing scope error", res.Errors)
+	}
+}
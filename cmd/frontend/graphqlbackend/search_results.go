@@ -1060,7 +1060,9 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 	options := &getPatternInfoOptions{}
 	if r.patternType == query.SearchTypeStructural {
 		options = &getPatternInfoOptions{performStructuralSearch: true}
-		forceOnlyResultType = "file"
+		if len(r.query.Values(query.FieldReplace)) == 0 {
+			forceOnlyResultType = "file"
+		}
 	}
 	if r.patternType == query.SearchTypeLiteral {
 		options = &getPatternInfoOptions{performLiteralSearch: true}
@@ -1338,7 +1340,7 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 			goroutine.Go(func() {
 				defer wg.Done()
 
-				codemodResults, codemodCommon, err := performCodemod(ctx, &args)
+				codemodResults, codemodCommon, err := performCodemod(ctx, &args, r.patternType)
 				// Timeouts are reported through searchResultsCommon so don't report an error for them
 				if err != nil && !isContextError(ctx, err) {
 					multiErrMu.Lock()
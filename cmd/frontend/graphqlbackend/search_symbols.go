@@ -60,7 +60,8 @@ func searchSymbols(ctx context.Context, args *search.TextParameters, limit int)
 		tr.Finish()
 	}()
 
-	if args.PatternInfo.Pattern == "" {
+	filters := symbolFiltersFromQuery(args.Query)
+	if args.PatternInfo.Pattern == "" && filters.empty() {
 		return nil, nil, nil
 	}
 
@@ -129,6 +130,13 @@ func searchSymbols(ctx context.Context, args *search.TextParameters, limit int)
 		)
 	}
 
+	// Zoekt can't evaluate symbol filters, so we ask the symbols service
+	// for the indexed repositories too.
+	if !filters.empty() {
+		searcherRepos = append(searcherRepos, zoektRepos...)
+		zoektRepos = nil
+	}
+
 	var (
 		run = parallel.NewRun(conf.SearchSymbolsParallelism())
 		mu  sync.Mutex
@@ -194,7 +202,7 @@ func searchSymbols(ctx context.Context, args *search.TextParameters, limit int)
 		run.Acquire()
 		goroutine.Go(func() {
 			defer run.Release()
-			repoSymbols, repoErr := searchSymbolsInRepo(ctx, repoRevs, args.PatternInfo, args.Query, limit)
+			repoSymbols, repoErr := searchSymbolsInRepo(ctx, repoRevs, args.PatternInfo, filters, limit)
 			if repoErr != nil {
 				tr.LogFields(otlog.String("repo", string(repoRevs.Repo.Name)), otlog.String("repoErr", repoErr.Error()), otlog.Bool("timeout", errcode.IsTimeout(repoErr)), otlog.Bool("temporary", errcode.IsTemporary(repoErr)))
 			}
@@ -251,7 +259,7 @@ func symbolCount(fmrs []*FileMatchResolver) int {
 	return nsym
 }
 
-func searchSymbolsInRepo(ctx context.Context, repoRevs *search.RepositoryRevisions, patternInfo *search.TextPatternInfo, query query.QueryInfo, limit int) (res []*FileMatchResolver, err error) {
+func searchSymbolsInRepo(ctx context.Context, repoRevs *search.RepositoryRevisions, patternInfo *search.TextPatternInfo, filters symbolFilters, limit int) (res []*FileMatchResolver, err error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "Search symbols in repo")
 	defer func() {
 		if err != nil {
@@ -286,6 +294,10 @@ func searchSymbolsInRepo(ctx context.Context, repoRevs *search.RepositoryRevisio
 		IsRegExp:        patternInfo.IsRegExp,
 		IncludePatterns: patternInfo.IncludePatterns,
 		ExcludePattern:  patternInfo.ExcludePattern,
+		Kinds:           filters.kinds,
+		Languages:       filters.languages,
+		Parent:          filters.parent,
+		ExportedOnly:    filters.exportedOnly,
 		// Ask for limit + 1 so we can detect whether there are more results than the limit.
 		First: limit + 1,
 	})
@@ -324,6 +336,35 @@ func searchSymbolsInRepo(ctx context.Context, repoRevs *search.RepositoryRevisio
 	return fileMatches, err
 }
 
+// symbolFilters are the filters in a query which restrict symbol results by
+// the properties of a symbol, rather than by its name or path. They are
+// evaluated by the symbols service.
+type symbolFilters struct {
+	kinds        []string // symbolkind: values
+	languages    []string // symbollang: values
+	parent       string   // symbolparent: regexp
+	exportedOnly bool     // symbolexported:yes
+}
+
+func symbolFiltersFromQuery(q query.QueryInfo) symbolFilters {
+	var f symbolFilters
+	f.kinds, _ = q.StringValues(query.FieldSymbolKind)
+	f.languages, _ = q.StringValues(query.FieldSymbolLang)
+	if parents, _ := q.RegexpPatterns(query.FieldSymbolParent); len(parents) > 0 {
+		f.parent = parents[len(parents)-1]
+	}
+	for _, v := range q.Values(query.FieldSymbolExported) {
+		if v.Bool != nil {
+			f.exportedOnly = *v.Bool
+		}
+	}
+	return f
+}
+
+func (f symbolFilters) empty() bool {
+	return len(f.kinds) == 0 && len(f.languages) == 0 && f.parent == "" && !f.exportedOnly
+}
+
 // makeFileMatchURIFromSymbol makes a git://repo?rev#path URI from a symbol
 // search result to use in a fileMatchResolver
 func makeFileMatchURIFromSymbol(symbolResult *searchSymbolResult, inputRev string) string {
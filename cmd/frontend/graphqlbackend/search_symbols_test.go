@@ -9,6 +9,7 @@ import (
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/internal/gituri"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
 	"github.com/sourcegraph/sourcegraph/internal/symbols/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
 )
@@ -257,3 +258,43 @@ func Test_limitingSymbolResults(t *testing.T) {
 		}
 	})
 }
+
+func TestSymbolFiltersFromQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  symbolFilters
+	}{
+		{query: "type:symbol foo", want: symbolFilters{}},
+		{
+			query: "type:symbol foo symbolkind:function symbolkind:method symbollang:go",
+			want:  symbolFilters{kinds: []string{"function", "method"}, languages: []string{"go"}},
+		},
+		{
+			query: `type:symbol foo symbolparent:^Server$ symbolexported:yes`,
+			want:  symbolFilters{parent: "^Server$", exportedOnly: true},
+		},
+		{
+			query: "type:symbol foo symbolexported:no",
+			want:  symbolFilters{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			q, err := query.ParseAndCheck(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := symbolFiltersFromQuery(q)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+			if got.empty() != reflect.DeepEqual(test.want, symbolFilters{}) {
+				t.Errorf("empty() = %v for %+v", got.empty(), got)
+			}
+		})
+	}
+
+	if _, err := query.ParseAndCheck("type:symbol foo symbolexported:maybe"); err == nil {
+		t.Error("expected symbolexported:maybe to be invalid")
+	}
+}
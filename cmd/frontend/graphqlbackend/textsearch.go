@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -24,6 +25,7 @@ import (
 
 	"github.com/inconshreveable/log15"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	searcherprotocol "github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/endpoint"
 	"github.com/sourcegraph/sourcegraph/internal/errcode"
@@ -187,8 +189,22 @@ var mockTextSearch func(ctx context.Context, repo gitserver.Repo, commit api.Com
 // textSearch searches repo@commit with p.
 // Note: the returned matches do not set fileMatch.uri
 func textSearch(ctx context.Context, searcherURLs *endpoint.Map, repo gitserver.Repo, commit api.CommitID, p *search.TextPatternInfo, fetchTimeout time.Duration) (matches []*FileMatchResolver, limitHit bool, err error) {
+	limitHit, err = textSearchStream(ctx, searcherURLs, repo, commit, p, fetchTimeout, func(fm *FileMatchResolver) {
+		matches = append(matches, fm)
+	})
+	return matches, limitHit, err
+}
+
+// textSearchStream is like textSearch, but calls send for each match as soon
+// as searcher returns it instead of collecting them. Calls to send are
+// serialized.
+func textSearchStream(ctx context.Context, searcherURLs *endpoint.Map, repo gitserver.Repo, commit api.CommitID, p *search.TextPatternInfo, fetchTimeout time.Duration, send func(*FileMatchResolver)) (limitHit bool, err error) {
 	if mockTextSearch != nil {
-		return mockTextSearch(ctx, repo, commit, p, fetchTimeout)
+		matches, limitHit, err := mockTextSearch(ctx, repo, commit, p, fetchTimeout)
+		for _, fm := range matches {
+			send(fm)
+		}
+		return limitHit, err
 	}
 
 	tr, ctx := trace.New(ctx, "searcher.client", fmt.Sprintf("%s@%s", repo.Name, commit))
@@ -211,7 +227,7 @@ func textSearch(ctx context.Context, searcherURLs *endpoint.Map, repo gitserver.
 	if deadline, ok := ctx.Deadline(); ok {
 		t, err := deadline.MarshalText()
 		if err != nil {
-			return nil, false, err
+			return false, err
 		}
 		q.Set("Deadline", string(t))
 	}
@@ -238,6 +254,7 @@ func textSearch(ctx context.Context, searcherURLs *endpoint.Map, repo gitserver.
 	// these fields from old frontends that do not (and provide a default in the latter case).
 	q.Set("PatternMatchesContent", strconv.FormatBool(p.PatternMatchesContent))
 	q.Set("PatternMatchesPath", strconv.FormatBool(p.PatternMatchesPath))
+	q.Set("Stream", "true")
 	rawQuery := q.Encode()
 
 	// Searcher caches the file contents for repo@commit since it is
@@ -251,13 +268,18 @@ func textSearch(ctx context.Context, searcherURLs *endpoint.Map, repo gitserver.
 		excludedSearchURLs = map[string]bool{}
 		attempt            = 0
 		maxAttempts        = 2
+		sent               = false
 	)
+	sendOnce := func(fm *FileMatchResolver) {
+		sent = true
+		send(fm)
+	}
 	for {
 		attempt++
 
 		searcherURL, err := searcherURLs.Get(consistentHashKey, excludedSearchURLs)
 		if err != nil {
-			return nil, false, err
+			return false, err
 		}
 
 		// Fallback to a bad host if nothing is left
@@ -265,28 +287,30 @@ func textSearch(ctx context.Context, searcherURLs *endpoint.Map, repo gitserver.
 			tr.LazyPrintf("failed to find endpoint, trying again without excludes")
 			searcherURL, err = searcherURLs.Get(consistentHashKey, nil)
 			if err != nil {
-				return nil, false, err
+				return false, err
 			}
 		}
 
 		url := searcherURL + "?" + rawQuery
 		tr.LazyPrintf("attempt %d: %s", attempt, url)
-		matches, limitHit, err = textSearchURL(ctx, url)
+		limitHit, err = textSearchURL(ctx, url, int(p.FileMatchLimit), sendOnce)
 		// Useful trace for debugging:
 		//
-		// tr.LazyPrintf("%d matches, limitHit=%v, err=%v, ctx.Err()=%v", len(matches), limitHit, err, ctx.Err())
+		// tr.LazyPrintf("limitHit=%v, err=%v, ctx.Err()=%v", limitHit, err, ctx.Err())
 		if err == nil || errcode.IsTimeout(err) {
-			return matches, limitHit, err
+			return limitHit, err
 		}
 
 		// If we are canceled, return that error.
 		if err := ctx.Err(); err != nil {
-			return nil, false, err
+			return false, err
 		}
 
-		// If not temporary or our last attempt then don't try again.
-		if !errcode.IsTemporary(err) || attempt == maxAttempts {
-			return nil, false, err
+		// If not temporary or our last attempt then don't try again. We
+		// also can't retry once matches were sent, since they would be
+		// sent again.
+		if !errcode.IsTemporary(err) || attempt == maxAttempts || sent {
+			return false, err
 		}
 
 		tr.LazyPrintf("transient error %s", err.Error())
@@ -295,10 +319,10 @@ func textSearch(ctx context.Context, searcherURLs *endpoint.Map, repo gitserver.
 	}
 }
 
-func textSearchURL(ctx context.Context, url string) ([]*FileMatchResolver, bool, error) {
+func textSearchURL(ctx context.Context, url string, fileMatchLimit int, send func(*FileMatchResolver)) (bool, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return nil, false, err
+		return false, err
 	}
 	req = req.WithContext(ctx)
 
@@ -317,17 +341,23 @@ func textSearchURL(ctx context.Context, url string) ([]*FileMatchResolver, bool,
 		if ctx.Err() != nil {
 			err = ctx.Err()
 		}
-		return nil, false, errors.Wrap(err, "searcher request failed")
+		return false, errors.Wrap(err, "searcher request failed")
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != 200 {
 		body, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
-			return nil, false, err
+			return false, err
 		}
-		return nil, false, errors.WithStack(&searcherError{StatusCode: resp.StatusCode, Message: string(body)})
+		return false, errors.WithStack(&searcherError{StatusCode: resp.StatusCode, Message: string(body)})
 	}
 
+	if resp.Header.Get("Content-Type") == searcherprotocol.StreamContentType {
+		return readSearcherStream(ctx, resp.Body, fileMatchLimit, send)
+	}
+
+	// BACKCOMPAT: searchers which do not support streaming ignore the Stream
+	// parameter and respond with a single JSON object.
 	r := struct {
 		Matches     []*FileMatchResolver
 		LimitHit    bool
@@ -335,12 +365,60 @@ func textSearchURL(ctx context.Context, url string) ([]*FileMatchResolver, bool,
 	}{}
 	err = json.NewDecoder(resp.Body).Decode(&r)
 	if err != nil {
-		return nil, false, errors.Wrap(err, "searcher response invalid")
+		return false, errors.Wrap(err, "searcher response invalid")
+	}
+	for _, fm := range r.Matches {
+		send(fm)
 	}
 	if r.DeadlineHit {
 		err = context.DeadlineExceeded
 	}
-	return r.Matches, r.LimitHit, err
+	return r.LimitHit, err
+}
+
+// readSearcherStream reads the newline-delimited JSON events of a streaming
+// searcher response from body and calls send for each match as soon as it is
+// decoded. It stops reading once more than fileMatchLimit matches have been
+// received, in which case limitHit is true.
+func readSearcherStream(ctx context.Context, body io.Reader, fileMatchLimit int, send func(*FileMatchResolver)) (limitHit bool, err error) {
+	dec := json.NewDecoder(body)
+	nMatches := 0
+	for {
+		var ev struct {
+			Match       *FileMatchResolver
+			Done        bool
+			LimitHit    bool
+			DeadlineHit bool
+			Error       string
+		}
+		if err := dec.Decode(&ev); err != nil {
+			// A stream which ends before the done event was cut short,
+			// most likely because ctx was canceled or timed out.
+			if ctx.Err() != nil {
+				return false, ctx.Err()
+			}
+			return false, errors.Wrap(err, "searcher response invalid")
+		}
+
+		if ev.Done {
+			if ev.Error != "" {
+				return ev.LimitHit, errors.New(ev.Error)
+			}
+			if ev.DeadlineHit {
+				return ev.LimitHit, context.DeadlineExceeded
+			}
+			return ev.LimitHit, nil
+		}
+
+		if ev.Match == nil {
+			continue
+		}
+		if fileMatchLimit > 0 && nMatches >= fileMatchLimit {
+			return true, nil
+		}
+		nMatches++
+		send(ev.Match)
+	}
 }
 
 type searcherError struct {
@@ -363,8 +441,21 @@ func (e *searcherError) Error() string {
 var mockSearchFilesInRepo func(ctx context.Context, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, info *search.TextPatternInfo, fetchTimeout time.Duration) (matches []*FileMatchResolver, limitHit bool, err error)
 
 func searchFilesInRepo(ctx context.Context, searcherURLs *endpoint.Map, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, info *search.TextPatternInfo, fetchTimeout time.Duration) (matches []*FileMatchResolver, limitHit bool, err error) {
+	limitHit, err = searchFilesInRepoStream(ctx, searcherURLs, repo, gitserverRepo, rev, info, fetchTimeout, func(fm *FileMatchResolver) {
+		matches = append(matches, fm)
+	})
+	return matches, limitHit, err
+}
+
+// searchFilesInRepoStream is like searchFilesInRepo, but calls send for each
+// match as soon as searcher returns it. Calls to send are serialized.
+func searchFilesInRepoStream(ctx context.Context, searcherURLs *endpoint.Map, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, info *search.TextPatternInfo, fetchTimeout time.Duration, send func(*FileMatchResolver)) (limitHit bool, err error) {
 	if mockSearchFilesInRepo != nil {
-		return mockSearchFilesInRepo(ctx, repo, gitserverRepo, rev, info, fetchTimeout)
+		matches, limitHit, err := mockSearchFilesInRepo(ctx, repo, gitserverRepo, rev, info, fetchTimeout)
+		for _, fm := range matches {
+			send(fm)
+		}
+		return limitHit, err
 	}
 
 	// Do not trigger a repo-updater lookup (e.g.,
@@ -373,28 +464,25 @@ func searchFilesInRepo(ctx context.Context, searcherURLs *endpoint.Map, repo *ty
 	// repo is not on gitserver.
 	commit, err := git.ResolveRevision(ctx, gitserverRepo, nil, rev, &git.ResolveRevisionOptions{NoEnsureRevision: true})
 	if err != nil {
-		return nil, false, err
+		return false, err
 	}
 
 	shouldBeSearched, err := repoShouldBeSearched(ctx, searcherURLs, info, gitserverRepo, commit, fetchTimeout)
 	if err != nil {
-		return nil, false, err
+		return false, err
 	}
 	if !shouldBeSearched {
-		return matches, false, err
+		return false, err
 	}
 
-	matches, limitHit, err = textSearch(ctx, searcherURLs, gitserverRepo, commit, info, fetchTimeout)
-
 	workspace := fileMatchURI(repo.Name, rev, "")
-	for _, fm := range matches {
+	return textSearchStream(ctx, searcherURLs, gitserverRepo, commit, info, fetchTimeout, func(fm *FileMatchResolver) {
 		fm.uri = workspace + fm.JPath
 		fm.Repo = repo
 		fm.CommitID = commit
 		fm.InputRev = &rev
-	}
-
-	return matches, limitHit, err
+		send(fm)
+	})
 }
 
 // repoShouldBeSearched determines whether a repository should be searched in, based on whether the repository
@@ -549,8 +637,25 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 		overLimitCanceled bool // canceled because we were over the limit
 	)
 
-	// addMatches assumes the caller holds mu.
-	addMatches := func(matches []*FileMatchResolver) {
+	// countMatches assumes the caller holds mu.
+	countMatches := func(n int) {
+		flattenedSize += n
+
+		// Stop searching once we have found enough matches. This does
+		// lead to potentially unstable result ordering, but is worth
+		// it for the performance benefit.
+		if flattenedSize > int(args.PatternInfo.FileMatchLimit) {
+			tr.LazyPrintf("cancel due to result size: %d > %d", flattenedSize, args.PatternInfo.FileMatchLimit)
+			overLimitCanceled = true
+			common.limitHit = true
+			cancel()
+		}
+	}
+
+	// appendMatches adds the matches of one repository to the results
+	// without counting them towards the limit. It assumes the caller holds
+	// mu.
+	appendMatches := func(matches []*FileMatchResolver) {
 		if len(matches) > 0 {
 			common.resultCount += int32(len(matches))
 			sort.Slice(matches, func(i, j int) bool {
@@ -558,17 +663,14 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 				return a > b
 			})
 			unflattened = append(unflattened, matches)
-			flattenedSize += len(matches)
-
-			// Stop searching once we have found enough matches. This does
-			// lead to potentially unstable result ordering, but is worth
-			// it for the performance benefit.
-			if flattenedSize > int(args.PatternInfo.FileMatchLimit) {
-				tr.LazyPrintf("cancel due to result size: %d > %d", flattenedSize, args.PatternInfo.FileMatchLimit)
-				overLimitCanceled = true
-				common.limitHit = true
-				cancel()
-			}
+		}
+	}
+
+	// addMatches assumes the caller holds mu.
+	addMatches := func(matches []*FileMatchResolver) {
+		if len(matches) > 0 {
+			appendMatches(matches)
+			countMatches(len(matches))
 		}
 	}
 
@@ -649,7 +751,18 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 					defer wg.Done()
 					defer done()
 
-					matches, repoLimitHit, err := searchFilesInRepo(ctx, args.SearcherURLs, repoRev.Repo, repoRev.GitserverRepo(), repoRev.RevSpecs()[0], args.PatternInfo, fetchTimeout)
+					// Matches count towards the limit as soon as searcher
+					// returns them, so we stop searching other repos
+					// early. They are only added to the results once the
+					// whole repo has been searched, since results are
+					// grouped by repo.
+					var matches []*FileMatchResolver
+					repoLimitHit, err := searchFilesInRepoStream(ctx, args.SearcherURLs, repoRev.Repo, repoRev.GitserverRepo(), repoRev.RevSpecs()[0], args.PatternInfo, fetchTimeout, func(fm *FileMatchResolver) {
+						matches = append(matches, fm)
+						mu.Lock()
+						countMatches(1)
+						mu.Unlock()
+					})
 					if err != nil {
 						tr.LogFields(otlog.String("repo", string(repoRev.Repo.Name)), otlog.Error(err), otlog.Bool("timeout", errcode.IsTimeout(err)), otlog.Bool("temporary", errcode.IsTemporary(err)))
 						log15.Warn("searchFilesInRepo failed", "error", err, "repo", repoRev.Repo.Name)
@@ -679,7 +792,7 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 							cancel()
 						}
 					}
-					addMatches(matches)
+					appendMatches(matches)
 				}(limitCtx, limitDone) // ends the Go routine for a call to searcher for a repo
 			} // ends the for loop iterating over repo's revs
 		} // ends the for loop iterating over repos
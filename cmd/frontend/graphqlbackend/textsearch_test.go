@@ -3,6 +3,7 @@ package graphqlbackend
 import (
 	"context"
 	"fmt"
+	"io"
 	"reflect"
 	"regexp"
 	"sort"
@@ -474,6 +475,108 @@ func TestRepoShouldBeSearched(t *testing.T) {
 	}
 }
 
+func TestReadSearcherStream(t *testing.T) {
+	const stream = `{"Match":{"Path":"a.go","LineMatches":[{"Preview":"foo","LineNumber":1,"OffsetAndLengths":[[0,3]]}]}}
+{"Match":{"Path":"b.go"}}
+{"Match":{"Path":"c.go"}}
+{"Done":true,"LimitHit":true}
+`
+	paths := func(matches []*FileMatchResolver) []string {
+		var ps []string
+		for _, m := range matches {
+			ps = append(ps, m.JPath)
+		}
+		return ps
+	}
+
+	tests := []struct {
+		name           string
+		stream         string
+		fileMatchLimit int
+		wantPaths      []string
+		wantLimitHit   bool
+		wantErr        string
+	}{
+		{
+			name:         "done",
+			stream:       stream,
+			wantPaths:    []string{"a.go", "b.go", "c.go"},
+			wantLimitHit: true,
+		},
+		{
+			name:           "client limit",
+			stream:         stream,
+			fileMatchLimit: 2,
+			wantPaths:      []string{"a.go", "b.go"},
+			wantLimitHit:   true,
+		},
+		{
+			name:      "deadline",
+			stream:    "{\"Match\":{\"Path\":\"a.go\"}}\n{\"Done\":true,\"DeadlineHit\":true}\n",
+			wantPaths: []string{"a.go"},
+			wantErr:   context.DeadlineExceeded.Error(),
+		},
+		{
+			name:      "error after matches",
+			stream:    "{\"Match\":{\"Path\":\"a.go\"}}\n{\"Done\":true,\"Error\":\"boom\"}\n",
+			wantPaths: []string{"a.go"},
+			wantErr:   "boom",
+		},
+		{
+			name:      "truncated",
+			stream:    "{\"Match\":{\"Path\":\"a.go\"}}\n",
+			wantPaths: []string{"a.go"},
+			wantErr:   "searcher response invalid: EOF",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var matches []*FileMatchResolver
+			limitHit, err := readSearcherStream(context.Background(), strings.NewReader(tt.stream), tt.fileMatchLimit, func(fm *FileMatchResolver) {
+				matches = append(matches, fm)
+			})
+			if (err == nil && tt.wantErr != "") || (err != nil && err.Error() != tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+			if got := paths(matches); !reflect.DeepEqual(got, tt.wantPaths) {
+				t.Errorf("got paths %v, want %v", got, tt.wantPaths)
+			}
+			if limitHit != tt.wantLimitHit {
+				t.Errorf("got limitHit %v, want %v", limitHit, tt.wantLimitHit)
+			}
+		})
+	}
+}
+
+func TestReadSearcherStream_SendsBeforeDone(t *testing.T) {
+	r, w := io.Pipe()
+	sent := make(chan *FileMatchResolver)
+	done := make(chan error)
+	go func() {
+		_, err := readSearcherStream(context.Background(), r, 0, func(fm *FileMatchResolver) {
+			sent <- fm
+		})
+		done <- err
+	}()
+
+	go func() {
+		_, _ = io.WriteString(w, "{\"Match\":{\"Path\":\"a.go\"}}\n")
+	}()
+	select {
+	case fm := <-sent:
+		if fm.JPath != "a.go" {
+			t.Errorf("got path %q, want %q", fm.JPath, "a.go")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("match was not sent before the stream ended")
+	}
+
+	_, _ = io.WriteString(w, "{\"Done\":true}\n")
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
 func makeRepositoryRevisions(repos ...string) []*search.RepositoryRevisions {
 	r := make([]*search.RepositoryRevisions, len(repos))
 	for i, repospec := range repos {
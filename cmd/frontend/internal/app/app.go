@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/NYTimes/gziphandler"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/envvar"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/auth/userpasswd"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/registry"
@@ -26,6 +27,9 @@ func NewHandler() http.Handler {
 	}))
 
 	r := router.Router()
+	// 🚨 SECURITY: Restricted access tokens may only be used for UI routes that allow them (see
+	// ui.Router).
+	r.Use(authz.ScopeMiddleware(map[string]string{router.UI: ""}))
 
 	m := http.NewServeMux()
 
@@ -18,6 +18,7 @@ import (
 	"github.com/gorilla/mux"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/envvar"
 	uirouter "github.com/sourcegraph/sourcegraph/cmd/frontend/internal/app/ui/router"
 	"github.com/sourcegraph/sourcegraph/internal/conf"
@@ -182,6 +183,11 @@ func initRouter() {
 	// basic pages with static titles
 	router := newRouter()
 	uirouter.Router = router // make accessible to other packages
+
+	// 🚨 SECURITY: Restricted access tokens may only be used to read raw files (which require the
+	// repo:read scope).
+	router.Use(authz.ScopeMiddleware(map[string]string{routeRaw: authz.ScopeRepoRead}))
+
 	router.Get(routeHome).Handler(handler(serveHome))
 	router.Get(routeThreads).Handler(handler(serveBrandedPageString("Threads")))
 	router.Get(routeCampaigns).Handler(handler(serveBrandedPageString("Campaigns")))
@@ -0,0 +1,28 @@
+package bg
+
+import (
+	"context"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+)
+
+const (
+	// accessTokenUsageMaxAge is how long requests authenticated with an access token are kept in
+	// the access token usage log.
+	accessTokenUsageMaxAge = 30 * 24 * time.Hour
+
+	// accessTokenUsageMaxPerToken is how many requests are kept in the access token usage log for
+	// each access token.
+	accessTokenUsageMaxPerToken = 1000
+)
+
+func DeleteOldAccessTokenUsageInPostgres(ctx context.Context) {
+	for {
+		if err := db.AccessTokenUsageLog.DeleteOld(ctx, accessTokenUsageMaxAge, accessTokenUsageMaxPerToken); err != nil {
+			log15.Error("deleting old rows from access_token_usage table", "error", err)
+		}
+		time.Sleep(time.Hour)
+	}
+}
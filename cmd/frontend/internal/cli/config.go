@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"os/user"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -265,8 +266,10 @@ func serviceConnections() conftypes.ServiceConnections {
 		}
 
 		serviceConnectionsVal = conftypes.ServiceConnections{
-			GitServers:  gitServers(),
-			PostgresDSN: dbutil.PostgresDSN(username, os.Getenv),
+			GitServers:        gitServers(),
+			GitServerReplicas: gitServerReplicas(),
+			GitServerHashing:  gitServerHashing(),
+			PostgresDSN:       dbutil.PostgresDSN(username, os.Getenv),
 		}
 	})
 	return serviceConnectionsVal
@@ -283,3 +286,25 @@ func gitServers() []string {
 	}
 	return strings.Fields(v)
 }
+
+func gitServerReplicas() int {
+	v := os.Getenv("SRC_GIT_SERVER_REPLICAS")
+	if v == "" {
+		return 1
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		log15.Warn("Ignoring invalid SRC_GIT_SERVER_REPLICAS, repositories will not be replicated.", "value", v)
+		return 1
+	}
+	return n
+}
+
+func gitServerHashing() string {
+	v := os.Getenv("SRC_GIT_SERVER_HASHING")
+	if v != "" && v != "rendezvous" {
+		log15.Warn("Ignoring invalid SRC_GIT_SERVER_HASHING, repositories will be assigned to gitservers by hashing modulo the number of gitservers.", "value", v)
+		return ""
+	}
+	return v
+}
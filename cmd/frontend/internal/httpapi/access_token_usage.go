@@ -0,0 +1,80 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+)
+
+type accessTokenUsageKey struct{}
+
+// withAccessTokenUsage returns a copy of ctx that carries the usage record of the access token that
+// authenticated the request, so that handlers can fill in the route and GraphQL operation name.
+func withAccessTokenUsage(ctx context.Context, u *db.AccessTokenUsage) context.Context {
+	return context.WithValue(ctx, accessTokenUsageKey{}, u)
+}
+
+func accessTokenUsageFromContext(ctx context.Context) *db.AccessTokenUsage {
+	u, _ := ctx.Value(accessTokenUsageKey{}).(*db.AccessTokenUsage)
+	return u
+}
+
+// accessTokenUsageRouteMiddleware is a router middleware that records the name of the matched route
+// in the access token usage record of the request (if the request was authenticated with an access
+// token).
+func accessTokenUsageRouteMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u := accessTokenUsageFromContext(r.Context()); u != nil {
+			if route := mux.CurrentRoute(r); route != nil && route.GetName() != "" {
+				u.Route = route.GetName()
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// setAccessTokenUsageOperationName records the name of the GraphQL request in the access token
+// usage record of the request (if the request was authenticated with an access token).
+func setAccessTokenUsageOperationName(ctx context.Context, name string) {
+	if u := accessTokenUsageFromContext(ctx); u != nil {
+		u.OperationName = name
+	}
+}
+
+// accessTokenUsageQueue buffers access token usage records until they are written to the database,
+// so that recording them never delays requests.
+var (
+	accessTokenUsageQueue       = make(chan *db.AccessTokenUsage, 1000)
+	startAccessTokenUsageWriter sync.Once
+)
+
+var mockLogAccessTokenUsage func(u *db.AccessTokenUsage)
+
+// logAccessTokenUsage asynchronously writes u to the access token usage log. If the database can't
+// keep up, records are dropped.
+func logAccessTokenUsage(u *db.AccessTokenUsage) {
+	if mockLogAccessTokenUsage != nil {
+		mockLogAccessTokenUsage(u)
+		return
+	}
+
+	startAccessTokenUsageWriter.Do(func() {
+		go func() {
+			for u := range accessTokenUsageQueue {
+				if err := db.AccessTokenUsageLog.Insert(context.Background(), u); err != nil {
+					log15.Error("Failed to record access token usage.", "accessTokenID", u.AccessTokenID, "err", err)
+				}
+			}
+		}()
+	})
+
+	select {
+	case accessTokenUsageQueue <- u:
+	default:
+		log15.Warn("Dropping access token usage record because the queue is full.", "accessTokenID", u.AccessTokenID)
+	}
+}
@@ -3,10 +3,12 @@ package httpapi
 import (
 	"net/http"
 
+	"github.com/felixge/httpsnoop"
 	"github.com/inconshreveable/log15"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	apirouter "github.com/sourcegraph/sourcegraph/cmd/frontend/internal/httpapi/router"
 	"github.com/sourcegraph/sourcegraph/internal/actor"
 	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/errcode"
@@ -60,14 +62,15 @@ func AccessTokenAuthMiddleware(next http.Handler) http.Handler {
 			// Validate access token.
 			//
 			// 🚨 SECURITY: It's important we check for the correct scopes to know what this token
-			// is allowed to do.
-			var requiredScope string
+			// is allowed to do. Tokens with only some of the user scopes are restricted further by
+			// the scopes that are required by each route (see routeScopes) and GraphQL field.
+			var requiredScopes []string
 			if sudoUser == "" {
-				requiredScope = authz.ScopeUserAll
+				requiredScopes = authz.UserScopes
 			} else {
-				requiredScope = authz.ScopeSiteAdminSudo
+				requiredScopes = []string{authz.ScopeSiteAdminSudo}
 			}
-			subjectUserID, err := db.AccessTokens.Lookup(r.Context(), token, requiredScope)
+			accessToken, err := db.AccessTokens.Lookup(r.Context(), token, requiredScopes)
 			if err != nil {
 				log15.Error("Invalid access token.", "token", token, "err", err)
 				http.Error(w, "Invalid access token.", http.StatusUnauthorized)
@@ -77,12 +80,13 @@ func AccessTokenAuthMiddleware(next http.Handler) http.Handler {
 			// Determine the actor's user ID.
 			var actorUserID int32
 			if sudoUser == "" {
-				actorUserID = subjectUserID
+				actorUserID = accessToken.SubjectUserID
+				r = r.WithContext(authz.WithScopes(r.Context(), accessToken.Scopes))
 			} else {
 				// 🚨 SECURITY: Confirm that the sudo token's subject is still a site admin, to
 				// prevent users from retaining site admin privileges after being demoted.
-				if err := backend.CheckUserIsSiteAdmin(r.Context(), subjectUserID); err != nil {
-					log15.Error("Sudo access token's subject is not a site admin.", "subjectUserID", subjectUserID, "err", err)
+				if err := backend.CheckUserIsSiteAdmin(r.Context(), accessToken.SubjectUserID); err != nil {
+					log15.Error("Sudo access token's subject is not a site admin.", "subjectUserID", accessToken.SubjectUserID, "err", err)
 					http.Error(w, "The subject user of a sudo access token must be a site admin.", http.StatusForbidden)
 					return
 				}
@@ -102,12 +106,39 @@ func AccessTokenAuthMiddleware(next http.Handler) http.Handler {
 					return
 				}
 				actorUserID = user.ID
-				log15.Debug("HTTP request used sudo token.", "requestURI", r.URL.RequestURI(), "tokenSubjectUserID", subjectUserID, "actorUserID", actorUserID, "actorUsername", user.Username)
+				log15.Debug("HTTP request used sudo token.", "requestURI", r.URL.RequestURI(), "tokenSubjectUserID", accessToken.SubjectUserID, "actorUserID", actorUserID, "actorUsername", user.Username)
 			}
 
 			r = r.WithContext(actor.WithActor(r.Context(), &actor.Actor{UID: actorUserID}))
+
+			// Record the request in the access token usage log. The route and GraphQL operation
+			// name are filled in by the handlers (see accessTokenUsageRouteMiddleware). The
+			// X-Forwarded-For header is recorded verbatim, because all but the entries added by
+			// trusted reverse proxies are controlled by the client.
+			usage := &db.AccessTokenUsage{
+				AccessTokenID: accessToken.ID,
+				RemoteAddr:    r.RemoteAddr,
+				ForwardedFor:  r.Header.Get("X-Forwarded-For"),
+				Route:         r.URL.Path,
+			}
+			r = r.WithContext(withAccessTokenUsage(r.Context(), usage))
+			m := httpsnoop.CaptureMetrics(next, w, r)
+			usage.StatusCode = m.Code
+			logAccessTokenUsage(usage)
+			return
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// routeScopes maps the names of HTTP API routes (see ./router) to the access token scope that is
+// required to access them. Routes that are not listed require authz.ScopeUserAll. An empty scope
+// means that any access token may be used; the GraphQL API checks scopes per field instead.
+var routeScopes = map[string]string{
+	apirouter.GraphQL:         "",
+	apirouter.SrcCliVersion:   "",
+	apirouter.SrcCliDownload:  "",
+	apirouter.RepoShield:      authz.ScopeRepoRead,
+	apirouter.CampaignsExport: authz.ScopeCampaignsRead,
+}
@@ -25,6 +25,7 @@ func serveGraphQL(schema *graphql.Schema) func(w http.ResponseWriter, r *http.Re
 			requestName = r.URL.RawQuery
 		}
 		r = r.WithContext(trace.WithGraphQLRequestName(r.Context(), requestName))
+		setAccessTokenUsageOperationName(r.Context(), requestName)
 
 		relayHandler.ServeHTTP(w, r)
 		return nil
@@ -17,9 +17,9 @@ import (
 // It is exposed as function in a package so that it can be called by other
 // main package implementations such as Sourcegraph Enterprise, which import
 // proprietary/private code.
-func Main(githubWebhook, bitbucketServerWebhook http.Handler) {
+func Main(githubWebhook, bitbucketServerWebhook, gitlabWebhook, campaignsExport http.Handler) {
 	env.Lock()
-	err := cli.Main(githubWebhook, bitbucketServerWebhook)
+	err := cli.Main(githubWebhook, bitbucketServerWebhook, gitlabWebhook, campaignsExport)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "fatal:", err)
 		os.Exit(1)
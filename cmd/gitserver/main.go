@@ -19,6 +19,7 @@ import (
 	"github.com/opentracing/opentracing-go"
 
 	"github.com/sourcegraph/sourcegraph/cmd/gitserver/server"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/debugserver"
 	"github.com/sourcegraph/sourcegraph/internal/env"
 	"github.com/sourcegraph/sourcegraph/internal/tracer"
@@ -29,6 +30,10 @@ var (
 	runRepoCleanup, _ = strconv.ParseBool(env.Get("SRC_RUN_REPO_CLEANUP", "", "Periodically remove inactive repositories."))
 	wantPctFree       = env.Get("SRC_REPOS_DESIRED_PERCENT_FREE", "10", "Target percentage of free space on disk.")
 	janitorInterval   = env.Get("SRC_REPOS_JANITOR_INTERVAL", "1m", "Interval between cleanup runs")
+	rebalanceInterval = env.Get("SRC_REPOS_REBALANCE_INTERVAL", "1m", "Interval between checks for repositories which moved to or from this gitserver after SRC_GIT_SERVERS changed")
+	bundlesDir        = env.Get("SRC_REPOS_BUNDLES_DIR", "", "Directory of git bundles to seed clones from, e.g. restored from a backup. The bundle of github.com/foo/bar is github.com/foo/bar.bundle.")
+	gitServerAddr     = env.Get("SRC_GIT_SERVER_ADDR", "", "The address of this gitserver in SRC_GIT_SERVERS. Defaults to the address matching the hostname.")
+	commitSigningKey  = env.Get("SRC_GIT_COMMIT_SIGNING_KEY", "", "ID of the GPG key in gitserver's keyring used to sign commits created from patches, e.g. by campaigns.")
 )
 
 func main() {
@@ -47,10 +52,29 @@ func main() {
 	if err != nil {
 		log.Fatalf("parsing $SRC_REPOS_DESIRED_PERCENT_FREE: %v", err)
 	}
+	hostname := gitServerAddr
+	if hostname == "" {
+		hostname, err = os.Hostname()
+		if err != nil {
+			log.Fatalf("failed to get hostname: %s", err)
+		}
+	}
 	gitserver := server.Server{
 		ReposDir:                reposDir,
 		DeleteStaleRepositories: runRepoCleanup,
 		DesiredPercentFree:      wantPctFree2,
+		BundlesDir:              bundlesDir,
+		Hostname:                hostname,
+		CommitSigningKey:        commitSigningKey,
+		GitServerAddrs: func() []string {
+			return conf.Get().ServiceConnections.GitServers
+		},
+		GitServerReplicas: func() int {
+			return conf.Get().ServiceConnections.GitServerReplicas
+		},
+		GitServerHashing: func() string {
+			return conf.Get().ServiceConnections.GitServerHashing
+		},
 	}
 	gitserver.RegisterMetrics()
 
@@ -78,6 +102,17 @@ func main() {
 		}
 	}()
 
+	rebalanceInterval2, err := time.ParseDuration(rebalanceInterval)
+	if err != nil {
+		log.Fatalf("parsing $SRC_REPOS_REBALANCE_INTERVAL: %v", err)
+	}
+	go func() {
+		for {
+			gitserver.RebalanceRepos()
+			time.Sleep(rebalanceInterval2)
+		}
+	}()
+
 	port := "3178"
 	host := ""
 	if env.InsecureDev {
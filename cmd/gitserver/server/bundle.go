@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+)
+
+// bundlePath returns the git bundle to seed the clone of repo from, or "" if
+// it should be cloned from its code host. Bundles of repo names which would
+// resolve outside of BundlesDir (e.g. containing "..") are never used.
+func (s *Server) bundlePath(repo api.RepoName, opts *cloneOptions) string {
+	if opts != nil && opts.Bundle != "" {
+		return opts.Bundle
+	}
+	if s.BundlesDir == "" {
+		return ""
+	}
+	path := filepath.Join(s.BundlesDir, filepath.FromSlash(string(protocol.NormalizeRepo(repo)))+".bundle")
+	if !strings.HasPrefix(path, filepath.Clean(s.BundlesDir)+string(filepath.Separator)) {
+		return ""
+	}
+	if fi, err := os.Stat(path); err != nil || !fi.Mode().IsRegular() {
+		return ""
+	}
+	return path
+}
+
+// fetchAfterSeeding points the origin of a clone seeded from a bundle at url
+// and fetches everything which changed since the bundle was created.
+func (s *Server) fetchAfterSeeding(ctx context.Context, repo api.RepoName, dir GitDir, url string, progress io.Writer) error {
+	cmd := exec.CommandContext(ctx, "git", "remote", "set-url", "origin", "--", url)
+	cmd.Dir = string(dir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "failed to set remote URL of seeded clone. Output: %s", string(output))
+	}
+
+	cmd, configRemoteOpts := fetchCmd(ctx, url)
+	cmd.Dir = string(dir)
+	redactor := newURLRedactor(url)
+	op := s.startOperation(repo, "fetch", dir)
+	output, err := runWith(ctx, cmd, configRemoteOpts, progress)
+	op.finish(cmd, output, redactor)
+	if err != nil {
+		return errors.Wrapf(err, "fetch after seeding from bundle failed. Output: %s", redactor.redact(string(output)))
+	}
+	return nil
+}
+
+// handleSeedBundle clones a repository from the git bundle in the request
+// body, and then fetches what changed since the bundle was created from the
+// code host. The repository is given by the "repo" query parameter, and its
+// remote URL by "url".
+func (s *Server) handleSeedBundle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	repo := protocol.NormalizeRepo(api.RepoName(q.Get("repo")))
+	url := q.Get("url")
+	if repo == "" || url == "" {
+		http.Error(w, "repo and url are required", http.StatusBadRequest)
+		return
+	}
+	dir := s.dir(repo)
+	if repoCloned(dir) {
+		http.Error(w, "repository is already cloned", http.StatusConflict)
+		return
+	}
+	if _, cloning := s.locker.Status(dir); cloning {
+		http.Error(w, "repository is already being cloned", http.StatusConflict)
+		return
+	}
+
+	tmp, err := s.tempDir("bundle-")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(tmp)
+
+	bundle := filepath.Join(tmp, "repo.bundle")
+	if err := writeBundle(bundle, r.Body); err != nil {
+		http.Error(w, "failed to read bundle: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cmd := exec.CommandContext(r.Context(), "git", "bundle", "list-heads", bundle)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		http.Error(w, "invalid bundle: "+string(output), http.StatusBadRequest)
+		return
+	}
+
+	// Like repo-update, we don't want to abort the clone if the client goes
+	// away.
+	ctx, cancel1 := s.serverContext()
+	defer cancel1()
+	ctx, cancel2 := context.WithTimeout(ctx, longGitCommandTimeout)
+	defer cancel2()
+
+	if _, err := s.cloneRepo(ctx, repo, url, &cloneOptions{Block: true, Bundle: bundle}); err != nil {
+		if os.IsExist(errors.Cause(err)) {
+			http.Error(w, "repository is already cloned", http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func writeBundle(path string, r io.Reader) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var repoSeededCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "src",
+	Subsystem: "gitserver",
+	Name:      "repo_seeded_from_bundle",
+	Help:      "number of successful clones seeded from a git bundle",
+})
+
+func init() {
+	prometheus.MustRegister(repoSeededCounter)
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/mutablelimiter"
+)
+
+// makeBundledRepo creates a repository with a commit, bundles it, and then
+// adds another commit which is not in the bundle. It returns the path of the
+// repository, of the bundle and the latest commit.
+func makeBundledRepo(t *testing.T, root string) (remote, bundle, head string) {
+	t.Helper()
+	remote = filepath.Join(root, "remote")
+	bundle = filepath.Join(root, "remote.bundle")
+	if err := os.MkdirAll(remote, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	cmd := func(name string, arg ...string) string {
+		t.Helper()
+		c := exec.Command(name, arg...)
+		c.Dir = remote
+		c.Env = append(os.Environ(),
+			"GIT_COMMITTER_NAME=a",
+			"GIT_COMMITTER_EMAIL=a@a.com",
+			"GIT_AUTHOR_NAME=a",
+			"GIT_AUTHOR_EMAIL=a@a.com",
+		)
+		b, err := c.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s %s failed: %s: %s", name, strings.Join(arg, " "), err, b)
+		}
+		return strings.TrimSpace(string(b))
+	}
+	cmd("git", "init", ".")
+	cmd("git", "commit", "--allow-empty", "-m", "in bundle")
+	cmd("git", "bundle", "create", bundle, "--all")
+	cmd("git", "commit", "--allow-empty", "-m", "after bundle")
+	return remote, bundle, cmd("git", "rev-parse", "HEAD")
+}
+
+func revParseHead(t *testing.T, dir GitDir) string {
+	t.Helper()
+	c := exec.Command("git", "rev-parse", "HEAD")
+	c.Dir = string(dir)
+	b, err := c.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git rev-parse HEAD failed: %s: %s", err, b)
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func TestCloneRepo_BundlesDir(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+	remote, bundle, wantHead := makeBundledRepo(t, root)
+
+	const repo = api.RepoName("example.com/foo/bar")
+	bundlesDir := filepath.Join(root, "bundles")
+	if err := os.MkdirAll(filepath.Join(bundlesDir, "example.com", "foo"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(bundle, filepath.Join(bundlesDir, "example.com", "foo", "bar.bundle")); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{
+		ReposDir:         filepath.Join(root, "repos"),
+		BundlesDir:       bundlesDir,
+		ctx:              context.Background(),
+		locker:           &RepositoryLocker{},
+		cloneLimiter:     mutablelimiter.New(1),
+		cloneableLimiter: mutablelimiter.New(1),
+	}
+	if _, err := s.cloneRepo(context.Background(), repo, remote, &cloneOptions{Block: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := s.dir(repo)
+	if got := revParseHead(t, dir); got != wantHead {
+		t.Errorf("got HEAD %s, want %s which was fetched after seeding", got, wantHead)
+	}
+	if got, err := repoRemoteURL(context.Background(), dir); err != nil || got != remote {
+		t.Errorf("got remote URL %q (err: %v), want %q", got, err, remote)
+	}
+}
+
+func TestBundlePath(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+
+	bundlesDir := filepath.Join(root, "bundles")
+	for _, name := range []string{"bundles/example.com/foo.bundle", "outside.bundle"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := &Server{BundlesDir: bundlesDir}
+	for repo, want := range map[api.RepoName]string{
+		"example.com/foo":           filepath.Join(bundlesDir, "example.com", "foo.bundle"),
+		"example.com/missing":       "",
+		"../outside":                "",
+		"example.com/../../outside": "",
+	} {
+		if got := s.bundlePath(repo, nil); got != want {
+			t.Errorf("bundlePath(%q) = %q, want %q", repo, got, want)
+		}
+	}
+}
+
+func TestHandleSeedBundle(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+	remote, bundle, wantHead := makeBundledRepo(t, root)
+
+	s := &Server{ReposDir: filepath.Join(root, "repos")}
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+	defer s.Stop()
+
+	seed := func(body []byte) int {
+		t.Helper()
+		q := url.Values{"repo": {"example.com/foo/bar"}, "url": {remote}}
+		resp, err := http.Post(srv.URL+"/seed-bundle?"+q.Encode(), "application/octet-stream", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if got := seed([]byte("not a bundle")); got != http.StatusBadRequest {
+		t.Errorf("got status %d for an invalid bundle, want %d", got, http.StatusBadRequest)
+	}
+
+	b, err := ioutil.ReadFile(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := seed(b); got != http.StatusOK {
+		t.Fatalf("got status %d, want %d", got, http.StatusOK)
+	}
+	if got := revParseHead(t, s.dir("example.com/foo/bar")); got != wantHead {
+		t.Errorf("got HEAD %s, want %s", got, wantHead)
+	}
+
+	if got := seed(b); got != http.StatusConflict {
+		t.Errorf("got status %d when seeding a cloned repository, want %d", got, http.StatusConflict)
+	}
+}
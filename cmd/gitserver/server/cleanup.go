@@ -220,6 +220,12 @@ func (s *Server) cleanupRepos() {
 		log15.Error("cleanup: error iterating over repositories", "error", err)
 	}
 
+	// Forget the history of repositories which are gone, e.g. because they
+	// moved to another gitserver or were never cloned successfully.
+	s.history.prune(time.Now().Add(-repoHistoryTTL), func(repo api.RepoName) bool {
+		return repoCloned(s.dir(repo))
+	})
+
 	if s.DiskSizer == nil {
 		s.DiskSizer = &StatDiskSizer{}
 	}
@@ -478,6 +484,8 @@ func (s *Server) removeRepoDirectory(gitDir GitDir) error {
 	// Everything after this point is just cleanup, so any error that occurs
 	// should not be returned, just logged.
 
+	s.history.remove(s.name(gitDir))
+
 	// Cleanup empty parent directories. We just attempt to remove and if we
 	// have a failure we assume it's due to the directory having other
 	// children. If we checked first we could race with someone else adding a
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+)
+
+const (
+	// maxRepoHistory is the number of clones and fetches remembered per
+	// repository.
+	maxRepoHistory = 10
+
+	// maxRepoHistoryRepos is the number of repositories whose history is
+	// remembered. Beyond it, the history of the repository which was least
+	// recently cloned or fetched is forgotten.
+	maxRepoHistoryRepos = 10000
+
+	// repoHistoryTTL is how long the history of a repository which is not
+	// cloned is remembered after its last clone or fetch.
+	repoHistoryTTL = 24 * time.Hour
+
+	// maxRepoHistoryStderr is the number of bytes of output remembered per
+	// clone or fetch.
+	maxRepoHistoryStderr = 1024
+)
+
+// repoHistory remembers the most recent clones and fetches of each
+// repository, so that site admins can debug repositories which fail to update
+// without access to gitserver's logs. The zero value is ready to use. History
+// is not persisted across restarts.
+type repoHistory struct {
+	mu      sync.Mutex
+	entries map[api.RepoName]*repoHistoryEntry
+}
+
+type repoHistoryEntry struct {
+	ops []protocol.RepoOperation // oldest first
+
+	// objects is the size in bytes of the repository's objects after the
+	// last operation, or -1 if it is unknown.
+	objects int64
+}
+
+// add records op of repo, after which the objects of repo have the given
+// size in bytes, or -1 if it is unknown.
+func (h *repoHistory) add(repo api.RepoName, op protocol.RepoOperation, objects int64) {
+	repo = protocol.NormalizeRepo(repo)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.entries == nil {
+		h.entries = make(map[api.RepoName]*repoHistoryEntry)
+	}
+	e, ok := h.entries[repo]
+	if !ok {
+		if len(h.entries) >= maxRepoHistoryRepos {
+			h.evictOldest()
+		}
+		e = &repoHistoryEntry{}
+		h.entries[repo] = e
+	}
+	ops := append(e.ops, op)
+	if len(ops) > maxRepoHistory {
+		// Copy rather than reslice so that we don't keep old operations
+		// alive in the backing array.
+		ops = append([]protocol.RepoOperation(nil), ops[len(ops)-maxRepoHistory:]...)
+	}
+	e.ops = ops
+	e.objects = objects
+}
+
+// evictOldest forgets the repository whose last operation ended first. h.mu
+// must be held.
+func (h *repoHistory) evictOldest() {
+	var (
+		oldest api.RepoName
+		end    time.Time
+	)
+	for repo, e := range h.entries {
+		if last := e.ops[len(e.ops)-1].End; oldest == "" || last.Before(end) {
+			oldest, end = repo, last
+		}
+	}
+	delete(h.entries, oldest)
+}
+
+// get returns the operations of repo, most recent first.
+func (h *repoHistory) get(repo api.RepoName) []protocol.RepoOperation {
+	repo = protocol.NormalizeRepo(repo)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var ops []protocol.RepoOperation
+	if e, ok := h.entries[repo]; ok {
+		ops = e.ops
+	}
+	res := make([]protocol.RepoOperation, 0, len(ops))
+	for i := len(ops) - 1; i >= 0; i-- {
+		res = append(res, ops[i])
+	}
+	return res
+}
+
+// objects returns the size in bytes of the objects of repo after its last
+// operation, or -1 if it is unknown.
+func (h *repoHistory) objects(repo api.RepoName) int64 {
+	repo = protocol.NormalizeRepo(repo)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if e, ok := h.entries[repo]; ok {
+		return e.objects
+	}
+	return -1
+}
+
+// remove forgets the history of repo.
+func (h *repoHistory) remove(repo api.RepoName) {
+	repo = protocol.NormalizeRepo(repo)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.entries, repo)
+}
+
+// prune forgets the history of the repositories whose last operation ended
+// before t and for which keep returns false.
+func (h *repoHistory) prune(t time.Time, keep func(api.RepoName) bool) {
+	h.mu.Lock()
+	var stale []api.RepoName
+	for repo, e := range h.entries {
+		if e.ops[len(e.ops)-1].End.Before(t) {
+			stale = append(stale, repo)
+		}
+	}
+	h.mu.Unlock()
+
+	// keep may be slow, so it is called without holding h.mu.
+	for _, repo := range stale {
+		if !keep(repo) {
+			h.remove(repo)
+		}
+	}
+}
+
+// repoOperation is a clone or fetch which is being recorded in the history.
+type repoOperation struct {
+	s             *Server
+	repo          api.RepoName
+	kind          string
+	dir           GitDir
+	start         time.Time
+	objectsBefore int64
+}
+
+// startOperation starts recording a clone or fetch of repo into dir.
+//
+// Sizing the objects of a repository runs git count-objects, so it is avoided
+// where possible: clones start out empty, and fetches start with the objects
+// left by the previous operation. Only the first fetch of a repository since
+// gitserver started, or the first after a failed operation, sizes them.
+func (s *Server) startOperation(repo api.RepoName, kind string, dir GitDir) *repoOperation {
+	var before int64
+	if kind != "clone" {
+		before = s.history.objects(repo)
+		if before < 0 {
+			before, _ = objectsSize(dir)
+		}
+	}
+	return &repoOperation{
+		s:             s,
+		repo:          repo,
+		kind:          kind,
+		dir:           dir,
+		start:         time.Now(),
+		objectsBefore: before,
+	}
+}
+
+// finish records the result of the git command cmd, which wrote output.
+//
+// A fetch which succeeded without output did not update any refs, so the
+// objects are only sized if the command failed or wrote output.
+func (o *repoOperation) finish(cmd *exec.Cmd, output []byte, redactor *urlRedactor) {
+	op := protocol.RepoOperation{
+		Kind:     o.kind,
+		Start:    o.start,
+		End:      time.Now(),
+		ExitCode: -1,
+	}
+	if cmd.ProcessState != nil {
+		op.ExitCode = cmd.ProcessState.Sys().(syscall.WaitStatus).ExitStatus()
+	}
+	objects := o.objectsBefore
+	if op.ExitCode != 0 {
+		// A failed operation may have left some objects behind. Leave
+		// sizing them to the next operation.
+		objects = -1
+	} else if len(output) > 0 || o.kind == "clone" {
+		if after, err := objectsSize(o.dir); err != nil {
+			objects = -1
+		} else {
+			objects = after
+			if after > o.objectsBefore {
+				op.Bytes = after - o.objectsBefore
+			}
+		}
+	}
+	// 🚨 SECURITY: The output can contain the remote URL, including
+	// credentials. Redact before truncating, since truncating could cut a
+	// credential so that it is no longer recognized.
+	stderr := redactor.redact(string(output))
+	if len(stderr) > maxRepoHistoryStderr {
+		stderr = stderr[len(stderr)-maxRepoHistoryStderr:]
+	}
+	op.Stderr = stderr
+
+	o.s.history.add(o.repo, op, objects)
+}
+
+// objectsSize returns the size in bytes of the loose and packed objects of
+// the repository in dir, as reported by git count-objects. Unlike walking the
+// objects directory this doesn't stat every loose object.
+func objectsSize(dir GitDir) (int64, error) {
+	cmd := exec.Command("git", "count-objects", "-v")
+	cmd.Dir = string(dir)
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, err
+	}
+	var kib int64
+	for _, line := range strings.Split(string(out), "\n") {
+		i := strings.Index(line, ": ")
+		if i < 0 {
+			continue
+		}
+		if key := line[:i]; key != "size" && key != "size-pack" {
+			continue
+		}
+		n, err := strconv.ParseInt(line[i+2:], 10, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to parse git count-objects output %q", line)
+		}
+		kib += n
+	}
+	return kib * 1024, nil
+}
+
+func (s *Server) handleRepoHistory(w http.ResponseWriter, r *http.Request) {
+	var req protocol.RepoHistoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := protocol.RepoHistoryResponse{
+		Operations: s.history.get(req.Repo),
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
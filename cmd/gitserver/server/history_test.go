@@ -0,0 +1,168 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/mutablelimiter"
+)
+
+func TestRepoHistory(t *testing.T) {
+	var h repoHistory
+	if got := h.get("r"); len(got) != 0 {
+		t.Fatalf("expected empty history, got %v", got)
+	}
+
+	for i := 0; i < maxRepoHistory+5; i++ {
+		h.add("r", protocol.RepoOperation{Kind: "fetch", ExitCode: i}, -1)
+	}
+	h.add("other", protocol.RepoOperation{Kind: "clone"}, -1)
+
+	got := h.get("r")
+	if len(got) != maxRepoHistory {
+		t.Fatalf("got %d operations, want %d", len(got), maxRepoHistory)
+	}
+	for i, op := range got {
+		if want := maxRepoHistory + 4 - i; op.ExitCode != want {
+			t.Errorf("operation %d: got exit code %d, want %d (most recent first)", i, op.ExitCode, want)
+		}
+	}
+}
+
+func TestRepoHistory_Evict(t *testing.T) {
+	var h repoHistory
+	start := time.Now()
+	for i := 0; i < maxRepoHistoryRepos; i++ {
+		h.add(api.RepoName(fmt.Sprintf("r%d", i)), protocol.RepoOperation{End: start.Add(time.Duration(i) * time.Second)}, -1)
+	}
+	// r0 is fetched again, so r1 is now the least recently updated.
+	h.add("r0", protocol.RepoOperation{End: start.Add(time.Hour)}, -1)
+	h.add("new", protocol.RepoOperation{End: start.Add(time.Hour)}, -1)
+
+	if len(h.entries) != maxRepoHistoryRepos {
+		t.Errorf("got history of %d repositories, want %d", len(h.entries), maxRepoHistoryRepos)
+	}
+	for repo, want := range map[api.RepoName]int{"r0": 2, "r1": 0, "r2": 1, "new": 1} {
+		if got := len(h.get(repo)); got != want {
+			t.Errorf("%s: got %d operations, want %d", repo, got, want)
+		}
+	}
+}
+
+func TestRepoHistory_Prune(t *testing.T) {
+	var h repoHistory
+	now := time.Now()
+	h.add("old-cloned", protocol.RepoOperation{End: now.Add(-2 * time.Hour)}, -1)
+	h.add("old-gone", protocol.RepoOperation{End: now.Add(-2 * time.Hour)}, -1)
+	h.add("recent-gone", protocol.RepoOperation{End: now}, -1)
+
+	h.prune(now.Add(-time.Hour), func(repo api.RepoName) bool { return repo == "old-cloned" })
+
+	for repo, want := range map[api.RepoName]int{"old-cloned": 1, "old-gone": 0, "recent-gone": 1} {
+		if got := len(h.get(repo)); got != want {
+			t.Errorf("%s: got %d operations, want %d", repo, got, want)
+		}
+	}
+}
+
+func TestRepoOperation_RedactsTruncatedStderr(t *testing.T) {
+	const token = "s3cr3t-t0k3n"
+	url := "https://user:" + token + "@example.com/foo/bar"
+	// Make the output so long that truncating it to maxRepoHistoryStderr
+	// bytes cuts the token, leaving only its last 5 bytes.
+	suffix := "@example.com/foo/bar' failed\n"
+	output := "fatal: unable to access 'https://user:" + token + suffix + strings.Repeat("x", maxRepoHistoryStderr-len(suffix)-5)
+
+	s := &Server{}
+	op := s.startOperation("example.com/foo/bar", "fetch", GitDir(filepath.Join(t.Name(), "does-not-exist")))
+	op.finish(&exec.Cmd{}, []byte(output), newURLRedactor(url))
+
+	got := s.history.get("example.com/foo/bar")
+	if len(got) != 1 {
+		t.Fatalf("got %d operations, want 1", len(got))
+	}
+	if stderr := got[0].Stderr; strings.Contains(stderr, token[len(token)-5:]) {
+		t.Errorf("stderr contains part of the token: %q", stderr)
+	} else if len(stderr) > maxRepoHistoryStderr {
+		t.Errorf("got %d bytes of stderr, want at most %d", len(stderr), maxRepoHistoryStderr)
+	}
+}
+
+func TestCloneRepo_RecordsHistory(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+	remote, _, _ := makeBundledRepo(t, root)
+
+	const repo = api.RepoName("example.com/foo/bar")
+	s := &Server{
+		ReposDir:         filepath.Join(root, "repos"),
+		ctx:              context.Background(),
+		locker:           &RepositoryLocker{},
+		cloneLimiter:     mutablelimiter.New(1),
+		cloneableLimiter: mutablelimiter.New(1),
+	}
+	start := time.Now()
+	if _, err := s.cloneRepo(context.Background(), repo, remote, &cloneOptions{Block: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(s.handleRepoHistory))
+	defer srv.Close()
+	body, _ := json.Marshal(&protocol.RepoHistoryRequest{Repo: repo})
+	resp, err := http.Post(srv.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var res protocol.RepoHistoryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Operations) != 1 {
+		t.Fatalf("got %d operations, want 1: %+v", len(res.Operations), res.Operations)
+	}
+	op := res.Operations[0]
+	if op.Kind != "clone" || op.ExitCode != 0 {
+		t.Errorf("unexpected operation %+v", op)
+	}
+	if op.Start.Before(start) || op.End.Before(op.Start) {
+		t.Errorf("unexpected times: start %s, end %s", op.Start, op.End)
+	}
+	if op.Bytes <= 0 {
+		t.Errorf("expected clone to add objects, got %d bytes", op.Bytes)
+	}
+	if objects := s.history.objects(repo); objects != op.Bytes {
+		t.Errorf("got %d bytes of objects after the clone, want %d", objects, op.Bytes)
+	}
+
+	// A fetch without output did not change anything, so the objects are
+	// not sized again.
+	fetch := s.startOperation(repo, "fetch", GitDir(filepath.Join(t.Name(), "does-not-exist")))
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	fetch.finish(cmd, nil, newURLRedactor(remote))
+	if objects := s.history.objects(repo); objects != op.Bytes {
+		t.Errorf("got %d bytes of objects after a fetch without output, want %d", objects, op.Bytes)
+	}
+
+	if err := s.deleteRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	if ops := s.history.get(repo); len(ops) != 0 {
+		t.Errorf("got %d operations after deleting the repository, want none", len(ops))
+	}
+}
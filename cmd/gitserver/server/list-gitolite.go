@@ -12,6 +12,10 @@ func (s *Server) handleListGitolite(w http.ResponseWriter, r *http.Request) {
 	defaultGitolite.listRepos(r.Context(), r.URL.Query().Get("gitolite"), w)
 }
 
+func (s *Server) handleListGitolitePerms(w http.ResponseWriter, r *http.Request) {
+	defaultGitolite.listRepoPerms(r.Context(), r.URL.Query().Get("gitolite"), w)
+}
+
 var defaultGitolite = gitoliteFetcher{client: gitoliteClient{}}
 
 type gitoliteFetcher struct {
@@ -20,6 +24,7 @@ type gitoliteFetcher struct {
 
 type iGitoliteClient interface {
 	ListRepos(ctx context.Context, host string) ([]*gitolite.Repo, error)
+	ListRepoPerms(ctx context.Context, host string) (*gitolite.Perms, error)
 }
 
 // listRepos lists the repos of a Gitolite server reachable at the address in gitoliteHost
@@ -42,8 +47,32 @@ func (g gitoliteFetcher) listRepos(ctx context.Context, gitoliteHost string, w h
 	}
 }
 
+// listRepoPerms lists the read permissions of the users of a Gitolite server reachable at the
+// address in gitoliteHost
+func (g gitoliteFetcher) listRepoPerms(ctx context.Context, gitoliteHost string, w http.ResponseWriter) {
+	if gitoliteHost == "" {
+		http.Error(w, "no gitolite host specified", http.StatusBadRequest)
+		return
+	}
+
+	perms, err := g.client.ListRepoPerms(ctx, gitoliteHost)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(perms); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
 type gitoliteClient struct{}
 
 func (c gitoliteClient) ListRepos(ctx context.Context, host string) ([]*gitolite.Repo, error) {
 	return gitolite.NewClient(host).ListRepos(ctx)
 }
+
+func (c gitoliteClient) ListRepoPerms(ctx context.Context, host string) (*gitolite.Perms, error) {
+	return gitolite.NewClient(host).ListRepoPerms(ctx)
+}
@@ -63,10 +63,61 @@ func Test_Gitolite_listRepos(t *testing.T) {
 	}
 }
 
+func Test_Gitolite_listRepoPerms(t *testing.T) {
+	g := gitoliteFetcher{
+		client: stubGitoliteClient{
+			ListRepoPerms_: func(ctx context.Context, host string) (*gitolite.Perms, error) {
+				return &gitolite.Perms{
+					Users: []string{"alice", "bob"},
+					Repos: []*gitolite.RepoPerms{{Name: "myrepo", Users: []string{"alice"}}},
+				}, nil
+			},
+		},
+	}
+
+	for _, test := range []struct {
+		gitoliteHost    string
+		expResponseCode int
+		expResponseBody string
+	}{
+		{
+			gitoliteHost:    "git@gitolite.example.com",
+			expResponseCode: 200,
+			expResponseBody: `{"Users":["alice","bob"],"Repos":[{"Name":"myrepo","Users":["alice"]}]}` + "\n",
+		},
+		{
+			gitoliteHost:    "",
+			expResponseCode: 400,
+			expResponseBody: "no gitolite host specified\n",
+		},
+	} {
+		t.Run(test.gitoliteHost, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			g.listRepoPerms(context.Background(), test.gitoliteHost, w)
+			resp := w.Result()
+			respBody, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.expResponseBody, string(respBody)); diff != "" {
+				t.Errorf("unexpected response body diff:\n%s", diff)
+			}
+			if diff := cmp.Diff(test.expResponseCode, resp.StatusCode); diff != "" {
+				t.Errorf("unexpected response code diff:\n%s", diff)
+			}
+		})
+	}
+}
+
 type stubGitoliteClient struct {
-	ListRepos_ func(ctx context.Context, host string) ([]*gitolite.Repo, error)
+	ListRepos_     func(ctx context.Context, host string) ([]*gitolite.Repo, error)
+	ListRepoPerms_ func(ctx context.Context, host string) (*gitolite.Perms, error)
 }
 
 func (c stubGitoliteClient) ListRepos(ctx context.Context, host string) ([]*gitolite.Repo, error) {
 	return c.ListRepos_(ctx, host)
 }
+
+func (c stubGitoliteClient) ListRepoPerms(ctx context.Context, host string) (*gitolite.Perms, error) {
+	return c.ListRepoPerms_(ctx, host)
+}
@@ -183,8 +183,18 @@ func (s *Server) createCommitFromPatch(ctx context.Context, req protocol.CreateC
 		committerEmail = authorEmail
 	}
 
-	cmd = exec.CommandContext(ctx, "git", "commit", "-m", message)
+	if req.CommitInfo.Sign && s.CommitSigningKey == "" {
+		resp.SetError(repo, "", "", errors.New("gitserver: commit signing requested, but no signing key is configured"))
+		return http.StatusBadRequest, resp
+	}
+
+	cmd = exec.CommandContext(ctx, "git", commitArgs(message, req.CommitInfo, s.CommitSigningKey)...)
 	cmd.Dir = tmpRepoDir
+	if req.CommitInfo.Sign {
+		// git runs gpg to sign the commit, which needs PATH and HOME or
+		// GNUPGHOME to find the binary and the keyring of gitserver.
+		cmd.Env = os.Environ()
+	}
 	cmd.Env = append(cmd.Env, []string{
 		tmpGitPathEnv,
 		altObjectsEnv,
@@ -242,7 +252,11 @@ func (s *Server) createCommitFromPatch(ctx context.Context, req protocol.CreateC
 	}
 
 	if req.Push {
-		cmd = exec.CommandContext(ctx, "git", "push", "--force", remoteURL, fmt.Sprintf("%s:%s", cmtHash, ref))
+		force := "--force"
+		if req.ExpectedHead != "" {
+			force = fmt.Sprintf("--force-with-lease=%s:%s", ref, req.ExpectedHead)
+		}
+		cmd = exec.CommandContext(ctx, "git", "push", force, remoteURL, fmt.Sprintf("%s:%s", cmtHash, ref))
 		cmd.Dir = repoGitDir
 
 		if out, err = run(cmd, "pushing ref"); err != nil {
@@ -264,6 +278,23 @@ func (s *Server) createCommitFromPatch(ctx context.Context, req protocol.CreateC
 	return http.StatusOK, resp
 }
 
+// commitArgs returns the arguments of the git command committing a patch
+// with the given message and commit info.
+func commitArgs(message string, info protocol.PatchCommitInfo, signingKey string) []string {
+	args := []string{"commit", "-m", message}
+	if info.SignOff {
+		args = append(args, "--signoff")
+	}
+	if info.Sign {
+		args = append(args, "--gpg-sign="+signingKey)
+	} else {
+		// Don't sign commits because of a commit.gpgSign setting in the
+		// global git config.
+		args = append(args, "--no-gpg-sign")
+	}
+	return args
+}
+
 func cleanUpTmpRepo(path string) {
 	err := os.RemoveAll(path)
 	if err != nil {
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+)
+
+func TestCommitArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		info protocol.PatchCommitInfo
+		want []string
+	}{
+		{
+			name: "default",
+			want: []string{"commit", "-m", "msg", "--no-gpg-sign"},
+		},
+		{
+			name: "sign off",
+			info: protocol.PatchCommitInfo{SignOff: true},
+			want: []string{"commit", "-m", "msg", "--signoff", "--no-gpg-sign"},
+		},
+		{
+			name: "sign",
+			info: protocol.PatchCommitInfo{Sign: true},
+			want: []string{"commit", "-m", "msg", "--gpg-sign=ABCDEF"},
+		},
+		{
+			name: "sign off and sign",
+			info: protocol.PatchCommitInfo{SignOff: true, Sign: true},
+			want: []string{"commit", "-m", "msg", "--signoff", "--gpg-sign=ABCDEF"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			have := commitArgs("msg", test.info, "ABCDEF")
+			if diff := cmp.Diff(test.want, have); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestCreateCommitFromPatch_Sign(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is not installed")
+	}
+
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+
+	// Use a throwaway keyring which is only reachable through GNUPGHOME, so
+	// the test fails if git commit doesn't run with gitserver's environment.
+	gnupgHome := filepath.Join(root, "gnupg")
+	if err := os.Mkdir(gnupgHome, 0700); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("GNUPGHOME", os.Getenv("GNUPGHOME"))
+	os.Setenv("GNUPGHOME", gnupgHome)
+	defer exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+
+	run := func(dir, name string, arg ...string) string {
+		t.Helper()
+		c := exec.Command(name, arg...)
+		c.Dir = dir
+		c.Env = append(os.Environ(),
+			"GIT_COMMITTER_NAME=a",
+			"GIT_COMMITTER_EMAIL=a@a.com",
+			"GIT_AUTHOR_NAME=a",
+			"GIT_AUTHOR_EMAIL=a@a.com",
+		)
+		b, err := c.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s %s failed: %s: %s", name, strings.Join(arg, " "), err, b)
+		}
+		return strings.TrimSpace(string(b))
+	}
+
+	run(root, "gpg", "--batch", "--passphrase", "", "--quick-gen-key", "test <test@example.com>", "default", "default", "never")
+	var key string
+	for _, line := range strings.Split(run(root, "gpg", "--list-secret-keys", "--with-colons"), "\n") {
+		if fields := strings.Split(line, ":"); fields[0] == "fpr" {
+			key = fields[9]
+			break
+		}
+	}
+	if key == "" {
+		t.Fatal("no fingerprint for the generated key")
+	}
+
+	remote, _, head := makeBundledRepo(t, root)
+	reposDir := filepath.Join(root, "repos")
+	run(root, "git", "clone", "--bare", remote, filepath.Join(reposDir, "example.com/foo/bar/.git"))
+
+	s := &Server{ReposDir: reposDir, CommitSigningKey: key}
+	status, resp := s.createCommitFromPatch(context.Background(), protocol.CreateCommitFromPatchRequest{
+		Repo:       "example.com/foo/bar",
+		BaseCommit: api.CommitID(head),
+		TargetRef:  "refs/heads/signed",
+		Patch: `diff --git a/README.md b/README.md
+new file mode 100644
+--- /dev/null
++++ b/README.md
+@@ -0,0 +1 @@
++signed
+`,
+		CommitInfo: protocol.PatchCommitInfo{Message: "signed", Date: time.Now(), Sign: true},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status %d: %+v", status, resp.Error)
+	}
+
+	commit := run(filepath.Join(reposDir, "example.com/foo/bar/.git"), "git", "cat-file", "commit", "refs/heads/signed")
+	if !strings.Contains(commit, "gpgsig -----BEGIN PGP SIGNATURE-----") {
+		t.Fatalf("commit is not signed:\n%s", commit)
+	}
+}
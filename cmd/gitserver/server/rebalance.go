@@ -0,0 +1,601 @@
+package server
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+)
+
+// Repositories are assigned to gitservers by hashing their names (see
+// gitserver.AddrsForKey), so when the set of gitserver addresses changes some
+// of them move to another gitserver: a small share with rendezvous hashing,
+// most of them with the default modulo hashing. Rather than cloning a moved
+// repository from the code host again, its new gitserver copies the
+// repository directory from the gitserver which still has it. Until the copy
+// is complete, the new gitserver forwards reads of the repository to its
+// peer. The old gitserver removes its copy once all of the repository's new
+// gitservers have it.
+
+// proxiedHeader is set on requests forwarded to a peer, so that the peer
+// never forwards them again.
+const proxiedHeader = "X-Sourcegraph-Gitserver-Proxied"
+
+// peerHTTPClient is used to talk to other gitservers.
+var peerHTTPClient = &http.Client{}
+
+const (
+	// migrationMissTTL is how long migrationSource remembers that no peer
+	// has a repository before asking the peers again.
+	migrationMissTTL = time.Minute
+
+	// migrationProbeTimeout bounds how long migrationSource waits for the
+	// peers to report whether they have a repository.
+	migrationProbeTimeout = 5 * time.Second
+)
+
+// RebalanceRepos copies the repositories this gitserver is responsible for
+// but doesn't have from the peers which have them, and removes the
+// repositories this gitserver is no longer responsible for once they have
+// been copied.
+//
+// It does nothing unless GitServerAddrs is set and one of its addresses
+// belongs to this gitserver. Repositories on a gitserver which was removed
+// from GitServerAddrs are not moved, since its peers no longer know about it.
+func (s *Server) RebalanceRepos() {
+	if s.GitServerAddrs == nil {
+		return
+	}
+	addrs := s.GitServerAddrs()
+	self := selfAddr(s.Hostname, addrs)
+	if self == "" {
+		log15.Debug("gitserver: not rebalancing repositories, this gitserver is not in the list of gitserver addresses", "hostname", s.Hostname)
+		return
+	}
+	ctx, cancel := s.serverContext()
+	defer cancel()
+
+	s.copyReposFromPeers(ctx, self, addrs)
+	s.removeMovedRepos(ctx, self, addrs)
+}
+
+// owners returns the addresses of the gitservers in addrs which repo belongs
+// on, primary first.
+func (s *Server) owners(addrs []string, repo api.RepoName) []string {
+	replicas := 1
+	if s.GitServerReplicas != nil {
+		replicas = s.GitServerReplicas()
+	}
+	hashing := gitserver.ModuloHashing
+	if s.GitServerHashing != nil {
+		hashing = gitserver.Hashing(s.GitServerHashing())
+	}
+	return gitserver.AddrsForKey(addrs, string(repo), replicas, hashing)
+}
+
+// isOwner reports whether repo belongs on self.
+func (s *Server) isOwner(self string, addrs []string, repo api.RepoName) bool {
+	for _, addr := range s.owners(addrs, repo) {
+		if addr == self {
+			return true
+		}
+	}
+	return false
+}
+
+// copyReposFromPeers copies the repositories which belong on self from the
+// peers which have them.
+func (s *Server) copyReposFromPeers(ctx context.Context, self string, addrs []string) {
+	sources := map[api.RepoName]string{}
+	for _, peer := range addrs {
+		if peer == self {
+			continue
+		}
+		repos, err := listPeer(ctx, peer)
+		if err != nil {
+			log15.Warn("gitserver: failed to list repositories of peer", "peer", peer, "error", err)
+			continue
+		}
+		for _, name := range repos {
+			repo := protocol.NormalizeRepo(api.RepoName(name))
+			if _, ok := sources[repo]; ok || !s.isOwner(self, addrs, repo) || repoCloned(s.dir(repo)) {
+				continue
+			}
+			sources[repo] = peer
+		}
+	}
+
+	s.migrationSourcesMu.Lock()
+	s.migrationSources = sources
+	s.migrationMisses = nil
+	s.migrationSourcesMu.Unlock()
+
+	type copyJob struct {
+		repo api.RepoName
+		peer string
+	}
+	jobs := make(chan copyJob, len(sources))
+	for repo, peer := range sources {
+		jobs <- copyJob{repo: repo, peer: peer}
+	}
+	close(jobs)
+
+	// Copies count against the clone limiter like clones do. Only start as
+	// many workers as it allows, so that thousands of moved repositories
+	// don't wait on it in as many goroutines with open connections.
+	workers, _ := s.queryCloneLimiter()
+	if workers < 1 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				if err := s.copyFromPeer(ctx, job.repo, job.peer); err != nil {
+					log15.Error("gitserver: failed to copy repository from peer", "repo", job.repo, "peer", job.peer, "error", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// removeMovedRepos removes the repositories which no longer belong on self
+// once every gitserver they belong on has them.
+func (s *Server) removeMovedRepos(ctx context.Context, self string, addrs []string) {
+	dirs, err := s.findGitDirs()
+	if err != nil {
+		log15.Error("gitserver: failed to find repositories to rebalance", "error", err)
+		return
+	}
+
+	for _, dir := range dirs {
+		if ctx.Err() != nil {
+			return
+		}
+		repo := s.name(dir)
+		owners := s.owners(addrs, repo)
+		if s.isOwner(self, addrs, repo) || !peersHaveRepo(ctx, owners, repo) {
+			continue
+		}
+
+		lock, ok := s.locker.TryAcquire(dir, "removing moved repository")
+		if !ok {
+			continue
+		}
+		err := s.removeRepoDirectory(dir)
+		lock.Release()
+		if err != nil {
+			log15.Error("gitserver: failed to remove moved repository", "repo", repo, "error", err)
+			continue
+		}
+		log15.Info("gitserver: removed repository which moved to peers", "repo", repo, "peers", owners)
+		repoMovedAwayCounter.Inc()
+	}
+}
+
+// migrationSource returns the peer to copy repo from if it moved to this
+// gitserver, or "" if it did not move here. It must only be called for
+// repositories which are not cloned.
+//
+// Repositories found by the last rebalance are looked up in
+// migrationSources. Others may have moved here since, so the peers are asked
+// concurrently whether they have them, rather than cloning them from the code
+// host again until the next rebalance. If none of them has the repository,
+// they are not asked again for migrationMissTTL.
+func (s *Server) migrationSource(ctx context.Context, repo api.RepoName) string {
+	repo = protocol.NormalizeRepo(repo)
+	s.migrationSourcesMu.Lock()
+	peer, ok := s.migrationSources[repo]
+	missed := time.Now().Before(s.migrationMisses[repo])
+	s.migrationSourcesMu.Unlock()
+	if ok {
+		return peer
+	}
+	if missed {
+		return ""
+	}
+
+	if s.GitServerAddrs == nil {
+		return ""
+	}
+	addrs := s.GitServerAddrs()
+	self := selfAddr(s.Hostname, addrs)
+	if self == "" || !s.isOwner(self, addrs, repo) {
+		return ""
+	}
+
+	peer = probePeers(ctx, self, addrs, repo)
+
+	s.migrationSourcesMu.Lock()
+	defer s.migrationSourcesMu.Unlock()
+	if peer == "" {
+		if s.migrationMisses == nil {
+			s.migrationMisses = map[api.RepoName]time.Time{}
+		}
+		now := time.Now()
+		for r, expiry := range s.migrationMisses {
+			if now.After(expiry) {
+				delete(s.migrationMisses, r)
+			}
+		}
+		s.migrationMisses[repo] = now.Add(migrationMissTTL)
+		return ""
+	}
+	if s.migrationSources == nil {
+		s.migrationSources = map[api.RepoName]string{}
+	}
+	s.migrationSources[repo] = peer
+	return peer
+}
+
+// probePeers asks all addrs but self concurrently whether they have repo,
+// and returns the first which does, or "" if none of them reports having it
+// within migrationProbeTimeout.
+func probePeers(ctx context.Context, self string, addrs []string, repo api.RepoName) string {
+	ctx, cancel := context.WithTimeout(ctx, migrationProbeTimeout)
+	defer cancel()
+
+	found := make(chan string, len(addrs))
+	var wg sync.WaitGroup
+	for _, peer := range addrs {
+		if peer == self {
+			continue
+		}
+		wg.Add(1)
+		go func(peer string) {
+			defer wg.Done()
+			if peersHaveRepo(ctx, []string{peer}, repo) {
+				found <- peer
+			}
+		}(peer)
+	}
+	go func() {
+		wg.Wait()
+		close(found)
+	}()
+	return <-found
+}
+
+// migrationSourceForClone returns the peer to copy repo from instead of
+// cloning it with opts, or "" if it should be cloned. Clones which overwrite
+// the repository or are seeded from a bundle are never replaced by a copy.
+func (s *Server) migrationSourceForClone(ctx context.Context, repo api.RepoName, opts *cloneOptions) string {
+	if opts != nil && (opts.Overwrite || opts.Bundle != "") {
+		return ""
+	}
+	return s.migrationSource(ctx, repo)
+}
+
+// copyFromPeer copies repo from the gitserver at peer. Like a clone, the copy
+// is written to a temporary directory and only moved into place once it is
+// complete.
+//
+// Once the copy is done or failed, repo is no longer considered to be copied
+// from peer, so a failed copy is retried by the next rebalance, or the
+// repository is cloned from the code host if no peer has it anymore.
+func (s *Server) copyFromPeer(ctx context.Context, repo api.RepoName, peer string) error {
+	dir := s.dir(repo)
+	lock, ok := s.locker.TryAcquire(dir, "copying from "+peer)
+	if !ok {
+		// Someone else is already cloning or copying the repository.
+		return nil
+	}
+	defer lock.Release()
+	defer func() {
+		s.migrationSourcesMu.Lock()
+		delete(s.migrationSources, protocol.NormalizeRepo(repo))
+		s.migrationSourcesMu.Unlock()
+	}()
+
+	if repoCloned(dir) {
+		return nil
+	}
+
+	ctx, cancel, err := s.acquireCloneLimiter(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	tmpPath, err := s.tempDir("copy-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpPath)
+	tmpPath = filepath.Join(tmpPath, ".git")
+
+	req, err := http.NewRequest("GET", "http://"+peer+"/repo-dir?repo="+url.QueryEscape(string(repo)), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := peerHTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("copying %s from %s: http status %d", repo, peer, resp.StatusCode)
+	}
+
+	if err := extractTar(resp.Body, tmpPath); err != nil {
+		return errors.Wrapf(err, "copying %s from %s", repo, peer)
+	}
+	if !repoCloned(GitDir(tmpPath)) {
+		return errors.Errorf("copying %s from %s: copy is not a git repository", repo, peer)
+	}
+
+	dstPath := string(dir)
+	if err := os.MkdirAll(filepath.Dir(dstPath), os.ModePerm); err != nil {
+		return err
+	}
+	if err := renameAndSync(tmpPath, dstPath); err != nil {
+		return err
+	}
+
+	log15.Info("repo copied from peer", "repo", repo, "peer", peer)
+	repoCopiedCounter.Inc()
+	return nil
+}
+
+// handleRepoDir writes a tar archive of the git directory of a repository.
+// It is used by peers to copy repositories which moved to them.
+func (s *Server) handleRepoDir(w http.ResponseWriter, r *http.Request) {
+	repo := protocol.NormalizeRepo(api.RepoName(r.URL.Query().Get("repo")))
+	if repo == "" {
+		http.Error(w, "no repo", http.StatusBadRequest)
+		return
+	}
+	dir := s.dir(repo)
+	if !repoCloned(dir) {
+		http.Error(w, "repository not found", http.StatusNotFound)
+		return
+	}
+
+	// Hold the update lock so that a concurrent fetch doesn't change refs
+	// while we copy them.
+	s.repoUpdateLocksMu.Lock()
+	mu := s.repoUpdateLocksLocked(repo).mu
+	s.repoUpdateLocksMu.Unlock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/x-tar")
+	if err := writeDirTar(w, string(dir)); err != nil {
+		log15.Error("gitserver: failed to write repository directory", "repo", repo, "error", err)
+		// We have already written part of the archive. Abort the response
+		// so that the peer sees a truncated body rather than a short
+		// archive it might mistake for a complete one.
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// proxyToPeer forwards an exec or archive request for a repository which is
+// being copied to this gitserver to the peer it is copied from.
+func (s *Server) proxyToPeer(w http.ResponseWriter, r *http.Request, peer string, req *protocol.ExecRequest) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	proxy := &httputil.ReverseProxy{
+		Director: func(pr *http.Request) {
+			pr.URL.Scheme = "http"
+			pr.URL.Host = peer
+			pr.Header.Set(proxiedHeader, "1")
+			if pr.Method == "POST" {
+				// We already consumed the body of exec requests.
+				pr.Body = ioutil.NopCloser(bytes.NewReader(body))
+				pr.ContentLength = int64(len(body))
+			}
+		},
+		// Flush immediately, like we do when running commands locally.
+		FlushInterval: -1,
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// writeDirTar writes the files and directories under root as a tar archive.
+func writeDirTar(w io.Writer, root string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				// Removed by a concurrent cleanup, e.g. a stale lock file.
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." || !(fi.IsDir() || fi.Mode().IsRegular()) {
+			// Git directories only consist of directories and regular
+			// files.
+			return nil
+		}
+
+		hdr, err := tar.FileInfoHeader(fi, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if fi.IsDir() {
+			hdr.Name += "/"
+			return tw.WriteHeader(hdr)
+		}
+
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err = io.CopyN(tw, f, hdr.Size)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// extractTar extracts a tar archive written by writeDirTar to dst.
+func extractTar(r io.Reader, dst string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// 🚨 SECURITY: Never write outside of dst.
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return errors.Errorf("invalid path in archive: %q", hdr.Name)
+		}
+		path := filepath.Join(dst, name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, os.ModePerm); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		default:
+			return errors.Errorf("unsupported file type %q in archive: %q", hdr.Typeflag, hdr.Name)
+		}
+	}
+}
+
+// listPeer returns the repositories cloned on the gitserver at peer.
+func listPeer(ctx context.Context, peer string) ([]string, error) {
+	req, err := http.NewRequest("GET", "http://"+peer+"/list?cloned", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := peerHTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("listing repositories of %s: http status %d", peer, resp.StatusCode)
+	}
+	var repos []string
+	err = json.NewDecoder(resp.Body).Decode(&repos)
+	return repos, err
+}
+
+// peersHaveRepo reports whether repo is cloned on every gitserver in peers.
+func peersHaveRepo(ctx context.Context, peers []string, repo api.RepoName) bool {
+	body, err := json.Marshal(&protocol.IsRepoClonedRequest{Repo: repo})
+	if err != nil {
+		return false
+	}
+	for _, peer := range peers {
+		req, err := http.NewRequest("POST", "http://"+peer+"/is-repo-cloned", bytes.NewReader(body))
+		if err != nil {
+			return false
+		}
+		resp, err := peerHTTPClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return false
+		}
+	}
+	return true
+}
+
+// selfAddr returns the entry of addrs which refers to this gitserver. That is
+// either hostname itself, or an address whose host is hostname or a DNS name
+// below it (e.g. "gitserver-1.gitserver:3178" for "gitserver-1").
+func selfAddr(hostname string, addrs []string) string {
+	if hostname == "" {
+		return ""
+	}
+	for _, addr := range addrs {
+		if addr == hostname {
+			return addr
+		}
+	}
+	for _, addr := range addrs {
+		host := addr
+		if h, _, err := net.SplitHostPort(addr); err == nil {
+			host = h
+		}
+		if host == hostname || strings.HasPrefix(host, hostname+".") {
+			return addr
+		}
+	}
+	return ""
+}
+
+var (
+	repoCopiedCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "gitserver",
+		Name:      "repo_copied_from_peer",
+		Help:      "number of repos copied from another gitserver after the set of gitservers changed",
+	})
+	repoMovedAwayCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "gitserver",
+		Name:      "repo_moved_to_peer",
+		Help:      "number of repos removed after they were copied to the gitservers now responsible for them",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(repoCopiedCounter)
+	prometheus.MustRegister(repoMovedAwayCounter)
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+)
+
+func TestWriteDirTar_ExtractTar(t *testing.T) {
+	src, cleanup1 := tmpDir(t)
+	defer cleanup1()
+	dst, cleanup2 := tmpDir(t)
+	defer cleanup2()
+
+	mkFiles(t, src, "HEAD", "refs/heads/master", "objects/pack/pack-1.pack")
+	writeFile(t, filepath.Join(src, "config"), []byte("[core]\n\tbare = true\n"))
+	if err := os.MkdirAll(filepath.Join(src, "refs", "tags"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeDirTar(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	if err := extractTar(&buf, filepath.Join(dst, ".git")); err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, dst,
+		".git/HEAD",
+		".git/config",
+		".git/refs/heads/master",
+		".git/refs/tags",
+		".git/objects/pack/pack-1.pack",
+	)
+	if b, err := ioutil.ReadFile(filepath.Join(dst, ".git", "config")); err != nil || string(b) != "[core]\n\tbare = true\n" {
+		t.Errorf("unexpected config contents %q (err: %v)", b, err)
+	}
+}
+
+func TestExtractTar_InvalidPath(t *testing.T) {
+	dst, cleanup := tmpDir(t)
+	defer cleanup()
+
+	for _, name := range []string{"../escape", "/abs", "a/../../escape"} {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := extractTar(&buf, dst); err == nil {
+			t.Errorf("expected error extracting %q", name)
+		}
+	}
+}
+
+func TestSelfAddr(t *testing.T) {
+	addrs := []string{"gitserver-0.gitserver:3178", "gitserver-1.gitserver:3178", "127.0.0.1:3178"}
+	tests := map[string]string{
+		"":                           "",
+		"gitserver-1":                "gitserver-1.gitserver:3178",
+		"gitserver-1.gitserver:3178": "gitserver-1.gitserver:3178",
+		"gitserver":                  "",
+		"gitserver-2":                "",
+		"127.0.0.1":                  "127.0.0.1:3178",
+	}
+	for hostname, want := range tests {
+		if got := selfAddr(hostname, addrs); got != want {
+			t.Errorf("selfAddr(%q) = %q, want %q", hostname, got, want)
+		}
+	}
+}
+
+func TestRebalanceRepos(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+
+	var (
+		servers []*Server
+		addrs   []string
+	)
+	for i := 0; i < 2; i++ {
+		s := &Server{ReposDir: filepath.Join(root, fmt.Sprintf("repos-%d", i))}
+		srv := httptest.NewServer(s.Handler())
+		defer srv.Close()
+		defer s.Stop()
+
+		u, _ := url.Parse(srv.URL)
+		s.Hostname = u.Host
+		s.GitServerAddrs = func() []string { return addrs }
+		servers = append(servers, s)
+		addrs = append(addrs, u.Host)
+	}
+	oldOwner, newOwner := servers[0], servers[1]
+
+	// Find a repository which belongs on the new owner, and put it on the
+	// old one as if it was cloned before the new owner was added.
+	var repo api.RepoName
+	for i := 0; repo == ""; i++ {
+		name := fmt.Sprintf("example.com/repo-%d", i)
+		if gitserver.AddrsForKey(addrs, name, 1, gitserver.ModuloHashing)[0] == newOwner.Hostname {
+			repo = api.RepoName(name)
+		}
+	}
+	dir := oldOwner.dir(repo)
+	if err := os.MkdirAll(string(dir), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "init", "--bare", string(dir)).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %s: %s", err, out)
+	}
+
+	// Before the new owner rebalances, it finds the old owner on demand.
+	if src := newOwner.migrationSource(context.Background(), repo); src != oldOwner.Hostname {
+		t.Errorf("got migration source %q, want %q", src, oldOwner.Hostname)
+	}
+
+	// The old owner must keep the repository until the new owner has it.
+	oldOwner.RebalanceRepos()
+	if !repoCloned(oldOwner.dir(repo)) {
+		t.Fatal("repository removed before it was copied")
+	}
+
+	newOwner.RebalanceRepos()
+	if !repoCloned(newOwner.dir(repo)) {
+		t.Fatal("repository was not copied to its new owner")
+	}
+	if src, ok := newOwner.migrationSources[repo]; ok {
+		t.Errorf("repository is still being copied from %q", src)
+	}
+	if !peersHaveRepo(context.Background(), []string{newOwner.Hostname}, repo) {
+		t.Error("new owner does not report the repository as cloned")
+	}
+
+	oldOwner.RebalanceRepos()
+	if repoCloned(oldOwner.dir(repo)) {
+		t.Error("repository was not removed from its old owner")
+	}
+}
+
+func TestMigrationSource_CachesMisses(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+
+	var (
+		servers []*Server
+		addrs   []string
+	)
+	for i := 0; i < 3; i++ {
+		s := &Server{ReposDir: filepath.Join(root, fmt.Sprintf("repos-%d", i))}
+		srv := httptest.NewServer(s.Handler())
+		defer srv.Close()
+		defer s.Stop()
+
+		u, _ := url.Parse(srv.URL)
+		s.Hostname = u.Host
+		s.GitServerAddrs = func() []string { return addrs }
+		servers = append(servers, s)
+		addrs = append(addrs, u.Host)
+	}
+	owner := servers[0]
+
+	var repo api.RepoName
+	for i := 0; repo == ""; i++ {
+		name := fmt.Sprintf("example.com/repo-%d", i)
+		if gitserver.AddrsForKey(addrs, name, 1, gitserver.ModuloHashing)[0] == owner.Hostname {
+			repo = api.RepoName(name)
+		}
+	}
+
+	if src := owner.migrationSource(context.Background(), repo); src != "" {
+		t.Fatalf("got migration source %q, want none", src)
+	}
+
+	// The peers are not asked again until the miss expires, even if one of
+	// them has the repository by now.
+	peer := servers[2]
+	dir := peer.dir(repo)
+	if err := os.MkdirAll(string(dir), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "init", "--bare", string(dir)).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %s: %s", err, out)
+	}
+	if src := owner.migrationSource(context.Background(), repo); src != "" {
+		t.Errorf("got migration source %q before the miss expired, want none", src)
+	}
+
+	owner.migrationSourcesMu.Lock()
+	owner.migrationMisses[repo] = time.Now().Add(-time.Second)
+	owner.migrationSourcesMu.Unlock()
+	if src := owner.migrationSource(context.Background(), repo); src != peer.Hostname {
+		t.Errorf("got migration source %q, want %q", src, peer.Hostname)
+	}
+}
@@ -116,6 +116,34 @@ type Server struct {
 	// DiskSizer tells how much disk is free and how large the disk is.
 	DiskSizer DiskSizer
 
+	// BundlesDir is an optional directory of git bundles to seed clones
+	// from, e.g. restored from a backup. The bundle of the repository
+	// github.com/foo/bar is ${BundlesDir}/github.com/foo/bar.bundle.
+	BundlesDir string
+
+	// Hostname is the hostname of this gitserver, or its address in
+	// GitServerAddrs. It is used to tell which repositories this gitserver
+	// is responsible for.
+	Hostname string
+
+	// CommitSigningKey is the ID of the GPG key used to sign commits created
+	// from patches that request signing. The key must be in the GPG keyring
+	// of gitserver. If it is empty, such requests fail.
+	CommitSigningKey string
+
+	// GitServerAddrs returns the addresses of all gitservers, including this
+	// one. If it is nil, repositories are never moved between gitservers.
+	GitServerAddrs func() []string
+
+	// GitServerReplicas returns the number of gitservers each repository is
+	// cloned onto.
+	GitServerReplicas func() int
+
+	// GitServerHashing returns the scheme used to assign repositories to the
+	// gitservers in GitServerAddrs (see gitserver.Hashing). If it is nil,
+	// gitserver.ModuloHashing is used.
+	GitServerHashing func() string
+
 	// skipCloneForTests is set by tests to avoid clones.
 	skipCloneForTests bool
 
@@ -138,6 +166,17 @@ type Server struct {
 
 	repoUpdateLocksMu sync.Mutex // protects the map below and also updates to locks.once
 	repoUpdateLocks   map[api.RepoName]*locks
+
+	// history remembers recent clones and fetches of each repository.
+	history repoHistory
+
+	migrationSourcesMu sync.Mutex // protects the maps below
+	// migrationSources maps repositories which moved to this gitserver to
+	// the peer they are being copied from.
+	migrationSources map[api.RepoName]string
+	// migrationMisses maps repositories which no peer had when asked to
+	// the time until which the peers are not asked again.
+	migrationMisses map[api.RepoName]time.Time
 }
 
 type locks struct {
@@ -227,6 +266,7 @@ func (s *Server) Handler() http.Handler {
 	mux.HandleFunc("/exec", s.handleExec)
 	mux.HandleFunc("/list", s.handleList)
 	mux.HandleFunc("/list-gitolite", s.handleListGitolite)
+	mux.HandleFunc("/list-gitolite-perms", s.handleListGitolitePerms)
 	mux.HandleFunc("/is-repo-cloneable", s.handleIsRepoCloneable)
 	mux.HandleFunc("/is-repo-cloned", s.handleIsRepoCloned)
 	mux.HandleFunc("/repos", s.handleRepoInfo)
@@ -234,6 +274,9 @@ func (s *Server) Handler() http.Handler {
 	mux.HandleFunc("/repo-update", s.handleRepoUpdate)
 	mux.HandleFunc("/getGitolitePhabricatorMetadata", s.handleGetGitolitePhabricatorMetadata)
 	mux.HandleFunc("/create-commit-from-patch", s.handleCreateCommitFromPatch)
+	mux.HandleFunc("/repo-dir", s.handleRepoDir)
+	mux.HandleFunc("/seed-bundle", s.handleSeedBundle)
+	mux.HandleFunc("/repo-history", s.handleRepoHistory)
 	mux.HandleFunc("/ping", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
@@ -608,6 +651,16 @@ func (s *Server) exec(w http.ResponseWriter, r *http.Request, req *protocol.Exec
 	}
 
 	dir := s.dir(req.Repo)
+	if !repoCloned(dir) && r.Header.Get(proxiedHeader) == "" {
+		if peer := s.migrationSource(r.Context(), req.Repo); peer != "" {
+			// The repository moved to us and is still being copied from
+			// peer, which can serve the request in the meantime.
+			status = "proxied-to-peer"
+			s.proxyToPeer(w, r, peer, req)
+			return
+		}
+	}
+
 	cloneProgress, cloneInProgress := s.locker.Status(dir)
 	if cloneInProgress {
 		status = "clone-in-progress"
@@ -724,6 +777,11 @@ type cloneOptions struct {
 
 	// Overwrite will overwrite the existing clone.
 	Overwrite bool
+
+	// Bundle is the path of a git bundle to seed the clone from. If it is
+	// empty, a bundle of the repository in Server.BundlesDir is used if
+	// there is one.
+	Bundle string
 }
 
 // cloneRepo issues a git clone command for the given repo. It is
@@ -732,6 +790,26 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 	if strings.ToLower(string(repo)) == "github.com/sourcegraphtest/alwayscloningtest" {
 		return "This will never finish cloning", nil
 	}
+
+	if peer := s.migrationSourceForClone(ctx, repo, opts); peer != "" {
+		// The repository moved to us from peer. Copy it from there rather
+		// than cloning it from the code host again.
+		if opts != nil && opts.Block {
+			if err := s.copyFromPeer(ctx, repo, peer); err != nil {
+				return "", errors.Wrapf(err, "failed to clone %s", repo)
+			}
+			return "", nil
+		}
+		go func() {
+			ctx, cancel := s.serverContext()
+			defer cancel()
+			if err := s.copyFromPeer(ctx, repo, peer); err != nil {
+				log15.Error("failed to copy repo from peer", "repo", repo, "peer", peer, "error", err)
+			}
+		}()
+		return "", nil
+	}
+
 	redactor := newURLRedactor(url)
 
 	dir := s.dir(repo)
@@ -807,8 +885,12 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 		tmpPath = filepath.Join(tmpPath, ".git")
 		tmp := GitDir(tmpPath)
 
+		bundle := s.bundlePath(repo, opts)
+
 		var cmd *exec.Cmd
-		if useRefspecOverrides() {
+		if bundle != "" {
+			cmd = exec.CommandContext(ctx, "git", "clone", "--mirror", "--progress", bundle, tmpPath)
+		} else if useRefspecOverrides() {
 			cmd, err = refspecOverridesCloneCmd(ctx, url, tmpPath)
 			if err != nil {
 				return err
@@ -818,16 +900,28 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 		}
 		// see issue #7322: skip LFS content in repositories with Git LFS configured
 		cmd.Env = append(cmd.Env, "GIT_LFS_SKIP_SMUDGE=1")
-		log15.Info("cloning repo", "repo", repo, "tmp", tmpPath, "dst", dstPath)
+		log15.Info("cloning repo", "repo", repo, "tmp", tmpPath, "dst", dstPath, "bundle", bundle)
 
 		pr, pw := io.Pipe()
 		defer pw.Close()
 		go readCloneProgress(redactor, lock, pr)
 
-		if output, err := runWithRemoteOpts(ctx, cmd, pw); err != nil {
+		op := s.startOperation(repo, "clone", tmp)
+		output, err := runWithRemoteOpts(ctx, cmd, pw)
+		op.finish(cmd, output, redactor)
+		if err != nil {
 			return errors.Wrapf(err, "clone failed. Output: %s", string(output))
 		}
 
+		if bundle != "" {
+			// The bundle only brings us up to the time it was created.
+			// Fetch everything since then from the code host.
+			if err := s.fetchAfterSeeding(ctx, repo, tmp, url, pw); err != nil {
+				return err
+			}
+			repoSeededCounter.Inc()
+		}
+
 		removeBadRefs(ctx, tmp)
 
 		// Update the last-changed stamp.
@@ -1054,14 +1148,7 @@ func (s *Server) doRepoUpdate(ctx context.Context, repo api.RepoName, url string
 	defer span.Finish()
 
 	s.repoUpdateLocksMu.Lock()
-	l, ok := s.repoUpdateLocks[repo]
-	if !ok {
-		l = &locks{
-			once: new(sync.Once),
-			mu:   new(sync.Mutex),
-		}
-		s.repoUpdateLocks[repo] = l
-	}
+	l := s.repoUpdateLocksLocked(repo)
 	once := l.once
 	mu := l.mu
 	s.repoUpdateLocksMu.Unlock()
@@ -1094,6 +1181,20 @@ func (s *Server) doRepoUpdate(ctx context.Context, repo api.RepoName, url string
 	}
 }
 
+// repoUpdateLocksLocked returns the update locks of repo. The caller must
+// hold s.repoUpdateLocksMu.
+func (s *Server) repoUpdateLocksLocked(repo api.RepoName) *locks {
+	l, ok := s.repoUpdateLocks[repo]
+	if !ok {
+		l = &locks{
+			once: new(sync.Once),
+			mu:   new(sync.Mutex),
+		}
+		s.repoUpdateLocks[repo] = l
+	}
+	return l
+}
+
 var (
 	badRefsOnce sync.Once
 	badRefs     []string
@@ -1302,16 +1403,7 @@ func (s *Server) doRepoUpdate2(repo api.RepoName, url string) error {
 		}
 	}
 
-	configRemoteOpts := true
-	var cmd *exec.Cmd
-	if customCmd := customFetchCmd(ctx, url); customCmd != nil {
-		cmd = customCmd
-		configRemoteOpts = false
-	} else if useRefspecOverrides() {
-		cmd = refspecOverridesFetchCmd(ctx, url)
-	} else {
-		cmd = exec.CommandContext(ctx, "git", "fetch", "--prune", url, "+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*", "+refs/pull/*:refs/pull/*", "+refs/sourcegraph/*:refs/sourcegraph/*")
-	}
+	cmd, configRemoteOpts := fetchCmd(ctx, url)
 	cmd.Dir = string(dir)
 
 	// drop temporary pack files after a fetch. this function won't
@@ -1320,7 +1412,10 @@ func (s *Server) doRepoUpdate2(repo api.RepoName, url string) error {
 	// when the cleanup happens, just that it does.
 	defer s.cleanTmpFiles(dir)
 
-	if output, err := runWith(ctx, cmd, configRemoteOpts, nil); err != nil {
+	op := s.startOperation(repo, "fetch", dir)
+	output, err := runWith(ctx, cmd, configRemoteOpts, nil)
+	op.finish(cmd, output, newURLRedactor(url))
+	if err != nil {
 		log15.Error("Failed to update", "repo", repo, "error", err, "output", string(output))
 		return errors.Wrap(err, "failed to update")
 	}
@@ -1337,7 +1432,7 @@ func (s *Server) doRepoUpdate2(repo api.RepoName, url string) error {
 	// try to fetch HEAD from origin
 	cmd = exec.CommandContext(ctx, "git", "remote", "show", url)
 	cmd.Dir = path.Join(s.ReposDir, string(repo))
-	output, err := runWithRemoteOpts(ctx, cmd, nil)
+	output, err = runWithRemoteOpts(ctx, cmd, nil)
 	if err != nil {
 		log15.Error("Failed to fetch remote info", "repo", repo, "error", err, "output", string(output))
 		return errors.Wrap(err, "failed to fetch remote info")
@@ -1379,6 +1474,18 @@ func (s *Server) doRepoUpdate2(repo api.RepoName, url string) error {
 	return nil
 }
 
+// fetchCmd returns the command which updates a repository from url, and
+// whether the remote options should be configured for it.
+func fetchCmd(ctx context.Context, url string) (cmd *exec.Cmd, configRemoteOpts bool) {
+	if customCmd := customFetchCmd(ctx, url); customCmd != nil {
+		return customCmd, false
+	}
+	if useRefspecOverrides() {
+		return refspecOverridesFetchCmd(ctx, url), true
+	}
+	return exec.CommandContext(ctx, "git", "fetch", "--prune", url, "+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*", "+refs/pull/*:refs/pull/*", "+refs/sourcegraph/*:refs/sourcegraph/*"), true
+}
+
 func (s *Server) ensureRevision(ctx context.Context, repo api.RepoName, url, rev string, repoDir GitDir) (didUpdate bool) {
 	if rev == "" || rev == "HEAD" {
 		return false
@@ -44,6 +44,20 @@ type RewriteSpecification struct {
 	// A template pattern that expresses how matches should be rewritten.
 	RewriteTemplate string
 
+	// IsRegExp if true will treat MatchTemplate as a regular expression
+	// instead of a structural (comby) match template. RewriteTemplate may
+	// then refer to capture groups of MatchTemplate with $1 or ${name}.
+	IsRegExp bool
+
+	// IsLiteral if true will treat MatchTemplate as a fixed string instead of
+	// a structural (comby) match template. RewriteTemplate is inserted
+	// literally.
+	IsLiteral bool
+
+	// IsCaseSensitive if false will ignore case when matching MatchTemplate.
+	// It only applies when IsRegExp or IsLiteral is true.
+	IsCaseSensitive bool
+
 	// A file extension suffix filtering which files to process (e.g., ".go")
 	FileExtension string
 
@@ -0,0 +1,142 @@
+package replace
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// diffContext is the number of unchanged lines shown around each change in a
+// unified diff.
+const diffContext = 3
+
+type diffLine struct {
+	// kind is ' ' for unchanged lines, '-' for removed and '+' for added
+	// lines.
+	kind byte
+	text string
+}
+
+// unifiedDiff returns the unified diff between the contents a and b of the
+// file at path. It returns an empty string if a and b are equal.
+func unifiedDiff(path, a, b string) string {
+	dmp := diffmatchpatch.New()
+	ca, cb, lines := dmp.DiffLinesToChars(a, b)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(ca, cb, false), lines)
+
+	var dl []diffLine
+	for _, d := range diffs {
+		var kind byte
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			kind = ' '
+		case diffmatchpatch.DiffDelete:
+			kind = '-'
+		case diffmatchpatch.DiffInsert:
+			kind = '+'
+		}
+		for _, l := range splitLines(d.Text) {
+			dl = append(dl, diffLine{kind: kind, text: l})
+		}
+	}
+
+	var buf strings.Builder
+	oldLine, newLine := 0, 0 // number of lines before dl[i]
+	for i := 0; i < len(dl); {
+		// Skip to the next change.
+		j := i
+		for j < len(dl) && dl[j].kind == ' ' {
+			j++
+		}
+		if j == len(dl) {
+			break
+		}
+		start := j - diffContext
+		if start < i {
+			start = i
+		}
+		// Lines between the previous hunk and this one are unchanged.
+		oldLine += start - i
+		newLine += start - i
+
+		// Extend the hunk until there are more than 2*diffContext
+		// unchanged lines between two changes.
+		end := j
+		for end < len(dl) {
+			if dl[end].kind != ' ' {
+				end++
+				continue
+			}
+			k := end
+			for k < len(dl) && dl[k].kind == ' ' {
+				k++
+			}
+			if k == len(dl) || k-end > 2*diffContext {
+				end += diffContext
+				if end > k {
+					end = k
+				}
+				break
+			}
+			end = k
+		}
+
+		if buf.Len() == 0 {
+			fmt.Fprintf(&buf, "--- %s\n+++ %s\n", path, path)
+		}
+
+		hunk := dl[start:end]
+		oldCount, newCount := 0, 0
+		for _, l := range hunk {
+			if l.kind != '+' {
+				oldCount++
+			}
+			if l.kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+		for _, l := range hunk {
+			buf.WriteByte(l.kind)
+			buf.WriteString(l.text)
+			if !strings.HasSuffix(l.text, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		oldLine += oldCount
+		newLine += newCount
+		i = end
+	}
+	return buf.String()
+}
+
+// hunkRange formats the range of a hunk starting after line (0-based) with
+// count lines.
+func hunkRange(line, count int) string {
+	if count == 0 {
+		// An empty range refers to the line before the hunk.
+		return fmt.Sprintf("%d,0", line)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", line+1)
+	}
+	return fmt.Sprintf("%d,%d", line+1, count)
+}
+
+// splitLines splits s after each newline. The last line does not end with a
+// newline if s doesn't.
+func splitLines(s string) []string {
+	var lines []string
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			lines = append(lines, s)
+			break
+		}
+		lines = append(lines, s[:i+1])
+		s = s[i+1:]
+	}
+	return lines
+}
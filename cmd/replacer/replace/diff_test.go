@@ -0,0 +1,85 @@
+package replace
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	var numbers []string
+	for i := 1; i <= 20; i++ {
+		numbers = append(numbers, fmt.Sprintf("%d\n", i))
+	}
+	twoChanges := append([]string{}, numbers...)
+	twoChanges[1] = "two\n"
+	twoChanges[17] = "eighteen\n"
+
+	cases := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: "",
+		},
+		{
+			name: "two hunks",
+			a:    strings.Join(numbers, ""),
+			b:    strings.Join(twoChanges, ""),
+			want: `--- f
++++ f
+@@ -1,5 +1,5 @@
+ 1
+-2
++two
+ 3
+ 4
+ 5
+@@ -15,6 +15,6 @@
+ 15
+ 16
+ 17
+-18
++eighteen
+ 19
+ 20
+`,
+		},
+		{
+			name: "insert",
+			a:    "a\n",
+			b:    "x\na\n",
+			want: `--- f
++++ f
+@@ -1 +1,2 @@
++x
+ a
+`,
+		},
+		{
+			name: "no newline at end of file",
+			a:    "a\nb",
+			b:    "a\nc",
+			want: `--- f
++++ f
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++c
+\ No newline at end of file
+`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := unifiedDiff("f", tc.a, tc.b); got != tc.want {
+				t.Errorf("got diff\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package replace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/replacer/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/store"
+)
+
+// regexRewriter rewrites file contents with a regular expression. It is used
+// for literal and regexp rewrite specifications, which don't need an
+// external tool.
+type regexRewriter struct {
+	re *regexp.Regexp
+
+	// rewrite is the replacement for every match of re.
+	rewrite []byte
+
+	// literal if true means rewrite is inserted as is, without expanding
+	// references to capture groups.
+	literal bool
+
+	// fileExtension and directoryExclude filter which files are rewritten.
+	// They have the same meaning as for comby.
+	fileExtension    string
+	directoryExclude string
+}
+
+// fileResult is a single JSON line in a replace response. It is the same
+// format as returned by comby with -json-lines -json-only-diff.
+type fileResult struct {
+	URI  string `json:"uri"`
+	Diff string `json:"diff"`
+}
+
+// compileRewriter returns a regexRewriter for spec, which must be a regexp or
+// literal rewrite specification.
+func compileRewriter(spec *protocol.RewriteSpecification) (*regexRewriter, error) {
+	expr := spec.MatchTemplate
+	if spec.IsLiteral {
+		expr = regexp.QuoteMeta(expr)
+	} else {
+		// We rewrite whole files, therefore we want the regex engine to
+		// consider newlines for anchors (^$). This is the same as searcher.
+		expr = "(?m:" + expr + ")"
+	}
+	if !spec.IsCaseSensitive {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, badRequestError{err.Error()}
+	}
+	return &regexRewriter{
+		re:               re,
+		rewrite:          []byte(spec.RewriteTemplate),
+		literal:          spec.IsLiteral,
+		fileExtension:    spec.FileExtension,
+		directoryExclude: spec.DirectoryExclude,
+	}, nil
+}
+
+// shouldRewrite returns whether the file at path should be rewritten.
+func (rw *regexRewriter) shouldRewrite(path string) bool {
+	if rw.fileExtension != "" && !strings.HasSuffix(path, rw.fileExtension) {
+		return false
+	}
+	if rw.directoryExclude != "" {
+		dir := strings.TrimSuffix(rw.directoryExclude, "/") + "/"
+		if strings.HasPrefix(path, dir) || strings.Contains(path, "/"+dir) {
+			return false
+		}
+	}
+	return true
+}
+
+// Rewrite returns the rewritten content of data and whether anything was
+// rewritten.
+func (rw *regexRewriter) Rewrite(data []byte) ([]byte, bool) {
+	if !rw.re.Match(data) {
+		return nil, false
+	}
+	var out []byte
+	if rw.literal {
+		out = rw.re.ReplaceAllLiteral(data, rw.rewrite)
+	} else {
+		out = rw.re.ReplaceAll(data, rw.rewrite)
+	}
+	return out, !bytes.Equal(out, data)
+}
+
+// rewriteZip rewrites every file in zf and writes a JSON line with the
+// unified diff of each changed file to w.
+func (rw *regexRewriter) rewriteZip(ctx context.Context, zf *store.ZipFile, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for i := range zf.Files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		f := &zf.Files[i]
+		if !rw.shouldRewrite(f.Name) {
+			continue
+		}
+		// Large and binary files are stored without content.
+		data := zf.DataFor(f)
+		if len(data) == 0 {
+			continue
+		}
+
+		out, changed := rw.Rewrite(data)
+		if !changed {
+			continue
+		}
+		res := fileResult{
+			URI:  f.Name,
+			Diff: unifiedDiff(f.Name, string(data), string(out)),
+		}
+		if err := enc.Encode(&res); err != nil {
+			return errors.Wrap(err, "failed to write result")
+		}
+	}
+	return nil
+}
+
+type badRequestError struct{ msg string }
+
+func (e badRequestError) Error() string    { return e.msg }
+func (e badRequestError) BadRequest() bool { return true }
@@ -12,6 +12,8 @@
 // * Pass the zip file path to external replacer tool(s) after validating
 // * Read tool stdout and write it out on the HTTP connection
 // * Input from stdout is expected to use JSON lines format, but the format isn't checked here: line-buffering is done on the frontend
+// * Literal and regexp rewrites don't need an external tool: they are done in
+//   process and produce the same JSON lines format
 
 package replace
 
@@ -211,6 +213,17 @@ func (s *Service) replace(ctx context.Context, p *protocol.Request, w http.Respo
 	archiveFiles.Observe(float64(nFiles))
 	archiveSize.Observe(float64(bytes))
 
+	if p.IsRegExp || p.IsLiteral {
+		rw, err := compileRewriter(&p.RewriteSpecification)
+		if err != nil {
+			return false, err
+		}
+
+		w.Header().Set("Transfer-Encoding", "chunked")
+		w.WriteHeader(http.StatusOK)
+		return false, rw.rewriteZip(ctx, zf, w)
+	}
+
 	w.Header().Set("Transfer-Encoding", "chunked")
 	w.WriteHeader(http.StatusOK)
 
@@ -261,6 +274,9 @@ func validateParams(p *protocol.Request) error {
 	if p.RewriteSpecification.MatchTemplate == "" {
 		return errors.New("MatchTemplate must be non-empty")
 	}
+	if p.RewriteSpecification.IsRegExp && p.RewriteSpecification.IsLiteral {
+		return errors.New("IsRegExp and IsLiteral are mutually exclusive")
+	}
 	return nil
 }
 
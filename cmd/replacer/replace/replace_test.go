@@ -8,6 +8,8 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -89,6 +91,95 @@ func main() {
 	}
 }
 
+func TestReplace_regexp(t *testing.T) {
+	files := map[string]string{
+		"README.md": `# Hello World
+
+Hello world example in go`,
+		"main.go": `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("Hello foo")
+}
+`,
+		"vendor/lib.go": `package lib
+
+func Hello() {}
+`,
+	}
+
+	cases := []struct {
+		arg  protocol.RewriteSpecification
+		want string
+	}{
+		{protocol.RewriteSpecification{
+			MatchTemplate:   `Hello (\w+)`,
+			RewriteTemplate: "Goodbye ${1}!",
+			IsRegExp:        true,
+			FileExtension:   ".go",
+		}, `
+{"uri":"main.go","diff":"--- main.go\n+++ main.go\n@@ -3,5 +3,5 @@\n import \"fmt\"\n \n func main() {\n-\tfmt.Println(\"Hello foo\")\n+\tfmt.Println(\"Goodbye foo!\")\n }\n"}
+`},
+		{protocol.RewriteSpecification{
+			MatchTemplate:    "hello",
+			RewriteTemplate:  "$1Bye",
+			IsLiteral:        true,
+			DirectoryExclude: "vendor",
+		}, `
+{"uri":"README.md","diff":"--- README.md\n+++ README.md\n@@ -1,3 +1,3 @@\n-# Hello World\n+# $1Bye World\n \n-Hello world example in go\n\\ No newline at end of file\n+$1Bye world example in go\n\\ No newline at end of file\n"}
+{"uri":"main.go","diff":"--- main.go\n+++ main.go\n@@ -3,5 +3,5 @@\n import \"fmt\"\n \n func main() {\n-\tfmt.Println(\"Hello foo\")\n+\tfmt.Println(\"$1Bye foo\")\n }\n"}
+`},
+		{protocol.RewriteSpecification{
+			MatchTemplate:   "hello",
+			RewriteTemplate: "Bye",
+			IsLiteral:       true,
+			IsCaseSensitive: true,
+		}, ``},
+	}
+
+	store, cleanup, err := testutil.NewStore(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	ts := httptest.NewServer(&replace.Service{Store: store})
+	defer ts.Close()
+
+	for _, test := range cases {
+		req := protocol.Request{
+			Repo:                 "foo",
+			URL:                  "u",
+			Commit:               "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
+			RewriteSpecification: test.arg,
+			FetchTimeout:         "5000ms",
+		}
+		got, err := doReplace(ts.URL, &req)
+		if err != nil {
+			t.Errorf("%v failed: %s", test.arg, err)
+			continue
+		}
+		// Files are rewritten in archive order, which is not stable.
+		lines := strings.SplitAfter(got, "\n")
+		sort.Strings(lines)
+		got = strings.Join(lines, "")
+
+		// We have an extra newline to make expected readable
+		if len(test.want) > 0 {
+			test.want = test.want[1:]
+		}
+
+		if got != test.want {
+			d, err := testutil.Diff(test.want, got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Errorf("%v unexpected response:\n%s", test.arg, d)
+		}
+	}
+}
+
 func TestReplace_badrequest(t *testing.T) {
 	cases := []protocol.Request{
 		{
@@ -97,6 +188,16 @@ func TestReplace_badrequest(t *testing.T) {
 			Commit: "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
 			// No MatchTemplate
 		},
+		{
+			Repo:   "foo",
+			URL:    "u",
+			Commit: "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
+			RewriteSpecification: protocol.RewriteSpecification{
+				MatchTemplate: "foo",
+				IsRegExp:      true,
+				IsLiteral:     true,
+			},
+		},
 	}
 
 	store, cleanup, err := testutil.NewStore(nil)
@@ -120,13 +221,17 @@ func TestReplace_badrequest(t *testing.T) {
 
 func doReplace(u string, p *protocol.Request) (string, error) {
 	form := url.Values{
-		"Repo":            []string{string(p.Repo)},
-		"URL":             []string{string(p.URL)},
-		"Commit":          []string{string(p.Commit)},
-		"FetchTimeout":    []string{p.FetchTimeout},
-		"MatchTemplate":   []string{p.RewriteSpecification.MatchTemplate},
-		"RewriteTemplate": []string{p.RewriteSpecification.RewriteTemplate},
-		"FileExtension":   []string{p.RewriteSpecification.FileExtension},
+		"Repo":             []string{string(p.Repo)},
+		"URL":              []string{string(p.URL)},
+		"Commit":           []string{string(p.Commit)},
+		"FetchTimeout":     []string{p.FetchTimeout},
+		"MatchTemplate":    []string{p.RewriteSpecification.MatchTemplate},
+		"RewriteTemplate":  []string{p.RewriteSpecification.RewriteTemplate},
+		"FileExtension":    []string{p.RewriteSpecification.FileExtension},
+		"DirectoryExclude": []string{p.RewriteSpecification.DirectoryExclude},
+		"IsRegExp":         []string{strconv.FormatBool(p.RewriteSpecification.IsRegExp)},
+		"IsLiteral":        []string{strconv.FormatBool(p.RewriteSpecification.IsLiteral)},
+		"IsCaseSensitive":  []string{strconv.FormatBool(p.RewriteSpecification.IsCaseSensitive)},
 	}
 	resp, err := http.PostForm(u, form)
 	if err != nil {
@@ -4,16 +4,20 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/inconshreveable/log15"
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
 	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
 	"github.com/sourcegraph/sourcegraph/internal/jsonc"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
 	"github.com/sourcegraph/sourcegraph/schema"
 )
 
@@ -88,6 +92,186 @@ func (s BitbucketCloudSource) ExternalServices() ExternalServices {
 	return ExternalServices{s.svc}
 }
 
+var _ ChangesetSource = BitbucketCloudSource{}
+
+// CreateChangeset creates a Bitbucket Cloud pull request for the given
+// *Changeset. Bitbucket Cloud doesn't reject pull requests duplicating an open
+// one, so we look for an existing pull request first.
+func (s BitbucketCloudSource) CreateChangeset(ctx context.Context, c *Changeset) (bool, error) {
+	repo := c.Repo.Metadata.(*bitbucketcloud.Repo)
+	source := git.AbbreviateRef(c.HeadRef)
+	destination := git.AbbreviateRef(c.BaseRef)
+
+	pr, err := s.client.OpenPullRequestByRefs(ctx, repo, source, destination)
+	if err != nil {
+		return false, errors.Wrap(err, "looking up existing pull request")
+	}
+
+	exists := pr != nil
+	if !exists {
+		pr, err = s.client.CreatePullRequest(ctx, repo, bitbucketcloud.CreatePullRequestOpts{
+			Title:       c.Title,
+			Description: c.Body,
+			Source: bitbucketcloud.PullRequestEndpoint{
+				Branch: bitbucketcloud.PullRequestBranch{Name: source},
+			},
+			Destination: bitbucketcloud.PullRequestEndpoint{
+				Branch: bitbucketcloud.PullRequestBranch{Name: destination},
+			},
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if err := s.loadPullRequestData(ctx, repo, pr); err != nil {
+		return false, errors.Wrap(err, "loading extra metadata")
+	}
+	if err := c.SetMetadata(pr); err != nil {
+		return false, errors.Wrap(err, "setting changeset metadata")
+	}
+
+	return exists, nil
+}
+
+// CloseChangeset declines the pull request of the given *Changeset on the
+// code host and updates its Metadata to the declined pull request.
+func (s BitbucketCloudSource) CloseChangeset(ctx context.Context, c *Changeset) error {
+	pr, ok := c.Changeset.Metadata.(*bitbucketcloud.PullRequest)
+	if !ok {
+		return errors.New("Changeset is not a Bitbucket Cloud pull request")
+	}
+	repo := c.Repo.Metadata.(*bitbucketcloud.Repo)
+
+	declined, err := s.client.DeclinePullRequest(ctx, repo, pr.ID)
+	if err != nil {
+		return err
+	}
+
+	declined.Activities = pr.Activities
+	declined.Statuses = pr.Statuses
+	c.Changeset.Metadata = declined
+
+	return nil
+}
+
+// bitbucketCloudMergeStrategies maps merge methods to the equivalent
+// Bitbucket Cloud merge strategies. Bitbucket Cloud can't rebase pull
+// requests, so fast-forwarding is the closest to ChangesetMergeMethodRebase.
+var bitbucketCloudMergeStrategies = map[campaigns.ChangesetMergeMethod]bitbucketcloud.MergeStrategy{
+	campaigns.ChangesetMergeMethodMerge:  bitbucketcloud.MergeStrategyMergeCommit,
+	campaigns.ChangesetMergeMethodSquash: bitbucketcloud.MergeStrategySquash,
+	campaigns.ChangesetMergeMethodRebase: bitbucketcloud.MergeStrategyFastForward,
+}
+
+// MergeChangeset merges the pull request of the given *Changeset on the code
+// host with the merge strategy equivalent to the given merge method.
+func (s BitbucketCloudSource) MergeChangeset(ctx context.Context, c *Changeset, method campaigns.ChangesetMergeMethod) error {
+	pr, ok := c.Changeset.Metadata.(*bitbucketcloud.PullRequest)
+	if !ok {
+		return errors.New("Changeset is not a Bitbucket Cloud pull request")
+	}
+	repo := c.Repo.Metadata.(*bitbucketcloud.Repo)
+
+	merged, err := s.client.MergePullRequest(ctx, repo, pr.ID, bitbucketCloudMergeStrategies[method])
+	if err != nil {
+		return err
+	}
+
+	merged.Activities = pr.Activities
+	merged.Statuses = pr.Statuses
+	c.Changeset.Metadata = merged
+
+	return nil
+}
+
+// LoadChangesets loads the latest state of the given Changesets from the codehost.
+func (s BitbucketCloudSource) LoadChangesets(ctx context.Context, cs ...*Changeset) error {
+	var notFound []*Changeset
+
+	for i := range cs {
+		repo := cs[i].Repo.Metadata.(*bitbucketcloud.Repo)
+		id, err := strconv.Atoi(cs[i].ExternalID)
+		if err != nil {
+			return errors.Wrap(err, "parsing changeset external id")
+		}
+
+		pr, err := s.client.PullRequest(ctx, repo, id)
+		if err != nil {
+			if bitbucketcloud.IsNotFound(err) {
+				notFound = append(notFound, cs[i])
+				if cs[i].Changeset.Metadata == nil {
+					cs[i].Changeset.Metadata = &bitbucketcloud.PullRequest{ID: id}
+				}
+				continue
+			}
+
+			return err
+		}
+
+		if err := s.loadPullRequestData(ctx, repo, pr); err != nil {
+			return errors.Wrap(err, "loading pull request data")
+		}
+		if prev, ok := cs[i].Changeset.Metadata.(*bitbucketcloud.PullRequest); ok {
+			pr.DetectUnapprovals(prev, time.Now())
+		}
+		if err := cs[i].SetMetadata(pr); err != nil {
+			return errors.Wrap(err, "setting changeset metadata")
+		}
+	}
+
+	if len(notFound) > 0 {
+		return ChangesetsNotFoundError{Changesets: notFound}
+	}
+
+	return nil
+}
+
+// UpdateChangeset updates the pull request of the given *Changeset on the
+// code host.
+func (s BitbucketCloudSource) UpdateChangeset(ctx context.Context, c *Changeset) error {
+	pr, ok := c.Changeset.Metadata.(*bitbucketcloud.PullRequest)
+	if !ok {
+		return errors.New("Changeset is not a Bitbucket Cloud pull request")
+	}
+	repo := c.Repo.Metadata.(*bitbucketcloud.Repo)
+
+	updated, err := s.client.UpdatePullRequest(ctx, repo, pr.ID, bitbucketcloud.UpdatePullRequestOpts{
+		Title:       c.Title,
+		Description: c.Body,
+		Destination: bitbucketcloud.PullRequestEndpoint{
+			Branch: bitbucketcloud.PullRequestBranch{Name: git.AbbreviateRef(c.BaseRef)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// The pull request returned by the API doesn't include the activity and
+	// statuses we loaded, so we keep them until the next sync.
+	updated.Activities = pr.Activities
+	updated.Statuses = pr.Statuses
+	c.Changeset.Metadata = updated
+
+	return nil
+}
+
+func (s BitbucketCloudSource) loadPullRequestData(ctx context.Context, repo *bitbucketcloud.Repo, pr *bitbucketcloud.PullRequest) error {
+	activities, err := s.client.PullRequestActivity(ctx, repo, pr.ID)
+	if err != nil {
+		return errors.Wrap(err, "loading pull request activity")
+	}
+	pr.Activities = activities
+
+	statuses, err := s.client.PullRequestStatuses(ctx, repo, pr.ID)
+	if err != nil {
+		return errors.Wrap(err, "loading pull request statuses")
+	}
+	pr.Statuses = statuses
+
+	return nil
+}
+
 func (s BitbucketCloudSource) makeRepo(r *bitbucketcloud.Repo) *Repo {
 	host, err := url.Parse(s.config.Url)
 	if err != nil {
@@ -5,14 +5,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/testutil"
 	"github.com/sourcegraph/sourcegraph/schema"
@@ -246,3 +251,100 @@ func TestBitbucketCloudSource_Exclude(t *testing.T) {
 		})
 	}
 }
+
+func TestBitbucketCloudSource_ChangesetSource(t *testing.T) {
+	repo := &bitbucketcloud.Repo{FullName: "sglocal/mux"}
+	prPath := "/2.0/repositories/sglocal/mux/pullrequests"
+
+	var created int
+	mux := http.NewServeMux()
+	mux.HandleFunc(prPath, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			if !strings.Contains(r.URL.Query().Get("q"), `source.branch.name = "existing"`) {
+				fmt.Fprint(w, `{"values": []}`)
+				return
+			}
+			fmt.Fprint(w, `{"values": [{"id": 2, "state": "OPEN", "source": {"branch": {"name": "existing"}}}]}`)
+		case "POST":
+			created++
+			fmt.Fprint(w, `{"id": 3, "state": "OPEN", "source": {"branch": {"name": "campaign"}}}`)
+		}
+	})
+	mux.HandleFunc(prPath+"/404", http.NotFound)
+	mux.HandleFunc(prPath+"/2", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 2, "state": "MERGED"}`)
+	})
+	mux.HandleFunc(prPath+"/2/decline", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 2, "state": "DECLINED"}`)
+	})
+	for _, id := range []string{"2", "3"} {
+		mux.HandleFunc(prPath+"/"+id+"/activity", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"values": [{"approval": {"date": "2020-04-01T12:00:00Z", "user": {"uuid": "{u1}"}}}]}`)
+		})
+		mux.HandleFunc(prPath+"/"+id+"/statuses", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"values": [{"key": "ci", "state": "SUCCESSFUL"}]}`)
+		})
+	}
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	svc := &ExternalService{Kind: "BITBUCKETCLOUD"}
+	src, err := newBitbucketCloudSource(svc, &schema.BitbucketCloudConnection{Url: "https://bitbucket.org"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(srv.URL)
+	src.client = bitbucketcloud.NewClient(u, srv.Client())
+
+	newChangeset := func(head string) *Changeset {
+		return &Changeset{
+			HeadRef:   "refs/heads/" + head,
+			BaseRef:   "refs/heads/master",
+			Changeset: &campaigns.Changeset{},
+			Repo:      &Repo{Metadata: repo},
+		}
+	}
+
+	for _, tc := range []struct {
+		head   string
+		exists bool
+		id     string
+	}{
+		{head: "campaign", exists: false, id: "3"},
+		{head: "existing", exists: true, id: "2"},
+	} {
+		c := newChangeset(tc.head)
+		exists, err := src.CreateChangeset(context.Background(), c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists != tc.exists {
+			t.Errorf("%s: got exists %v, want %v", tc.head, exists, tc.exists)
+		}
+		pr := c.Changeset.Metadata.(*bitbucketcloud.PullRequest)
+		if c.ExternalID != tc.id || c.ExternalServiceType != bitbucketcloud.ServiceType || len(pr.Activities) != 1 || len(pr.Statuses) != 1 {
+			t.Errorf("%s: unexpected changeset %+v with pull request %+v", tc.head, c.Changeset, pr)
+		}
+	}
+	if created != 1 {
+		t.Errorf("created %d pull requests, want 1", created)
+	}
+
+	found := &Changeset{Changeset: &campaigns.Changeset{ExternalID: "2"}, Repo: &Repo{Metadata: repo}}
+	missing := &Changeset{Changeset: &campaigns.Changeset{ExternalID: "404"}, Repo: &Repo{Metadata: repo}}
+	err = src.LoadChangesets(context.Background(), found, missing)
+	if nf, ok := err.(ChangesetsNotFoundError); !ok || len(nf.Changesets) != 1 || nf.Changesets[0] != missing {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if pr := found.Changeset.Metadata.(*bitbucketcloud.PullRequest); pr.State != bitbucketcloud.PullRequestStateMerged || len(pr.Activities) != 1 {
+		t.Errorf("unexpected pull request %+v", pr)
+	}
+
+	if err := src.CloseChangeset(context.Background(), found); err != nil {
+		t.Fatal(err)
+	}
+	if pr := found.Changeset.Metadata.(*bitbucketcloud.PullRequest); pr.State != bitbucketcloud.PullRequestStateDeclined || len(pr.Statuses) != 1 {
+		t.Errorf("unexpected pull request %+v", pr)
+	}
+}
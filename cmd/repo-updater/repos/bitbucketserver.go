@@ -11,6 +11,7 @@ import (
 	"github.com/inconshreveable/log15"
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
 	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
@@ -149,6 +150,33 @@ func (s BitbucketServerSource) CloseChangeset(ctx context.Context, c *Changeset)
 	return nil
 }
 
+// bitbucketServerMergeStrategies maps merge methods to the IDs of the
+// equivalent Bitbucket Server merge strategies.
+var bitbucketServerMergeStrategies = map[campaigns.ChangesetMergeMethod]string{
+	campaigns.ChangesetMergeMethodMerge:  "no-ff",
+	campaigns.ChangesetMergeMethodSquash: "squash",
+	campaigns.ChangesetMergeMethodRebase: "rebase-no-ff",
+}
+
+// MergeChangeset merges the pull request of the given *Changeset on
+// Bitbucket Server with the merge strategy equivalent to the given merge
+// method. The strategy must be enabled in the repository.
+func (s BitbucketServerSource) MergeChangeset(ctx context.Context, c *Changeset, method campaigns.ChangesetMergeMethod) error {
+	pr, ok := c.Changeset.Metadata.(*bitbucketserver.PullRequest)
+	if !ok {
+		return errors.New("Changeset is not a Bitbucket Server pull request")
+	}
+
+	err := s.client.MergePullRequest(ctx, pr, bitbucketServerMergeStrategies[method])
+	if err != nil {
+		return err
+	}
+
+	c.Changeset.Metadata = pr
+
+	return nil
+}
+
 // LoadChangesets loads the latest state of the given Changesets from the codehost.
 func (s BitbucketServerSource) LoadChangesets(ctx context.Context, cs ...*Changeset) error {
 	var notFound []*Changeset
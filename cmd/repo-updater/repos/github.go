@@ -12,6 +12,7 @@ import (
 
 	"github.com/inconshreveable/log15"
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
 	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
@@ -151,10 +152,25 @@ func (s GithubSource) ExternalServices() ExternalServices {
 	return ExternalServices{s.svc}
 }
 
-var _ ChangesetSource = GithubSource{}
+var (
+	_ DraftChangesetSource    = GithubSource{}
+	_ ChangesetCommentSource  = GithubSource{}
+	_ ChangesetLabelSource    = GithubSource{}
+	_ ChangesetReviewerSource = GithubSource{}
+)
 
 // CreateChangeset creates the given *Changeset in the code host.
 func (s GithubSource) CreateChangeset(ctx context.Context, c *Changeset) (bool, error) {
+	return s.createChangeset(ctx, c, false)
+}
+
+// CreateDraftChangeset creates the given *Changeset in the code host as a
+// draft pull request.
+func (s GithubSource) CreateDraftChangeset(ctx context.Context, c *Changeset) (bool, error) {
+	return s.createChangeset(ctx, c, true)
+}
+
+func (s GithubSource) createChangeset(ctx context.Context, c *Changeset, draft bool) (bool, error) {
 	var exists bool
 	repo := c.Repo.Metadata.(*github.Repository)
 
@@ -164,6 +180,7 @@ func (s GithubSource) CreateChangeset(ctx context.Context, c *Changeset) (bool,
 		Body:         c.Body,
 		HeadRefName:  git.AbbreviateRef(c.HeadRef),
 		BaseRefName:  git.AbbreviateRef(c.BaseRef),
+		Draft:        draft,
 	})
 
 	if err != nil {
@@ -206,6 +223,112 @@ func (s GithubSource) CloseChangeset(ctx context.Context, c *Changeset) error {
 	return nil
 }
 
+// UndraftChangeset marks the draft pull request of the given *Changeset on
+// GitHub as ready for review.
+func (s GithubSource) UndraftChangeset(ctx context.Context, c *Changeset) error {
+	pr, ok := c.Changeset.Metadata.(*github.PullRequest)
+	if !ok {
+		return errors.New("Changeset is not a GitHub pull request")
+	}
+
+	if !pr.IsDraft {
+		return nil
+	}
+
+	err := s.client.MarkPullRequestReadyForReview(ctx, pr)
+	if err != nil {
+		return err
+	}
+
+	c.Changeset.Metadata = pr
+
+	return nil
+}
+
+// MergeChangeset merges the pull request of the given *Changeset on GitHub
+// with the given merge method.
+func (s GithubSource) MergeChangeset(ctx context.Context, c *Changeset, method campaigns.ChangesetMergeMethod) error {
+	pr, ok := c.Changeset.Metadata.(*github.PullRequest)
+	if !ok {
+		return errors.New("Changeset is not a GitHub pull request")
+	}
+
+	// Our merge methods have the same names as GitHub's.
+	err := s.client.MergePullRequest(ctx, pr, string(method))
+	if err != nil {
+		return err
+	}
+
+	c.Changeset.Metadata = pr
+
+	return nil
+}
+
+// CreateChangesetComment posts a comment with the given body on the pull
+// request of the given *Changeset.
+func (s GithubSource) CreateChangesetComment(ctx context.Context, c *Changeset, body string) error {
+	pr, ok := c.Changeset.Metadata.(*github.PullRequest)
+	if !ok {
+		return errors.New("Changeset is not a GitHub pull request")
+	}
+
+	return s.client.AddPullRequestComment(ctx, pr, body)
+}
+
+// AddChangesetLabels adds the labels with the given names to the pull request
+// of the given *Changeset.
+func (s GithubSource) AddChangesetLabels(ctx context.Context, c *Changeset, labels []string) error {
+	owner, name, pr, err := githubChangesetPullRequest(c)
+	if err != nil {
+		return err
+	}
+
+	return s.client.AddPullRequestLabels(ctx, owner, name, pr.Number, labels)
+}
+
+// RemoveChangesetLabels removes the labels with the given names from the pull
+// request of the given *Changeset.
+func (s GithubSource) RemoveChangesetLabels(ctx context.Context, c *Changeset, labels []string) error {
+	owner, name, pr, err := githubChangesetPullRequest(c)
+	if err != nil {
+		return err
+	}
+
+	for _, label := range labels {
+		if err := s.client.RemovePullRequestLabel(ctx, owner, name, pr.Number, label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RequestChangesetReviewers requests reviews from the users with the given
+// logins on the pull request of the given *Changeset.
+func (s GithubSource) RequestChangesetReviewers(ctx context.Context, c *Changeset, reviewers []string) error {
+	owner, name, pr, err := githubChangesetPullRequest(c)
+	if err != nil {
+		return err
+	}
+
+	return s.client.RequestPullRequestReviewers(ctx, owner, name, pr.Number, reviewers)
+}
+
+// githubChangesetPullRequest returns the pull request of the given *Changeset
+// and the owner and name of its repository.
+func githubChangesetPullRequest(c *Changeset) (owner, name string, pr *github.PullRequest, err error) {
+	pr, ok := c.Changeset.Metadata.(*github.PullRequest)
+	if !ok {
+		return "", "", nil, errors.New("Changeset is not a GitHub pull request")
+	}
+
+	repo := c.Repo.Metadata.(*github.Repository)
+	owner, name, err = github.SplitRepositoryNameWithOwner(repo.NameWithOwner)
+	if err != nil {
+		return "", "", nil, errors.Wrap(err, "getting repo owner and name")
+	}
+	return owner, name, pr, nil
+}
+
 // LoadChangesets loads the latest state of the given Changesets from the codehost.
 func (s GithubSource) LoadChangesets(ctx context.Context, cs ...*Changeset) error {
 	prs := make([]*github.PullRequest, len(cs))
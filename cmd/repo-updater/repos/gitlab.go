@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -11,11 +12,13 @@ import (
 
 	"github.com/inconshreveable/log15"
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
 	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
 	"github.com/sourcegraph/sourcegraph/internal/jsonc"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
 	"github.com/sourcegraph/sourcegraph/schema"
 )
 
@@ -111,6 +114,242 @@ func (s GitLabSource) ExternalServices() ExternalServices {
 	return ExternalServices{s.svc}
 }
 
+var (
+	_ DraftChangesetSource   = GitLabSource{}
+	_ ChangesetCommentSource = GitLabSource{}
+	_ ChangesetLabelSource   = GitLabSource{}
+)
+
+// CreateChangeset creates a GitLab merge request for the given *Changeset.
+func (s GitLabSource) CreateChangeset(ctx context.Context, c *Changeset) (bool, error) {
+	return s.createChangeset(ctx, c, c.Title)
+}
+
+// CreateDraftChangeset creates a GitLab merge request for the given
+// *Changeset that's marked as work in progress.
+func (s GitLabSource) CreateDraftChangeset(ctx context.Context, c *Changeset) (bool, error) {
+	return s.createChangeset(ctx, c, gitlabWIPTitle(c.Title))
+}
+
+func (s GitLabSource) createChangeset(ctx context.Context, c *Changeset, title string) (bool, error) {
+	var exists bool
+	project := c.Repo.Metadata.(*gitlab.Project)
+	source := git.AbbreviateRef(c.HeadRef)
+	target := git.AbbreviateRef(c.BaseRef)
+
+	mr, err := s.client.CreateMergeRequest(ctx, project, gitlab.CreateMergeRequestOpts{
+		SourceBranch: source,
+		TargetBranch: target,
+		Title:        title,
+		Description:  c.Body,
+	})
+	if err != nil {
+		if err != gitlab.ErrMergeRequestAlreadyExists {
+			return exists, err
+		}
+		mr, err = s.client.GetOpenMergeRequestByRefs(ctx, project, source, target)
+		if err != nil {
+			return exists, errors.Wrap(err, "fetching existing merge request")
+		}
+		exists = true
+	}
+
+	if err := s.loadMergeRequestData(ctx, project, mr); err != nil {
+		return false, errors.Wrap(err, "loading extra metadata")
+	}
+	if err := c.SetMetadata(mr); err != nil {
+		return false, errors.Wrap(err, "setting changeset metadata")
+	}
+
+	return exists, nil
+}
+
+// CloseChangeset closes the merge request of the given *Changeset on the code
+// host and updates its Metadata to the closed merge request.
+func (s GitLabSource) CloseChangeset(ctx context.Context, c *Changeset) error {
+	return s.updateMergeRequest(ctx, c, gitlab.UpdateMergeRequestOpts{StateEvent: "close"})
+}
+
+// MergeChangeset merges the merge request of the given *Changeset on the code
+// host according to the merge method of its project. GitLab can't rebase
+// merge requests when merging them, so the ChangesetMergeMethodRebase is only
+// supported by projects whose merge method is fast-forward.
+func (s GitLabSource) MergeChangeset(ctx context.Context, c *Changeset, method campaigns.ChangesetMergeMethod) error {
+	mr, ok := c.Changeset.Metadata.(*gitlab.MergeRequest)
+	if !ok {
+		return errors.New("Changeset is not a GitLab merge request")
+	}
+	project := c.Repo.Metadata.(*gitlab.Project)
+
+	merged, err := s.client.AcceptMergeRequest(ctx, project, mr, gitlab.AcceptMergeRequestOpts{
+		SHA:    mr.DiffRefs.HeadSHA,
+		Squash: method == campaigns.ChangesetMergeMethodSquash,
+	})
+	if err != nil {
+		return err
+	}
+
+	merged.Notes = mr.Notes
+	merged.Pipelines = mr.Pipelines
+	c.Changeset.Metadata = merged
+
+	return nil
+}
+
+// LoadChangesets loads the latest state of the given Changesets from the codehost.
+func (s GitLabSource) LoadChangesets(ctx context.Context, cs ...*Changeset) error {
+	var notFound []*Changeset
+
+	for i := range cs {
+		project := cs[i].Repo.Metadata.(*gitlab.Project)
+		iid, err := strconv.Atoi(cs[i].ExternalID)
+		if err != nil {
+			return errors.Wrap(err, "parsing changeset external id")
+		}
+
+		mr, err := s.client.GetMergeRequest(ctx, project, iid)
+		if err != nil {
+			if gitlab.IsNotFound(err) {
+				notFound = append(notFound, cs[i])
+				if cs[i].Changeset.Metadata == nil {
+					cs[i].Changeset.Metadata = &gitlab.MergeRequest{IID: iid, ProjectID: project.ID}
+				}
+				continue
+			}
+
+			return err
+		}
+
+		if err := s.loadMergeRequestData(ctx, project, mr); err != nil {
+			return errors.Wrap(err, "loading merge request data")
+		}
+		if err := cs[i].SetMetadata(mr); err != nil {
+			return errors.Wrap(err, "setting changeset metadata")
+		}
+	}
+
+	if len(notFound) > 0 {
+		return ChangesetsNotFoundError{Changesets: notFound}
+	}
+
+	return nil
+}
+
+// UpdateChangeset updates the merge request of the given *Changeset on the
+// code host.
+func (s GitLabSource) UpdateChangeset(ctx context.Context, c *Changeset) error {
+	title := c.Title
+	// Updating the title must not mark a merge request that's work in
+	// progress as ready.
+	if mr, ok := c.Changeset.Metadata.(*gitlab.MergeRequest); ok && mr.WorkInProgress {
+		title = gitlabWIPTitle(title)
+	}
+
+	return s.updateMergeRequest(ctx, c, gitlab.UpdateMergeRequestOpts{
+		Title:        title,
+		Description:  c.Body,
+		TargetBranch: git.AbbreviateRef(c.BaseRef),
+	})
+}
+
+// UndraftChangeset marks the merge request of the given *Changeset as ready
+// by removing the work in progress prefix from its title.
+func (s GitLabSource) UndraftChangeset(ctx context.Context, c *Changeset) error {
+	mr, ok := c.Changeset.Metadata.(*gitlab.MergeRequest)
+	if !ok {
+		return errors.New("Changeset is not a GitLab merge request")
+	}
+
+	if !mr.WorkInProgress {
+		return nil
+	}
+
+	return s.updateMergeRequest(ctx, c, gitlab.UpdateMergeRequestOpts{
+		Title: gitlabWIPPrefix.ReplaceAllString(mr.Title, ""),
+	})
+}
+
+// gitlabWIPPrefix matches the title prefixes that mark a GitLab merge request
+// as work in progress.
+var gitlabWIPPrefix = regexp.MustCompile(`(?i)^(\s*(\[wip\]|wip:|\[draft\]|draft:|\(draft\)))+\s*`)
+
+// gitlabWIPTitle returns the title of a GitLab merge request with the given
+// title that's marked as work in progress.
+func gitlabWIPTitle(title string) string {
+	return "WIP: " + gitlabWIPPrefix.ReplaceAllString(title, "")
+}
+
+func (s GitLabSource) updateMergeRequest(ctx context.Context, c *Changeset, opts gitlab.UpdateMergeRequestOpts) error {
+	mr, ok := c.Changeset.Metadata.(*gitlab.MergeRequest)
+	if !ok {
+		return errors.New("Changeset is not a GitLab merge request")
+	}
+	project := c.Repo.Metadata.(*gitlab.Project)
+
+	updated, err := s.client.UpdateMergeRequest(ctx, project, mr, opts)
+	if err != nil {
+		return err
+	}
+
+	// The merge request returned by the API doesn't include the notes and
+	// pipelines we loaded, so we keep them until the next sync.
+	updated.Notes = mr.Notes
+	updated.Pipelines = mr.Pipelines
+	c.Changeset.Metadata = updated
+
+	return nil
+}
+
+// CreateChangesetComment posts a note with the given body on the merge
+// request of the given *Changeset.
+func (s GitLabSource) CreateChangesetComment(ctx context.Context, c *Changeset, body string) error {
+	mr, ok := c.Changeset.Metadata.(*gitlab.MergeRequest)
+	if !ok {
+		return errors.New("Changeset is not a GitLab merge request")
+	}
+	project := c.Repo.Metadata.(*gitlab.Project)
+
+	note, err := s.client.CreateMergeRequestNote(ctx, project, mr.IID, body)
+	if err != nil {
+		return err
+	}
+
+	mr.Notes = append(mr.Notes, note)
+	return nil
+}
+
+// AddChangesetLabels adds the labels with the given names to the merge
+// request of the given *Changeset.
+func (s GitLabSource) AddChangesetLabels(ctx context.Context, c *Changeset, labels []string) error {
+	return s.updateMergeRequest(ctx, c, gitlab.UpdateMergeRequestOpts{
+		AddLabels: strings.Join(labels, ","),
+	})
+}
+
+// RemoveChangesetLabels removes the labels with the given names from the
+// merge request of the given *Changeset.
+func (s GitLabSource) RemoveChangesetLabels(ctx context.Context, c *Changeset, labels []string) error {
+	return s.updateMergeRequest(ctx, c, gitlab.UpdateMergeRequestOpts{
+		RemoveLabels: strings.Join(labels, ","),
+	})
+}
+
+func (s GitLabSource) loadMergeRequestData(ctx context.Context, project *gitlab.Project, mr *gitlab.MergeRequest) error {
+	notes, err := s.client.GetMergeRequestNotes(ctx, project, mr.IID)
+	if err != nil {
+		return errors.Wrap(err, "loading merge request notes")
+	}
+	mr.Notes = notes
+
+	pipelines, err := s.client.GetMergeRequestPipelines(ctx, project, mr.IID)
+	if err != nil {
+		return errors.Wrap(err, "loading merge request pipelines")
+	}
+	mr.Pipelines = pipelines
+
+	return nil
+}
+
 func (s GitLabSource) makeRepo(proj *gitlab.Project) *Repo {
 	urn := s.svc.URN()
 	return &Repo{
@@ -12,6 +12,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/inconshreveable/log15"
 	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/rcache"
 	"github.com/sourcegraph/sourcegraph/internal/testutil"
@@ -212,3 +213,254 @@ func TestGitLabSource_makeRepo(t *testing.T) {
 		})
 	}
 }
+
+func TestGitLabSource_ChangesetSource(t *testing.T) {
+	project := &gitlab.Project{ProjectCommon: gitlab.ProjectCommon{ID: 1}}
+	notes := []*gitlab.Note{{ID: 1, Body: "lgtm"}}
+	pipelines := []*gitlab.Pipeline{{ID: 2, Status: gitlab.PipelineStatusSuccess}}
+
+	gitlab.MockGetMergeRequestNotes = func(_ *gitlab.Client, _ context.Context, _ *gitlab.Project, iid int) ([]*gitlab.Note, error) {
+		return notes, nil
+	}
+	gitlab.MockGetMergeRequestPipelines = func(_ *gitlab.Client, _ context.Context, _ *gitlab.Project, iid int) ([]*gitlab.Pipeline, error) {
+		return pipelines, nil
+	}
+	defer func() {
+		gitlab.MockCreateMergeRequest = nil
+		gitlab.MockGetOpenMergeRequestByRefs = nil
+		gitlab.MockGetMergeRequest = nil
+		gitlab.MockUpdateMergeRequest = nil
+		gitlab.MockAcceptMergeRequest = nil
+		gitlab.MockGetMergeRequestNotes = nil
+		gitlab.MockGetMergeRequestPipelines = nil
+		gitlab.MockCreateMergeRequestNote = nil
+	}()
+
+	svc := &ExternalService{Kind: "GITLAB"}
+	src, err := newGitLabSource(svc, &schema.GitLabConnection{Url: "https://gitlab.com"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("CreateChangeset already exists", func(t *testing.T) {
+		gitlab.MockCreateMergeRequest = func(*gitlab.Client, context.Context, *gitlab.Project, gitlab.CreateMergeRequestOpts) (*gitlab.MergeRequest, error) {
+			return nil, gitlab.ErrMergeRequestAlreadyExists
+		}
+		gitlab.MockGetOpenMergeRequestByRefs = func(_ *gitlab.Client, _ context.Context, _ *gitlab.Project, source, target string) (*gitlab.MergeRequest, error) {
+			if source != "campaign" || target != "master" {
+				t.Errorf("unexpected refs %q and %q", source, target)
+			}
+			return &gitlab.MergeRequest{IID: 3, SourceBranch: source}, nil
+		}
+
+		c := &Changeset{
+			HeadRef:   "refs/heads/campaign",
+			BaseRef:   "refs/heads/master",
+			Changeset: &campaigns.Changeset{},
+			Repo:      &Repo{Metadata: project},
+		}
+		exists, err := src.CreateChangeset(context.Background(), c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists {
+			t.Error("expected existing merge request")
+		}
+		mr := c.Changeset.Metadata.(*gitlab.MergeRequest)
+		if c.ExternalID != "3" || c.ExternalServiceType != gitlab.ServiceType || len(mr.Notes) != 1 || len(mr.Pipelines) != 1 {
+			t.Errorf("unexpected changeset %+v with merge request %+v", c.Changeset, mr)
+		}
+	})
+
+	t.Run("LoadChangesets not found", func(t *testing.T) {
+		gitlab.MockGetMergeRequest = func(_ *gitlab.Client, _ context.Context, _ *gitlab.Project, iid int) (*gitlab.MergeRequest, error) {
+			if iid == 404 {
+				return nil, gitlab.ErrNotFound
+			}
+			return &gitlab.MergeRequest{IID: iid, State: gitlab.MergeRequestStateMerged}, nil
+		}
+
+		found := &Changeset{Changeset: &campaigns.Changeset{ExternalID: "4"}, Repo: &Repo{Metadata: project}}
+		missing := &Changeset{Changeset: &campaigns.Changeset{ExternalID: "404"}, Repo: &Repo{Metadata: project}}
+		err := src.LoadChangesets(context.Background(), found, missing)
+		if nf, ok := err.(ChangesetsNotFoundError); !ok || len(nf.Changesets) != 1 || nf.Changesets[0] != missing {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if mr := found.Changeset.Metadata.(*gitlab.MergeRequest); mr.State != gitlab.MergeRequestStateMerged || len(mr.Notes) != 1 {
+			t.Errorf("unexpected merge request %+v", mr)
+		}
+		if mr := missing.Changeset.Metadata.(*gitlab.MergeRequest); mr.IID != 404 {
+			t.Errorf("unexpected merge request %+v", mr)
+		}
+	})
+
+	t.Run("CloseChangeset", func(t *testing.T) {
+		gitlab.MockUpdateMergeRequest = func(_ *gitlab.Client, _ context.Context, _ *gitlab.Project, mr *gitlab.MergeRequest, opts gitlab.UpdateMergeRequestOpts) (*gitlab.MergeRequest, error) {
+			if opts.StateEvent != "close" {
+				t.Errorf("unexpected state event %q", opts.StateEvent)
+			}
+			return &gitlab.MergeRequest{IID: mr.IID, State: gitlab.MergeRequestStateClosed}, nil
+		}
+
+		c := &Changeset{
+			Changeset: &campaigns.Changeset{Metadata: &gitlab.MergeRequest{IID: 5, Notes: notes, Pipelines: pipelines}},
+			Repo:      &Repo{Metadata: project},
+		}
+		if err := src.CloseChangeset(context.Background(), c); err != nil {
+			t.Fatal(err)
+		}
+		mr := c.Changeset.Metadata.(*gitlab.MergeRequest)
+		if mr.State != gitlab.MergeRequestStateClosed || len(mr.Notes) != 1 || len(mr.Pipelines) != 1 {
+			t.Errorf("unexpected merge request %+v", mr)
+		}
+	})
+
+	t.Run("MergeChangeset", func(t *testing.T) {
+		gitlab.MockAcceptMergeRequest = func(_ *gitlab.Client, _ context.Context, _ *gitlab.Project, mr *gitlab.MergeRequest, opts gitlab.AcceptMergeRequestOpts) (*gitlab.MergeRequest, error) {
+			if want := (gitlab.AcceptMergeRequestOpts{SHA: "deadbeef", Squash: true}); opts != want {
+				t.Errorf("unexpected opts %+v, want %+v", opts, want)
+			}
+			return &gitlab.MergeRequest{IID: mr.IID, State: gitlab.MergeRequestStateMerged}, nil
+		}
+
+		c := &Changeset{
+			Changeset: &campaigns.Changeset{Metadata: &gitlab.MergeRequest{
+				IID:       6,
+				DiffRefs:  gitlab.DiffRefs{HeadSHA: "deadbeef"},
+				Notes:     notes,
+				Pipelines: pipelines,
+			}},
+			Repo: &Repo{Metadata: project},
+		}
+		if err := src.MergeChangeset(context.Background(), c, campaigns.ChangesetMergeMethodSquash); err != nil {
+			t.Fatal(err)
+		}
+		mr := c.Changeset.Metadata.(*gitlab.MergeRequest)
+		if mr.State != gitlab.MergeRequestStateMerged || len(mr.Notes) != 1 || len(mr.Pipelines) != 1 {
+			t.Errorf("unexpected merge request %+v", mr)
+		}
+	})
+
+	t.Run("CreateDraftChangeset", func(t *testing.T) {
+		gitlab.MockCreateMergeRequest = func(_ *gitlab.Client, _ context.Context, _ *gitlab.Project, opts gitlab.CreateMergeRequestOpts) (*gitlab.MergeRequest, error) {
+			if want := "WIP: Refactor"; opts.Title != want {
+				t.Errorf("unexpected title %q, want %q", opts.Title, want)
+			}
+			return &gitlab.MergeRequest{IID: 7, Title: opts.Title, WorkInProgress: true}, nil
+		}
+
+		c := &Changeset{
+			Title:     "Refactor",
+			HeadRef:   "refs/heads/campaign",
+			BaseRef:   "refs/heads/master",
+			Changeset: &campaigns.Changeset{},
+			Repo:      &Repo{Metadata: project},
+		}
+		exists, err := src.CreateDraftChangeset(context.Background(), c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists {
+			t.Error("unexpected existing merge request")
+		}
+		if mr := c.Changeset.Metadata.(*gitlab.MergeRequest); !mr.WorkInProgress {
+			t.Errorf("unexpected merge request %+v", mr)
+		}
+	})
+
+	t.Run("UpdateChangeset keeps work in progress", func(t *testing.T) {
+		gitlab.MockUpdateMergeRequest = func(_ *gitlab.Client, _ context.Context, _ *gitlab.Project, mr *gitlab.MergeRequest, opts gitlab.UpdateMergeRequestOpts) (*gitlab.MergeRequest, error) {
+			if want := "WIP: Refactor more"; opts.Title != want {
+				t.Errorf("unexpected title %q, want %q", opts.Title, want)
+			}
+			return &gitlab.MergeRequest{IID: mr.IID, Title: opts.Title, WorkInProgress: true}, nil
+		}
+
+		c := &Changeset{
+			Title:     "Refactor more",
+			BaseRef:   "refs/heads/master",
+			Changeset: &campaigns.Changeset{Metadata: &gitlab.MergeRequest{IID: 7, Title: "WIP: Refactor", WorkInProgress: true}},
+			Repo:      &Repo{Metadata: project},
+		}
+		if err := src.UpdateChangeset(context.Background(), c); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("UndraftChangeset", func(t *testing.T) {
+		var updated int
+		gitlab.MockUpdateMergeRequest = func(_ *gitlab.Client, _ context.Context, _ *gitlab.Project, mr *gitlab.MergeRequest, opts gitlab.UpdateMergeRequestOpts) (*gitlab.MergeRequest, error) {
+			updated++
+			if want := "Refactor"; opts.Title != want {
+				t.Errorf("unexpected title %q, want %q", opts.Title, want)
+			}
+			return &gitlab.MergeRequest{IID: mr.IID, Title: opts.Title}, nil
+		}
+
+		c := &Changeset{
+			Changeset: &campaigns.Changeset{Metadata: &gitlab.MergeRequest{IID: 7, Title: "[WIP] WIP: Refactor", WorkInProgress: true}},
+			Repo:      &Repo{Metadata: project},
+		}
+		if err := src.UndraftChangeset(context.Background(), c); err != nil {
+			t.Fatal(err)
+		}
+		if mr := c.Changeset.Metadata.(*gitlab.MergeRequest); mr.Title != "Refactor" {
+			t.Errorf("unexpected merge request %+v", mr)
+		}
+
+		// Merge requests that are ready already aren't updated.
+		if err := src.UndraftChangeset(context.Background(), c); err != nil {
+			t.Fatal(err)
+		}
+		if updated != 1 {
+			t.Errorf("have %d updates, want 1", updated)
+		}
+	})
+
+	t.Run("CreateChangesetComment", func(t *testing.T) {
+		gitlab.MockCreateMergeRequestNote = func(_ *gitlab.Client, _ context.Context, _ *gitlab.Project, iid int, body string) (*gitlab.Note, error) {
+			if iid != 8 || body != "Please review" {
+				t.Errorf("unexpected note %q on merge request %d", body, iid)
+			}
+			return &gitlab.Note{ID: 2, Body: body}, nil
+		}
+
+		c := &Changeset{
+			Changeset: &campaigns.Changeset{Metadata: &gitlab.MergeRequest{IID: 8, Notes: notes}},
+			Repo:      &Repo{Metadata: project},
+		}
+		if err := src.CreateChangesetComment(context.Background(), c, "Please review"); err != nil {
+			t.Fatal(err)
+		}
+		if mr := c.Changeset.Metadata.(*gitlab.MergeRequest); len(mr.Notes) != 2 {
+			t.Errorf("unexpected merge request %+v", mr)
+		}
+	})
+
+	t.Run("AddChangesetLabels and RemoveChangesetLabels", func(t *testing.T) {
+		var have []gitlab.UpdateMergeRequestOpts
+		gitlab.MockUpdateMergeRequest = func(_ *gitlab.Client, _ context.Context, _ *gitlab.Project, mr *gitlab.MergeRequest, opts gitlab.UpdateMergeRequestOpts) (*gitlab.MergeRequest, error) {
+			have = append(have, opts)
+			return &gitlab.MergeRequest{IID: mr.IID}, nil
+		}
+
+		c := &Changeset{
+			Changeset: &campaigns.Changeset{Metadata: &gitlab.MergeRequest{IID: 9}},
+			Repo:      &Repo{Metadata: project},
+		}
+		if err := src.AddChangesetLabels(context.Background(), c, []string{"campaign", "needs review"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := src.RemoveChangesetLabels(context.Background(), c, []string{"stale"}); err != nil {
+			t.Fatal(err)
+		}
+
+		want := []gitlab.UpdateMergeRequestOpts{
+			{AddLabels: "campaign,needs review"},
+			{RemoveLabels: "stale"},
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("have updates %+v, want %+v", have, want)
+		}
+	})
+}
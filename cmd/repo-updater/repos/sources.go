@@ -9,6 +9,7 @@ import (
 
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
 )
 
@@ -104,6 +105,60 @@ type ChangesetSource interface {
 
 	// UpdateChangeset can update Changesets.
 	UpdateChangeset(context.Context, *Changeset) error
+
+	// MergeChangeset merges the Changeset on the source with the given merge
+	// method and updates it to the merged state. Changesets whose head
+	// changed since they were last loaded are not merged, where the code host
+	// supports it.
+	MergeChangeset(context.Context, *Changeset, campaigns.ChangesetMergeMethod) error
+}
+
+// A DraftChangesetSource is a ChangesetSource that can create Changesets as
+// drafts, which are visible on the codehost but not ready to be reviewed or
+// merged yet.
+type DraftChangesetSource interface {
+	ChangesetSource
+
+	// CreateDraftChangeset will create the Changeset as a draft on the
+	// source. If it already exists, *Changeset will be populated and the
+	// return value will be true.
+	CreateDraftChangeset(context.Context, *Changeset) (bool, error)
+	// UndraftChangeset marks the draft Changeset as ready for review on the
+	// source and updates it. Changesets that aren't drafts are left as is.
+	UndraftChangeset(context.Context, *Changeset) error
+}
+
+// A ChangesetCommentSource is a ChangesetSource that can post comments on
+// Changesets.
+type ChangesetCommentSource interface {
+	ChangesetSource
+
+	// CreateChangesetComment posts a comment with the given body on the
+	// Changeset on the source.
+	CreateChangesetComment(ctx context.Context, c *Changeset, body string) error
+}
+
+// A ChangesetLabelSource is a ChangesetSource that can add labels to and
+// remove labels from Changesets.
+type ChangesetLabelSource interface {
+	ChangesetSource
+
+	// AddChangesetLabels adds the labels with the given names to the
+	// Changeset on the source.
+	AddChangesetLabels(ctx context.Context, c *Changeset, labels []string) error
+	// RemoveChangesetLabels removes the labels with the given names from the
+	// Changeset on the source. Labels that aren't set are ignored.
+	RemoveChangesetLabels(ctx context.Context, c *Changeset, labels []string) error
+}
+
+// A ChangesetReviewerSource is a ChangesetSource that can request reviews of
+// Changesets from users.
+type ChangesetReviewerSource interface {
+	ChangesetSource
+
+	// RequestChangesetReviewers requests reviews of the Changeset from the
+	// users with the given usernames on the source.
+	RequestChangesetReviewers(ctx context.Context, c *Changeset, reviewers []string) error
 }
 
 // ChangesetsNotFoundError is returned by LoadChangesets if any of the passed
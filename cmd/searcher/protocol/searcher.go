@@ -2,6 +2,7 @@
 package protocol
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -42,6 +43,12 @@ type Request struct {
 	// The deadline for the search request.
 	// It is parsed with time.Time.UnmarshalText.
 	Deadline string
+
+	// Stream if true will make searcher write each FileMatch as soon as it
+	// is found instead of a single Response once the search is done. The
+	// response body is a sequence of newline-delimited JSON encoded
+	// StreamEvents, the last of which has Done set.
+	Stream bool
 }
 
 // GitserverRepo returns the repository information necessary to perform gitserver requests.
@@ -105,10 +112,87 @@ type PatternInfo struct {
 
 	// CombyRule is a rule that constrains matching for structural search. It only applies when IsStructuralPat is true.
 	CombyRule string
+
+	// PatternExpr if non-nil is a boolean expression of patterns which is
+	// searched for instead of Pattern. Each pattern in the expression is
+	// interpreted like Pattern (ie IsRegExp, IsWordMatch and IsCaseSensitive
+	// apply). It can not be combined with IsStructuralPat. If
+	// PatternMatchesPath is true, each pattern is true for a file if it
+	// matches its path or its content.
+	//
+	// The frontend does not send PatternExpr yet: and/or queries are only
+	// parsed behind the experimental andOrQuery flag, and indexed search
+	// can't evaluate them.
+	PatternExpr *PatternExpr
+}
+
+// Operators of a PatternExpr.
+const (
+	PatternExprAnd = "and"
+	PatternExprOr  = "or"
+	PatternExprNot = "not"
+)
+
+// PatternExpr is a boolean expression of patterns. It is evaluated per file:
+// a pattern is true for a file if it matches the file. LineMatches are
+// returned for every pattern which matches and is not negated.
+//
+// It is sent to searcher JSON encoded.
+type PatternExpr struct {
+	// Op is the operator of an inner node. It is one of PatternExprAnd,
+	// PatternExprOr or PatternExprNot. It is empty for a leaf node.
+	Op string `json:",omitempty"`
+
+	// Operands are the operands of an inner node. A PatternExprNot node has
+	// exactly one operand.
+	Operands []*PatternExpr `json:",omitempty"`
+
+	// Pattern is the pattern of a leaf node.
+	Pattern string `json:",omitempty"`
+}
+
+func (e *PatternExpr) String() string {
+	if e.Op == "" {
+		return fmt.Sprintf("%q", e.Pattern)
+	}
+	operands := make([]string, 0, len(e.Operands))
+	for _, o := range e.Operands {
+		operands = append(operands, o.String())
+	}
+	return fmt.Sprintf("(%s %s)", e.Op, strings.Join(operands, " "))
+}
+
+// MarshalText implements encoding.TextMarshaler, so that a PatternExpr can
+// be sent as a single form value.
+func (e *PatternExpr) MarshalText() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (e *PatternExpr) UnmarshalText(text []byte) error {
+	return json.Unmarshal(text, e)
+}
+
+// MarshalJSON implements json.Marshaler. Without it encoding/json would
+// prefer MarshalText and encode PatternExpr as a string.
+func (e *PatternExpr) MarshalJSON() ([]byte, error) {
+	return json.Marshal((*patternExprJSON)(e))
 }
 
+// UnmarshalJSON implements json.Unmarshaler.
+func (e *PatternExpr) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*patternExprJSON)(e))
+}
+
+// patternExprJSON has the same fields as PatternExpr, but none of its
+// methods.
+type patternExprJSON PatternExpr
+
 func (p *PatternInfo) String() string {
 	args := []string{fmt.Sprintf("%q", p.Pattern)}
+	if p.PatternExpr != nil {
+		args[0] = p.PatternExpr.String()
+	}
 	if p.IsRegExp {
 		args = append(args, "re")
 	}
@@ -166,6 +250,32 @@ type Response struct {
 	DeadlineHit bool
 }
 
+// StreamContentType is the Content-Type of a streaming search response.
+const StreamContentType = "application/x-ndjson"
+
+// StreamEvent is a single value of a streaming search response. Every event
+// but the last one contains a single Match. The last event has Done set and
+// describes how the search finished.
+type StreamEvent struct {
+	Match *FileMatch `json:",omitempty"`
+
+	// Done is true for the last event of the stream.
+	Done bool `json:",omitempty"`
+
+	// LimitHit is true if the stream may not include all FileMatches because
+	// a match limit was hit. Only set on the last event.
+	LimitHit bool `json:",omitempty"`
+
+	// DeadlineHit is true if the stream may not include all FileMatches
+	// because a deadline was hit. Only set on the last event.
+	DeadlineHit bool `json:",omitempty"`
+
+	// Error is set on the last event if the search failed after matches
+	// were already sent. Errors which happen before the first match is sent
+	// are reported with a non-200 status code instead.
+	Error string `json:",omitempty"`
+}
+
 // FileMatch is the struct used by vscode to receive search results
 type FileMatch struct {
 	Path        string
@@ -18,6 +18,7 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/inconshreveable/log15"
@@ -99,22 +100,18 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	matches, limitHit, deadlineHit, err := s.search(ctx, &p)
-	if err != nil {
-		code := http.StatusInternalServerError
-		if isBadRequest(err) || ctx.Err() == context.Canceled {
-			code = http.StatusBadRequest
-		} else if isTemporary(err) {
-			code = http.StatusServiceUnavailable
-		} else {
-			log.Printf("internal error serving %#+v: %s", p, err)
-		}
-		http.Error(w, err.Error(), code)
+	if p.Stream {
+		s.serveStream(ctx, w, &p)
 		return
 	}
-	if matches == nil {
-		// Return an empty list
-		matches = make([]protocol.FileMatch, 0)
+
+	matches := make([]protocol.FileMatch, 0)
+	limitHit, deadlineHit, err := s.search(ctx, &p, func(fm protocol.FileMatch) {
+		matches = append(matches, fm)
+	})
+	if err != nil {
+		writeSearchError(ctx, w, &p, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -130,7 +127,81 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(&resp)
 }
 
-func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []protocol.FileMatch, limitHit, deadlineHit bool, err error) {
+// serveStream writes a streaming response for p. Each FileMatch is written
+// and flushed as soon as it is found. Response headers are only written once
+// the first match is sent, so errors which happen before that (such as
+// failing to fetch the archive) still result in a non-200 status code.
+func (s *Service) serveStream(ctx context.Context, w http.ResponseWriter, p *protocol.Request) {
+	var (
+		enc        = json.NewEncoder(w)
+		flusher, _ = w.(http.Flusher)
+		started    bool
+	)
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		w.Header().Set("Content-Type", protocol.StreamContentType)
+		w.WriteHeader(http.StatusOK)
+	}
+	send := func(ev *protocol.StreamEvent) {
+		start()
+		// As in the non-streaming case the only reasonable error is the
+		// client going away, which cancels ctx and stops the search.
+		_ = enc.Encode(ev)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	limitHit, deadlineHit, err := s.search(ctx, p, func(fm protocol.FileMatch) {
+		send(&protocol.StreamEvent{Match: &fm})
+	})
+	if err != nil && !started {
+		writeSearchError(ctx, w, p, err)
+		return
+	}
+
+	done := &protocol.StreamEvent{
+		Done:        true,
+		LimitHit:    limitHit,
+		DeadlineHit: deadlineHit,
+	}
+	if err != nil {
+		done.Error = err.Error()
+	}
+	send(done)
+}
+
+// writeSearchError writes err returned by search as an HTTP error with the
+// appropriate status code.
+func writeSearchError(ctx context.Context, w http.ResponseWriter, p *protocol.Request, err error) {
+	code := http.StatusInternalServerError
+	if isBadRequest(err) || ctx.Err() == context.Canceled {
+		code = http.StatusBadRequest
+	} else if isTemporary(err) {
+		code = http.StatusServiceUnavailable
+	} else {
+		log.Printf("internal error serving %#+v: %s", p, err)
+	}
+	http.Error(w, err.Error(), code)
+}
+
+// search runs the search described by p, calling send for every FileMatch
+// found. Calls to send are serialized.
+func (s *Service) search(ctx context.Context, p *protocol.Request, send func(protocol.FileMatch)) (limitHit, deadlineHit bool, err error) {
+	var (
+		mu       sync.Mutex // protects nMatches and serializes calls to send
+		nMatches int
+	)
+	countAndSend := func(fm protocol.FileMatch) {
+		mu.Lock()
+		defer mu.Unlock()
+		nMatches++
+		send(fm)
+	}
+
 	tr := trace.New("search", fmt.Sprintf("%s@%s", p.Repo, p.Commit))
 	tr.LazyPrintf("%s", p.Pattern)
 
@@ -140,6 +211,9 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 	span.SetTag("url", p.URL)
 	span.SetTag("commit", p.Commit)
 	span.SetTag("pattern", p.Pattern)
+	if p.PatternExpr != nil {
+		span.SetTag("patternExpr", p.PatternExpr.String())
+	}
 	span.SetTag("isRegExp", strconv.FormatBool(p.IsRegExp))
 	span.SetTag("isStructuralPat", strconv.FormatBool(p.IsStructuralPat))
 	span.SetTag("languages", p.Languages)
@@ -151,6 +225,7 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 	span.SetTag("patternMatchesContent", p.PatternMatchesContent)
 	span.SetTag("patternMatchesPath", p.PatternMatchesPath)
 	span.SetTag("deadline", p.Deadline)
+	span.SetTag("stream", p.Stream)
 	defer func(start time.Time) {
 		code := "200"
 		// We often have canceled and timed out requests. We do not want to
@@ -176,21 +251,21 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 				code = "500"
 			}
 		}
-		tr.LazyPrintf("code=%s matches=%d limitHit=%v deadlineHit=%v", code, len(matches), limitHit, deadlineHit)
+		tr.LazyPrintf("code=%s matches=%d limitHit=%v deadlineHit=%v", code, nMatches, limitHit, deadlineHit)
 		tr.Finish()
 		requestTotal.WithLabelValues(code).Inc()
-		span.LogFields(otlog.Int("matches.len", len(matches)))
+		span.LogFields(otlog.Int("matches.len", nMatches))
 		span.SetTag("limitHit", limitHit)
 		span.SetTag("deadlineHit", deadlineHit)
 		span.Finish()
 		if s.Log != nil {
-			s.Log.Debug("search request", "repo", p.Repo, "commit", p.Commit, "pattern", p.Pattern, "isRegExp", p.IsRegExp, "isStructuralPat", p.IsStructuralPat, "languages", p.Languages, "isWordMatch", p.IsWordMatch, "isCaseSensitive", p.IsCaseSensitive, "patternMatchesContent", p.PatternMatchesContent, "patternMatchesPath", p.PatternMatchesPath, "matches", len(matches), "code", code, "duration", time.Since(start), "err", err)
+			s.Log.Debug("search request", "repo", p.Repo, "commit", p.Commit, "pattern", p.Pattern, "isRegExp", p.IsRegExp, "isStructuralPat", p.IsStructuralPat, "languages", p.Languages, "isWordMatch", p.IsWordMatch, "isCaseSensitive", p.IsCaseSensitive, "patternMatchesContent", p.PatternMatchesContent, "patternMatchesPath", p.PatternMatchesPath, "stream", p.Stream, "matches", nMatches, "code", code, "duration", time.Since(start), "err", err)
 		}
 	}(time.Now())
 
 	rg, err := compile(&p.PatternInfo)
 	if err != nil {
-		return nil, false, false, badRequestError{err.Error()}
+		return false, false, badRequestError{err.Error()}
 	}
 
 	if p.FetchTimeout == "" {
@@ -198,7 +273,7 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 	}
 	fetchTimeout, err := time.ParseDuration(p.FetchTimeout)
 	if err != nil {
-		return nil, false, false, err
+		return false, false, err
 	}
 	prepareCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
 	defer cancel()
@@ -214,7 +289,7 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 
 	zipPath, zf, err := store.GetZipFileWithRetry(getZf)
 	if err != nil {
-		return nil, false, false, errors.Wrap(err, "failed to get archive")
+		return false, false, errors.Wrap(err, "failed to get archive")
 	}
 	defer zf.Close()
 
@@ -228,11 +303,17 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 	archiveSize.Observe(float64(bytes))
 
 	if p.IsStructuralPat {
+		// comby reports all matches at once, so structural matches are only
+		// sent once the whole archive has been searched.
+		var matches []protocol.FileMatch
 		matches, limitHit, err = structuralSearch(ctx, zipPath, p.Pattern, p.CombyRule, p.Languages, p.IncludePatterns, p.Repo)
+		for _, fm := range matches {
+			countAndSend(fm)
+		}
 	} else {
-		matches, limitHit, err = regexSearch(ctx, rg, zf, p.FileMatchLimit, p.PatternMatchesContent, p.PatternMatchesPath)
+		limitHit, err = regexSearchStream(ctx, rg, zf, p.FileMatchLimit, p.PatternMatchesContent, p.PatternMatchesPath, countAndSend)
 	}
-	return matches, limitHit, false, err
+	return limitHit, false, err
 }
 
 func validateParams(p *protocol.Request) error {
@@ -243,7 +324,7 @@ func validateParams(p *protocol.Request) error {
 	if len(p.Commit) != 40 {
 		return errors.Errorf("Commit must be resolved (Commit=%q)", p.Commit)
 	}
-	if p.Pattern == "" && p.ExcludePattern == "" && len(p.IncludePatterns) == 0 {
+	if p.Pattern == "" && p.PatternExpr == nil && p.ExcludePattern == "" && len(p.IncludePatterns) == 0 {
 		return errors.New("At least one of pattern and include/exclude pattners must be non-empty")
 	}
 	return nil
@@ -0,0 +1,213 @@
+package search
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/store"
+)
+
+// exprGrep evaluates a protocol.PatternExpr against files. Every leaf of the
+// expression is matched by its own readerGrep, so like readerGrep it is not
+// concurrency safe.
+type exprGrep struct {
+	// op is the operator of an inner node, or empty for a leaf.
+	op string
+
+	// operands are the operands of an inner node.
+	operands []*exprGrep
+
+	// rg matches the pattern of a leaf node.
+	rg *readerGrep
+}
+
+// compileExpr returns an exprGrep for evaluating e. Every pattern in e is
+// compiled with the options in p.
+func compileExpr(p *protocol.PatternInfo, e *protocol.PatternExpr) (*exprGrep, error) {
+	switch e.Op {
+	case "":
+		if e.Pattern == "" {
+			return nil, errors.New("pattern expression contains an empty pattern")
+		}
+		if len(e.Operands) > 0 {
+			return nil, errors.Errorf("pattern %q can not have operands", e.Pattern)
+		}
+		leaf := *p
+		leaf.Pattern = e.Pattern
+		leaf.PatternExpr = nil
+		rg, err := compile(&leaf)
+		if err != nil {
+			return nil, err
+		}
+		return &exprGrep{rg: rg}, nil
+
+	case protocol.PatternExprNot:
+		if len(e.Operands) != 1 {
+			return nil, errors.Errorf("%s expects exactly one operand, got %d", e.Op, len(e.Operands))
+		}
+
+	case protocol.PatternExprAnd, protocol.PatternExprOr:
+		if len(e.Operands) == 0 {
+			return nil, errors.Errorf("%s expects at least one operand", e.Op)
+		}
+
+	default:
+		return nil, errors.Errorf("unknown pattern expression operator %q", e.Op)
+	}
+
+	eg := &exprGrep{op: e.Op}
+	for _, o := range e.Operands {
+		og, err := compileExpr(p, o)
+		if err != nil {
+			return nil, err
+		}
+		eg.operands = append(eg.operands, og)
+	}
+	return eg, nil
+}
+
+// Copy returns a copied version of eg that is safe to use from another
+// goroutine.
+func (eg *exprGrep) Copy() *exprGrep {
+	if eg.rg != nil {
+		return &exprGrep{rg: eg.rg.Copy()}
+	}
+	c := &exprGrep{op: eg.op, operands: make([]*exprGrep, len(eg.operands))}
+	for i, o := range eg.operands {
+		c.operands[i] = o.Copy()
+	}
+	return c
+}
+
+// matchString returns whether eg evaluates to true when each pattern is
+// matched against s. It is intended to be used to match file paths.
+func (eg *exprGrep) matchString(s string) bool {
+	switch eg.op {
+	case "":
+		return eg.rg.matchString(s)
+	case protocol.PatternExprNot:
+		return !eg.operands[0].matchString(s)
+	case protocol.PatternExprAnd:
+		for _, o := range eg.operands {
+			if !o.matchString(s) {
+				return false
+			}
+		}
+		return true
+	default: // protocol.PatternExprOr
+		for _, o := range eg.operands {
+			if o.matchString(s) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Find evaluates eg against the content of f. If matchPaths is true, each
+// pattern is also matched against the path of f, and is true if it matches
+// either. match reports whether f satisfies eg. If it does, matches contains
+// the LineMatches of every pattern which matched the content of f and is not
+// negated, merged per line.
+func (eg *exprGrep) Find(zf *store.ZipFile, f *store.SrcFile, matchPaths bool) (matches []protocol.LineMatch, match, limitHit bool, err error) {
+	matches, match, limitHit, err = eg.find(zf, f, matchPaths)
+	if err != nil || !match {
+		return nil, false, false, err
+	}
+	matches = mergeLineMatches(matches)
+	if len(matches) > maxLineMatches {
+		matches = matches[:maxLineMatches]
+		limitHit = true
+	}
+	return matches, true, limitHit, nil
+}
+
+func (eg *exprGrep) find(zf *store.ZipFile, f *store.SrcFile, matchPaths bool) (matches []protocol.LineMatch, match, limitHit bool, err error) {
+	switch eg.op {
+	case "":
+		matches, limitHit, err = eg.rg.Find(zf, f)
+		if err != nil {
+			return nil, false, false, err
+		}
+		match = len(matches) > 0 || (matchPaths && eg.rg.matchString(f.Name))
+		return matches, match, limitHit, nil
+
+	case protocol.PatternExprNot:
+		// The line matches of a negated pattern are not interesting since
+		// they are the reason a file is excluded.
+		_, match, _, err = eg.operands[0].find(zf, f, matchPaths)
+		return nil, !match, false, err
+
+	case protocol.PatternExprAnd:
+		for _, o := range eg.operands {
+			lm, ok, lh, err := o.find(zf, f, matchPaths)
+			if err != nil || !ok {
+				return nil, false, false, err
+			}
+			matches = append(matches, lm...)
+			limitHit = limitHit || lh
+		}
+		return matches, true, limitHit, nil
+
+	default: // protocol.PatternExprOr
+		// Evaluate every operand, even once one is true, so that we return
+		// the line matches of all matching patterns.
+		for _, o := range eg.operands {
+			lm, ok, lh, err := o.find(zf, f, matchPaths)
+			if err != nil {
+				return nil, false, false, err
+			}
+			if ok {
+				matches = append(matches, lm...)
+				limitHit = limitHit || lh
+				match = true
+			}
+		}
+		return matches, match, limitHit, nil
+	}
+}
+
+// mergeLineMatches combines LineMatches for the same line, as found by
+// different patterns, into one and sorts them by line number.
+func mergeLineMatches(matches []protocol.LineMatch) []protocol.LineMatch {
+	if len(matches) < 2 {
+		return matches
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].LineNumber < matches[j].LineNumber
+	})
+
+	merged := matches[:1]
+	for _, m := range matches[1:] {
+		last := &merged[len(merged)-1]
+		if m.LineNumber != last.LineNumber {
+			merged = append(merged, m)
+			continue
+		}
+		last.OffsetAndLengths = append(last.OffsetAndLengths, m.OffsetAndLengths...)
+		last.LimitHit = last.LimitHit || m.LimitHit
+	}
+
+	for i := range merged {
+		ol := merged[i].OffsetAndLengths
+		sort.Slice(ol, func(i, j int) bool {
+			if ol[i][0] != ol[j][0] {
+				return ol[i][0] < ol[j][0]
+			}
+			return ol[i][1] < ol[j][1]
+		})
+		// Remove ranges found by more than one pattern.
+		deduped := ol[:0]
+		for j, r := range ol {
+			if j > 0 && r == ol[j-1] {
+				continue
+			}
+			deduped = append(deduped, r)
+		}
+		merged[i].OffsetAndLengths = deduped
+	}
+	return merged
+}
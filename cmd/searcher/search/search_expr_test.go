@@ -0,0 +1,81 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+)
+
+func TestCompileExpr_invalid(t *testing.T) {
+	cases := map[string]*protocol.PatternExpr{
+		"empty pattern": {Pattern: ""},
+		"leaf with operands": {
+			Pattern:  "foo",
+			Operands: []*protocol.PatternExpr{{Pattern: "bar"}},
+		},
+		"not with two operands": {
+			Op:       protocol.PatternExprNot,
+			Operands: []*protocol.PatternExpr{{Pattern: "foo"}, {Pattern: "bar"}},
+		},
+		"and without operands": {Op: protocol.PatternExprAnd},
+		"unknown operator":     {Op: "xor", Operands: []*protocol.PatternExpr{{Pattern: "foo"}}},
+		"bad regexp": {
+			Op:       protocol.PatternExprOr,
+			Operands: []*protocol.PatternExpr{{Pattern: "foo"}, {Pattern: "(bar"}},
+		},
+	}
+	for name, e := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := compile(&protocol.PatternInfo{PatternExpr: e, IsRegExp: true}); err == nil {
+				t.Fatalf("expected %s to fail to compile", e)
+			}
+		})
+	}
+
+	_, err := compile(&protocol.PatternInfo{
+		PatternExpr:     &protocol.PatternExpr{Pattern: "foo"},
+		IsStructuralPat: true,
+	})
+	if err == nil {
+		t.Fatal("expected pattern expression with structural search to fail to compile")
+	}
+}
+
+func TestExprGrep_matchString(t *testing.T) {
+	rg, err := compile(&protocol.PatternInfo{PatternExpr: &protocol.PatternExpr{
+		Op: protocol.PatternExprAnd,
+		Operands: []*protocol.PatternExpr{
+			{Pattern: "foo"},
+			{Op: protocol.PatternExprNot, Operands: []*protocol.PatternExpr{{Pattern: "_test"}}},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for s, want := range map[string]bool{
+		"foo.go":      true,
+		"FOO.go":      true,
+		"foo_test.go": false,
+		"bar.go":      false,
+	} {
+		if got := rg.matchString(s); got != want {
+			t.Errorf("matchString(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestMergeLineMatches(t *testing.T) {
+	got := mergeLineMatches([]protocol.LineMatch{
+		{LineNumber: 3, Preview: "foo bar", OffsetAndLengths: [][2]int{{4, 3}}},
+		{LineNumber: 1, Preview: "bar", OffsetAndLengths: [][2]int{{0, 3}}},
+		{LineNumber: 3, Preview: "foo bar", OffsetAndLengths: [][2]int{{0, 3}, {4, 3}}, LimitHit: true},
+	})
+	want := []protocol.LineMatch{
+		{LineNumber: 1, Preview: "bar", OffsetAndLengths: [][2]int{{0, 3}}},
+		{LineNumber: 3, Preview: "foo bar", OffsetAndLengths: [][2]int{{0, 3}, {4, 3}}, LimitHit: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -43,6 +43,10 @@ type readerGrep struct {
 	// re is the regexp to match, or nil if empty ("match all files' content").
 	re *regexp.Regexp
 
+	// expr if non-nil is a boolean expression of patterns which is matched
+	// instead of re. re is nil if expr is set.
+	expr *exprGrep
+
 	// ignoreCase if true means we need to do case insensitive matching.
 	ignoreCase bool
 
@@ -67,9 +71,19 @@ type readerGrep struct {
 func compile(p *protocol.PatternInfo) (*readerGrep, error) {
 	var (
 		re               *regexp.Regexp
+		expr             *exprGrep
 		literalSubstring []byte
 	)
-	if p.Pattern != "" {
+	if p.PatternExpr != nil {
+		if p.IsStructuralPat {
+			return nil, errors.New("pattern expressions are not supported for structural search")
+		}
+		var err error
+		expr, err = compileExpr(p, p.PatternExpr)
+		if err != nil {
+			return nil, err
+		}
+	} else if p.Pattern != "" {
 		expr := p.Pattern
 		if !p.IsRegExp {
 			expr = regexp.QuoteMeta(expr)
@@ -123,6 +137,7 @@ func compile(p *protocol.PatternInfo) (*readerGrep, error) {
 
 	return &readerGrep{
 		re:               re,
+		expr:             expr,
 		ignoreCase:       !p.IsCaseSensitive,
 		matchPath:        matchPath,
 		literalSubstring: literalSubstring,
@@ -132,8 +147,13 @@ func compile(p *protocol.PatternInfo) (*readerGrep, error) {
 // Copy returns a copied version of rg that is safe to use from another
 // goroutine.
 func (rg *readerGrep) Copy() *readerGrep {
+	var expr *exprGrep
+	if rg.expr != nil {
+		expr = rg.expr.Copy()
+	}
 	return &readerGrep{
 		re:               rg.re,
+		expr:             expr,
 		ignoreCase:       rg.ignoreCase,
 		matchPath:        rg.matchPath,
 		literalSubstring: rg.literalSubstring,
@@ -143,6 +163,9 @@ func (rg *readerGrep) Copy() *readerGrep {
 // matchString returns whether rg's regexp pattern matches s. It is intended to be
 // used to match file paths.
 func (rg *readerGrep) matchString(s string) bool {
+	if rg.expr != nil {
+		return rg.expr.matchString(s)
+	}
 	if rg.re == nil {
 		return true
 	}
@@ -283,18 +306,51 @@ func appendMatches(matches []protocol.LineMatch, fileBuf []byte, matchLineBuf []
 	return matches
 }
 
-// FindZip is a convenience function to run Find on f.
-func (rg *readerGrep) FindZip(zf *store.ZipFile, f *store.SrcFile) (protocol.FileMatch, error) {
-	lm, limitHit, err := rg.Find(zf, f)
+// FindZip is a convenience function to run Find on f. match reports whether
+// the content of f matches, or if matchPaths is true, whether its path does.
+func (rg *readerGrep) FindZip(zf *store.ZipFile, f *store.SrcFile, matchPaths bool) (fm protocol.FileMatch, match bool, err error) {
+	var (
+		lm       []protocol.LineMatch
+		limitHit bool
+	)
+	if rg.expr != nil {
+		// An expression can match a file without any line matches, for
+		// example "not foo". Every pattern of the expression is matched
+		// against the path and the content, so that "not foo" excludes
+		// files which contain foo in either.
+		lm, match, limitHit, err = rg.expr.Find(zf, f, matchPaths)
+	} else {
+		lm, limitHit, err = rg.Find(zf, f)
+		match = len(lm) > 0
+		if !match && matchPaths {
+			// Try matching against the file path.
+			match = rg.matchString(f.Name)
+		}
+	}
 	return protocol.FileMatch{
 		Path:        f.Name,
 		LineMatches: lm,
 		LimitHit:    limitHit,
-	}, err
+	}, match, err
 }
 
 // regexSearch concurrently searches files in zr looking for matches using rg.
 func regexSearch(ctx context.Context, rg *readerGrep, zf *store.ZipFile, fileMatchLimit int, patternMatchesContent, patternMatchesPaths bool) (fm []protocol.FileMatch, limitHit bool, err error) {
+	var mu sync.Mutex // protects matches
+	matches := []protocol.FileMatch{}
+	limitHit, err = regexSearchStream(ctx, rg, zf, fileMatchLimit, patternMatchesContent, patternMatchesPaths, func(fm protocol.FileMatch) {
+		mu.Lock()
+		matches = append(matches, fm)
+		mu.Unlock()
+	})
+	return matches, limitHit, err
+}
+
+// regexSearchStream is like regexSearch, but calls send for each FileMatch as
+// soon as it is found instead of collecting them. send is called at most
+// fileMatchLimit times and may be called concurrently, so a slow send only
+// holds up the worker that found the match.
+func regexSearchStream(ctx context.Context, rg *readerGrep, zf *store.ZipFile, fileMatchLimit int, patternMatchesContent, patternMatchesPaths bool, send func(protocol.FileMatch)) (limitHit bool, err error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "RegexSearch")
 	ext.Component.Set(span, "regex_search")
 	if rg.re != nil {
@@ -332,24 +388,25 @@ func regexSearch(ctx context.Context, rg *readerGrep, zf *store.ZipFile, fileMat
 	var (
 		filesmu   sync.Mutex // protects files
 		files     = zf.Files
-		matchesmu sync.Mutex // protects matches, limitHit
-		matches   = []protocol.FileMatch{}
+		matchesmu sync.Mutex // protects nMatches and limitHit
+		nMatches  int
 	)
 
-	if rg.re == nil || (patternMatchesPaths && !patternMatchesContent) {
+	if (rg.re == nil && rg.expr == nil) || (patternMatchesPaths && !patternMatchesContent) {
 		// Fast path for only matching file paths (or with a nil pattern, which matches all files,
 		// so is effectively matching only on file paths).
 		for _, f := range files {
 			if rg.matchPath.MatchPath(f.Name) && rg.matchString(f.Name) {
-				if len(matches) < fileMatchLimit {
-					matches = append(matches, protocol.FileMatch{Path: f.Name})
+				if nMatches < fileMatchLimit {
+					nMatches++
+					send(protocol.FileMatch{Path: f.Name})
 				} else {
 					limitHit = true
 					break
 				}
 			}
 		}
-		return matches, limitHit, nil
+		return limitHit, nil
 	}
 
 	var (
@@ -393,8 +450,7 @@ func regexSearch(ctx context.Context, rg *readerGrep, zf *store.ZipFile, fileMat
 				atomic.AddUint32(&filesSearched, 1)
 
 				// process
-				var fm protocol.FileMatch
-				fm, err := rg.FindZip(zf, f)
+				fm, match, err := rg.FindZip(zf, f, patternMatchesPaths)
 				if err != nil {
 					wgErrOnce.Do(func() {
 						wgErr = err
@@ -402,23 +458,21 @@ func regexSearch(ctx context.Context, rg *readerGrep, zf *store.ZipFile, fileMat
 					})
 					return
 				}
-				match := len(fm.LineMatches) > 0
-				if !match && patternMatchesPaths {
-					// Try matching against the file path.
-					match = rg.matchString(f.Name)
-					if match {
-						fm.Path = f.Name
-					}
-				}
 				if match {
+					// Reserve a slot under the lock, but send outside of
+					// it so other workers aren't blocked on send.
 					matchesmu.Lock()
-					if len(matches) < fileMatchLimit {
-						matches = append(matches, fm)
+					ok := nMatches < fileMatchLimit
+					if ok {
+						nMatches++
 					} else {
 						limitHit = true
 						cancel()
 					}
 					matchesmu.Unlock()
+					if ok {
+						send(fm)
+					}
 				}
 			}
 		}(rg.Copy())
@@ -437,7 +491,7 @@ func regexSearch(ctx context.Context, rg *readerGrep, zf *store.ZipFile, fileMat
 		otlog.Int("filesSearched", int(atomic.LoadUint32(&filesSearched))),
 	)
 
-	return matches, limitHit, err
+	return limitHit, err
 }
 
 // lowerRegexpASCII lowers rune literals and expands char classes to include
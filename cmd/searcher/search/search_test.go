@@ -180,6 +180,75 @@ main.go:7:}
 `},
 
 		{protocol.PatternInfo{Pattern: "^$", IsRegExp: true}, ``},
+
+		{protocol.PatternInfo{PatternExpr: &protocol.PatternExpr{
+			Op: protocol.PatternExprAnd,
+			Operands: []*protocol.PatternExpr{
+				{Pattern: "world"},
+				{Op: protocol.PatternExprNot, Operands: []*protocol.PatternExpr{{Pattern: "fmt"}}},
+			},
+		}}, `
+README.md:1:# Hello World
+README.md:3:Hello world example in go
+`},
+
+		{protocol.PatternInfo{PatternExpr: &protocol.PatternExpr{
+			Op: protocol.PatternExprOr,
+			Operands: []*protocol.PatternExpr{
+				{Pattern: "example"},
+				{Pattern: "^func"},
+			},
+		}, IsRegExp: true}, `
+README.md:3:Hello world example in go
+main.go:5:func main() {
+`},
+
+		{protocol.PatternInfo{PatternExpr: &protocol.PatternExpr{
+			Op: protocol.PatternExprAnd,
+			Operands: []*protocol.PatternExpr{
+				{Pattern: "import"},
+				{Pattern: "fmt"},
+			},
+		}}, `
+main.go:3:import "fmt"
+main.go:6:	fmt.Println("Hello world")
+`},
+
+		{protocol.PatternInfo{PatternExpr: &protocol.PatternExpr{
+			Op:       protocol.PatternExprNot,
+			Operands: []*protocol.PatternExpr{{Pattern: "hello"}},
+		}}, `
+abc.txt
+milton.png
+`},
+
+		{protocol.PatternInfo{PatternExpr: &protocol.PatternExpr{
+			Op:       protocol.PatternExprNot,
+			Operands: []*protocol.PatternExpr{{Pattern: "fmt"}},
+		}, PatternMatchesPath: true, PatternMatchesContent: true}, `
+README.md
+abc.txt
+milton.png
+`},
+
+		{protocol.PatternInfo{PatternExpr: &protocol.PatternExpr{
+			Op:       protocol.PatternExprNot,
+			Operands: []*protocol.PatternExpr{{Pattern: "main"}},
+		}, PatternMatchesPath: true, PatternMatchesContent: true}, `
+README.md
+abc.txt
+milton.png
+`},
+
+		{protocol.PatternInfo{PatternExpr: &protocol.PatternExpr{
+			Op: protocol.PatternExprAnd,
+			Operands: []*protocol.PatternExpr{
+				{Pattern: "readme"},
+				{Pattern: "example"},
+			},
+		}, PatternMatchesPath: true, PatternMatchesContent: true}, `
+README.md:3:Hello world example in go
+`},
 	}
 
 	store, cleanup, err := newStore(files)
@@ -190,38 +259,41 @@ main.go:7:}
 	ts := httptest.NewServer(&search.Service{Store: store})
 	defer ts.Close()
 
-	for i, test := range cases {
-		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			test.arg.PatternMatchesContent = true
-			req := protocol.Request{
-				Repo:         "foo",
-				URL:          "u",
-				Commit:       "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
-				PatternInfo:  test.arg,
-				FetchTimeout: "2000ms",
-			}
-			m, err := doSearch(ts.URL, &req)
-			if err != nil {
-				t.Fatalf("%v failed: %s", test.arg, err)
-			}
-			sort.Sort(sortByPath(m))
-			got := toString(m)
-			err = sanityCheckSorted(m)
-			if err != nil {
-				t.Fatalf("%v malformed response: %s\n%s", test.arg, err, got)
-			}
-			// We have an extra newline to make expected readable
-			if len(test.want) > 0 {
-				test.want = test.want[1:]
-			}
-			if got != test.want {
-				d, err := testutil.Diff(test.want, got)
+	for _, stream := range []bool{false, true} {
+		for i, test := range cases {
+			t.Run(fmt.Sprintf("%d/stream=%v", i, stream), func(t *testing.T) {
+				test.arg.PatternMatchesContent = true
+				req := protocol.Request{
+					Repo:         "foo",
+					URL:          "u",
+					Commit:       "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
+					PatternInfo:  test.arg,
+					FetchTimeout: "2000ms",
+					Stream:       stream,
+				}
+				m, err := doSearch(ts.URL, &req)
 				if err != nil {
-					t.Fatal(err)
+					t.Fatalf("%v failed: %s", test.arg, err)
 				}
-				t.Fatalf("%s unexpected response:\n%s", test.arg.String(), d)
-			}
-		})
+				sort.Sort(sortByPath(m))
+				got := toString(m)
+				err = sanityCheckSorted(m)
+				if err != nil {
+					t.Fatalf("%v malformed response: %s\n%s", test.arg, err, got)
+				}
+				// We have an extra newline to make expected readable
+				if len(test.want) > 0 {
+					test.want = test.want[1:]
+				}
+				if got != test.want {
+					d, err := testutil.Diff(test.want, got)
+					if err != nil {
+						t.Fatal(err)
+					}
+					t.Fatalf("%s unexpected response:\n%s", test.arg.String(), d)
+				}
+			})
+		}
 	}
 }
 
@@ -378,6 +450,16 @@ func doSearch(u string, p *protocol.Request) ([]protocol.FileMatch, error) {
 	if p.PatternMatchesPath {
 		form.Set("PatternMatchesPath", "true")
 	}
+	if p.PatternExpr != nil {
+		b, err := p.PatternExpr.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		form.Set("PatternExpr", string(b))
+	}
+	if p.Stream {
+		form.Set("Stream", "true")
+	}
 	resp, err := http.PostForm(u, form)
 	if err != nil {
 		return nil, err
@@ -391,6 +473,27 @@ func doSearch(u string, p *protocol.Request) ([]protocol.FileMatch, error) {
 		return nil, fmt.Errorf("non-200 response: code=%d body=%s", resp.StatusCode, string(body))
 	}
 
+	if p.Stream {
+		if ct := resp.Header.Get("Content-Type"); ct != protocol.StreamContentType {
+			return nil, fmt.Errorf("unexpected Content-Type %q for streaming response", ct)
+		}
+		matches := []protocol.FileMatch{}
+		dec := json.NewDecoder(bytes.NewReader(body))
+		for {
+			var ev protocol.StreamEvent
+			if err := dec.Decode(&ev); err != nil {
+				return nil, fmt.Errorf("stream ended without done event: %s", err)
+			}
+			if ev.Done {
+				if ev.Error != "" {
+					return nil, errors.New(ev.Error)
+				}
+				return matches, nil
+			}
+			matches = append(matches, *ev.Match)
+		}
+	}
+
 	var r protocol.Response
 	err = json.Unmarshal(body, &r)
 	if err != nil {
@@ -0,0 +1,216 @@
+// Package goparser extracts symbols from Go files with go/parser. Unlike
+// ctags it knows the receiver of every method and the full signature of
+// functions.
+package goparser
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/cmd/symbols/internal/pkg/ctags"
+)
+
+// The kinds of symbols. They are the same as the kinds universal-ctags
+// reports for Go, so that results don't depend on which parser was used.
+const (
+	kindPackage    = "package"
+	kindFunc       = "func"
+	kindConst      = "const"
+	kindVar        = "var"
+	kindType       = "type"
+	kindStruct     = "struct"
+	kindInterface  = "interface"
+	kindMember     = "member"
+	kindMethodSpec = "methodSpec"
+)
+
+const language = "Go"
+
+// NewParser returns a ctags.Parser for Go files. The returned parser is cheap
+// and does not need to be pooled, but like any ctags.Parser it is not safe
+// for concurrent use.
+func NewParser() (ctags.Parser, error) {
+	return &goParser{}, nil
+}
+
+type goParser struct{}
+
+func (*goParser) Close() {}
+
+// Parse returns the top-level declarations, struct fields and interface
+// methods in content. It returns an error if content is not valid Go.
+func (*goParser) Parse(path string, content []byte) ([]ctags.Entry, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, content, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &extractor{
+		fset:    fset,
+		path:    path,
+		content: content,
+		pkg:     f.Name.Name,
+	}
+	e.add(f.Name, kindPackage, "", "", "")
+
+	// Methods can be declared before their receiver type, so we need the kind
+	// of every type in the file before we visit function declarations.
+	typeKinds := map[string]string{}
+	for _, decl := range f.Decls {
+		if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.TYPE {
+			for _, spec := range d.Specs {
+				ts := spec.(*ast.TypeSpec)
+				typeKinds[ts.Name.Name] = typeKind(ts)
+			}
+		}
+	}
+
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			e.addFunc(d, typeKinds)
+		case *ast.GenDecl:
+			e.addGenDecl(d)
+		}
+	}
+	return e.entries, nil
+}
+
+type extractor struct {
+	fset    *token.FileSet
+	path    string
+	content []byte
+	pkg     string
+	entries []ctags.Entry
+}
+
+func (e *extractor) addFunc(d *ast.FuncDecl, typeKinds map[string]string) {
+	parent, parentKind := e.pkg, kindPackage
+	if d.Recv != nil && len(d.Recv.List) > 0 {
+		if recv := receiverTypeName(d.Recv.List[0].Type); recv != "" {
+			parent, parentKind = recv, typeKinds[recv]
+			if parentKind == "" {
+				// The receiver is declared in another file of the package.
+				parentKind = kindType
+			}
+		}
+	}
+	e.add(d.Name, kindFunc, parent, parentKind, e.signature(d.Type))
+}
+
+func (e *extractor) addGenDecl(d *ast.GenDecl) {
+	for _, spec := range d.Specs {
+		switch s := spec.(type) {
+		case *ast.ValueSpec:
+			kind := kindVar
+			if d.Tok == token.CONST {
+				kind = kindConst
+			}
+			for _, name := range s.Names {
+				e.add(name, kind, e.pkg, kindPackage, "")
+			}
+
+		case *ast.TypeSpec:
+			kind := typeKind(s)
+			e.add(s.Name, kind, e.pkg, kindPackage, "")
+			switch t := s.Type.(type) {
+			case *ast.StructType:
+				for _, field := range t.Fields.List {
+					for _, name := range field.Names {
+						e.add(name, kindMember, s.Name.Name, kind, "")
+					}
+				}
+			case *ast.InterfaceType:
+				for _, method := range t.Methods.List {
+					ft, ok := method.Type.(*ast.FuncType)
+					if !ok {
+						// Embedded interface.
+						continue
+					}
+					for _, name := range method.Names {
+						e.add(name, kindMethodSpec, s.Name.Name, kind, e.signature(ft))
+					}
+				}
+			}
+		}
+	}
+}
+
+func (e *extractor) add(name *ast.Ident, kind, parent, parentKind, signature string) {
+	if name.Name == "_" {
+		return
+	}
+	pos := e.fset.Position(name.Pos())
+	e.entries = append(e.entries, ctags.Entry{
+		Name:       name.Name,
+		Path:       e.path,
+		Line:       pos.Line,
+		Kind:       kind,
+		Language:   language,
+		Parent:     parent,
+		ParentKind: parentKind,
+		Pattern:    e.pattern(pos.Offset),
+		Signature:  signature,
+
+		// Like ctags, never report Go symbols as file limited: unexported
+		// identifiers are visible in their whole package.
+	})
+}
+
+// signature returns the parameters and results of a function, e.g.
+// "(path string, content []byte) ([]Entry, error)".
+func (e *extractor) signature(ft *ast.FuncType) string {
+	var buf bytes.Buffer
+	// Print a function type without the func keyword, which is how
+	// universal-ctags reports signatures.
+	_ = printer.Fprint(&buf, e.fset, ft)
+	return strings.TrimPrefix(buf.String(), "func")
+}
+
+// pattern returns a ctags search pattern which matches the line containing
+// offset.
+func (e *extractor) pattern(offset int) string {
+	start := bytes.LastIndexByte(e.content[:offset], '\n') + 1
+	end := bytes.IndexByte(e.content[offset:], '\n')
+	if end < 0 {
+		end = len(e.content)
+	} else {
+		end += offset
+	}
+	line := strings.TrimSuffix(string(e.content[start:end]), "\r")
+	line = strings.NewReplacer(`\`, `\\`, `/`, `\/`).Replace(line)
+	return "/^" + line + "$/"
+}
+
+func typeKind(ts *ast.TypeSpec) string {
+	switch ts.Type.(type) {
+	case *ast.StructType:
+		return kindStruct
+	case *ast.InterfaceType:
+		return kindInterface
+	default:
+		return kindType
+	}
+}
+
+// receiverTypeName returns the name of the type of a method receiver, e.g.
+// "T" for "*T".
+func receiverTypeName(expr ast.Expr) string {
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			expr = t.X
+		case *ast.ParenExpr:
+			expr = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package goparser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/symbols/internal/pkg/ctags"
+)
+
+func TestParser(t *testing.T) {
+	src := `package foo
+
+const Max = 1
+
+var _, cache = 1, map[string]string{}
+
+type Reader interface {
+	Read(p []byte) (n int, err error)
+	io.Closer
+}
+
+func (s *server) Close() error { return nil }
+
+type server struct {
+	Addr string
+}
+
+func New(addr string) *server { return &server{Addr: addr} }
+`
+	p, err := NewParser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	got, err := p.Parse("a/foo.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := func(name string, line int, kind, parent, parentKind, pattern, signature string, fileLimited bool) ctags.Entry {
+		return ctags.Entry{
+			Name:        name,
+			Path:        "a/foo.go",
+			Line:        line,
+			Kind:        kind,
+			Language:    "Go",
+			Parent:      parent,
+			ParentKind:  parentKind,
+			Pattern:     pattern,
+			Signature:   signature,
+			FileLimited: fileLimited,
+		}
+	}
+	want := []ctags.Entry{
+		entry("foo", 1, "package", "", "", "/^package foo$/", "", false),
+		entry("Max", 3, "const", "foo", "package", "/^const Max = 1$/", "", false),
+		entry("cache", 5, "var", "foo", "package", "/^var _, cache = 1, map[string]string{}$/", "", false),
+		entry("Reader", 7, "interface", "foo", "package", "/^type Reader interface {$/", "", false),
+		entry("Read", 8, "methodSpec", "Reader", "interface", "/^\tRead(p []byte) (n int, err error)$/", "(p []byte) (n int, err error)", false),
+		entry("Close", 12, "func", "server", "struct", "/^func (s *server) Close() error { return nil }$/", "() error", false),
+		entry("server", 14, "struct", "foo", "package", "/^type server struct {$/", "", false),
+		entry("Addr", 15, "member", "server", "struct", "/^\tAddr string$/", "", false),
+		entry("New", 18, "func", "foo", "package", "/^func New(addr string) *server { return &server{Addr: addr} }$/", "(addr string) *server", false),
+	}
+	if !reflect.DeepEqual(got, want) {
+		for i := range got {
+			if i < len(want) && !reflect.DeepEqual(got[i], want[i]) {
+				t.Errorf("entry %d: got %+v, want %+v", i, got[i], want[i])
+			}
+		}
+		if len(got) != len(want) {
+			t.Errorf("got %d entries, want %d", len(got), len(want))
+		}
+	}
+}
+
+func TestParser_pattern(t *testing.T) {
+	p, _ := NewParser()
+	got, err := p.Parse("a.go", []byte("package a\n\nvar URL = \"http://x\\\\y\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `/^var URL = "http:\/\/x\\\\y"$/`; got[1].Pattern != want {
+		t.Errorf("got pattern %s, want %s", got[1].Pattern, want)
+	}
+}
+
+func TestParser_invalid(t *testing.T) {
+	p, _ := NewParser()
+	if _, err := p.Parse("a.go", []byte("package a\nfunc {")); err == nil {
+		t.Error("expected error parsing invalid Go")
+	}
+}
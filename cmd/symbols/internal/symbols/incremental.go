@@ -0,0 +1,202 @@
+package symbols
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/diskcache"
+	"github.com/sourcegraph/sourcegraph/internal/symbols/protocol"
+)
+
+const (
+	// maxAncestorDistance is the number of first-parent ancestors of a commit
+	// we look at when searching the cache for a database to start from.
+	maxAncestorDistance = 20
+
+	// maxChangedPaths is the largest number of paths changed since an
+	// ancestor for which we update the ancestor's database. For more changes
+	// it is cheaper to parse the whole archive again.
+	maxChangedPaths = 1000
+)
+
+// Changes are the paths which differ between two commits.
+type Changes struct {
+	Added    []string
+	Modified []string
+	Deleted  []string
+}
+
+// ParseGitDiffNameStatus parses the output of
+// `git diff -z --name-status --no-renames <commitA> <commitB>`.
+func ParseGitDiffNameStatus(out []byte) (Changes, error) {
+	var changes Changes
+	fields := bytes.Split(bytes.TrimSuffix(out, []byte{0}), []byte{0})
+	if len(fields) == 1 && len(fields[0]) == 0 {
+		return changes, nil
+	}
+	if len(fields)%2 != 0 {
+		return changes, errors.Errorf("unexpected git diff output: odd number of fields (%d)", len(fields))
+	}
+	for i := 0; i < len(fields); i += 2 {
+		status, path := string(fields[i]), string(fields[i+1])
+		switch status {
+		case "A":
+			changes.Added = append(changes.Added, path)
+		case "M", "T":
+			changes.Modified = append(changes.Modified, path)
+		case "D":
+			changes.Deleted = append(changes.Deleted, path)
+		default:
+			return changes, errors.Errorf("unexpected git diff status %q for path %q", status, path)
+		}
+	}
+	return changes, nil
+}
+
+// canIndexIncrementally reports whether s has the hooks needed to build a
+// database from the database of an ancestor commit.
+func (s *Service) canIndexIncrementally() bool {
+	return s.Ancestors != nil && s.GitDiff != nil && s.FetchTarPaths != nil
+}
+
+// writeSymbolsIncrementally copies the database of the nearest ancestor of
+// commitID which is already in the cache to dbFile, and then reparses only the
+// files which changed since that ancestor. It returns false if there is no
+// suitable ancestor, in which case dbFile has to be written from scratch.
+func (s *Service) writeSymbolsIncrementally(ctx context.Context, dbFile string, repo api.RepoName, commitID api.CommitID) (ok bool, err error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "writeSymbolsIncrementally")
+	span.SetTag("repo", string(repo))
+	span.SetTag("commit", string(commitID))
+	defer func() {
+		span.SetTag("incremental", ok)
+		if err != nil {
+			ext.Error.Set(span, true)
+			span.LogFields(otlog.Error(err))
+		}
+		span.Finish()
+	}()
+
+	ancestor, ancestorDB, err := s.findIndexedAncestor(ctx, repo, commitID)
+	if err != nil || ancestorDB == nil {
+		return false, err
+	}
+	defer ancestorDB.File.Close()
+	span.SetTag("ancestor", string(ancestor))
+
+	changes, err := s.GitDiff(ctx, repo, ancestor, commitID)
+	if err != nil {
+		return false, errors.Wrap(err, "GitDiff")
+	}
+	numChanged := len(changes.Added) + len(changes.Modified) + len(changes.Deleted)
+	span.SetTag("changed", numChanged)
+	if numChanged > maxChangedPaths {
+		return false, nil
+	}
+
+	if err := copyFileTo(dbFile, ancestorDB.File); err != nil {
+		return false, errors.Wrap(err, "copying ancestor database")
+	}
+
+	db, err := sqlx.Open("sqlite3_with_pcre", dbFile)
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	deleteStatement, err := tx.Preparex("DELETE FROM symbols WHERE path = ?")
+	if err != nil {
+		return false, err
+	}
+	for _, paths := range [][]string{changes.Modified, changes.Deleted} {
+		for _, path := range paths {
+			if _, err := deleteStatement.Exec(path); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	if paths := append(changes.Added, changes.Modified...); len(paths) > 0 {
+		insertStatement, err := prepareInsertSymbol(tx)
+		if err != nil {
+			return false, err
+		}
+		err = s.parseUncached(ctx, repo, commitID, paths, func(symbol protocol.Symbol, parser string) error {
+			symbolInDBValue := symbolToSymbolInDB(symbol, parser)
+			_, err := insertStatement.Exec(&symbolInDBValue)
+			return err
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	incrementalIndexes.Inc()
+	return true, nil
+}
+
+// findIndexedAncestor returns the nearest ancestor of commitID which has a
+// database in the cache, along with that database. The returned file is nil if
+// there is no such ancestor.
+func (s *Service) findIndexedAncestor(ctx context.Context, repo api.RepoName, commitID api.CommitID) (api.CommitID, *diskcache.File, error) {
+	ancestors, err := s.Ancestors(ctx, repo, commitID, maxAncestorDistance)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "Ancestors")
+	}
+	for _, ancestor := range ancestors {
+		f, err := s.cache.OpenIfExists(symbolsDBKey(repo, ancestor))
+		if err == nil {
+			return ancestor, f, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", nil, err
+		}
+	}
+	return "", nil, nil
+}
+
+// copyFileTo replaces the contents of the file at path with the contents of
+// src.
+func copyFileTo(path string, src io.Reader) error {
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+var incrementalIndexes = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "symbols",
+	Subsystem: "store",
+	Name:      "incremental_indexes",
+	Help:      "The total number of databases built from the database of an ancestor commit.",
+})
+
+func init() {
+	prometheus.MustRegister(incrementalIndexes)
+}
@@ -0,0 +1,127 @@
+package symbols
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/symbols/internal/pkg/ctags"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/symbols/protocol"
+)
+
+func TestParseGitDiffNameStatus(t *testing.T) {
+	got, err := ParseGitDiffNameStatus([]byte("A\x00a.go\x00M\x00dir/b.go\x00T\x00c\x00D\x00d e.go\x00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Changes{
+		Added:    []string{"a.go"},
+		Modified: []string{"dir/b.go", "c"},
+		Deleted:  []string{"d e.go"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	got, err = ParseGitDiffNameStatus(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, Changes{}) {
+		t.Errorf("got %+v, want no changes", got)
+	}
+
+	for _, out := range []string{"A\x00", "R100\x00a.go\x00b.go\x00"} {
+		if _, err := ParseGitDiffNameStatus([]byte(out)); err == nil {
+			t.Errorf("expected error parsing %q", out)
+		}
+	}
+}
+
+func TestService_incremental(t *testing.T) {
+	MustRegisterSqlite3WithPcre()
+
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { os.RemoveAll(tmpDir) }()
+
+	commits := map[api.CommitID]map[string]string{
+		"a": {"a.js": "x", "b.js": "y", "c.js": "z"},
+		"b": {"a.js": "x", "b.js": "y2", "d.js": "w"},
+	}
+	var fetchedPaths []string
+	service := Service{
+		FetchTar: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (io.ReadCloser, error) {
+			return createTar(commits[commit])
+		},
+		FetchTarPaths: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, paths []string) (io.ReadCloser, error) {
+			files := map[string]string{}
+			for _, p := range paths {
+				files[p] = commits[commit][p]
+				fetchedPaths = append(fetchedPaths, p)
+			}
+			return createTar(files)
+		},
+		Ancestors: func(ctx context.Context, repo api.RepoName, commit api.CommitID, n int) ([]api.CommitID, error) {
+			if commit == "b" {
+				return []api.CommitID{"a"}, nil
+			}
+			return nil, nil
+		},
+		GitDiff: func(ctx context.Context, repo api.RepoName, commitA, commitB api.CommitID) (Changes, error) {
+			return Changes{Added: []string{"d.js"}, Modified: []string{"b.js"}, Deleted: []string{"c.js"}}, nil
+		},
+		NewParser: func() (ctags.Parser, error) {
+			return contentParser{}, nil
+		},
+		Path: tmpDir,
+	}
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	search := func(commit api.CommitID) []protocol.Symbol {
+		result, err := service.search(context.Background(), protocol.SearchArgs{Repo: "r", CommitID: commit, First: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Slice(result.Symbols, func(i, j int) bool { return result.Symbols[i].Path < result.Symbols[j].Path })
+		return result.Symbols
+	}
+
+	search("a")
+	if fetchedPaths != nil {
+		t.Fatalf("expected full index of a, fetched paths %v", fetchedPaths)
+	}
+
+	got := search("b")
+	want := []protocol.Symbol{
+		{Name: "x", Path: "a.js"},
+		{Name: "y2", Path: "b.js"},
+		{Name: "w", Path: "d.js"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	sort.Strings(fetchedPaths)
+	if want := []string{"b.js", "d.js"}; !reflect.DeepEqual(fetchedPaths, want) {
+		t.Errorf("fetched paths %v, want %v", fetchedPaths, want)
+	}
+}
+
+// contentParser returns one symbol per file, named after the file's content.
+type contentParser struct{}
+
+func (contentParser) Parse(name string, content []byte) ([]ctags.Entry, error) {
+	return []ctags.Entry{{Name: string(content), Path: name}}, nil
+}
+
+func (contentParser) Close() {}
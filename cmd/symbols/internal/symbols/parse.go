@@ -3,6 +3,7 @@ package symbols
 import (
 	"context"
 	"fmt"
+	"path"
 	"runtime"
 	"strings"
 	"sync"
@@ -19,25 +20,83 @@ import (
 	"golang.org/x/net/trace"
 )
 
-// startParsers starts the parser process pool.
+// ctagsParserName is recorded with the symbols parsed by the parsers created
+// by Service.NewParser.
+const ctagsParserName = "ctags"
+
+// LanguageParser parses the files of one language instead of ctags.
+type LanguageParser struct {
+	// Name identifies the parser. It is recorded with every symbol it finds.
+	Name string
+
+	// Extensions are the extensions (e.g. ".go") of the files to parse with
+	// this parser.
+	Extensions []string
+
+	// New returns a new parser. Unlike the parsers returned by
+	// Service.NewParser, they are reused after they fail to parse a file.
+	New func() (ctags.Parser, error)
+}
+
+// parserPool is a pool of parsers of the same kind.
+type parserPool struct {
+	name      string
+	newParser func() (ctags.Parser, error)
+	parsers   chan ctags.Parser
+
+	// closeOnError if true means a parser which returned an error is closed
+	// and replaced, since it may be in a bad state.
+	closeOnError bool
+}
+
+func newParserPool(name string, newParser func() (ctags.Parser, error), n int, closeOnError bool) (*parserPool, error) {
+	pool := &parserPool{
+		name:         name,
+		newParser:    newParser,
+		parsers:      make(chan ctags.Parser, n),
+		closeOnError: closeOnError,
+	}
+	for i := 0; i < n; i++ {
+		parser, err := newParser()
+		if err != nil {
+			return nil, errors.Wrapf(err, "creating %s parser", name)
+		}
+		pool.parsers <- parser
+	}
+	return pool, nil
+}
+
+// startParsers starts the parser process pool and the pools of language
+// parsers.
 func (s *Service) startParsers() error {
 	n := s.NumParserProcesses
 	if n == 0 {
 		n = runtime.GOMAXPROCS(0)
 	}
 
-	s.parsers = make(chan ctags.Parser, n)
-	for i := 0; i < n; i++ {
-		parser, err := s.NewParser()
+	var err error
+	s.parsers, err = newParserPool(ctagsParserName, s.NewParser, n, true)
+	if err != nil {
+		return err
+	}
+
+	s.languageParsers = make(map[string]*parserPool)
+	for _, lp := range s.LanguageParsers {
+		pool, err := newParserPool(lp.Name, lp.New, n, false)
 		if err != nil {
-			return errors.Wrap(err, "NewParser")
+			return err
+		}
+		for _, ext := range lp.Extensions {
+			s.languageParsers[ext] = pool
 		}
-		s.parsers <- parser
 	}
 	return nil
 }
 
-func (s *Service) parseUncached(ctx context.Context, repo api.RepoName, commitID api.CommitID, callback func(symbol protocol.Symbol) error) (err error) {
+// parseUncached parses the files of repo@commitID and calls callback for
+// every symbol found, along with the name of the parser which found it. If
+// paths is non-empty only those files are parsed.
+func (s *Service) parseUncached(ctx context.Context, repo api.RepoName, commitID api.CommitID, paths []string, callback func(symbol protocol.Symbol, parser string) error) (err error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "parseUncached")
 	defer func() {
 		if err != nil {
@@ -48,6 +107,7 @@ func (s *Service) parseUncached(ctx context.Context, repo api.RepoName, commitID
 	}()
 	span.SetTag("repo", string(repo))
 	span.SetTag("commit", string(commitID))
+	span.SetTag("paths", len(paths))
 
 	tr := trace.New("parseUncached", string(repo))
 	tr.LazyPrintf("commitID: %s", commitID)
@@ -63,7 +123,7 @@ func (s *Service) parseUncached(ctx context.Context, repo api.RepoName, commitID
 	}()
 
 	tr.LazyPrintf("fetch")
-	parseRequests, errChan, err := s.fetchRepositoryArchive(ctx, repo, commitID)
+	parseRequests, errChan, err := s.fetchRepositoryArchive(ctx, repo, commitID, paths)
 	tr.LazyPrintf("fetch (returned chans)")
 	if err != nil {
 		return err
@@ -96,7 +156,7 @@ func (s *Service) parseUncached(ctx context.Context, repo api.RepoName, commitID
 				wg.Done()
 				<-sem
 			}()
-			entries, parseErr := s.parse(ctx, req)
+			entries, parser, parseErr := s.parse(ctx, req)
 			if parseErr != nil && parseErr != context.Canceled && parseErr != context.DeadlineExceeded {
 				log15.Error("Error parsing symbols.", "repo", repo, "commitID", commitID, "path", req.path, "dataSize", len(req.data), "error", parseErr)
 			}
@@ -108,7 +168,7 @@ func (s *Service) parseUncached(ctx context.Context, repo api.RepoName, commitID
 						continue
 					}
 					totalSymbols++
-					err = callback(entryToSymbol(e))
+					err = callback(entryToSymbol(e), parser)
 					if err != nil {
 						log15.Error("Failed to add symbol", "symbol", e, "error", err)
 						return
@@ -123,8 +183,26 @@ func (s *Service) parseUncached(ctx context.Context, repo api.RepoName, commitID
 	return <-errChan
 }
 
-// parse gets a parser from the pool and uses it to satisfy the parse request.
-func (s *Service) parse(ctx context.Context, req parseRequest) (entries []ctags.Entry, err error) {
+// parse satisfies the parse request with the language parser registered for
+// the extension of the file, falling back to ctags if there is none or it
+// fails. It returns the name of the parser used.
+func (s *Service) parse(ctx context.Context, req parseRequest) (entries []ctags.Entry, parser string, err error) {
+	if pool, ok := s.languageParsers[path.Ext(req.path)]; ok {
+		entries, err := s.parseWith(ctx, pool, req)
+		if err == nil {
+			return entries, pool.name, nil
+		}
+		if ctx.Err() != nil {
+			return nil, "", ctx.Err()
+		}
+		log15.Debug("Language parser failed, falling back to ctags.", "parser", pool.name, "path", req.path, "error", err)
+	}
+	entries, err = s.parseWith(ctx, s.parsers, req)
+	return entries, ctagsParserName, err
+}
+
+// parseWith gets a parser from pool and uses it to satisfy the parse request.
+func (s *Service) parseWith(ctx context.Context, pool *parserPool, req parseRequest) (entries []ctags.Entry, err error) {
 	parseQueueSize.Inc()
 
 	select {
@@ -134,7 +212,7 @@ func (s *Service) parse(ctx context.Context, req parseRequest) (entries []ctags.
 			parseQueueTimeouts.Inc()
 		}
 		return nil, ctx.Err()
-	case parser, ok := <-s.parsers:
+	case parser, ok := <-pool.parsers:
 		parseQueueSize.Dec()
 
 		if !ok {
@@ -145,8 +223,9 @@ func (s *Service) parse(ctx context.Context, req parseRequest) (entries []ctags.
 			// The parser failed for some previous receiver (who returned a nil parser to the channel). Try
 			// creating a parser.
 			var err error
-			parser, err = s.NewParser()
+			parser, err = pool.newParser()
 			if err != nil {
+				pool.parsers <- nil
 				return nil, err
 			}
 		}
@@ -157,16 +236,16 @@ func (s *Service) parse(ctx context.Context, req parseRequest) (entries []ctags.
 					err = fmt.Errorf("panic: %s", e)
 				}
 			}
-			if err == nil {
+			if err == nil || !pool.closeOnError {
 				// Return parser to pool.
-				s.parsers <- parser
+				pool.parsers <- parser
 			} else {
 				// Close parser and return nil to pool, indicating that the next receiver should create a new
 				// parser.
-				log15.Error("Closing failed parser and creating a new one.", "path", req.path, "error", err)
+				log15.Error("Closing failed parser and creating a new one.", "parser", pool.name, "path", req.path, "error", err)
 				parseFailed.Inc()
 				parser.Close()
-				s.parsers <- nil
+				pool.parsers <- nil
 			}
 		}()
 		parsing.Inc()
@@ -5,10 +5,13 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"go/ast"
 	"log"
 	"net/http"
+	"os"
 	"regexp/syntax"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sourcegraph/sourcegraph/internal/env"
@@ -29,16 +32,22 @@ import (
 // maxFileSize is the limit on file size in bytes. Only files smaller than this are processed.
 const maxFileSize = 1 << 19 // 512KB
 
-var libSqlite3Pcre = env.Get("LIBSQLITE3_PCRE", "", "path to the libsqlite3-pcre library")
+var (
+	libSqlite3Pcre = env.Get("LIBSQLITE3_PCRE", "", "path to the libsqlite3-pcre library")
+
+	registerSqlite3WithPcreOnce sync.Once
+)
 
 // MustRegisterSqlite3WithPcre registers a sqlite3 driver with PCRE support and
-// panics if it can't.
+// panics if it can't. It is safe to call more than once.
 func MustRegisterSqlite3WithPcre() {
 	if libSqlite3Pcre == "" {
 		env.PrintHelp()
 		log.Fatal("can't find the libsqlite3-pcre library because LIBSQLITE3_PCRE was not set")
 	}
-	sql.Register("sqlite3_with_pcre", &sqlite3.SQLiteDriver{Extensions: []string{libSqlite3Pcre}})
+	registerSqlite3WithPcreOnce.Do(func() {
+		sql.Register("sqlite3_with_pcre", &sqlite3.SQLiteDriver{Extensions: []string{libSqlite3Pcre}})
+	})
 }
 
 func (s *Service) handleSearch(w http.ResponseWriter, r *http.Request) {
@@ -115,7 +124,7 @@ func (s *Service) search(ctx context.Context, args protocol.SearchArgs) (result
 // specified in `args`. If the database doesn't already exist in the disk cache,
 // it will create a new one and write all the symbols into it.
 func (s *Service) getDBFile(ctx context.Context, args protocol.SearchArgs) (string, error) {
-	diskcacheFile, err := s.cache.OpenWithPath(ctx, fmt.Sprintf("%d-%s@%s", symbolsDBVersion, args.Repo, args.CommitID), func(fetcherCtx context.Context, tempDBFile string) error {
+	diskcacheFile, err := s.cache.OpenWithPath(ctx, symbolsDBKey(args.Repo, args.CommitID), func(fetcherCtx context.Context, tempDBFile string) error {
 		err := s.writeAllSymbolsToNewDB(fetcherCtx, tempDBFile, args.Repo, args.CommitID)
 		if err != nil {
 			if err == context.Canceled {
@@ -133,6 +142,12 @@ func (s *Service) getDBFile(ctx context.Context, args protocol.SearchArgs) (stri
 	return diskcacheFile.File.Name(), err
 }
 
+// symbolsDBKey returns the disk cache key of the sqlite3 database for
+// repo@commitID.
+func symbolsDBKey(repo api.RepoName, commitID api.CommitID) string {
+	return fmt.Sprintf("%d-%s@%s", symbolsDBVersion, repo, commitID)
+}
+
 // isLiteralEquality checks if the given regex matches literal strings exactly.
 // Returns whether or not the regex is exact, along with the literal string if
 // so.
@@ -151,6 +166,27 @@ func isLiteralEquality(expr string) (ok bool, lit string, err error) {
 	return true, string(r.Sub[1].Rune), nil
 }
 
+// kindAliases maps symbol kinds to the names other parsers use for the same
+// kind, e.g. ctags reports Go functions as "func" and C functions as
+// "function".
+var kindAliases = map[string][]string{
+	"function": {"func"},
+	"func":     {"function"},
+	"variable": {"var"},
+	"var":      {"variable"},
+	"constant": {"const"},
+	"const":    {"constant"},
+}
+
+// withKindAliases returns kinds together with their aliases.
+func withKindAliases(kinds []string) []string {
+	all := append([]string(nil), kinds...)
+	for _, k := range kinds {
+		all = append(all, kindAliases[strings.ToLower(k)]...)
+	}
+	return all
+}
+
 func filterSymbols(ctx context.Context, db *sqlx.DB, args protocol.SearchArgs) (res []protocol.Symbol, err error) {
 	span, _ := opentracing.StartSpanFromContext(ctx, "filterSymbols")
 	defer func() {
@@ -208,6 +244,33 @@ func filterSymbols(ctx context.Context, db *sqlx.DB, args protocol.SearchArgs) (
 	}
 	conditions = append(conditions, negateAll(makeCondition("path", args.ExcludePattern))...)
 
+	// makeInCondition matches column case insensitively against any of values.
+	makeInCondition := func(column string, values []string) []*sqlf.Query {
+		if len(values) == 0 {
+			return nil
+		}
+		lowered := make([]*sqlf.Query, 0, len(values))
+		for _, v := range values {
+			lowered = append(lowered, sqlf.Sprintf("%s", strings.ToLower(v)))
+		}
+		return []*sqlf.Query{sqlf.Sprintf("lower("+column+") IN (%s)", sqlf.Join(lowered, ","))}
+	}
+	conditions = append(conditions, makeInCondition("kind", withKindAliases(args.Kinds))...)
+	conditions = append(conditions, makeInCondition("language", args.Languages)...)
+
+	if args.Parent != "" {
+		// There is no lowercase column for parent, so we always use a regex.
+		parent := args.Parent
+		if !args.IsCaseSensitive {
+			parent = "(?i:" + parent + ")"
+		}
+		conditions = append(conditions, sqlf.Sprintf("parent REGEXP %s", parent))
+	}
+
+	if args.ExportedOnly {
+		conditions = append(conditions, sqlf.Sprintf("exported"))
+	}
+
 	var sqlQuery *sqlf.Query
 	if len(conditions) == 0 {
 		sqlQuery = sqlf.Sprintf("SELECT * FROM symbols LIMIT %s", args.First)
@@ -233,11 +296,12 @@ func filterSymbols(ctx context.Context, db *sqlx.DB, args protocol.SearchArgs) (
 // filenames to prevent a newer version of the symbols service from attempting
 // to read from a database created by an older (and likely incompatible) symbols
 // service. Increment this when you change the database schema.
-const symbolsDBVersion = 3
+const symbolsDBVersion = 5
 
-// symbolInDB is the same as `protocol.Symbol`, but with two additional columns:
-// namelowercase and pathlowercase, which enable indexed case insensitive
-// queries.
+// symbolInDB is the same as `protocol.Symbol`, but with four additional
+// columns: namelowercase and pathlowercase, which enable indexed case
+// insensitive queries, exported, which is used for the ExportedOnly search
+// argument, and parser, the name of the parser which found the symbol.
 type symbolInDB struct {
 	Name          string
 	NameLowercase string // derived from `Name`
@@ -252,9 +316,12 @@ type symbolInDB struct {
 	Pattern       string
 
 	FileLimited bool
+	Exported    bool // derived from `Name`, `Language` and `FileLimited`
+
+	Parser string
 }
 
-func symbolToSymbolInDB(symbol protocol.Symbol) symbolInDB {
+func symbolToSymbolInDB(symbol protocol.Symbol, parser string) symbolInDB {
 	return symbolInDB{
 		Name:          symbol.Name,
 		NameLowercase: strings.ToLower(symbol.Name),
@@ -269,7 +336,22 @@ func symbolToSymbolInDB(symbol protocol.Symbol) symbolInDB {
 		Pattern:       symbol.Pattern,
 
 		FileLimited: symbol.FileLimited,
+		Exported:    isExported(symbol),
+
+		Parser: parser,
+	}
+}
+
+// isExported returns whether symbol is visible outside of its file and, for
+// Go, outside of its package.
+func isExported(symbol protocol.Symbol) bool {
+	if symbol.FileLimited {
+		return false
+	}
+	if strings.EqualFold(symbol.Language, "go") {
+		return symbol.Kind == "package" || ast.IsExported(symbol.Name)
 	}
+	return true
 }
 
 func symbolInDBToSymbol(symbolInDB symbolInDB) protocol.Symbol {
@@ -288,9 +370,29 @@ func symbolInDBToSymbol(symbolInDB symbolInDB) protocol.Symbol {
 	}
 }
 
-// writeAllSymbolsToNewDB fetches the repo@commit from gitserver, parses all the
-// symbols, and writes them to the blank database file `dbFile`.
+// writeAllSymbolsToNewDB writes all the symbols of repo@commit to the blank
+// database file `dbFile`. If the database of an ancestor commit is in the
+// cache, it is copied and only the files which changed since are parsed.
+// Otherwise the repo@commit is fetched from gitserver and every file is
+// parsed.
 func (s *Service) writeAllSymbolsToNewDB(ctx context.Context, dbFile string, repoName api.RepoName, commitID api.CommitID) error {
+	if s.canIndexIncrementally() {
+		ok, err := s.writeSymbolsIncrementally(ctx, dbFile, repoName, commitID)
+		if ok {
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log15.Warn("Failed to index symbols incrementally, parsing all files.", "repo", repoName, "commitID", commitID, "error", err)
+		}
+		// dbFile may contain a copy of the ancestor's database.
+		if err := os.Truncate(dbFile, 0); err != nil {
+			return err
+		}
+	}
+
 	db, err := sqlx.Open("sqlite3_with_pcre", dbFile)
 	if err != nil {
 		return err
@@ -303,10 +405,38 @@ func (s *Service) writeAllSymbolsToNewDB(ctx context.Context, dbFile string, rep
 		return err
 	}
 
+	if err := createSymbolsTable(tx); err != nil {
+		return err
+	}
+
+	insertStatement, err := prepareInsertSymbol(tx)
+	if err != nil {
+		return err
+	}
+
+	err = s.parseUncached(ctx, repoName, commitID, nil, func(symbol protocol.Symbol, parser string) error {
+		symbolInDBValue := symbolToSymbolInDB(symbol, parser)
+		_, err := insertStatement.Exec(&symbolInDBValue)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// createSymbolsTable creates the symbols table and its indexes.
+func createSymbolsTable(tx *sqlx.Tx) error {
 	// The column names are the lowercase version of fields in `symbolInDB`
 	// because sqlx lowercases struct fields by default. See
 	// http://jmoiron.github.io/sqlx/#query
-	_, err = tx.Exec(
+	_, err := tx.Exec(
 		`CREATE TABLE IF NOT EXISTS symbols (
 			name VARCHAR(256) NOT NULL,
 			namelowercase VARCHAR(256) NOT NULL,
@@ -319,7 +449,9 @@ func (s *Service) writeAllSymbolsToNewDB(ctx context.Context, dbFile string, rep
 			parentkind VARCHAR(255) NOT NULL,
 			signature VARCHAR(255) NOT NULL,
 			pattern VARCHAR(255) NOT NULL,
-			filelimited BOOLEAN NOT NULL
+			filelimited BOOLEAN NOT NULL,
+			exported BOOLEAN NOT NULL,
+			parser VARCHAR(255) NOT NULL
 		)`)
 	if err != nil {
 		return err
@@ -342,32 +474,15 @@ func (s *Service) writeAllSymbolsToNewDB(ctx context.Context, dbFile string, rep
 	}
 
 	_, err = tx.Exec(`CREATE INDEX pathlowercase_index ON symbols(pathlowercase);`)
-	if err != nil {
-		return err
-	}
+	return err
+}
 
-	insertStatement, err := tx.PrepareNamed(
+// prepareInsertSymbol returns a statement which inserts a symbolInDB into the
+// symbols table.
+func prepareInsertSymbol(tx *sqlx.Tx) (*sqlx.NamedStmt, error) {
+	return tx.PrepareNamed(
 		fmt.Sprintf(
 			"INSERT INTO symbols %s VALUES %s",
-			"( name,  namelowercase,  path,  pathlowercase,  line,  kind,  language,  parent,  parentkind,  signature,  pattern,  filelimited)",
-			"(:name, :namelowercase, :path, :pathlowercase, :line, :kind, :language, :parent, :parentkind, :signature, :pattern, :filelimited)"))
-	if err != nil {
-		return err
-	}
-
-	err = s.parseUncached(ctx, repoName, commitID, func(symbol protocol.Symbol) error {
-		symbolInDBValue := symbolToSymbolInDB(symbol)
-		_, err := insertStatement.Exec(&symbolInDBValue)
-		return err
-	})
-	if err != nil {
-		return err
-	}
-
-	err = tx.Commit()
-	if err != nil {
-		return err
-	}
-
-	return nil
+			"( name,  namelowercase,  path,  pathlowercase,  line,  kind,  language,  parent,  parentkind,  signature,  pattern,  filelimited,  exported,  parser)",
+			"(:name, :namelowercase, :path, :pathlowercase, :line, :kind, :language, :parent, :parentkind, :signature, :pattern, :filelimited, :exported, :parser)"))
 }
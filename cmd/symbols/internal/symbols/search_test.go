@@ -6,9 +6,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"reflect"
 	"testing"
 
 	"github.com/inconshreveable/log15"
+	"github.com/jmoiron/sqlx"
 	"github.com/sourcegraph/sourcegraph/cmd/symbols/internal/pkg/ctags"
 	"github.com/sourcegraph/sourcegraph/internal/symbols/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/testutil"
@@ -86,3 +88,93 @@ func BenchmarkSearch(b *testing.B) {
 		runQueryTest(test)
 	}
 }
+
+func TestFilterSymbols(t *testing.T) {
+	MustRegisterSqlite3WithPcre()
+
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := sqlx.Open("sqlite3_with_pcre", path.Join(tmpDir, "symbols.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fn := protocol.Symbol{Name: "Run", Path: "a.go", Kind: "function", Language: "Go"}
+	method := protocol.Symbol{Name: "Close", Path: "a.go", Kind: "method", Language: "Go", Parent: "Server", ParentKind: "struct"}
+	static := protocol.Symbol{Name: "helper", Path: "b.c", Kind: "function", Language: "C", FileLimited: true}
+	unexported := protocol.Symbol{Name: "serve", Path: "a.go", Kind: "func", Language: "Go"}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := createSymbolsTable(tx); err != nil {
+		t.Fatal(err)
+	}
+	insertStatement, err := prepareInsertSymbol(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, symbol := range []protocol.Symbol{fn, method, static, unexported} {
+		symbolInDBValue := symbolToSymbolInDB(symbol, ctagsParserName)
+		if _, err := insertStatement.Exec(&symbolInDBValue); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		args protocol.SearchArgs
+		want []protocol.Symbol
+	}{
+		"kind":                 {args: protocol.SearchArgs{Kinds: []string{"Function"}}, want: []protocol.Symbol{fn, static, unexported}},
+		"kind alias":           {args: protocol.SearchArgs{Kinds: []string{"func"}}, want: []protocol.Symbol{fn, static, unexported}},
+		"kinds":                {args: protocol.SearchArgs{Kinds: []string{"method", "function"}}, want: []protocol.Symbol{fn, method, static, unexported}},
+		"language":             {args: protocol.SearchArgs{Languages: []string{"go"}}, want: []protocol.Symbol{fn, method, unexported}},
+		"kind and language":    {args: protocol.SearchArgs{Kinds: []string{"function"}, Languages: []string{"C"}}, want: []protocol.Symbol{static}},
+		"parent":               {args: protocol.SearchArgs{Parent: "^serv"}, want: []protocol.Symbol{method}},
+		"parent casesensitive": {args: protocol.SearchArgs{Parent: "^serv", IsCaseSensitive: true}, want: nil},
+		"exported":             {args: protocol.SearchArgs{ExportedOnly: true}, want: []protocol.Symbol{fn, method}},
+		"exported and query":   {args: protocol.SearchArgs{Query: "e", ExportedOnly: true}, want: []protocol.Symbol{method}},
+	}
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			test.args.First = 10
+			got, err := filterSymbols(context.Background(), db, test.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsExported(t *testing.T) {
+	tests := map[string]struct {
+		symbol protocol.Symbol
+		want   bool
+	}{
+		"file limited":   {symbol: protocol.Symbol{Name: "helper", Language: "C", FileLimited: true}, want: false},
+		"not limited":    {symbol: protocol.Symbol{Name: "helper", Language: "C"}, want: true},
+		"go exported":    {symbol: protocol.Symbol{Name: "Run", Kind: "func", Language: "Go"}, want: true},
+		"go unexported":  {symbol: protocol.Symbol{Name: "serve", Kind: "func", Language: "Go"}, want: false},
+		"go package":     {symbol: protocol.Symbol{Name: "symbols", Kind: "package", Language: "Go"}, want: true},
+		"go file scoped": {symbol: protocol.Symbol{Name: "Run", Kind: "func", Language: "Go", FileLimited: true}, want: false},
+	}
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			if got := isExported(test.symbol); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -22,12 +22,34 @@ type Service struct {
 	// determine if the error is a bad request (eg invalid repo).
 	FetchTar func(context.Context, gitserver.Repo, api.CommitID) (io.ReadCloser, error)
 
+	// FetchTarPaths is like FetchTar, but the tar archive only contains the
+	// specified paths.
+	FetchTarPaths func(context.Context, gitserver.Repo, api.CommitID, []string) (io.ReadCloser, error)
+
+	// Ancestors returns up to n first-parent ancestors of commit, nearest
+	// first. It does not include commit.
+	Ancestors func(ctx context.Context, repo api.RepoName, commit api.CommitID, n int) ([]api.CommitID, error)
+
+	// GitDiff returns the paths which changed between commitA and commitB.
+	//
+	// If FetchTarPaths, Ancestors and GitDiff are set, the symbols of a
+	// commit are indexed by updating the database of an ancestor which is
+	// already in the cache, instead of parsing every file.
+	GitDiff func(ctx context.Context, repo api.RepoName, commitA, commitB api.CommitID) (Changes, error)
+
 	// MaxConcurrentFetchTar is the maximum number of concurrent calls allowed
 	// to FetchTar. It defaults to 15.
 	MaxConcurrentFetchTar int
 
+	// NewParser returns a ctags parser. It is used for every file which is
+	// not parsed by one of LanguageParsers.
 	NewParser func() (ctags.Parser, error)
 
+	// LanguageParsers are used instead of ctags for the files with their
+	// extensions. If a language parser fails to parse a file, the file is
+	// parsed with ctags.
+	LanguageParsers []LanguageParser
+
 	// NumParserProcesses is the maximum number of ctags parser child processes to run.
 	NumParserProcesses int
 
@@ -48,7 +70,10 @@ type Service struct {
 	fetchSem chan int
 
 	// pool of ctags parser child processes
-	parsers chan ctags.Parser
+	parsers *parserPool
+
+	// languageParsers are the pools of LanguageParsers by file extension.
+	languageParsers map[string]*parserPool
 }
 
 // Start must be called before any requests are handled.
@@ -16,6 +16,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/sourcegraph/sourcegraph/cmd/symbols/internal/pkg/ctags"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver"
@@ -195,3 +196,73 @@ func (m mockParser) Parse(name string, content []byte) ([]ctags.Entry, error) {
 }
 
 func (mockParser) Close() {}
+
+func TestService_languageParsers(t *testing.T) {
+	MustRegisterSqlite3WithPcre()
+
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { os.RemoveAll(tmpDir) }()
+
+	files := map[string]string{"a.js": "var x = 1", "b.go": "package b", "c.go": "invalid"}
+	service := Service{
+		FetchTar: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (io.ReadCloser, error) {
+			return createTar(files)
+		},
+		NewParser: func() (ctags.Parser, error) {
+			return pathParser{name: "ctags"}, nil
+		},
+		LanguageParsers: []LanguageParser{{
+			Name:       "go",
+			Extensions: []string{".go"},
+			New: func() (ctags.Parser, error) {
+				return pathParser{name: "go", fail: "c.go"}, nil
+			},
+		}},
+		Path: tmpDir,
+	}
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	dbFile, err := service.getDBFile(context.Background(), protocol.SearchArgs{Repo: "r", CommitID: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sqlx.Open("sqlite3_with_pcre", dbFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var rows []struct{ Name, Parser string }
+	if err := db.Select(&rows, "SELECT name, parser FROM symbols ORDER BY path"); err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ Name, Parser string }{
+		{Name: "ctags:a.js", Parser: "ctags"},
+		{Name: "go:b.go", Parser: "go"},
+		{Name: "ctags:c.go", Parser: "ctags"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got %+v, want %+v", rows, want)
+	}
+}
+
+// pathParser returns one symbol per file, named after the parser and the path.
+// It fails to parse the file at path fail.
+type pathParser struct {
+	name string
+	fail string
+}
+
+func (p pathParser) Parse(path string, content []byte) ([]ctags.Entry, error) {
+	if path == p.fail {
+		return nil, fmt.Errorf("%s: can't parse %s", p.name, path)
+	}
+	return []ctags.Entry{{Name: p.name + ":" + path, Path: path}}, nil
+}
+
+func (pathParser) Close() {}
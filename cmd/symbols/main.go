@@ -13,6 +13,7 @@ import (
 	"os/signal"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/inconshreveable/log15"
@@ -21,6 +22,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/sourcegraph/sourcegraph/cmd/symbols/internal/pkg/ctags"
+	"github.com/sourcegraph/sourcegraph/cmd/symbols/internal/pkg/goparser"
 	"github.com/sourcegraph/sourcegraph/cmd/symbols/internal/symbols"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/debugserver"
@@ -51,6 +53,15 @@ func main() {
 		FetchTar: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (io.ReadCloser, error) {
 			return gitserver.DefaultClient.Archive(ctx, repo, gitserver.ArchiveOptions{Treeish: string(commit), Format: "tar"})
 		},
+		FetchTarPaths: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, paths []string) (io.ReadCloser, error) {
+			pathspecs := make([]string, len(paths))
+			for i, p := range paths {
+				pathspecs[i] = ":(literal)" + p
+			}
+			return gitserver.DefaultClient.Archive(ctx, repo, gitserver.ArchiveOptions{Treeish: string(commit), Format: "tar", Paths: pathspecs})
+		},
+		Ancestors: ancestors,
+		GitDiff:   gitDiff,
 		NewParser: func() (ctags.Parser, error) {
 			parser, err := ctags.NewParser(ctags.GetCommand())
 			if err != nil {
@@ -58,6 +69,9 @@ func main() {
 			}
 			return parser, nil
 		},
+		LanguageParsers: []symbols.LanguageParser{
+			{Name: "go", Extensions: []string{".go"}, New: goparser.NewParser},
+		},
 		Path: cacheDir,
 	}
 	if mb, err := strconv.ParseInt(cacheSizeMB, 10, 64); err != nil {
@@ -90,6 +104,34 @@ func main() {
 	}
 }
 
+// ancestors returns up to n first-parent ancestors of commit.
+func ancestors(ctx context.Context, repo api.RepoName, commit api.CommitID, n int) ([]api.CommitID, error) {
+	cmd := gitserver.DefaultClient.Command("git", "rev-list", "--first-parent", "--max-count="+strconv.Itoa(n+1), string(commit))
+	cmd.Repo = gitserver.Repo{Name: repo}
+	out, err := cmd.Output(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "git rev-list")
+	}
+	var commits []api.CommitID
+	for _, line := range strings.Fields(string(out)) {
+		if c := api.CommitID(line); c != commit {
+			commits = append(commits, c)
+		}
+	}
+	return commits, nil
+}
+
+// gitDiff returns the paths which changed between commitA and commitB.
+func gitDiff(ctx context.Context, repo api.RepoName, commitA, commitB api.CommitID) (symbols.Changes, error) {
+	cmd := gitserver.DefaultClient.Command("git", "diff", "-z", "--name-status", "--no-renames", string(commitA), string(commitB), "--")
+	cmd.Repo = gitserver.Repo{Name: repo}
+	out, err := cmd.Output(ctx)
+	if err != nil {
+		return symbols.Changes{}, errors.Wrap(err, "git diff")
+	}
+	return symbols.ParseGitDiffNameStatus(out)
+}
+
 func shutdownOnSIGINT(s *http.Server) {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
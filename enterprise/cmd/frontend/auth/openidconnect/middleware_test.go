@@ -10,6 +10,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -17,6 +18,7 @@ import (
 	oidc "github.com/coreos/go-oidc"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/auth"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/auth/providers"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/external/session"
 	"github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/licensing"
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/license"
@@ -96,7 +98,8 @@ func newOIDCIDServer(t *testing.T, code string, oidcProvider *schema.OpenIDConne
 			"profile": "This is a profile",
 			"email": "`+email+`",
 			"email_verified": true,
-			"picture": "https://example.com/picture.png"
+			"picture": "https://example.com/picture.png",
+			"groups": ["engineering", "security"]
 		}`, testOIDCUser)))
 	})
 
@@ -108,6 +111,12 @@ func newOIDCIDServer(t *testing.T, code string, oidcProvider *schema.OpenIDConne
 		}
 		return 0, "safeErr", fmt.Errorf("account %v not found in mock", op.ExternalAccount)
 	}
+	db.Mocks.UserGroups.Set = func(userID int32, serviceType, serviceID string, names []string) error {
+		if want := []string{"engineering", "security"}; userID != 123 || serviceType != "openidconnect" || !reflect.DeepEqual(names, want) {
+			t.Errorf("got group memberships %q for user %d of %s, want %q", names, userID, serviceType, want)
+		}
+		return nil
+	}
 
 	return srv, &email
 }
@@ -140,7 +149,10 @@ func TestMiddleware(t *testing.T) {
 
 	oidcIDServer, emailPtr := newOIDCIDServer(t, "THECODE", &mockGetProviderValue.config)
 	defer oidcIDServer.Close()
-	defer func() { auth.MockGetAndSaveUser = nil }()
+	defer func() {
+		auth.MockGetAndSaveUser = nil
+		db.Mocks.UserGroups.Set = nil
+	}()
 	mockGetProviderValue.config.Issuer = oidcIDServer.URL
 
 	if err := mockGetProviderValue.Refresh(context.Background()); err != nil {
@@ -319,7 +331,10 @@ func TestMiddleware_NoOpenRedirect(t *testing.T) {
 
 	oidcIDServer, _ := newOIDCIDServer(t, "THECODE", &mockGetProviderValue.config)
 	defer oidcIDServer.Close()
-	defer func() { auth.MockGetAndSaveUser = nil }()
+	defer func() {
+		auth.MockGetAndSaveUser = nil
+		db.Mocks.UserGroups.Set = nil
+	}()
 	mockGetProviderValue.config.Issuer = oidcIDServer.URL
 
 	if err := mockGetProviderValue.Refresh(context.Background()); err != nil {
@@ -3,6 +3,7 @@ package openidconnect
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	oidc "github.com/coreos/go-oidc"
 	"github.com/pkg/errors"
@@ -75,5 +76,50 @@ func getOrCreateUser(ctx context.Context, p *provider, idToken *oidc.IDToken, us
 	if err != nil {
 		return nil, safeErrMsg, err
 	}
+
+	// The OpenID Connect provider is the source of truth for the user's group memberships, which
+	// are used to grant repository permissions (see the `permissions.groups` site config).
+	groupsClaimName := p.config.GroupsClaimName
+	if groupsClaimName == "" {
+		groupsClaimName = "groups"
+	}
+	groups := groupsFromClaims(groupsClaimName, idToken, userInfo)
+	if err := db.UserGroups.Set(ctx, userID, providerType, pi.ServiceID, groups); err != nil {
+		return nil, "Unexpected error saving group memberships from the OpenID Connect provider.", err
+	}
 	return actor.FromUser(userID), "", nil
 }
+
+// claimsSource is implemented by *oidc.IDToken and *oidc.UserInfo.
+type claimsSource interface {
+	Claims(v interface{}) error
+}
+
+// groupsFromClaims returns the group names in the claim with the given name of the first of the
+// sources that has it. The claim is either a list of names or a single name.
+func groupsFromClaims(name string, sources ...claimsSource) []string {
+	for _, src := range sources {
+		var claims map[string]interface{}
+		if err := src.Claims(&claims); err != nil {
+			continue
+		}
+
+		var groups []string
+		switch v := claims[name].(type) {
+		case []interface{}:
+			for _, g := range v {
+				if g, ok := g.(string); ok && strings.TrimSpace(g) != "" {
+					groups = append(groups, strings.TrimSpace(g))
+				}
+			}
+		case string:
+			if strings.TrimSpace(v) != "" {
+				groups = append(groups, strings.TrimSpace(v))
+			}
+		default:
+			continue
+		}
+		return groups
+	}
+	return nil
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/crewjam/saml/samlidp"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/auth"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/auth/providers"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/external/session"
 	"github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/licensing"
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/license"
@@ -208,6 +209,13 @@ func TestMiddleware(t *testing.T) {
 		return 0, "safeErr", fmt.Errorf("account %v not found in mock", op.ExternalAccount)
 	}
 	defer func() { auth.MockGetAndSaveUser = nil }()
+	db.Mocks.UserGroups.Set = func(userID int32, serviceType, serviceID string, names []string) error {
+		if userID != mockedUserID || serviceType != "saml" {
+			return fmt.Errorf("unexpected group memberships for user %d of %s", userID, serviceType)
+		}
+		return nil
+	}
+	defer func() { db.Mocks.UserGroups.Set = nil }()
 
 	// Set up the test handler.
 	authedHandler := http.NewServeMux()
@@ -18,6 +18,7 @@ type authnResponseInfo struct {
 	spec                 extsvc.ExternalAccountSpec
 	email, displayName   string
 	unnormalizedUsername string
+	groups               []string
 	accountData          interface{}
 }
 
@@ -57,6 +58,10 @@ func readAuthnResponse(p *provider, encodedResp string) (*authnResponseInfo, err
 	if pn := attr.Get("eduPersonPrincipalName"); email == "" && mightBeEmail(pn) {
 		email = pn
 	}
+	groupsAttributeName := p.config.GroupsAttributeName
+	if groupsAttributeName == "" {
+		groupsAttributeName = "groups"
+	}
 	info := authnResponseInfo{
 		spec: extsvc.ExternalAccountSpec{
 			ServiceType: providerType,
@@ -67,6 +72,7 @@ func readAuthnResponse(p *provider, encodedResp string) (*authnResponseInfo, err
 		email:                email,
 		unnormalizedUsername: firstNonempty(attr.Get("login"), attr.Get("uid"), attr.Get("username"), attr.Get("http://schemas.xmlsoap.org/ws/2005/05/identity/claims/name"), email),
 		displayName:          firstNonempty(attr.Get("displayName"), attr.Get("givenName")+" "+attr.Get("surname"), attr.Get("http://schemas.xmlsoap.org/claims/CommonName"), attr.Get("http://schemas.xmlsoap.org/ws/2005/05/identity/claims/givenname")),
+		groups:               attr.GetAll(groupsAttributeName),
 		accountData:          assertions,
 	}
 	if assertions.NameID == "" {
@@ -108,6 +114,12 @@ func getOrCreateUser(ctx context.Context, info *authnResponseInfo) (_ *actor.Act
 	if err != nil {
 		return nil, safeErrMsg, err
 	}
+
+	// The SAML identity provider is the source of truth for the user's group memberships, which
+	// are used to grant repository permissions (see the `permissions.groups` site config).
+	if err := db.UserGroups.Set(ctx, userID, info.spec.ServiceType, info.spec.ServiceID, info.groups); err != nil {
+		return nil, "Unexpected error saving group memberships from the SAML response.", err
+	}
 	return actor.FromUser(userID), "", nil
 }
 
@@ -125,3 +137,22 @@ func (v samlAssertionValues) Get(key string) string {
 	}
 	return ""
 }
+
+// GetAll returns all values of the attribute with the given name or friendly name. Identity
+// providers send multi-valued attributes (such as group memberships) as a single attribute with
+// multiple values.
+func (v samlAssertionValues) GetAll(key string) []string {
+	for _, a := range v {
+		if a.Name != key && a.FriendlyName != key {
+			continue
+		}
+		var values []string
+		for _, av := range a.Values {
+			if s := strings.TrimSpace(av.Value); s != "" {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
@@ -15,9 +14,12 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/hooks"
 	edb "github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/authz/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/authz/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/authz/github"
 	"github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/authz/gitlab"
+	"github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/authz/gitolite"
+	"github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/authz/groups"
 	"github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/licensing"
 	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/db/dbconn"
@@ -136,6 +138,9 @@ type ExternalServicesStore interface {
 	ListGitLabConnections(context.Context) ([]*schema.GitLabConnection, error)
 	ListGitHubConnections(context.Context) ([]*schema.GitHubConnection, error)
 	ListBitbucketServerConnections(context.Context) ([]*schema.BitbucketServerConnection, error)
+	ListBitbucketCloudConnections(context.Context) ([]*schema.BitbucketCloudConnection, error)
+	ListGitoliteConnections(context.Context) ([]*schema.GitoliteConnection, error)
+	ListOtherExternalServicesConnections(context.Context) ([]*schema.OtherExternalServiceConnection, error)
 }
 
 // ProvidersFromConfig returns the set of permission-related providers derived from the site config.
@@ -188,17 +193,38 @@ func ProvidersFromConfig(
 		warnings = append(warnings, bbsWarnings...)
 	}
 
-	// 🚨 SECURITY: Warn the admin when both code host authz provider and the permissions user mapping are configured.
-	if cfg.SiteConfiguration.PermissionsUserMapping != nil &&
-		cfg.SiteConfiguration.PermissionsUserMapping.Enabled && len(providers) > 0 {
-		serviceTypes := make([]string, len(providers))
-		for i := range providers {
-			serviceTypes[i] = strconv.Quote(providers[i].ServiceType())
+	if bbcConns, err := s.ListBitbucketCloudConnections(ctx); err != nil {
+		seriousProblems = append(seriousProblems, fmt.Sprintf("Could not load Bitbucket Cloud external service configs: %s", err))
+	} else {
+		bbcProviders, bbcProblems, bbcWarnings := bitbucketcloud.NewAuthzProviders(bbcConns)
+		providers = append(providers, bbcProviders...)
+		seriousProblems = append(seriousProblems, bbcProblems...)
+		warnings = append(warnings, bbcWarnings...)
+	}
+
+	if gitoliteConns, err := s.ListGitoliteConnections(ctx); err != nil {
+		seriousProblems = append(seriousProblems, fmt.Sprintf("Could not load Gitolite external service configs: %s", err))
+	} else {
+		gitoliteProviders, gitoliteProblems, gitoliteWarnings := gitolite.NewAuthzProviders(gitoliteConns)
+		providers = append(providers, gitoliteProviders...)
+		seriousProblems = append(seriousProblems, gitoliteProblems...)
+		warnings = append(warnings, gitoliteWarnings...)
+	}
+
+	if otherConns, err := s.ListOtherExternalServicesConnections(ctx); err != nil {
+		seriousProblems = append(seriousProblems, fmt.Sprintf("Could not load OTHER external service configs: %s", err))
+	} else {
+		groupsProviders, groupsProblems, groupsWarnings := groups.NewAuthzProviders(cfg.PermissionsGroups, otherConns)
+		providers = append(providers, groupsProviders...)
+		seriousProblems = append(seriousProblems, groupsProblems...)
+		warnings = append(warnings, groupsWarnings...)
+
+		// Group permissions are only enforced by RepoPerms, which isn't used when permissions are
+		// synced in the background. The repositories of the group authz providers are then hidden
+		// from all users (see authzFilter).
+		if len(groupsProviders) > 0 && cfg.PermissionsBackgroundSync != nil && cfg.PermissionsBackgroundSync.Enabled {
+			seriousProblems = append(seriousProblems, "Group permissions (`permissions.groups`) can't be enforced when `permissions.backgroundSync` is enabled")
 		}
-		msg := fmt.Sprintf(
-			"The permissions user mapping (site configuration `permissions.userMapping`) cannot be enabled when %s authorization providers are in use. Blocking access to all repositories until the conflict is resolved.",
-			strings.Join(serviceTypes, ", "))
-		seriousProblems = append(seriousProblems, msg)
 	}
 
 	return allowAccessByDefault, providers, seriousProblems, warnings
@@ -14,6 +14,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/authz/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/schema"
 )
@@ -69,6 +70,9 @@ func Test_authzProvidersFromConfig(t *testing.T) {
 		cfg                          conf.Unified
 		gitlabConnections            []*schema.GitLabConnection
 		bitbucketServerConnections   []*schema.BitbucketServerConnection
+		bitbucketCloudConnections    []*schema.BitbucketCloudConnection
+		gitoliteConnections          []*schema.GitoliteConnection
+		otherConnections             []*schema.OtherExternalServiceConnection
 		expAuthzAllowAccessByDefault bool
 		expAuthzProviders            func(*testing.T, []authz.Provider)
 		expSeriousProblems           []string
@@ -444,10 +448,112 @@ func Test_authzProvidersFromConfig(t *testing.T) {
 				}
 			},
 		},
+		{
+			description: "Bitbucket Cloud exact username matching",
+			cfg:         conf.Unified{},
+			bitbucketCloudConnections: []*schema.BitbucketCloudConnection{
+				{
+					Authorization: &schema.BitbucketCloudAuthorization{
+						IdentityProvider: schema.BitbucketCloudIdentityProvider{
+							Username: &schema.BitbucketCloudUsernameIdentity{
+								Type: "username",
+							},
+						},
+					},
+					Url:         "https://bitbucket.org",
+					Username:    "admin",
+					AppPassword: "secret-password",
+					Teams:       []string{"mycorp"},
+				},
+			},
+			expAuthzAllowAccessByDefault: true,
+			expAuthzProviders: func(t *testing.T, have []authz.Provider) {
+				if len(have) != 1 {
+					t.Fatalf("got %d providers, want 1", len(have))
+				}
+
+				if have[0].ServiceType() != bitbucketcloud.ServiceType || have[0].ServiceID() != "https://bitbucket.org/" {
+					t.Fatalf("no Bitbucket Cloud authz provider returned")
+				}
+			},
+		},
+		{
+			description: "Gitolite without identityProvider",
+			cfg:         conf.Unified{},
+			gitoliteConnections: []*schema.GitoliteConnection{
+				{
+					Authorization: &schema.GitoliteAuthorization{},
+					Host:          "git@gitolite.mycorp.org",
+					Prefix:        "gitolite.mycorp.org/",
+				},
+			},
+			expAuthzAllowAccessByDefault: false,
+			expSeriousProblems:           []string{"No identityProvider was specified"},
+		},
+		{
+			description: "Group permissions for OTHER external services",
+			cfg: conf.Unified{
+				SiteConfiguration: schema.SiteConfiguration{
+					PermissionsGroups: []*schema.PermissionsGroup{
+						{Name: "engineering", Repos: []string{"git\\.mycorp\\.org/engineering/.*"}},
+					},
+				},
+			},
+			otherConnections: []*schema.OtherExternalServiceConnection{
+				{Url: "https://git.mycorp.org/scm", Repos: []string{"engineering/app"}},
+				{Repos: []string{"https://git.example.com/public"}},
+			},
+			expAuthzAllowAccessByDefault: true,
+			expAuthzProviders: func(t *testing.T, have []authz.Provider) {
+				if len(have) != 1 {
+					t.Fatalf("got %d providers, want 1", len(have))
+				}
+
+				if have[0].ServiceType() != "other" || have[0].ServiceID() != "https://git.mycorp.org" {
+					t.Fatalf("no group authz provider returned")
+				}
+			},
+		},
+		{
+			description: "Group permissions with background permissions syncing",
+			cfg: conf.Unified{
+				SiteConfiguration: schema.SiteConfiguration{
+					PermissionsGroups: []*schema.PermissionsGroup{
+						{Name: "engineering", Repos: []string{"git\\.mycorp\\.org/engineering/.*"}},
+					},
+					PermissionsBackgroundSync: &schema.PermissionsBackgroundSync{Enabled: true},
+				},
+			},
+			otherConnections: []*schema.OtherExternalServiceConnection{
+				{Url: "https://git.mycorp.org", Repos: []string{"engineering/app"}},
+			},
+			expAuthzAllowAccessByDefault: false,
+			expAuthzProviders: func(t *testing.T, have []authz.Provider) {
+				if len(have) != 1 {
+					t.Fatalf("got %d providers, want 1", len(have))
+				}
+			},
+			expSeriousProblems: []string{"Group permissions (`permissions.groups`) can't be enforced when `permissions.backgroundSync` is enabled"},
+		},
+		{
+			description: "Group permissions with an invalid repository pattern",
+			cfg: conf.Unified{
+				SiteConfiguration: schema.SiteConfiguration{
+					PermissionsGroups: []*schema.PermissionsGroup{
+						{Name: "engineering", Repos: []string{"("}},
+					},
+				},
+			},
+			otherConnections: []*schema.OtherExternalServiceConnection{
+				{Url: "https://git.mycorp.org", Repos: []string{"engineering/app"}},
+			},
+			expAuthzAllowAccessByDefault: false,
+			expSeriousProblems:           []string{"invalid repository pattern \"(\" of group \"engineering\": error parsing regexp: missing closing ): `(`"},
+		},
 
 		// For Sourcegraph authz provider
 		{
-			description: "Conflicted configuration between Sourcegraph and GitLab authz provider",
+			description: "Permissions user mapping together with GitLab authz provider",
 			cfg: conf.Unified{
 				SiteConfiguration: schema.SiteConfiguration{
 					PermissionsUserMapping: &schema.PermissionsUserMapping{
@@ -475,40 +581,18 @@ func Test_authzProvidersFromConfig(t *testing.T) {
 					Token: "asdf",
 				},
 			},
-			expAuthzAllowAccessByDefault: false,
-			expSeriousProblems:           []string{"The permissions user mapping (site configuration `permissions.userMapping`) cannot be enabled when \"gitlab\" authorization providers are in use. Blocking access to all repositories until the conflict is resolved."},
-		},
-		{
-			description: "Conflicted configuration between Sourcegraph and Bitbucket Server authz provider",
-			cfg: conf.Unified{
-				SiteConfiguration: schema.SiteConfiguration{
-					PermissionsUserMapping: &schema.PermissionsUserMapping{
-						Enabled: true,
-						BindID:  "email",
-					},
-				},
-			},
-			bitbucketServerConnections: []*schema.BitbucketServerConnection{
-				{
-					Authorization: &schema.BitbucketServerAuthorization{
-						IdentityProvider: schema.BitbucketServerIdentityProvider{
-							Username: &schema.BitbucketServerUsernameIdentity{
-								Type: "username",
-							},
-						},
-						Oauth: schema.BitbucketServerOAuth{
-							ConsumerKey: "sourcegraph",
-							SigningKey:  bogusKey,
-						},
-						Ttl: "15m",
+			expAuthzAllowAccessByDefault: true,
+			expAuthzProviders: providersEqual(
+				gitlabAuthzProviderParams{
+					OAuthOp: gitlab.OAuthProviderOp{
+						BaseURL:           mustURLParse(t, "https://gitlab.mine"),
+						Token:             "asdf",
+						CacheTTL:          48 * time.Hour,
+						MinBatchThreshold: 200,
+						MaxBatchRequests:  300,
 					},
-					Url:      "https://bitbucketserver.mycorp.org",
-					Username: "admin",
-					Token:    "secret-token",
 				},
-			},
-			expAuthzAllowAccessByDefault: false,
-			expSeriousProblems:           []string{"The permissions user mapping (site configuration `permissions.userMapping`) cannot be enabled when \"bitbucketServer\" authorization providers are in use. Blocking access to all repositories until the conflict is resolved."},
+			),
 		},
 	}
 
@@ -518,6 +602,9 @@ func Test_authzProvidersFromConfig(t *testing.T) {
 		store := fakeStore{
 			gitlabs:          test.gitlabConnections,
 			bitbucketServers: test.bitbucketServerConnections,
+			bitbucketClouds:  test.bitbucketCloudConnections,
+			gitolites:        test.gitoliteConnections,
+			others:           test.otherConnections,
 		}
 
 		allowAccessByDefault, authzProviders, seriousProblems, _ :=
@@ -554,6 +641,9 @@ type fakeStore struct {
 	gitlabs          []*schema.GitLabConnection
 	githubs          []*schema.GitHubConnection
 	bitbucketServers []*schema.BitbucketServerConnection
+	bitbucketClouds  []*schema.BitbucketCloudConnection
+	gitolites        []*schema.GitoliteConnection
+	others           []*schema.OtherExternalServiceConnection
 }
 
 func (s fakeStore) ListGitHubConnections(context.Context) ([]*schema.GitHubConnection, error) {
@@ -567,3 +657,15 @@ func (s fakeStore) ListGitLabConnections(context.Context) ([]*schema.GitLabConne
 func (s fakeStore) ListBitbucketServerConnections(context.Context) ([]*schema.BitbucketServerConnection, error) {
 	return s.bitbucketServers, nil
 }
+
+func (s fakeStore) ListBitbucketCloudConnections(context.Context) ([]*schema.BitbucketCloudConnection, error) {
+	return s.bitbucketClouds, nil
+}
+
+func (s fakeStore) ListGitoliteConnections(context.Context) ([]*schema.GitoliteConnection, error) {
+	return s.gitolites, nil
+}
+
+func (s fakeStore) ListOtherExternalServicesConnections(context.Context) ([]*schema.OtherExternalServiceConnection, error) {
+	return s.others, nil
+}
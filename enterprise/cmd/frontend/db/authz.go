@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/RoaringBitmap/roaring"
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
@@ -122,13 +123,21 @@ func (s *authzStore) AuthorizedRepos(ctx context.Context, args *db.AuthorizedRep
 		Perm:   args.Perm,
 		Type:   args.Type,
 	}
-	if err := s.store.LoadUserPermissions(ctx, p); err != nil {
-		if err == authz.ErrPermsNotFound {
-			return []*types.Repo{}, nil
-		}
+	if err := s.store.LoadUserPermissions(ctx, p); err == authz.ErrPermsNotFound {
+		p.IDs = roaring.NewBitmap()
+	} else if err != nil {
 		return nil, err
 	}
 
+	// Permissions granted to organizations apply to their current members.
+	if args.Type == authz.PermRepos {
+		ids, err := s.store.LoadUserOrgPermissions(ctx, args.UserID, args.Perm)
+		if err != nil {
+			return nil, errors.Wrap(err, "load user org permissions")
+		}
+		p.IDs.Or(ids)
+	}
+
 	perms := p.AuthorizedRepos(args.Repos)
 	filtered := make([]*types.Repo, len(perms))
 	for i, r := range perms {
@@ -32,6 +32,7 @@ func TestIntegration_PermsStore(t *testing.T) {
 		{"PermsStore/GrantPendingPermissions", testPermsStore_GrantPendingPermissions(db)},
 		{"PermsStore/DeleteAllUserPermissions", testPermsStore_DeleteAllUserPermissions(db)},
 		{"PermsStore/DeleteAllUserPendingPermissions", testPermsStore_DeleteAllUserPendingPermissions(db)},
+		{"PermsStore/OrgPermissions", testPermsStore_OrgPermissions(db)},
 		{"PermsStore/DatabaseDeadlocks", testPermsStore_DatabaseDeadlocks(db)},
 
 		{"PermsStore/ListExternalAccounts", testPermsStore_ListExternalAccounts(db)},
@@ -21,7 +21,8 @@ var ErrPermsUpdatedAtNotSet = errors.New("permissions UpdatedAt timestamp must b
 
 // PermsStore is the unified interface for managing permissions explicitly in the database.
 // It is concurrency-safe and maintains data consistency over the 'user_permissions',
-// 'repo_permissions', 'user_pending_permissions', and 'repo_pending_permissions' tables.
+// 'repo_permissions', 'user_pending_permissions', 'repo_pending_permissions' and
+// 'org_repo_permissions' tables.
 type PermsStore struct {
 	db    dbutil.DB
 	clock func() time.Time
@@ -1123,6 +1124,116 @@ AND bind_id IN (%s)`,
 	return nil
 }
 
+// SetRepoOrgPermissions replaces the organizations whose members have the given permission to
+// the repository. Unlike the permissions of users, the members of the organizations are resolved
+// when the permissions are loaded, so that they follow changes of membership.
+func (s *PermsStore) SetRepoOrgPermissions(ctx context.Context, repoID int32, perm authz.Perms, orgIDs []int32) (err error) {
+	if Mocks.Perms.SetRepoOrgPermissions != nil {
+		return Mocks.Perms.SetRepoOrgPermissions(ctx, repoID, perm, orgIDs)
+	}
+
+	ctx, save := s.observe(ctx, "SetRepoOrgPermissions", "")
+	defer func() {
+		save(&err,
+			otlog.Int32("repoID", repoID),
+			otlog.String("perm", perm.String()),
+			otlog.Object("orgIDs", orgIDs),
+		)
+	}()
+
+	var txs *PermsStore
+	if s.inTx() {
+		txs = s
+	} else {
+		txs, err = s.Transact(ctx)
+		if err != nil {
+			return err
+		}
+		defer txs.Done(&err)
+	}
+
+	q := sqlf.Sprintf(`
+-- source: enterprise/cmd/frontend/db/perms_store.go:PermsStore.SetRepoOrgPermissions
+DELETE FROM org_repo_permissions
+WHERE repo_id = %s
+AND permission = %s
+`, repoID, perm.String())
+	if err = txs.execute(ctx, q); err != nil {
+		return errors.Wrap(err, "execute delete repo org permissions query")
+	}
+
+	if len(orgIDs) == 0 {
+		return nil
+	}
+
+	updatedAt := txs.clock()
+	items := make([]*sqlf.Query, len(orgIDs))
+	for i := range orgIDs {
+		items[i] = sqlf.Sprintf("(%s, %s, %s, %s)", repoID, perm.String(), orgIDs[i], updatedAt)
+	}
+	q = sqlf.Sprintf(`
+-- source: enterprise/cmd/frontend/db/perms_store.go:PermsStore.SetRepoOrgPermissions
+INSERT INTO org_repo_permissions
+  (repo_id, permission, org_id, updated_at)
+VALUES
+  %s
+ON CONFLICT DO NOTHING
+`, sqlf.Join(items, ","))
+	if err = txs.execute(ctx, q); err != nil {
+		return errors.Wrap(err, "execute insert repo org permissions query")
+	}
+
+	return nil
+}
+
+// LoadUserOrgPermissions returns the IDs of the repositories that the user has the given
+// permission to as a member of the organizations set by SetRepoOrgPermissions.
+func (s *PermsStore) LoadUserOrgPermissions(ctx context.Context, userID int32, perm authz.Perms) (ids *roaring.Bitmap, err error) {
+	if Mocks.Perms.LoadUserOrgPermissions != nil {
+		return Mocks.Perms.LoadUserOrgPermissions(ctx, userID, perm)
+	}
+
+	ctx, save := s.observe(ctx, "LoadUserOrgPermissions", "")
+	defer func() { save(&err, otlog.Int32("userID", userID), otlog.String("perm", perm.String())) }()
+
+	q := sqlf.Sprintf(`
+-- source: enterprise/cmd/frontend/db/perms_store.go:PermsStore.LoadUserOrgPermissions
+SELECT DISTINCT perms.repo_id
+FROM org_repo_permissions AS perms
+JOIN org_members AS members ON members.org_id = perms.org_id
+JOIN orgs ON orgs.id = perms.org_id
+WHERE members.user_id = %s
+AND perms.permission = %s
+AND orgs.deleted_at IS NULL
+`, userID, perm.String())
+	return s.loadIDs(ctx, q)
+}
+
+// LoadRepoOrgPermissions returns the IDs of the users who have the given permission to the
+// repository as members of the organizations set by SetRepoOrgPermissions.
+func (s *PermsStore) LoadRepoOrgPermissions(ctx context.Context, repoID int32, perm authz.Perms) (ids *roaring.Bitmap, err error) {
+	if Mocks.Perms.LoadRepoOrgPermissions != nil {
+		return Mocks.Perms.LoadRepoOrgPermissions(ctx, repoID, perm)
+	}
+
+	ctx, save := s.observe(ctx, "LoadRepoOrgPermissions", "")
+	defer func() { save(&err, otlog.Int32("repoID", repoID), otlog.String("perm", perm.String())) }()
+
+	q := sqlf.Sprintf(`
+-- source: enterprise/cmd/frontend/db/perms_store.go:PermsStore.LoadRepoOrgPermissions
+SELECT DISTINCT members.user_id
+FROM org_repo_permissions AS perms
+JOIN org_members AS members ON members.org_id = perms.org_id
+JOIN orgs ON orgs.id = perms.org_id
+JOIN users ON users.id = members.user_id
+WHERE perms.repo_id = %s
+AND perms.permission = %s
+AND orgs.deleted_at IS NULL
+AND users.deleted_at IS NULL
+`, repoID, perm.String())
+	return s.loadIDs(ctx, q)
+}
+
 func (s *PermsStore) execute(ctx context.Context, q *sqlf.Query, vs ...interface{}) (err error) {
 	ctx, save := s.observe(ctx, "execute", "")
 	defer func() { save(&err, otlog.Object("q", q)) }()
@@ -1211,6 +1322,40 @@ func (s *PermsStore) load(ctx context.Context, q *sqlf.Query) (*permsLoadValues,
 	return vals, nil
 }
 
+// loadIDs runs the query, which must select a single integer column, and returns the values
+// of all rows.
+func (s *PermsStore) loadIDs(ctx context.Context, q *sqlf.Query) (*roaring.Bitmap, error) {
+	var err error
+	ctx, save := s.observe(ctx, "loadIDs", "")
+	defer func() {
+		save(&err,
+			otlog.String("Query.Query", q.Query(sqlf.PostgresBindVar)),
+			otlog.Object("Query.Args", q.Args()),
+		)
+	}()
+
+	var rows *sql.Rows
+	rows, err = s.db.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := roaring.NewBitmap()
+	for rows.Next() {
+		var id int32
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids.Add(uint32(id))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
 // batchLoadIDs runs the query and returns unmarshalled IDs with their corresponding object ID value.
 func (s *PermsStore) batchLoadIDs(ctx context.Context, q *sqlf.Query) (map[int32]*roaring.Bitmap, error) {
 	var err error
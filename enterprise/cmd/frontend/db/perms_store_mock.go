@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 
+	"github.com/RoaringBitmap/roaring"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
 )
@@ -15,6 +16,9 @@ type MockPerms struct {
 	SetUserPermissions           func(ctx context.Context, p *authz.UserPermissions) error
 	SetRepoPermissions           func(ctx context.Context, p *authz.RepoPermissions) error
 	SetRepoPendingPermissions    func(ctx context.Context, accounts *extsvc.ExternalAccounts, p *authz.RepoPermissions) error
+	SetRepoOrgPermissions        func(ctx context.Context, repoID int32, perm authz.Perms, orgIDs []int32) error
+	LoadUserOrgPermissions       func(ctx context.Context, userID int32, perm authz.Perms) (*roaring.Bitmap, error)
+	LoadRepoOrgPermissions       func(ctx context.Context, repoID int32, perm authz.Perms) (*roaring.Bitmap, error)
 	ListPendingUsers             func(ctx context.Context) ([]string, error)
 	ListExternalAccounts         func(ctx context.Context, userID int32) ([]*extsvc.ExternalAccount, error)
 	GetUserIDsByExternalAccounts func(ctx context.Context, accounts *extsvc.ExternalAccounts) (map[string]int32, error)
@@ -25,7 +25,7 @@ func cleanupPermsTables(t *testing.T, s *PermsStore) {
 		return
 	}
 
-	q := `TRUNCATE TABLE user_permissions, repo_permissions, user_pending_permissions, repo_pending_permissions;`
+	q := `TRUNCATE TABLE user_permissions, repo_permissions, user_pending_permissions, repo_pending_permissions, org_repo_permissions;`
 	if err := s.execute(context.Background(), sqlf.Sprintf(q)); err != nil {
 		t.Fatal(err)
 	}
@@ -1630,6 +1630,81 @@ func testPermsStore_DeleteAllUserPendingPermissions(db *sql.DB) func(*testing.T)
 	}
 }
 
+func testPermsStore_OrgPermissions(db *sql.DB) func(*testing.T) {
+	return func(t *testing.T) {
+		s := NewPermsStore(db, clock)
+		t.Cleanup(func() {
+			cleanupPermsTables(t, s)
+			cleanupUsersTable(t, s)
+			if t.Failed() {
+				return
+			}
+			if err := s.execute(context.Background(), sqlf.Sprintf(`TRUNCATE TABLE orgs RESTART IDENTITY CASCADE`)); err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		ctx := context.Background()
+
+		qs := []*sqlf.Query{
+			sqlf.Sprintf(`INSERT INTO users(username) VALUES('alice')`), // ID=1
+			sqlf.Sprintf(`INSERT INTO users(username) VALUES('bob')`),   // ID=2
+			sqlf.Sprintf(`INSERT INTO orgs(name) VALUES('acme')`),       // ID=1
+			sqlf.Sprintf(`INSERT INTO orgs(name) VALUES('other')`),      // ID=2
+			sqlf.Sprintf(`INSERT INTO org_members(org_id, user_id) VALUES(1, 1)`),
+			sqlf.Sprintf(`INSERT INTO org_members(org_id, user_id) VALUES(2, 2)`),
+		}
+		for _, q := range qs {
+			if err := s.execute(ctx, q); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := s.SetRepoOrgPermissions(ctx, 1, authz.Read, []int32{1, 2}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetRepoOrgPermissions(ctx, 2, authz.Read, []int32{1}); err != nil {
+			t.Fatal(err)
+		}
+
+		checkUser := func(userID int32, want []int) {
+			t.Helper()
+			ids, err := s.LoadUserOrgPermissions(ctx, userID, authz.Read)
+			if err != nil {
+				t.Fatal(err)
+			}
+			equal(t, fmt.Sprintf("repo IDs of user=%d", userID), want, bitmapToArray(ids))
+		}
+		checkUser(1, []int{1, 2})
+		checkUser(2, []int{1})
+
+		ids, err := s.LoadRepoOrgPermissions(ctx, 1, authz.Read)
+		if err != nil {
+			t.Fatal(err)
+		}
+		equal(t, "user IDs of repo=1", []int{1, 2}, bitmapToArray(ids))
+
+		// Replacing the organizations of a repository revokes the permissions of the others.
+		if err := s.SetRepoOrgPermissions(ctx, 1, authz.Read, []int32{2}); err != nil {
+			t.Fatal(err)
+		}
+		checkUser(1, []int{2})
+
+		// Members removed from an organization lose its permissions, new ones gain them.
+		qs = []*sqlf.Query{
+			sqlf.Sprintf(`DELETE FROM org_members WHERE org_id = 1 AND user_id = 1`),
+			sqlf.Sprintf(`INSERT INTO org_members(org_id, user_id) VALUES(1, 2)`),
+		}
+		for _, q := range qs {
+			if err := s.execute(ctx, q); err != nil {
+				t.Fatal(err)
+			}
+		}
+		checkUser(1, []int{})
+		checkUser(2, []int{1, 2})
+	}
+}
+
 func testPermsStore_DatabaseDeadlocks(db *sql.DB) func(*testing.T) {
 	return func(t *testing.T) {
 		s := NewPermsStore(db, time.Now)
@@ -0,0 +1,87 @@
+package bitbucketcloud
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// NewAuthzProviders returns the set of Bitbucket Cloud authz providers derived from the connections.
+// It also returns any validation problems with the config, separating these into "serious problems" and
+// "warnings". "Serious problems" are those that should make Sourcegraph set authz.allowAccessByDefault
+// to false. "Warnings" are all other validation problems.
+func NewAuthzProviders(
+	conns []*schema.BitbucketCloudConnection,
+) (ps []authz.Provider, problems []string, warnings []string) {
+	for _, c := range conns {
+		p, err := newAuthzProvider(c)
+		if err != nil {
+			problems = append(problems, err.Error())
+		} else if p != nil {
+			ps = append(ps, p)
+		}
+	}
+
+	for _, p := range ps {
+		for _, problem := range p.Validate() {
+			warnings = append(warnings, fmt.Sprintf("Bitbucket Cloud config for %s was invalid: %s", p.ServiceID(), problem))
+		}
+	}
+
+	return ps, problems, warnings
+}
+
+func newAuthzProvider(c *schema.BitbucketCloudConnection) (authz.Provider, error) {
+	if c.Authorization == nil {
+		return nil, nil
+	}
+
+	errs := new(multierror.Error)
+
+	baseURL, err := url.Parse(c.Url)
+	if err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	apiURLStr := c.ApiURL
+	if apiURLStr == "" {
+		apiURLStr = "https://api.bitbucket.org"
+	}
+	apiURL, err := url.Parse(apiURLStr)
+	if err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	if errs.ErrorOrNil() != nil {
+		return nil, errs
+	}
+
+	cli := bitbucketcloud.NewClient(extsvc.NormalizeBaseURL(apiURL), nil)
+	cli.Username = c.Username
+	cli.AppPassword = c.AppPassword
+
+	// The repositories of the connection are those of the account's own workspace and of
+	// the configured teams.
+	workspaces := []string{c.Username}
+	for _, t := range c.Teams {
+		if t != c.Username {
+			workspaces = append(workspaces, t)
+		}
+	}
+
+	var p authz.Provider
+	switch idp := c.Authorization.IdentityProvider; {
+	case idp.Username != nil:
+		p = NewProvider(cli, extsvc.NewCodeHost(baseURL, bitbucketcloud.ServiceType), workspaces)
+	default:
+		errs = multierror.Append(errs, errors.Errorf("No identityProvider was specified"))
+	}
+
+	return p, errs.ErrorOrNil()
+}
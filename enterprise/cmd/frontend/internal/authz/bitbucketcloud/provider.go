@@ -0,0 +1,219 @@
+// Package bitbucketcloud contains an authorization provider for Bitbucket Cloud.
+package bitbucketcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
+)
+
+// Provider is an implementation of AuthzProvider that provides repository permissions as
+// determined from the repository permissions of Bitbucket Cloud workspaces.
+type Provider struct {
+	client     *bitbucketcloud.Client
+	codeHost   *extsvc.CodeHost
+	workspaces []string
+	pageSize   int // Page size to use in paginated requests.
+}
+
+var _ authz.Provider = (*Provider)(nil)
+
+// NewProvider returns a new Bitbucket Cloud authorization provider that uses the given
+// bitbucketcloud.Client to read the repository permissions of the given workspaces. The client
+// must be authenticated as an administrator of all workspaces. It assumes usernames of
+// Sourcegraph accounts match 1-1 with nicknames of Bitbucket Cloud users.
+func NewProvider(cli *bitbucketcloud.Client, codeHost *extsvc.CodeHost, workspaces []string) *Provider {
+	return &Provider{
+		client:     cli,
+		codeHost:   codeHost,
+		workspaces: workspaces,
+		pageSize:   100,
+	}
+}
+
+// Validate validates that the Provider can read the repository permissions of its workspaces.
+func (p *Provider) Validate() (problems []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, w := range p.workspaces {
+		if _, _, err := p.client.RepoPermissions(ctx, &bitbucketcloud.PageToken{Pagelen: 1}, w, ""); err != nil {
+			problems = append(problems, fmt.Sprintf("reading repository permissions of workspace %q: %s", w, err))
+		}
+	}
+	return problems
+}
+
+// ServiceID returns the absolute URL that identifies Bitbucket Cloud.
+func (p *Provider) ServiceID() string { return p.codeHost.ServiceID }
+
+// ServiceType returns the type of this Provider, namely, "bitbucketCloud".
+func (p *Provider) ServiceType() string { return p.codeHost.ServiceType }
+
+// FetchAccount satisfies the authz.Provider interface. It returns the account of the workspace
+// member whose nickname is the username of the given user. It fails if the nickname is shared by
+// more than one Bitbucket Cloud user, since the account can't be told apart then.
+func (p *Provider) FetchAccount(ctx context.Context, user *types.User, _ []*extsvc.ExternalAccount) (*extsvc.ExternalAccount, error) {
+	if user == nil {
+		return nil, nil
+	}
+
+	bitbucketUser, err := p.workspaceMember(ctx, user.Username)
+	if err != nil || bitbucketUser == nil {
+		return nil, err
+	}
+
+	accountData, err := json.Marshal(bitbucketUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return &extsvc.ExternalAccount{
+		UserID: user.ID,
+		ExternalAccountSpec: extsvc.ExternalAccountSpec{
+			ServiceType: p.codeHost.ServiceType,
+			ServiceID:   p.codeHost.ServiceID,
+			AccountID:   bitbucketUser.UUID,
+		},
+		ExternalAccountData: extsvc.ExternalAccountData{
+			AccountData: (*json.RawMessage)(&accountData),
+		},
+	}, nil
+}
+
+// RepoPerms returns the permissions the given external account has in relation to the given set
+// of repos. Public repos are readable by everyone, private repos only by the users that have
+// permissions on them in their workspace.
+//
+// Every call fetches the permissions of the account from Bitbucket Cloud, so this provider
+// should be used with `permissions.backgroundSync` enabled, in which case this method isn't
+// called.
+func (p *Provider) RepoPerms(ctx context.Context, acct *extsvc.ExternalAccount, repos []*types.Repo) ([]authz.RepoPerms, error) {
+	readable := make(map[string]bool)
+	if acct != nil && extsvc.IsHostOfAccount(p.codeHost, acct) {
+		ids, err := p.FetchUserPerms(ctx, acct)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			readable[string(id)] = true
+		}
+	}
+
+	perms := make([]authz.RepoPerms, 0, len(repos))
+	for _, r := range repos {
+		if !extsvc.IsHostOfRepo(p.codeHost, &r.ExternalRepo) {
+			continue
+		}
+		if !r.Private || readable[r.ExternalRepo.ID] {
+			perms = append(perms, authz.RepoPerms{Repo: r, Perms: authz.Read})
+		}
+	}
+	return perms, nil
+}
+
+// FetchUserPerms returns a list of repository UUIDs that the given account has read access to
+// in the workspaces of the provider. The repository UUID has the same value as it would be used
+// as api.ExternalRepoSpec.ID.
+//
+// This method may return partial but valid results in case of error, and it is up to
+// callers to decide whether to discard.
+func (p *Provider) FetchUserPerms(ctx context.Context, account *extsvc.ExternalAccount) ([]extsvc.ExternalRepoID, error) {
+	switch {
+	case account == nil:
+		return nil, errors.New("no account provided")
+	case !extsvc.IsHostOfAccount(p.codeHost, account):
+		return nil, fmt.Errorf("not a code host of the account: want %s but have %s",
+			p.codeHost.ServiceID, account.ExternalAccountSpec.ServiceID)
+	}
+
+	var ids []extsvc.ExternalRepoID
+	err := p.repoPermissions(ctx, fmt.Sprintf("user.uuid=%q", account.AccountID), func(perm *bitbucketcloud.RepoPermission) {
+		ids = append(ids, extsvc.ExternalRepoID(perm.Repository.UUID))
+	})
+	return ids, err
+}
+
+// FetchRepoPerms returns a list of user UUIDs who have read access to the given repo. The user
+// UUID has the same value as it would be used as extsvc.ExternalAccount.AccountID. The returned
+// list includes both direct access and access inherited from group membership.
+//
+// This method may return partial but valid results in case of error, and it is up to
+// callers to decide whether to discard.
+func (p *Provider) FetchRepoPerms(ctx context.Context, repo *api.ExternalRepoSpec) ([]extsvc.ExternalAccountID, error) {
+	switch {
+	case repo == nil:
+		return nil, errors.New("no repo provided")
+	case !extsvc.IsHostOfRepo(p.codeHost, repo):
+		return nil, fmt.Errorf("not a code host of the repo: want %s but have %s",
+			p.codeHost.ServiceID, repo.ServiceID)
+	}
+
+	var ids []extsvc.ExternalAccountID
+	err := p.repoPermissions(ctx, fmt.Sprintf("repository.uuid=%q", repo.ID), func(perm *bitbucketcloud.RepoPermission) {
+		ids = append(ids, extsvc.ExternalAccountID(perm.User.UUID))
+	})
+	return ids, err
+}
+
+// repoPermissions calls f for every repository permission in the workspaces of the provider
+// that matches the given query. Every permission grants read access.
+func (p *Provider) repoPermissions(ctx context.Context, query string, f func(*bitbucketcloud.RepoPermission)) error {
+	for _, w := range p.workspaces {
+		for page := (&bitbucketcloud.PageToken{Pagelen: p.pageSize}); ; {
+			perms, next, err := p.client.RepoPermissions(ctx, page, w, query)
+			if err != nil {
+				return errors.Wrapf(err, "listing repository permissions of workspace %q", w)
+			}
+
+			for _, perm := range perms {
+				f(perm)
+			}
+
+			if !next.HasMore() {
+				break
+			}
+			page = next
+		}
+	}
+	return nil
+}
+
+// workspaceMember returns the member of the workspaces of the provider with the given
+// nickname, or nil if there is none. Nicknames aren't unique on Bitbucket Cloud, so members are
+// told apart by their UUID, and it's an error if members with different UUIDs have the nickname.
+func (p *Provider) workspaceMember(ctx context.Context, nickname string) (*bitbucketcloud.Account, error) {
+	var member *bitbucketcloud.Account
+	for _, w := range p.workspaces {
+		for page := (&bitbucketcloud.PageToken{Pagelen: p.pageSize}); ; {
+			members, next, err := p.client.WorkspaceMembers(ctx, page, w)
+			if err != nil {
+				return nil, errors.Wrapf(err, "listing members of workspace %q", w)
+			}
+
+			for _, m := range members {
+				if m.User.Nickname != nickname {
+					continue
+				}
+				if member != nil && member.UUID != m.User.UUID {
+					return nil, errors.Errorf("more than one workspace member has the nickname %q", nickname)
+				}
+				member = &m.User
+			}
+
+			if !next.HasMore() {
+				break
+			}
+			page = next
+		}
+	}
+	return member, nil
+}
@@ -0,0 +1,209 @@
+package bitbucketcloud
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
+)
+
+// mockRoutes maps "METHOD /path?query" to response bodies. Unknown routes respond with 404.
+type mockRoutes map[string]string
+
+func (m mockRoutes) Do(req *http.Request) (*http.Response, error) {
+	body, ok := m[req.Method+" "+req.URL.RequestURI()]
+	code := http.StatusOK
+	if !ok {
+		code = http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: code,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func q(query string) string {
+	return url.Values{"pagelen": {"100"}, "q": {query}}.Encode()
+}
+
+func newTestProvider(routes mockRoutes) *Provider {
+	cli := bitbucketcloud.NewClient(&url.URL{Scheme: "https", Host: "api.bitbucket.org", Path: "/"}, routes)
+	codeHost := extsvc.NewCodeHost(&url.URL{Scheme: "https", Host: "bitbucket.org"}, bitbucketcloud.ServiceType)
+	return NewProvider(cli, codeHost, []string{"alice", "sglocal"})
+}
+
+var testRoutes = mockRoutes{
+	"GET /2.0/workspaces/alice/members?pagelen=100": `{"values": [
+		{"user": {"uuid": "{alice}", "nickname": "alice"}}
+	]}`,
+	"GET /2.0/workspaces/sglocal/members?pagelen=100": `{"values": [
+		{"user": {"uuid": "{alice}", "nickname": "alice"}},
+		{"user": {"uuid": "{bob}", "nickname": "bob"}}
+	]}`,
+
+	"GET /2.0/workspaces/alice/permissions/repositories?" + q(`user.uuid="{bob}"`): `{"values": []}`,
+	"GET /2.0/workspaces/sglocal/permissions/repositories?" + q(`user.uuid="{bob}"`): `{
+		"values": [{"permission": "read", "user": {"uuid": "{bob}"}, "repository": {"uuid": "{mux}"}}],
+		"next": "https://api.bitbucket.org/2.0/workspaces/sglocal/permissions/repositories?page=2"
+	}`,
+	"GET /2.0/workspaces/sglocal/permissions/repositories?page=2": `{
+		"values": [{"permission": "write", "user": {"uuid": "{bob}"}, "repository": {"uuid": "{vegeta}"}}]
+	}`,
+
+	"GET /2.0/workspaces/alice/permissions/repositories?" + q(`repository.uuid="{mux}"`): `{"values": []}`,
+	"GET /2.0/workspaces/sglocal/permissions/repositories?" + q(`repository.uuid="{mux}"`): `{"values": [
+		{"permission": "admin", "user": {"uuid": "{alice}"}, "repository": {"uuid": "{mux}"}},
+		{"permission": "read", "user": {"uuid": "{bob}"}, "repository": {"uuid": "{mux}"}}
+	]}`,
+}
+
+func TestProvider_FetchAccount(t *testing.T) {
+	p := newTestProvider(testRoutes)
+	ctx := context.Background()
+
+	acct, err := p.FetchAccount(ctx, &types.User{ID: 2, Username: "bob"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var data bitbucketcloud.Account
+	if err := json.Unmarshal(*acct.AccountData, &data); err != nil {
+		t.Fatal(err)
+	}
+	if acct.UserID != 2 || acct.AccountID != "{bob}" || acct.ServiceID != "https://bitbucket.org/" || data.Nickname != "bob" {
+		t.Errorf("unexpected account %+v with data %+v", acct, data)
+	}
+
+	acct, err = p.FetchAccount(ctx, &types.User{ID: 3, Username: "carol"}, nil)
+	if err != nil || acct != nil {
+		t.Errorf("got account %+v (err: %v), want nil for a user who is not a workspace member", acct, err)
+	}
+
+	// alice is a member of both workspaces, but with the same UUID.
+	acct, err = p.FetchAccount(ctx, &types.User{ID: 4, Username: "alice"}, nil)
+	if err != nil || acct == nil || acct.AccountID != "{alice}" {
+		t.Errorf("got account %+v (err: %v), want the account of alice", acct, err)
+	}
+
+	// Nicknames aren't unique, so a user whose nickname is shared by another
+	// user can't be told apart from them.
+	routes := mockRoutes{}
+	for route, body := range testRoutes {
+		routes[route] = body
+	}
+	routes["GET /2.0/workspaces/alice/members?pagelen=100"] = `{"values": [
+		{"user": {"uuid": "{alice}", "nickname": "alice"}},
+		{"user": {"uuid": "{other-bob}", "nickname": "bob"}}
+	]}`
+	acct, err = newTestProvider(routes).FetchAccount(ctx, &types.User{ID: 2, Username: "bob"}, nil)
+	if err == nil || acct != nil {
+		t.Errorf("got account %+v (err: %v), want an error for an ambiguous nickname", acct, err)
+	}
+}
+
+func TestProvider_FetchUserPerms(t *testing.T) {
+	p := newTestProvider(testRoutes)
+
+	ids, err := p.FetchUserPerms(context.Background(), &extsvc.ExternalAccount{
+		ExternalAccountSpec: extsvc.ExternalAccountSpec{
+			ServiceType: p.ServiceType(),
+			ServiceID:   p.ServiceID(),
+			AccountID:   "{bob}",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]extsvc.ExternalRepoID{"{mux}", "{vegeta}"}, ids); diff != "" {
+		t.Error(diff)
+	}
+
+	_, err = p.FetchUserPerms(context.Background(), &extsvc.ExternalAccount{
+		ExternalAccountSpec: extsvc.ExternalAccountSpec{ServiceType: "github", ServiceID: "https://github.com/"},
+	})
+	if err == nil {
+		t.Error("got no error for an account of another code host")
+	}
+}
+
+func TestProvider_FetchRepoPerms(t *testing.T) {
+	p := newTestProvider(testRoutes)
+
+	ids, err := p.FetchRepoPerms(context.Background(), &api.ExternalRepoSpec{
+		ID:          "{mux}",
+		ServiceType: p.ServiceType(),
+		ServiceID:   p.ServiceID(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]extsvc.ExternalAccountID{"{alice}", "{bob}"}, ids); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestProvider_RepoPerms(t *testing.T) {
+	p := newTestProvider(testRoutes)
+	repo := func(id api.RepoID, uuid string, private bool) *types.Repo {
+		return &types.Repo{
+			ID:      id,
+			Private: private,
+			ExternalRepo: api.ExternalRepoSpec{
+				ID:          uuid,
+				ServiceType: p.ServiceType(),
+				ServiceID:   p.ServiceID(),
+			},
+		}
+	}
+	mux, vegeta, secret, public := repo(1, "{mux}", true), repo(2, "{vegeta}", true), repo(3, "{secret}", true), repo(4, "{public}", false)
+	repos := []*types.Repo{mux, vegeta, secret, public}
+
+	bob := &extsvc.ExternalAccount{
+		ExternalAccountSpec: extsvc.ExternalAccountSpec{
+			ServiceType: p.ServiceType(),
+			ServiceID:   p.ServiceID(),
+			AccountID:   "{bob}",
+		},
+	}
+
+	for _, tc := range []struct {
+		name string
+		acct *extsvc.ExternalAccount
+		want []authz.RepoPerms
+	}{
+		{
+			name: "no account",
+			want: []authz.RepoPerms{{Repo: public, Perms: authz.Read}},
+		},
+		{
+			name: "with account",
+			acct: bob,
+			want: []authz.RepoPerms{
+				{Repo: mux, Perms: authz.Read},
+				{Repo: vegeta, Perms: authz.Read},
+				{Repo: public, Perms: authz.Read},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			perms, err := p.RepoPerms(context.Background(), tc.acct, repos)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, perms); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Package gitolite contains an authorization provider for Gitolite.
+package gitolite
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitolite"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+)
+
+// Provider is an implementation of AuthzProvider that provides repository permissions as
+// determined from the access rules of a Gitolite server.
+type Provider struct {
+	host     string
+	codeHost *extsvc.CodeHost
+
+	// listPerms lists the permissions of the users of the Gitolite server. Only gitserver can
+	// talk to Gitolite, so this asks gitserver by default.
+	listPerms func(ctx context.Context, host string) (*gitolite.Perms, error)
+
+	// Listing the permissions runs `ssh info` and fetches the gitolite-admin repository, so the
+	// result is reused for permsTTL. mu is held while listing, so that concurrent syncs wait for
+	// a single listing.
+	mu      sync.Mutex
+	perms   *gitolite.Perms
+	expires time.Time
+	now     func() time.Time
+}
+
+// permsTTL is how long the listed permissions are reused. It matches the interval at which the
+// permissions syncer schedules a round of syncs, so every round reads the access rules once.
+const permsTTL = time.Minute
+
+var _ authz.Provider = (*Provider)(nil)
+
+// NewProvider returns a new Gitolite authorization provider for the Gitolite server at the
+// given host. It assumes usernames of Sourcegraph accounts match 1-1 with Gitolite user names.
+func NewProvider(host string) *Provider {
+	return &Provider{
+		host: host,
+		codeHost: &extsvc.CodeHost{
+			ServiceID:   gitolite.ServiceID(host),
+			ServiceType: gitolite.ServiceType,
+		},
+		listPerms: gitserver.DefaultClient.ListGitolitePerms,
+		now:       time.Now,
+	}
+}
+
+// cachedPerms returns the permissions of the users of the Gitolite server, listing them again
+// only if they were listed more than permsTTL ago.
+func (p *Provider) cachedPerms(ctx context.Context) (*gitolite.Perms, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.perms != nil && p.now().Before(p.expires) {
+		return p.perms, nil
+	}
+
+	perms, err := p.listPerms(ctx, p.host)
+	if err != nil {
+		return nil, err
+	}
+	p.perms, p.expires = perms, p.now().Add(permsTTL)
+	return perms, nil
+}
+
+// Validate validates that the access rules of the Gitolite server can be read.
+func (p *Provider) Validate() []string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := p.listPerms(ctx, p.host); err != nil {
+		return []string{err.Error()}
+	}
+	return nil
+}
+
+// ServiceID returns the host of the Gitolite server.
+func (p *Provider) ServiceID() string { return p.codeHost.ServiceID }
+
+// ServiceType returns the type of this Provider, namely, "gitolite".
+func (p *Provider) ServiceType() string { return p.codeHost.ServiceType }
+
+// FetchAccount satisfies the authz.Provider interface. It returns the account of the Gitolite
+// user whose name is the username of the given user, if that user has a key.
+func (p *Provider) FetchAccount(ctx context.Context, user *types.User, _ []*extsvc.ExternalAccount) (*extsvc.ExternalAccount, error) {
+	if user == nil {
+		return nil, nil
+	}
+
+	perms, err := p.cachedPerms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range perms.Users {
+		if name == user.Username {
+			return &extsvc.ExternalAccount{
+				UserID: user.ID,
+				ExternalAccountSpec: extsvc.ExternalAccountSpec{
+					ServiceType: p.codeHost.ServiceType,
+					ServiceID:   p.codeHost.ServiceID,
+					AccountID:   name,
+				},
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// RepoPerms returns the permissions the given external account has in relation to the given set
+// of repos.
+//
+// The access rules are read from the Gitolite server at most once per minute, so changes take up
+// to a minute to apply. This provider should still be used with `permissions.backgroundSync`
+// enabled, in which case this method isn't called.
+func (p *Provider) RepoPerms(ctx context.Context, acct *extsvc.ExternalAccount, repos []*types.Repo) ([]authz.RepoPerms, error) {
+	if acct == nil || !extsvc.IsHostOfAccount(p.codeHost, acct) {
+		return []authz.RepoPerms{}, nil
+	}
+
+	ids, err := p.FetchUserPerms(ctx, acct)
+	if err != nil {
+		return nil, err
+	}
+
+	readable := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		readable[string(id)] = true
+	}
+
+	perms := make([]authz.RepoPerms, 0, len(repos))
+	for _, r := range repos {
+		if extsvc.IsHostOfRepo(p.codeHost, &r.ExternalRepo) && readable[r.ExternalRepo.ID] {
+			perms = append(perms, authz.RepoPerms{Repo: r, Perms: authz.Read})
+		}
+	}
+	return perms, nil
+}
+
+// FetchUserPerms returns a list of the names of the repositories that the given account has
+// read access to. The repository name has the same value as it would be used as
+// api.ExternalRepoSpec.ID.
+func (p *Provider) FetchUserPerms(ctx context.Context, account *extsvc.ExternalAccount) ([]extsvc.ExternalRepoID, error) {
+	switch {
+	case account == nil:
+		return nil, errors.New("no account provided")
+	case !extsvc.IsHostOfAccount(p.codeHost, account):
+		return nil, fmt.Errorf("not a code host of the account: want %s but have %s",
+			p.codeHost.ServiceID, account.ExternalAccountSpec.ServiceID)
+	}
+
+	perms, err := p.cachedPerms(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing gitolite permissions")
+	}
+
+	var ids []extsvc.ExternalRepoID
+	for _, r := range perms.Repos {
+		for _, u := range r.Users {
+			if u == account.AccountID {
+				ids = append(ids, extsvc.ExternalRepoID(r.Name))
+				break
+			}
+		}
+	}
+	return ids, nil
+}
+
+// FetchRepoPerms returns a list of the names of the Gitolite users who have read access to the
+// given repo. The user name has the same value as it would be used as
+// extsvc.ExternalAccount.AccountID.
+func (p *Provider) FetchRepoPerms(ctx context.Context, repo *api.ExternalRepoSpec) ([]extsvc.ExternalAccountID, error) {
+	switch {
+	case repo == nil:
+		return nil, errors.New("no repo provided")
+	case !extsvc.IsHostOfRepo(p.codeHost, repo):
+		return nil, fmt.Errorf("not a code host of the repo: want %s but have %s",
+			p.codeHost.ServiceID, repo.ServiceID)
+	}
+
+	perms, err := p.cachedPerms(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing gitolite permissions")
+	}
+
+	for _, r := range perms.Repos {
+		if r.Name != repo.ID {
+			continue
+		}
+		ids := make([]extsvc.ExternalAccountID, 0, len(r.Users))
+		for _, u := range r.Users {
+			ids = append(ids, extsvc.ExternalAccountID(u))
+		}
+		return ids, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,190 @@
+package gitolite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitolite"
+)
+
+func newTestProvider(t *testing.T) *Provider {
+	p := NewProvider("git@gitolite.example.com")
+	p.listPerms = func(ctx context.Context, host string) (*gitolite.Perms, error) {
+		if host != "git@gitolite.example.com" {
+			t.Fatalf("unexpected host %q", host)
+		}
+		return &gitolite.Perms{
+			Users: []string{"alice", "bob"},
+			Repos: []*gitolite.RepoPerms{
+				{Name: "public", Users: []string{"alice", "bob"}},
+				{Name: "secret", Users: []string{"alice"}},
+				{Name: "nobody"},
+			},
+		}, nil
+	}
+	return p
+}
+
+func account(p *Provider, name string) *extsvc.ExternalAccount {
+	return &extsvc.ExternalAccount{
+		ExternalAccountSpec: extsvc.ExternalAccountSpec{
+			ServiceType: p.ServiceType(),
+			ServiceID:   p.ServiceID(),
+			AccountID:   name,
+		},
+	}
+}
+
+func TestProvider_FetchAccount(t *testing.T) {
+	p := newTestProvider(t)
+	ctx := context.Background()
+
+	acct, err := p.FetchAccount(ctx, &types.User{ID: 1, Username: "bob"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := account(p, "bob")
+	want.UserID = 1
+	if diff := cmp.Diff(want, acct); diff != "" {
+		t.Error(diff)
+	}
+
+	acct, err = p.FetchAccount(ctx, &types.User{ID: 2, Username: "carol"}, nil)
+	if err != nil || acct != nil {
+		t.Errorf("got account %+v (err: %v), want nil for a user without a Gitolite key", acct, err)
+	}
+}
+
+func TestProvider_FetchUserPerms(t *testing.T) {
+	p := newTestProvider(t)
+
+	ids, err := p.FetchUserPerms(context.Background(), account(p, "alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]extsvc.ExternalRepoID{"public", "secret"}, ids); diff != "" {
+		t.Error(diff)
+	}
+
+	other := account(p, "alice")
+	other.ServiceID = "git@other.example.com"
+	if _, err := p.FetchUserPerms(context.Background(), other); err == nil {
+		t.Error("got no error for an account of another code host")
+	}
+}
+
+func TestProvider_FetchRepoPerms(t *testing.T) {
+	p := newTestProvider(t)
+
+	for name, want := range map[string][]extsvc.ExternalAccountID{
+		"secret":  {"alice"},
+		"nobody":  {},
+		"missing": nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ids, err := p.FetchRepoPerms(context.Background(), &api.ExternalRepoSpec{
+				ID:          name,
+				ServiceType: p.ServiceType(),
+				ServiceID:   p.ServiceID(),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, ids); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestProvider_RepoPerms(t *testing.T) {
+	p := newTestProvider(t)
+	repo := func(id api.RepoID, name string) *types.Repo {
+		return &types.Repo{
+			ID: id,
+			ExternalRepo: api.ExternalRepoSpec{
+				ID:          name,
+				ServiceType: p.ServiceType(),
+				ServiceID:   p.ServiceID(),
+			},
+		}
+	}
+	public, secret, nobody := repo(1, "public"), repo(2, "secret"), repo(3, "nobody")
+	repos := []*types.Repo{public, secret, nobody}
+
+	for _, tc := range []struct {
+		name string
+		acct *extsvc.ExternalAccount
+		want []authz.RepoPerms
+	}{
+		{
+			name: "no account",
+			want: []authz.RepoPerms{},
+		},
+		{
+			name: "alice",
+			acct: account(p, "alice"),
+			want: []authz.RepoPerms{{Repo: public, Perms: authz.Read}, {Repo: secret, Perms: authz.Read}},
+		},
+		{
+			name: "bob",
+			acct: account(p, "bob"),
+			want: []authz.RepoPerms{{Repo: public, Perms: authz.Read}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			perms, err := p.RepoPerms(context.Background(), tc.acct, repos)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, perms); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestProvider_CachesPerms(t *testing.T) {
+	p := newTestProvider(t)
+	listPerms := p.listPerms
+	calls := 0
+	p.listPerms = func(ctx context.Context, host string) (*gitolite.Perms, error) {
+		calls++
+		return listPerms(ctx, host)
+	}
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	fetch := func() {
+		t.Helper()
+		if _, err := p.FetchUserPerms(ctx, account(p, "alice")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.FetchRepoPerms(ctx, &api.ExternalRepoSpec{
+			ID:          "secret",
+			ServiceType: p.ServiceType(),
+			ServiceID:   p.ServiceID(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fetch()
+	fetch()
+	if calls != 1 {
+		t.Errorf("got %d listings within the TTL, want 1", calls)
+	}
+
+	now = now.Add(permsTTL)
+	fetch()
+	if calls != 2 {
+		t.Errorf("got %d listings after the TTL, want 2", calls)
+	}
+}
@@ -0,0 +1,62 @@
+package groups
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// NewAuthzProviders returns the set of group-based authz providers derived from the groups in the
+// site config and the connections of external services of kind OTHER. There is one provider per
+// connection with a url, and none if no groups are configured.
+// It also returns any validation problems with the config, separating these into "serious problems" and
+// "warnings". "Serious problems" are those that should make Sourcegraph set authz.allowAccessByDefault
+// to false. "Warnings" are all other validation problems.
+func NewAuthzProviders(
+	groups []*schema.PermissionsGroup,
+	conns []*schema.OtherExternalServiceConnection,
+) (ps []authz.Provider, problems []string, warnings []string) {
+	if len(groups) == 0 {
+		return nil, nil, nil
+	}
+
+	for _, c := range conns {
+		if c.Url == "" {
+			warnings = append(warnings, fmt.Sprintf("Group permissions are not enforced for the repositories of an external service of kind OTHER without a url (repositories: %q)", c.Repos))
+			continue
+		}
+
+		serviceID, err := otherServiceID(c)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("Could not parse the url %q of an external service of kind OTHER: %s", c.Url, err))
+			continue
+		}
+
+		p, err := NewProvider(serviceID, groups)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		ps = append(ps, p)
+	}
+
+	return ps, problems, warnings
+}
+
+// otherServiceID returns the ExternalRepoSpec.ServiceID of the repositories of the given
+// external service connection of kind OTHER. It must match the service ID repo-updater assigns
+// to them: the url itself for repositories listed by src-expose, and the url without its path
+// for the others (see OtherSource in repo-updater).
+func otherServiceID(c *schema.OtherExternalServiceConnection) (string, error) {
+	u, err := url.Parse(c.Url)
+	if err != nil {
+		return "", err
+	}
+	if len(c.Repos) == 1 && c.Repos[0] == "src-expose" {
+		return c.Url, nil
+	}
+	u.Path, u.RawQuery = "", ""
+	return u.String(), nil
+}
@@ -0,0 +1,122 @@
+// Package groups contains an authorization provider that grants access to repositories based on
+// the groups users are members of, as reported by SAML and OpenID Connect identity providers.
+package groups
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// serviceType is the service type of the repositories of external services of kind OTHER.
+const serviceType = "other"
+
+// Provider is an implementation of AuthzProvider that provides repository permissions for the
+// repositories of an external service of kind OTHER, as determined from the groups of users and
+// the repository patterns configured for each group in the site configuration.
+type Provider struct {
+	codeHost *extsvc.CodeHost
+
+	// repos maps group names to the patterns of the names of the repositories their members can
+	// read. The patterns are anchored, so they must match the whole name.
+	repos map[string][]*regexp.Regexp
+
+	// listGroups lists the names of the groups the given user is a member of. The memberships are
+	// persisted when users sign in with SAML or OpenID Connect, so this reads them from the
+	// database by default.
+	listGroups func(ctx context.Context, userID int32) ([]string, error)
+}
+
+var _ authz.Provider = (*Provider)(nil)
+
+// NewProvider returns a new group-based authorization provider for the code host with the given
+// service ID. Repository patterns must match the whole repository name. It returns an error if any
+// of them is invalid.
+func NewProvider(serviceID string, groups []*schema.PermissionsGroup) (*Provider, error) {
+	repos := make(map[string][]*regexp.Regexp, len(groups))
+	for _, g := range groups {
+		for _, pattern := range g.Repos {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, errors.Wrapf(err, "invalid repository pattern %q of group %q", pattern, g.Name)
+			}
+			// 🚨 SECURITY: Unanchored patterns would grant access to every repository whose name
+			// merely contains a match.
+			repos[g.Name] = append(repos[g.Name], regexp.MustCompile("^(?:"+pattern+")$"))
+		}
+	}
+
+	return &Provider{
+		codeHost: &extsvc.CodeHost{
+			ServiceID:   serviceID,
+			ServiceType: serviceType,
+		},
+		repos:      repos,
+		listGroups: db.UserGroups.ListNames,
+	}, nil
+}
+
+// Validate always returns nil, because the groups of users are only known once they sign in.
+func (p *Provider) Validate() []string { return nil }
+
+// ServiceID returns the base URL of the repositories of the external service.
+func (p *Provider) ServiceID() string { return p.codeHost.ServiceID }
+
+// ServiceType returns the type of this Provider, namely, "other".
+func (p *Provider) ServiceType() string { return p.codeHost.ServiceType }
+
+// FetchAccount satisfies the authz.Provider interface. Group memberships belong to Sourcegraph
+// users, so it returns an account whose ID is the ID of the given user.
+func (p *Provider) FetchAccount(ctx context.Context, user *types.User, _ []*extsvc.ExternalAccount) (*extsvc.ExternalAccount, error) {
+	if user == nil {
+		return nil, nil
+	}
+
+	return &extsvc.ExternalAccount{
+		UserID: user.ID,
+		ExternalAccountSpec: extsvc.ExternalAccountSpec{
+			ServiceType: p.codeHost.ServiceType,
+			ServiceID:   p.codeHost.ServiceID,
+			AccountID:   strconv.Itoa(int(user.ID)),
+		},
+	}, nil
+}
+
+// RepoPerms returns the permissions the given external account has in relation to the given set
+// of repos. Members of a group can read the repositories whose names match any of the patterns
+// of the group.
+func (p *Provider) RepoPerms(ctx context.Context, acct *extsvc.ExternalAccount, repos []*types.Repo) ([]authz.RepoPerms, error) {
+	if acct == nil || !extsvc.IsHostOfAccount(p.codeHost, acct) {
+		return []authz.RepoPerms{}, nil
+	}
+
+	names, err := p.listGroups(ctx, acct.UserID)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing user groups")
+	}
+
+	var patterns []*regexp.Regexp
+	for _, name := range names {
+		patterns = append(patterns, p.repos[name]...)
+	}
+
+	perms := make([]authz.RepoPerms, 0, len(repos))
+	for _, r := range repos {
+		if !extsvc.IsHostOfRepo(p.codeHost, &r.ExternalRepo) {
+			continue
+		}
+		for _, re := range patterns {
+			if re.MatchString(string(r.Name)) {
+				perms = append(perms, authz.RepoPerms{Repo: r, Perms: authz.Read})
+				break
+			}
+		}
+	}
+	return perms, nil
+}
@@ -0,0 +1,170 @@
+package groups
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func newTestProvider(t *testing.T) *Provider {
+	p, err := NewProvider("https://git.example.com", []*schema.PermissionsGroup{
+		{Name: "engineering", Repos: []string{"git\\.example\\.com/engineering/.*"}},
+		{Name: "security", Repos: []string{"git\\.example\\.com/security/.*", ".*/secrets"}},
+		{Name: "engineering", Repos: []string{"git\\.example\\.com/shared/.*", "git\\.example\\.com/shared|git\\.example\\.com/tools"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.listGroups = func(ctx context.Context, userID int32) ([]string, error) {
+		switch userID {
+		case 1:
+			return []string{"engineering"}, nil
+		case 2:
+			return []string{"engineering", "security"}, nil
+		default:
+			return nil, nil
+		}
+	}
+	return p
+}
+
+func account(p *Provider, userID int32) *extsvc.ExternalAccount {
+	acct, _ := p.FetchAccount(context.Background(), &types.User{ID: userID}, nil)
+	return acct
+}
+
+func TestNewProvider(t *testing.T) {
+	_, err := NewProvider("https://git.example.com", []*schema.PermissionsGroup{{Name: "engineering", Repos: []string{"("}}})
+	if err == nil {
+		t.Error("got no error for an invalid repository pattern")
+	}
+}
+
+func TestProvider_FetchAccount(t *testing.T) {
+	p := newTestProvider(t)
+
+	acct, err := p.FetchAccount(context.Background(), &types.User{ID: 1, Username: "alice"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &extsvc.ExternalAccount{
+		UserID: 1,
+		ExternalAccountSpec: extsvc.ExternalAccountSpec{
+			ServiceType: "other",
+			ServiceID:   "https://git.example.com",
+			AccountID:   "1",
+		},
+	}
+	if diff := cmp.Diff(want, acct); diff != "" {
+		t.Error(diff)
+	}
+
+	acct, err = p.FetchAccount(context.Background(), nil, nil)
+	if err != nil || acct != nil {
+		t.Errorf("got account %+v (err: %v), want nil for an anonymous user", acct, err)
+	}
+}
+
+func TestProvider_RepoPerms(t *testing.T) {
+	p := newTestProvider(t)
+	repo := func(id api.RepoID, name, serviceID string) *types.Repo {
+		return &types.Repo{
+			ID:   id,
+			Name: api.RepoName(name),
+			ExternalRepo: api.ExternalRepoSpec{
+				ID:          name,
+				ServiceType: "other",
+				ServiceID:   serviceID,
+			},
+		}
+	}
+	app := repo(1, "git.example.com/engineering/app", "https://git.example.com")
+	lib := repo(2, "git.example.com/shared/lib", "https://git.example.com")
+	scanner := repo(3, "git.example.com/security/scanner", "https://git.example.com")
+	secrets := repo(4, "git.example.com/ops/secrets", "https://git.example.com")
+	elsewhere := repo(5, "git.other.com/engineering/app", "https://git.other.com")
+	// Patterns must match the whole name, including each alternative.
+	prefixed := repo(6, "git.example.com/fork/git.example.com/engineering/app", "https://git.example.com")
+	tools := repo(7, "git.example.com/tools", "https://git.example.com")
+	toolsFork := repo(8, "git.example.com/tools-fork", "https://git.example.com")
+	repos := []*types.Repo{app, lib, scanner, secrets, elsewhere, prefixed, tools, toolsFork}
+
+	for _, tc := range []struct {
+		name string
+		acct *extsvc.ExternalAccount
+		want []authz.RepoPerms
+	}{
+		{
+			name: "no account",
+			want: []authz.RepoPerms{},
+		},
+		{
+			name: "no groups",
+			acct: account(p, 3),
+			want: []authz.RepoPerms{},
+		},
+		{
+			name: "one group",
+			acct: account(p, 1),
+			want: []authz.RepoPerms{{Repo: app, Perms: authz.Read}, {Repo: lib, Perms: authz.Read}, {Repo: tools, Perms: authz.Read}},
+		},
+		{
+			name: "two groups",
+			acct: account(p, 2),
+			want: []authz.RepoPerms{
+				{Repo: app, Perms: authz.Read},
+				{Repo: lib, Perms: authz.Read},
+				{Repo: scanner, Perms: authz.Read},
+				{Repo: secrets, Perms: authz.Read},
+				{Repo: tools, Perms: authz.Read},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			perms, err := p.RepoPerms(context.Background(), tc.acct, repos)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, perms); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestOtherServiceID(t *testing.T) {
+	for _, tc := range []struct {
+		conn *schema.OtherExternalServiceConnection
+		want string
+	}{
+		{
+			conn: &schema.OtherExternalServiceConnection{Url: "https://git.example.com/", Repos: []string{"a/b"}},
+			want: "https://git.example.com",
+		},
+		{
+			conn: &schema.OtherExternalServiceConnection{Url: "https://git.example.com/prefix?x=y", Repos: []string{"a/b"}},
+			want: "https://git.example.com",
+		},
+		{
+			// src-expose repositories use the url as is.
+			conn: &schema.OtherExternalServiceConnection{Url: "http://src-expose:3434/", Repos: []string{"src-expose"}},
+			want: "http://src-expose:3434/",
+		},
+	} {
+		t.Run(tc.conn.Url, func(t *testing.T) {
+			have, err := otherServiceID(tc.conn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have != tc.want {
+				t.Errorf("got service ID %q, want %q", have, tc.want)
+			}
+		})
+	}
+}
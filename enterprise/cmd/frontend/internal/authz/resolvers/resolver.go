@@ -15,6 +15,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	edb "github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/db/dbutil"
 	"github.com/sourcegraph/sourcegraph/internal/errcode"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
@@ -41,41 +42,189 @@ func (r *Resolver) SetRepositoryPermissionsForUsers(ctx context.Context, args *g
 		return nil, err
 	}
 	// Make sure the repo ID is valid.
-	if _, err = db.Repos.Get(ctx, repoID); err != nil {
+	repo, err := db.Repos.Get(ctx, repoID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.setRepositoryPermissions(ctx, []*repoPermsSpec{{repo: repo, bindIDs: args.BindIDs}})
+	if err != nil {
+		return nil, err
+	}
+	return &graphqlbackend.EmptyResponse{}, nil
+}
+
+func (r *Resolver) SetRepositoryPermissionsInBulk(ctx context.Context, args *graphqlbackend.RepoPermsInBulkArgs) (*graphqlbackend.EmptyResponse, error) {
+	// 🚨 SECURITY: Only site admins can mutate repository permissions.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
 		return nil, err
 	}
 
+	specs := make([]*repoPermsSpec, 0, len(args.Permissions))
+	for _, in := range args.Permissions {
+		var (
+			repo *types.Repo
+			err  error
+		)
+		switch {
+		case in.Repository != nil && in.RepositoryName == nil:
+			var repoID api.RepoID
+			repoID, err = graphqlbackend.UnmarshalRepositoryID(*in.Repository)
+			if err != nil {
+				return nil, err
+			}
+			repo, err = db.Repos.Get(ctx, repoID)
+		case in.Repository == nil && in.RepositoryName != nil:
+			repo, err = db.Repos.GetByName(ctx, api.RepoName(*in.RepositoryName))
+		default:
+			return nil, errors.New("exactly one of repository and repositoryName must be given")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		spec := &repoPermsSpec{repo: repo}
+		if in.BindIDs != nil {
+			spec.bindIDs = *in.BindIDs
+		}
+		// Organizations are stored as such, their members are resolved when permissions are
+		// checked. Not giving any replaces the organizations of the repository as well.
+		spec.orgIDs = []int32{}
+		if in.Organizations != nil {
+			for _, id := range *in.Organizations {
+				orgID, err := graphqlbackend.UnmarshalOrgID(id)
+				if err != nil {
+					return nil, err
+				}
+				// Make sure the organization ID is valid.
+				if _, err := db.Orgs.GetByID(ctx, orgID); err != nil {
+					return nil, err
+				}
+				spec.orgIDs = append(spec.orgIDs, orgID)
+			}
+		}
+		specs = append(specs, spec)
+	}
+
+	if err := r.setRepositoryPermissions(ctx, specs); err != nil {
+		return nil, err
+	}
+	return &graphqlbackend.EmptyResponse{}, nil
+}
+
+// repoPermsSpec describes the full set of users that have permissions to a repository.
+type repoPermsSpec struct {
+	repo *types.Repo
+	// bindIDs are the usernames or emails (according to site configuration) of the users,
+	// including those who have not signed up yet.
+	bindIDs []string
+	// orgIDs are the IDs of the organizations whose members have permissions. If it is nil,
+	// the organizations of the repository are left unchanged.
+	orgIDs []int32
+}
+
+// setRepositoryPermissions replaces the permissions of the repositories in specs within a
+// single transaction. Bind IDs that don't belong to any user are saved as pending permissions.
+func (r *Resolver) setRepositoryPermissions(ctx context.Context, specs []*repoPermsSpec) (err error) {
+	// 🚨 SECURITY: Permissions of repositories whose code host has an authz provider are enforced
+	// by that provider, explicit permissions would silently have no effect.
+	_, providers := authz.GetProviders()
+	for _, spec := range specs {
+		for _, p := range providers {
+			if spec.repo.ExternalRepo.ServiceID == p.ServiceID() {
+				return fmt.Errorf("permissions of repository %q are managed by the %s authorization provider for %s",
+					spec.repo.Name, p.ServiceType(), p.ServiceID())
+			}
+		}
+	}
+
 	// Filter out bind IDs that only contains whitespaces.
-	bindIDs := args.BindIDs[:0]
-	for i := range args.BindIDs {
-		args.BindIDs[i] = strings.TrimSpace(args.BindIDs[i])
-		if len(args.BindIDs[i]) == 0 {
-			continue
+	var bindIDs []string
+	for _, spec := range specs {
+		ids := spec.bindIDs[:0]
+		for i := range spec.bindIDs {
+			id := strings.TrimSpace(spec.bindIDs[i])
+			if len(id) == 0 {
+				continue
+			}
+			ids = append(ids, id)
 		}
-		bindIDs = append(bindIDs, args.BindIDs[i])
+		spec.bindIDs = ids
+		bindIDs = append(bindIDs, ids...)
 	}
 
-	bindIDSet := make(map[string]struct{})
-	for i := range bindIDs {
-		bindIDSet[bindIDs[i]] = struct{}{}
+	userIDs, err := userIDsByBindIDs(ctx, bindIDs)
+	if err != nil {
+		return err
 	}
 
-	p := &authz.RepoPermissions{
-		RepoID:  int32(repoID),
-		Perm:    authz.Read, // Note: We currently only support read for repository permissions.
-		UserIDs: roaring.NewBitmap(),
+	txs, err := r.store.Transact(ctx)
+	if err != nil {
+		return errors.Wrap(err, "start transaction")
+	}
+	defer txs.Done(&err)
+
+	for _, spec := range specs {
+		p := &authz.RepoPermissions{
+			RepoID:  int32(spec.repo.ID),
+			Perm:    authz.Read, // Note: We currently only support read for repository permissions.
+			UserIDs: roaring.NewBitmap(),
+		}
+		pendingBindIDSet := make(map[string]struct{})
+		for _, id := range spec.bindIDs {
+			if userID, ok := userIDs[id]; ok {
+				p.UserIDs.Add(uint32(userID))
+			} else {
+				pendingBindIDSet[id] = struct{}{}
+			}
+		}
+
+		pendingBindIDs := make([]string, 0, len(pendingBindIDSet))
+		for id := range pendingBindIDSet {
+			pendingBindIDs = append(pendingBindIDs, id)
+		}
+
+		accounts := &extsvc.ExternalAccounts{
+			ServiceType: authz.SourcegraphServiceType,
+			ServiceID:   authz.SourcegraphServiceID,
+			AccountIDs:  pendingBindIDs,
+		}
+
+		if err = txs.SetRepoPermissions(ctx, p); err != nil {
+			return errors.Wrap(err, "set repository permissions")
+		} else if err = txs.SetRepoPendingPermissions(ctx, accounts, p); err != nil {
+			return errors.Wrap(err, "set repository pending permissions")
+		}
+
+		if spec.orgIDs != nil {
+			if err = txs.SetRepoOrgPermissions(ctx, p.RepoID, p.Perm, spec.orgIDs); err != nil {
+				return errors.Wrap(err, "set repository organization permissions")
+			}
+		}
+	}
+
+	return nil
+}
+
+// userIDsByBindIDs returns the IDs of existing users keyed by their bind IDs according to
+// the site configuration.
+func userIDsByBindIDs(ctx context.Context, bindIDs []string) (map[string]int32, error) {
+	userIDs := make(map[string]int32)
+	if len(bindIDs) == 0 {
+		return userIDs, nil
 	}
+
 	cfg := globals.PermissionsUserMapping()
 	switch cfg.BindID {
 	case "email":
+		// 🚨 SECURITY: It is critical to only use verified emails.
 		emails, err := db.UserEmails.GetVerifiedEmails(ctx, bindIDs...)
 		if err != nil {
 			return nil, err
 		}
 
 		for i := range emails {
-			p.UserIDs.Add(uint32(emails[i].UserID))
-			delete(bindIDSet, emails[i].Email)
+			userIDs[emails[i].Email] = emails[i].UserID
 		}
 
 	case "username":
@@ -85,38 +234,14 @@ func (r *Resolver) SetRepositoryPermissionsForUsers(ctx context.Context, args *g
 		}
 
 		for i := range users {
-			p.UserIDs.Add(uint32(users[i].ID))
-			delete(bindIDSet, users[i].Username)
+			userIDs[users[i].Username] = users[i].ID
 		}
 
 	default:
 		return nil, fmt.Errorf("unrecognized user mapping bind ID type %q", cfg.BindID)
 	}
 
-	pendingBindIDs := make([]string, 0, len(bindIDSet))
-	for id := range bindIDSet {
-		pendingBindIDs = append(pendingBindIDs, id)
-	}
-
-	txs, err := r.store.Transact(ctx)
-	if err != nil {
-		return nil, errors.Wrap(err, "start transaction")
-	}
-	defer txs.Done(&err)
-
-	accounts := &extsvc.ExternalAccounts{
-		ServiceType: authz.SourcegraphServiceType,
-		ServiceID:   authz.SourcegraphServiceID,
-		AccountIDs:  pendingBindIDs,
-	}
-
-	if err = txs.SetRepoPermissions(ctx, p); err != nil {
-		return nil, errors.Wrap(err, "set repository permissions")
-	} else if err = txs.SetRepoPendingPermissions(ctx, accounts, p); err != nil {
-		return nil, errors.Wrap(err, "set repository pending permissions")
-	}
-
-	return &graphqlbackend.EmptyResponse{}, nil
+	return userIDs, nil
 }
 
 func (r *Resolver) AuthorizedUserRepositories(ctx context.Context, args *graphqlbackend.AuthorizedRepoArgs) (graphqlbackend.RepositoryConnectionResolver, error) {
@@ -172,6 +297,15 @@ func (r *Resolver) AuthorizedUserRepositories(ctx context.Context, args *graphql
 		ids = roaring.NewBitmap()
 	}
 
+	// Users also have the permissions of the organizations they are members of.
+	if user != nil {
+		orgIDs, err := r.store.LoadUserOrgPermissions(ctx, user.ID, authz.Read)
+		if err != nil {
+			return nil, err
+		}
+		ids.Or(orgIDs)
+	}
+
 	return &repositoryConnectionResolver{
 		ids:   ids,
 		first: args.First,
@@ -216,6 +350,12 @@ func (r *Resolver) AuthorizedUsers(ctx context.Context, args *graphqlbackend.Rep
 		p.UserIDs = roaring.NewBitmap()
 	}
 
+	orgUserIDs, err := r.store.LoadRepoOrgPermissions(ctx, p.RepoID, p.Perm)
+	if err != nil {
+		return nil, err
+	}
+	p.UserIDs.Or(orgUserIDs)
+
 	return &userConnectionResolver{
 		ids:   p.UserIDs,
 		first: args.First,
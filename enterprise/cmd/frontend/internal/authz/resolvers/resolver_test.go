@@ -193,6 +193,129 @@ func TestResolver_SetRepositoryPermissionsForUsers(t *testing.T) {
 	}
 }
 
+func TestResolver_SetRepositoryPermissionsInBulk(t *testing.T) {
+	t.Run("authenticated as non-admin", func(t *testing.T) {
+		db.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+			return &types.User{}, nil
+		}
+		defer func() {
+			db.Mocks.Users.GetByCurrentAuthUser = nil
+		}()
+
+		ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 1})
+		result, err := (&Resolver{}).SetRepositoryPermissionsInBulk(ctx, &graphqlbackend.RepoPermsInBulkArgs{})
+		if want := backend.ErrMustBeSiteAdmin; err != want {
+			t.Errorf("err: want %q but got %v", want, err)
+		}
+		if result != nil {
+			t.Errorf("result: want nil but got %v", result)
+		}
+	})
+
+	globals.SetPermissionsUserMapping(&schema.PermissionsUserMapping{BindID: "username"})
+
+	db.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{SiteAdmin: true}, nil
+	}
+	db.Mocks.Users.GetByUsernames = func(context.Context, ...string) ([]*types.User, error) {
+		return []*types.User{{ID: 1, Username: "alice"}}, nil
+	}
+	db.Mocks.Orgs.GetByID = func(_ context.Context, orgID int32) (*types.Org, error) {
+		if orgID != 2 {
+			return nil, fmt.Errorf("unexpected org ID %d", orgID)
+		}
+		return &types.Org{ID: orgID}, nil
+	}
+	db.Mocks.Repos.Get = func(_ context.Context, id api.RepoID) (*types.Repo, error) {
+		return &types.Repo{ID: id, ExternalRepo: api.ExternalRepoSpec{ServiceID: "https://gitlab.com/"}}, nil
+	}
+	db.Mocks.Repos.GetByName = func(_ context.Context, name api.RepoName) (*types.Repo, error) {
+		return &types.Repo{ID: 1, Name: name, ExternalRepo: api.ExternalRepoSpec{ServiceID: "https://other.com/"}}, nil
+	}
+	edb.Mocks.Perms.Transact = func(_ context.Context) (*edb.PermsStore, error) {
+		return &edb.PermsStore{}, nil
+	}
+	userIDs := make(map[int32][]uint32)
+	edb.Mocks.Perms.SetRepoPermissions = func(_ context.Context, p *authz.RepoPermissions) error {
+		userIDs[p.RepoID] = p.UserIDs.ToArray()
+		return nil
+	}
+	pendingBindIDs := make(map[int32][]string)
+	edb.Mocks.Perms.SetRepoPendingPermissions = func(_ context.Context, accounts *extsvc.ExternalAccounts, p *authz.RepoPermissions) error {
+		pendingBindIDs[p.RepoID] = accounts.AccountIDs
+		return nil
+	}
+	orgIDs := make(map[int32][]int32)
+	edb.Mocks.Perms.SetRepoOrgPermissions = func(_ context.Context, repoID int32, _ authz.Perms, ids []int32) error {
+		orgIDs[repoID] = ids
+		return nil
+	}
+	defer func() {
+		db.Mocks.Users = db.MockUsers{}
+		db.Mocks.Orgs = db.MockOrgs{}
+		db.Mocks.Repos = db.MockRepos{}
+		edb.Mocks.Perms = edb.MockPerms{}
+	}()
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: mustParseGraphQLSchema(t, nil),
+			Query: `
+				mutation {
+					setRepositoryPermissionsInBulk(permissions: [
+						{repositoryName: "other.com/r1", bindIDs: ["alice", " ", "bob"]},
+						{repository: "UmVwb3NpdG9yeToy", organizations: ["T3JnOjI="]}
+					]) {
+						alwaysNil
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"setRepositoryPermissionsInBulk": {
+						"alwaysNil": null
+					}
+				}
+			`,
+		},
+	})
+
+	if diff := cmp.Diff(map[int32][]uint32{1: {1}, 2: {}}, userIDs); diff != "" {
+		t.Errorf("userIDs: %v", diff)
+	}
+	// Organizations are stored rather than expanded to their members, and not giving any
+	// removes those set before.
+	if diff := cmp.Diff(map[int32][]int32{1: {}, 2: {2}}, orgIDs); diff != "" {
+		t.Errorf("orgIDs: %v", diff)
+	}
+	if diff := cmp.Diff(map[int32][]string{1: {"bob"}, 2: {}}, pendingBindIDs); diff != "" {
+		t.Errorf("pendingBindIDs: %v", diff)
+	}
+
+	t.Run("repository managed by an authz provider", func(t *testing.T) {
+		authz.SetProviders(false, []authz.Provider{&fakeProvider{serviceID: "https://gitlab.com/"}})
+		defer authz.SetProviders(true, nil)
+
+		repoID := graphqlbackend.MarshalRepositoryID(2)
+		_, err := (&Resolver{}).SetRepositoryPermissionsInBulk(context.Background(), &graphqlbackend.RepoPermsInBulkArgs{
+			Permissions: []*graphqlbackend.RepoPermsInput{{Repository: &repoID}},
+		})
+		want := `permissions of repository "" are managed by the gitlab authorization provider for https://gitlab.com/`
+		if err == nil || err.Error() != want {
+			t.Fatalf("err: want %q but got %v", want, err)
+		}
+	})
+}
+
+// fakeProvider is an authz.Provider that only has a service ID.
+type fakeProvider struct {
+	authz.Provider
+	serviceID string
+}
+
+func (p *fakeProvider) ServiceID() string   { return p.serviceID }
+func (p *fakeProvider) ServiceType() string { return "gitlab" }
+
 func TestResolver_AuthorizedUserRepositories(t *testing.T) {
 	t.Run("authenticated as non-admin", func(t *testing.T) {
 		db.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
@@ -244,6 +367,13 @@ func TestResolver_AuthorizedUserRepositories(t *testing.T) {
 		p.IDs.Add(2)
 		return nil
 	}
+	edb.Mocks.Perms.LoadUserOrgPermissions = func(_ context.Context, userID int32, _ authz.Perms) (*roaring.Bitmap, error) {
+		ids := roaring.NewBitmap()
+		if userID == 1 {
+			ids.Add(3)
+		}
+		return ids, nil
+	}
 	defer func() {
 		db.Mocks.Users = db.MockUsers{}
 		edb.Mocks.Perms = edb.MockPerms{}
@@ -273,7 +403,8 @@ func TestResolver_AuthorizedUserRepositories(t *testing.T) {
 				{
 					"authorizedUserRepositories": {
 						"nodes": [
-							{"id":"UmVwb3NpdG9yeTox"}
+							{"id":"UmVwb3NpdG9yeTox"},
+							{"id":"UmVwb3NpdG9yeToz"}
 						]
     				}
 				}
@@ -301,7 +432,8 @@ func TestResolver_AuthorizedUserRepositories(t *testing.T) {
 				{
 					"authorizedUserRepositories": {
 						"nodes": [
-							{"id":"UmVwb3NpdG9yeTox"}
+							{"id":"UmVwb3NpdG9yeTox"},
+							{"id":"UmVwb3NpdG9yeToz"}
 						]
     				}
 				}
@@ -476,6 +608,11 @@ func TestResolver_AuthorizedUsers(t *testing.T) {
 		p.UserIDs.Add(1)
 		return nil
 	}
+	edb.Mocks.Perms.LoadRepoOrgPermissions = func(context.Context, int32, authz.Perms) (*roaring.Bitmap, error) {
+		ids := roaring.NewBitmap()
+		ids.Add(2)
+		return ids, nil
+	}
 	defer func() {
 		db.Mocks.Users = db.MockUsers{}
 		db.Mocks.Repos = db.MockRepos{}
@@ -507,7 +644,8 @@ func TestResolver_AuthorizedUsers(t *testing.T) {
 					"repository": {
 						"authorizedUsers": {
 							"nodes":[
-								{"id":"VXNlcjox"}
+								{"id":"VXNlcjox"},
+								{"id":"VXNlcjoy"}
 							]
 						}
     				}
@@ -9,6 +9,7 @@ import (
 	"context"
 	"database/sql"
 	"log"
+	"net/http"
 	"os"
 	"strconv"
 	"time"
@@ -86,7 +87,14 @@ func main() {
 
 	go bitbucketServerWebhook.Upsert(30 * time.Second)
 
-	shared.Main(githubWebhook, bitbucketServerWebhook)
+	gitlabWebhook := campaigns.NewGitLabWebhook(campaignsStore, repositories, clock)
+
+	replacer := campaigns.NewReplacerClient(graphqlbackend.ReplacerURL, http.DefaultClient)
+	go campaigns.RunPatchJobs(ctx, campaignsStore, clock, replacer, 5*time.Second)
+
+	campaignsExport := campaigns.NewExportHandler(campaignsStore, clock)
+
+	shared.Main(githubWebhook, bitbucketServerWebhook, gitlabWebhook, campaignsExport)
 }
 
 func initLicensing() {
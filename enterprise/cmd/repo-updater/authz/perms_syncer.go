@@ -19,6 +19,7 @@ import (
 	edb "github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/ratelimit"
 	"github.com/sourcegraph/sourcegraph/internal/trace"
 )
 
@@ -235,6 +236,16 @@ func (s *PermsSyncer) syncUserPerms(ctx context.Context, userID int32) (err erro
 		p.IDs.Add(uint32(rs[i].ID))
 	}
 
+	// Explicit permissions set via the GraphQL API are stored in the same place, so we need
+	// to carry them over instead of overwriting them with what the code hosts tell us.
+	if globals.PermissionsUserMapping().Enabled {
+		ids, err := s.explicitUserPerms(ctx, userID)
+		if err != nil {
+			return errors.Wrap(err, "load explicit user permissions")
+		}
+		p.IDs.Or(ids)
+	}
+
 	err = s.permsStore.SetUserPermissions(ctx, p)
 	if err != nil {
 		return errors.Wrap(err, "set user permissions")
@@ -244,6 +255,52 @@ func (s *PermsSyncer) syncUserPerms(ctx context.Context, userID int32) (err erro
 	return nil
 }
 
+// explicitUserPerms returns the IDs of repositories that the given user currently has
+// permissions to and are not managed by any authz provider, i.e. those permissions
+// that were set explicitly when permissions user mapping is enabled.
+func (s *PermsSyncer) explicitUserPerms(ctx context.Context, userID int32) (*roaring.Bitmap, error) {
+	p := &authz.UserPermissions{
+		UserID: userID,
+		Perm:   authz.Read, // Note: We currently only support read for repository permissions.
+		Type:   authz.PermRepos,
+	}
+	err := s.permsStore.LoadUserPermissions(ctx, p)
+	if err == authz.ErrPermsNotFound {
+		return roaring.NewBitmap(), nil
+	} else if err != nil {
+		return nil, err
+	} else if p.IDs.IsEmpty() {
+		return p.IDs, nil
+	}
+
+	repoIDs := make([]api.RepoID, 0, p.IDs.GetCardinality())
+	for _, id := range p.IDs.ToArray() {
+		repoIDs = append(repoIDs, api.RepoID(id))
+	}
+
+	rs, err := s.reposStore.ListRepos(ctx, repos.StoreListReposArgs{
+		IDs:     repoIDs,
+		PerPage: int64(len(repoIDs)), // We want to get all repositories in one shot
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list repositories")
+	}
+
+	_, providers := authz.GetProviders()
+	managed := make(map[string]bool, len(providers))
+	for i := range providers {
+		managed[providers[i].ServiceID()] = true
+	}
+
+	ids := roaring.NewBitmap()
+	for i := range rs {
+		if !managed[rs[i].ExternalRepo.ServiceID] {
+			ids.Add(uint32(rs[i].ID))
+		}
+	}
+	return ids, nil
+}
+
 // syncRepoPerms processes permissions syncing request in repository-centric way.
 // It discards requests that are made for non-private repositories based on the
 // value of "repo.private" column.
@@ -348,6 +405,14 @@ func (s *PermsSyncer) syncRepoPerms(ctx context.Context, repoID api.RepoID) (err
 func (s *PermsSyncer) syncPerms(ctx context.Context, request *syncRequest) error {
 	defer s.queue.remove(request.Type, request.ID, true)
 
+	// Requests driven by user actions don't wait for the rate limit budget of
+	// the code host, all others back off to leave room for more urgent work.
+	if request.Priority == PriorityHigh {
+		ctx = ratelimit.WithPriority(ctx, ratelimit.PriorityInteractive)
+	} else {
+		ctx = ratelimit.WithPriority(ctx, ratelimit.PriorityBackground)
+	}
+
 	var err error
 	switch request.Type {
 	case requestTypeUser:
@@ -6,15 +6,18 @@ import (
 	"testing"
 	"time"
 
+	"github.com/RoaringBitmap/roaring"
 	"github.com/google/go-cmp/cmp"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/cmd/repo-updater/repos"
 	edb "github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
+	"github.com/sourcegraph/sourcegraph/schema"
 )
 
 func TestPermsSyncer_ScheduleUsers(t *testing.T) {
@@ -172,6 +175,71 @@ func TestPermsSyncer_syncUserPerms(t *testing.T) {
 	}
 }
 
+func TestPermsSyncer_syncUserPerms_explicitPerms(t *testing.T) {
+	before := globals.PermissionsUserMapping()
+	globals.SetPermissionsUserMapping(&schema.PermissionsUserMapping{Enabled: true, BindID: "email"})
+	defer globals.SetPermissionsUserMapping(before)
+
+	p := &mockProvider{
+		serviceType: gitlab.ServiceType,
+		serviceID:   "https://gitlab.com/",
+	}
+	authz.SetProviders(false, []authz.Provider{p})
+	defer authz.SetProviders(true, nil)
+
+	p.fetchUserPerms = func(context.Context, *extsvc.ExternalAccount) ([]extsvc.ExternalRepoID, error) {
+		return []extsvc.ExternalRepoID{"3"}, nil
+	}
+
+	edb.Mocks.Perms.ListExternalAccounts = func(context.Context, int32) ([]*extsvc.ExternalAccount, error) {
+		return []*extsvc.ExternalAccount{{
+			ExternalAccountSpec: extsvc.ExternalAccountSpec{
+				ServiceType: p.ServiceType(),
+				ServiceID:   p.ServiceID(),
+			},
+		}}, nil
+	}
+	edb.Mocks.Perms.LoadUserPermissions = func(_ context.Context, p *authz.UserPermissions) error {
+		p.IDs = roaring.BitmapOf(1, 2)
+		return nil
+	}
+	edb.Mocks.Perms.SetUserPermissions = func(_ context.Context, p *authz.UserPermissions) error {
+		// Repository 1 is no longer readable on GitLab, repository 2 has explicit permissions.
+		expIDs := []uint32{2, 3}
+		if diff := cmp.Diff(expIDs, p.IDs.ToArray()); diff != "" {
+			return fmt.Errorf("IDs: %v", diff)
+		}
+		return nil
+	}
+	defer func() {
+		edb.Mocks.Perms = edb.MockPerms{}
+	}()
+
+	reposStore := &mockReposStore{
+		listRepos: func(_ context.Context, args repos.StoreListReposArgs) ([]*repos.Repo, error) {
+			if len(args.IDs) > 0 {
+				return []*repos.Repo{
+					{ID: 1, ExternalRepo: api.ExternalRepoSpec{ServiceID: p.ServiceID()}},
+					{ID: 2, ExternalRepo: api.ExternalRepoSpec{ServiceID: "https://other.com/"}},
+				}, nil
+			}
+			return []*repos.Repo{{ID: 3}}, nil
+		},
+	}
+	clock := func() time.Time {
+		return time.Now().UTC().Truncate(time.Microsecond)
+	}
+	permsStore := edb.NewPermsStore(nil, clock)
+	s := NewPermsSyncer(reposStore, permsStore, clock)
+	s.metrics.syncDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{}, []string{"type", "success"})
+	s.metrics.syncErrors = prometheus.NewCounterVec(prometheus.CounterOpts{}, []string{"type"})
+
+	err := s.syncUserPerms(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestPermsSyncer_syncRepoPerms(t *testing.T) {
 	p := &mockProvider{
 		serviceType: gitlab.ServiceType,
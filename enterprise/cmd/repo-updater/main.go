@@ -58,10 +58,27 @@ func enterpriseInit(
 	}
 
 	go campaigns.RunChangesetJobs(ctx, campaignsStore, clock, gitserver.DefaultClient, 5*time.Second)
+	go campaigns.RunChangesetActionJobs(ctx, campaignsStore, clock, cf, 5*time.Second)
 
 	// Set up syncer
 	go syncer.Run(ctx)
 
+	// Set up auto-merging of changesets
+	merger := &campaigns.ChangesetMerger{
+		Store:       campaignsStore,
+		ReposStore:  repoStore,
+		HTTPFactory: cf,
+	}
+	go merger.Run(ctx)
+
+	// Set up rebasing of outdated changesets
+	rebaser := &campaigns.ChangesetRebaser{
+		Store:      campaignsStore,
+		ReposStore: repoStore,
+		GitClient:  gitserver.DefaultClient,
+	}
+	go rebaser.Run(ctx)
+
 	// Set up expired patch set deletion
 	go func() {
 		for {
@@ -73,6 +90,17 @@ func enterpriseInit(
 		}
 	}()
 
+	// Set up failing of changeset action jobs that never finished
+	go func() {
+		for {
+			err := campaignsStore.FailStalledChangesetActionJobs(ctx)
+			if err != nil {
+				log15.Error("FailStalledChangesetActionJobs", "error", err)
+			}
+			time.Sleep(2 * time.Minute)
+		}
+	}()
+
 	// TODO(jchen): This is an unfortunate compromise to not rewrite ossDB.ExternalServices for now.
 	dbconn.Global = db
 	permsStore := frontendDB.NewPermsStore(db, clock)
@@ -86,6 +114,7 @@ func enterpriseInit(
 // startBackgroundPermsSync sets up background permissions syncing.
 func startBackgroundPermsSync(ctx context.Context, syncer *authz.PermsSyncer, db dbutil.DB) {
 	globals.WatchPermissionsBackgroundSync()
+	globals.WatchPermissionsUserMapping()
 	go func() {
 		t := time.NewTicker(5 * time.Second)
 		for range t.C {
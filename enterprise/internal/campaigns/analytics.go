@@ -0,0 +1,253 @@
+package campaigns
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
+)
+
+// ChangesetTimeline summarizes the lifetime of a single Changeset on its
+// codehost. Timestamps of things that didn't happen (yet) are zero.
+type ChangesetTimeline struct {
+	ChangesetID int64
+	Repo        api.RepoName
+	// Owner is the namespace the repository belongs to, i.e. its name
+	// without the last path element.
+	Owner       string
+	ExternalID  string
+	ExternalURL string
+	State       campaigns.ChangesetState
+
+	OpenedAt      time.Time
+	FirstReviewAt time.Time
+	ApprovedAt    time.Time
+	MergedAt      time.Time
+	ClosedAt      time.Time
+
+	// TimeToMerge is the time between OpenedAt and MergedAt, or zero if the
+	// Changeset hasn't been merged.
+	TimeToMerge time.Duration
+
+	// CIFailures is the number of failed checks reported by check events,
+	// such as commit statuses, check runs and pipelines. Failed GitHub check
+	// suites aren't counted in addition to their failed check runs.
+	CIFailures int
+}
+
+// OwnerStats aggregates the ChangesetTimelines of the repositories that
+// belong to a single owner.
+type OwnerStats struct {
+	Owner    string
+	Total    int32
+	Open     int32
+	Merged   int32
+	Closed   int32
+	Approved int32
+
+	CIFailures int
+
+	// MedianTimeToMerge is the median TimeToMerge of the merged Changesets.
+	MedianTimeToMerge time.Duration
+}
+
+// CalcChangesetTimelines calculates a ChangesetTimeline for each of the given
+// Changesets from their Events. repoNames maps the IDs of the Changesets'
+// repositories to their names.
+func CalcChangesetTimelines(cs []*campaigns.Changeset, repoNames map[api.RepoID]api.RepoName, es ...Event) ([]*ChangesetTimeline, error) {
+	events := Events(es)
+	sort.Sort(events)
+
+	byChangesetID := make(map[int64]Events)
+	for _, e := range events {
+		id := e.Changeset()
+		byChangesetID[id] = append(byChangesetID[id], e)
+	}
+
+	timelines := make([]*ChangesetTimeline, 0, len(cs))
+	for _, c := range cs {
+		t, err := calcChangesetTimeline(c, byChangesetID[c.ID])
+		if err != nil {
+			return nil, err
+		}
+
+		t.Repo = repoNames[c.RepoID]
+		t.Owner = repoOwner(t.Repo)
+		timelines = append(timelines, t)
+	}
+
+	return timelines, nil
+}
+
+func calcChangesetTimeline(c *campaigns.Changeset, csEvents Events) (*ChangesetTimeline, error) {
+	t := &ChangesetTimeline{
+		ChangesetID: c.ID,
+		ExternalID:  c.ExternalID,
+		State:       c.ExternalState,
+		OpenedAt:    c.ExternalCreatedAt(),
+	}
+
+	// Not all changesets have a URL, e.g. Bitbucket Server pull requests
+	// without links, which isn't a reason to not report on them.
+	if url, err := c.URL(); err == nil {
+		t.ExternalURL = url
+	}
+
+	for _, e := range csEvents {
+		et := e.Timestamp()
+		if et.IsZero() {
+			continue
+		}
+
+		switch e.Type() {
+		case campaigns.ChangesetEventKindGitHubClosed,
+			campaigns.ChangesetEventKindBitbucketServerDeclined,
+			campaigns.ChangesetEventKindGitLabClosed,
+			campaigns.ChangesetEventKindBitbucketCloudDeclined:
+
+			t.ClosedAt = et
+
+		case campaigns.ChangesetEventKindGitHubReopened,
+			campaigns.ChangesetEventKindBitbucketServerReopened,
+			campaigns.ChangesetEventKindGitLabReopened:
+
+			t.ClosedAt = time.Time{}
+
+		case campaigns.ChangesetEventKindGitHubMerged,
+			campaigns.ChangesetEventKindBitbucketServerMerged,
+			campaigns.ChangesetEventKindGitLabMerged,
+			campaigns.ChangesetEventKindBitbucketCloudMerged:
+
+			if t.MergedAt.IsZero() {
+				t.MergedAt = et
+			}
+
+		case campaigns.ChangesetEventKindGitHubReviewed,
+			campaigns.ChangesetEventKindBitbucketServerApproved,
+			campaigns.ChangesetEventKindBitbucketServerReviewed,
+			campaigns.ChangesetEventKindGitLabApproved,
+			campaigns.ChangesetEventKindBitbucketCloudApproved:
+
+			if t.FirstReviewAt.IsZero() {
+				t.FirstReviewAt = et
+			}
+
+			s, err := reviewState(e)
+			if err != nil {
+				return nil, err
+			}
+			if s == campaigns.ChangesetReviewStateApproved && t.ApprovedAt.IsZero() {
+				t.ApprovedAt = et
+			}
+
+		// A GitHub check suite fails when one of its check runs fails, so
+		// we only count the check runs to not count a failure twice.
+		case campaigns.ChangesetEventKindCommitStatus,
+			campaigns.ChangesetEventKindCheckRun,
+			campaigns.ChangesetEventKindGitLabPipeline,
+			campaigns.ChangesetEventKindBitbucketCloudCommitStatus:
+
+			s, err := checkState(e)
+			if err != nil {
+				return nil, err
+			}
+			if s == campaigns.ChangesetCheckStateFailed {
+				t.CIFailures++
+			}
+		}
+	}
+
+	// On GitHub, a merged pull request is also closed, but we only want to
+	// report it as merged.
+	if !t.MergedAt.IsZero() {
+		t.ClosedAt = time.Time{}
+		if !t.OpenedAt.IsZero() {
+			t.TimeToMerge = t.MergedAt.Sub(t.OpenedAt)
+		}
+	}
+
+	// We don't have an event for the deletion of a Changeset, but we set
+	// ExternalDeletedAt manually in the Syncer.
+	if t.MergedAt.IsZero() && t.ClosedAt.IsZero() && !c.ExternalDeletedAt.IsZero() {
+		t.ClosedAt = c.ExternalDeletedAt
+	}
+
+	return t, nil
+}
+
+// CalcOwnerStats aggregates the given ChangesetTimelines by the owner of
+// their repositories. The returned OwnerStats are sorted by owner.
+func CalcOwnerStats(ts []*ChangesetTimeline) []*OwnerStats {
+	byOwner := make(map[string]*OwnerStats)
+	timesToMerge := make(map[string][]time.Duration)
+
+	for _, t := range ts {
+		s, ok := byOwner[t.Owner]
+		if !ok {
+			s = &OwnerStats{Owner: t.Owner}
+			byOwner[t.Owner] = s
+		}
+
+		s.Total++
+		switch {
+		case !t.MergedAt.IsZero():
+			s.Merged++
+			timesToMerge[t.Owner] = append(timesToMerge[t.Owner], t.TimeToMerge)
+		case !t.ClosedAt.IsZero():
+			s.Closed++
+		default:
+			s.Open++
+		}
+
+		if !t.ApprovedAt.IsZero() {
+			s.Approved++
+		}
+		s.CIFailures += t.CIFailures
+	}
+
+	stats := make([]*OwnerStats, 0, len(byOwner))
+	for owner, s := range byOwner {
+		s.MedianTimeToMerge = medianDuration(timesToMerge[owner])
+		stats = append(stats, s)
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Owner < stats[j].Owner })
+
+	return stats
+}
+
+// repoOwner returns the namespace of the repository with the given name,
+// e.g. "github.com/sourcegraph" for "github.com/sourcegraph/sourcegraph".
+func repoOwner(name api.RepoName) string {
+	i := strings.LastIndex(string(name), "/")
+	if i < 0 {
+		return string(name)
+	}
+	return string(name[:i])
+}
+
+func medianDuration(ds []time.Duration) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+
+	mid := len(ds) / 2
+	if len(ds)%2 == 0 {
+		return (ds[mid-1] + ds[mid]) / 2
+	}
+	return ds[mid]
+}
+
+func checkState(e Event) (campaigns.ChangesetCheckState, error) {
+	changesetEvent, ok := e.(*campaigns.ChangesetEvent)
+	if !ok {
+		return "", errors.New("Check event not ChangesetEvent")
+	}
+
+	return changesetEvent.CheckState(), nil
+}
@@ -0,0 +1,177 @@
+package campaigns
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
+)
+
+func TestCalcChangesetTimelines(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	daysAgo := func(days int) time.Time { return now.AddDate(0, 0, -days) }
+
+	changesets := []*campaigns.Changeset{
+		withRepo(ghChangeset(1, daysAgo(5)), 1),
+		withRepo(ghChangeset(2, daysAgo(4)), 2),
+		withRepo(glChangeset(3, daysAgo(3)), 3),
+		withRepo(setExternalDeletedAt(ghChangeset(4, daysAgo(3)), daysAgo(1)), 1),
+	}
+
+	repoNames := map[api.RepoID]api.RepoName{
+		1: "github.com/sourcegraph/sourcegraph",
+		2: "github.com/sourcegraph/src-cli",
+		3: "gitlab.com/acme/api",
+	}
+
+	events := []Event{
+		ghReview(1, daysAgo(4), "reviewer", "COMMENTED"),
+		ghReview(1, daysAgo(3), "reviewer", "APPROVED"),
+		ghCommitStatus(1, daysAgo(4), "FAILURE"),
+		ghCommitStatus(1, daysAgo(3), "ERROR"),
+		ghCommitStatus(1, daysAgo(3), "SUCCESS"),
+		ghCheckSuite(1, daysAgo(3), "FAILURE"),
+		ghCheckRun(1, daysAgo(3), "FAILURE"),
+		fakeEvent{t: daysAgo(2), kind: campaigns.ChangesetEventKindGitHubClosed, id: 1},
+		fakeEvent{t: daysAgo(2), kind: campaigns.ChangesetEventKindGitHubMerged, id: 1},
+
+		fakeEvent{t: daysAgo(3), kind: campaigns.ChangesetEventKindGitHubClosed, id: 2},
+
+		glApproval(3, daysAgo(2), "reviewer", campaigns.ChangesetEventKindGitLabApproved),
+		glPipeline(3, daysAgo(2), gitlab.PipelineStatusFailed),
+		fakeEvent{t: daysAgo(1), kind: campaigns.ChangesetEventKindGitLabMerged, id: 3},
+	}
+
+	have, err := CalcChangesetTimelines(changesets, repoNames, events...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*ChangesetTimeline{
+		{
+			ChangesetID:   1,
+			Repo:          "github.com/sourcegraph/sourcegraph",
+			Owner:         "github.com/sourcegraph",
+			OpenedAt:      daysAgo(5),
+			FirstReviewAt: daysAgo(4),
+			ApprovedAt:    daysAgo(3),
+			MergedAt:      daysAgo(2),
+			TimeToMerge:   daysAgo(2).Sub(daysAgo(5)),
+			CIFailures:    3,
+		},
+		{
+			ChangesetID: 2,
+			Repo:        "github.com/sourcegraph/src-cli",
+			Owner:       "github.com/sourcegraph",
+			OpenedAt:    daysAgo(4),
+			ClosedAt:    daysAgo(3),
+		},
+		{
+			ChangesetID:   3,
+			Repo:          "gitlab.com/acme/api",
+			Owner:         "gitlab.com/acme",
+			OpenedAt:      daysAgo(3),
+			FirstReviewAt: daysAgo(2),
+			ApprovedAt:    daysAgo(2),
+			MergedAt:      daysAgo(1),
+			TimeToMerge:   daysAgo(1).Sub(daysAgo(3)),
+			CIFailures:    1,
+		},
+		{
+			ChangesetID: 4,
+			Repo:        "github.com/sourcegraph/sourcegraph",
+			Owner:       "github.com/sourcegraph",
+			OpenedAt:    daysAgo(3),
+			ClosedAt:    daysAgo(1),
+		},
+	}
+
+	if diff := cmp.Diff(want, have); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestCalcOwnerStats(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	timelines := []*ChangesetTimeline{
+		{Owner: "github.com/sourcegraph", MergedAt: now, ApprovedAt: now, TimeToMerge: 2 * time.Hour, CIFailures: 1},
+		{Owner: "github.com/sourcegraph", MergedAt: now, TimeToMerge: 4 * time.Hour},
+		{Owner: "github.com/sourcegraph", MergedAt: now, TimeToMerge: 12 * time.Hour, CIFailures: 2},
+		{Owner: "github.com/sourcegraph", ClosedAt: now},
+		{Owner: "gitlab.com/acme", ApprovedAt: now},
+		{Owner: "gitlab.com/acme", MergedAt: now, TimeToMerge: time.Hour},
+		{Owner: "gitlab.com/acme", MergedAt: now, TimeToMerge: 3 * time.Hour},
+	}
+
+	have := CalcOwnerStats(timelines)
+	want := []*OwnerStats{
+		{
+			Owner:             "github.com/sourcegraph",
+			Total:             4,
+			Merged:            3,
+			Closed:            1,
+			Approved:          1,
+			CIFailures:        3,
+			MedianTimeToMerge: 4 * time.Hour,
+		},
+		{
+			Owner:             "gitlab.com/acme",
+			Total:             3,
+			Open:              1,
+			Merged:            2,
+			Approved:          1,
+			MedianTimeToMerge: 2 * time.Hour,
+		},
+	}
+
+	if diff := cmp.Diff(want, have); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func withRepo(c *campaigns.Changeset, id api.RepoID) *campaigns.Changeset {
+	c.RepoID = id
+	return c
+}
+
+func ghCommitStatus(id int64, t time.Time, state string) *campaigns.ChangesetEvent {
+	return &campaigns.ChangesetEvent{
+		ChangesetID: id,
+		Kind:        campaigns.ChangesetEventKindCommitStatus,
+		Metadata: &github.CommitStatus{
+			SHA:        "deadbeef",
+			Context:    "ci",
+			State:      state,
+			ReceivedAt: t,
+		},
+	}
+}
+
+func ghCheckSuite(id int64, t time.Time, conclusion string) *campaigns.ChangesetEvent {
+	return &campaigns.ChangesetEvent{
+		ChangesetID: id,
+		Kind:        campaigns.ChangesetEventKindCheckSuite,
+		Metadata:    &github.CheckSuite{ID: "suite", Status: "COMPLETED", Conclusion: conclusion, ReceivedAt: t},
+	}
+}
+
+func ghCheckRun(id int64, t time.Time, conclusion string) *campaigns.ChangesetEvent {
+	return &campaigns.ChangesetEvent{
+		ChangesetID: id,
+		Kind:        campaigns.ChangesetEventKindCheckRun,
+		Metadata:    &github.CheckRun{ID: "run", Status: "COMPLETED", Conclusion: conclusion, ReceivedAt: t},
+	}
+}
+
+func glPipeline(id int64, t time.Time, status gitlab.PipelineStatus) *campaigns.ChangesetEvent {
+	return &campaigns.ChangesetEvent{
+		ChangesetID: id,
+		Kind:        campaigns.ChangesetEventKindGitLabPipeline,
+		Metadata:    &gitlab.Pipeline{Status: status, UpdatedAt: t},
+	}
+}
@@ -166,7 +166,9 @@ func computeCounts(c *ChangesetCounts, csEvents Events) error {
 
 		switch e.Type() {
 		case campaigns.ChangesetEventKindGitHubClosed,
-			campaigns.ChangesetEventKindBitbucketServerDeclined:
+			campaigns.ChangesetEventKindBitbucketServerDeclined,
+			campaigns.ChangesetEventKindGitLabClosed,
+			campaigns.ChangesetEventKindBitbucketCloudDeclined:
 
 			c.Open--
 			c.Closed++
@@ -175,7 +177,8 @@ func computeCounts(c *ChangesetCounts, csEvents Events) error {
 			c.AddReviewState(currentReviewState, -1)
 
 		case campaigns.ChangesetEventKindGitHubReopened,
-			campaigns.ChangesetEventKindBitbucketServerReopened:
+			campaigns.ChangesetEventKindBitbucketServerReopened,
+			campaigns.ChangesetEventKindGitLabReopened:
 
 			c.Open++
 			c.Closed--
@@ -184,7 +187,9 @@ func computeCounts(c *ChangesetCounts, csEvents Events) error {
 			c.AddReviewState(currentReviewState, 1)
 
 		case campaigns.ChangesetEventKindGitHubMerged,
-			campaigns.ChangesetEventKindBitbucketServerMerged:
+			campaigns.ChangesetEventKindBitbucketServerMerged,
+			campaigns.ChangesetEventKindGitLabMerged,
+			campaigns.ChangesetEventKindBitbucketCloudMerged:
 
 			// If it was closed, all "review counts" have been updated by the
 			// closed events and we just need to reverse these two counts
@@ -205,7 +210,9 @@ func computeCounts(c *ChangesetCounts, csEvents Events) error {
 
 		case campaigns.ChangesetEventKindGitHubReviewed,
 			campaigns.ChangesetEventKindBitbucketServerApproved,
-			campaigns.ChangesetEventKindBitbucketServerReviewed:
+			campaigns.ChangesetEventKindBitbucketServerReviewed,
+			campaigns.ChangesetEventKindGitLabApproved,
+			campaigns.ChangesetEventKindBitbucketCloudApproved:
 
 			s, err := reviewState(e)
 			if err != nil {
@@ -248,7 +255,9 @@ func computeCounts(c *ChangesetCounts, csEvents Events) error {
 			}
 
 		case campaigns.ChangesetEventKindBitbucketServerUnapproved,
-			campaigns.ChangesetEventKindGitHubReviewDismissed:
+			campaigns.ChangesetEventKindGitHubReviewDismissed,
+			campaigns.ChangesetEventKindGitLabUnapproved,
+			campaigns.ChangesetEventKindBitbucketCloudUnapproved:
 			author, err := reviewAuthor(e)
 			if err != nil {
 				return err
@@ -257,12 +266,14 @@ func computeCounts(c *ChangesetCounts, csEvents Events) error {
 				continue
 			}
 
-			if e.Type() == campaigns.ChangesetEventKindBitbucketServerUnapproved {
-				// A BitbucketServer Unapproved can only follow a previous Approved by
-				// the same author.
+			if e.Type() == campaigns.ChangesetEventKindBitbucketServerUnapproved ||
+				e.Type() == campaigns.ChangesetEventKindGitLabUnapproved ||
+				e.Type() == campaigns.ChangesetEventKindBitbucketCloudUnapproved {
+				// An unapproval on Bitbucket Server, GitLab or Bitbucket Cloud
+				// can only follow a previous approval by the same author.
 				lastReview, ok := lastReviewByAuthor[author]
 				if !ok || lastReview != campaigns.ChangesetReviewStateApproved {
-					log15.Warn("Bitbucket Server Unapproval not following an Approval", "event", e)
+					log15.Warn("Unapproval not following an Approval", "event", e)
 					continue
 				}
 			}
@@ -7,8 +7,10 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/sourcegraph/sourcegraph/internal/campaigns"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 )
 
 func TestCalcCounts(t *testing.T) {
@@ -1175,6 +1177,103 @@ func TestCalcCounts(t *testing.T) {
 				{Time: daysAgo(0), Total: 1, Open: 1, OpenApproved: 1},
 			},
 		},
+		{
+			codehosts: "gitlab",
+			name:      "single changeset approved and unapproved",
+			changesets: []*campaigns.Changeset{
+				glChangeset(1, daysAgo(3)),
+			},
+			start: daysAgo(3),
+			events: []Event{
+				glApproval(1, daysAgo(2), "user1", campaigns.ChangesetEventKindGitLabApproved),
+				glApproval(1, daysAgo(1), "user1", campaigns.ChangesetEventKindGitLabUnapproved),
+			},
+			want: []*ChangesetCounts{
+				{Time: daysAgo(3), Total: 1, Open: 1, OpenPending: 1},
+				{Time: daysAgo(2), Total: 1, Open: 1, OpenApproved: 1},
+				{Time: daysAgo(1), Total: 1, Open: 1, OpenPending: 1},
+				{Time: daysAgo(0), Total: 1, Open: 1, OpenPending: 1},
+			},
+		},
+		{
+			codehosts: "gitlab",
+			name:      "single changeset closed reopened merged",
+			changesets: []*campaigns.Changeset{
+				glChangeset(1, daysAgo(3)),
+			},
+			start: daysAgo(3),
+			events: []Event{
+				fakeEvent{t: daysAgo(2), kind: campaigns.ChangesetEventKindGitLabClosed, id: 1},
+				fakeEvent{t: daysAgo(1), kind: campaigns.ChangesetEventKindGitLabReopened, id: 1},
+				fakeEvent{t: daysAgo(0), kind: campaigns.ChangesetEventKindGitLabMerged, id: 1},
+			},
+			want: []*ChangesetCounts{
+				{Time: daysAgo(3), Total: 1, Open: 1, OpenPending: 1},
+				{Time: daysAgo(2), Total: 1, Closed: 1},
+				{Time: daysAgo(1), Total: 1, Open: 1, OpenPending: 1},
+				{Time: daysAgo(0), Total: 1, Merged: 1},
+			},
+		},
+		{
+			codehosts: "bitbucketcloud",
+			name:      "single changeset approved merged and declined",
+			changesets: []*campaigns.Changeset{
+				bbcChangeset(1, daysAgo(3)),
+				bbcChangeset(2, daysAgo(3)),
+			},
+			start: daysAgo(3),
+			events: []Event{
+				&campaigns.ChangesetEvent{
+					ChangesetID: 1,
+					Kind:        campaigns.ChangesetEventKindBitbucketCloudApproved,
+					Metadata: &bitbucketcloud.Approval{
+						Date: daysAgo(2),
+						User: bitbucketcloud.Account{UUID: "{user1}"},
+					},
+				},
+				fakeEvent{t: daysAgo(1), kind: campaigns.ChangesetEventKindBitbucketCloudMerged, id: 1},
+				fakeEvent{t: daysAgo(1), kind: campaigns.ChangesetEventKindBitbucketCloudDeclined, id: 2},
+			},
+			want: []*ChangesetCounts{
+				{Time: daysAgo(3), Total: 2, Open: 2, OpenPending: 2},
+				{Time: daysAgo(2), Total: 2, Open: 2, OpenPending: 1, OpenApproved: 1},
+				{Time: daysAgo(1), Total: 2, Merged: 1, Closed: 1},
+				{Time: daysAgo(0), Total: 2, Merged: 1, Closed: 1},
+			},
+		},
+		{
+			codehosts: "bitbucketcloud",
+			name:      "single changeset approved and unapproved",
+			changesets: []*campaigns.Changeset{
+				bbcChangeset(1, daysAgo(3)),
+			},
+			start: daysAgo(3),
+			events: []Event{
+				&campaigns.ChangesetEvent{
+					ChangesetID: 1,
+					Kind:        campaigns.ChangesetEventKindBitbucketCloudApproved,
+					Metadata: &bitbucketcloud.Approval{
+						Date: daysAgo(2),
+						User: bitbucketcloud.Account{UUID: "{user1}"},
+					},
+				},
+				&campaigns.ChangesetEvent{
+					ChangesetID: 1,
+					Kind:        campaigns.ChangesetEventKindBitbucketCloudUnapproved,
+					Metadata: &bitbucketcloud.Unapproval{
+						ApprovalDate: daysAgo(2),
+						Date:         daysAgo(1),
+						User:         bitbucketcloud.Account{UUID: "{user1}"},
+					},
+				},
+			},
+			want: []*ChangesetCounts{
+				{Time: daysAgo(3), Total: 1, Open: 1, OpenPending: 1},
+				{Time: daysAgo(2), Total: 1, Open: 1, OpenApproved: 1},
+				{Time: daysAgo(1), Total: 1, Open: 1, OpenPending: 1},
+				{Time: daysAgo(0), Total: 1, Open: 1, OpenPending: 1},
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -1271,3 +1370,22 @@ func bbsActivity(id int64, t time.Time, username string, kind campaigns.Changese
 		},
 	}
 }
+
+func bbcChangeset(id int64, t time.Time) *campaigns.Changeset {
+	return &campaigns.Changeset{ID: id, Metadata: &bitbucketcloud.PullRequest{CreatedOn: t}}
+}
+
+func glChangeset(id int64, t time.Time) *campaigns.Changeset {
+	return &campaigns.Changeset{ID: id, Metadata: &gitlab.MergeRequest{CreatedAt: t}}
+}
+
+func glApproval(id int64, t time.Time, username string, kind campaigns.ChangesetEventKind) *campaigns.ChangesetEvent {
+	n := gitlab.Note{CreatedAt: t, Author: gitlab.User{Username: username}, System: true}
+	e := &campaigns.ChangesetEvent{ChangesetID: id, Kind: kind}
+	if kind == campaigns.ChangesetEventKindGitLabApproved {
+		e.Metadata = &gitlab.ReviewApprovedEvent{Note: n}
+	} else {
+		e.Metadata = &gitlab.ReviewUnapprovedEvent{Note: n}
+	}
+	return e
+}
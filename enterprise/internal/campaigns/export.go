@@ -0,0 +1,414 @@
+package campaigns
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+)
+
+// ExportHandler serves the analytics of a campaign, i.e. the timelines of
+// its changesets, their aggregation by repository owner and the burndown
+// chart, as JSON or CSV. The campaign is given by its GraphQL ID in the "id"
+// route variable.
+//
+// The format is selected with the "format" query parameter, which is "json"
+// (the default) or "csv". CSV exports contain a single report, selected with
+// the "report" query parameter: "changesets" (the default), "owners" or
+// "burndown".
+type ExportHandler struct {
+	Store *Store
+	Now   func() time.Time
+}
+
+// NewExportHandler returns a new ExportHandler.
+func NewExportHandler(store *Store, now func() time.Time) *ExportHandler {
+	return &ExportHandler{Store: store, Now: now}
+}
+
+func (h *ExportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 🚨 SECURITY: Only site admins or users when read-access is enabled may
+	// export campaigns.
+	if !conf.CampaignsReadAccessEnabled() {
+		if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	id := graphql.ID(mux.Vars(r)["id"])
+	var campaignID int64
+	if relay.UnmarshalKind(id) != "Campaign" {
+		http.Error(w, "invalid campaign ID", http.StatusBadRequest)
+		return
+	}
+	if err := relay.UnmarshalSpec(id, &campaignID); err != nil {
+		http.Error(w, "invalid campaign ID", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	report := r.URL.Query().Get("report")
+	switch format {
+	case "", "json", "csv":
+	default:
+		http.Error(w, fmt.Sprintf("unsupported format %q", format), http.StatusBadRequest)
+		return
+	}
+	switch report {
+	case "", "changesets", "owners", "burndown":
+	default:
+		http.Error(w, fmt.Sprintf("unsupported report %q", report), http.StatusBadRequest)
+		return
+	}
+
+	campaign, err := h.Store.GetCampaign(ctx, GetCampaignOpts{ID: campaignID})
+	if err == ErrNoResults {
+		http.Error(w, "campaign not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log15.Error("Exporting campaign", "campaign_id", campaignID, "err", err)
+		http.Error(w, "getting campaign failed", http.StatusInternalServerError)
+		return
+	}
+
+	export, err := h.export(ctx, campaign)
+	if err != nil {
+		log15.Error("Exporting campaign", "campaign_id", campaignID, "err", err)
+		http.Error(w, "exporting campaign failed", http.StatusInternalServerError)
+		return
+	}
+
+	if format == "csv" {
+		if report == "" {
+			report = "changesets"
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"campaign-%d-%s.csv\"", campaign.ID, report))
+		if err := export.writeCSV(w, report); err != nil {
+			log15.Error("Writing campaign export", "campaign_id", campaignID, "err", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(export); err != nil {
+		log15.Error("Writing campaign export", "campaign_id", campaignID, "err", err)
+	}
+}
+
+func (h *ExportHandler) export(ctx context.Context, campaign *campaigns.Campaign) (*campaignExport, error) {
+	cs, _, err := h.Store.ListChangesets(ctx, ListChangesetsOpts{CampaignID: campaign.ID, Limit: -1})
+	if err != nil {
+		return nil, err
+	}
+
+	repoIDs := make([]api.RepoID, 0, len(cs))
+	for _, c := range cs {
+		repoIDs = append(repoIDs, c.RepoID)
+	}
+
+	// 🚨 SECURITY: db.Repos.GetByIDs only returns the repositories the user
+	// has access to, so we leave out the changesets of all other ones.
+	rs, err := db.Repos.GetByIDs(ctx, repoIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	repoNames := make(map[api.RepoID]api.RepoName, len(rs))
+	for _, r := range rs {
+		repoNames[r.ID] = r.Name
+	}
+
+	visible := cs[:0]
+	for _, c := range cs {
+		if _, ok := repoNames[c.RepoID]; ok {
+			visible = append(visible, c)
+		}
+	}
+	cs = visible
+
+	changesetIDs := make([]int64, len(cs))
+	for i, c := range cs {
+		changesetIDs[i] = c.ID
+	}
+
+	es, _, err := h.Store.ListChangesetEvents(ctx, ListChangesetEventsOpts{
+		ChangesetIDs: changesetIDs,
+		Limit:        -1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]Event, len(es))
+	for i, e := range es {
+		events[i] = e
+	}
+
+	timelines, err := CalcChangesetTimelines(cs, repoNames, events...)
+	if err != nil {
+		return nil, err
+	}
+
+	now := h.Now().UTC()
+	counts, err := CalcCounts(campaign.CreatedAt.UTC(), now, cs, events...)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &campaignExport{
+		GeneratedAt: now,
+		Changesets:  make([]*changesetExport, 0, len(timelines)),
+		Owners:      make([]*ownerExport, 0),
+		Burndown:    make([]*countsExport, 0, len(counts)),
+	}
+	export.Campaign.ID = relay.MarshalID("Campaign", campaign.ID)
+	export.Campaign.Name = campaign.Name
+
+	for _, t := range timelines {
+		export.Changesets = append(export.Changesets, &changesetExport{
+			ID:                 relay.MarshalID("ExternalChangeset", t.ChangesetID),
+			Repository:         string(t.Repo),
+			Owner:              t.Owner,
+			ExternalID:         t.ExternalID,
+			ExternalURL:        t.ExternalURL,
+			State:              t.State,
+			OpenedAt:           nullableTime(t.OpenedAt),
+			FirstReviewAt:      nullableTime(t.FirstReviewAt),
+			ApprovedAt:         nullableTime(t.ApprovedAt),
+			MergedAt:           nullableTime(t.MergedAt),
+			ClosedAt:           nullableTime(t.ClosedAt),
+			TimeToMergeSeconds: nullableSeconds(t.MergedAt, t.TimeToMerge),
+			CIFailures:         t.CIFailures,
+		})
+	}
+
+	for _, s := range CalcOwnerStats(timelines) {
+		export.Owners = append(export.Owners, &ownerExport{
+			Owner:                    s.Owner,
+			Total:                    s.Total,
+			Open:                     s.Open,
+			Merged:                   s.Merged,
+			Closed:                   s.Closed,
+			Approved:                 s.Approved,
+			CIFailures:               s.CIFailures,
+			MedianTimeToMergeSeconds: int64(s.MedianTimeToMerge.Seconds()),
+		})
+	}
+
+	for _, c := range counts {
+		export.Burndown = append(export.Burndown, &countsExport{
+			Date:                 c.Time,
+			Total:                c.Total,
+			Merged:               c.Merged,
+			Closed:               c.Closed,
+			Open:                 c.Open,
+			OpenApproved:         c.OpenApproved,
+			OpenChangesRequested: c.OpenChangesRequested,
+			OpenPending:          c.OpenPending,
+		})
+	}
+
+	return export, nil
+}
+
+type campaignExport struct {
+	Campaign struct {
+		ID   graphql.ID `json:"id"`
+		Name string     `json:"name"`
+	} `json:"campaign"`
+	GeneratedAt time.Time          `json:"generatedAt"`
+	Changesets  []*changesetExport `json:"changesets"`
+	Owners      []*ownerExport     `json:"owners"`
+	Burndown    []*countsExport    `json:"burndown"`
+}
+
+func (e *campaignExport) writeCSV(w io.Writer, report string) error {
+	cw := csv.NewWriter(w)
+
+	switch report {
+	case "owners":
+		_ = cw.Write(ownerExportHeader)
+		for _, o := range e.Owners {
+			_ = cw.Write(o.record())
+		}
+	case "burndown":
+		_ = cw.Write(countsExportHeader)
+		for _, c := range e.Burndown {
+			_ = cw.Write(c.record())
+		}
+	default:
+		_ = cw.Write(changesetExportHeader)
+		for _, c := range e.Changesets {
+			_ = cw.Write(c.record())
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+type changesetExport struct {
+	ID                 graphql.ID               `json:"id"`
+	Repository         string                   `json:"repository"`
+	Owner              string                   `json:"owner"`
+	ExternalID         string                   `json:"externalID"`
+	ExternalURL        string                   `json:"externalURL"`
+	State              campaigns.ChangesetState `json:"state"`
+	OpenedAt           *time.Time               `json:"openedAt"`
+	FirstReviewAt      *time.Time               `json:"firstReviewAt"`
+	ApprovedAt         *time.Time               `json:"approvedAt"`
+	MergedAt           *time.Time               `json:"mergedAt"`
+	ClosedAt           *time.Time               `json:"closedAt"`
+	TimeToMergeSeconds *int64                   `json:"timeToMergeSeconds"`
+	CIFailures         int                      `json:"ciFailures"`
+}
+
+var changesetExportHeader = []string{
+	"id",
+	"repository",
+	"owner",
+	"externalID",
+	"externalURL",
+	"state",
+	"openedAt",
+	"firstReviewAt",
+	"approvedAt",
+	"mergedAt",
+	"closedAt",
+	"timeToMergeSeconds",
+	"ciFailures",
+}
+
+func (c *changesetExport) record() []string {
+	var timeToMerge string
+	if c.TimeToMergeSeconds != nil {
+		timeToMerge = strconv.FormatInt(*c.TimeToMergeSeconds, 10)
+	}
+
+	return []string{
+		string(c.ID),
+		c.Repository,
+		c.Owner,
+		c.ExternalID,
+		c.ExternalURL,
+		string(c.State),
+		csvTime(c.OpenedAt),
+		csvTime(c.FirstReviewAt),
+		csvTime(c.ApprovedAt),
+		csvTime(c.MergedAt),
+		csvTime(c.ClosedAt),
+		timeToMerge,
+		strconv.Itoa(c.CIFailures),
+	}
+}
+
+type ownerExport struct {
+	Owner                    string `json:"owner"`
+	Total                    int32  `json:"total"`
+	Open                     int32  `json:"open"`
+	Merged                   int32  `json:"merged"`
+	Closed                   int32  `json:"closed"`
+	Approved                 int32  `json:"approved"`
+	CIFailures               int    `json:"ciFailures"`
+	MedianTimeToMergeSeconds int64  `json:"medianTimeToMergeSeconds"`
+}
+
+var ownerExportHeader = []string{
+	"owner",
+	"total",
+	"open",
+	"merged",
+	"closed",
+	"approved",
+	"ciFailures",
+	"medianTimeToMergeSeconds",
+}
+
+func (o *ownerExport) record() []string {
+	return []string{
+		o.Owner,
+		strconv.Itoa(int(o.Total)),
+		strconv.Itoa(int(o.Open)),
+		strconv.Itoa(int(o.Merged)),
+		strconv.Itoa(int(o.Closed)),
+		strconv.Itoa(int(o.Approved)),
+		strconv.Itoa(o.CIFailures),
+		strconv.FormatInt(o.MedianTimeToMergeSeconds, 10),
+	}
+}
+
+type countsExport struct {
+	Date                 time.Time `json:"date"`
+	Total                int32     `json:"total"`
+	Merged               int32     `json:"merged"`
+	Closed               int32     `json:"closed"`
+	Open                 int32     `json:"open"`
+	OpenApproved         int32     `json:"openApproved"`
+	OpenChangesRequested int32     `json:"openChangesRequested"`
+	OpenPending          int32     `json:"openPending"`
+}
+
+var countsExportHeader = []string{
+	"date",
+	"total",
+	"merged",
+	"closed",
+	"open",
+	"openApproved",
+	"openChangesRequested",
+	"openPending",
+}
+
+func (c *countsExport) record() []string {
+	return []string{
+		c.Date.Format(time.RFC3339),
+		strconv.Itoa(int(c.Total)),
+		strconv.Itoa(int(c.Merged)),
+		strconv.Itoa(int(c.Closed)),
+		strconv.Itoa(int(c.Open)),
+		strconv.Itoa(int(c.OpenApproved)),
+		strconv.Itoa(int(c.OpenChangesRequested)),
+		strconv.Itoa(int(c.OpenPending)),
+	}
+}
+
+func nullableTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func nullableSeconds(mergedAt time.Time, d time.Duration) *int64 {
+	if mergedAt.IsZero() {
+		return nil
+	}
+	s := int64(d.Seconds())
+	return &s
+}
+
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
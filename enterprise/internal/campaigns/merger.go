@@ -0,0 +1,237 @@
+package campaigns
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/repo-updater/repos"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
+	"github.com/sourcegraph/sourcegraph/internal/env"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+)
+
+// maxConcurrentMerges defines the maximum number of changesets to merge in
+// parallel on a single code host.
+var maxConcurrentMerges = env.Get("A8N_MAX_CONCURRENT_MERGES", "1", "maximum number of changesets to merge in parallel per code host")
+
+const defaultConcurrentMerges = 1
+
+// mergeRetryDelay is the time after which merging a changeset that failed
+// to merge is retried.
+var mergeRetryDelay = 30 * time.Minute
+
+// A ChangesetMerger periodically merges the changesets of campaigns with
+// auto-merge enabled, once they are approved and their checks passed.
+type ChangesetMerger struct {
+	Store       MergeStore
+	ReposStore  repos.Store
+	HTTPFactory *httpcli.Factory
+
+	// Interval determines how often the changesets to merge are computed.
+	Interval time.Duration
+
+	// MaxConcurrentMerges is the maximum number of changesets merged in
+	// parallel on a single code host. If zero, A8N_MAX_CONCURRENT_MERGES is
+	// used.
+	MaxConcurrentMerges int
+
+	// failed maps the IDs of changesets that failed to merge to the time
+	// after which merging them is retried.
+	failed map[int64]time.Time
+
+	// Replaceable for testing
+	clock func() time.Time
+}
+
+type MergeStore interface {
+	SyncStore
+	ListChangesetMergeCandidates(context.Context) ([]campaigns.ChangesetMergeCandidate, error)
+}
+
+// Run will start the process of merging changesets. It is long running
+// and is expected to be launched once at startup.
+func (m *ChangesetMerger) Run(ctx context.Context) {
+	interval := m.Interval
+	if interval == 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.MergeChangesets(ctx); err != nil {
+			log15.Error("Merging changesets", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// MergeChangesets merges all changesets that can be merged automatically,
+// except those that recently failed to merge, and syncs the merged
+// changesets.
+func (m *ChangesetMerger) MergeChangesets(ctx context.Context) error {
+	m.init()
+
+	candidates, err := m.Store.ListChangesetMergeCandidates(ctx)
+	if err != nil {
+		return errors.Wrap(err, "listing changeset merge candidates")
+	}
+
+	now := m.clock()
+	methods := make(map[int64]campaigns.ChangesetMergeMethod, len(candidates))
+	ids := make([]int64, 0, len(candidates))
+	for _, c := range candidates {
+		methods[c.ChangesetID] = c.MergeMethod
+		if retry, ok := m.failed[c.ChangesetID]; ok && now.Before(retry) {
+			continue
+		}
+		ids = append(ids, c.ChangesetID)
+	}
+
+	// Forget about failures of changesets that aren't candidates anymore,
+	// e.g. because they have been merged or closed on the code host.
+	for id := range m.failed {
+		if _, ok := methods[id]; !ok {
+			delete(m.failed, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	cs, _, err := m.Store.ListChangesets(ctx, ListChangesetsOpts{IDs: ids, Limit: -1})
+	if err != nil {
+		return err
+	}
+
+	syncer := &ChangesetSyncer{
+		Store:       m.Store,
+		ReposStore:  m.ReposStore,
+		HTTPFactory: m.HTTPFactory,
+	}
+
+	bySource, err := syncer.GroupChangesetsBySource(ctx, cs...)
+	if err != nil {
+		return err
+	}
+
+	merged := m.MergeChangesetsWithSources(ctx, bySource, methods)
+	if len(merged) == 0 {
+		return nil
+	}
+
+	// Sync the merged changesets right away, so that their new state and
+	// events show up without waiting for the next scheduled sync.
+	return syncer.SyncChangesetsWithSources(ctx, merged)
+}
+
+// MergeChangesetsWithSources merges the given changesets with the given
+// ChangesetSources, using the merge method in methods keyed by changeset ID.
+// At most MaxConcurrentMerges changesets are merged at a time per source.
+// It returns the changesets that were merged, grouped by source.
+func (m *ChangesetMerger) MergeChangesetsWithSources(ctx context.Context, bySource []*SourceChangesets, methods map[int64]campaigns.ChangesetMergeMethod) []*SourceChangesets {
+	m.init()
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		merged []*SourceChangesets
+	)
+
+	for _, s := range bySource {
+		wg.Add(1)
+		go func(s *SourceChangesets) {
+			defer wg.Done()
+
+			ok, failed := m.mergeWithSource(ctx, s, methods)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			retry := m.clock().Add(mergeRetryDelay)
+			for _, id := range failed {
+				m.failed[id] = retry
+			}
+			if len(ok) > 0 {
+				merged = append(merged, &SourceChangesets{
+					ChangesetSource: s.ChangesetSource,
+					Changesets:      ok,
+				})
+			}
+		}(s)
+	}
+
+	wg.Wait()
+
+	return merged
+}
+
+// mergeWithSource merges the changesets of the given SourceChangesets and
+// returns the merged changesets and the IDs of the ones that failed to merge.
+func (m *ChangesetMerger) mergeWithSource(ctx context.Context, s *SourceChangesets, methods map[int64]campaigns.ChangesetMergeMethod) (merged []*repos.Changeset, failed []int64) {
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, m.MaxConcurrentMerges)
+	)
+
+	for _, c := range s.Changesets {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(c *repos.Changeset) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			method, ok := methods[c.Changeset.ID]
+			if !ok || !method.Valid() {
+				method = campaigns.ChangesetMergeMethodMerge
+			}
+
+			err := s.MergeChangeset(ctx, c, method)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				log15.Error("Merging changeset", "changeset_id", c.Changeset.ID, "method", method, "err", err)
+				failed = append(failed, c.Changeset.ID)
+				return
+			}
+			merged = append(merged, c)
+		}(c)
+	}
+
+	wg.Wait()
+
+	return merged, failed
+}
+
+func (m *ChangesetMerger) init() {
+	if m.clock == nil {
+		m.clock = time.Now
+	}
+	if m.failed == nil {
+		m.failed = make(map[int64]time.Time)
+	}
+	if m.MaxConcurrentMerges <= 0 {
+		n, err := strconv.Atoi(maxConcurrentMerges)
+		if err != nil || n <= 0 {
+			log15.Error("Parsing max concurrent merges failed. Falling back to default.", "default", defaultConcurrentMerges, "err", err)
+			n = defaultConcurrentMerges
+		}
+		m.MaxConcurrentMerges = n
+	}
+}
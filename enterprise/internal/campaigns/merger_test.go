@@ -0,0 +1,115 @@
+package campaigns
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/cmd/repo-updater/repos"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
+)
+
+func TestChangesetMerger_MergeChangesetsWithSources(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	github := &fakeMergeSource{fail: map[int64]bool{2: true}}
+	gitlab := &fakeMergeSource{}
+
+	bySource := []*SourceChangesets{
+		{ChangesetSource: github, Changesets: testMergeChangesets(1, 2, 3)},
+		{ChangesetSource: gitlab, Changesets: testMergeChangesets(4, 5)},
+	}
+	methods := map[int64]campaigns.ChangesetMergeMethod{
+		1: campaigns.ChangesetMergeMethodSquash,
+		2: campaigns.ChangesetMergeMethodRebase,
+		4: campaigns.ChangesetMergeMethodMerge,
+		5: "invalid",
+	}
+
+	m := &ChangesetMerger{MaxConcurrentMerges: 2, clock: func() time.Time { return now }}
+	merged := m.MergeChangesetsWithSources(context.Background(), bySource, methods)
+
+	var mergedIDs []int64
+	for _, s := range merged {
+		for _, c := range s.Changesets {
+			mergedIDs = append(mergedIDs, c.Changeset.ID)
+		}
+	}
+	sort.Slice(mergedIDs, func(i, j int) bool { return mergedIDs[i] < mergedIDs[j] })
+	if diff := cmp.Diff([]int64{1, 3, 4, 5}, mergedIDs); diff != "" {
+		t.Errorf("merged changesets: %s", diff)
+	}
+
+	if diff := cmp.Diff(map[int64]time.Time{2: now.Add(mergeRetryDelay)}, m.failed); diff != "" {
+		t.Errorf("failed changesets: %s", diff)
+	}
+
+	wantMethods := map[int64]campaigns.ChangesetMergeMethod{
+		1: campaigns.ChangesetMergeMethodSquash,
+		2: campaigns.ChangesetMergeMethodRebase,
+		3: campaigns.ChangesetMergeMethodMerge,
+	}
+	if diff := cmp.Diff(wantMethods, github.methods); diff != "" {
+		t.Errorf("github merge methods: %s", diff)
+	}
+	wantMethods = map[int64]campaigns.ChangesetMergeMethod{
+		4: campaigns.ChangesetMergeMethodMerge,
+		5: campaigns.ChangesetMergeMethodMerge,
+	}
+	if diff := cmp.Diff(wantMethods, gitlab.methods); diff != "" {
+		t.Errorf("gitlab merge methods: %s", diff)
+	}
+
+	if github.maxInFlight > 2 || gitlab.maxInFlight > 2 {
+		t.Errorf("exceeded concurrent merges: github=%d gitlab=%d", github.maxInFlight, gitlab.maxInFlight)
+	}
+}
+
+func testMergeChangesets(ids ...int64) []*repos.Changeset {
+	cs := make([]*repos.Changeset, 0, len(ids))
+	for _, id := range ids {
+		cs = append(cs, &repos.Changeset{Changeset: &campaigns.Changeset{ID: id}})
+	}
+	return cs
+}
+
+// fakeMergeSource is a repos.ChangesetSource that records the changesets
+// it merges.
+type fakeMergeSource struct {
+	repos.ChangesetSource
+
+	fail map[int64]bool
+
+	mu          sync.Mutex
+	methods     map[int64]campaigns.ChangesetMergeMethod
+	inFlight    int
+	maxInFlight int
+}
+
+func (s *fakeMergeSource) MergeChangeset(ctx context.Context, c *repos.Changeset, method campaigns.ChangesetMergeMethod) error {
+	s.mu.Lock()
+	if s.methods == nil {
+		s.methods = make(map[int64]campaigns.ChangesetMergeMethod)
+	}
+	s.methods[c.Changeset.ID] = method
+	s.inFlight++
+	if s.inFlight > s.maxInFlight {
+		s.maxInFlight = s.inFlight
+	}
+	s.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	s.mu.Lock()
+	s.inFlight--
+	s.mu.Unlock()
+
+	if s.fail[c.Changeset.ID] {
+		return errors.New("merge conflict")
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package campaigns
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/repo-updater/repos"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+// A ChangesetRebaser periodically rebases the open changesets of campaigns
+// whose base ref advanced, by reapplying their patches on the latest commit
+// of the base ref and force-pushing the result to their branches.
+type ChangesetRebaser struct {
+	Store      *Store
+	ReposStore repos.Store
+	GitClient  GitserverClient
+
+	// Interval determines how often the changesets to rebase are computed.
+	Interval time.Duration
+
+	// skipped maps the IDs of changesets that can't be rebased onto the
+	// current base commit to that commit, so that rebasing them is only
+	// retried once their base ref advances again.
+	skipped map[int64]api.CommitID
+
+	// Replaceable for testing
+	clock func() time.Time
+}
+
+// Run will start the process of rebasing changesets. It is long running
+// and is expected to be launched once at startup.
+func (r *ChangesetRebaser) Run(ctx context.Context) {
+	interval := r.Interval
+	if interval == 0 {
+		interval = 5 * time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.RebaseChangesets(ctx); err != nil {
+			log15.Error("Rebasing changesets", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RebaseChangesets rebases all open changesets created by campaigns whose
+// base ref advanced since their branch was last pushed.
+func (r *ChangesetRebaser) RebaseChangesets(ctx context.Context) error {
+	r.init()
+
+	jobs, err := r.Store.ListRebaseableChangesetJobs(ctx)
+	if err != nil {
+		return errors.Wrap(err, "listing rebaseable changeset jobs")
+	}
+
+	candidates := make(map[int64]bool, len(jobs))
+	for _, job := range jobs {
+		candidates[job.ChangesetID] = true
+
+		if err := r.RebaseChangeset(ctx, job); err != nil {
+			log15.Error("Rebasing changeset", "changeset_id", job.ChangesetID, "err", err)
+		}
+	}
+
+	for id := range r.skipped {
+		if !candidates[id] {
+			delete(r.skipped, id)
+		}
+	}
+
+	return nil
+}
+
+// RebaseChangeset rebases the changeset of the given ChangesetJob if the
+// base ref of its patch advanced. If the patch no longer applies, the
+// changeset is marked as conflicted. Branches with commits that weren't
+// created by Sourcegraph are never rebased, including commits pushed after
+// gitserver last fetched the branch.
+func (r *ChangesetRebaser) RebaseChangeset(ctx context.Context, job *campaigns.ChangesetJob) (err error) {
+	r.init()
+
+	patch, err := r.Store.GetPatch(ctx, GetPatchOpts{ID: job.PatchID})
+	if err != nil {
+		return errors.Wrap(err, "getting patch")
+	}
+
+	rs, err := r.ReposStore.ListRepos(ctx, repos.StoreListReposArgs{IDs: []api.RepoID{api.RepoID(patch.RepoID)}})
+	if err != nil {
+		return err
+	}
+	if len(rs) != 1 {
+		return errors.Errorf("repo not found: %d", patch.RepoID)
+	}
+	repo := gitserver.Repo{Name: api.RepoName(rs[0].Name)}
+
+	baseRef := patchBaseRef(patch)
+	base, err := git.ResolveRevision(ctx, repo, nil, baseRef, nil)
+	if err != nil {
+		return errors.Wrapf(err, "resolving base ref %q", baseRef)
+	}
+
+	previousBase := api.CommitID(job.BaseCommit)
+	if previousBase == "" {
+		previousBase = patch.Rev
+	}
+
+	if base == previousBase || r.skipped[job.ChangesetID] == base {
+		return nil
+	}
+
+	// The branch consists of a single commit on top of the previous base
+	// commit, unless somebody pushed to it. We don't want to overwrite their
+	// work, so we leave the branch alone in that case.
+	branch := git.EnsureRefPrefix(job.Branch)
+	head, err := git.ResolveRevision(ctx, repo, nil, branch, nil)
+	if err != nil {
+		return errors.Wrapf(err, "resolving branch %q", branch)
+	}
+	parent, err := git.ResolveRevision(ctx, repo, nil, branch+"~1", nil)
+	if err != nil {
+		return errors.Wrapf(err, "resolving parent of branch %q", branch)
+	}
+	if parent != previousBase {
+		log15.Warn("Not rebasing changeset, its branch has been changed outside of Sourcegraph", "changeset_id", job.ChangesetID, "branch", branch)
+		r.skipped[job.ChangesetID] = base
+		return nil
+	}
+
+	c, err := r.Store.GetCampaign(ctx, GetCampaignOpts{ID: job.CampaignID})
+	if err != nil {
+		return errors.Wrap(err, "getting campaign")
+	}
+
+	now := r.clock()
+
+	req, err := patchCommitRequest(ctx, repo.Name, c, patch, base, now)
+	if err != nil {
+		return err
+	}
+	req.TargetRef = job.Branch
+	// gitserver's copy of the branch can be outdated, so the push only
+	// overwrites the branch if it's still at the head we checked above.
+	req.ExpectedHead = head
+
+	_, err = r.GitClient.CreateCommitFromPatch(ctx, req)
+	if isStaleHeadError(err) {
+		log15.Warn("Not rebasing changeset, its branch has been pushed to since it was last fetched", "changeset_id", job.ChangesetID, "branch", branch)
+		r.skipped[job.ChangesetID] = base
+		return nil
+	}
+	if err != nil && !isPatchApplyError(err) {
+		return errors.Wrap(err, "creating commit from patch")
+	}
+	conflicted := err != nil
+
+	tx, err := r.Store.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Done(&err)
+
+	cs, err := tx.GetChangeset(ctx, GetChangesetOpts{ID: job.ChangesetID})
+	if err != nil {
+		return errors.Wrap(err, "getting changeset")
+	}
+
+	if conflicted {
+		r.skipped[job.ChangesetID] = base
+		if cs.Conflicted {
+			return nil
+		}
+		cs.Conflicted = true
+		return tx.UpdateChangesets(ctx, cs)
+	}
+
+	job.BaseCommit = string(base)
+	if err = tx.UpdateChangesetJob(ctx, job); err != nil {
+		return err
+	}
+
+	if cs.Conflicted {
+		cs.Conflicted = false
+		if err = tx.UpdateChangesets(ctx, cs); err != nil {
+			return err
+		}
+	}
+
+	rebase := &campaigns.ChangesetRebase{
+		BaseRef:            baseRef,
+		PreviousBaseCommit: string(previousBase),
+		BaseCommit:         string(base),
+		RebasedAt:          now,
+	}
+
+	return tx.UpsertChangesetEvents(ctx, &campaigns.ChangesetEvent{
+		ChangesetID: cs.ID,
+		Kind:        campaigns.ChangesetEventKindFor(rebase),
+		Key:         rebase.Key(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Metadata:    rebase,
+	})
+}
+
+func (r *ChangesetRebaser) init() {
+	if r.clock == nil {
+		r.clock = r.Store.Clock()
+	}
+	if r.skipped == nil {
+		r.skipped = make(map[int64]api.CommitID)
+	}
+}
+
+// isPatchApplyError returns true if the given error was returned by
+// CreateCommitFromPatch because the patch doesn't apply to the base commit.
+func isPatchApplyError(err error) bool {
+	diffErr, ok := err.(*protocol.CreateCommitFromPatchError)
+	return ok && strings.HasPrefix(diffErr.Command, "git apply")
+}
+
+// isStaleHeadError returns true if the push of a rebased branch was
+// rejected because the branch doesn't point at the expected head anymore.
+func isStaleHeadError(err error) bool {
+	pushErr, ok := err.(*protocol.CreateCommitFromPatchError)
+	return ok && strings.HasPrefix(pushErr.Command, "git push") && strings.Contains(pushErr.CombinedOutput, "(stale info)")
+}
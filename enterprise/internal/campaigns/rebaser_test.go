@@ -0,0 +1,238 @@
+package campaigns
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/repo-updater/repos"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbconn"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbtesting"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+func TestChangesetRebaser(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := backend.WithAuthzBypass(context.Background())
+	dbtesting.SetupGlobalTestDB(t)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	clock := func() time.Time {
+		return now.UTC().Truncate(time.Microsecond)
+	}
+
+	user := createTestUser(ctx, t)
+	store := NewStoreWithClock(dbconn.Global, clock)
+
+	repo := testRepo(0, github.ServiceType)
+	reposStore := repos.NewDBStore(dbconn.Global, sql.TxOptions{})
+	if err := reposStore.UpsertRepos(ctx, repo); err != nil {
+		t.Fatal(err)
+	}
+
+	patchSet := &campaigns.PatchSet{UserID: user.ID}
+	if err := store.CreatePatchSet(ctx, patchSet); err != nil {
+		t.Fatal(err)
+	}
+
+	patch := testPatch(patchSet.ID, repo.ID, now)
+	if err := store.CreatePatch(ctx, patch); err != nil {
+		t.Fatal(err)
+	}
+
+	campaign := testCampaign(user.ID, patchSet.ID)
+	if err := store.CreateCampaign(ctx, campaign); err != nil {
+		t.Fatal(err)
+	}
+
+	changeset := testChangeset(repo.ID, campaign.ID, 0, campaigns.ChangesetStateOpen)
+	if err := store.CreateChangesets(ctx, changeset); err != nil {
+		t.Fatal(err)
+	}
+
+	job := &campaigns.ChangesetJob{
+		CampaignID:  campaign.ID,
+		PatchID:     patch.ID,
+		ChangesetID: changeset.ID,
+		Branch:      campaign.Branch,
+		BaseCommit:  string(patch.Rev),
+		StartedAt:   now,
+		FinishedAt:  now,
+	}
+	if err := store.CreateChangesetJob(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+
+	// revisions maps the revision specs resolved by the rebaser to commits.
+	revisions := map[string]api.CommitID{
+		"refs/heads/master":        patch.Rev,
+		"refs/heads/test-branch":   "head0",
+		"refs/heads/test-branch~1": patch.Rev,
+	}
+	git.Mocks.ResolveRevision = func(spec string, opt *git.ResolveRevisionOptions) (api.CommitID, error) {
+		return revisions[spec], nil
+	}
+	defer git.ResetMocks()
+
+	gitClient := &recordingGitserverClient{}
+	rebaser := &ChangesetRebaser{
+		Store:      store,
+		ReposStore: reposStore,
+		GitClient:  gitClient,
+	}
+
+	rebase := func(t *testing.T) (*campaigns.ChangesetJob, *campaigns.Changeset) {
+		t.Helper()
+
+		if err := rebaser.RebaseChangesets(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		j, err := store.GetChangesetJob(ctx, GetChangesetJobOpts{ID: job.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := store.GetChangeset(ctx, GetChangesetOpts{ID: changeset.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return j, c
+	}
+
+	t.Run("base unchanged", func(t *testing.T) {
+		j, _ := rebase(t)
+		if have := len(gitClient.requests); have != 0 {
+			t.Fatalf("have %d commits created, want none", have)
+		}
+		if have, want := j.BaseCommit, string(patch.Rev); have != want {
+			t.Fatalf("have base commit %q, want %q", have, want)
+		}
+	})
+
+	t.Run("patch does not apply", func(t *testing.T) {
+		revisions["refs/heads/master"] = "base1"
+		gitClient.err = &protocol.CreateCommitFromPatchError{Command: "git apply -p0 --unidiff-zero"}
+
+		j, c := rebase(t)
+		if have := len(gitClient.requests); have != 1 {
+			t.Fatalf("have %d commits created, want 1", have)
+		}
+		if !c.Conflicted {
+			t.Fatal("changeset not marked as conflicted")
+		}
+		if have, want := j.BaseCommit, string(patch.Rev); have != want {
+			t.Fatalf("have base commit %q, want %q", have, want)
+		}
+
+		// Rebasing onto the same base commit isn't retried.
+		rebase(t)
+		if have := len(gitClient.requests); have != 1 {
+			t.Fatalf("have %d commits created, want 1", have)
+		}
+	})
+
+	t.Run("base advanced", func(t *testing.T) {
+		revisions["refs/heads/master"] = "base2"
+		gitClient.err = nil
+		gitClient.requests = nil
+
+		j, c := rebase(t)
+		if have := len(gitClient.requests); have != 1 {
+			t.Fatalf("have %d commits created, want 1", have)
+		}
+
+		req := gitClient.requests[0]
+		if have, want := req.BaseCommit, api.CommitID("base2"); have != want {
+			t.Fatalf("have request base commit %q, want %q", have, want)
+		}
+		if have, want := req.TargetRef, campaign.Branch; have != want {
+			t.Fatalf("have request target ref %q, want %q", have, want)
+		}
+		if have, want := req.ExpectedHead, api.CommitID("head0"); have != want {
+			t.Fatalf("have request expected head %q, want %q", have, want)
+		}
+
+		if c.Conflicted {
+			t.Fatal("changeset still marked as conflicted")
+		}
+		if have, want := j.BaseCommit, "base2"; have != want {
+			t.Fatalf("have base commit %q, want %q", have, want)
+		}
+
+		events, _, err := store.ListChangesetEvents(ctx, ListChangesetEventsOpts{
+			ChangesetIDs: []int64{changeset.ID},
+			Limit:        -1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 1 || events[0].Kind != campaigns.ChangesetEventKindRebased || events[0].Key != "base2" {
+			t.Fatalf("have events %+v, want a single rebased event", events)
+		}
+	})
+
+	t.Run("branch pushed to since it was last fetched", func(t *testing.T) {
+		revisions["refs/heads/master"] = "base3"
+		gitClient.err = &protocol.CreateCommitFromPatchError{
+			Command:        "git push --force-with-lease=refs/heads/test-branch:head0",
+			CombinedOutput: " ! [rejected]        abc -> test-branch (stale info)",
+		}
+		gitClient.requests = nil
+
+		j, c := rebase(t)
+		if have := len(gitClient.requests); have != 1 {
+			t.Fatalf("have %d commits created, want 1", have)
+		}
+		if c.Conflicted {
+			t.Fatal("changeset marked as conflicted")
+		}
+		if have, want := j.BaseCommit, "base2"; have != want {
+			t.Fatalf("have base commit %q, want %q", have, want)
+		}
+
+		// Rebasing onto the same base commit isn't retried.
+		rebase(t)
+		if have := len(gitClient.requests); have != 1 {
+			t.Fatalf("have %d commits created, want 1", have)
+		}
+	})
+
+	t.Run("branch changed outside of Sourcegraph", func(t *testing.T) {
+		revisions["refs/heads/master"] = "base4"
+		revisions["refs/heads/test-branch~1"] = "human-commit"
+		gitClient.err = nil
+		gitClient.requests = nil
+
+		j, _ := rebase(t)
+		if have := len(gitClient.requests); have != 0 {
+			t.Fatalf("have %d commits created, want none", have)
+		}
+		if have, want := j.BaseCommit, "base2"; have != want {
+			t.Fatalf("have base commit %q, want %q", have, want)
+		}
+	})
+}
+
+// recordingGitserverClient is a GitserverClient that records the requests
+// it receives.
+type recordingGitserverClient struct {
+	requests []protocol.CreateCommitFromPatchRequest
+	err      error
+}
+
+func (c *recordingGitserverClient) CreateCommitFromPatch(ctx context.Context, req protocol.CreateCommitFromPatchRequest) (string, error) {
+	c.requests = append(c.requests, req)
+	if c.err != nil {
+		return "", c.err
+	}
+	return req.TargetRef, nil
+}
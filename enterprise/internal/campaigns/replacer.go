@@ -0,0 +1,102 @@
+package campaigns
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+)
+
+// ReplacerClient computes the diff resulting from rewriting a repository at
+// a given commit according to a PatchSetSpec.
+type ReplacerClient interface {
+	Replace(ctx context.Context, repo api.RepoName, commit api.CommitID, spec *campaigns.PatchSetSpec) (string, error)
+}
+
+// NewReplacerClient returns a ReplacerClient that sends its requests to the
+// replacer service at the given URL.
+func NewReplacerClient(replacerURL string, doer httpcli.Doer) ReplacerClient {
+	return &replacerClient{url: replacerURL, doer: doer}
+}
+
+type replacerClient struct {
+	url  string
+	doer httpcli.Doer
+}
+
+// Replace implements ReplacerClient. The diffs of all rewritten files are
+// concatenated into a single diff, which is empty if no file was rewritten.
+func (c *replacerClient) Replace(ctx context.Context, repo api.RepoName, commit api.CommitID, spec *campaigns.PatchSetSpec) (string, error) {
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("repo", string(repo))
+	q.Set("commit", string(commit))
+	q.Set("matchtemplate", spec.MatchTemplate)
+	q.Set("rewritetemplate", spec.RewriteTemplate)
+	q.Set("fileextension", spec.FileExtension)
+	q.Set("directoryexclude", spec.DirectoryExclude)
+	q.Set("isregexp", strconv.FormatBool(spec.IsRegExp))
+	q.Set("isliteral", strconv.FormatBool(spec.IsLiteral))
+	q.Set("iscasesensitive", strconv.FormatBool(spec.IsCaseSensitive))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.doer.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", errors.Wrap(err, "replacer request failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return "", errors.Errorf("replacer request failed with status %d: %s", resp.StatusCode, body)
+	}
+
+	// The replacer responds with one JSON object per rewritten file. Unlike
+	// the codemod search results, we can't skip lines we fail to decode,
+	// since that would silently drop files from the patch.
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 100), 10*bufio.MaxScanTokenSize)
+
+	var diff strings.Builder
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var result struct {
+			URI  string `json:"uri"`
+			Diff string `json:"diff"`
+		}
+		if err := json.Unmarshal(line, &result); err != nil {
+			return "", errors.Wrap(err, "decoding replacer result")
+		}
+
+		diff.WriteString(result.Diff)
+		if !strings.HasSuffix(result.Diff, "\n") {
+			diff.WriteString("\n")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", errors.Wrap(err, "reading replacer results")
+	}
+
+	return diff.String(), nil
+}
@@ -0,0 +1,76 @@
+package campaigns
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
+)
+
+func TestReplacerClient(t *testing.T) {
+	spec := &campaigns.PatchSetSpec{
+		MatchTemplate:   "foo",
+		RewriteTemplate: "bar",
+		FileExtension:   "go",
+	}
+
+	var query map[string][]string
+	responses := map[string]string{
+		"github.com/a/b": `{"uri":"a.go","diff":"--- a.go\n+++ a.go\n@@ -1 +1 @@\n-foo\n+bar"}
+{"uri":"b.go","diff":"--- b.go\n+++ b.go\n@@ -1 +1 @@\n-foo\n+bar\n"}
+`,
+		"github.com/a/unchanged": "",
+		"github.com/a/invalid":   "not json\n",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		body, ok := responses[r.URL.Query().Get("repo")]
+		if !ok {
+			http.Error(w, "repo not found", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	c := NewReplacerClient(srv.URL, http.DefaultClient)
+	ctx := context.Background()
+
+	diff, err := c.Replace(ctx, "github.com/a/b", "deadbeef", spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--- a.go\n+++ a.go\n@@ -1 +1 @@\n-foo\n+bar\n--- b.go\n+++ b.go\n@@ -1 +1 @@\n-foo\n+bar\n"
+	if diff != want {
+		t.Errorf("unexpected diff:\n%s", cmp.Diff(want, diff))
+	}
+
+	wantQuery := map[string][]string{
+		"repo":             {"github.com/a/b"},
+		"commit":           {"deadbeef"},
+		"matchtemplate":    {"foo"},
+		"rewritetemplate":  {"bar"},
+		"fileextension":    {"go"},
+		"directoryexclude": {""},
+		"isregexp":         {"false"},
+		"isliteral":        {"false"},
+		"iscasesensitive":  {"false"},
+	}
+	if d := cmp.Diff(wantQuery, query); d != "" {
+		t.Errorf("unexpected query: %s", d)
+	}
+
+	if diff, err := c.Replace(ctx, "github.com/a/unchanged", "deadbeef", spec); err != nil || diff != "" {
+		t.Errorf("got diff %q (err: %v), want empty diff", diff, err)
+	}
+
+	for _, repo := range []string{"github.com/a/invalid", "github.com/a/missing"} {
+		if _, err := c.Replace(ctx, api.RepoName(repo), "deadbeef", spec); err == nil {
+			t.Errorf("%s: expected error", repo)
+		}
+	}
+}
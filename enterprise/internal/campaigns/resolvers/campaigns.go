@@ -139,7 +139,57 @@ func (r *campaignResolver) ClosedAt() *graphqlbackend.DateTime {
 	return &graphqlbackend.DateTime{Time: r.Campaign.ClosedAt}
 }
 
+func (r *campaignResolver) AutoMerge() bool {
+	return r.Campaign.AutoMerge
+}
+
+func (r *campaignResolver) MergeMethod() campaigns.ChangesetMergeMethod {
+	if r.Campaign.MergeMethod == "" {
+		return campaigns.ChangesetMergeMethodMerge
+	}
+	return r.Campaign.MergeMethod
+}
+
+func (r *campaignResolver) CommitMessage() *string {
+	if r.Campaign.CommitMessage == "" {
+		return nil
+	}
+	return &r.Campaign.CommitMessage
+}
+
+func (r *campaignResolver) CommitAuthorName() *string {
+	if r.Campaign.CommitAuthorName == "" {
+		return nil
+	}
+	return &r.Campaign.CommitAuthorName
+}
+
+func (r *campaignResolver) CommitAuthorEmail() *string {
+	if r.Campaign.CommitAuthorEmail == "" {
+		return nil
+	}
+	return &r.Campaign.CommitAuthorEmail
+}
+
+func (r *campaignResolver) CommitSignOff() bool {
+	return r.Campaign.CommitSignOff
+}
+
+func (r *campaignResolver) CommitSign() bool {
+	return r.Campaign.CommitSign
+}
+
+func (r *campaignResolver) DraftOnCodeHost() bool {
+	return r.Campaign.DraftOnCodeHost
+}
+
 func (r *campaignResolver) PublishedAt(ctx context.Context) (*graphqlbackend.DateTime, error) {
+	// The changesets of a draft are created on the code host, but the
+	// Campaign isn't published until they're marked as ready for review.
+	if r.Campaign.DraftOnCodeHost {
+		return nil, nil
+	}
+
 	if r.Campaign.PatchSetID == 0 {
 		return &graphqlbackend.DateTime{Time: r.Campaign.CreatedAt}, nil
 	}
@@ -188,6 +238,19 @@ func (r *campaignResolver) Patches(
 	}
 }
 
+func (r *campaignResolver) ChangesetActions(
+	ctx context.Context,
+	args *graphqlutil.ConnectionArgs,
+) graphqlbackend.ChangesetActionConnectionResolver {
+	return &changesetActionsConnectionResolver{
+		store: r.store,
+		opts: ee.ListChangesetActionJobsOpts{
+			CampaignID: r.Campaign.ID,
+			Limit:      int(args.GetFirst()),
+		},
+	}
+}
+
 func (r *campaignResolver) ChangesetCountsOverTime(
 	ctx context.Context,
 	args *graphqlbackend.ChangesetCountsArgs,
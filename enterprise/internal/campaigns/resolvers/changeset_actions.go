@@ -0,0 +1,161 @@
+package resolvers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
+	ee "github.com/sourcegraph/sourcegraph/enterprise/internal/campaigns"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+)
+
+type changesetActionsConnectionResolver struct {
+	store *ee.Store
+	opts  ee.ListChangesetActionJobsOpts
+
+	// cache results because they are used by multiple fields
+	once sync.Once
+	jobs []*campaigns.ChangesetActionJob
+	next int64
+	err  error
+}
+
+func (r *changesetActionsConnectionResolver) Nodes(ctx context.Context) ([]graphqlbackend.ChangesetActionResolver, error) {
+	jobs, _, err := r.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]graphqlbackend.ChangesetActionResolver, 0, len(jobs))
+	for _, j := range jobs {
+		resolvers = append(resolvers, &changesetActionResolver{store: r.store, job: j})
+	}
+	return resolvers, nil
+}
+
+func (r *changesetActionsConnectionResolver) TotalCount(ctx context.Context) (int32, error) {
+	opts := ee.CountChangesetActionJobsOpts{CampaignID: r.opts.CampaignID}
+	count, err := r.store.CountChangesetActionJobs(ctx, opts)
+	return int32(count), err
+}
+
+func (r *changesetActionsConnectionResolver) PageInfo(ctx context.Context) (*graphqlutil.PageInfo, error) {
+	_, next, err := r.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return graphqlutil.HasNextPage(next != 0), nil
+}
+
+func (r *changesetActionsConnectionResolver) compute(ctx context.Context) ([]*campaigns.ChangesetActionJob, int64, error) {
+	r.once.Do(func() {
+		r.jobs, r.next, r.err = r.store.ListChangesetActionJobs(ctx, r.opts)
+	})
+	return r.jobs, r.next, r.err
+}
+
+type changesetActionResolver struct {
+	store *ee.Store
+	job   *campaigns.ChangesetActionJob
+}
+
+const changesetActionIDKind = "ChangesetAction"
+
+func marshalChangesetActionID(id int64) graphql.ID {
+	return relay.MarshalID(changesetActionIDKind, id)
+}
+
+func (r *changesetActionResolver) ID() graphql.ID {
+	return marshalChangesetActionID(r.job.ID)
+}
+
+func (r *changesetActionResolver) Kind() campaigns.ChangesetActionKind {
+	return r.job.Kind
+}
+
+func (r *changesetActionResolver) Campaign(ctx context.Context) (graphqlbackend.CampaignResolver, error) {
+	campaign, err := r.store.GetCampaign(ctx, ee.GetCampaignOpts{ID: r.job.CampaignID})
+	if err != nil {
+		return nil, err
+	}
+	return &campaignResolver{store: r.store, Campaign: campaign}, nil
+}
+
+func (r *changesetActionResolver) Changeset(ctx context.Context) (graphqlbackend.ExternalChangesetResolver, error) {
+	changeset, err := r.store.GetChangeset(ctx, ee.GetChangesetOpts{ID: r.job.ChangesetID})
+	if err != nil {
+		return nil, err
+	}
+	return &changesetResolver{store: r.store, Changeset: changeset}, nil
+}
+
+func (r *changesetActionResolver) RequestedBy(ctx context.Context) (*graphqlbackend.UserResolver, error) {
+	if r.job.UserID == 0 {
+		return nil, nil
+	}
+	user, err := graphqlbackend.UserByIDInt32(ctx, r.job.UserID)
+	if errcode.IsNotFound(err) {
+		return nil, nil
+	}
+	return user, err
+}
+
+func (r *changesetActionResolver) Body() *string {
+	if r.job.Payload.Body == "" {
+		return nil
+	}
+	return &r.job.Payload.Body
+}
+
+func (r *changesetActionResolver) AddLabels() []string {
+	return nonNilStrings(r.job.Payload.AddLabels)
+}
+
+func (r *changesetActionResolver) RemoveLabels() []string {
+	return nonNilStrings(r.job.Payload.RemoveLabels)
+}
+
+func (r *changesetActionResolver) Reviewers() []string {
+	return nonNilStrings(r.job.Payload.Reviewers)
+}
+
+func (r *changesetActionResolver) State() campaigns.BackgroundProcessState {
+	switch {
+	case r.job.FinishedAt.IsZero():
+		return campaigns.BackgroundProcessStateProcessing
+	case r.job.Error != "":
+		return campaigns.BackgroundProcessStateErrored
+	default:
+		return campaigns.BackgroundProcessStateCompleted
+	}
+}
+
+func (r *changesetActionResolver) Error() *string {
+	if r.job.Error == "" {
+		return nil
+	}
+	return &r.job.Error
+}
+
+func (r *changesetActionResolver) CreatedAt() graphqlbackend.DateTime {
+	return graphqlbackend.DateTime{Time: r.job.CreatedAt}
+}
+
+func (r *changesetActionResolver) FinishedAt() *graphqlbackend.DateTime {
+	if r.job.FinishedAt.IsZero() {
+		return nil
+	}
+	return &graphqlbackend.DateTime{Time: r.job.FinishedAt}
+}
+
+// nonNilStrings returns ss, or an empty slice if ss is nil, so that it's
+// serialized as an empty list.
+func nonNilStrings(ss []string) []string {
+	if ss == nil {
+		return []string{}
+	}
+	return ss
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/campaigns"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
 )
 
@@ -230,9 +231,15 @@ func (r *changesetResolver) CheckState(ctx context.Context) (*campaigns.Changese
 	return &state, nil
 }
 
+func (r *changesetResolver) Conflicted() bool {
+	return r.Changeset.Conflicted
+}
+
 func (r *changesetResolver) Labels(ctx context.Context) ([]graphqlbackend.ChangesetLabelResolver, error) {
-	// Only GitHub supports labels on pull requests so don't make a DB call unless we need to
-	if _, ok := r.Changeset.Metadata.(*github.PullRequest); !ok {
+	// Only GitHub and GitLab support labels on pull requests so don't make a DB call unless we need to
+	switch r.Changeset.Metadata.(type) {
+	case *github.PullRequest, *gitlab.MergeRequest:
+	default:
 		return []graphqlbackend.ChangesetLabelResolver{}, nil
 	}
 	es, err := r.computeEvents(ctx)
@@ -24,6 +24,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
 	"github.com/sourcegraph/sourcegraph/internal/repoupdater"
 	"github.com/sourcegraph/sourcegraph/internal/trace"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
 )
 
 // Resolver is the GraphQL resolver of all things related to Campaigns.
@@ -230,6 +231,34 @@ func (r *Resolver) CreateCampaign(ctx context.Context, args *graphqlbackend.Crea
 		campaign.Branch = *args.Input.Branch
 	}
 
+	if args.Input.AutoMerge != nil {
+		campaign.AutoMerge = *args.Input.AutoMerge
+	}
+
+	if args.Input.MergeMethod != nil {
+		campaign.MergeMethod = *args.Input.MergeMethod
+	}
+
+	if args.Input.CommitMessage != nil {
+		campaign.CommitMessage = *args.Input.CommitMessage
+	}
+
+	if args.Input.CommitAuthorName != nil {
+		campaign.CommitAuthorName = *args.Input.CommitAuthorName
+	}
+
+	if args.Input.CommitAuthorEmail != nil {
+		campaign.CommitAuthorEmail = *args.Input.CommitAuthorEmail
+	}
+
+	if args.Input.CommitSignOff != nil {
+		campaign.CommitSignOff = *args.Input.CommitSignOff
+	}
+
+	if args.Input.CommitSign != nil {
+		campaign.CommitSign = *args.Input.CommitSign
+	}
+
 	if args.Input.PatchSet != nil {
 		patchSetID, err := unmarshalPatchSetID(*args.Input.PatchSet)
 		if err != nil {
@@ -243,6 +272,10 @@ func (r *Resolver) CreateCampaign(ctx context.Context, args *graphqlbackend.Crea
 		draft = *args.Input.Draft
 	}
 
+	if args.Input.DraftOnCodeHost != nil {
+		campaign.DraftOnCodeHost = *args.Input.DraftOnCodeHost
+	}
+
 	switch relay.UnmarshalKind(args.Input.Namespace) {
 	case "User":
 		err = relay.UnmarshalSpec(args.Input.Namespace, &campaign.NamespaceUserID)
@@ -286,6 +319,13 @@ func (r *Resolver) UpdateCampaign(ctx context.Context, args *graphqlbackend.Upda
 	updateArgs.Name = args.Input.Name
 	updateArgs.Description = args.Input.Description
 	updateArgs.Branch = args.Input.Branch
+	updateArgs.AutoMerge = args.Input.AutoMerge
+	updateArgs.MergeMethod = args.Input.MergeMethod
+	updateArgs.CommitMessage = args.Input.CommitMessage
+	updateArgs.CommitAuthorName = args.Input.CommitAuthorName
+	updateArgs.CommitAuthorEmail = args.Input.CommitAuthorEmail
+	updateArgs.CommitSignOff = args.Input.CommitSignOff
+	updateArgs.CommitSign = args.Input.CommitSign
 
 	if args.Input.PatchSet != nil {
 		patchSetID, err := unmarshalPatchSetID(*args.Input.PatchSet)
@@ -587,6 +627,144 @@ func (r *Resolver) CreatePatchSetFromPatches(ctx context.Context, args graphqlba
 	return &patchSetResolver{store: r.store, patchSet: patchSet}, nil
 }
 
+func (r *Resolver) CreatePatchSetFromSpec(ctx context.Context, args *graphqlbackend.CreatePatchSetFromSpecArgs) (graphqlbackend.PatchSetResolver, error) {
+	var err error
+	tr, ctx := trace.New(ctx, "Resolver.CreatePatchSetFromSpec", fmt.Sprintf("Query: %q", args.Input.Query))
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	// 🚨 SECURITY: Only site admins may create patch sets for now.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	user, err := backend.CurrentUser(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%v", backend.ErrNotAuthenticated)
+	}
+	if user == nil {
+		return nil, backend.ErrNotAuthenticated
+	}
+
+	spec := &campaigns.PatchSetSpec{
+		Query:           args.Input.Query,
+		MatchTemplate:   args.Input.MatchTemplate,
+		RewriteTemplate: args.Input.RewriteTemplate,
+		IsRegExp:        args.Input.IsRegExp,
+		IsLiteral:       args.Input.IsLiteral,
+		IsCaseSensitive: args.Input.IsCaseSensitive,
+	}
+	if args.Input.FileExtension != nil {
+		spec.FileExtension = *args.Input.FileExtension
+	}
+	if args.Input.DirectoryExclude != nil {
+		spec.DirectoryExclude = *args.Input.DirectoryExclude
+	}
+
+	jobs, err := patchJobsFromSearch(ctx, spec.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	svc := ee.NewService(r.store, gitserver.DefaultClient, r.httpFactory)
+	patchSet, err := svc.CreatePatchSetFromSpec(ctx, spec, jobs, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &patchSetResolver{store: r.store, patchSet: patchSet}, nil
+}
+
+// patchJobsFromSearch returns a PatchJob for every repository and revision
+// in the results of the given search query.
+func patchJobsFromSearch(ctx context.Context, query string) ([]*campaigns.PatchJob, error) {
+	search, err := graphqlbackend.NewSearchImplementer(&graphqlbackend.SearchArgs{
+		Version: "V2",
+		Query:   query,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := search.Results(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if alert := results.Alert(); alert != nil && len(results.Results()) == 0 {
+		return nil, errors.Errorf("search query: %s", alert.Title())
+	}
+	if results.LimitHit() {
+		return nil, errors.New("search query has more results than the result limit, use count: to raise the limit so that no repository is missed")
+	}
+
+	type repoRev struct {
+		repo api.RepoID
+		rev  api.CommitID
+	}
+	var (
+		jobs  []*campaigns.PatchJob
+		seen  = map[repoRev]bool{}
+		heads = map[api.RepoID]*graphqlbackend.GitRefResolver{}
+	)
+	defaultBranch := func(repo *graphqlbackend.RepositoryResolver) (*graphqlbackend.GitRefResolver, error) {
+		id := repo.Type().ID
+		if head, ok := heads[id]; ok {
+			return head, nil
+		}
+		head, err := repo.DefaultBranch(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting default branch of %s", repo.Name())
+		}
+		heads[id] = head
+		return head, nil
+	}
+	add := func(repo api.RepoID, rev api.CommitID, baseRef string) {
+		if seen[repoRev{repo, rev}] {
+			return
+		}
+		seen[repoRev{repo, rev}] = true
+		jobs = append(jobs, &campaigns.PatchJob{RepoID: repo, Rev: rev, BaseRef: baseRef})
+	}
+
+	for _, res := range results.Results() {
+		if fm, ok := res.ToFileMatch(); ok {
+			if fm.InputRev != nil && *fm.InputRev != "" && *fm.InputRev != "HEAD" {
+				add(fm.Repo.ID, fm.CommitID, git.EnsureRefPrefix(*fm.InputRev))
+				continue
+			}
+
+			head, err := defaultBranch(graphqlbackend.NewRepositoryResolver(fm.Repo))
+			if err != nil {
+				return nil, err
+			}
+			if head != nil {
+				add(fm.Repo.ID, fm.CommitID, head.Name())
+			}
+			continue
+		}
+
+		if repo, ok := res.ToRepository(); ok {
+			head, err := defaultBranch(repo)
+			if err != nil {
+				return nil, err
+			}
+			if head == nil {
+				// The repository is empty.
+				continue
+			}
+			oid, err := head.Target().OID(ctx)
+			if err != nil {
+				return nil, errors.Wrapf(err, "resolving default branch of %s", repo.Name())
+			}
+			add(repo.Type().ID, api.CommitID(oid), head.Name())
+		}
+	}
+
+	return jobs, nil
+}
+
 func (r *Resolver) CloseCampaign(ctx context.Context, args *graphqlbackend.CloseCampaignArgs) (_ graphqlbackend.CampaignResolver, err error) {
 	tr, ctx := trace.New(ctx, "Resolver.CloseCampaign", fmt.Sprintf("Campaign: %q", args.Campaign))
 	defer func() {
@@ -695,6 +873,106 @@ func (r *Resolver) SyncChangeset(ctx context.Context, args *graphqlbackend.SyncC
 	return &graphqlbackend.EmptyResponse{}, nil
 }
 
+func (r *Resolver) CommentOnCampaignChangesets(ctx context.Context, args *graphqlbackend.CommentOnCampaignChangesetsArgs) ([]graphqlbackend.ChangesetActionResolver, error) {
+	return r.enqueueChangesetActions(ctx, "Resolver.CommentOnCampaignChangesets", args.Campaign, args.Filter, campaigns.ChangesetActionKindComment, campaigns.ChangesetActionPayload{
+		Body: args.Body,
+	})
+}
+
+func (r *Resolver) LabelCampaignChangesets(ctx context.Context, args *graphqlbackend.LabelCampaignChangesetsArgs) ([]graphqlbackend.ChangesetActionResolver, error) {
+	var payload campaigns.ChangesetActionPayload
+	if args.AddLabels != nil {
+		payload.AddLabels = *args.AddLabels
+	}
+	if args.RemoveLabels != nil {
+		payload.RemoveLabels = *args.RemoveLabels
+	}
+	return r.enqueueChangesetActions(ctx, "Resolver.LabelCampaignChangesets", args.Campaign, args.Filter, campaigns.ChangesetActionKindLabel, payload)
+}
+
+func (r *Resolver) RequestCampaignChangesetReviewers(ctx context.Context, args *graphqlbackend.RequestCampaignChangesetReviewersArgs) ([]graphqlbackend.ChangesetActionResolver, error) {
+	return r.enqueueChangesetActions(ctx, "Resolver.RequestCampaignChangesetReviewers", args.Campaign, args.Filter, campaigns.ChangesetActionKindRequestReviewers, campaigns.ChangesetActionPayload{
+		Reviewers: args.Reviewers,
+	})
+}
+
+func (r *Resolver) enqueueChangesetActions(
+	ctx context.Context,
+	traceName string,
+	campaign graphql.ID,
+	filter *graphqlbackend.CampaignChangesetsFilter,
+	kind campaigns.ChangesetActionKind,
+	payload campaigns.ChangesetActionPayload,
+) (_ []graphqlbackend.ChangesetActionResolver, err error) {
+	tr, ctx := trace.New(ctx, traceName, fmt.Sprintf("Campaign: %q", campaign))
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	// 🚨 SECURITY: Only site admins may update campaigns for now
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, errors.Wrap(err, "checking if user is admin")
+	}
+
+	user, err := backend.CurrentUser(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting current user")
+	}
+
+	campaignID, err := unmarshalCampaignID(campaign)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshaling campaign id")
+	}
+
+	actionArgs := ee.ChangesetActionArgs{
+		CampaignID: campaignID,
+		UserID:     user.ID,
+		Kind:       kind,
+		Payload:    payload,
+	}
+
+	if filter != nil {
+		if filter.Changesets != nil {
+			if len(*filter.Changesets) == 0 {
+				return nil, ee.ErrChangesetActionNoChangesets
+			}
+			for _, id := range *filter.Changesets {
+				changesetID, err := unmarshalChangesetID(id)
+				if err != nil {
+					return nil, errors.Wrap(err, "unmarshaling changeset id")
+				}
+				actionArgs.ChangesetIDs = append(actionArgs.ChangesetIDs, changesetID)
+			}
+		}
+
+		if filter.State != nil && !filter.State.Valid() {
+			return nil, errors.Errorf("changeset state not valid: %q", *filter.State)
+		}
+		if filter.ReviewState != nil && !filter.ReviewState.Valid() {
+			return nil, errors.Errorf("changeset review state not valid: %q", *filter.ReviewState)
+		}
+		if filter.CheckState != nil && !filter.CheckState.Valid() {
+			return nil, errors.Errorf("changeset check state not valid: %q", *filter.CheckState)
+		}
+		actionArgs.ExternalState = filter.State
+		actionArgs.ExternalReviewState = filter.ReviewState
+		actionArgs.ExternalCheckState = filter.CheckState
+	}
+
+	svc := ee.NewService(r.store, gitserver.DefaultClient, r.httpFactory)
+	jobs, err := svc.EnqueueChangesetActions(ctx, actionArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]graphqlbackend.ChangesetActionResolver, 0, len(jobs))
+	for _, j := range jobs {
+		resolvers = append(resolvers, &changesetActionResolver{store: r.store, job: j})
+	}
+	return resolvers, nil
+}
+
 func parseCampaignState(s *string) (campaigns.CampaignState, error) {
 	if s == nil {
 		return campaigns.CampaignStateAny, nil
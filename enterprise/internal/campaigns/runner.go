@@ -9,6 +9,8 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/internal/campaigns"
 	"github.com/sourcegraph/sourcegraph/internal/env"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/ratelimit"
 )
 
 // maxWorkers defines the maximum number of repository jobs to run in parallel.
@@ -20,11 +22,6 @@ const defaultWorkerCount = 8
 // for finding pending jobs and running them.
 // ctx should be canceled to terminate the function
 func RunChangesetJobs(ctx context.Context, s *Store, clock func() time.Time, gitClient GitserverClient, backoffDuration time.Duration) {
-	workerCount, err := strconv.Atoi(maxWorkers)
-	if err != nil {
-		log15.Error("Parsing max worker count failed. Falling back to default.", "default", defaultWorkerCount, "err", err)
-		workerCount = defaultWorkerCount
-	}
 	process := func(ctx context.Context, s *Store, job campaigns.ChangesetJob) error {
 		c, err := s.GetCampaign(ctx, GetCampaignOpts{
 			ID: job.CampaignID,
@@ -32,22 +29,74 @@ func RunChangesetJobs(ctx context.Context, s *Store, clock func() time.Time, git
 		if err != nil {
 			return errors.Wrap(err, "getting campaign")
 		}
+		// Publishing changesets draws from the same code host rate limit
+		// budget as syncing, but takes precedence over it.
+		ctx = ratelimit.WithPriority(ctx, ratelimit.PriorityPublish)
 		if runErr := RunChangesetJob(ctx, clock, s, gitClient, nil, c, &job); runErr != nil {
-			log15.Error("RunChangesetJob", "jobID", job.ID, "err", err)
+			log15.Error("RunChangesetJob", "jobID", job.ID, "err", runErr)
 		}
 		// We don't assign to err here so that we don't roll back the transaction
 		// RunChangesetJob will save the error in the job row
 		return nil
 	}
+	runWorkers(ctx, "changeset job", backoffDuration, func(ctx context.Context) (bool, error) {
+		return s.ProcessPendingChangesetJobs(ctx, process)
+	})
+}
+
+// RunPatchJobs should run in a background goroutine and is responsible for
+// finding pending patch jobs and running them.
+// ctx should be canceled to terminate the function
+func RunPatchJobs(ctx context.Context, s *Store, clock func() time.Time, replacer ReplacerClient, backoffDuration time.Duration) {
+	process := func(ctx context.Context, s *Store, job campaigns.PatchJob) error {
+		// Errors returned by the replacer are saved in the job row by
+		// RunPatchJob. Other errors roll back the transaction, so that the
+		// job is retried.
+		return RunPatchJob(ctx, clock, s, replacer, &job)
+	}
+	runWorkers(ctx, "patch job", backoffDuration, func(ctx context.Context) (bool, error) {
+		return s.ProcessPendingPatchJobs(ctx, process)
+	})
+}
+
+// RunChangesetActionJobs should run in a background goroutine and is
+// responsible for finding pending changeset action jobs and running them.
+// ctx should be canceled to terminate the function
+func RunChangesetActionJobs(ctx context.Context, s *Store, clock func() time.Time, cf *httpcli.Factory, backoffDuration time.Duration) {
+	process := func(ctx context.Context, s *Store, job campaigns.ChangesetActionJob) error {
+		// Bulk actions are requested by users, but they can touch hundreds
+		// of changesets, so they yield to interactive requests like
+		// publishing does.
+		ctx = ratelimit.WithPriority(ctx, ratelimit.PriorityPublish)
+		// Errors are saved in the job row by RunChangesetActionJob. The job
+		// isn't retried, since its action might have been performed on the
+		// codehost already.
+		return RunChangesetActionJob(ctx, clock, s, cf, &job)
+	}
+	runWorkers(ctx, "changeset action job", backoffDuration, func(ctx context.Context) (bool, error) {
+		return s.ProcessPendingChangesetActionJobs(ctx, process)
+	})
+}
+
+// runWorkers starts maxWorkers goroutines which call processPending until
+// ctx is canceled. processPending runs a single pending job, if any, and
+// reports whether it did. Workers back off for backoffDuration on error or
+// when no job was pending. kind names the jobs in log messages.
+func runWorkers(ctx context.Context, kind string, backoffDuration time.Duration, processPending func(context.Context) (bool, error)) {
+	workerCount, err := strconv.Atoi(maxWorkers)
+	if err != nil {
+		log15.Error("Parsing max worker count failed. Falling back to default.", "default", defaultWorkerCount, "err", err)
+		workerCount = defaultWorkerCount
+	}
 	worker := func() {
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			default:
-				didRun, err := s.ProcessPendingChangesetJobs(context.Background(), process)
+				didRun, err := processPending(context.Background())
 				if err != nil {
-					log15.Error("Running changeset job", "err", err)
+					log15.Error("Running "+kind, "err", err)
 				}
 				// Back off on error or when no jobs available
 				if err != nil || !didRun {
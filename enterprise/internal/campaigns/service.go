@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net/mail"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/hashicorp/go-multierror"
@@ -11,9 +14,11 @@ import (
 	"github.com/opentracing/opentracing-go/log"
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/repo-updater/repos"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/campaigns"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
 	"github.com/sourcegraph/sourcegraph/internal/trace"
@@ -55,19 +60,14 @@ func (s *Service) CreatePatchSetFromPatches(ctx context.Context, patches []*camp
 		return nil, backend.ErrNotAuthenticated
 	}
 	// Look up all repositories
-	reposStore := repos.NewDBStore(s.store.DB(), sql.TxOptions{})
 	repoIDs := make([]api.RepoID, len(patches))
 	for i, patch := range patches {
 		repoIDs[i] = api.RepoID(patch.RepoID)
 	}
-	allRepos, err := reposStore.ListRepos(ctx, repos.StoreListReposArgs{IDs: repoIDs})
+	reposByID, err := s.reposByID(ctx, repoIDs)
 	if err != nil {
 		return nil, err
 	}
-	reposByID := make(map[api.RepoID]*repos.Repo, len(patches))
-	for _, repo := range allRepos {
-		reposByID[repo.ID] = repo
-	}
 
 	tx, err := s.store.Transact(ctx)
 	if err != nil {
@@ -99,6 +99,141 @@ func (s *Service) CreatePatchSetFromPatches(ctx context.Context, patches []*camp
 	return patchSet, nil
 }
 
+// ErrPatchSetSpecMatchTemplateBlank is returned by CreatePatchSetFromSpec if
+// the match template of the PatchSetSpec is blank.
+var ErrPatchSetSpecMatchTemplateBlank = errors.New("match template cannot be blank")
+
+// ErrPatchSetSpecNoRepos is returned by CreatePatchSetFromSpec if none of the
+// given repositories is supported by campaigns.
+var ErrPatchSetSpecNoRepos = errors.New("search query does not match any repository supported by campaigns")
+
+// CreatePatchSetFromSpec creates a PatchSet whose Patches are computed in the
+// background from the given spec: for every given PatchJob it creates a
+// PatchJob in the new PatchSet, which is picked up by RunPatchJobs. The
+// PatchJobs are expected to have their RepoID, Rev and BaseRef set, usually
+// from the results of the search query of the spec.
+func (s *Service) CreatePatchSetFromSpec(ctx context.Context, spec *campaigns.PatchSetSpec, jobs []*campaigns.PatchJob, userID int32) (*campaigns.PatchSet, error) {
+	if userID == 0 {
+		return nil, backend.ErrNotAuthenticated
+	}
+	if spec.MatchTemplate == "" {
+		return nil, ErrPatchSetSpecMatchTemplateBlank
+	}
+
+	repoIDs := make([]api.RepoID, len(jobs))
+	for i, job := range jobs {
+		repoIDs[i] = job.RepoID
+	}
+	reposByID, err := s.reposByID(ctx, repoIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.store.Transact(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Done(&err)
+
+	patchSet := &campaigns.PatchSet{UserID: userID, Spec: spec}
+	err = tx.CreatePatchSet(ctx, patchSet)
+	if err != nil {
+		return nil, err
+	}
+
+	created := 0
+	for _, job := range jobs {
+		repo := reposByID[job.RepoID]
+		if repo == nil {
+			err = fmt.Errorf("repository ID %d not found", job.RepoID)
+			return nil, err
+		}
+		if !campaigns.IsRepoSupported(&repo.ExternalRepo) {
+			continue
+		}
+
+		job.PatchSetID = patchSet.ID
+		if err = tx.CreatePatchJob(ctx, job); err != nil {
+			return nil, err
+		}
+		created++
+	}
+
+	if created == 0 {
+		err = ErrPatchSetSpecNoRepos
+		return nil, err
+	}
+
+	return patchSet, nil
+}
+
+// RunPatchJob runs the given PatchJob by calling the replacer with the spec
+// of its PatchSet and storing the resulting diff as a Patch. Errors returned
+// by the replacer are saved in the job, so that they can be reported in the
+// status of the PatchSet. Repositories in which nothing was rewritten don't
+// result in a Patch.
+func RunPatchJob(
+	ctx context.Context,
+	clock func() time.Time,
+	store *Store,
+	replacer ReplacerClient,
+	job *campaigns.PatchJob,
+) (err error) {
+	tr, ctx := trace.New(ctx, "service.RunPatchJob", fmt.Sprintf("job_id: %d", job.ID))
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+	tr.LogFields(log.Int64("job_id", job.ID), log.Int64("patch_set_id", job.PatchSetID))
+
+	if !job.FinishedAt.IsZero() {
+		log15.Info("PatchJob already finished", "id", job.ID)
+		return nil
+	}
+
+	patchSet, err := store.GetPatchSet(ctx, GetPatchSetOpts{ID: job.PatchSetID})
+	if err != nil {
+		return errors.Wrap(err, "getting patch set")
+	}
+	if patchSet.Spec == nil {
+		return errors.Errorf("patch set %d has no spec", patchSet.ID)
+	}
+
+	reposStore := repos.NewDBStore(store.DB(), sql.TxOptions{})
+	rs, err := reposStore.ListRepos(ctx, repos.StoreListReposArgs{IDs: []api.RepoID{job.RepoID}})
+	if err != nil {
+		return errors.Wrap(err, "listing repos")
+	}
+
+	var diff string
+	switch {
+	case len(rs) != 1:
+		job.Error = fmt.Sprintf("repository ID %d not found", job.RepoID)
+	default:
+		diff, err = replacer.Replace(ctx, api.RepoName(rs[0].Name), job.Rev, patchSet.Spec)
+		if err != nil {
+			job.Error = fmt.Sprintf("%s: %s", rs[0].Name, err)
+			err = nil
+		}
+	}
+
+	if job.Error == "" && diff != "" {
+		err = store.CreatePatch(ctx, &campaigns.Patch{
+			PatchSetID: job.PatchSetID,
+			RepoID:     job.RepoID,
+			Rev:        job.Rev,
+			BaseRef:    job.BaseRef,
+			Diff:       diff,
+		})
+		if err != nil {
+			return errors.Wrap(err, "creating patch")
+		}
+	}
+
+	job.FinishedAt = clock()
+	return store.UpdatePatchJob(ctx, job)
+}
+
 // CreateCampaign creates the Campaign. When a PatchSetID is set on the
 // Campaign and the Campaign is not created as a draft, it calls
 // CreateChangesetJobs inside the same transaction in which it creates the
@@ -115,6 +250,18 @@ func (s *Service) CreateCampaign(ctx context.Context, c *campaigns.Campaign, dra
 		return ErrCampaignNameBlank
 	}
 
+	if c.MergeMethod != "" && !c.MergeMethod.Valid() {
+		return ErrCampaignMergeMethodInvalid
+	}
+
+	if err := validateCommitConfig(c); err != nil {
+		return err
+	}
+
+	if c.DraftOnCodeHost && !draft {
+		return ErrCampaignDraftOnCodeHostPublished
+	}
+
 	tx, err := s.store.Transact(ctx)
 	if err != nil {
 		return err
@@ -130,6 +277,14 @@ func (s *Service) CreateCampaign(ctx context.Context, c *campaigns.Campaign, dra
 			err = ErrPatchSetDuplicate
 			return err
 		}
+
+		processing, err := patchSetIsProcessing(ctx, tx, c.PatchSetID)
+		if err != nil {
+			return err
+		}
+		if processing {
+			return ErrPatchSetProcessing
+		}
 	}
 
 	c.CreatedAt = s.clock()
@@ -144,7 +299,9 @@ func (s *Service) CreateCampaign(ctx context.Context, c *campaigns.Campaign, dra
 		return err
 	}
 
-	if c.PatchSetID == 0 || draft {
+	// Campaigns whose changesets are drafts on the code host need the
+	// changesets to be created right away.
+	if c.PatchSetID == 0 || (draft && !c.DraftOnCodeHost) {
 		return nil
 	}
 
@@ -271,28 +428,14 @@ func RunChangesetJob(
 		ensureUniqueRef = false
 	}
 
-	ref, err := gitClient.CreateCommitFromPatch(ctx, protocol.CreateCommitFromPatchRequest{
-		Repo:       api.RepoName(repo.Name),
-		BaseCommit: patch.Rev,
-		// IMPORTANT: We add a trailing newline here, otherwise `git apply`
-		// will fail with "corrupt patch at line <N>" where N is the last line.
-		Patch:     patch.Diff + "\n",
-		TargetRef: branch,
-		UniqueRef: ensureUniqueRef,
-		CommitInfo: protocol.PatchCommitInfo{
-			Message:     c.Name,
-			AuthorName:  "Sourcegraph Bot",
-			AuthorEmail: "campaigns@sourcegraph.com",
-			Date:        job.CreatedAt,
-		},
-		// We use unified diffs, not git diffs, which means they're missing the
-		// `a/` and `/b` filename prefixes. `-p0` tells `git apply` to not
-		// expect and strip prefixes.
-		// Since we also produce diffs manually, we might not have context lines,
-		// so we need to disable that check with `--unidiff-zero`.
-		GitApplyArgs: []string{"-p0", "--unidiff-zero"},
-		Push:         true,
-	})
+	req, err := patchCommitRequest(ctx, api.RepoName(repo.Name), c, patch, patch.Rev, job.CreatedAt)
+	if err != nil {
+		return err
+	}
+	req.TargetRef = branch
+	req.UniqueRef = ensureUniqueRef
+
+	ref, err := gitClient.CreateCommitFromPatch(ctx, req)
 	if err != nil {
 		if diffErr, ok := err.(*protocol.CreateCommitFromPatchError); ok {
 			return errors.Errorf("creating commit from patch for repo %q: %q (command: %q, output: %q)",
@@ -304,6 +447,7 @@ func RunChangesetJob(
 		return fmt.Errorf("ref %q doesn't match ChangesetJob's branch %q", ref, job.Branch)
 	}
 	job.Branch = ref
+	job.BaseCommit = string(patch.Rev)
 
 	var externalService *repos.ExternalService
 	{
@@ -329,6 +473,14 @@ func RunChangesetJob(
 				if cfg.Token != "" {
 					externalService = e
 				}
+			case *schema.GitLabConnection:
+				if cfg.Token != "" {
+					externalService = e
+				}
+			case *schema.BitbucketCloudConnection:
+				if cfg.Username != "" && cfg.AppPassword != "" {
+					externalService = e
+				}
 			}
 			if externalService != nil {
 				break
@@ -345,15 +497,10 @@ func RunChangesetJob(
 		return err
 	}
 
-	baseRef := "refs/heads/master"
-	if patch.BaseRef != "" {
-		baseRef = patch.BaseRef
-	}
-
 	cs := repos.Changeset{
 		Title:   c.Name,
 		Body:    c.Description,
-		BaseRef: baseRef,
+		BaseRef: patchBaseRef(patch),
 		HeadRef: git.EnsureRefPrefix(ref),
 		Repo:    repo,
 		Changeset: &campaigns.Changeset{
@@ -370,7 +517,16 @@ func RunChangesetJob(
 	// TODO: If we're updating the changeset, there's a race condition here.
 	// It's possible that `CreateChangeset` doesn't return the newest head ref
 	// commit yet, because the API of the codehost doesn't return it yet.
-	exists, err := ccs.CreateChangeset(ctx, &cs)
+	var exists bool
+	if c.DraftOnCodeHost {
+		dcs, ok := ccs.(repos.DraftChangesetSource)
+		if !ok {
+			return errors.Errorf("creating draft changesets on code host of repo %q is not supported", repo.Name)
+		}
+		exists, err = dcs.CreateDraftChangeset(ctx, &cs)
+	} else {
+		exists, err = ccs.CreateChangeset(ctx, &cs)
+	}
 	if err != nil {
 		return errors.Wrap(err, "creating changeset")
 	}
@@ -387,6 +543,17 @@ func RunChangesetJob(
 				return errors.Wrap(err, "updating changeset")
 			}
 		}
+
+		// The Changeset was created as a draft if the Campaign was created
+		// with DraftOnCodeHost and has been published since. Closed drafts
+		// can't be marked as ready for review.
+		dcs, ok := ccs.(repos.DraftChangesetSource)
+		state, _ := campaigns.ComputeChangesetState(cs.Changeset, nil)
+		if ok && !c.DraftOnCodeHost && state == campaigns.ChangesetStateOpen {
+			if err := dcs.UndraftChangeset(ctx, &cs); err != nil {
+				return errors.Wrap(err, "undrafting changeset")
+			}
+		}
 	}
 
 	// We keep a clone because CreateChangesets might overwrite the changeset
@@ -408,6 +575,9 @@ func RunChangesetJob(
 		if err := clone.SetMetadata(cs.Changeset.Metadata); err != nil {
 			return errors.Wrap(err, "setting changeset metadata")
 		}
+		// The branch has just been recreated from the patch, so any
+		// conflict from a previous rebase is gone.
+		clone.Conflicted = false
 		events = clone.Events()
 		clone.SetDerivedState(events)
 		if err = store.UpdateChangesets(ctx, clone); err != nil {
@@ -433,6 +603,198 @@ func RunChangesetJob(
 	return
 }
 
+// patchCommitRequest returns the request to create a commit on the given base
+// commit from the given Patch of the Campaign and push it to the code host.
+// The caller is expected to set the TargetRef.
+func patchCommitRequest(ctx context.Context, repo api.RepoName, c *campaigns.Campaign, patch *campaigns.Patch, base api.CommitID, date time.Time) (protocol.CreateCommitFromPatchRequest, error) {
+	info, err := campaignCommitInfo(ctx, c, repo)
+	if err != nil {
+		return protocol.CreateCommitFromPatchRequest{}, err
+	}
+	info.Date = date
+
+	return protocol.CreateCommitFromPatchRequest{
+		Repo:       repo,
+		BaseCommit: base,
+		// IMPORTANT: We add a trailing newline here, otherwise `git apply`
+		// will fail with "corrupt patch at line <N>" where N is the last line.
+		Patch:      patch.Diff + "\n",
+		CommitInfo: info,
+		// We use unified diffs, not git diffs, which means they're missing the
+		// `a/` and `/b` filename prefixes. `-p0` tells `git apply` to not
+		// expect and strip prefixes.
+		// Since we also produce diffs manually, we might not have context lines,
+		// so we need to disable that check with `--unidiff-zero`.
+		GitApplyArgs: []string{"-p0", "--unidiff-zero"},
+		Push:         true,
+	}, nil
+}
+
+// The author of commits created for campaigns whose author has no verified
+// email, unless the campaign overrides it.
+const (
+	campaignsBotName  = "Sourcegraph Bot"
+	campaignsBotEmail = "campaigns@sourcegraph.com"
+)
+
+// campaignCommitInfo returns the message, author and signing options of the
+// commits created for the changesets of the given Campaign in the given
+// repository.
+func campaignCommitInfo(ctx context.Context, c *campaigns.Campaign, repo api.RepoName) (protocol.PatchCommitInfo, error) {
+	message, err := renderCommitMessage(c, repo)
+	if err != nil {
+		return protocol.PatchCommitInfo{}, err
+	}
+
+	info := protocol.PatchCommitInfo{
+		Message:     message,
+		AuthorName:  c.CommitAuthorName,
+		AuthorEmail: c.CommitAuthorEmail,
+		SignOff:     c.CommitSignOff,
+		Sign:        c.CommitSign,
+	}
+
+	if info.AuthorName == "" || info.AuthorEmail == "" {
+		name, email, err := campaignAuthorIdentity(ctx, c.AuthorID)
+		if err != nil {
+			return protocol.PatchCommitInfo{}, err
+		}
+		if info.AuthorName == "" {
+			info.AuthorName = name
+		}
+		if info.AuthorEmail == "" {
+			info.AuthorEmail = email
+		}
+	}
+
+	return info, nil
+}
+
+// campaignAuthorIdentity returns the name and verified primary email of the
+// user with the given ID, falling back to the Sourcegraph bot if the user has
+// no verified email.
+func campaignAuthorIdentity(ctx context.Context, userID int32) (name, email string, err error) {
+	email, verified, err := db.UserEmails.GetPrimaryEmail(ctx, userID)
+	if err != nil && !errcode.IsNotFound(err) {
+		return "", "", errors.Wrap(err, "getting campaign author email")
+	}
+	if err != nil || !verified {
+		return campaignsBotName, campaignsBotEmail, nil
+	}
+
+	user, err := db.Users.GetByID(ctx, userID)
+	if err != nil {
+		return "", "", errors.Wrap(err, "getting campaign author")
+	}
+
+	name = user.DisplayName
+	if name == "" {
+		name = user.Username
+	}
+	return name, email, nil
+}
+
+// commitMessageData is the data the CommitMessage template of a Campaign is
+// executed with.
+type commitMessageData struct {
+	CampaignName        string
+	CampaignDescription string
+	Branch              string
+	Repository          string
+}
+
+// renderCommitMessage executes the CommitMessage template of the given
+// Campaign for a commit in the given repository. It returns the Campaign's
+// name if the Campaign has no template.
+func renderCommitMessage(c *campaigns.Campaign, repo api.RepoName) (string, error) {
+	if c.CommitMessage == "" {
+		return c.Name, nil
+	}
+
+	tmpl, err := parseCommitMessage(c.CommitMessage)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	err = tmpl.Execute(&b, commitMessageData{
+		CampaignName:        c.Name,
+		CampaignDescription: c.Description,
+		Branch:              c.Branch,
+		Repository:          string(repo),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "executing commit message template")
+	}
+
+	message := strings.TrimSpace(b.String())
+	if message == "" {
+		return c.Name, nil
+	}
+	return message, nil
+}
+
+func parseCommitMessage(text string) (*template.Template, error) {
+	return template.New("commit message").Option("missingkey=error").Parse(text)
+}
+
+// patchBaseRef returns the ref the changeset of the given Patch is opened
+// against.
+func patchBaseRef(patch *campaigns.Patch) string {
+	if patch.BaseRef != "" {
+		return patch.BaseRef
+	}
+	return "refs/heads/master"
+}
+
+// applyCommitConfigUpdate sets the commit configuration of the given
+// Campaign from the given UpdateCampaignArgs and reports whether it changed.
+func applyCommitConfigUpdate(c *campaigns.Campaign, args UpdateCampaignArgs) (changed bool) {
+	setString := func(field *string, arg *string) {
+		if arg != nil && *field != *arg {
+			*field = *arg
+			changed = true
+		}
+	}
+	setBool := func(field *bool, arg *bool) {
+		if arg != nil && *field != *arg {
+			*field = *arg
+			changed = true
+		}
+	}
+
+	setString(&c.CommitMessage, args.CommitMessage)
+	setString(&c.CommitAuthorName, args.CommitAuthorName)
+	setString(&c.CommitAuthorEmail, args.CommitAuthorEmail)
+	setBool(&c.CommitSignOff, args.CommitSignOff)
+	setBool(&c.CommitSign, args.CommitSign)
+
+	return changed
+}
+
+// validateCommitConfig returns an error if the commit message template of
+// the given Campaign can't be rendered or its commit author is incomplete.
+// The template is executed for a sample repository, so that references to
+// unknown fields are caught before any commit is created.
+func validateCommitConfig(c *campaigns.Campaign) error {
+	if c.CommitMessage != "" {
+		if _, err := renderCommitMessage(c, "example.com/sample/repository"); err != nil {
+			return ErrCampaignCommitMessageInvalid
+		}
+	}
+
+	if (c.CommitAuthorName == "") != (c.CommitAuthorEmail == "") {
+		return ErrCampaignCommitAuthorIncomplete
+	}
+	if c.CommitAuthorEmail != "" {
+		if _, err := mail.ParseAddress(c.CommitAuthorEmail); err != nil {
+			return ErrCampaignCommitAuthorIncomplete
+		}
+	}
+
+	return nil
+}
+
 // ErrCloseProcessingCampaign is returned by CloseCampaign if the Campaign has
 // been published at the time of closing but its ChangesetJobs have not
 // finished execution.
@@ -506,9 +868,16 @@ func (s *Service) CloseCampaign(ctx context.Context, id int64, closeChangesets b
 	return campaign, nil
 }
 
+// ErrPublishProcessingCampaign is returned by PublishCampaign if the
+// Campaign's changesets are drafts on the codehosts and its ChangesetJobs
+// have not finished execution.
+var ErrPublishProcessingCampaign = errors.New("cannot publish a Campaign while draft changesets are being created on codehosts")
+
 // PublishCampaign publishes the Campaign with the given ID
 // by turning the Patches attached to the PatchSet of
-// the Campaign into ChangesetJobs and enqueuing them
+// the Campaign into ChangesetJobs and enqueuing them.
+// If the Campaign's changesets are drafts on the codehosts, its ChangesetJobs
+// are rerun to mark them as ready for review.
 func (s *Service) PublishCampaign(ctx context.Context, id int64) (campaign *campaigns.Campaign, err error) {
 	traceTitle := fmt.Sprintf("campaign: %d", id)
 	tr, ctx := trace.New(ctx, "service.PublishCampaign", traceTitle)
@@ -527,7 +896,40 @@ func (s *Service) PublishCampaign(ctx context.Context, id int64) (campaign *camp
 	if err != nil {
 		return nil, errors.Wrap(err, "getting campaign")
 	}
-	return campaign, s.createChangesetJobsWithStore(ctx, tx, campaign)
+
+	if !campaign.DraftOnCodeHost {
+		return campaign, s.createChangesetJobsWithStore(ctx, tx, campaign)
+	}
+
+	// ChangesetJobs that are still running might create drafts after
+	// we marked the others as ready for review.
+	processing, err := campaignIsProcessing(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if processing {
+		return nil, ErrPublishProcessingCampaign
+	}
+
+	campaign.DraftOnCodeHost = false
+	if err = tx.UpdateCampaign(ctx, campaign); err != nil {
+		return nil, err
+	}
+
+	// Rerunning the ChangesetJobs marks the drafts they created as ready for
+	// review. The ones that failed on codehosts that don't support drafts
+	// create their changesets now that they don't need to be drafts anymore.
+	if err = tx.ResetChangesetJobs(ctx, id); err != nil {
+		return nil, err
+	}
+
+	// Patches published individually in the meantime, e.g. ones added
+	// by updating the patch set, already have ChangesetJobs.
+	if err = s.createChangesetJobsWithStore(ctx, tx, campaign); err != nil && err != ErrNoPatches {
+		return nil, err
+	}
+
+	return campaign, nil
 }
 
 // ErrDeleteProcessingCampaign is returned by DeleteCampaign if the Campaign
@@ -648,6 +1050,239 @@ func (s *Service) CloseOpenChangesets(ctx context.Context, cs []*campaigns.Chang
 	return syncer.SyncChangesetsWithSources(ctx, bySource)
 }
 
+// ChangesetActionArgs are the arguments of EnqueueChangesetActions.
+type ChangesetActionArgs struct {
+	CampaignID int64
+	// UserID is the ID of the user requesting the action.
+	UserID int32
+
+	Kind    campaigns.ChangesetActionKind
+	Payload campaigns.ChangesetActionPayload
+
+	// ChangesetIDs, ExternalState, ExternalReviewState and
+	// ExternalCheckState filter the changesets of the Campaign the action is
+	// performed on. Unset filters match all changesets.
+	ChangesetIDs        []int64
+	ExternalState       *campaigns.ChangesetState
+	ExternalReviewState *campaigns.ChangesetReviewState
+	ExternalCheckState  *campaigns.ChangesetCheckState
+}
+
+// ErrChangesetActionKindInvalid is returned by EnqueueChangesetActions if the
+// kind of the action is unknown.
+var ErrChangesetActionKindInvalid = errors.New("changeset action kind is invalid")
+
+// ErrChangesetActionPayloadBlank is returned by EnqueueChangesetActions if
+// the action has no comment body, labels or reviewers, depending on its kind.
+var ErrChangesetActionPayloadBlank = errors.New("changeset action needs a comment body, labels or reviewers")
+
+// ErrChangesetActionNoChangesets is returned by EnqueueChangesetActions if no
+// changeset of the Campaign matches the filters of the action.
+var ErrChangesetActionNoChangesets = errors.New("no changesets of the campaign match the filters")
+
+// EnqueueChangesetActions creates a ChangesetActionJob for each changeset of
+// the Campaign that matches the filters in args. The jobs are picked up and
+// performed on the codehosts by RunChangesetActionJobs.
+func (s *Service) EnqueueChangesetActions(ctx context.Context, args ChangesetActionArgs) (jobs []*campaigns.ChangesetActionJob, err error) {
+	traceTitle := fmt.Sprintf("campaign: %d, kind: %s", args.CampaignID, args.Kind)
+	tr, ctx := trace.New(ctx, "service.EnqueueChangesetActions", traceTitle)
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	if err := validateChangesetAction(args.Kind, args.Payload); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.store.Transact(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Done(&err)
+
+	campaign, err := tx.GetCampaign(ctx, GetCampaignOpts{ID: args.CampaignID})
+	if err != nil {
+		return nil, errors.Wrap(err, "getting campaign")
+	}
+
+	opts := ListChangesetsOpts{
+		CampaignID:          campaign.ID,
+		IDs:                 args.ChangesetIDs,
+		WithoutDeleted:      true,
+		ExternalState:       args.ExternalState,
+		ExternalReviewState: args.ExternalReviewState,
+		ExternalCheckState:  args.ExternalCheckState,
+		Limit:               -1,
+	}
+	cs, _, err := tx.ListChangesets(ctx, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing changesets")
+	}
+
+	if len(cs) == 0 {
+		err = ErrChangesetActionNoChangesets
+		return nil, err
+	}
+
+	jobs = make([]*campaigns.ChangesetActionJob, 0, len(cs))
+	for _, c := range cs {
+		job := &campaigns.ChangesetActionJob{
+			CampaignID:  campaign.ID,
+			ChangesetID: c.ID,
+			UserID:      args.UserID,
+			Kind:        args.Kind,
+			Payload:     args.Payload,
+		}
+		if err = tx.CreateChangesetActionJob(ctx, job); err != nil {
+			return nil, errors.Wrap(err, "creating changeset action job")
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+func validateChangesetAction(kind campaigns.ChangesetActionKind, p campaigns.ChangesetActionPayload) error {
+	switch kind {
+	case campaigns.ChangesetActionKindComment:
+		if strings.TrimSpace(p.Body) == "" {
+			return ErrChangesetActionPayloadBlank
+		}
+	case campaigns.ChangesetActionKindLabel:
+		if len(p.AddLabels) == 0 && len(p.RemoveLabels) == 0 {
+			return ErrChangesetActionPayloadBlank
+		}
+	case campaigns.ChangesetActionKindRequestReviewers:
+		if len(p.Reviewers) == 0 {
+			return ErrChangesetActionPayloadBlank
+		}
+	default:
+		return ErrChangesetActionKindInvalid
+	}
+	return nil
+}
+
+// RunChangesetActionJob performs the action of the given ChangesetActionJob
+// on the codehost of its Changeset and syncs the Changeset afterwards. All
+// errors, including those returned by the codehost and actions not being
+// supported by it, are saved in the job, which is finished either way.
+func RunChangesetActionJob(
+	ctx context.Context,
+	clock func() time.Time,
+	store *Store,
+	cf *httpcli.Factory,
+	job *campaigns.ChangesetActionJob,
+) (err error) {
+	tr, ctx := trace.New(ctx, "service.RunChangesetActionJob", fmt.Sprintf("job_id: %d", job.ID))
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+	tr.LogFields(log.Int64("job_id", job.ID), log.Int64("changeset_id", job.ChangesetID))
+
+	if !job.FinishedAt.IsZero() {
+		log15.Info("ChangesetActionJob already finished", "id", job.ID)
+		return nil
+	}
+
+	// The job isn't retried, so errors which prevent us from performing
+	// the action are saved in it, too.
+	defer func() {
+		if err != nil {
+			job.Error = err.Error()
+		}
+		job.FinishedAt = clock()
+		if e := store.UpdateChangesetActionJob(ctx, job); e != nil {
+			if err == nil {
+				err = e
+			} else {
+				err = multierror.Append(err, e)
+			}
+		}
+	}()
+
+	c, err := store.GetChangeset(ctx, GetChangesetOpts{ID: job.ChangesetID})
+	if err != nil {
+		return errors.Wrap(err, "getting changeset")
+	}
+
+	syncer := ChangesetSyncer{
+		ReposStore:  repos.NewDBStore(store.DB(), sql.TxOptions{}),
+		Store:       store,
+		HTTPFactory: cf,
+	}
+
+	bySource, err := syncer.GroupChangesetsBySource(ctx, c)
+	if err != nil {
+		return errors.Wrap(err, "getting changeset source")
+	}
+
+	var src *SourceChangesets
+	for _, s := range bySource {
+		if len(s.Changesets) != 0 {
+			src = s
+			break
+		}
+	}
+
+	switch {
+	case src == nil:
+		job.Error = fmt.Sprintf("no codehost connection found for changeset %d", c.ID)
+	case c.IsDeleted():
+		job.Error = fmt.Sprintf("changeset %d has been deleted on the codehost", c.ID)
+	default:
+		if err := runChangesetAction(ctx, src.ChangesetSource, src.Changesets[0], job); err != nil {
+			job.Error = err.Error()
+			break
+		}
+		// The action produces new events on the codehost, such as comments
+		// or label changes, which we want to show in the changeset's
+		// timeline right away.
+		if err := syncer.SyncChangesetsWithSources(ctx, []*SourceChangesets{src}); err != nil {
+			log15.Warn("Syncing changeset after changeset action failed", "changeset_id", c.ID, "err", err)
+		}
+	}
+
+	return nil
+}
+
+func runChangesetAction(ctx context.Context, src repos.ChangesetSource, c *repos.Changeset, job *campaigns.ChangesetActionJob) error {
+	switch job.Kind {
+	case campaigns.ChangesetActionKindComment:
+		cs, ok := src.(repos.ChangesetCommentSource)
+		if !ok {
+			return errors.Errorf("commenting on changesets of repo %q is not supported", c.Repo.Name)
+		}
+		return cs.CreateChangesetComment(ctx, c, job.Payload.Body)
+
+	case campaigns.ChangesetActionKindLabel:
+		ls, ok := src.(repos.ChangesetLabelSource)
+		if !ok {
+			return errors.Errorf("labeling changesets of repo %q is not supported", c.Repo.Name)
+		}
+		if len(job.Payload.AddLabels) != 0 {
+			if err := ls.AddChangesetLabels(ctx, c, job.Payload.AddLabels); err != nil {
+				return err
+			}
+		}
+		if len(job.Payload.RemoveLabels) != 0 {
+			return ls.RemoveChangesetLabels(ctx, c, job.Payload.RemoveLabels)
+		}
+		return nil
+
+	case campaigns.ChangesetActionKindRequestReviewers:
+		rs, ok := src.(repos.ChangesetReviewerSource)
+		if !ok {
+			return errors.Errorf("requesting reviewers of changesets of repo %q is not supported", c.Repo.Name)
+		}
+		return rs.RequestChangesetReviewers(ctx, c, job.Payload.Reviewers)
+
+	default:
+		return ErrChangesetActionKindInvalid
+	}
+}
+
 // CreateChangesetJobForPatch creates a ChangesetJob for the
 // Patch with the given ID. The Patch has to belong to a
 // PatchSet that was attached to a Campaign.
@@ -709,6 +1344,14 @@ type UpdateCampaignArgs struct {
 	Description *string
 	Branch      *string
 	PatchSet    *int64
+	AutoMerge   *bool
+	MergeMethod *campaigns.ChangesetMergeMethod
+
+	CommitMessage     *string
+	CommitAuthorName  *string
+	CommitAuthorEmail *string
+	CommitSignOff     *bool
+	CommitSign        *bool
 }
 
 // ErrCampaignNameBlank is returned by CreateCampaign or UpdateCampaign if the
@@ -719,6 +1362,25 @@ var ErrCampaignNameBlank = errors.New("Campaign title cannot be blank")
 // branch is blank. This is only enforced when creating published campaigns with a patch set.
 var ErrCampaignBranchBlank = errors.New("Campaign branch cannot be blank")
 
+// ErrCampaignMergeMethodInvalid is returned by CreateCampaign or UpdateCampaign
+// if the specified Campaign's merge method is not a valid merge method.
+var ErrCampaignMergeMethodInvalid = errors.New("Campaign merge method is invalid")
+
+// ErrCampaignCommitMessageInvalid is returned by CreateCampaign or
+// UpdateCampaign if the specified Campaign's commit message is not a valid
+// template.
+var ErrCampaignCommitMessageInvalid = errors.New("Campaign commit message is not a valid template")
+
+// ErrCampaignCommitAuthorIncomplete is returned by CreateCampaign or
+// UpdateCampaign if only one of the specified Campaign's commit author name
+// and email is set, or the email is not an email address.
+var ErrCampaignCommitAuthorIncomplete = errors.New("Campaign commit author needs both a name and an email address")
+
+// ErrCampaignDraftOnCodeHostPublished is returned by CreateCampaign if the
+// specified Campaign's changesets are to be drafts on the codehosts, but the
+// Campaign is not created in draft mode.
+var ErrCampaignDraftOnCodeHostPublished = errors.New("Campaign changesets can only be drafts on the codehost while the Campaign is a draft")
+
 // ErrPublishedCampaignBranchChange is returned by UpdateCampaign if there is an
 // attempt to change the branch of a published campaign with a patch set (or a campaign with individually published changesets).
 var ErrPublishedCampaignBranchChange = errors.New("Published campaign branch cannot be changed")
@@ -727,6 +1389,10 @@ var ErrPublishedCampaignBranchChange = errors.New("Published campaign branch can
 // specified patch set is already attached to another campaign.
 var ErrPatchSetDuplicate = errors.New("Campaign cannot use the same patch set as another campaign")
 
+// ErrPatchSetProcessing is returned by CreateCampaign or UpdateCampaign if
+// the Patches of the specified patch set are still being computed.
+var ErrPatchSetProcessing = errors.New("Campaign cannot use a patch set whose patches are still being computed")
+
 // ErrClosedCampaignUpdatePatchIllegal is returned by UpdateCampaign if a patch set
 // is to be attached to a closed campaign.
 var ErrClosedCampaignUpdatePatchIllegal = errors.New("cannot update the patch set of a closed campaign")
@@ -760,7 +1426,7 @@ func (s *Service) UpdateCampaign(ctx context.Context, args UpdateCampaignArgs) (
 		return nil, nil, ErrClosedCampaignUpdatePatchIllegal
 	}
 
-	var updateAttributes, updatePatchSetID, updateBranch bool
+	var updateAttributes, updatePatchSetID, updateBranch, updateAutoMerge, updateCommitConfig bool
 
 	if args.Name != nil && campaign.Name != *args.Name {
 		if *args.Name == "" {
@@ -790,6 +1456,14 @@ func (s *Service) UpdateCampaign(ctx context.Context, args UpdateCampaignArgs) (
 			return nil, nil, ErrPatchSetDuplicate
 		}
 
+		processing, err := patchSetIsProcessing(ctx, tx, *args.PatchSet)
+		if err != nil {
+			return nil, nil, err
+		}
+		if processing {
+			return nil, nil, ErrPatchSetProcessing
+		}
+
 		campaign.PatchSetID = *args.PatchSet
 		updatePatchSetID = true
 	}
@@ -803,7 +1477,34 @@ func (s *Service) UpdateCampaign(ctx context.Context, args UpdateCampaignArgs) (
 		updateBranch = true
 	}
 
+	if args.AutoMerge != nil && campaign.AutoMerge != *args.AutoMerge {
+		campaign.AutoMerge = *args.AutoMerge
+		updateAutoMerge = true
+	}
+
+	if args.MergeMethod != nil && campaign.MergeMethod != *args.MergeMethod {
+		if !args.MergeMethod.Valid() {
+			return nil, nil, ErrCampaignMergeMethodInvalid
+		}
+
+		campaign.MergeMethod = *args.MergeMethod
+		updateAutoMerge = true
+	}
+
+	if applyCommitConfigUpdate(campaign, args) {
+		if err := validateCommitConfig(campaign); err != nil {
+			return nil, nil, err
+		}
+		updateCommitConfig = true
+	}
+
 	if !updateAttributes && !updatePatchSetID && !updateBranch {
+		if updateAutoMerge || updateCommitConfig {
+			// Changesets are merged by the ChangesetMerger, independently of
+			// the ChangesetJobs, and the commit configuration only applies to
+			// commits created from now on, so there's nothing else to update.
+			return campaign, nil, tx.UpdateCampaign(ctx, campaign)
+		}
 		return campaign, nil, nil
 	}
 
@@ -1125,3 +1826,24 @@ func campaignIsProcessing(ctx context.Context, store *Store, campaign int64) (bo
 	}
 	return status.Processing(), nil
 }
+
+func patchSetIsProcessing(ctx context.Context, store *Store, patchSet int64) (bool, error) {
+	status, err := store.GetPatchSetStatus(ctx, patchSet)
+	if err != nil {
+		return false, err
+	}
+	return status.Processing(), nil
+}
+
+func (s *Service) reposByID(ctx context.Context, ids []api.RepoID) (map[api.RepoID]*repos.Repo, error) {
+	reposStore := repos.NewDBStore(s.store.DB(), sql.TxOptions{})
+	rs, err := reposStore.ListRepos(ctx, repos.StoreListReposArgs{IDs: ids})
+	if err != nil {
+		return nil, err
+	}
+	reposByID := make(map[api.RepoID]*repos.Repo, len(rs))
+	for _, repo := range rs {
+		reposByID[repo.ID] = repo
+	}
+	return reposByID, nil
+}
@@ -3,12 +3,16 @@ package campaigns
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"sort"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
@@ -17,9 +21,12 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/campaigns"
 	"github.com/sourcegraph/sourcegraph/internal/db/dbconn"
 	"github.com/sourcegraph/sourcegraph/internal/db/dbtesting"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/schema"
 )
 
 func init() {
@@ -105,6 +112,86 @@ func TestService(t *testing.T) {
 		}
 	})
 
+	t.Run("CreatePatchSetFromSpec", func(t *testing.T) {
+		svc := NewServiceWithClock(store, nil, nil, clock)
+
+		spec := &campaigns.PatchSetSpec{Query: "repo:github.com/sourcegraph", MatchTemplate: "foo", RewriteTemplate: "bar"}
+		jobs := []*campaigns.PatchJob{
+			{RepoID: rs[0].ID, Rev: "deadbeef", BaseRef: "refs/heads/master"},
+			{RepoID: rs[1].ID, Rev: "f00b4r", BaseRef: "refs/heads/master"},
+			{RepoID: rs[2].ID, Rev: "b4r", BaseRef: "refs/heads/master"},
+		}
+
+		if _, err := svc.CreatePatchSetFromSpec(ctx, &campaigns.PatchSetSpec{Query: "repo:a"}, jobs, user.ID); err != ErrPatchSetSpecMatchTemplateBlank {
+			t.Fatalf("got error %v, want %v", err, ErrPatchSetSpecMatchTemplateBlank)
+		}
+
+		patchSet, err := svc.CreatePatchSetFromSpec(ctx, spec, jobs, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		have, err := store.GetPatchSet(ctx, GetPatchSetOpts{ID: patchSet.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(have.Spec, spec); diff != "" {
+			t.Fatal(diff)
+		}
+
+		// The campaign can't be created before all patches are computed.
+		campaign := testCampaign(user.ID, patchSet.ID)
+		if err := svc.CreateCampaign(ctx, campaign, true); err != ErrPatchSetProcessing {
+			t.Fatalf("got error %v, want %v", err, ErrPatchSetProcessing)
+		}
+
+		replacer := &dummyReplacerClient{
+			diffs: map[api.RepoName]string{
+				api.RepoName(rs[0].Name): "--- f\n+++ f\n@@ -1 +1 @@\n-foo\n+bar\n",
+				api.RepoName(rs[1].Name): "",
+			},
+		}
+		for {
+			ran, err := store.ProcessPendingPatchJobs(ctx, func(ctx context.Context, s *Store, job campaigns.PatchJob) error {
+				return RunPatchJob(ctx, clock, s, replacer, &job)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ran {
+				break
+			}
+		}
+
+		patches, _, err := store.ListPatches(ctx, ListPatchesOpts{PatchSetID: patchSet.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range patches {
+			p.ID = 0 // ignore database ID when checking for expected output
+		}
+		wantPatches := []*campaigns.Patch{{
+			PatchSetID: patchSet.ID,
+			RepoID:     rs[0].ID,
+			Rev:        "deadbeef",
+			BaseRef:    "refs/heads/master",
+			Diff:       replacer.diffs[api.RepoName(rs[0].Name)],
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}}
+		if diff := cmp.Diff(patches, wantPatches); diff != "" {
+			t.Fatal(diff)
+		}
+
+		status, err := store.GetPatchSetStatus(ctx, patchSet.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.State() != campaigns.BackgroundProcessStateErrored || len(status.Errors()) != 1 {
+			t.Fatalf("unexpected status %+v", status)
+		}
+	})
+
 	t.Run("CreateCampaign", func(t *testing.T) {
 		patchSet := &campaigns.PatchSet{UserID: user.ID}
 		err = store.CreatePatchSet(ctx, patchSet)
@@ -194,6 +281,86 @@ func TestService(t *testing.T) {
 		}
 	})
 
+	t.Run("CreateCampaignAsDraftOnCodeHost", func(t *testing.T) {
+		patchSet := &campaigns.PatchSet{UserID: user.ID}
+		err = store.CreatePatchSet(ctx, patchSet)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, repo := range rs {
+			patch := testPatch(patchSet.ID, repo.ID, now)
+			err := store.CreatePatch(ctx, patch)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		campaign := testCampaign(user.ID, patchSet.ID)
+		campaign.DraftOnCodeHost = true
+
+		svc := NewServiceWithClock(store, gitClient, cf, clock)
+		err = svc.CreateCampaign(ctx, campaign, false)
+		if err != ErrCampaignDraftOnCodeHostPublished {
+			t.Fatalf("CreateCampaign returned unexpected error: %v", err)
+		}
+
+		err = svc.CreateCampaign(ctx, campaign, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The draft changesets are created on the code host right away.
+		haveJobs, _, err := store.ListChangesetJobs(ctx, ListChangesetJobsOpts{
+			CampaignID: campaign.ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(haveJobs) != len(rs) {
+			t.Errorf("wrong number of ChangesetJobs: %d. want=%d", len(haveJobs), len(rs))
+		}
+
+		_, err = svc.PublishCampaign(ctx, campaign.ID)
+		if err != ErrPublishProcessingCampaign {
+			t.Fatalf("PublishCampaign returned unexpected error: %v", err)
+		}
+
+		for _, j := range haveJobs {
+			j.StartedAt = now
+			j.FinishedAt = now
+			if err := store.UpdateChangesetJob(ctx, j); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		published, err := svc.PublishCampaign(ctx, campaign.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if published.DraftOnCodeHost {
+			t.Fatal("published campaign is still a draft on the code host")
+		}
+
+		// Publishing doesn't create any new ChangesetJobs, but reruns the
+		// existing ones to mark their changesets as ready for review.
+		haveJobs, _, err = store.ListChangesetJobs(ctx, ListChangesetJobsOpts{
+			CampaignID: campaign.ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(haveJobs) != len(rs) {
+			t.Errorf("wrong number of ChangesetJobs: %d. want=%d", len(haveJobs), len(rs))
+		}
+		for _, j := range haveJobs {
+			if !j.StartedAt.IsZero() || !j.FinishedAt.IsZero() {
+				t.Errorf("ChangesetJob %d was not reset", j.ID)
+			}
+		}
+	})
+
 	t.Run("CreateCampaignWithPatchSetAttachedToOtherCampaign", func(t *testing.T) {
 		patchSet := &campaigns.PatchSet{UserID: user.ID}
 		err = store.CreatePatchSet(ctx, patchSet)
@@ -274,6 +441,65 @@ func TestService(t *testing.T) {
 		}
 	})
 
+	t.Run("EnqueueChangesetActions", func(t *testing.T) {
+		campaign := testCampaign(user.ID, 0)
+		if err := store.CreateCampaign(ctx, campaign); err != nil {
+			t.Fatal(err)
+		}
+
+		states := []campaigns.ChangesetState{
+			campaigns.ChangesetStateOpen,
+			campaigns.ChangesetStateOpen,
+			campaigns.ChangesetStateMerged,
+		}
+		changesets := make([]*campaigns.Changeset, 0, len(states))
+		for i, state := range states {
+			changesets = append(changesets, testChangeset(rs[i].ID, campaign.ID, int64(1000+i), state))
+		}
+		if err := store.CreateChangesets(ctx, changesets...); err != nil {
+			t.Fatal(err)
+		}
+
+		svc := NewServiceWithClock(store, gitClient, cf, clock)
+
+		open := campaigns.ChangesetStateOpen
+		jobs, err := svc.EnqueueChangesetActions(ctx, ChangesetActionArgs{
+			CampaignID:    campaign.ID,
+			UserID:        user.ID,
+			Kind:          campaigns.ChangesetActionKindComment,
+			Payload:       campaigns.ChangesetActionPayload{Body: "Please review"},
+			ExternalState: &open,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var have []int64
+		for _, j := range jobs {
+			if j.Kind != campaigns.ChangesetActionKindComment || j.Payload.Body != "Please review" || j.UserID != user.ID {
+				t.Errorf("unexpected job %+v", j)
+			}
+			have = append(have, j.ChangesetID)
+		}
+		want := []int64{changesets[0].ID, changesets[1].ID}
+		if diff := cmp.Diff(have, want); diff != "" {
+			t.Fatal(diff)
+		}
+
+		// The merged changeset isn't open, so nothing matches.
+		_, err = svc.EnqueueChangesetActions(ctx, ChangesetActionArgs{
+			CampaignID:    campaign.ID,
+			UserID:        user.ID,
+			Kind:          campaigns.ChangesetActionKindLabel,
+			Payload:       campaigns.ChangesetActionPayload{AddLabels: []string{"campaign"}},
+			ChangesetIDs:  []int64{changesets[2].ID},
+			ExternalState: &open,
+		})
+		if err != ErrChangesetActionNoChangesets {
+			t.Fatalf("have error %v, want %v", err, ErrChangesetActionNoChangesets)
+		}
+	})
+
 	t.Run("UpdateCampaign", func(t *testing.T) {
 		strPointer := func(s string) *string { return &s }
 		subTests := []struct {
@@ -389,6 +615,58 @@ func TestService(t *testing.T) {
 		}
 	})
 
+	t.Run("UpdateCampaignAutoMerge", func(t *testing.T) {
+		patchSet := &campaigns.PatchSet{UserID: user.ID}
+		err = store.CreatePatchSet(ctx, patchSet)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		patch := testPatch(patchSet.ID, rs[0].ID, now)
+		err := store.CreatePatch(ctx, patch)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		svc := NewServiceWithClock(store, gitClient, cf, clock)
+		campaign := testCampaign(user.ID, patchSet.ID)
+
+		// The campaign is published but its ChangesetJobs are unprocessed,
+		// which doesn't prevent changing how its changesets are merged.
+		err = svc.CreateCampaign(ctx, campaign, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		invalid := campaigns.ChangesetMergeMethod("OCTOPUS")
+		_, _, err = svc.UpdateCampaign(ctx, UpdateCampaignArgs{Campaign: campaign.ID, MergeMethod: &invalid})
+		if err != ErrCampaignMergeMethodInvalid {
+			t.Fatalf("want error %q, have %v", ErrCampaignMergeMethodInvalid, err)
+		}
+
+		autoMerge := true
+		squash := campaigns.ChangesetMergeMethodSquash
+		args := UpdateCampaignArgs{Campaign: campaign.ID, AutoMerge: &autoMerge, MergeMethod: &squash}
+
+		updatedCampaign, _, err := svc.UpdateCampaign(ctx, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !updatedCampaign.AutoMerge || updatedCampaign.MergeMethod != squash {
+			t.Errorf("auto-merge not updated: %+v", updatedCampaign)
+		}
+
+		persisted, err := store.GetCampaign(ctx, GetCampaignOpts{ID: campaign.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !persisted.AutoMerge || persisted.MergeMethod != squash {
+			t.Errorf("auto-merge not persisted: %+v", persisted)
+		}
+	})
+
 	t.Run("UpdateCampaignWithPatchSetAttachedToOtherCampaign", func(t *testing.T) {
 		svc := NewServiceWithClock(store, gitClient, cf, clock)
 
@@ -1073,6 +1351,381 @@ func fakeRunChangesetJobs(
 	return cs
 }
 
+func TestRunChangesetJob(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := backend.WithAuthzBypass(context.Background())
+	dbtesting.SetupGlobalTestDB(t)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	clock := func() time.Time {
+		return now.UTC().Truncate(time.Microsecond)
+	}
+
+	gitClient := &dummyGitserverClient{response: "refs/heads/test-branch", responseErr: nil}
+	cf := httpcli.NewFactory(nil)
+
+	user := createTestUser(ctx, t)
+
+	store := NewStoreWithClock(dbconn.Global, clock)
+	reposStore := repos.NewDBStore(dbconn.Global, sql.TxOptions{})
+
+	tests := []struct {
+		name        string
+		kind        string
+		serviceType string
+		config      func(url string) interface{}
+		metadata    interface{}
+		// handler fakes the API of the code host.
+		handler        func(t *testing.T) http.Handler
+		wantExternalID string
+	}{
+		{
+			name:        "GitLab",
+			kind:        "GITLAB",
+			serviceType: gitlab.ServiceType,
+			config: func(url string) interface{} {
+				return &schema.GitLabConnection{Url: url, Token: "secret", ProjectQuery: []string{"none"}}
+			},
+			metadata: &gitlab.Project{ProjectCommon: gitlab.ProjectCommon{ID: 1, PathWithNamespace: "sourcegraph/sourcegraph"}},
+			handler: func(t *testing.T) http.Handler {
+				mux := http.NewServeMux()
+				mux.HandleFunc("/api/v4/projects/1/merge_requests", func(w http.ResponseWriter, r *http.Request) {
+					if r.Method != "POST" {
+						t.Errorf("unexpected %s %s", r.Method, r.URL)
+						http.Error(w, "unexpected request", http.StatusBadRequest)
+						return
+					}
+					var opts gitlab.CreateMergeRequestOpts
+					if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+						t.Error(err)
+					}
+					if opts.SourceBranch != "test-branch" || opts.TargetBranch != "master" {
+						t.Errorf("unexpected merge request options %+v", opts)
+					}
+					fmt.Fprint(w, `{"id": 100, "iid": 7, "project_id": 1, "state": "opened", "source_branch": "test-branch", "target_branch": "master"}`)
+				})
+				mux.HandleFunc("/api/v4/projects/1/merge_requests/7/notes", func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, `[]`)
+				})
+				mux.HandleFunc("/api/v4/projects/1/merge_requests/7/pipelines", func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, `[]`)
+				})
+				return mux
+			},
+			wantExternalID: "7",
+		},
+		{
+			name:        "BitbucketCloud",
+			kind:        "BITBUCKETCLOUD",
+			serviceType: bitbucketcloud.ServiceType,
+			config: func(url string) interface{} {
+				return &schema.BitbucketCloudConnection{Url: "https://bitbucket.org", ApiURL: url, Username: "user", AppPassword: "secret"}
+			},
+			metadata: &bitbucketcloud.Repo{FullName: "sourcegraph/sourcegraph"},
+			handler: func(t *testing.T) http.Handler {
+				const prPath = "/2.0/repositories/sourcegraph/sourcegraph/pullrequests"
+				mux := http.NewServeMux()
+				mux.HandleFunc(prPath, func(w http.ResponseWriter, r *http.Request) {
+					switch r.Method {
+					case "GET":
+						// There is no open pull request for the branch yet.
+						fmt.Fprint(w, `{"values": []}`)
+					case "POST":
+						var opts bitbucketcloud.CreatePullRequestOpts
+						if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+							t.Error(err)
+						}
+						if opts.Source.Branch.Name != "test-branch" || opts.Destination.Branch.Name != "master" {
+							t.Errorf("unexpected pull request options %+v", opts)
+						}
+						fmt.Fprint(w, `{"id": 3, "state": "OPEN", "source": {"branch": {"name": "test-branch"}}, "destination": {"branch": {"name": "master"}}}`)
+					}
+				})
+				mux.HandleFunc(prPath+"/3/activity", func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, `{"values": []}`)
+				})
+				mux.HandleFunc(prPath+"/3/statuses", func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, `{"values": []}`)
+				})
+				return mux
+			},
+			wantExternalID: "3",
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(tc.handler(t))
+			defer srv.Close()
+
+			extSvc := &repos.ExternalService{
+				Kind:        tc.kind,
+				DisplayName: tc.name,
+				Config:      marshalJSON(t, tc.config(srv.URL)),
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			}
+			if err := reposStore.UpsertExternalServices(ctx, extSvc); err != nil {
+				t.Fatal(err)
+			}
+
+			repo := testRepo(100+i, tc.serviceType)
+			repo.Metadata = tc.metadata
+			repo.Sources = map[string]*repos.SourceInfo{
+				extSvc.URN(): {ID: extSvc.URN(), CloneURL: srv.URL + "/sourcegraph/sourcegraph"},
+			}
+			if err := reposStore.UpsertRepos(ctx, repo); err != nil {
+				t.Fatal(err)
+			}
+
+			patchSet := &campaigns.PatchSet{UserID: user.ID}
+			if err := store.CreatePatchSet(ctx, patchSet); err != nil {
+				t.Fatal(err)
+			}
+			patch := testPatch(patchSet.ID, repo.ID, now)
+			if err := store.CreatePatch(ctx, patch); err != nil {
+				t.Fatal(err)
+			}
+			campaign := testCampaign(user.ID, patchSet.ID)
+			if err := store.CreateCampaign(ctx, campaign); err != nil {
+				t.Fatal(err)
+			}
+			job := &campaigns.ChangesetJob{CampaignID: campaign.ID, PatchID: patch.ID}
+			if err := store.CreateChangesetJob(ctx, job); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := RunChangesetJob(ctx, clock, store, gitClient, cf, campaign, job); err != nil {
+				t.Fatal(err)
+			}
+
+			if job.Error != "" || job.ChangesetID == 0 || job.Branch != "refs/heads/test-branch" {
+				t.Fatalf("unexpected changeset job %+v", job)
+			}
+			changeset, err := store.GetChangeset(ctx, GetChangesetOpts{ID: job.ChangesetID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have, want := changeset.ExternalServiceType, tc.serviceType; have != want {
+				t.Errorf("wrong external service type. want=%q, have=%q", want, have)
+			}
+			if have, want := changeset.ExternalID, tc.wantExternalID; have != want {
+				t.Errorf("wrong external ID. want=%q, have=%q", want, have)
+			}
+			if have, want := changeset.ExternalState, campaigns.ChangesetStateOpen; have != want {
+				t.Errorf("wrong external state. want=%q, have=%q", want, have)
+			}
+		})
+	}
+}
+
+func TestCampaignCommitInfo(t *testing.T) {
+	ctx := context.Background()
+
+	db.Mocks.Users.GetByID = func(ctx context.Context, id int32) (*types.User, error) {
+		return &types.User{ID: id, Username: "jdoe", DisplayName: "Jane Doe"}, nil
+	}
+	defer func() {
+		db.Mocks.Users = db.MockUsers{}
+		db.Mocks.UserEmails = db.MockUserEmails{}
+	}()
+
+	campaign := &campaigns.Campaign{
+		Name:        "Update dependencies",
+		Description: "Updates all the dependencies",
+		Branch:      "update-deps",
+		AuthorID:    1,
+	}
+
+	tests := []struct {
+		name     string
+		campaign func(*campaigns.Campaign)
+		email    string
+		verified bool
+		want     protocol.PatchCommitInfo
+	}{
+		{
+			name:     "defaults",
+			email:    "jane@example.com",
+			verified: true,
+			want: protocol.PatchCommitInfo{
+				Message:     "Update dependencies",
+				AuthorName:  "Jane Doe",
+				AuthorEmail: "jane@example.com",
+			},
+		},
+		{
+			name:  "unverified email",
+			email: "jane@example.com",
+			want: protocol.PatchCommitInfo{
+				Message:     "Update dependencies",
+				AuthorName:  "Sourcegraph Bot",
+				AuthorEmail: "campaigns@sourcegraph.com",
+			},
+		},
+		{
+			name: "no email",
+			want: protocol.PatchCommitInfo{
+				Message:     "Update dependencies",
+				AuthorName:  "Sourcegraph Bot",
+				AuthorEmail: "campaigns@sourcegraph.com",
+			},
+		},
+		{
+			name: "configured",
+			campaign: func(c *campaigns.Campaign) {
+				c.CommitMessage = "chore: {{.CampaignName}} in {{.Repository}}\n\n{{.CampaignDescription}}\n"
+				c.CommitAuthorName = "Release Bot"
+				c.CommitAuthorEmail = "release-bot@example.com"
+				c.CommitSignOff = true
+				c.CommitSign = true
+			},
+			want: protocol.PatchCommitInfo{
+				Message:     "chore: Update dependencies in github.com/sourcegraph/sourcegraph\n\nUpdates all the dependencies",
+				AuthorName:  "Release Bot",
+				AuthorEmail: "release-bot@example.com",
+				SignOff:     true,
+				Sign:        true,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db.Mocks.UserEmails.GetPrimaryEmail = func(ctx context.Context, id int32) (string, bool, error) {
+				if tc.email == "" {
+					return "", false, notFoundError{}
+				}
+				return tc.email, tc.verified, nil
+			}
+
+			c := campaign.Clone()
+			if tc.campaign != nil {
+				tc.campaign(c)
+			}
+
+			have, err := campaignCommitInfo(ctx, c, "github.com/sourcegraph/sourcegraph")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, have); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestValidateCommitConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		campaign campaigns.Campaign
+		want     error
+	}{
+		{name: "empty"},
+		{
+			name:     "valid",
+			campaign: campaigns.Campaign{CommitMessage: "{{.CampaignName}}", CommitAuthorName: "Jane", CommitAuthorEmail: "jane@example.com"},
+		},
+		{
+			name:     "invalid template",
+			campaign: campaigns.Campaign{CommitMessage: "{{.CampaignName"},
+			want:     ErrCampaignCommitMessageInvalid,
+		},
+		{
+			name:     "unknown field",
+			campaign: campaigns.Campaign{CommitMessage: "{{.CampaignTitle}}"},
+			want:     ErrCampaignCommitMessageInvalid,
+		},
+		{
+			name:     "failing function",
+			campaign: campaigns.Campaign{CommitMessage: `{{index .Repository 100}}`},
+			want:     ErrCampaignCommitMessageInvalid,
+		},
+		{
+			name:     "name without email",
+			campaign: campaigns.Campaign{CommitAuthorName: "Jane"},
+			want:     ErrCampaignCommitAuthorIncomplete,
+		},
+		{
+			name:     "invalid email",
+			campaign: campaigns.Campaign{CommitAuthorName: "Jane", CommitAuthorEmail: "jane"},
+			want:     ErrCampaignCommitAuthorIncomplete,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if have := validateCommitConfig(&tc.campaign); have != tc.want {
+				t.Fatalf("have error %v, want %v", have, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateChangesetAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    campaigns.ChangesetActionKind
+		payload campaigns.ChangesetActionPayload
+		want    error
+	}{
+		{
+			name:    "comment",
+			kind:    campaigns.ChangesetActionKindComment,
+			payload: campaigns.ChangesetActionPayload{Body: "Please review"},
+		},
+		{
+			name:    "blank comment",
+			kind:    campaigns.ChangesetActionKindComment,
+			payload: campaigns.ChangesetActionPayload{Body: " \n"},
+			want:    ErrChangesetActionPayloadBlank,
+		},
+		{
+			name:    "remove labels only",
+			kind:    campaigns.ChangesetActionKindLabel,
+			payload: campaigns.ChangesetActionPayload{RemoveLabels: []string{"stale"}},
+		},
+		{
+			name: "no labels",
+			kind: campaigns.ChangesetActionKindLabel,
+			want: ErrChangesetActionPayloadBlank,
+		},
+		{
+			name:    "reviewers",
+			kind:    campaigns.ChangesetActionKindRequestReviewers,
+			payload: campaigns.ChangesetActionPayload{Reviewers: []string{"jane"}},
+		},
+		{
+			name:    "labels instead of reviewers",
+			kind:    campaigns.ChangesetActionKindRequestReviewers,
+			payload: campaigns.ChangesetActionPayload{AddLabels: []string{"campaign"}},
+			want:    ErrChangesetActionPayloadBlank,
+		},
+		{
+			name:    "unknown kind",
+			kind:    "MERGE",
+			payload: campaigns.ChangesetActionPayload{Body: "Please review"},
+			want:    ErrChangesetActionKindInvalid,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if have := validateChangesetAction(tc.kind, tc.payload); have != tc.want {
+				t.Fatalf("have error %v, want %v", have, tc.want)
+			}
+		})
+	}
+}
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string  { return "not found" }
+func (notFoundError) NotFound() bool { return true }
+
 var testUser = db.NewUser{
 	Email:                 "thorsten@sourcegraph.com",
 	Username:              "thorsten",
@@ -1147,3 +1800,15 @@ type dummyGitserverClient struct {
 func (d *dummyGitserverClient) CreateCommitFromPatch(ctx context.Context, req protocol.CreateCommitFromPatchRequest) (string, error) {
 	return d.response, d.responseErr
 }
+
+type dummyReplacerClient struct {
+	diffs map[api.RepoName]string
+}
+
+func (d *dummyReplacerClient) Replace(ctx context.Context, repo api.RepoName, commit api.CommitID, spec *campaigns.PatchSetSpec) (string, error) {
+	diff, ok := d.diffs[repo]
+	if !ok {
+		return "", errors.New("replacer request failed")
+	}
+	return diff, nil
+}
@@ -15,8 +15,10 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/campaigns"
 	"github.com/sourcegraph/sourcegraph/internal/db/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 )
 
 // Store exposes methods to read and write campaigns domain models
@@ -107,6 +109,7 @@ RETURNING j.id,
   j.patch_id,
   j.changeset_id,
   j.branch,
+  j.base_commit,
   j.error,
   j.started_at,
   j.finished_at,
@@ -265,7 +268,8 @@ WITH batch AS (
       external_updated_at   timestamptz,
       external_state        text,
       external_review_state text,
-      external_check_state  text
+      external_check_state  text,
+      conflicted            boolean
     )
   )
   WITH ORDINALITY
@@ -288,7 +292,8 @@ changed AS (
     external_updated_at,
     external_state,
     external_review_state,
-    external_check_state
+    external_check_state,
+    conflicted
   )
   SELECT
     repo_id,
@@ -303,7 +308,8 @@ changed AS (
     external_updated_at,
     external_state,
     external_review_state,
-    external_check_state
+    external_check_state,
+    conflicted
   FROM batch
   ON CONFLICT ON CONSTRAINT
     changesets_repo_external_id_unique
@@ -327,7 +333,8 @@ SELECT
   COALESCE(changed.external_updated_at, existing.external_updated_at) AS external_updated_at,
   COALESCE(changed.external_state, existing.external_state) AS external_state,
   COALESCE(changed.external_review_state, existing.external_review_state) AS external_review_state,
-  COALESCE(changed.external_check_state, existing.external_check_state) AS external_check_state
+  COALESCE(changed.external_check_state, existing.external_check_state) AS external_check_state,
+  COALESCE(changed.conflicted, existing.conflicted) AS conflicted
 FROM changed
 RIGHT JOIN batch ON batch.repo_id = changed.repo_id
 AND batch.external_id = changed.external_id
@@ -366,6 +373,7 @@ func batchChangesetsQuery(fmtstr string, cs []*campaigns.Changeset) (*sqlf.Query
 		ExternalState       *campaigns.ChangesetState       `json:"external_state"`
 		ExternalReviewState *campaigns.ChangesetReviewState `json:"external_review_state"`
 		ExternalCheckState  *campaigns.ChangesetCheckState  `json:"external_check_state"`
+		Conflicted          bool                            `json:"conflicted"`
 	}
 
 	records := make([]record, 0, len(cs))
@@ -393,6 +401,7 @@ func batchChangesetsQuery(fmtstr string, cs []*campaigns.Changeset) (*sqlf.Query
 			ExternalBranch:      c.ExternalBranch,
 			ExternalDeletedAt:   nullTimeColumn(c.ExternalDeletedAt),
 			ExternalUpdatedAt:   nullTimeColumn(c.ExternalUpdatedAt),
+			Conflicted:          c.Conflicted,
 		}
 		if len(c.ExternalState) > 0 {
 			r.ExternalState = &c.ExternalState
@@ -524,7 +533,8 @@ SELECT
   external_updated_at,
   external_state,
   external_review_state,
-  external_check_state
+  external_check_state,
+  conflicted
 FROM changesets
 WHERE %s
 LIMIT 1
@@ -594,6 +604,49 @@ ORDER BY changesets.id ASC
 `)
 }
 
+// ListChangesetMergeCandidates returns the changesets that can be merged
+// automatically: those that are open, approved, have passing checks and
+// belong to an open campaign with auto-merge enabled. If a changeset belongs
+// to multiple such campaigns, the merge method of the oldest one is used.
+func (s *Store) ListChangesetMergeCandidates(ctx context.Context) ([]campaigns.ChangesetMergeCandidate, error) {
+	q := sqlf.Sprintf(
+		listChangesetMergeCandidatesQueryFmtstr,
+		campaigns.ChangesetStateOpen,
+		campaigns.ChangesetReviewStateApproved,
+		campaigns.ChangesetCheckStatePassed,
+	)
+
+	results := make([]campaigns.ChangesetMergeCandidate, 0)
+	_, _, err := s.query(ctx, q, func(sc scanner) (last, count int64, err error) {
+		var c campaigns.ChangesetMergeCandidate
+		if err = sc.Scan(&c.ChangesetID, &c.MergeMethod); err != nil {
+			return 0, 0, err
+		}
+		results = append(results, c)
+		return c.ChangesetID, 1, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+var listChangesetMergeCandidatesQueryFmtstr = `
+-- source: enterprise/internal/campaigns/store.go:ListChangesetMergeCandidates
+SELECT DISTINCT ON (changesets.id)
+  changesets.id,
+  COALESCE(campaigns.merge_method, 'MERGE')
+FROM changesets
+JOIN campaigns ON changesets.campaign_ids ? campaigns.id::text
+WHERE campaigns.auto_merge
+AND campaigns.closed_at IS NULL
+AND changesets.external_deleted_at IS NULL
+AND changesets.external_state = %s
+AND changesets.external_review_state = %s
+AND changesets.external_check_state = %s
+ORDER BY changesets.id ASC, campaigns.id ASC
+`
+
 // ListChangesetsOpts captures the query options needed for
 // listing changesets.
 type ListChangesetsOpts struct {
@@ -645,7 +698,8 @@ SELECT
   external_updated_at,
   external_state,
   external_review_state,
-  external_check_state
+  external_check_state,
+  conflicted
 FROM changesets
 WHERE %s
 ORDER BY id ASC
@@ -734,7 +788,8 @@ changed AS (
 	external_updated_at   = batch.external_updated_at,
     external_state        = batch.external_state,
     external_review_state = batch.external_review_state,
-    external_check_state  = batch.external_check_state
+    external_check_state  = batch.external_check_state,
+    conflicted            = batch.conflicted
   FROM batch
   WHERE changesets.id = batch.id
   RETURNING changesets.*
@@ -756,7 +811,8 @@ SELECT
   changed.external_updated_at,
   changed.external_state,
   changed.external_review_state,
-  changed.external_check_state
+  changed.external_check_state,
+  changed.conflicted
 FROM changed
 LEFT JOIN batch ON batch.repo_id = changed.repo_id
 AND batch.external_id = changed.external_id
@@ -1103,9 +1159,17 @@ INSERT INTO campaigns (
   updated_at,
   changeset_ids,
   patch_set_id,
-  closed_at
+  closed_at,
+  auto_merge,
+  merge_method,
+  commit_message,
+  commit_author_name,
+  commit_author_email,
+  commit_sign_off,
+  commit_sign,
+  draft_on_code_host
 )
-VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s)
+VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s)
 RETURNING
   id,
   name,
@@ -1118,7 +1182,15 @@ RETURNING
   updated_at,
   changeset_ids,
   patch_set_id,
-  closed_at
+  closed_at,
+  auto_merge,
+  merge_method,
+  commit_message,
+  commit_author_name,
+  commit_author_email,
+  commit_sign_off,
+  commit_sign,
+  draft_on_code_host
 `
 
 func (s *Store) createCampaignQuery(c *campaigns.Campaign) (*sqlf.Query, error) {
@@ -1148,6 +1220,14 @@ func (s *Store) createCampaignQuery(c *campaigns.Campaign) (*sqlf.Query, error)
 		changesetIDs,
 		nullInt64Column(c.PatchSetID),
 		nullTimeColumn(c.ClosedAt),
+		c.AutoMerge,
+		nullStringColumn(string(c.MergeMethod)),
+		nullStringColumn(c.CommitMessage),
+		nullStringColumn(c.CommitAuthorName),
+		nullStringColumn(c.CommitAuthorEmail),
+		c.CommitSignOff,
+		c.CommitSign,
+		c.DraftOnCodeHost,
 	), nil
 }
 
@@ -1179,6 +1259,13 @@ func nullStringColumn(s string) *string {
 	return &s
 }
 
+func nullPatchSetSpecColumn(spec *campaigns.PatchSetSpec) (interface{}, error) {
+	if spec == nil {
+		return nil, nil
+	}
+	return json.Marshal(spec)
+}
+
 // UpdateCampaign updates the given Campaign.
 func (s *Store) UpdateCampaign(ctx context.Context, c *campaigns.Campaign) error {
 	q, err := s.updateCampaignQuery(c)
@@ -1205,8 +1292,16 @@ SET (
   updated_at,
   changeset_ids,
   patch_set_id,
-  closed_at
-) = (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s)
+  closed_at,
+  auto_merge,
+  merge_method,
+  commit_message,
+  commit_author_name,
+  commit_author_email,
+  commit_sign_off,
+  commit_sign,
+  draft_on_code_host
+) = (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s)
 WHERE id = %s
 RETURNING
   id,
@@ -1220,7 +1315,15 @@ RETURNING
   updated_at,
   changeset_ids,
   patch_set_id,
-  closed_at
+  closed_at,
+  auto_merge,
+  merge_method,
+  commit_message,
+  commit_author_name,
+  commit_author_email,
+  commit_sign_off,
+  commit_sign,
+  draft_on_code_host
 `
 
 func (s *Store) updateCampaignQuery(c *campaigns.Campaign) (*sqlf.Query, error) {
@@ -1243,6 +1346,14 @@ func (s *Store) updateCampaignQuery(c *campaigns.Campaign) (*sqlf.Query, error)
 		changesetIDs,
 		nullInt64Column(c.PatchSetID),
 		nullTimeColumn(c.ClosedAt),
+		c.AutoMerge,
+		nullStringColumn(string(c.MergeMethod)),
+		nullStringColumn(c.CommitMessage),
+		nullStringColumn(c.CommitAuthorName),
+		nullStringColumn(c.CommitAuthorEmail),
+		c.CommitSignOff,
+		c.CommitSign,
+		c.DraftOnCodeHost,
 		c.ID,
 	), nil
 }
@@ -1354,7 +1465,15 @@ SELECT
   updated_at,
   changeset_ids,
   patch_set_id,
-  closed_at
+  closed_at,
+  auto_merge,
+  merge_method,
+  commit_message,
+  commit_author_name,
+  commit_author_email,
+  commit_sign_off,
+  commit_sign,
+  draft_on_code_host
 FROM campaigns
 WHERE %s
 LIMIT 1
@@ -1423,7 +1542,15 @@ SELECT
   updated_at,
   changeset_ids,
   patch_set_id,
-  closed_at
+  closed_at,
+  auto_merge,
+  merge_method,
+  commit_message,
+  commit_author_name,
+  commit_author_email,
+  commit_sign_off,
+  commit_sign,
+  draft_on_code_host
 FROM campaigns
 WHERE %s
 ORDER BY id ASC
@@ -1484,17 +1611,24 @@ var createPatchSetQueryFmtstr = `
 INSERT INTO patch_sets (
   created_at,
   updated_at,
-  user_id
+  user_id,
+  spec
 )
-VALUES (%s, %s, %s)
+VALUES (%s, %s, %s, %s)
 RETURNING
   id,
   created_at,
   updated_at,
-  user_id
+  user_id,
+  spec
 `
 
 func (s *Store) createPatchSetQuery(c *campaigns.PatchSet) (*sqlf.Query, error) {
+	spec, err := nullPatchSetSpecColumn(c.Spec)
+	if err != nil {
+		return nil, err
+	}
+
 	if c.CreatedAt.IsZero() {
 		c.CreatedAt = s.now()
 	}
@@ -1508,6 +1642,7 @@ func (s *Store) createPatchSetQuery(c *campaigns.PatchSet) (*sqlf.Query, error)
 		c.CreatedAt,
 		c.UpdatedAt,
 		c.UserID,
+		spec,
 	), nil
 }
 
@@ -1529,23 +1664,31 @@ var updatePatchSetQueryFmtstr = `
 UPDATE patch_sets
 SET (
   updated_at,
-  user_id
-) = (%s, %s)
+  user_id,
+  spec
+) = (%s, %s, %s)
 WHERE id = %s
 RETURNING
   id,
   created_at,
   updated_at,
-  user_id
+  user_id,
+  spec
 `
 
 func (s *Store) updatePatchSetQuery(c *campaigns.PatchSet) (*sqlf.Query, error) {
+	spec, err := nullPatchSetSpecColumn(c.Spec)
+	if err != nil {
+		return nil, err
+	}
+
 	c.UpdatedAt = s.now()
 
 	return sqlf.Sprintf(
 		updatePatchSetQueryFmtstr,
 		c.UpdatedAt,
 		c.UserID,
+		spec,
 		c.ID,
 	), nil
 }
@@ -1594,6 +1737,16 @@ NOT EXISTS (
   WHERE
   campaigns.patch_set_id = patch_sets.id
 )
+AND
+NOT EXISTS (
+  SELECT 1
+  FROM
+  patch_jobs
+  WHERE
+  patch_jobs.patch_set_id = patch_sets.id
+  AND
+  patch_jobs.finished_at IS NULL
+)
 `
 
 // CountPatchSets returns the number of code mods in the database.
@@ -1641,7 +1794,8 @@ SELECT
   id,
   created_at,
   updated_at,
-  user_id
+  user_id,
+  spec
 FROM patch_sets
 WHERE %s
 LIMIT 1
@@ -1740,7 +1894,8 @@ SELECT
   id,
   created_at,
   updated_at,
-  user_id
+  user_id,
+  spec
 FROM patch_sets
 WHERE %s
 ORDER BY id ASC
@@ -2111,19 +2266,21 @@ INSERT INTO changeset_jobs (
   patch_id,
   changeset_id,
   branch,
+  base_commit,
   error,
   started_at,
   finished_at,
   created_at,
   updated_at
 )
-VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s)
+VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s)
 RETURNING
   id,
   campaign_id,
   patch_id,
   changeset_id,
   branch,
+  base_commit,
   error,
   started_at,
   finished_at,
@@ -2146,6 +2303,7 @@ func (s *Store) createChangesetJobQuery(c *campaigns.ChangesetJob) (*sqlf.Query,
 		c.PatchID,
 		nullInt64Column(c.ChangesetID),
 		c.Branch,
+		nullStringColumn(c.BaseCommit),
 		nullStringColumn(c.Error),
 		nullTimeColumn(c.StartedAt),
 		nullTimeColumn(c.FinishedAt),
@@ -2175,11 +2333,12 @@ SET (
   patch_id,
   changeset_id,
   branch,
+  base_commit,
   error,
   started_at,
   finished_at,
   updated_at
-) = (%s, %s, %s, %s, %s, %s, %s, %s)
+) = (%s, %s, %s, %s, %s, %s, %s, %s, %s)
 WHERE id = %s
 RETURNING
   id,
@@ -2187,6 +2346,7 @@ RETURNING
   patch_id,
   changeset_id,
   branch,
+  base_commit,
   error,
   started_at,
   finished_at,
@@ -2203,6 +2363,7 @@ func (s *Store) updateChangesetJobQuery(c *campaigns.ChangesetJob) (*sqlf.Query,
 		c.PatchID,
 		nullInt64Column(c.ChangesetID),
 		c.Branch,
+		nullStringColumn(c.BaseCommit),
 		nullStringColumn(c.Error),
 		nullTimeColumn(c.StartedAt),
 		nullTimeColumn(c.FinishedAt),
@@ -2325,6 +2486,7 @@ SELECT
   patch_id,
   changeset_id,
   branch,
+  base_commit,
   error,
   started_at,
   finished_at,
@@ -2399,6 +2561,7 @@ SELECT
   changeset_jobs.patch_id,
   changeset_jobs.changeset_id,
   changeset_jobs.branch,
+  changeset_jobs.base_commit,
   changeset_jobs.error,
   changeset_jobs.started_at,
   changeset_jobs.finished_at,
@@ -2444,6 +2607,39 @@ func listChangesetJobsQuery(opts *ListChangesetJobsOpts) *sqlf.Query {
 	return sqlf.Sprintf(queryTemplate, sqlf.Join(preds, "\n AND "))
 }
 
+// ListRebaseableChangesetJobs lists the ChangesetJobs that successfully
+// created a changeset on a branch that is still open and belongs to an open
+// campaign.
+func (s *Store) ListRebaseableChangesetJobs(ctx context.Context) ([]*campaigns.ChangesetJob, error) {
+	q := sqlf.Sprintf(
+		listChangesetJobsQueryFmtstrSelect+listRebaseableChangesetJobsQueryFmtstrConditions,
+		campaigns.ChangesetStateOpen,
+	)
+
+	jobs := make([]*campaigns.ChangesetJob, 0)
+	_, _, err := s.query(ctx, q, func(sc scanner) (last, count int64, err error) {
+		var j campaigns.ChangesetJob
+		if err = scanChangesetJob(&j, sc); err != nil {
+			return 0, 0, err
+		}
+		jobs = append(jobs, &j)
+		return j.ID, 1, err
+	})
+	return jobs, err
+}
+
+var listRebaseableChangesetJobsQueryFmtstrConditions = `
+JOIN changesets ON changesets.id = changeset_jobs.changeset_id
+JOIN campaigns ON campaigns.id = changeset_jobs.campaign_id
+WHERE changeset_jobs.finished_at IS NOT NULL
+AND COALESCE(changeset_jobs.error, '') = ''
+AND COALESCE(changeset_jobs.branch, '') != ''
+AND campaigns.closed_at IS NULL
+AND changesets.external_deleted_at IS NULL
+AND changesets.external_state = %s
+ORDER BY changeset_jobs.id ASC
+`
+
 // ResetFailedChangesetJobs resets the Error, StartedAt and FinishedAt fields
 // of the ChangesetJobs belonging to the Campaign with the given ID that
 // resulted in an error.
@@ -2490,6 +2686,664 @@ SET
 WHERE %s
 `
 
+// CreatePatchJob creates the given PatchJob.
+func (s *Store) CreatePatchJob(ctx context.Context, j *campaigns.PatchJob) error {
+	q, err := s.createPatchJobQuery(j)
+	if err != nil {
+		return err
+	}
+
+	return s.exec(ctx, q, func(sc scanner) (last, count int64, err error) {
+		err = scanPatchJob(j, sc)
+		return j.ID, 1, err
+	})
+}
+
+var createPatchJobQueryFmtstr = `
+-- source: enterprise/internal/campaigns/store.go:CreatePatchJob
+INSERT INTO patch_jobs (
+  patch_set_id,
+  repo_id,
+  rev,
+  base_ref,
+  error,
+  started_at,
+  finished_at,
+  created_at,
+  updated_at
+)
+VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s)
+RETURNING
+  id,
+  patch_set_id,
+  repo_id,
+  rev,
+  base_ref,
+  error,
+  started_at,
+  finished_at,
+  created_at,
+  updated_at
+`
+
+func (s *Store) createPatchJobQuery(j *campaigns.PatchJob) (*sqlf.Query, error) {
+	if j.CreatedAt.IsZero() {
+		j.CreatedAt = s.now()
+	}
+
+	if j.UpdatedAt.IsZero() {
+		j.UpdatedAt = j.CreatedAt
+	}
+
+	return sqlf.Sprintf(
+		createPatchJobQueryFmtstr,
+		j.PatchSetID,
+		j.RepoID,
+		j.Rev,
+		j.BaseRef,
+		nullStringColumn(j.Error),
+		nullTimeColumn(j.StartedAt),
+		nullTimeColumn(j.FinishedAt),
+		j.CreatedAt,
+		j.UpdatedAt,
+	), nil
+}
+
+// UpdatePatchJob updates the given PatchJob.
+func (s *Store) UpdatePatchJob(ctx context.Context, j *campaigns.PatchJob) error {
+	q, err := s.updatePatchJobQuery(j)
+	if err != nil {
+		return err
+	}
+
+	return s.exec(ctx, q, func(sc scanner) (last, count int64, err error) {
+		err = scanPatchJob(j, sc)
+		return j.ID, 1, err
+	})
+}
+
+var updatePatchJobQueryFmtstr = `
+-- source: enterprise/internal/campaigns/store.go:UpdatePatchJob
+UPDATE patch_jobs
+SET (
+  patch_set_id,
+  repo_id,
+  rev,
+  base_ref,
+  error,
+  started_at,
+  finished_at,
+  updated_at
+) = (%s, %s, %s, %s, %s, %s, %s, %s)
+WHERE id = %s
+RETURNING
+  id,
+  patch_set_id,
+  repo_id,
+  rev,
+  base_ref,
+  error,
+  started_at,
+  finished_at,
+  created_at,
+  updated_at
+`
+
+func (s *Store) updatePatchJobQuery(j *campaigns.PatchJob) (*sqlf.Query, error) {
+	j.UpdatedAt = s.now()
+
+	return sqlf.Sprintf(
+		updatePatchJobQueryFmtstr,
+		j.PatchSetID,
+		j.RepoID,
+		j.Rev,
+		j.BaseRef,
+		nullStringColumn(j.Error),
+		nullTimeColumn(j.StartedAt),
+		nullTimeColumn(j.FinishedAt),
+		j.UpdatedAt,
+		j.ID,
+	), nil
+}
+
+// GetPatchJobOpts captures the query options needed for getting a PatchJob
+type GetPatchJobOpts struct {
+	ID int64
+}
+
+// GetPatchJob gets a PatchJob matching the given options.
+func (s *Store) GetPatchJob(ctx context.Context, opts GetPatchJobOpts) (*campaigns.PatchJob, error) {
+	q := getPatchJobQuery(&opts)
+
+	var j campaigns.PatchJob
+	err := s.exec(ctx, q, func(sc scanner) (_, _ int64, err error) {
+		return 0, 0, scanPatchJob(&j, sc)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if j.ID == 0 {
+		return nil, ErrNoResults
+	}
+
+	return &j, nil
+}
+
+var getPatchJobsQueryFmtstr = `
+-- source: enterprise/internal/campaigns/store.go:GetPatchJob
+SELECT
+  id,
+  patch_set_id,
+  repo_id,
+  rev,
+  base_ref,
+  error,
+  started_at,
+  finished_at,
+  created_at,
+  updated_at
+FROM patch_jobs
+WHERE %s
+LIMIT 1
+`
+
+func getPatchJobQuery(opts *GetPatchJobOpts) *sqlf.Query {
+	var preds []*sqlf.Query
+	if opts.ID != 0 {
+		preds = append(preds, sqlf.Sprintf("id = %s", opts.ID))
+	}
+
+	if len(preds) == 0 {
+		preds = append(preds, sqlf.Sprintf("TRUE"))
+	}
+
+	return sqlf.Sprintf(getPatchJobsQueryFmtstr, sqlf.Join(preds, "\n AND "))
+}
+
+// ListPatchJobsOpts captures the query options needed for
+// listing patch jobs.
+type ListPatchJobsOpts struct {
+	PatchSetID int64
+	Cursor     int64
+	Limit      int
+}
+
+// ListPatchJobs lists PatchJobs with the given filters.
+func (s *Store) ListPatchJobs(ctx context.Context, opts ListPatchJobsOpts) (js []*campaigns.PatchJob, next int64, err error) {
+	q := listPatchJobsQuery(&opts)
+
+	js = make([]*campaigns.PatchJob, 0, opts.Limit)
+	_, _, err = s.query(ctx, q, func(sc scanner) (last, count int64, err error) {
+		var j campaigns.PatchJob
+		if err = scanPatchJob(&j, sc); err != nil {
+			return 0, 0, err
+		}
+		js = append(js, &j)
+		return j.ID, 1, err
+	})
+
+	if opts.Limit != 0 && len(js) == opts.Limit {
+		next = js[len(js)-1].ID
+		js = js[:len(js)-1]
+	}
+
+	return js, next, err
+}
+
+var listPatchJobsQueryFmtstr = `
+-- source: enterprise/internal/campaigns/store.go:ListPatchJobs
+SELECT
+  id,
+  patch_set_id,
+  repo_id,
+  rev,
+  base_ref,
+  error,
+  started_at,
+  finished_at,
+  created_at,
+  updated_at
+FROM patch_jobs
+WHERE %s
+ORDER BY id ASC
+LIMIT %s
+`
+
+func listPatchJobsQuery(opts *ListPatchJobsOpts) *sqlf.Query {
+	if opts.Limit == 0 {
+		opts.Limit = defaultListLimit
+	}
+	opts.Limit++
+
+	preds := []*sqlf.Query{
+		sqlf.Sprintf("id >= %s", opts.Cursor),
+	}
+
+	if opts.PatchSetID != 0 {
+		preds = append(preds, sqlf.Sprintf("patch_set_id = %s", opts.PatchSetID))
+	}
+
+	return sqlf.Sprintf(
+		listPatchJobsQueryFmtstr,
+		sqlf.Join(preds, "\n AND "),
+		opts.Limit,
+	)
+}
+
+// GetPatchSetStatus gets the campaigns.BackgroundProcessStatus of the
+// PatchJobs computing the Patches of a PatchSet.
+func (s *Store) GetPatchSetStatus(ctx context.Context, id int64) (*campaigns.BackgroundProcessStatus, error) {
+	return s.queryBackgroundProcessStatus(ctx, sqlf.Sprintf(
+		getPatchSetStatusQueryFmtstr,
+		sqlf.Sprintf("patch_set_id = %s", id),
+	))
+}
+
+var getPatchSetStatusQueryFmtstr = `
+-- source: enterprise/internal/campaigns/store.go:GetPatchSetStatus
+SELECT
+  -- canceled is here so that this can be used with scanBackgroundProcessStatus
+  false AS canceled,
+  COUNT(*) AS total,
+  COUNT(*) FILTER (WHERE finished_at IS NULL) AS pending,
+  COUNT(*) FILTER (WHERE finished_at IS NOT NULL) AS completed,
+  array_agg(error) FILTER (WHERE error != '') AS errors
+FROM patch_jobs
+WHERE %s
+LIMIT 1
+`
+
+// ProcessPendingPatchJobs attempts to fetch one pending patch job.
+// A pending job is one that has never been started.
+// If found, 'process' is called. We guarantee that if process is called it will have exclusive global access to
+// the job. All operations on the job should be done using the supplied store as they will run in a transaction.
+// Returning an error will roll back the transaction.
+// NOTE: It should not be called from within an existing transaction
+func (s *Store) ProcessPendingPatchJobs(ctx context.Context, process func(ctx context.Context, s *Store, job campaigns.PatchJob) error) (didRun bool, err error) {
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "starting transaction")
+	}
+	defer tx.Done(&err)
+	q := sqlf.Sprintf(getPendingPatchJobQuery)
+	var job campaigns.PatchJob
+	_, count, err := tx.query(ctx, q, func(sc scanner) (last, count int64, err error) {
+		err = scanPatchJob(&job, sc)
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "scanning patch job row")
+		}
+		return job.ID, 1, nil
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "querying for pending patch job")
+	}
+	if count == 0 {
+		return false, nil
+	}
+	err = process(ctx, tx, job)
+	return true, err
+}
+
+const getPendingPatchJobQuery = `
+UPDATE patch_jobs SET started_at = now() WHERE id = (
+	SELECT id FROM patch_jobs
+	WHERE started_at IS NULL
+	ORDER BY id ASC
+	FOR UPDATE SKIP LOCKED LIMIT 1
+)
+RETURNING id,
+  patch_set_id,
+  repo_id,
+  rev,
+  base_ref,
+  error,
+  started_at,
+  finished_at,
+  created_at,
+  updated_at
+`
+
+// CreateChangesetActionJob creates the given ChangesetActionJob.
+func (s *Store) CreateChangesetActionJob(ctx context.Context, j *campaigns.ChangesetActionJob) error {
+	q, err := s.createChangesetActionJobQuery(j)
+	if err != nil {
+		return err
+	}
+
+	return s.exec(ctx, q, func(sc scanner) (last, count int64, err error) {
+		err = scanChangesetActionJob(j, sc)
+		return j.ID, 1, err
+	})
+}
+
+var createChangesetActionJobQueryFmtstr = `
+-- source: enterprise/internal/campaigns/store.go:CreateChangesetActionJob
+INSERT INTO changeset_action_jobs (
+  campaign_id,
+  changeset_id,
+  user_id,
+  kind,
+  payload,
+  error,
+  started_at,
+  finished_at,
+  created_at,
+  updated_at
+)
+VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s)
+RETURNING
+  id,
+  campaign_id,
+  changeset_id,
+  user_id,
+  kind,
+  payload,
+  error,
+  started_at,
+  finished_at,
+  created_at,
+  updated_at
+`
+
+func (s *Store) createChangesetActionJobQuery(j *campaigns.ChangesetActionJob) (*sqlf.Query, error) {
+	payload, err := json.Marshal(j.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if j.CreatedAt.IsZero() {
+		j.CreatedAt = s.now()
+	}
+
+	if j.UpdatedAt.IsZero() {
+		j.UpdatedAt = j.CreatedAt
+	}
+
+	return sqlf.Sprintf(
+		createChangesetActionJobQueryFmtstr,
+		j.CampaignID,
+		j.ChangesetID,
+		nullInt32Column(j.UserID),
+		j.Kind,
+		payload,
+		nullStringColumn(j.Error),
+		nullTimeColumn(j.StartedAt),
+		nullTimeColumn(j.FinishedAt),
+		j.CreatedAt,
+		j.UpdatedAt,
+	), nil
+}
+
+// UpdateChangesetActionJob updates the given ChangesetActionJob.
+func (s *Store) UpdateChangesetActionJob(ctx context.Context, j *campaigns.ChangesetActionJob) error {
+	q, err := s.updateChangesetActionJobQuery(j)
+	if err != nil {
+		return err
+	}
+
+	return s.exec(ctx, q, func(sc scanner) (last, count int64, err error) {
+		err = scanChangesetActionJob(j, sc)
+		return j.ID, 1, err
+	})
+}
+
+var updateChangesetActionJobQueryFmtstr = `
+-- source: enterprise/internal/campaigns/store.go:UpdateChangesetActionJob
+UPDATE changeset_action_jobs
+SET (
+  campaign_id,
+  changeset_id,
+  user_id,
+  kind,
+  payload,
+  error,
+  started_at,
+  finished_at,
+  updated_at
+) = (%s, %s, %s, %s, %s, %s, %s, %s, %s)
+WHERE id = %s
+RETURNING
+  id,
+  campaign_id,
+  changeset_id,
+  user_id,
+  kind,
+  payload,
+  error,
+  started_at,
+  finished_at,
+  created_at,
+  updated_at
+`
+
+func (s *Store) updateChangesetActionJobQuery(j *campaigns.ChangesetActionJob) (*sqlf.Query, error) {
+	payload, err := json.Marshal(j.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	j.UpdatedAt = s.now()
+
+	return sqlf.Sprintf(
+		updateChangesetActionJobQueryFmtstr,
+		j.CampaignID,
+		j.ChangesetID,
+		nullInt32Column(j.UserID),
+		j.Kind,
+		payload,
+		nullStringColumn(j.Error),
+		nullTimeColumn(j.StartedAt),
+		nullTimeColumn(j.FinishedAt),
+		j.UpdatedAt,
+		j.ID,
+	), nil
+}
+
+// CountChangesetActionJobsOpts captures the query options needed for
+// counting changeset action jobs.
+type CountChangesetActionJobsOpts struct {
+	CampaignID  int64
+	ChangesetID int64
+}
+
+// CountChangesetActionJobs returns the number of ChangesetActionJobs in the
+// database.
+func (s *Store) CountChangesetActionJobs(ctx context.Context, opts CountChangesetActionJobsOpts) (count int64, _ error) {
+	q := countChangesetActionJobsQuery(&opts)
+	return count, s.exec(ctx, q, func(sc scanner) (_, _ int64, err error) {
+		err = sc.Scan(&count)
+		return 0, count, err
+	})
+}
+
+var countChangesetActionJobsQueryFmtstr = `
+-- source: enterprise/internal/campaigns/store.go:CountChangesetActionJobs
+SELECT COUNT(id) FROM changeset_action_jobs WHERE %s
+`
+
+func countChangesetActionJobsQuery(opts *CountChangesetActionJobsOpts) *sqlf.Query {
+	var preds []*sqlf.Query
+	if opts.CampaignID != 0 {
+		preds = append(preds, sqlf.Sprintf("campaign_id = %s", opts.CampaignID))
+	}
+
+	if opts.ChangesetID != 0 {
+		preds = append(preds, sqlf.Sprintf("changeset_id = %s", opts.ChangesetID))
+	}
+
+	if len(preds) == 0 {
+		preds = append(preds, sqlf.Sprintf("TRUE"))
+	}
+
+	return sqlf.Sprintf(countChangesetActionJobsQueryFmtstr, sqlf.Join(preds, "\n AND "))
+}
+
+// ListChangesetActionJobsOpts captures the query options needed for
+// listing changeset action jobs.
+type ListChangesetActionJobsOpts struct {
+	CampaignID  int64
+	ChangesetID int64
+	Cursor      int64
+	Limit       int
+}
+
+// ListChangesetActionJobs lists ChangesetActionJobs with the given filters.
+func (s *Store) ListChangesetActionJobs(ctx context.Context, opts ListChangesetActionJobsOpts) (js []*campaigns.ChangesetActionJob, next int64, err error) {
+	q := listChangesetActionJobsQuery(&opts)
+
+	js = make([]*campaigns.ChangesetActionJob, 0, opts.Limit)
+	_, _, err = s.query(ctx, q, func(sc scanner) (last, count int64, err error) {
+		var j campaigns.ChangesetActionJob
+		if err = scanChangesetActionJob(&j, sc); err != nil {
+			return 0, 0, err
+		}
+		js = append(js, &j)
+		return j.ID, 1, err
+	})
+
+	if opts.Limit != 0 && len(js) == opts.Limit {
+		next = js[len(js)-1].ID
+		js = js[:len(js)-1]
+	}
+
+	return js, next, err
+}
+
+var listChangesetActionJobsQueryFmtstr = `
+-- source: enterprise/internal/campaigns/store.go:ListChangesetActionJobs
+SELECT
+  id,
+  campaign_id,
+  changeset_id,
+  user_id,
+  kind,
+  payload,
+  error,
+  started_at,
+  finished_at,
+  created_at,
+  updated_at
+FROM changeset_action_jobs
+WHERE %s
+ORDER BY id ASC
+LIMIT %s
+`
+
+func listChangesetActionJobsQuery(opts *ListChangesetActionJobsOpts) *sqlf.Query {
+	if opts.Limit == 0 {
+		opts.Limit = defaultListLimit
+	}
+	opts.Limit++
+
+	preds := []*sqlf.Query{
+		sqlf.Sprintf("id >= %s", opts.Cursor),
+	}
+
+	if opts.CampaignID != 0 {
+		preds = append(preds, sqlf.Sprintf("campaign_id = %s", opts.CampaignID))
+	}
+
+	if opts.ChangesetID != 0 {
+		preds = append(preds, sqlf.Sprintf("changeset_id = %s", opts.ChangesetID))
+	}
+
+	return sqlf.Sprintf(
+		listChangesetActionJobsQueryFmtstr,
+		sqlf.Join(preds, "\n AND "),
+		opts.Limit,
+	)
+}
+
+// ProcessPendingChangesetActionJobs attempts to fetch one pending changeset
+// action job. A pending job is one that has never been started.
+// If found, 'process' is called. We guarantee that if process is called it will have exclusive global access to
+// the job.
+// Unlike the other pending jobs, the job is marked as started before process
+// is called and outside of a transaction, so returning an error doesn't
+// make it pending again. Actions such as posting a comment can't be undone,
+// so a job which failed after its action was performed on the codehost must
+// not be retried. process is responsible for saving the outcome in the job.
+// Jobs which never finish, e.g. because repo-updater stopped while running
+// them, are failed by FailStalledChangesetActionJobs.
+func (s *Store) ProcessPendingChangesetActionJobs(ctx context.Context, process func(ctx context.Context, s *Store, job campaigns.ChangesetActionJob) error) (didRun bool, err error) {
+	q := sqlf.Sprintf(getPendingChangesetActionJobQuery)
+	var job campaigns.ChangesetActionJob
+	_, count, err := s.query(ctx, q, func(sc scanner) (last, count int64, err error) {
+		err = scanChangesetActionJob(&job, sc)
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "scanning changeset action job row")
+		}
+		return job.ID, 1, nil
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "querying for pending changeset action job")
+	}
+	if count == 0 {
+		return false, nil
+	}
+	err = process(ctx, s, job)
+	return true, err
+}
+
+const getPendingChangesetActionJobQuery = `
+UPDATE changeset_action_jobs SET started_at = now() WHERE id = (
+	SELECT id FROM changeset_action_jobs
+	WHERE started_at IS NULL
+	ORDER BY id ASC
+	FOR UPDATE SKIP LOCKED LIMIT 1
+)
+RETURNING id,
+  campaign_id,
+  changeset_id,
+  user_id,
+  kind,
+  payload,
+  error,
+  started_at,
+  finished_at,
+  created_at,
+  updated_at
+`
+
+// ChangesetActionJobTimeout is how long a changeset action job may run
+// before FailStalledChangesetActionJobs marks it as failed.
+const ChangesetActionJobTimeout = 30 * time.Minute
+
+// FailStalledChangesetActionJobs marks ChangesetActionJobs that were started
+// more than ChangesetActionJobTimeout ago, but never finished, as failed.
+// This happens when repo-updater stops while running a job. Since started
+// jobs aren't retried, they would otherwise stay running forever.
+func (s *Store) FailStalledChangesetActionJobs(ctx context.Context) error {
+	now := s.now()
+	q := sqlf.Sprintf(
+		failStalledChangesetActionJobsQueryFmtstr,
+		"job timed out before finishing",
+		now,
+		now,
+		now.Add(-ChangesetActionJobTimeout),
+	)
+
+	rows, err := s.db.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+var failStalledChangesetActionJobsQueryFmtstr = `
+-- source: enterprise/internal/campaigns/store.go:FailStalledChangesetActionJobs
+UPDATE changeset_action_jobs
+SET
+  error = %s,
+  finished_at = %s,
+  updated_at = %s
+WHERE
+  started_at < %s
+AND
+  finished_at IS NULL
+`
+
 // GetChangesetExternalIDs allows us to find the external ids for pull requests based on
 // a slice of head refs. We need this in order to match incoming webhooks to pull requests as
 // the only information they provide is the remote branch
@@ -2595,6 +3449,7 @@ func scanChangeset(t *campaigns.Changeset, s scanner) error {
 		&dbutil.NullString{S: &externalState},
 		&dbutil.NullString{S: &externamReviewState},
 		&dbutil.NullString{S: &externalCheckState},
+		&t.Conflicted,
 	)
 	if err != nil {
 		return errors.Wrap(err, "scanning changeset")
@@ -2609,6 +3464,10 @@ func scanChangeset(t *campaigns.Changeset, s scanner) error {
 		t.Metadata = new(github.PullRequest)
 	case bitbucketserver.ServiceType:
 		t.Metadata = new(bitbucketserver.PullRequest)
+	case gitlab.ServiceType:
+		t.Metadata = new(gitlab.MergeRequest)
+	case bitbucketcloud.ServiceType:
+		t.Metadata = new(bitbucketcloud.PullRequest)
 	default:
 		return errors.New("unknown external service type")
 	}
@@ -2662,11 +3521,29 @@ func scanCampaign(c *campaigns.Campaign, s scanner) error {
 		&dbutil.JSONInt64Set{Set: &c.ChangesetIDs},
 		&dbutil.NullInt64{N: &c.PatchSetID},
 		&dbutil.NullTime{Time: &c.ClosedAt},
+		&c.AutoMerge,
+		&dbutil.NullString{S: (*string)(&c.MergeMethod)},
+		&dbutil.NullString{S: &c.CommitMessage},
+		&dbutil.NullString{S: &c.CommitAuthorName},
+		&dbutil.NullString{S: &c.CommitAuthorEmail},
+		&c.CommitSignOff,
+		&c.CommitSign,
+		&c.DraftOnCodeHost,
 	)
 }
 
 func scanPatchSet(c *campaigns.PatchSet, s scanner) error {
-	return s.Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt, &c.UserID)
+	var spec []byte
+	if err := s.Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt, &c.UserID, &spec); err != nil {
+		return err
+	}
+
+	c.Spec = nil
+	if spec == nil {
+		return nil
+	}
+	c.Spec = new(campaigns.PatchSetSpec)
+	return json.Unmarshal(spec, c.Spec)
 }
 
 func scanPatch(c *campaigns.Patch, s scanner) error {
@@ -2689,6 +3566,7 @@ func scanChangesetJob(c *campaigns.ChangesetJob, s scanner) error {
 		&c.PatchID,
 		&dbutil.NullInt64{N: &c.ChangesetID},
 		&c.Branch,
+		&dbutil.NullString{S: &c.BaseCommit},
 		&dbutil.NullString{S: &c.Error},
 		&dbutil.NullTime{Time: &c.StartedAt},
 		&dbutil.NullTime{Time: &c.FinishedAt},
@@ -2697,6 +3575,44 @@ func scanChangesetJob(c *campaigns.ChangesetJob, s scanner) error {
 	)
 }
 
+func scanPatchJob(j *campaigns.PatchJob, s scanner) error {
+	return s.Scan(
+		&j.ID,
+		&j.PatchSetID,
+		&j.RepoID,
+		&j.Rev,
+		&j.BaseRef,
+		&dbutil.NullString{S: &j.Error},
+		&dbutil.NullTime{Time: &j.StartedAt},
+		&dbutil.NullTime{Time: &j.FinishedAt},
+		&j.CreatedAt,
+		&j.UpdatedAt,
+	)
+}
+
+func scanChangesetActionJob(j *campaigns.ChangesetActionJob, s scanner) error {
+	var payload []byte
+	err := s.Scan(
+		&j.ID,
+		&j.CampaignID,
+		&j.ChangesetID,
+		&dbutil.NullInt32{N: &j.UserID},
+		&j.Kind,
+		&payload,
+		&dbutil.NullString{S: &j.Error},
+		&dbutil.NullTime{Time: &j.StartedAt},
+		&dbutil.NullTime{Time: &j.FinishedAt},
+		&j.CreatedAt,
+		&j.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	j.Payload = campaigns.ChangesetActionPayload{}
+	return json.Unmarshal(payload, &j.Payload)
+}
+
 func scanBackgroundProcessStatus(b *campaigns.BackgroundProcessStatus, s scanner) error {
 	return s.Scan(
 		&b.Canceled,
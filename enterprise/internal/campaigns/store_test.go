@@ -74,6 +74,17 @@ func testStore(db *sql.DB) func(*testing.T) {
 						c.ClosedAt = time.Time{}
 					}
 
+					if i == 1 {
+						c.AutoMerge = true
+						c.MergeMethod = cmpgn.ChangesetMergeMethodSquash
+						c.CommitMessage = "{{.CampaignName}}"
+						c.CommitAuthorName = "Jane Doe"
+						c.CommitAuthorEmail = "jane@example.com"
+						c.CommitSignOff = true
+						c.CommitSign = true
+						c.DraftOnCodeHost = true
+					}
+
 					if i%2 == 0 {
 						c.NamespaceOrgID = 23
 					} else {
@@ -277,6 +288,10 @@ func testStore(db *sql.DB) func(*testing.T) {
 					c.Description += "-updated"
 					c.AuthorID++
 					c.ClosedAt = c.ClosedAt.Add(5 * time.Second)
+					c.AutoMerge = !c.AutoMerge
+					c.MergeMethod = cmpgn.ChangesetMergeMethodRebase
+					c.CommitMessage = "updated"
+					c.CommitSignOff = !c.CommitSignOff
 
 					if c.NamespaceUserID != 0 {
 						c.NamespaceUserID++
@@ -1257,12 +1272,79 @@ func testStore(db *sql.DB) func(*testing.T) {
 			}
 		})
 
+		t.Run("ListChangesetMergeCandidates", func(t *testing.T) {
+			var cs []*cmpgn.Changeset
+			for i := 0; i < 4; i++ {
+				c := &cmpgn.Changeset{
+					RepoID:              repo.ID,
+					ExternalID:          fmt.Sprintf("merge-candidate-%d", i),
+					ExternalServiceType: "github",
+					ExternalState:       cmpgn.ChangesetStateOpen,
+					ExternalReviewState: cmpgn.ChangesetReviewStateApproved,
+					ExternalCheckState:  cmpgn.ChangesetCheckStatePassed,
+				}
+				cs = append(cs, c)
+			}
+			// Not approved
+			cs[2].ExternalReviewState = cmpgn.ChangesetReviewStatePending
+			// Checks failed
+			cs[3].ExternalCheckState = cmpgn.ChangesetCheckStateFailed
+
+			if err := s.CreateChangesets(ctx, cs...); err != nil {
+				t.Fatal(err)
+			}
+
+			var campaignIDs []int64
+			for i, autoMerge := range []bool{true, false} {
+				c := &cmpgn.Campaign{
+					Name:           fmt.Sprintf("Merge candidates %d", i),
+					AuthorID:       23,
+					NamespaceOrgID: 23,
+					AutoMerge:      autoMerge,
+					MergeMethod:    cmpgn.ChangesetMergeMethodSquash,
+				}
+				if err := s.CreateCampaign(ctx, c); err != nil {
+					t.Fatal(err)
+				}
+				campaignIDs = append(campaignIDs, c.ID)
+			}
+
+			// Only cs[1] belongs to the campaign that doesn't auto-merge.
+			cs[0].CampaignIDs = []int64{campaignIDs[0]}
+			cs[1].CampaignIDs = []int64{campaignIDs[1]}
+			cs[2].CampaignIDs = []int64{campaignIDs[0]}
+			cs[3].CampaignIDs = []int64{campaignIDs[0]}
+			if err := s.UpdateChangesets(ctx, cs...); err != nil {
+				t.Fatal(err)
+			}
+
+			have, err := s.ListChangesetMergeCandidates(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := []cmpgn.ChangesetMergeCandidate{
+				{ChangesetID: cs[0].ID, MergeMethod: cmpgn.ChangesetMergeMethodSquash},
+			}
+			if diff := cmp.Diff(want, have); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
 		t.Run("PatchSets", func(t *testing.T) {
 			patchSets := make([]*cmpgn.PatchSet, 0, 3)
 
 			t.Run("Create", func(t *testing.T) {
 				for i := 0; i < cap(patchSets); i++ {
 					c := &cmpgn.PatchSet{UserID: 999}
+					if i == 0 {
+						c.Spec = &cmpgn.PatchSetSpec{
+							Query:           "repo:github.com/sourcegraph/sourcegraph",
+							MatchTemplate:   "fmt.Sprintf(\"%d\", :[v])",
+							RewriteTemplate: "strconv.Itoa(:[v])",
+							FileExtension:   "go",
+						}
+					}
 
 					want := c.Clone()
 					have := c
@@ -1803,9 +1885,10 @@ func testStore(db *sql.DB) func(*testing.T) {
 
 		t.Run("PatchSet DeleteExpired", func(t *testing.T) {
 			tests := []struct {
-				hasCampaign bool
-				createdAt   time.Time
-				wantDeleted bool
+				hasCampaign        bool
+				hasPendingPatchJob bool
+				createdAt          time.Time
+				wantDeleted        bool
 			}{
 				{
 					hasCampaign: false,
@@ -1827,6 +1910,11 @@ func testStore(db *sql.DB) func(*testing.T) {
 					createdAt:   now.Add(-500 * time.Minute),
 					wantDeleted: false,
 				},
+				{
+					hasPendingPatchJob: true,
+					createdAt:          now.Add(-500 * time.Minute),
+					wantDeleted:        false,
+				},
 			}
 
 			for _, tc := range tests {
@@ -1852,6 +1940,20 @@ func testStore(db *sql.DB) func(*testing.T) {
 					}
 				}
 
+				if tc.hasPendingPatchJob {
+					j := &cmpgn.PatchJob{
+						PatchSetID: patchSet.ID,
+						RepoID:     repo.ID,
+						Rev:        "deadbeef",
+						BaseRef:    "refs/heads/master",
+					}
+
+					err := s.CreatePatchJob(ctx, j)
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+
 				err = s.DeleteExpiredPatchSets(ctx)
 				if err != nil {
 					t.Fatal(err)
@@ -1872,6 +1974,242 @@ func testStore(db *sql.DB) func(*testing.T) {
 			}
 		})
 
+		t.Run("PatchJobs", func(t *testing.T) {
+			patchSet := &cmpgn.PatchSet{UserID: 999, Spec: &cmpgn.PatchSetSpec{Query: "repo:foo", MatchTemplate: "foo"}}
+			if err := s.CreatePatchSet(ctx, patchSet); err != nil {
+				t.Fatal(err)
+			}
+
+			patchJobs := make([]*cmpgn.PatchJob, 0, 3)
+
+			t.Run("Create", func(t *testing.T) {
+				for i := 0; i < cap(patchJobs); i++ {
+					j := &cmpgn.PatchJob{
+						PatchSetID: patchSet.ID,
+						RepoID:     api.RepoID(i + 1),
+						Rev:        api.CommitID("deadbeef"),
+						BaseRef:    "refs/heads/master",
+					}
+
+					want := j.Clone()
+					have := j
+
+					err := s.CreatePatchJob(ctx, have)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					if have.ID == 0 {
+						t.Fatal("ID should not be zero")
+					}
+
+					want.ID = have.ID
+					want.CreatedAt = now
+					want.UpdatedAt = now
+
+					if diff := cmp.Diff(have, want); diff != "" {
+						t.Fatal(diff)
+					}
+
+					patchJobs = append(patchJobs, j)
+				}
+			})
+
+			t.Run("Get", func(t *testing.T) {
+				t.Run("ByID", func(t *testing.T) {
+					want := patchJobs[0]
+
+					have, err := s.GetPatchJob(ctx, GetPatchJobOpts{ID: want.ID})
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					if diff := cmp.Diff(have, want); diff != "" {
+						t.Fatal(diff)
+					}
+				})
+
+				t.Run("NoResults", func(t *testing.T) {
+					_, have := s.GetPatchJob(ctx, GetPatchJobOpts{ID: 0xdeadbeef})
+					want := ErrNoResults
+
+					if have != want {
+						t.Fatalf("have err %v, want %v", have, want)
+					}
+				})
+			})
+
+			t.Run("List", func(t *testing.T) {
+				for i := 1; i <= len(patchJobs); i++ {
+					opts := ListPatchJobsOpts{PatchSetID: patchSet.ID, Limit: i}
+
+					have, _, err := s.ListPatchJobs(ctx, opts)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					if diff := cmp.Diff(have, patchJobs[:i]); diff != "" {
+						t.Fatalf("opts: %+v, diff: %s", opts, diff)
+					}
+				}
+			})
+
+			t.Run("Status", func(t *testing.T) {
+				status, err := s.GetPatchSetStatus(ctx, patchSet.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := &cmpgn.BackgroundProcessStatus{
+					Total:        int32(len(patchJobs)),
+					Pending:      int32(len(patchJobs)),
+					ProcessState: cmpgn.BackgroundProcessStateProcessing,
+				}
+				if diff := cmp.Diff(status, want); diff != "" {
+					t.Fatal(diff)
+				}
+			})
+
+			t.Run("Update", func(t *testing.T) {
+				for i, j := range patchJobs {
+					j.StartedAt = now
+					j.FinishedAt = now
+					if i == 0 {
+						j.Error = "replacer request failed"
+					}
+
+					want := j.Clone()
+					have := j
+
+					if err := s.UpdatePatchJob(ctx, have); err != nil {
+						t.Fatal(err)
+					}
+
+					if diff := cmp.Diff(have, want); diff != "" {
+						t.Fatal(diff)
+					}
+				}
+
+				status, err := s.GetPatchSetStatus(ctx, patchSet.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := &cmpgn.BackgroundProcessStatus{
+					Total:         int32(len(patchJobs)),
+					Completed:     int32(len(patchJobs)),
+					ProcessState:  cmpgn.BackgroundProcessStateErrored,
+					ProcessErrors: []string{"replacer request failed"},
+				}
+				if diff := cmp.Diff(status, want); diff != "" {
+					t.Fatal(diff)
+				}
+			})
+		})
+
+		t.Run("ChangesetActionJobs", func(t *testing.T) {
+			actionJobs := make([]*cmpgn.ChangesetActionJob, 0, 3)
+
+			t.Run("Create", func(t *testing.T) {
+				for i := 0; i < cap(actionJobs); i++ {
+					j := &cmpgn.ChangesetActionJob{
+						CampaignID:  1,
+						ChangesetID: int64(i + 1),
+						UserID:      int32(i),
+						Kind:        cmpgn.ChangesetActionKindLabel,
+						Payload: cmpgn.ChangesetActionPayload{
+							AddLabels:    []string{"campaign"},
+							RemoveLabels: []string{"stale"},
+						},
+					}
+
+					want := j.Clone()
+					have := j
+
+					err := s.CreateChangesetActionJob(ctx, have)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					if have.ID == 0 {
+						t.Fatal("ID should not be zero")
+					}
+
+					want.ID = have.ID
+					want.CreatedAt = now
+					want.UpdatedAt = now
+
+					if diff := cmp.Diff(have, want); diff != "" {
+						t.Fatal(diff)
+					}
+
+					actionJobs = append(actionJobs, j)
+				}
+			})
+
+			t.Run("Count", func(t *testing.T) {
+				count, err := s.CountChangesetActionJobs(ctx, CountChangesetActionJobsOpts{CampaignID: 1})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if have, want := count, int64(len(actionJobs)); have != want {
+					t.Fatalf("have count: %d, want: %d", have, want)
+				}
+
+				count, err = s.CountChangesetActionJobs(ctx, CountChangesetActionJobsOpts{ChangesetID: actionJobs[0].ChangesetID})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if have, want := count, int64(1); have != want {
+					t.Fatalf("have count: %d, want: %d", have, want)
+				}
+			})
+
+			t.Run("List", func(t *testing.T) {
+				for i := 1; i <= len(actionJobs); i++ {
+					opts := ListChangesetActionJobsOpts{CampaignID: 1, Limit: i}
+
+					have, next, err := s.ListChangesetActionJobs(ctx, opts)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					if diff := cmp.Diff(have, actionJobs[:i]); diff != "" {
+						t.Fatalf("opts: %+v, diff: %s", opts, diff)
+					}
+
+					var wantNext int64
+					if i < len(actionJobs) {
+						wantNext = actionJobs[i].ID
+					}
+					if next != wantNext {
+						t.Fatalf("opts: %+v: have next %v, want %v", opts, next, wantNext)
+					}
+				}
+			})
+
+			t.Run("Update", func(t *testing.T) {
+				for i, j := range actionJobs {
+					j.StartedAt = now
+					j.FinishedAt = now
+					if i == 0 {
+						j.Error = "label not found"
+					}
+
+					want := j.Clone()
+					have := j
+
+					if err := s.UpdateChangesetActionJob(ctx, have); err != nil {
+						t.Fatal(err)
+					}
+
+					if diff := cmp.Diff(have, want); diff != "" {
+						t.Fatal(diff)
+					}
+				}
+			})
+		})
+
 		t.Run("ChangesetJobs", func(t *testing.T) {
 			changesetJobs := make([]*cmpgn.ChangesetJob, 0, 3)
 
@@ -2689,6 +3027,102 @@ func testProcessChangesetJob(db *sql.DB) func(*testing.T) {
 				t.Errorf("Want %d, got %d", 1, rc)
 			}
 		})
+
+		t.Run("GetPendingChangesetActionJobIsNotRetried", func(t *testing.T) {
+			tx := dbtest.NewTx(t, db)
+			s := NewStoreWithClock(tx, clock)
+
+			process := func(ctx context.Context, s *Store, job cmpgn.ChangesetActionJob) error {
+				return errors.New("failed after commenting")
+			}
+
+			job := &cmpgn.ChangesetActionJob{
+				CampaignID:  campaign.ID,
+				ChangesetID: 1,
+				UserID:      user.ID,
+				Kind:        cmpgn.ChangesetActionKindComment,
+				Payload:     cmpgn.ChangesetActionPayload{Body: "comment"},
+			}
+			if err := s.CreateChangesetActionJob(ctx, job); err != nil {
+				t.Fatal(err)
+			}
+
+			ran, err := s.ProcessPendingChangesetActionJobs(ctx, process)
+			if err == nil || err.Error() != "failed after commenting" {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !ran {
+				t.Fatalf("process function should have run")
+			}
+
+			// The job must not be pending again, otherwise the comment would
+			// be posted twice.
+			ran, err = s.ProcessPendingChangesetActionJobs(ctx, process)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ran {
+				t.Fatalf("process function should not have run again")
+			}
+		})
+
+		t.Run("FailStalledChangesetActionJobs", func(t *testing.T) {
+			tx := dbtest.NewTx(t, db)
+
+			job := &cmpgn.ChangesetActionJob{
+				CampaignID:  campaign.ID,
+				ChangesetID: 1,
+				UserID:      user.ID,
+				Kind:        cmpgn.ChangesetActionKindComment,
+				Payload:     cmpgn.ChangesetActionPayload{Body: "comment"},
+			}
+			if err := NewStoreWithClock(tx, clock).CreateChangesetActionJob(ctx, job); err != nil {
+				t.Fatal(err)
+			}
+
+			// Simulate repo-updater stopping while the job runs.
+			process := func(ctx context.Context, s *Store, job cmpgn.ChangesetActionJob) error {
+				return nil
+			}
+			if _, err := NewStoreWithClock(tx, clock).ProcessPendingChangesetActionJobs(ctx, process); err != nil {
+				t.Fatal(err)
+			}
+
+			loadJob := func() *cmpgn.ChangesetActionJob {
+				t.Helper()
+				js, _, err := NewStoreWithClock(tx, clock).ListChangesetActionJobs(ctx, ListChangesetActionJobsOpts{CampaignID: campaign.ID})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(js) != 1 {
+					t.Fatalf("want 1 job, have %d", len(js))
+				}
+				return js[0]
+			}
+
+			for _, tc := range []struct {
+				name    string
+				now     time.Time
+				stalled bool
+			}{
+				{name: "running", now: time.Now()},
+				{name: "stalled", now: time.Now().Add(ChangesetActionJobTimeout + time.Minute), stalled: true},
+			} {
+				now := tc.now.UTC().Truncate(time.Microsecond)
+				s := NewStoreWithClock(tx, func() time.Time { return now })
+				if err := s.FailStalledChangesetActionJobs(ctx); err != nil {
+					t.Fatal(err)
+				}
+
+				have := loadJob()
+				if have.FinishedAt.IsZero() == tc.stalled {
+					t.Fatalf("%s: unexpected finished at %v", tc.name, have.FinishedAt)
+				}
+				if tc.stalled && have.Error == "" {
+					t.Fatalf("%s: stalled job has no error", tc.name)
+				}
+			}
+		})
 	}
 }
 
@@ -11,6 +11,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/campaigns"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/ratelimit"
 )
 
 // A ChangesetSyncer periodically syncs metadata of changesets
@@ -71,6 +72,13 @@ func (s *ChangesetSyncer) Run(ctx context.Context) {
 	var next scheduledSync
 	var ok bool
 
+	// Syncs run in their own goroutine, so that a background sync waiting
+	// for the rate limit budget doesn't hold up this loop. At most one sync
+	// per priority runs at a time, so high priority syncs don't queue up
+	// behind a waiting background sync.
+	running := make(map[priority]bool)
+	done := make(chan priority)
+
 	// NOTE: All mutations of the queue should be done is this loop as operations on the queue
 	// are not safe for concurrent use
 	for {
@@ -78,7 +86,7 @@ func (s *ChangesetSyncer) Run(ctx context.Context) {
 		var timerChan <-chan time.Time
 		next, ok = s.queue.Peek()
 
-		if ok {
+		if ok && !running[next.priority] {
 			// Queue isn't empty
 			if next.priority == priorityHigh {
 				// Fire ASAP
@@ -104,13 +112,25 @@ func (s *ChangesetSyncer) Run(ctx context.Context) {
 			}
 			s.queue.Upsert(schedule...)
 		case <-timerChan:
-			err := s.syncFunc(ctx, next.changesetID)
-			if err != nil {
-				log15.Error("Syncing changeset", "err", err)
-				// We'll continue and remove it as it'll get retried on next schedule
-			}
-			// Remove item now that it has been processed
+			// Remove item now that it is being processed. If it fails, it'll
+			// get retried on next schedule.
 			s.queue.Remove(next.changesetID)
+			running[next.priority] = true
+			go func(next scheduledSync) {
+				err := s.syncFunc(ratelimit.WithPriority(ctx, next.priority.rateLimitPriority()), next.changesetID)
+				if err != nil {
+					log15.Error("Syncing changeset", "err", err)
+				}
+				select {
+				case done <- next.priority:
+				case <-ctx.Done():
+				}
+			}(next)
+		case p := <-done:
+			if timer != nil {
+				timer.Stop()
+			}
+			running[p] = false
 		case ids := <-s.priorityNotify:
 			if timer != nil {
 				timer.Stop()
@@ -509,6 +529,16 @@ const (
 	priorityHigh
 )
 
+// rateLimitPriority returns the priority with which a sync draws from the
+// rate limit budget of the code host. High priority syncs are requested by
+// users, so they don't wait for the budget.
+func (p priority) rateLimitPriority() ratelimit.Priority {
+	if p == priorityHigh {
+		return ratelimit.PriorityInteractive
+	}
+	return ratelimit.PriorityBackground
+}
+
 // A SourceChangesets groups *repos.Changesets together with the
 // repos.ChangesetSource that can be used to modify the changesets.
 type SourceChangesets struct {
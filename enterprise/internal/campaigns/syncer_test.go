@@ -264,6 +264,54 @@ func TestSyncerRun(t *testing.T) {
 		}
 	})
 
+	t.Run("Priority not blocked by waiting background sync", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		now := time.Now()
+		store := MockSyncStore{
+			listChangesetSyncData: func(ctx context.Context) ([]campaigns.ChangesetSyncData, error) {
+				return []campaigns.ChangesetSyncData{
+					{
+						ChangesetID:       1,
+						UpdatedAt:         now.Add(-2 * maxSyncDelay),
+						LatestEvent:       now.Add(-2 * maxSyncDelay),
+						ExternalUpdatedAt: now.Add(-2 * maxSyncDelay),
+					},
+				}, nil
+			},
+		}
+		backgroundStarted := make(chan struct{})
+		prioritySynced := make(chan struct{})
+		syncFunc := func(ctx context.Context, id int64) error {
+			if id == 1 {
+				// Wait for the rate limit budget, which doesn't come.
+				close(backgroundStarted)
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			close(prioritySynced)
+			return nil
+		}
+		syncer := &ChangesetSyncer{
+			Store:                   store,
+			ComputeScheduleInterval: 10 * time.Minute,
+			syncFunc:                syncFunc,
+			priorityNotify:          make(chan []int64, 1),
+		}
+		go syncer.Run(ctx)
+
+		select {
+		case <-backgroundStarted:
+		case <-time.After(50 * time.Millisecond):
+			t.Fatal("Background sync not called")
+		}
+		syncer.priorityNotify <- []int64{2}
+		select {
+		case <-prioritySynced:
+		case <-time.After(50 * time.Millisecond):
+			t.Fatal("Priority sync blocked by background sync")
+		}
+	})
 }
 
 type MockSyncStore struct {
@@ -2,6 +2,7 @@ package campaigns
 
 import (
 	"context"
+	"crypto/subtle"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -22,6 +23,8 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
 	bbs "github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
+	"github.com/sourcegraph/sourcegraph/internal/repoupdater"
 	"github.com/sourcegraph/sourcegraph/schema"
 )
 
@@ -79,6 +82,8 @@ func extractExternalServiceID(extSvc *repos.ExternalService) (string, error) {
 		serviceID = c.Url
 	case *schema.BitbucketServerConnection:
 		serviceID = c.Url
+	case *schema.GitLabConnection:
+		serviceID = c.Url
 	}
 	if serviceID == "" {
 		return "", errors.New("could not determine service id")
@@ -92,6 +97,30 @@ func extractExternalServiceID(extSvc *repos.ExternalService) (string, error) {
 	return extsvc.NormalizeBaseURL(u).String(), nil
 }
 
+// enqueueChangesetSync enqueues the changeset of pr for syncing, e.g. when a
+// webhook event doesn't contain the event itself.
+func (h Webhook) enqueueChangesetSync(ctx context.Context, externalServiceID string, pr PR) error {
+	r, err := h.getRepoForPR(ctx, h.Store, pr, externalServiceID)
+	if err != nil {
+		log15.Debug("Webhook event could not be matched to repo", "err", err)
+		return nil
+	}
+
+	cs, err := h.Store.GetChangeset(ctx, GetChangesetOpts{
+		RepoID:              r.ID,
+		ExternalID:          strconv.FormatInt(pr.ID, 10),
+		ExternalServiceType: h.ServiceType,
+	})
+	if err != nil {
+		if err == ErrNoResults {
+			err = nil // Nothing to do
+		}
+		return err
+	}
+
+	return repoupdater.DefaultClient.EnqueueChangesetSync(ctx, []int64{cs.ID})
+}
+
 func (h Webhook) upsertChangesetEvent(
 	ctx context.Context,
 	externalServiceID string,
@@ -184,6 +213,13 @@ type BitbucketServerWebhook struct {
 	Name string
 }
 
+// GitLabWebhook receives GitLab project webhook events that are relevant to
+// campaigns, normalizes those events into ChangesetEvents and upserts them
+// to the database.
+type GitLabWebhook struct {
+	*Webhook
+}
+
 func NewGitHubWebhook(store *Store, repos repos.Store, now func() time.Time) *GitHubWebhook {
 	return &GitHubWebhook{&Webhook{store, repos, now, github.ServiceType}}
 }
@@ -195,6 +231,10 @@ func NewBitbucketServerWebhook(store *Store, repos repos.Store, now func() time.
 	}
 }
 
+func NewGitLabWebhook(store *Store, repos repos.Store, now func() time.Time) *GitLabWebhook {
+	return &GitLabWebhook{&Webhook{store, repos, now, gitlab.ServiceType}}
+}
+
 // ServeHTTP implements the http.Handler interface.
 func (h *GitHubWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	e, extSvc, httpErr := h.parseEvent(r)
@@ -884,6 +924,131 @@ func (h *BitbucketServerWebhook) convertEvent(theirs interface{}) (pr PR, ours i
 	return
 }
 
+// ServeHTTP implements the http.Handler interface.
+func (h *GitLabWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e, extSvc, hErr := h.parseEvent(r)
+	if hErr != nil {
+		respond(w, hErr.code, hErr)
+		return
+	}
+
+	externalServiceID, err := extractExternalServiceID(extSvc)
+	if err != nil {
+		respond(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// The events of merge request webhooks are recorded as system notes, which
+	// the payloads don't include. Syncing the notes records them, with the same
+	// keys as all later syncs.
+	if mr, ok := e.(*gitlab.MergeRequestEvent); ok && mr.RecordedAsNote() {
+		pr := PR{ID: int64(mr.ObjectAttributes.IID), RepoExternalID: strconv.Itoa(mr.Project.ID)}
+		if err := h.enqueueChangesetSync(r.Context(), externalServiceID, pr); err != nil {
+			respond(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	pr, ev := h.convertEvent(e)
+	if pr == (PR{}) || ev == nil {
+		log15.Debug("Dropping GitLab webhook event", "type", fmt.Sprintf("%T", e))
+		respond(w, http.StatusOK, nil) // Nothing to do
+		return
+	}
+
+	if err := h.upsertChangesetEvent(r.Context(), externalServiceID, pr, ev); err != nil {
+		respond(w, http.StatusInternalServerError, err)
+	}
+}
+
+func (h *GitLabWebhook) parseEvent(r *http.Request) (interface{}, *repos.ExternalService, *httpError) {
+	payload, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, nil, &httpError{http.StatusInternalServerError, err}
+	}
+
+	args := repos.StoreListExternalServicesArgs{Kinds: []string{"GITLAB"}}
+	es, err := h.Repos.ListExternalServices(r.Context(), args)
+	if err != nil {
+		return nil, nil, &httpError{http.StatusInternalServerError, err}
+	}
+
+	// 🚨 SECURITY: GitLab doesn't sign webhook payloads, but sends the secret
+	// token it was configured with. If it doesn't match any of the secrets in
+	// the GitLab external services config, we return a 401 to the client.
+	token := gitlab.WebhookToken(r)
+
+	var extSvc *repos.ExternalService
+	for _, e := range es {
+		c, _ := e.Configuration()
+		con, ok := c.(*schema.GitLabConnection)
+		if !ok {
+			continue
+		}
+
+		for _, hook := range con.Webhooks {
+			if hook.Secret != "" && subtle.ConstantTimeCompare([]byte(token), []byte(hook.Secret)) == 1 {
+				extSvc = e
+				break
+			}
+		}
+		if extSvc != nil {
+			break
+		}
+	}
+
+	if extSvc == nil {
+		return nil, nil, &httpError{http.StatusUnauthorized, nil}
+	}
+
+	e, err := gitlab.ParseWebhookEvent(gitlab.WebhookEventType(r), payload)
+	if err != nil {
+		return nil, nil, &httpError{http.StatusBadRequest, err}
+	}
+	return e, extSvc, nil
+}
+
+func (h *GitLabWebhook) convertEvent(theirs interface{}) (pr PR, ours interface{ Key() string }) {
+	log15.Debug("GitLab webhook received", "type", fmt.Sprintf("%T", theirs))
+
+	switch e := theirs.(type) {
+	case *gitlab.NoteEvent:
+		n := e.Note()
+		if n == nil {
+			return
+		}
+		ev := n.ToEvent()
+		if ev == nil {
+			return
+		}
+		pr := PR{ID: int64(e.MergeRequest.IID), RepoExternalID: strconv.Itoa(e.Project.ID)}
+		return pr, ev
+
+	case *gitlab.PipelineEvent:
+		if e.MergeRequest == nil {
+			return
+		}
+		pr := PR{ID: int64(e.MergeRequest.IID), RepoExternalID: strconv.Itoa(e.Project.ID)}
+		return pr, h.pipeline(e)
+	}
+
+	return
+}
+
+func (h *GitLabWebhook) pipeline(e *gitlab.PipelineEvent) *gitlab.Pipeline {
+	return &gitlab.Pipeline{
+		ID:        e.ObjectAttributes.ID,
+		SHA:       e.ObjectAttributes.SHA,
+		Ref:       e.ObjectAttributes.Ref,
+		Status:    e.ObjectAttributes.Status,
+		WebURL:    fmt.Sprintf("%s/pipelines/%d", e.Project.WebURL, e.ObjectAttributes.ID),
+		CreatedAt: e.ObjectAttributes.CreatedAt.Time,
+		// The payload has no update time, so we use the time the status
+		// change was received at.
+		UpdatedAt: h.Now(),
+	}
+}
+
 type httpError struct {
 	code int
 	err  error
@@ -25,6 +25,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/repo-updater/repos"
 	"github.com/sourcegraph/sourcegraph/internal/campaigns"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
 	"github.com/sourcegraph/sourcegraph/internal/httptestutil"
 	"github.com/sourcegraph/sourcegraph/internal/rcache"
@@ -375,3 +376,97 @@ func parseTimestamp(t testing.TB, ts string) time.Time {
 
 	return timestamp
 }
+
+func TestGitLabWebhook(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	clock := func() time.Time { return now }
+
+	repoStore := new(repos.FakeStore)
+	err := repoStore.UpsertExternalServices(ctx, &repos.ExternalService{
+		Kind:        "GITLAB",
+		DisplayName: "GitLab",
+		Config: marshalJSON(t, &schema.GitLabConnection{
+			Url:      "https://gitlab.com",
+			Token:    "token",
+			Webhooks: []*schema.GitLabWebhook{{Secret: "secret"}},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewGitLabWebhook(nil, repoStore, clock)
+
+	request := func(eventType, token, payload string) *http.Request {
+		req := httptest.NewRequest("POST", "/.api/gitlab-webhooks", strings.NewReader(payload))
+		req.Header.Set("X-Gitlab-Event", eventType)
+		if token != "" {
+			req.Header.Set("X-Gitlab-Token", token)
+		}
+		return req
+	}
+
+	for _, token := range []string{"", "wrong"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, request("Merge Request Hook", token, `{}`))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("token %q: got status %d, want %d", token, rec.Code, http.StatusUnauthorized)
+		}
+	}
+
+	// Events which aren't about merge requests are accepted and dropped.
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, request("Pipeline Hook", "secret", `{"project": {"id": 1}, "object_attributes": {"id": 2, "status": "running"}}`))
+	if rec.Code != http.StatusOK {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	tests := []struct {
+		eventType string
+		payload   string
+		wantPR    PR
+		want      interface{ Key() string }
+	}{
+		{
+			// Merge request events are recorded by syncing the merge
+			// request's notes instead.
+			eventType: "Merge Request Hook",
+			payload:   `{"user": {"username": "jane"}, "project": {"id": 1}, "object_attributes": {"iid": 2, "action": "merge", "updated_at": "2020-04-01 12:00:00 UTC"}}`,
+		},
+		{
+			eventType: "Note Hook",
+			payload:   `{"user": {"username": "jane"}, "project": {"id": 1}, "object_attributes": {"id": 3, "note": "lgtm", "noteable_type": "MergeRequest"}, "merge_request": {"iid": 2}}`,
+			wantPR:    PR{ID: 2, RepoExternalID: "1"},
+			want:      &gitlab.Note{ID: 3, Body: "lgtm", Author: gitlab.User{Username: "jane"}},
+		},
+		{
+			eventType: "Pipeline Hook",
+			payload:   `{"project": {"id": 1, "web_url": "https://gitlab.com/a/b"}, "object_attributes": {"id": 4, "sha": "abc", "status": "failed"}, "merge_request": {"iid": 2}}`,
+			wantPR:    PR{ID: 2, RepoExternalID: "1"},
+			want: &gitlab.Pipeline{
+				ID:        4,
+				SHA:       "abc",
+				Status:    gitlab.PipelineStatusFailed,
+				WebURL:    "https://gitlab.com/a/b/pipelines/4",
+				UpdatedAt: now,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.eventType, func(t *testing.T) {
+			e, _, hErr := h.parseEvent(request(tc.eventType, "secret", tc.payload))
+			if hErr != nil {
+				t.Fatal(hErr)
+			}
+			pr, ev := h.convertEvent(e)
+			if pr != tc.wantPR {
+				t.Errorf("got PR %+v, want %+v", pr, tc.wantPR)
+			}
+			if diff := cmp.Diff(ev, tc.want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
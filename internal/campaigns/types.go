@@ -12,8 +12,10 @@ import (
 	"github.com/inconshreveable/log15"
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 )
 
 // SupportedExternalServices are the external service types currently supported
@@ -23,6 +25,8 @@ import (
 var SupportedExternalServices = map[string]struct{}{
 	github.ServiceType:          {},
 	bitbucketserver.ServiceType: {},
+	gitlab.ServiceType:          {},
+	bitbucketcloud.ServiceType:  {},
 }
 
 // IsRepoSupported returns whether the given ExternalRepoSpec is supported by
@@ -38,6 +42,10 @@ type PatchSet struct {
 
 	UserID int32
 
+	// Spec is only set on PatchSets whose Patches are computed by
+	// Sourcegraph instead of being uploaded by the user.
+	Spec *PatchSetSpec
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -45,9 +53,57 @@ type PatchSet struct {
 // Clone returns a clone of a PatchSet.
 func (c *PatchSet) Clone() *PatchSet {
 	cc := *c
+	if c.Spec != nil {
+		spec := *c.Spec
+		cc.Spec = &spec
+	}
 	return &cc
 }
 
+// A PatchSetSpec specifies how to compute the Patches of a PatchSet: the
+// files in the repositories matching Query are rewritten by the replacer
+// service according to the remaining fields.
+type PatchSetSpec struct {
+	Query string `json:"query"`
+
+	// MatchTemplate and RewriteTemplate are comby templates, unless IsRegExp
+	// is set, in which case MatchTemplate is a regular expression and
+	// RewriteTemplate its replacement.
+	MatchTemplate   string `json:"matchTemplate"`
+	RewriteTemplate string `json:"rewriteTemplate"`
+	IsRegExp        bool   `json:"isRegExp,omitempty"`
+	IsLiteral       bool   `json:"isLiteral,omitempty"`
+	IsCaseSensitive bool   `json:"isCaseSensitive,omitempty"`
+
+	FileExtension    string `json:"fileExtension,omitempty"`
+	DirectoryExclude string `json:"directoryExclude,omitempty"`
+}
+
+// A PatchJob computes the Patch of a PatchSet with a PatchSetSpec in a
+// specific repository at a specific revision.
+type PatchJob struct {
+	ID         int64
+	PatchSetID int64
+
+	RepoID  api.RepoID
+	Rev     api.CommitID
+	BaseRef string
+
+	Error string
+
+	StartedAt  time.Time
+	FinishedAt time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Clone returns a clone of a PatchJob.
+func (j *PatchJob) Clone() *PatchJob {
+	jj := *j
+	return &jj
+}
+
 // A Patch is the application of a CampaignType over PatchSet arguments in
 // a specific repository at a specific revision.
 type Patch struct {
@@ -84,6 +140,32 @@ type Campaign struct {
 	ChangesetIDs    []int64
 	PatchSetID      int64
 	ClosedAt        time.Time
+
+	// AutoMerge enables merging the changesets of the Campaign as soon as
+	// they are approved and their checks passed, using MergeMethod.
+	AutoMerge   bool
+	MergeMethod ChangesetMergeMethod
+
+	// CommitMessage is the text/template of the message of the commits
+	// created for the changesets of the Campaign. It defaults to the Name of
+	// the Campaign.
+	CommitMessage string
+	// CommitAuthorName and CommitAuthorEmail are the author of the commits
+	// created for the changesets of the Campaign. They default to the name
+	// and verified primary email of the Campaign's author.
+	CommitAuthorName  string
+	CommitAuthorEmail string
+	// CommitSignOff adds a Signed-off-by trailer for the commit author to
+	// the commit messages.
+	CommitSignOff bool
+	// CommitSign signs the commits with the GPG key configured on gitserver.
+	CommitSign bool
+
+	// DraftOnCodeHost is true while the changesets of an unpublished
+	// Campaign are created as drafts on the code host, e.g. as GitHub draft
+	// pull requests or GitLab WIP merge requests. Publishing the Campaign
+	// marks them as ready for review.
+	DraftOnCodeHost bool
 }
 
 // Clone returns a clone of a Campaign.
@@ -127,6 +209,29 @@ func (s ChangesetState) Valid() bool {
 	}
 }
 
+// ChangesetMergeMethod defines the method used to merge the changesets of a
+// Campaign with AutoMerge enabled.
+type ChangesetMergeMethod string
+
+// ChangesetMergeMethod constants.
+const (
+	ChangesetMergeMethodMerge  ChangesetMergeMethod = "MERGE"
+	ChangesetMergeMethodSquash ChangesetMergeMethod = "SQUASH"
+	ChangesetMergeMethodRebase ChangesetMergeMethod = "REBASE"
+)
+
+// Valid returns true if the given ChangesetMergeMethod is valid.
+func (m ChangesetMergeMethod) Valid() bool {
+	switch m {
+	case ChangesetMergeMethodMerge,
+		ChangesetMergeMethodSquash,
+		ChangesetMergeMethodRebase:
+		return true
+	default:
+		return false
+	}
+}
+
 // ChangesetLabel represents a label applied to a changeset
 type ChangesetLabel struct {
 	Name        string
@@ -238,6 +343,10 @@ type ChangesetJob struct {
 
 	Branch string
 
+	// BaseCommit is the commit the Branch was last based on. It's the Rev
+	// of the Patch, unless the changeset has been rebased since.
+	BaseCommit string
+
 	Error string
 
 	StartedAt  time.Time
@@ -266,6 +375,76 @@ func (c *ChangesetJob) Reset() {
 	c.FinishedAt = time.Time{}
 }
 
+// ChangesetActionKind defines the kind of bulk action that a
+// ChangesetActionJob performs on a Changeset on its code host.
+type ChangesetActionKind string
+
+// ChangesetActionKind constants.
+const (
+	ChangesetActionKindComment          ChangesetActionKind = "COMMENT"
+	ChangesetActionKindLabel            ChangesetActionKind = "LABEL"
+	ChangesetActionKindRequestReviewers ChangesetActionKind = "REQUEST_REVIEWERS"
+)
+
+// Valid returns true if the given ChangesetActionKind is valid.
+func (k ChangesetActionKind) Valid() bool {
+	switch k {
+	case ChangesetActionKindComment,
+		ChangesetActionKindLabel,
+		ChangesetActionKindRequestReviewers:
+		return true
+	default:
+		return false
+	}
+}
+
+// ChangesetActionPayload holds the arguments of a ChangesetActionJob. Which
+// fields are set depends on the ChangesetActionKind of the job.
+type ChangesetActionPayload struct {
+	// Body is the body of the comment posted by COMMENT actions.
+	Body string `json:"body,omitempty"`
+
+	// AddLabels and RemoveLabels are the names of the labels added to and
+	// removed from the changeset by LABEL actions.
+	AddLabels    []string `json:"addLabels,omitempty"`
+	RemoveLabels []string `json:"removeLabels,omitempty"`
+
+	// Reviewers are the code host usernames of the reviewers requested by
+	// REQUEST_REVIEWERS actions.
+	Reviewers []string `json:"reviewers,omitempty"`
+}
+
+// A ChangesetActionJob performs a bulk action, such as posting a comment, on
+// a single Changeset of a Campaign on its code host.
+type ChangesetActionJob struct {
+	ID          int64
+	CampaignID  int64
+	ChangesetID int64
+
+	// UserID is the ID of the user that requested the action.
+	UserID int32
+
+	Kind    ChangesetActionKind
+	Payload ChangesetActionPayload
+
+	Error string
+
+	StartedAt  time.Time
+	FinishedAt time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Clone returns a clone of a ChangesetActionJob.
+func (j *ChangesetActionJob) Clone() *ChangesetActionJob {
+	jj := *j
+	jj.Payload.AddLabels = append([]string(nil), j.Payload.AddLabels...)
+	jj.Payload.RemoveLabels = append([]string(nil), j.Payload.RemoveLabels...)
+	jj.Payload.Reviewers = append([]string(nil), j.Payload.Reviewers...)
+	return &jj
+}
+
 // A Changeset is a changeset on a code host belonging to a Repository and many
 // Campaigns.
 type Changeset struct {
@@ -283,6 +462,10 @@ type Changeset struct {
 	ExternalState       ChangesetState
 	ExternalReviewState ChangesetReviewState
 	ExternalCheckState  ChangesetCheckState
+
+	// Conflicted is true if the changeset couldn't be rebased onto the
+	// latest commit of its base ref, because its patch no longer applies.
+	Conflicted bool
 }
 
 // Clone returns a clone of a Changeset.
@@ -306,6 +489,18 @@ func (c *Changeset) SetMetadata(meta interface{}) error {
 		c.ExternalServiceType = bitbucketserver.ServiceType
 		c.ExternalBranch = git.AbbreviateRef(pr.FromRef.ID)
 		c.ExternalUpdatedAt = unixMilliToTime(int64(pr.UpdatedDate))
+	case *gitlab.MergeRequest:
+		c.Metadata = pr
+		c.ExternalID = strconv.Itoa(pr.IID)
+		c.ExternalServiceType = gitlab.ServiceType
+		c.ExternalBranch = pr.SourceBranch
+		c.ExternalUpdatedAt = pr.UpdatedAt
+	case *bitbucketcloud.PullRequest:
+		c.Metadata = pr
+		c.ExternalID = strconv.Itoa(pr.ID)
+		c.ExternalServiceType = bitbucketcloud.ServiceType
+		c.ExternalBranch = pr.Source.Branch.Name
+		c.ExternalUpdatedAt = pr.UpdatedOn
 	default:
 		return errors.New("unknown changeset type")
 	}
@@ -350,6 +545,10 @@ func (c *Changeset) Title() (string, error) {
 		return m.Title, nil
 	case *bitbucketserver.PullRequest:
 		return m.Title, nil
+	case *gitlab.MergeRequest:
+		return m.Title, nil
+	case *bitbucketcloud.PullRequest:
+		return m.Title, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -364,6 +563,10 @@ func (c *Changeset) ExternalCreatedAt() time.Time {
 		return m.CreatedAt
 	case *bitbucketserver.PullRequest:
 		return unixMilliToTime(int64(m.CreatedDate))
+	case *gitlab.MergeRequest:
+		return m.CreatedAt
+	case *bitbucketcloud.PullRequest:
+		return m.CreatedOn
 	default:
 		return time.Time{}
 	}
@@ -376,6 +579,10 @@ func (c *Changeset) Body() (string, error) {
 		return m.Body, nil
 	case *bitbucketserver.PullRequest:
 		return m.Description, nil
+	case *gitlab.MergeRequest:
+		return m.Description, nil
+	case *bitbucketcloud.PullRequest:
+		return m.Description, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -409,6 +616,28 @@ func (c *Changeset) state() (s ChangesetState, err error) {
 		} else {
 			s = ChangesetState(m.State)
 		}
+	case *gitlab.MergeRequest:
+		switch m.State {
+		case gitlab.MergeRequestStateOpened:
+			s = ChangesetStateOpen
+		case gitlab.MergeRequestStateClosed, gitlab.MergeRequestStateLocked:
+			s = ChangesetStateClosed
+		case gitlab.MergeRequestStateMerged:
+			s = ChangesetStateMerged
+		default:
+			s = ChangesetState(m.State)
+		}
+	case *bitbucketcloud.PullRequest:
+		switch m.State {
+		case bitbucketcloud.PullRequestStateOpen:
+			s = ChangesetStateOpen
+		case bitbucketcloud.PullRequestStateDeclined, bitbucketcloud.PullRequestStateSuperseded:
+			s = ChangesetStateClosed
+		case bitbucketcloud.PullRequestStateMerged:
+			s = ChangesetStateMerged
+		default:
+			s = ChangesetState(m.State)
+		}
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -431,6 +660,10 @@ func (c *Changeset) URL() (s string, err error) {
 		}
 		selfLink := m.Links.Self[0]
 		return selfLink.Href, nil
+	case *gitlab.MergeRequest:
+		return m.WebURL, nil
+	case *bitbucketcloud.PullRequest:
+		return m.Links.HTML.Href, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -471,6 +704,60 @@ func (c *Changeset) Events() (events []*ChangesetEvent) {
 				Metadata:    a,
 			})
 		}
+
+	case *gitlab.MergeRequest:
+		events = make([]*ChangesetEvent, 0, len(m.Notes)+len(m.Pipelines))
+		for _, n := range m.Notes {
+			e := n.ToEvent()
+			if e == nil {
+				continue
+			}
+			events = append(events, &ChangesetEvent{
+				ChangesetID: c.ID,
+				Key:         e.Key(),
+				Kind:        ChangesetEventKindFor(e),
+				Metadata:    e,
+			})
+		}
+		for _, p := range m.Pipelines {
+			events = append(events, &ChangesetEvent{
+				ChangesetID: c.ID,
+				Key:         p.Key(),
+				Kind:        ChangesetEventKindFor(p),
+				Metadata:    p,
+			})
+		}
+
+	case *bitbucketcloud.PullRequest:
+		events = make([]*ChangesetEvent, 0, len(m.Activities)+len(m.Statuses))
+		for _, a := range m.Activities {
+			e := a.ToEvent()
+			if e == nil {
+				continue
+			}
+			events = append(events, &ChangesetEvent{
+				ChangesetID: c.ID,
+				Key:         e.Key(),
+				Kind:        ChangesetEventKindFor(e),
+				Metadata:    e,
+			})
+		}
+		for _, st := range m.Statuses {
+			events = append(events, &ChangesetEvent{
+				ChangesetID: c.ID,
+				Key:         st.Key(),
+				Kind:        ChangesetEventKindFor(st),
+				Metadata:    st,
+			})
+		}
+		for _, u := range m.Unapprovals {
+			events = append(events, &ChangesetEvent{
+				ChangesetID: c.ID,
+				Key:         u.Key(),
+				Kind:        ChangesetEventKindFor(u),
+				Metadata:    u,
+			})
+		}
 	}
 	return events
 }
@@ -484,6 +771,11 @@ func (c *Changeset) HeadRefOid() (string, error) {
 		return m.HeadRefOid, nil
 	case *bitbucketserver.PullRequest:
 		return "", nil
+	case *gitlab.MergeRequest:
+		return m.DiffRefs.HeadSHA, nil
+	case *bitbucketcloud.PullRequest:
+		// Bitbucket Cloud only returns abbreviated commit hashes.
+		return "", nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -497,6 +789,10 @@ func (c *Changeset) HeadRef() (string, error) {
 		return "refs/heads/" + m.HeadRefName, nil
 	case *bitbucketserver.PullRequest:
 		return m.FromRef.ID, nil
+	case *gitlab.MergeRequest:
+		return "refs/heads/" + m.SourceBranch, nil
+	case *bitbucketcloud.PullRequest:
+		return "refs/heads/" + m.Source.Branch.Name, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -511,6 +807,11 @@ func (c *Changeset) BaseRefOid() (string, error) {
 		return m.BaseRefOid, nil
 	case *bitbucketserver.PullRequest:
 		return "", nil
+	case *gitlab.MergeRequest:
+		return m.DiffRefs.BaseSHA, nil
+	case *bitbucketcloud.PullRequest:
+		// Bitbucket Cloud only returns abbreviated commit hashes.
+		return "", nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -524,6 +825,10 @@ func (c *Changeset) BaseRef() (string, error) {
 		return "refs/heads/" + m.BaseRefName, nil
 	case *bitbucketserver.PullRequest:
 		return m.ToRef.ID, nil
+	case *gitlab.MergeRequest:
+		return "refs/heads/" + m.TargetBranch, nil
+	case *bitbucketcloud.PullRequest:
+		return "refs/heads/" + m.Destination.Branch.Name, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -541,6 +846,13 @@ func (c *Changeset) Labels() []ChangesetLabel {
 			}
 		}
 		return labels
+	case *gitlab.MergeRequest:
+		// GitLab only returns the names of labels on a merge request.
+		labels := make([]ChangesetLabel, len(m.Labels))
+		for i, name := range m.Labels {
+			labels[i] = ChangesetLabel{Name: name}
+		}
+		return labels
 	default:
 		return []ChangesetLabel{}
 	}
@@ -570,6 +882,31 @@ func (c *Changeset) reviewState() (s ChangesetReviewState, err error) {
 				states[ChangesetReviewStateApproved] = true
 			}
 		}
+
+	case *gitlab.MergeRequest:
+		// GitLab only records approvals and their removal, in the system
+		// notes of the merge request.
+		approvedBy := map[string]bool{}
+		for _, n := range m.Notes {
+			switch e := n.ToEvent().(type) {
+			case *gitlab.ReviewApprovedEvent:
+				approvedBy[e.Author.Username] = true
+			case *gitlab.ReviewUnapprovedEvent:
+				delete(approvedBy, e.Author.Username)
+			}
+		}
+		if len(approvedBy) > 0 {
+			states[ChangesetReviewStateApproved] = true
+		}
+
+	case *bitbucketcloud.PullRequest:
+		for _, p := range m.Participants {
+			if p.Approved {
+				states[ChangesetReviewStateApproved] = true
+			} else if p.Role == "REVIEWER" {
+				states[ChangesetReviewStatePending] = true
+			}
+		}
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -667,11 +1004,11 @@ func (ce ChangesetEvents) State() ChangesetState {
 	state := ChangesetStateOpen
 	for _, e := range ce {
 		switch e.Kind {
-		case ChangesetEventKindGitHubClosed, ChangesetEventKindBitbucketServerDeclined:
+		case ChangesetEventKindGitHubClosed, ChangesetEventKindBitbucketServerDeclined, ChangesetEventKindGitLabClosed, ChangesetEventKindBitbucketCloudDeclined:
 			state = ChangesetStateClosed
-		case ChangesetEventKindGitHubMerged, ChangesetEventKindBitbucketServerMerged:
+		case ChangesetEventKindGitHubMerged, ChangesetEventKindBitbucketServerMerged, ChangesetEventKindGitLabMerged, ChangesetEventKindBitbucketCloudMerged:
 			state = ChangesetStateMerged
-		case ChangesetEventKindGitHubReopened, ChangesetEventKindBitbucketServerReopened:
+		case ChangesetEventKindGitHubReopened, ChangesetEventKindBitbucketServerReopened, ChangesetEventKindGitLabReopened:
 			state = ChangesetStateOpen
 		}
 	}
@@ -687,6 +1024,12 @@ func ComputeCheckState(c *Changeset, events ChangesetEvents) ChangesetCheckState
 
 	case *bitbucketserver.PullRequest:
 		return computeBitbucketBuildStatus(m)
+
+	case *gitlab.MergeRequest:
+		return computeGitLabPipelineState(m, events)
+
+	case *bitbucketcloud.PullRequest:
+		return computeBitbucketCloudBuildStatus(m, events)
 	}
 
 	return ChangesetCheckStateUnknown
@@ -713,8 +1056,9 @@ func ComputeReviewState(c *Changeset, events ChangesetEvents) (ChangesetReviewSt
 	}
 
 	// GitHub only stores the ReviewState in events, we can't look at the
-	// Changeset.
-	if c.ExternalServiceType == github.ServiceType {
+	// Changeset. The same is true for GitLab, where approvals are derived from
+	// system notes that are also synced as events.
+	if c.ExternalServiceType == github.ServiceType || c.ExternalServiceType == gitlab.ServiceType {
 		return events.reviewState()
 	}
 
@@ -748,6 +1092,94 @@ func parseBitbucketBuildState(s string) ChangesetCheckState {
 	}
 }
 
+// computeGitLabPipelineState returns the state of the most recent pipeline of
+// the merge request, taking into account pipeline webhook events that arrived
+// after the last sync.
+func computeGitLabPipelineState(mr *gitlab.MergeRequest, events []*ChangesetEvent) ChangesetCheckState {
+	var latest *gitlab.Pipeline
+	consider := func(p *gitlab.Pipeline) {
+		switch {
+		case latest == nil, p.ID > latest.ID:
+			latest = p
+		case p.ID == latest.ID && p.UpdatedAt.After(latest.UpdatedAt):
+			latest = p
+		}
+	}
+
+	for _, p := range mr.Pipelines {
+		consider(p)
+	}
+	for _, e := range events {
+		if p, ok := e.Metadata.(*gitlab.Pipeline); ok {
+			consider(p)
+		}
+	}
+
+	if latest == nil {
+		return ChangesetCheckStateUnknown
+	}
+	return parseGitLabPipelineStatus(latest.Status)
+}
+
+func parseGitLabPipelineStatus(status gitlab.PipelineStatus) ChangesetCheckState {
+	switch status {
+	case gitlab.PipelineStatusSuccess:
+		return ChangesetCheckStatePassed
+	case gitlab.PipelineStatusFailed, gitlab.PipelineStatusCanceled:
+		return ChangesetCheckStateFailed
+	case gitlab.PipelineStatusCreated,
+		gitlab.PipelineStatusWaitingForResource,
+		gitlab.PipelineStatusPreparing,
+		gitlab.PipelineStatusPending,
+		gitlab.PipelineStatusRunning,
+		gitlab.PipelineStatusScheduled,
+		gitlab.PipelineStatusManual:
+		return ChangesetCheckStatePending
+	default:
+		return ChangesetCheckStateUnknown
+	}
+}
+
+// computeBitbucketCloudBuildStatus combines the most recent status of every
+// build of the pull request, taking into account statuses that were recorded
+// as events after the last sync.
+func computeBitbucketCloudBuildStatus(pr *bitbucketcloud.PullRequest, events []*ChangesetEvent) ChangesetCheckState {
+	latest := make(map[string]*bitbucketcloud.CommitStatus)
+	consider := func(s *bitbucketcloud.CommitStatus) {
+		if l, ok := latest[s.Key()]; !ok || s.UpdatedOn.After(l.UpdatedOn) {
+			latest[s.Key()] = s
+		}
+	}
+
+	for _, s := range pr.Statuses {
+		consider(s)
+	}
+	for _, e := range events {
+		if s, ok := e.Metadata.(*bitbucketcloud.CommitStatus); ok {
+			consider(s)
+		}
+	}
+
+	states := make([]ChangesetCheckState, 0, len(latest))
+	for _, s := range latest {
+		states = append(states, parseBitbucketCloudBuildState(s.State))
+	}
+	return combineCheckStates(states)
+}
+
+func parseBitbucketCloudBuildState(s bitbucketcloud.CommitStatusState) ChangesetCheckState {
+	switch s {
+	case bitbucketcloud.CommitStatusStateFailed, bitbucketcloud.CommitStatusStateStopped:
+		return ChangesetCheckStateFailed
+	case bitbucketcloud.CommitStatusStateInProgress:
+		return ChangesetCheckStatePending
+	case bitbucketcloud.CommitStatusStateSuccessful:
+		return ChangesetCheckStatePassed
+	default:
+		return ChangesetCheckStateUnknown
+	}
+}
+
 func computeGitHubCheckState(lastSynced time.Time, pr *github.PullRequest, events []*ChangesetEvent) ChangesetCheckState {
 	// We should only consider the latest commit. This could be from a sync or a webhook that
 	// has occurred later
@@ -978,6 +1410,26 @@ func (e *ChangesetEvent) Actor() string {
 		a = e.Actor.Login
 	case *github.LabelEvent:
 		a = e.Actor.Login
+	case *gitlab.Note:
+		a = e.Author.Username
+	case *gitlab.ReviewApprovedEvent:
+		a = e.Author.Username
+	case *gitlab.ReviewUnapprovedEvent:
+		a = e.Author.Username
+	case *gitlab.MergeRequestClosedEvent:
+		a = e.Author.Username
+	case *gitlab.MergeRequestReopenedEvent:
+		a = e.Author.Username
+	case *gitlab.MergeRequestMergedEvent:
+		a = e.Author.Username
+	case *bitbucketcloud.Approval:
+		a = e.User.Nickname
+	case *bitbucketcloud.Unapproval:
+		a = e.User.Nickname
+	case *bitbucketcloud.Comment:
+		a = e.User.Nickname
+	case *bitbucketcloud.PullRequestUpdate:
+		a = e.Author.Nickname
 	}
 
 	return a
@@ -1006,6 +1458,34 @@ func (e *ChangesetEvent) ReviewAuthor() (string, error) {
 			return "", errors.New("activity user is blank")
 		}
 		return username, nil
+
+	case *gitlab.ReviewApprovedEvent:
+		username := meta.Author.Username
+		if username == "" {
+			return "", errors.New("approval author is blank")
+		}
+		return username, nil
+
+	case *gitlab.ReviewUnapprovedEvent:
+		username := meta.Author.Username
+		if username == "" {
+			return "", errors.New("unapproval author is blank")
+		}
+		return username, nil
+
+	case *bitbucketcloud.Approval:
+		uuid := meta.User.UUID
+		if uuid == "" {
+			return "", errors.New("approval user is blank")
+		}
+		return uuid, nil
+
+	case *bitbucketcloud.Unapproval:
+		uuid := meta.User.UUID
+		if uuid == "" {
+			return "", errors.New("unapproval user is blank")
+		}
+		return uuid, nil
 	default:
 		return "", nil
 	}
@@ -1014,7 +1494,9 @@ func (e *ChangesetEvent) ReviewAuthor() (string, error) {
 // ReviewState returns the review state of the ChangesetEvent if it is a review event.
 func (e *ChangesetEvent) ReviewState() (ChangesetReviewState, error) {
 	switch e.Kind {
-	case ChangesetEventKindBitbucketServerApproved:
+	case ChangesetEventKindBitbucketServerApproved,
+		ChangesetEventKindGitLabApproved,
+		ChangesetEventKindBitbucketCloudApproved:
 		return ChangesetReviewStateApproved, nil
 
 	// BitbucketServer's "REVIEWED" activity is created when someone clicks
@@ -1037,7 +1519,9 @@ func (e *ChangesetEvent) ReviewState() (ChangesetReviewState, error) {
 		return s, nil
 
 	case ChangesetEventKindGitHubReviewDismissed,
-		ChangesetEventKindBitbucketServerUnapproved:
+		ChangesetEventKindBitbucketServerUnapproved,
+		ChangesetEventKindGitLabUnapproved,
+		ChangesetEventKindBitbucketCloudUnapproved:
 		return ChangesetReviewStateDismissed, nil
 
 	default:
@@ -1045,6 +1529,26 @@ func (e *ChangesetEvent) ReviewState() (ChangesetReviewState, error) {
 	}
 }
 
+// CheckState returns the state of the check reported by the ChangesetEvent
+// if it is a check event, e.g. a commit status or a pipeline, and
+// ChangesetCheckStateUnknown otherwise.
+func (e *ChangesetEvent) CheckState() ChangesetCheckState {
+	switch m := e.Metadata.(type) {
+	case *github.CommitStatus:
+		return parseGithubCheckState(m.State)
+	case *github.CheckSuite:
+		return parseGithubCheckSuiteState(m.Status, m.Conclusion)
+	case *github.CheckRun:
+		return parseGithubCheckSuiteState(m.Status, m.Conclusion)
+	case *gitlab.Pipeline:
+		return parseGitLabPipelineStatus(m.Status)
+	case *bitbucketcloud.CommitStatus:
+		return parseBitbucketCloudBuildState(m.State)
+	default:
+		return ChangesetCheckStateUnknown
+	}
+}
+
 // Type returns the ChangesetEventKind of the ChangesetEvent.
 func (e *ChangesetEvent) Type() ChangesetEventKind {
 	return e.Kind
@@ -1095,6 +1599,32 @@ func (e *ChangesetEvent) Timestamp() time.Time {
 		return e.ReceivedAt
 	case *bitbucketserver.Activity:
 		t = unixMilliToTime(int64(e.CreatedDate))
+	case *gitlab.Note:
+		t = e.UpdatedAt
+	case *gitlab.ReviewApprovedEvent:
+		t = e.CreatedAt
+	case *gitlab.ReviewUnapprovedEvent:
+		t = e.CreatedAt
+	case *gitlab.MergeRequestClosedEvent:
+		t = e.CreatedAt
+	case *gitlab.MergeRequestReopenedEvent:
+		t = e.CreatedAt
+	case *gitlab.MergeRequestMergedEvent:
+		t = e.CreatedAt
+	case *gitlab.Pipeline:
+		t = e.UpdatedAt
+	case *bitbucketcloud.Approval:
+		t = e.Date
+	case *bitbucketcloud.Unapproval:
+		t = e.Date
+	case *bitbucketcloud.Comment:
+		t = e.UpdatedOn
+	case *bitbucketcloud.PullRequestUpdate:
+		t = e.Date
+	case *bitbucketcloud.CommitStatus:
+		t = e.UpdatedOn
+	case *ChangesetRebase:
+		t = e.RebasedAt
 	}
 
 	return t
@@ -1411,11 +1941,130 @@ func (e *ChangesetEvent) Update(o *ChangesetEvent) {
 		}
 		e.CheckRuns = o.CheckRuns
 
+	case *gitlab.Note:
+		o := o.Metadata.(*gitlab.Note)
+		updateGitLabNote(e, o)
+
+	case *gitlab.ReviewApprovedEvent:
+		o := o.Metadata.(*gitlab.ReviewApprovedEvent)
+		updateGitLabNote(&e.Note, &o.Note)
+
+	case *gitlab.ReviewUnapprovedEvent:
+		o := o.Metadata.(*gitlab.ReviewUnapprovedEvent)
+		updateGitLabNote(&e.Note, &o.Note)
+
+	case *gitlab.MergeRequestClosedEvent:
+		o := o.Metadata.(*gitlab.MergeRequestClosedEvent)
+		updateGitLabNote(&e.Note, &o.Note)
+
+	case *gitlab.MergeRequestReopenedEvent:
+		o := o.Metadata.(*gitlab.MergeRequestReopenedEvent)
+		updateGitLabNote(&e.Note, &o.Note)
+
+	case *gitlab.MergeRequestMergedEvent:
+		o := o.Metadata.(*gitlab.MergeRequestMergedEvent)
+		updateGitLabNote(&e.Note, &o.Note)
+
+	case *gitlab.Pipeline:
+		o := o.Metadata.(*gitlab.Pipeline)
+
+		// Pipelines change their status over time, so the most recently
+		// updated one wins.
+		if o.UpdatedAt.After(e.UpdatedAt) {
+			*e = *o
+		}
+
+	case *bitbucketcloud.Approval:
+		o := o.Metadata.(*bitbucketcloud.Approval)
+
+		if e.User == (bitbucketcloud.Account{}) {
+			e.User = o.User
+		}
+
+		if e.Date.IsZero() {
+			e.Date = o.Date
+		}
+
+	case *bitbucketcloud.Unapproval:
+		o := o.Metadata.(*bitbucketcloud.Unapproval)
+
+		if e.User == (bitbucketcloud.Account{}) {
+			e.User = o.User
+		}
+
+		if e.ApprovalDate.IsZero() {
+			e.ApprovalDate = o.ApprovalDate
+		}
+
+		if e.Date.IsZero() {
+			e.Date = o.Date
+		}
+
+	case *bitbucketcloud.Comment:
+		o := o.Metadata.(*bitbucketcloud.Comment)
+
+		if e.User == (bitbucketcloud.Account{}) {
+			e.User = o.User
+		}
+
+		if e.CreatedOn.IsZero() {
+			e.CreatedOn = o.CreatedOn
+		}
+
+		// Comments can be edited and deleted.
+		if o.UpdatedOn.After(e.UpdatedOn) {
+			e.Content = o.Content
+			e.Deleted = o.Deleted
+			e.UpdatedOn = o.UpdatedOn
+		}
+
+	case *bitbucketcloud.PullRequestUpdate:
+		o := o.Metadata.(*bitbucketcloud.PullRequestUpdate)
+
+		if e.Author == (bitbucketcloud.Account{}) {
+			e.Author = o.Author
+		}
+
+		if e.Title == "" {
+			e.Title = o.Title
+		}
+
+	case *bitbucketcloud.CommitStatus:
+		o := o.Metadata.(*bitbucketcloud.CommitStatus)
+
+		// A build reports new statuses under the same key, so the most
+		// recently updated one wins.
+		if o.UpdatedOn.After(e.UpdatedOn) {
+			*e = *o
+		}
+
 	default:
 		panic(errors.Errorf("unknown changeset event metadata %T", e))
 	}
 }
 
+func updateGitLabNote(e, o *gitlab.Note) {
+	if e.ID == 0 {
+		e.ID = o.ID
+	}
+
+	if e.Author.Username == "" {
+		e.Author = o.Author
+	}
+
+	if o.Body != "" && e.Body != o.Body {
+		e.Body = o.Body
+	}
+
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = o.CreatedAt
+	}
+
+	if e.UpdatedAt.Before(o.UpdatedAt) {
+		e.UpdatedAt = o.UpdatedAt
+	}
+}
+
 func updateGithubCheckRun(e, o *github.CheckRun) {
 	if e.Status == "" {
 		e.Status = o.Status
@@ -1540,6 +2189,35 @@ func ChangesetEventKindFor(e interface{}) ChangesetEventKind {
 		return ChangesetEventKindCheckRun
 	case *bitbucketserver.Activity:
 		return ChangesetEventKind("bitbucketserver:" + strings.ToLower(string(e.Action)))
+	case *gitlab.Note:
+		return ChangesetEventKindGitLabCommented
+	case *gitlab.ReviewApprovedEvent:
+		return ChangesetEventKindGitLabApproved
+	case *gitlab.ReviewUnapprovedEvent:
+		return ChangesetEventKindGitLabUnapproved
+	case *gitlab.MergeRequestClosedEvent:
+		return ChangesetEventKindGitLabClosed
+	case *gitlab.MergeRequestReopenedEvent:
+		return ChangesetEventKindGitLabReopened
+	case *gitlab.MergeRequestMergedEvent:
+		return ChangesetEventKindGitLabMerged
+	case *gitlab.Pipeline:
+		return ChangesetEventKindGitLabPipeline
+	case *bitbucketcloud.Approval:
+		return ChangesetEventKindBitbucketCloudApproved
+	case *bitbucketcloud.Unapproval:
+		return ChangesetEventKindBitbucketCloudUnapproved
+	case *bitbucketcloud.Comment:
+		return ChangesetEventKindBitbucketCloudCommented
+	case *bitbucketcloud.PullRequestUpdate:
+		if e.State == bitbucketcloud.PullRequestStateMerged {
+			return ChangesetEventKindBitbucketCloudMerged
+		}
+		return ChangesetEventKindBitbucketCloudDeclined
+	case *bitbucketcloud.CommitStatus:
+		return ChangesetEventKindBitbucketCloudCommitStatus
+	case *ChangesetRebase:
+		return ChangesetEventKindRebased
 	default:
 		panic(errors.Errorf("unknown changeset event kind for %T", e))
 	}
@@ -1590,6 +2268,40 @@ func NewChangesetEventMetadata(k ChangesetEventKind) (interface{}, error) {
 		case ChangesetEventKindCheckRun:
 			return new(github.CheckRun), nil
 		}
+	case strings.HasPrefix(string(k), "gitlab"):
+		switch k {
+		case ChangesetEventKindGitLabCommented:
+			return new(gitlab.Note), nil
+		case ChangesetEventKindGitLabApproved:
+			return new(gitlab.ReviewApprovedEvent), nil
+		case ChangesetEventKindGitLabUnapproved:
+			return new(gitlab.ReviewUnapprovedEvent), nil
+		case ChangesetEventKindGitLabClosed:
+			return new(gitlab.MergeRequestClosedEvent), nil
+		case ChangesetEventKindGitLabReopened:
+			return new(gitlab.MergeRequestReopenedEvent), nil
+		case ChangesetEventKindGitLabMerged:
+			return new(gitlab.MergeRequestMergedEvent), nil
+		case ChangesetEventKindGitLabPipeline:
+			return new(gitlab.Pipeline), nil
+		}
+	case strings.HasPrefix(string(k), "bitbucketcloud"):
+		switch k {
+		case ChangesetEventKindBitbucketCloudApproved:
+			return new(bitbucketcloud.Approval), nil
+		case ChangesetEventKindBitbucketCloudUnapproved:
+			return new(bitbucketcloud.Unapproval), nil
+		case ChangesetEventKindBitbucketCloudCommented:
+			return new(bitbucketcloud.Comment), nil
+		case ChangesetEventKindBitbucketCloudDeclined:
+			return &bitbucketcloud.PullRequestUpdate{State: bitbucketcloud.PullRequestStateDeclined}, nil
+		case ChangesetEventKindBitbucketCloudMerged:
+			return &bitbucketcloud.PullRequestUpdate{State: bitbucketcloud.PullRequestStateMerged}, nil
+		case ChangesetEventKindBitbucketCloudCommitStatus:
+			return new(bitbucketcloud.CommitStatus), nil
+		}
+	case k == ChangesetEventKindRebased:
+		return new(ChangesetRebase), nil
 	}
 	return nil, errors.Errorf("unknown changeset event kind %q", k)
 }
@@ -1630,8 +2342,41 @@ const (
 	ChangesetEventKindBitbucketServerUpdated    ChangesetEventKind = "bitbucketserver:updated"
 	ChangesetEventKindBitbucketServerCommented  ChangesetEventKind = "bitbucketserver:commented"
 	ChangesetEventKindBitbucketServerMerged     ChangesetEventKind = "bitbucketserver:merged"
+
+	ChangesetEventKindGitLabCommented  ChangesetEventKind = "gitlab:commented"
+	ChangesetEventKindGitLabApproved   ChangesetEventKind = "gitlab:approved"
+	ChangesetEventKindGitLabUnapproved ChangesetEventKind = "gitlab:unapproved"
+	ChangesetEventKindGitLabClosed     ChangesetEventKind = "gitlab:closed"
+	ChangesetEventKindGitLabReopened   ChangesetEventKind = "gitlab:reopened"
+	ChangesetEventKindGitLabMerged     ChangesetEventKind = "gitlab:merged"
+	ChangesetEventKindGitLabPipeline   ChangesetEventKind = "gitlab:pipeline"
+
+	ChangesetEventKindBitbucketCloudApproved     ChangesetEventKind = "bitbucketcloud:approved"
+	ChangesetEventKindBitbucketCloudUnapproved   ChangesetEventKind = "bitbucketcloud:unapproved"
+	ChangesetEventKindBitbucketCloudCommented    ChangesetEventKind = "bitbucketcloud:commented"
+	ChangesetEventKindBitbucketCloudDeclined     ChangesetEventKind = "bitbucketcloud:declined"
+	ChangesetEventKindBitbucketCloudMerged       ChangesetEventKind = "bitbucketcloud:merged"
+	ChangesetEventKindBitbucketCloudCommitStatus ChangesetEventKind = "bitbucketcloud:commit_status"
+
+	ChangesetEventKindRebased ChangesetEventKind = "sourcegraph:rebased"
 )
 
+// ChangesetRebase is the metadata of a ChangesetEvent recording that
+// Sourcegraph rebased a changeset onto a new commit of its base ref by
+// reapplying its patch.
+type ChangesetRebase struct {
+	BaseRef            string    `json:"baseRef"`
+	PreviousBaseCommit string    `json:"previousBaseCommit"`
+	BaseCommit         string    `json:"baseCommit"`
+	RebasedAt          time.Time `json:"rebasedAt"`
+}
+
+// Key is a unique key identifying this rebase in the context of its
+// changeset.
+func (r *ChangesetRebase) Key() string {
+	return r.BaseCommit
+}
+
 // ChangesetSyncData represents data about the sync status of a changeset
 type ChangesetSyncData struct {
 	ChangesetID int64
@@ -1643,6 +2388,15 @@ type ChangesetSyncData struct {
 	ExternalUpdatedAt time.Time
 }
 
+// ChangesetMergeCandidate is an open, approved Changeset with passing checks
+// that belongs to a Campaign with AutoMerge enabled.
+type ChangesetMergeCandidate struct {
+	ChangesetID int64
+	// MergeMethod is the merge method of the Campaign the Changeset is
+	// merged by.
+	MergeMethod ChangesetMergeMethod
+}
+
 func unixMilliToTime(ms int64) time.Time {
 	return time.Unix(0, ms*int64(time.Millisecond))
 }
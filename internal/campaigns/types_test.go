@@ -8,8 +8,10 @@ import (
 	"github.com/google/go-cmp/cmp/cmpopts"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 )
 
 func TestChangesetMetadata(t *testing.T) {
@@ -245,6 +247,54 @@ func TestChangesetEvents(t *testing.T) {
 		})
 	}
 
+	{ // GitLab
+
+		user := gitlab.User{Username: "john-doe"}
+		reviewer := gitlab.User{Username: "jane-doe"}
+
+		notes := []*gitlab.Note{
+			{ID: 1, Author: reviewer, Body: "lgtm"},
+			{ID: 2, Author: reviewer, Body: "approved this merge request", System: true},
+			{ID: 3, Author: user, Body: "added 1 commit", System: true},
+			{ID: 4, Author: user, Body: "merged", System: true},
+		}
+		pipeline := &gitlab.Pipeline{ID: 5, Status: gitlab.PipelineStatusSuccess}
+
+		approved := &gitlab.ReviewApprovedEvent{Note: *notes[1]}
+		merged := &gitlab.MergeRequestMergedEvent{Note: *notes[3]}
+
+		cases = append(cases, testCase{"gitlab",
+			Changeset{
+				ID: 25,
+				Metadata: &gitlab.MergeRequest{
+					Notes:     notes,
+					Pipelines: []*gitlab.Pipeline{pipeline},
+				},
+			},
+			[]*ChangesetEvent{{
+				ChangesetID: 25,
+				Kind:        ChangesetEventKindGitLabCommented,
+				Key:         notes[0].Key(),
+				Metadata:    notes[0],
+			}, {
+				ChangesetID: 25,
+				Kind:        ChangesetEventKindGitLabApproved,
+				Key:         approved.Key(),
+				Metadata:    approved,
+			}, {
+				ChangesetID: 25,
+				Kind:        ChangesetEventKindGitLabMerged,
+				Key:         merged.Key(),
+				Metadata:    merged,
+			}, {
+				ChangesetID: 25,
+				Kind:        ChangesetEventKindGitLabPipeline,
+				Key:         pipeline.Key(),
+				Metadata:    pipeline,
+			}},
+		})
+	}
+
 	for _, tc := range cases {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
@@ -618,6 +668,180 @@ func TestComputeGithubCheckState(t *testing.T) {
 	}
 }
 
+func TestComputeGitLabCheckState(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	pipeline := func(id int, status gitlab.PipelineStatus, minutesSinceSync int) *gitlab.Pipeline {
+		return &gitlab.Pipeline{
+			ID:        id,
+			Status:    status,
+			UpdatedAt: now.Add(time.Duration(minutesSinceSync) * time.Minute),
+		}
+	}
+	pipelineEvent := func(p *gitlab.Pipeline) *ChangesetEvent {
+		return &ChangesetEvent{Kind: ChangesetEventKindGitLabPipeline, Metadata: p}
+	}
+
+	tests := []struct {
+		name      string
+		pipelines []*gitlab.Pipeline
+		events    []*ChangesetEvent
+		want      ChangesetCheckState
+	}{
+		{
+			name: "no pipelines",
+			want: ChangesetCheckStateUnknown,
+		},
+		{
+			name:      "latest pipeline wins",
+			pipelines: []*gitlab.Pipeline{pipeline(2, gitlab.PipelineStatusRunning, 0), pipeline(1, gitlab.PipelineStatusFailed, 0)},
+			want:      ChangesetCheckStatePending,
+		},
+		{
+			name:      "webhook updates synced pipeline",
+			pipelines: []*gitlab.Pipeline{pipeline(1, gitlab.PipelineStatusRunning, -1)},
+			events:    []*ChangesetEvent{pipelineEvent(pipeline(1, gitlab.PipelineStatusSuccess, 1))},
+			want:      ChangesetCheckStatePassed,
+		},
+		{
+			name:      "stale webhook is ignored",
+			pipelines: []*gitlab.Pipeline{pipeline(1, gitlab.PipelineStatusCanceled, 0)},
+			events:    []*ChangesetEvent{pipelineEvent(pipeline(1, gitlab.PipelineStatusRunning, -1))},
+			want:      ChangesetCheckStateFailed,
+		},
+		{
+			name:      "new pipeline from webhook",
+			pipelines: []*gitlab.Pipeline{pipeline(1, gitlab.PipelineStatusSuccess, 0)},
+			events:    []*ChangesetEvent{pipelineEvent(pipeline(2, gitlab.PipelineStatusFailed, 1))},
+			want:      ChangesetCheckStateFailed,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Changeset{Metadata: &gitlab.MergeRequest{Pipelines: tc.pipelines}}
+			if have := ComputeCheckState(c, tc.events); have != tc.want {
+				t.Errorf("have %q, want %q", have, tc.want)
+			}
+		})
+	}
+}
+
+func TestComputeGitLabState(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	note := func(username, body string, minutesSinceSync int) *gitlab.Note {
+		return &gitlab.Note{
+			Author:    gitlab.User{Username: username},
+			Body:      body,
+			System:    true,
+			CreatedAt: now.Add(time.Duration(minutesSinceSync) * time.Minute),
+		}
+	}
+	event := func(n *gitlab.Note) *ChangesetEvent {
+		e := n.ToEvent()
+		return &ChangesetEvent{Kind: ChangesetEventKindFor(e), Key: e.Key(), Metadata: e}
+	}
+
+	c := &Changeset{
+		UpdatedAt:           now,
+		ExternalServiceType: gitlab.ServiceType,
+		Metadata: &gitlab.MergeRequest{
+			State: gitlab.MergeRequestStateOpened,
+			Notes: []*gitlab.Note{note("alice", "approved this merge request", -2)},
+		},
+	}
+
+	state, err := c.state()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != ChangesetStateOpen {
+		t.Errorf("have state %q, want %q", state, ChangesetStateOpen)
+	}
+	reviewState, err := c.reviewState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reviewState != ChangesetReviewStateApproved {
+		t.Errorf("have review state %q, want %q", reviewState, ChangesetReviewStateApproved)
+	}
+
+	// Events received by webhook after the last sync take precedence.
+	events := ChangesetEvents{
+		event(note("alice", "approved this merge request", -2)),
+		event(note("alice", "unapproved this merge request", 1)),
+		event(note("bob", "closed", 2)),
+	}
+	if state, err := ComputeChangesetState(c, events); err != nil || state != ChangesetStateClosed {
+		t.Errorf("have state %q (err: %v), want %q", state, err, ChangesetStateClosed)
+	}
+	if state, err := ComputeReviewState(c, events); err != nil || state != ChangesetReviewStatePending {
+		t.Errorf("have review state %q (err: %v), want %q", state, err, ChangesetReviewStatePending)
+	}
+}
+
+func TestComputeBitbucketCloudState(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	status := func(key string, state bitbucketcloud.CommitStatusState, minutesSinceSync int) *bitbucketcloud.CommitStatus {
+		return &bitbucketcloud.CommitStatus{
+			BuildKey:  key,
+			State:     state,
+			UpdatedOn: now.Add(time.Duration(minutesSinceSync) * time.Minute),
+		}
+	}
+	event := func(e interface{ Key() string }) *ChangesetEvent {
+		return &ChangesetEvent{Kind: ChangesetEventKindFor(e), Key: e.Key(), Metadata: e}
+	}
+
+	c := &Changeset{
+		UpdatedAt:           now,
+		ExternalServiceType: bitbucketcloud.ServiceType,
+		Metadata: &bitbucketcloud.PullRequest{
+			State: bitbucketcloud.PullRequestStateOpen,
+			Participants: []bitbucketcloud.Participant{
+				{User: bitbucketcloud.Account{UUID: "{alice}"}, Role: "REVIEWER", Approved: true},
+				{User: bitbucketcloud.Account{UUID: "{bob}"}, Role: "REVIEWER"},
+			},
+			Statuses: []*bitbucketcloud.CommitStatus{
+				status("build", bitbucketcloud.CommitStatusStateSuccessful, -1),
+				status("lint", bitbucketcloud.CommitStatusStateInProgress, -1),
+			},
+		},
+	}
+
+	state, err := c.state()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != ChangesetStateOpen {
+		t.Errorf("have state %q, want %q", state, ChangesetStateOpen)
+	}
+	reviewState, err := c.reviewState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reviewState != ChangesetReviewStateApproved {
+		t.Errorf("have review state %q, want %q", reviewState, ChangesetReviewStateApproved)
+	}
+	if have, want := ComputeCheckState(c, nil), ChangesetCheckStatePending; have != want {
+		t.Errorf("have check state %q, want %q", have, want)
+	}
+
+	events := ChangesetEvents{
+		event(&bitbucketcloud.Approval{User: bitbucketcloud.Account{UUID: "{alice}"}, Date: now.Add(-2 * time.Minute)}),
+		event(status("lint", bitbucketcloud.CommitStatusStateStopped, 1)),
+		event(&bitbucketcloud.PullRequestUpdate{State: bitbucketcloud.PullRequestStateMerged, Date: now.Add(2 * time.Minute)}),
+	}
+	if state, err := ComputeChangesetState(c, events); err != nil || state != ChangesetStateMerged {
+		t.Errorf("have state %q (err: %v), want %q", state, err, ChangesetStateMerged)
+	}
+	if state, err := ComputeReviewState(c, events); err != nil || state != ChangesetReviewStateApproved {
+		t.Errorf("have review state %q (err: %v), want %q", state, err, ChangesetReviewStateApproved)
+	}
+	if have, want := ComputeCheckState(c, events), ChangesetCheckStateFailed; have != want {
+		t.Errorf("have check state %q, want %q", have, want)
+	}
+}
+
 func TestChangesetEventsLabels(t *testing.T) {
 	now := time.Now()
 	labelEvent := func(name string, kind ChangesetEventKind, when time.Time) *ChangesetEvent {
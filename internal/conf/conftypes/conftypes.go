@@ -11,6 +11,15 @@ type ServiceConnections struct {
 	// to.
 	GitServers []string `json:"gitServers"`
 
+	// GitServerReplicas is the number of gitserver instances each repository
+	// is cloned onto. Values less than 2 disable replication.
+	GitServerReplicas int `json:"gitServerReplicas"`
+
+	// GitServerHashing is the scheme used to assign repositories to gitserver
+	// instances: "rendezvous" for rendezvous hashing, or "" for hashing modulo
+	// the number of instances.
+	GitServerHashing string `json:"gitServerHashing"`
+
 	// PostgresDSN is the PostgreSQL DB data source name.
 	// eg: "postgres://sg@pgsql/sourcegraph?sslmode=false"
 	PostgresDSN string `json:"postgresDSN"`
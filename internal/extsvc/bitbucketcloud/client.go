@@ -18,6 +18,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
 	"github.com/sourcegraph/sourcegraph/internal/metrics"
+	"github.com/sourcegraph/sourcegraph/internal/ratelimit"
 	"golang.org/x/time/rate"
 )
 
@@ -153,6 +154,12 @@ func (c *Client) reqPage(ctx context.Context, url string, results interface{}) (
 	return &next, nil
 }
 
+// rateLimitKey returns the credentials the rate limit of Bitbucket Cloud
+// applies to, so that all clients using them share a rate limit budget.
+func (c *Client) rateLimitKey() string {
+	return c.Username + ":" + c.AppPassword
+}
+
 func (c *Client) do(ctx context.Context, req *http.Request, result interface{}) error {
 	req.URL = c.URL.ResolveReference(req.URL)
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
@@ -176,10 +183,19 @@ func (c *Client) do(ctx context.Context, req *http.Request, result interface{})
 		log15.Warn("Bitbucket Cloud self-enforced API rate limit: request delayed longer than expected due to rate limit", "delay", d)
 	}
 
+	// Bitbucket doesn't report its rate limit in response headers, so the
+	// budget is unknown. The monitor still makes requests of the same
+	// credentials back off together once Bitbucket asks to retry later.
+	monitor := ratelimit.DefaultMonitorRegistry.GetOrSet(c.URL.String(), c.rateLimitKey(), "X-")
+	if err := monitor.Wait(ctx, 1); err != nil {
+		return err
+	}
+
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
+	monitor.Update(resp.Header)
 
 	defer resp.Body.Close()
 
@@ -286,3 +302,12 @@ func (e *httpError) Unauthorized() bool {
 func (e *httpError) NotFound() bool {
 	return e.StatusCode == http.StatusNotFound
 }
+
+// IsNotFound reports whether err is a Bitbucket Cloud API not found error.
+func IsNotFound(err error) bool {
+	switch e := errors.Cause(err).(type) {
+	case *httpError:
+		return e.NotFound()
+	}
+	return false
+}
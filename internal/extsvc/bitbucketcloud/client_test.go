@@ -4,12 +4,15 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/internal/ratelimit"
 )
 
 var update = flag.Bool("update", false, "update testdata")
@@ -119,3 +122,53 @@ func TestClient_Repos(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_RateLimitRetryAfter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	uri, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli := NewClient(uri, nil)
+	cli.Username, cli.AppPassword = "a", "secret"
+	other := NewClient(uri, nil)
+	other.Username, other.AppPassword = "b", "secret"
+
+	get := func(ctx context.Context, c *Client) error {
+		req, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.do(ctx, req, nil)
+	}
+
+	if err := get(context.Background(), cli); err == nil {
+		t.Fatal("want error for rate limited request")
+	}
+
+	_, _, retry, _ := ratelimit.DefaultMonitorRegistry.GetOrSet(uri.String(), "a:secret", "X-").Get()
+	if retry <= 0 {
+		t.Fatalf("want Retry-After to be recorded, have retry in %s", retry)
+	}
+
+	// Background requests with the same credentials wait for the retry,
+	// requests with other credentials don't.
+	background := func() (context.Context, context.CancelFunc) {
+		return context.WithTimeout(ratelimit.WithPriority(context.Background(), ratelimit.PriorityBackground), 50*time.Millisecond)
+	}
+	ctx, cancel := background()
+	defer cancel()
+	if err := get(ctx, cli); err != context.DeadlineExceeded {
+		t.Fatalf("want background request to wait, have %v", err)
+	}
+	ctx, cancel = background()
+	defer cancel()
+	if err := get(ctx, other); err == nil || err == context.DeadlineExceeded {
+		t.Fatalf("want request with other credentials to be sent, have %v", err)
+	}
+}
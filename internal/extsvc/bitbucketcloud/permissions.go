@@ -0,0 +1,57 @@
+package bitbucketcloud
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// RepoPermission is the effective permission of a user on a repository of a workspace, i.e. the
+// highest permission the user has on the repository, whether granted directly or through a group.
+type RepoPermission struct {
+	Permission string  `json:"permission"` // "read", "write" or "admin"
+	User       Account `json:"user"`
+	Repository Repo    `json:"repository"`
+}
+
+// WorkspaceMembership is the membership of a user in a workspace.
+type WorkspaceMembership struct {
+	User Account `json:"user"`
+}
+
+// WorkspaceMembers returns a page of the members of the given workspace.
+//
+// API docs: https://developer.atlassian.com/bitbucket/api/2/reference/resource/workspaces/%7Bworkspace%7D/members
+func (c *Client) WorkspaceMembers(ctx context.Context, pageToken *PageToken, workspace string) ([]*WorkspaceMembership, *PageToken, error) {
+	var members []*WorkspaceMembership
+	var next *PageToken
+	var err error
+	if pageToken.HasMore() {
+		next, err = c.reqPage(ctx, pageToken.Next, &members)
+	} else {
+		next, err = c.page(ctx, fmt.Sprintf("/2.0/workspaces/%s/members", workspace), nil, pageToken, &members)
+	}
+	return members, next, err
+}
+
+// RepoPermissions returns a page of the effective repository permissions of the users of the given
+// workspace. If the query is not empty, it is used to filter the permissions (e.g.
+// `user.uuid="{...}"` or `repository.uuid="{...}"`). The authenticated user must be an
+// administrator of the workspace.
+//
+// API docs: https://developer.atlassian.com/bitbucket/api/2/reference/resource/workspaces/%7Bworkspace%7D/permissions/repositories
+func (c *Client) RepoPermissions(ctx context.Context, pageToken *PageToken, workspace, query string) ([]*RepoPermission, *PageToken, error) {
+	var perms []*RepoPermission
+	var next *PageToken
+	var err error
+	if pageToken.HasMore() {
+		next, err = c.reqPage(ctx, pageToken.Next, &perms)
+	} else {
+		var qry url.Values
+		if query != "" {
+			qry = url.Values{"q": []string{query}}
+		}
+		next, err = c.page(ctx, fmt.Sprintf("/2.0/workspaces/%s/permissions/repositories", workspace), qry, pageToken, &perms)
+	}
+	return perms, next, err
+}
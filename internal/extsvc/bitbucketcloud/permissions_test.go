@@ -0,0 +1,68 @@
+package bitbucketcloud
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClient_RepoPermissions(t *testing.T) {
+	q := url.Values{"q": {`user.uuid="{alice}"`}}
+	cli := newMockClient(mockHTTPRoutes{
+		"GET /2.0/workspaces/sglocal/permissions/repositories?" + q.Encode(): `{
+			"values": [{"permission": "read", "user": {"uuid": "{alice}", "nickname": "alice"}, "repository": {"uuid": "{mux}", "full_name": "sglocal/mux"}}],
+			"next": "https://api.bitbucket.org/2.0/workspaces/sglocal/permissions/repositories?page=2"
+		}`,
+		"GET /2.0/workspaces/sglocal/permissions/repositories?page=2": `{
+			"values": [{"permission": "admin", "user": {"uuid": "{alice}", "nickname": "alice"}, "repository": {"uuid": "{vegeta}", "full_name": "sglocal/vegeta"}}]
+		}`,
+	})
+
+	var got []*RepoPermission
+	for page := (&PageToken{}); ; {
+		perms, next, err := cli.RepoPermissions(context.Background(), page, "sglocal", `user.uuid="{alice}"`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, perms...)
+		if !next.HasMore() {
+			break
+		}
+		page = next
+	}
+
+	want := []*RepoPermission{
+		{Permission: "read", User: Account{UUID: "{alice}", Nickname: "alice"}, Repository: Repo{UUID: "{mux}", FullName: "sglocal/mux"}},
+		{Permission: "admin", User: Account{UUID: "{alice}", Nickname: "alice"}, Repository: Repo{UUID: "{vegeta}", FullName: "sglocal/vegeta"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestClient_WorkspaceMembers(t *testing.T) {
+	cli := newMockClient(mockHTTPRoutes{
+		"GET /2.0/workspaces/sglocal/members": `{"values": [
+			{"user": {"uuid": "{alice}", "account_id": "1", "nickname": "alice"}},
+			{"user": {"uuid": "{bob}", "account_id": "2", "nickname": "bob"}}
+		]}`,
+	})
+
+	members, next, err := cli.WorkspaceMembers(context.Background(), &PageToken{}, "sglocal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.HasMore() {
+		t.Errorf("got next page %+v, want none", next)
+	}
+
+	want := []*WorkspaceMembership{
+		{User: Account{UUID: "{alice}", AccountID: "1", Nickname: "alice"}},
+		{User: Account{UUID: "{bob}", AccountID: "2", Nickname: "bob"}},
+	}
+	if diff := cmp.Diff(want, members); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,404 @@
+package bitbucketcloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// PullRequestState is the state of a Bitbucket Cloud pull request.
+type PullRequestState string
+
+const (
+	PullRequestStateOpen       PullRequestState = "OPEN"
+	PullRequestStateMerged     PullRequestState = "MERGED"
+	PullRequestStateDeclined   PullRequestState = "DECLINED"
+	PullRequestStateSuperseded PullRequestState = "SUPERSEDED"
+)
+
+// PullRequest is a Bitbucket Cloud pull request.
+type PullRequest struct {
+	ID                int                 `json:"id"`
+	Title             string              `json:"title"`
+	Description       string              `json:"description"`
+	State             PullRequestState    `json:"state"`
+	Author            Account             `json:"author"`
+	Source            PullRequestEndpoint `json:"source"`
+	Destination       PullRequestEndpoint `json:"destination"`
+	Participants      []Participant       `json:"participants"`
+	CloseSourceBranch bool                `json:"close_source_branch"`
+	Links             Links               `json:"links"`
+	CreatedOn         time.Time           `json:"created_on"`
+	UpdatedOn         time.Time           `json:"updated_on"`
+
+	// Activities and Statuses are not part of the pull request returned by
+	// the Bitbucket Cloud API. They are loaded separately with
+	// PullRequestActivity and PullRequestStatuses.
+	Activities []*Activity     `json:"activities,omitempty"`
+	Statuses   []*CommitStatus `json:"statuses,omitempty"`
+
+	// Unapprovals aren't returned by the Bitbucket Cloud API either. They
+	// are detected by DetectUnapprovals and carried over from one sync to
+	// the next.
+	Unapprovals []*Unapproval `json:"unapprovals,omitempty"`
+}
+
+// DetectUnapprovals records an Unapproval at now for every approval in the
+// activity of prev, the previously synced state of the pull request, which is
+// missing from the activity of pr and whose user no longer approves pr.
+// Bitbucket Cloud removes approvals from the activity log of a pull request
+// when they are withdrawn instead of logging an unapproval.
+func (pr *PullRequest) DetectUnapprovals(prev *PullRequest, now time.Time) {
+	pr.Unapprovals = append([]*Unapproval(nil), prev.Unapprovals...)
+
+	current := make(map[string]bool, len(pr.Activities))
+	for _, a := range pr.Activities {
+		if a.Approval != nil {
+			current[a.Approval.Key()] = true
+		}
+	}
+	approved := make(map[string]bool, len(pr.Participants))
+	for _, p := range pr.Participants {
+		if p.Approved {
+			approved[p.User.UUID] = true
+		}
+	}
+
+	for _, a := range prev.Activities {
+		if a.Approval == nil || current[a.Approval.Key()] || approved[a.Approval.User.UUID] {
+			continue
+		}
+		pr.Unapprovals = append(pr.Unapprovals, &Unapproval{
+			User:         a.Approval.User,
+			ApprovalDate: a.Approval.Date,
+			Date:         now,
+		})
+	}
+}
+
+// PullRequestEndpoint is the source or destination of a pull request.
+type PullRequestEndpoint struct {
+	Branch     PullRequestBranch  `json:"branch"`
+	Commit     *PullRequestCommit `json:"commit,omitempty"`
+	Repository *Repo              `json:"repository,omitempty"`
+}
+
+// PullRequestBranch is the branch of a PullRequestEndpoint.
+type PullRequestBranch struct {
+	Name string `json:"name"`
+}
+
+// PullRequestCommit is the commit of a PullRequestEndpoint. Bitbucket Cloud
+// only returns the abbreviated hash of the commit.
+type PullRequestCommit struct {
+	Hash string `json:"hash"`
+}
+
+// Account is a Bitbucket Cloud user or team.
+type Account struct {
+	UUID        string `json:"uuid"`
+	AccountID   string `json:"account_id"`
+	Nickname    string `json:"nickname"`
+	DisplayName string `json:"display_name"`
+}
+
+// Participant is a user taking part in the review of a pull request.
+type Participant struct {
+	User     Account `json:"user"`
+	Role     string  `json:"role"` // "PARTICIPANT" or "REVIEWER"
+	Approved bool    `json:"approved"`
+}
+
+// CreatePullRequestOpts are the options of CreatePullRequest.
+type CreatePullRequestOpts struct {
+	Title             string              `json:"title"`
+	Description       string              `json:"description,omitempty"`
+	Source            PullRequestEndpoint `json:"source"`
+	Destination       PullRequestEndpoint `json:"destination"`
+	CloseSourceBranch bool                `json:"close_source_branch"`
+}
+
+// CreatePullRequest creates a pull request in the given repository.
+func (c *Client) CreatePullRequest(ctx context.Context, repo *Repo, opts CreatePullRequestOpts) (*PullRequest, error) {
+	req, err := newJSONRequest("POST", pullRequestsPath(repo), &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var pr PullRequest
+	if err := c.do(ctx, req, &pr); err != nil {
+		return nil, errors.Wrap(err, "creating pull request")
+	}
+	return &pr, nil
+}
+
+// PullRequest returns the pull request with the given ID.
+func (c *Client) PullRequest(ctx context.Context, repo *Repo, id int) (*PullRequest, error) {
+	req, err := http.NewRequest("GET", pullRequestPath(repo, id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var pr PullRequest
+	if err := c.do(ctx, req, &pr); err != nil {
+		return nil, errors.Wrap(err, "getting pull request")
+	}
+	return &pr, nil
+}
+
+// OpenPullRequestByRefs returns the open pull request from source to
+// destination branch in the given repository, or nil if there is none.
+func (c *Client) OpenPullRequestByRefs(ctx context.Context, repo *Repo, source, destination string) (*PullRequest, error) {
+	// See https://developer.atlassian.com/bitbucket/api/2/reference/meta/filtering
+	q := url.Values{"q": {fmt.Sprintf(
+		`source.branch.name = %q AND destination.branch.name = %q AND state = %q`,
+		source, destination, PullRequestStateOpen,
+	)}}
+
+	var prs []*PullRequest
+	if _, err := c.reqPage(ctx, pullRequestsPath(repo)+"?"+q.Encode(), &prs); err != nil {
+		return nil, errors.Wrap(err, "listing pull requests")
+	}
+	for _, pr := range prs {
+		// Only pull requests from the same repository, not from forks.
+		if pr.Source.Repository == nil || pr.Source.Repository.FullName == repo.FullName {
+			return pr, nil
+		}
+	}
+	return nil, nil
+}
+
+// UpdatePullRequestOpts are the options of UpdatePullRequest.
+type UpdatePullRequestOpts struct {
+	Title       string              `json:"title"`
+	Description string              `json:"description"`
+	Destination PullRequestEndpoint `json:"destination"`
+}
+
+// UpdatePullRequest updates the title, description and destination branch of
+// the given pull request and returns its new state.
+func (c *Client) UpdatePullRequest(ctx context.Context, repo *Repo, id int, opts UpdatePullRequestOpts) (*PullRequest, error) {
+	req, err := newJSONRequest("PUT", pullRequestPath(repo, id), &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var pr PullRequest
+	if err := c.do(ctx, req, &pr); err != nil {
+		return nil, errors.Wrap(err, "updating pull request")
+	}
+	return &pr, nil
+}
+
+// DeclinePullRequest declines the given pull request and returns its new
+// state. Declined pull requests can't be reopened on Bitbucket Cloud.
+func (c *Client) DeclinePullRequest(ctx context.Context, repo *Repo, id int) (*PullRequest, error) {
+	req, err := http.NewRequest("POST", pullRequestPath(repo, id)+"/decline", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var pr PullRequest
+	if err := c.do(ctx, req, &pr); err != nil {
+		return nil, errors.Wrap(err, "declining pull request")
+	}
+	return &pr, nil
+}
+
+// MergeStrategy is the strategy used to merge a pull request.
+type MergeStrategy string
+
+const (
+	MergeStrategyMergeCommit MergeStrategy = "merge_commit"
+	MergeStrategySquash      MergeStrategy = "squash"
+	MergeStrategyFastForward MergeStrategy = "fast_forward"
+)
+
+// MergePullRequest merges the given pull request with the given strategy and
+// returns its new state. An empty strategy uses the default strategy of the
+// repository.
+func (c *Client) MergePullRequest(ctx context.Context, repo *Repo, id int, strategy MergeStrategy) (*PullRequest, error) {
+	req, err := newJSONRequest("POST", pullRequestPath(repo, id)+"/merge", &struct {
+		MergeStrategy MergeStrategy `json:"merge_strategy,omitempty"`
+	}{strategy})
+	if err != nil {
+		return nil, err
+	}
+
+	var pr PullRequest
+	if err := c.do(ctx, req, &pr); err != nil {
+		return nil, errors.Wrap(err, "merging pull request")
+	}
+	return &pr, nil
+}
+
+// PullRequestActivity returns the activity of the given pull request: its
+// comments, approvals and updates.
+func (c *Client) PullRequestActivity(ctx context.Context, repo *Repo, id int) ([]*Activity, error) {
+	var all []*Activity
+	next := &PageToken{Pagelen: 50}
+	for first := true; first || next.HasMore(); first = false {
+		var activities []*Activity
+		var err error
+		if first {
+			next, err = c.page(ctx, pullRequestPath(repo, id)+"/activity", nil, next, &activities)
+		} else {
+			next, err = c.reqPage(ctx, next.Next, &activities)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "getting pull request activity")
+		}
+		all = append(all, activities...)
+	}
+	return all, nil
+}
+
+// PullRequestStatuses returns the build statuses of the commits of the given
+// pull request.
+func (c *Client) PullRequestStatuses(ctx context.Context, repo *Repo, id int) ([]*CommitStatus, error) {
+	var all []*CommitStatus
+	next := &PageToken{Pagelen: 100}
+	for first := true; first || next.HasMore(); first = false {
+		var statuses []*CommitStatus
+		var err error
+		if first {
+			next, err = c.page(ctx, pullRequestPath(repo, id)+"/statuses", nil, next, &statuses)
+		} else {
+			next, err = c.reqPage(ctx, next.Next, &statuses)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "getting pull request statuses")
+		}
+		all = append(all, statuses...)
+	}
+	return all, nil
+}
+
+func pullRequestsPath(repo *Repo) string {
+	return fmt.Sprintf("/2.0/repositories/%s/pullrequests", repo.FullName)
+}
+
+func pullRequestPath(repo *Repo, id int) string {
+	return pullRequestsPath(repo) + "/" + strconv.Itoa(id)
+}
+
+func newJSONRequest(method, urlStr string, body interface{}) (*http.Request, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return http.NewRequest(method, urlStr, bytes.NewReader(b))
+}
+
+// Activity is an entry of the activity log of a pull request. Exactly one of
+// its fields is set.
+type Activity struct {
+	Approval *Approval          `json:"approval,omitempty"`
+	Comment  *Comment           `json:"comment,omitempty"`
+	Update   *PullRequestUpdate `json:"update,omitempty"`
+}
+
+// ToEvent returns the pull request event the activity represents, or nil if
+// it's not one we record. Of the updates of a pull request only the ones
+// declining or merging it are recorded, since every push to the source branch
+// results in an update too.
+func (a *Activity) ToEvent() interface{ Key() string } {
+	switch {
+	case a.Approval != nil:
+		return a.Approval
+	case a.Comment != nil:
+		return a.Comment
+	case a.Update != nil:
+		switch a.Update.State {
+		case PullRequestStateDeclined, PullRequestStateMerged:
+			return a.Update
+		}
+	}
+	return nil
+}
+
+// Approval is the approval of a pull request by a user.
+type Approval struct {
+	User Account   `json:"user"`
+	Date time.Time `json:"date"`
+}
+
+// Key is a unique key identifying this approval.
+func (a *Approval) Key() string {
+	return fmt.Sprintf("approval:%s:%d", a.User.UUID, a.Date.Unix())
+}
+
+// Unapproval is the withdrawal of an Approval. Bitbucket Cloud doesn't report
+// when that happened, so Date is the time it was detected.
+type Unapproval struct {
+	User         Account   `json:"user"`
+	ApprovalDate time.Time `json:"approval_date"`
+	Date         time.Time `json:"date"`
+}
+
+// Key is a unique key identifying this unapproval.
+func (u *Unapproval) Key() string {
+	return fmt.Sprintf("unapproval:%s:%d", u.User.UUID, u.ApprovalDate.Unix())
+}
+
+// Comment is a comment on a pull request.
+type Comment struct {
+	ID      int `json:"id"`
+	Content struct {
+		Raw string `json:"raw"`
+	} `json:"content"`
+	User      Account   `json:"user"`
+	Deleted   bool      `json:"deleted"`
+	CreatedOn time.Time `json:"created_on"`
+	UpdatedOn time.Time `json:"updated_on"`
+}
+
+// Key is a unique key identifying this comment.
+func (c *Comment) Key() string { return strconv.Itoa(c.ID) }
+
+// PullRequestUpdate is a change of the state, title, description or
+// branches of a pull request.
+type PullRequestUpdate struct {
+	State  PullRequestState `json:"state"`
+	Title  string           `json:"title"`
+	Author Account          `json:"author"`
+	Date   time.Time        `json:"date"`
+}
+
+// Key is a unique key identifying this update.
+func (u *PullRequestUpdate) Key() string {
+	return fmt.Sprintf("update:%s:%d", u.State, u.Date.Unix())
+}
+
+// CommitStatusState is the state of a CommitStatus.
+type CommitStatusState string
+
+const (
+	CommitStatusStateSuccessful CommitStatusState = "SUCCESSFUL"
+	CommitStatusStateFailed     CommitStatusState = "FAILED"
+	CommitStatusStateInProgress CommitStatusState = "INPROGRESS"
+	CommitStatusStateStopped    CommitStatusState = "STOPPED"
+)
+
+// CommitStatus is the build status reported for a commit of a pull request,
+// e.g. by Bitbucket Pipelines or an external CI.
+type CommitStatus struct {
+	BuildKey    string            `json:"key"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	State       CommitStatusState `json:"state"`
+	URL         string            `json:"url"`
+	CreatedOn   time.Time         `json:"created_on"`
+	UpdatedOn   time.Time         `json:"updated_on"`
+}
+
+// Key is a unique key identifying this build. Builds report a new status
+// under the same key when they're re-run or run for a new commit.
+func (s *CommitStatus) Key() string { return "status:" + s.BuildKey }
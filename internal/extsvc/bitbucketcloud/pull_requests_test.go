@@ -0,0 +1,154 @@
+package bitbucketcloud
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type mockHTTPRoutes map[string]string
+
+func (m mockHTTPRoutes) Do(req *http.Request) (*http.Response, error) {
+	body, ok := m[req.Method+" "+req.URL.RequestURI()]
+	code := http.StatusOK
+	if !ok {
+		code = http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: code,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func newMockClient(routes mockHTTPRoutes) *Client {
+	return NewClient(&url.URL{Scheme: "https", Host: "api.bitbucket.org"}, routes)
+}
+
+func TestClient_OpenPullRequestByRefs(t *testing.T) {
+	repo := &Repo{FullName: "sglocal/mux"}
+	q := url.Values{"q": {`source.branch.name = "campaign" AND destination.branch.name = "master" AND state = "OPEN"`}}
+	cli := newMockClient(mockHTTPRoutes{
+		"GET /2.0/repositories/sglocal/mux/pullrequests?" + q.Encode(): `{"values": [
+			{"id": 1, "source": {"branch": {"name": "campaign"}, "repository": {"full_name": "fork/mux"}}},
+			{"id": 2, "source": {"branch": {"name": "campaign"}, "repository": {"full_name": "sglocal/mux"}}}
+		]}`,
+		"GET /2.0/repositories/sglocal/empty/pullrequests?" + q.Encode(): `{"values": []}`,
+	})
+
+	pr, err := cli.OpenPullRequestByRefs(context.Background(), repo, "campaign", "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr == nil || pr.ID != 2 {
+		t.Errorf("got %+v, want pull request 2", pr)
+	}
+
+	pr, err = cli.OpenPullRequestByRefs(context.Background(), &Repo{FullName: "sglocal/empty"}, "campaign", "master")
+	if err != nil || pr != nil {
+		t.Errorf("got %+v (err: %v), want nil", pr, err)
+	}
+}
+
+func TestClient_PullRequestActivity(t *testing.T) {
+	repo := &Repo{FullName: "sglocal/mux"}
+	cli := newMockClient(mockHTTPRoutes{
+		"GET /2.0/repositories/sglocal/mux/pullrequests/1/activity?pagelen=50": `{
+			"next": "https://api.bitbucket.org/2.0/repositories/sglocal/mux/pullrequests/1/activity?ctx=abc",
+			"values": [
+				{"approval": {"date": "2020-04-01T12:00:00Z", "user": {"uuid": "{u1}", "nickname": "alice"}}},
+				{"update": {"state": "OPEN", "date": "2020-04-01T11:00:00Z"}}
+			]
+		}`,
+		"GET /2.0/repositories/sglocal/mux/pullrequests/1/activity?ctx=abc": `{"values": [
+			{"comment": {"id": 7, "content": {"raw": "lgtm"}, "user": {"uuid": "{u1}"}}},
+			{"update": {"state": "MERGED", "date": "2020-04-02T12:00:00Z"}}
+		]}`,
+	})
+
+	activities, err := cli.PullRequestActivity(context.Background(), repo, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activities) != 4 {
+		t.Fatalf("got %d activities, want 4", len(activities))
+	}
+
+	var keys []string
+	for _, a := range activities {
+		if e := a.ToEvent(); e != nil {
+			keys = append(keys, e.Key())
+		}
+	}
+	want := []string{
+		"approval:{u1}:" + unix("2020-04-01T12:00:00Z"),
+		"7",
+		"update:MERGED:" + unix("2020-04-02T12:00:00Z"),
+	}
+	if diff := cmp.Diff(want, keys); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func unix(ts string) string {
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		panic(err)
+	}
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+func TestPullRequest_DetectUnapprovals(t *testing.T) {
+	t0 := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	now := t0.Add(time.Hour)
+	approval := func(uuid string) *Activity {
+		return &Activity{Approval: &Approval{User: Account{UUID: uuid}, Date: t0}}
+	}
+
+	earlier := &Unapproval{User: Account{UUID: "{u0}"}, ApprovalDate: t0.Add(-time.Hour), Date: t0}
+	prev := &PullRequest{
+		Activities:  []*Activity{approval("{u1}"), approval("{u2}"), approval("{u3}")},
+		Unapprovals: []*Unapproval{earlier},
+	}
+	pr := &PullRequest{
+		// {u1} still approves. {u2}'s approval is gone from the activity,
+		// but {u2} is still listed as approving, so it's not withdrawn yet.
+		Activities:   []*Activity{approval("{u1}")},
+		Participants: []Participant{{User: Account{UUID: "{u2}"}, Approved: true}},
+	}
+	pr.DetectUnapprovals(prev, now)
+
+	want := []*Unapproval{
+		earlier,
+		{User: Account{UUID: "{u3}"}, ApprovalDate: t0, Date: now},
+	}
+	if diff := cmp.Diff(want, pr.Unapprovals); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestClient_MergePullRequest(t *testing.T) {
+	repo := &Repo{FullName: "sglocal/mux"}
+	cli := newMockClient(mockHTTPRoutes{
+		"POST /2.0/repositories/sglocal/mux/pullrequests/1/merge": `{"id": 1, "state": "MERGED"}`,
+	})
+
+	pr, err := cli.MergePullRequest(context.Background(), repo, 1, MergeStrategySquash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.ID != 1 || pr.State != PullRequestStateMerged {
+		t.Errorf("unexpected pull request %+v", pr)
+	}
+
+	if _, err := cli.MergePullRequest(context.Background(), repo, 2, MergeStrategySquash); err == nil {
+		t.Error("expected error merging missing pull request")
+	}
+}
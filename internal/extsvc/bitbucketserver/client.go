@@ -25,6 +25,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
 	"github.com/sourcegraph/sourcegraph/internal/metrics"
+	"github.com/sourcegraph/sourcegraph/internal/ratelimit"
 	"github.com/sourcegraph/sourcegraph/schema"
 	"golang.org/x/time/rate"
 )
@@ -563,6 +564,36 @@ func (c *Client) DeclinePullRequest(ctx context.Context, pr *PullRequest) error
 	return c.send(ctx, "POST", path, qry, nil, pr)
 }
 
+// MergePullRequest merges the given PullRequest with the given merge
+// strategy (e.g. "no-ff", "squash" or "rebase-no-ff"), returning an error in
+// case of failure. An empty strategy uses the default strategy of the
+// repository.
+func (c *Client) MergePullRequest(ctx context.Context, pr *PullRequest, strategyID string) error {
+	if pr.ToRef.Repository.Slug == "" {
+		return errors.New("repository slug empty")
+	}
+
+	if pr.ToRef.Repository.Project.Key == "" {
+		return errors.New("project key empty")
+	}
+
+	path := fmt.Sprintf(
+		"rest/api/1.0/projects/%s/repos/%s/pull-requests/%d/merge",
+		pr.ToRef.Repository.Project.Key,
+		pr.ToRef.Repository.Slug,
+		pr.ID,
+	)
+
+	qry := url.Values{"version": {strconv.Itoa(pr.Version)}}
+
+	var payload interface{}
+	if strategyID != "" {
+		payload = map[string]string{"strategyId": strategyID}
+	}
+
+	return c.send(ctx, "POST", path, qry, payload, pr)
+}
+
 // LoadPullRequestActivities loads the given PullRequest's timeline of activities,
 // returning an error in case of failure.
 func (c *Client) LoadPullRequestActivities(ctx context.Context, pr *PullRequest) (err error) {
@@ -769,6 +800,20 @@ func (c *Client) send(ctx context.Context, method, path string, qry url.Values,
 	return c.do(ctx, req, result)
 }
 
+// rateLimitKey returns the credentials the rate limit of Bitbucket Server
+// applies to, so that all clients using them share a rate limit budget.
+func (c *Client) rateLimitKey() string {
+	switch {
+	case c.Oauth != nil:
+		// OAuth requests impersonate the user with the given username.
+		return "oauth:" + c.Oauth.Credentials.Token + ":" + c.Username
+	case c.Token != "":
+		return c.Token
+	default:
+		return c.Username + ":" + c.Password
+	}
+}
+
 func (c *Client) do(ctx context.Context, req *http.Request, result interface{}) error {
 	req.URL = c.URL.ResolveReference(req.URL)
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
@@ -792,10 +837,19 @@ func (c *Client) do(ctx context.Context, req *http.Request, result interface{})
 		log15.Warn("Bitbucket self-enforced API rate limit: request delayed longer than expected due to rate limit", "delay", d)
 	}
 
+	// Bitbucket doesn't report its rate limit in response headers, so the
+	// budget is unknown. The monitor still makes requests of the same
+	// credentials back off together once Bitbucket asks to retry later.
+	monitor := ratelimit.DefaultMonitorRegistry.GetOrSet(c.URL.String(), c.rateLimitKey(), "X-")
+	if err := monitor.Wait(ctx, 1); err != nil {
+		return err
+	}
+
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
+	monitor.Update(resp.Header)
 
 	defer resp.Body.Close()
 
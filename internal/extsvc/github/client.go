@@ -156,7 +156,7 @@ func NewClient(apiURL *url.URL, defaultToken string, cli httpcli.Doer) *Client {
 		githubDotCom: urlIsGitHubDotCom(apiURL),
 		defaultToken: defaultToken,
 		httpClient:   cli,
-		RateLimit:    &ratelimit.Monitor{HeaderPrefix: "X-"},
+		RateLimit:    ratelimit.DefaultMonitorRegistry.GetOrSet(apiURL.String(), defaultToken, "X-"),
 		repoCache:    map[string]*rcache.Cache{},
 	}
 }
@@ -196,6 +196,16 @@ func (c *Client) do(ctx context.Context, token string, req *http.Request, result
 		req.Header.Set("Authorization", "bearer "+c.defaultToken)
 	}
 
+	// Requests with another token than the default token draw from that
+	// token's rate limit budget.
+	rateLimit := c.RateLimit
+	if token != "" && token != c.defaultToken {
+		rateLimit = ratelimit.DefaultMonitorRegistry.GetOrSet(c.apiURL.String(), token, "X-")
+	}
+	if err := rateLimit.Wait(ctx, 1); err != nil {
+		return err
+	}
+
 	var resp *http.Response
 
 	span, ctx := opentracing.StartSpanFromContext(ctx, "GitHub")
@@ -216,7 +226,7 @@ func (c *Client) do(ctx context.Context, token string, req *http.Request, result
 	}
 
 	defer resp.Body.Close()
-	c.RateLimit.Update(resp.Header)
+	rateLimit.Update(resp.Header)
 	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
 		var err APIError
 		if body, readErr := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<13)); readErr != nil { // 8kb
@@ -288,6 +298,26 @@ func (c *Client) requestGet(ctx context.Context, token, requestURI string, resul
 	return c.do(ctx, token, req, result)
 }
 
+// requestJSON sends a request with the given method and body encoded as JSON
+// to the REST API and decodes the response into result.
+func (c *Client) requestJSON(ctx context.Context, token, method, requestURI string, body, result interface{}) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, requestURI, r)
+	if err != nil {
+		return err
+	}
+
+	return c.do(ctx, token, req, result)
+}
+
 func (c *Client) requestGraphQL(ctx context.Context, token, query string, vars map[string]interface{}, result interface{}) (err error) {
 	reqBody, err := json.Marshal(struct {
 		Query     string                 `json:"query"`
@@ -314,6 +344,9 @@ func (c *Client) requestGraphQL(ctx context.Context, token, query string, vars m
 	// Enable Checks API
 	// https://developer.github.com/v4/previews/#checks
 	req.Header.Add("Accept", "application/vnd.github.antiope-preview+json")
+	// Enable draft pull requests
+	// https://developer.github.com/v4/previews/#draft-pull-requests-preview
+	req.Header.Add("Accept", "application/vnd.github.shadow-cat-preview+json")
 	var respBody struct {
 		Data   json.RawMessage `json:"data"`
 		Errors graphqlErrors   `json:"errors"`
@@ -8,6 +8,7 @@ import (
 	"flag"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -16,11 +17,13 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/dnaeon/go-vcr/cassette"
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
 	"github.com/sourcegraph/sourcegraph/internal/httptestutil"
+	"github.com/sourcegraph/sourcegraph/internal/ratelimit"
 	"github.com/sourcegraph/sourcegraph/internal/rcache"
 	"github.com/sourcegraph/sourcegraph/internal/testutil"
 )
@@ -316,6 +319,43 @@ func TestClient_GetAuthenticatedUserOrgs(t *testing.T) {
 	)
 }
 
+func TestClient_RateLimitPerToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remaining := "4000"
+		if r.Header.Get("Authorization") == "bearer user-token" {
+			remaining = "100"
+		}
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", remaining)
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		fmt.Fprint(w, `{}`)
+	}))
+	defer srv.Close()
+
+	uri, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli := NewClient(uri, "default-token", nil)
+
+	ctx := context.Background()
+	var result struct{}
+	if err := cli.requestGet(ctx, "", "/", &result); err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.requestGet(ctx, "user-token", "/", &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if remaining, _, _, _ := cli.RateLimit.Get(); remaining != 4000 {
+		t.Errorf("default token: have %d remaining, want 4000", remaining)
+	}
+	userRateLimit := ratelimit.DefaultMonitorRegistry.GetOrSet(uri.String(), "user-token", "X-")
+	if remaining, _, _, _ := userRateLimit.Get(); remaining != 100 {
+		t.Errorf("user token: have %d remaining, want 100", remaining)
+	}
+}
+
 func newClient(t testing.TB, name string) (*Client, func()) {
 	t.Helper()
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -137,6 +138,7 @@ type PullRequest struct {
 	HeadRefName   string
 	BaseRefName   string
 	Number        int64
+	IsDraft       bool
 	Author        Actor
 	Participants  []Actor
 	Labels        struct{ Nodes []Label }
@@ -457,6 +459,9 @@ type CreatePullRequestInput struct {
 	Title string `json:"title"`
 	// The body of the pull request (optional).
 	Body string `json:"body"`
+	// Whether the pull request is a draft that can't be merged until it's
+	// marked as ready for review (optional).
+	Draft bool `json:"draft,omitempty"`
 }
 
 // CreatePullRequest creates a PullRequest on Github.
@@ -588,6 +593,154 @@ func (c *Client) ClosePullRequest(ctx context.Context, pr *PullRequest) error {
 	return nil
 }
 
+// MergePullRequest merges the PullRequest on GitHub with the given merge
+// method ("MERGE", "SQUASH" or "REBASE") and updates it. The pull request is
+// only merged if its head is still at pr.HeadRefOid.
+func (c *Client) MergePullRequest(ctx context.Context, pr *PullRequest, mergeMethod string) error {
+	var q strings.Builder
+	q.WriteString(pullRequestFragments)
+	q.WriteString(`mutation	MergePullRequest($input:MergePullRequestInput!) {
+  mergePullRequest(input:$input) {
+    pullRequest {
+      ... pr
+    }
+  }
+}`)
+
+	var result struct {
+		MergePullRequest struct {
+			PullRequest struct {
+				PullRequest
+				Participants  struct{ Nodes []Actor }
+				TimelineItems struct{ Nodes []TimelineItem }
+			} `json:"pullRequest"`
+		} `json:"mergePullRequest"`
+	}
+
+	input := map[string]interface{}{"input": struct {
+		ID              string `json:"pullRequestId"`
+		MergeMethod     string `json:"mergeMethod"`
+		ExpectedHeadOid string `json:"expectedHeadOid,omitempty"`
+	}{
+		ID:              pr.ID,
+		MergeMethod:     mergeMethod,
+		ExpectedHeadOid: pr.HeadRefOid,
+	}}
+	err := c.requestGraphQL(ctx, "", q.String(), input, &result)
+	if err != nil {
+		return err
+	}
+
+	*pr = result.MergePullRequest.PullRequest.PullRequest
+	pr.TimelineItems = result.MergePullRequest.PullRequest.TimelineItems.Nodes
+	pr.Participants = result.MergePullRequest.PullRequest.Participants.Nodes
+
+	return nil
+}
+
+// MarkPullRequestReadyForReview marks the draft PullRequest on GitHub as
+// ready for review and updates it.
+func (c *Client) MarkPullRequestReadyForReview(ctx context.Context, pr *PullRequest) error {
+	var q strings.Builder
+	q.WriteString(pullRequestFragments)
+	q.WriteString(`mutation	MarkPullRequestReadyForReview($input:MarkPullRequestReadyForReviewInput!) {
+  markPullRequestReadyForReview(input:$input) {
+    pullRequest {
+      ... pr
+    }
+  }
+}`)
+
+	var result struct {
+		MarkPullRequestReadyForReview struct {
+			PullRequest struct {
+				PullRequest
+				Participants  struct{ Nodes []Actor }
+				TimelineItems struct{ Nodes []TimelineItem }
+			} `json:"pullRequest"`
+		} `json:"markPullRequestReadyForReview"`
+	}
+
+	input := map[string]interface{}{"input": struct {
+		ID string `json:"pullRequestId"`
+	}{ID: pr.ID}}
+	err := c.requestGraphQL(ctx, "", q.String(), input, &result)
+	if err != nil {
+		return err
+	}
+
+	*pr = result.MarkPullRequestReadyForReview.PullRequest.PullRequest
+	pr.TimelineItems = result.MarkPullRequestReadyForReview.PullRequest.TimelineItems.Nodes
+	pr.Participants = result.MarkPullRequestReadyForReview.PullRequest.Participants.Nodes
+
+	return nil
+}
+
+// AddPullRequestComment posts a comment with the given body on the
+// PullRequest on GitHub.
+func (c *Client) AddPullRequestComment(ctx context.Context, pr *PullRequest, body string) error {
+	q := `mutation	AddComment($input:AddCommentInput!) {
+  addComment(input:$input) {
+    subject { id }
+  }
+}`
+
+	var result struct {
+		AddComment struct {
+			Subject struct {
+				ID string `json:"id"`
+			} `json:"subject"`
+		} `json:"addComment"`
+	}
+
+	input := map[string]interface{}{"input": struct {
+		SubjectID string `json:"subjectId"`
+		Body      string `json:"body"`
+	}{SubjectID: pr.ID, Body: body}}
+	return c.requestGraphQL(ctx, "", q, input, &result)
+}
+
+// AddPullRequestLabels adds the labels with the given names to the pull
+// request with the given number in the repository with the given owner and
+// name. Labels that don't exist yet are created by GitHub.
+func (c *Client) AddPullRequestLabels(ctx context.Context, owner, name string, number int64, labels []string) error {
+	requestURI := fmt.Sprintf("repos/%s/%s/issues/%d/labels", owner, name, number)
+	body := struct {
+		Labels []string `json:"labels"`
+	}{Labels: labels}
+
+	var result []struct{ Name string }
+	return c.requestJSON(ctx, "", "POST", requestURI, body, &result)
+}
+
+// RemovePullRequestLabel removes the label with the given name from the pull
+// request with the given number in the repository with the given owner and
+// name.
+func (c *Client) RemovePullRequestLabel(ctx context.Context, owner, name string, number int64, label string) error {
+	requestURI := fmt.Sprintf("repos/%s/%s/issues/%d/labels/%s", owner, name, number, url.PathEscape(label))
+
+	var result []struct{ Name string }
+	err := c.requestJSON(ctx, "", "DELETE", requestURI, nil, &result)
+	if err != nil && IsNotFound(err) {
+		// The label isn't set on the pull request.
+		return nil
+	}
+	return err
+}
+
+// RequestPullRequestReviewers requests reviews from the users with the given
+// logins on the pull request with the given number in the repository with
+// the given owner and name.
+func (c *Client) RequestPullRequestReviewers(ctx context.Context, owner, name string, number int64, reviewers []string) error {
+	requestURI := fmt.Sprintf("repos/%s/%s/pulls/%d/requested_reviewers", owner, name, number)
+	body := struct {
+		Reviewers []string `json:"reviewers"`
+	}{Reviewers: reviewers}
+
+	var result struct{}
+	return c.requestJSON(ctx, "", "POST", requestURI, body, &result)
+}
+
 // LoadPullRequests loads a list of PullRequests from Github.
 func (c *Client) LoadPullRequests(ctx context.Context, prs ...*PullRequest) error {
 	const batchSize = 15
@@ -806,6 +959,7 @@ fragment pr on PullRequest {
   state
   url
   number
+  isDraft
   createdAt
   updatedAt
   headRefOid
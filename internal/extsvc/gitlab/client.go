@@ -67,7 +67,7 @@ type ClientProvider struct {
 	gitlabClients   map[string]*Client
 	gitlabClientsMu sync.Mutex
 
-	RateLimit *ratelimit.Monitor // the API rate limit monitor
+	RateLimit *ratelimit.Monitor // the API rate limit monitor of unauthenticated clients
 }
 
 type CommonOp struct {
@@ -89,11 +89,12 @@ func NewClientProvider(baseURL *url.URL, cli httpcli.Doer) *ClientProvider {
 		return category
 	})
 
+	baseURL = baseURL.ResolveReference(&url.URL{Path: path.Join(baseURL.Path, "api/v4") + "/"})
 	return &ClientProvider{
-		baseURL:       baseURL.ResolveReference(&url.URL{Path: path.Join(baseURL.Path, "api/v4") + "/"}),
+		baseURL:       baseURL,
 		httpClient:    cli,
 		gitlabClients: make(map[string]*Client),
-		RateLimit:     &ratelimit.Monitor{},
+		RateLimit:     ratelimit.DefaultMonitorRegistry.GetOrSet(baseURL.String(), "", ""),
 	}
 }
 
@@ -141,7 +142,13 @@ func (p *ClientProvider) getClient(op getClientOp) *Client {
 		return c
 	}
 
-	c := p.newClient(p.baseURL, op, p.httpClient, p.RateLimit)
+	// GitLab rate limits each token separately, so clients only share the
+	// rate limit budget with the clients for the same token.
+	rateLimit := p.RateLimit
+	if token := op.personalAccessToken + op.oauthToken; token != "" {
+		rateLimit = ratelimit.DefaultMonitorRegistry.GetOrSet(p.baseURL.String(), token, "")
+	}
+	c := p.newClient(p.baseURL, op, p.httpClient, rateLimit)
 	p.gitlabClients[key] = c
 	return c
 }
@@ -213,6 +220,10 @@ func (c *Client) do(ctx context.Context, req *http.Request, result interface{})
 		req.Header.Set("Sudo", c.Sudo)
 	}
 
+	if err := c.RateLimit.Wait(ctx, 1); err != nil {
+		return nil, err
+	}
+
 	var resp *http.Response
 
 	span, ctx := opentracing.StartSpanFromContext(ctx, "GitLab")
@@ -236,7 +247,7 @@ func (c *Client) do(ctx context.Context, req *http.Request, result interface{})
 	trace("GitLab API", "method", req.Method, "url", req.URL.String(), "respCode", resp.StatusCode)
 
 	c.RateLimit.Update(resp.Header)
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return nil, errors.Wrap(httpError(resp.StatusCode), fmt.Sprintf("unexpected response from GitLab API (%s)", req.URL))
 	}
 
@@ -0,0 +1,284 @@
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/peterhellberg/link"
+	"github.com/pkg/errors"
+)
+
+// MergeRequestState is the state of a GitLab merge request.
+type MergeRequestState string
+
+const (
+	MergeRequestStateOpened MergeRequestState = "opened"
+	MergeRequestStateClosed MergeRequestState = "closed"
+	MergeRequestStateLocked MergeRequestState = "locked"
+	MergeRequestStateMerged MergeRequestState = "merged"
+)
+
+// MergeRequest is a GitLab merge request (equivalent to a GitHub pull request).
+type MergeRequest struct {
+	ID              int               `json:"id"`
+	IID             int               `json:"iid"` // the ID of the merge request within its project
+	ProjectID       int               `json:"project_id"`
+	SourceProjectID int               `json:"source_project_id"`
+	Title           string            `json:"title"`
+	Description     string            `json:"description"`
+	State           MergeRequestState `json:"state"`
+	WebURL          string            `json:"web_url"`
+	SourceBranch    string            `json:"source_branch"`
+	TargetBranch    string            `json:"target_branch"`
+	Labels          []string          `json:"labels"`
+	WorkInProgress  bool              `json:"work_in_progress"`
+	Author          User              `json:"author"`
+	DiffRefs        DiffRefs          `json:"diff_refs"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	MergedAt        *time.Time        `json:"merged_at"`
+	ClosedAt        *time.Time        `json:"closed_at"`
+
+	// Notes and Pipelines are not part of the merge request returned by the
+	// GitLab API. They are loaded separately with GetMergeRequestNotes and
+	// GetMergeRequestPipelines.
+	Notes     []*Note     `json:"notes,omitempty"`
+	Pipelines []*Pipeline `json:"pipelines,omitempty"`
+}
+
+// DiffRefs are the commits a merge request is based on.
+type DiffRefs struct {
+	BaseSHA  string `json:"base_sha"`
+	HeadSHA  string `json:"head_sha"`
+	StartSHA string `json:"start_sha"`
+}
+
+// ErrMergeRequestAlreadyExists is returned by CreateMergeRequest when an open
+// merge request for the same source branch already exists.
+var ErrMergeRequestAlreadyExists = errors.New("merge request already exists")
+
+// CreateMergeRequestOpts are the options of CreateMergeRequest.
+type CreateMergeRequestOpts struct {
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+	Title        string `json:"title"`
+	Description  string `json:"description,omitempty"`
+}
+
+// CreateMergeRequest creates a merge request in the given project.
+func (c *Client) CreateMergeRequest(ctx context.Context, project *Project, opts CreateMergeRequestOpts) (*MergeRequest, error) {
+	if MockCreateMergeRequest != nil {
+		return MockCreateMergeRequest(c, ctx, project, opts)
+	}
+
+	req, err := newJSONRequest("POST", fmt.Sprintf("projects/%d/merge_requests", project.ID), &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var mr MergeRequest
+	if _, err := c.do(ctx, req, &mr); err != nil {
+		if HTTPErrorCode(err) == http.StatusConflict {
+			return nil, ErrMergeRequestAlreadyExists
+		}
+		return nil, errors.Wrap(err, "creating merge request")
+	}
+	return &mr, nil
+}
+
+// GetMergeRequest returns the merge request with the given project-scoped ID.
+func (c *Client) GetMergeRequest(ctx context.Context, project *Project, iid int) (*MergeRequest, error) {
+	if MockGetMergeRequest != nil {
+		return MockGetMergeRequest(c, ctx, project, iid)
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("projects/%d/merge_requests/%d", project.ID, iid), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var mr MergeRequest
+	if _, err := c.do(ctx, req, &mr); err != nil {
+		return nil, errors.Wrap(err, "getting merge request")
+	}
+	return &mr, nil
+}
+
+// GetOpenMergeRequestByRefs returns the open merge request from source to
+// target branch in the given project.
+func (c *Client) GetOpenMergeRequestByRefs(ctx context.Context, project *Project, source, target string) (*MergeRequest, error) {
+	if MockGetOpenMergeRequestByRefs != nil {
+		return MockGetOpenMergeRequestByRefs(c, ctx, project, source, target)
+	}
+
+	q := url.Values{
+		"state":         {string(MergeRequestStateOpened)},
+		"source_branch": {source},
+		"target_branch": {target},
+	}
+	req, err := http.NewRequest("GET", fmt.Sprintf("projects/%d/merge_requests?%s", project.ID, q.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var mrs []*MergeRequest
+	if _, err := c.do(ctx, req, &mrs); err != nil {
+		return nil, errors.Wrap(err, "listing merge requests")
+	}
+	if len(mrs) != 1 {
+		return nil, errors.Errorf("expected one open merge request from %q to %q, got %d", source, target, len(mrs))
+	}
+	return mrs[0], nil
+}
+
+// UpdateMergeRequestOpts are the options of UpdateMergeRequest. Empty fields
+// are left unchanged.
+type UpdateMergeRequestOpts struct {
+	TargetBranch string `json:"target_branch,omitempty"`
+	Title        string `json:"title,omitempty"`
+	Description  string `json:"description,omitempty"`
+	// StateEvent is either "close" or "reopen".
+	StateEvent string `json:"state_event,omitempty"`
+	// AddLabels and RemoveLabels are comma-separated lists of the names of
+	// the labels to add to and remove from the merge request.
+	AddLabels    string `json:"add_labels,omitempty"`
+	RemoveLabels string `json:"remove_labels,omitempty"`
+}
+
+// UpdateMergeRequest updates the given merge request and returns its new
+// state.
+func (c *Client) UpdateMergeRequest(ctx context.Context, project *Project, mr *MergeRequest, opts UpdateMergeRequestOpts) (*MergeRequest, error) {
+	if MockUpdateMergeRequest != nil {
+		return MockUpdateMergeRequest(c, ctx, project, mr, opts)
+	}
+
+	req, err := newJSONRequest("PUT", fmt.Sprintf("projects/%d/merge_requests/%d", project.ID, mr.IID), &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated MergeRequest
+	if _, err := c.do(ctx, req, &updated); err != nil {
+		return nil, errors.Wrap(err, "updating merge request")
+	}
+	return &updated, nil
+}
+
+// AcceptMergeRequestOpts are the options of AcceptMergeRequest.
+type AcceptMergeRequestOpts struct {
+	// SHA, if set, must match the head of the source branch for the merge
+	// request to be merged.
+	SHA    string `json:"sha,omitempty"`
+	Squash bool   `json:"squash,omitempty"`
+}
+
+// AcceptMergeRequest merges the given merge request according to the merge
+// method of its project and returns its new state.
+func (c *Client) AcceptMergeRequest(ctx context.Context, project *Project, mr *MergeRequest, opts AcceptMergeRequestOpts) (*MergeRequest, error) {
+	if MockAcceptMergeRequest != nil {
+		return MockAcceptMergeRequest(c, ctx, project, mr, opts)
+	}
+
+	req, err := newJSONRequest("PUT", fmt.Sprintf("projects/%d/merge_requests/%d/merge", project.ID, mr.IID), &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var merged MergeRequest
+	if _, err := c.do(ctx, req, &merged); err != nil {
+		return nil, errors.Wrap(err, "accepting merge request")
+	}
+	return &merged, nil
+}
+
+// GetMergeRequestNotes returns all notes of the given merge request, including
+// system notes such as approvals.
+func (c *Client) GetMergeRequestNotes(ctx context.Context, project *Project, iid int) ([]*Note, error) {
+	if MockGetMergeRequestNotes != nil {
+		return MockGetMergeRequestNotes(c, ctx, project, iid)
+	}
+
+	var all []*Note
+	next := fmt.Sprintf("projects/%d/merge_requests/%d/notes?sort=asc&per_page=100", project.ID, iid)
+	for next != "" {
+		var notes []*Note
+		var err error
+		if next, err = c.getPage(ctx, next, &notes); err != nil {
+			return nil, errors.Wrap(err, "getting merge request notes")
+		}
+		all = append(all, notes...)
+	}
+	return all, nil
+}
+
+// CreateMergeRequestNote posts a note with the given body on the given merge
+// request.
+func (c *Client) CreateMergeRequestNote(ctx context.Context, project *Project, iid int, body string) (*Note, error) {
+	if MockCreateMergeRequestNote != nil {
+		return MockCreateMergeRequestNote(c, ctx, project, iid, body)
+	}
+
+	req, err := newJSONRequest("POST", fmt.Sprintf("projects/%d/merge_requests/%d/notes", project.ID, iid), struct {
+		Body string `json:"body"`
+	}{Body: body})
+	if err != nil {
+		return nil, err
+	}
+
+	var note Note
+	if _, err := c.do(ctx, req, &note); err != nil {
+		return nil, errors.Wrap(err, "creating merge request note")
+	}
+	return &note, nil
+}
+
+// GetMergeRequestPipelines returns all pipelines of the given merge request.
+func (c *Client) GetMergeRequestPipelines(ctx context.Context, project *Project, iid int) ([]*Pipeline, error) {
+	if MockGetMergeRequestPipelines != nil {
+		return MockGetMergeRequestPipelines(c, ctx, project, iid)
+	}
+
+	var all []*Pipeline
+	next := fmt.Sprintf("projects/%d/merge_requests/%d/pipelines?per_page=100", project.ID, iid)
+	for next != "" {
+		var pipelines []*Pipeline
+		var err error
+		if next, err = c.getPage(ctx, next, &pipelines); err != nil {
+			return nil, errors.Wrap(err, "getting merge request pipelines")
+		}
+		all = append(all, pipelines...)
+	}
+	return all, nil
+}
+
+// getPage decodes the page at urlStr into result and returns the URL of the
+// next page, or "" if it was the last page.
+func (c *Client) getPage(ctx context.Context, urlStr string, result interface{}) (string, error) {
+	req, err := http.NewRequest("GET", urlStr, nil)
+	if err != nil {
+		return "", err
+	}
+	respHeader, err := c.do(ctx, req, result)
+	if err != nil {
+		return "", err
+	}
+
+	// Get URL to next page. See https://docs.gitlab.com/ee/api/README.html#pagination-link-header.
+	if l := link.Parse(respHeader.Get("Link"))["next"]; l != nil {
+		return l.URI, nil
+	}
+	return "", nil
+}
+
+func newJSONRequest(method, urlStr string, body interface{}) (*http.Request, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return http.NewRequest(method, urlStr, bytes.NewReader(b))
+}
@@ -0,0 +1,77 @@
+package gitlab
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type mockHTTPRoutes map[string]*http.Response
+
+func (m mockHTTPRoutes) Do(req *http.Request) (*http.Response, error) {
+	resp, ok := m[req.Method+" "+req.URL.RequestURI()]
+	if !ok {
+		resp = &http.Response{StatusCode: http.StatusNotFound, Body: ioutil.NopCloser(strings.NewReader(""))}
+	}
+	resp.Request = req
+	return resp, nil
+}
+
+func jsonResponse(code int, body string, header http.Header) *http.Response {
+	return &http.Response{
+		StatusCode: code,
+		Header:     header,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestClient_CreateMergeRequest(t *testing.T) {
+	c := newTestClient(t)
+	project := &Project{ProjectCommon: ProjectCommon{ID: 1}}
+
+	c.httpClient = mockHTTPRoutes{
+		"POST /projects/1/merge_requests": jsonResponse(http.StatusCreated, `{"id": 10, "iid": 2, "title": "t", "state": "opened", "source_branch": "s"}`, nil),
+	}
+	mr, err := c.CreateMergeRequest(context.Background(), project, CreateMergeRequestOpts{SourceBranch: "s", TargetBranch: "master", Title: "t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mr.IID != 2 || mr.State != MergeRequestStateOpened || mr.SourceBranch != "s" {
+		t.Errorf("unexpected merge request %+v", mr)
+	}
+
+	c.httpClient = mockHTTPRoutes{
+		"POST /projects/1/merge_requests": jsonResponse(http.StatusConflict, `{"message": ["Another open merge request already exists for this source branch: !2"]}`, nil),
+	}
+	if _, err := c.CreateMergeRequest(context.Background(), project, CreateMergeRequestOpts{SourceBranch: "s", TargetBranch: "master", Title: "t"}); err != ErrMergeRequestAlreadyExists {
+		t.Errorf("got error %v, want %v", err, ErrMergeRequestAlreadyExists)
+	}
+}
+
+func TestClient_GetMergeRequestNotes(t *testing.T) {
+	c := newTestClient(t)
+	project := &Project{ProjectCommon: ProjectCommon{ID: 1}}
+
+	next := http.Header{"Link": {`<https://example.com/projects/1/merge_requests/2/notes?page=2&per_page=100&sort=asc>; rel="next"`}}
+	c.httpClient = mockHTTPRoutes{
+		"GET /projects/1/merge_requests/2/notes?sort=asc&per_page=100":        jsonResponse(http.StatusOK, `[{"id": 1, "body": "a"}]`, next),
+		"GET /projects/1/merge_requests/2/notes?page=2&per_page=100&sort=asc": jsonResponse(http.StatusOK, `[{"id": 2, "body": "approved this merge request", "system": true}]`, nil),
+	}
+
+	notes, err := c.GetMergeRequestNotes(context.Background(), project, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 2 || notes[0].ID != 1 || notes[1].ID != 2 {
+		t.Fatalf("unexpected notes %+v", notes)
+	}
+	if _, ok := notes[1].ToEvent().(*ReviewApprovedEvent); !ok {
+		t.Errorf("expected approval event, got %T", notes[1].ToEvent())
+	}
+
+	if _, err := c.GetMergeRequestNotes(context.Background(), project, 3); !IsNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
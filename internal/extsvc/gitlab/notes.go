@@ -0,0 +1,125 @@
+package gitlab
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Note is a comment on a GitLab merge request. GitLab also records many
+// changes of a merge request, such as approvals, as system notes.
+type Note struct {
+	ID        int       `json:"id"`
+	Body      string    `json:"body"`
+	Author    User      `json:"author"`
+	System    bool      `json:"system"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Key is a unique key identifying this note.
+func (n *Note) Key() string { return strconv.Itoa(n.ID) }
+
+// The bodies of the system notes which GitLab creates for changes of a
+// merge request that we're interested in.
+const (
+	systemNoteApproved   = "approved this merge request"
+	systemNoteUnapproved = "unapproved this merge request"
+	systemNoteClosed     = "closed"
+	systemNoteReopened   = "reopened"
+	systemNoteMerged     = "merged"
+)
+
+// ToEvent returns the merge request event the note represents. A user's note
+// is a comment and is returned as is, while system notes are converted to the
+// event they record. System notes we don't know about are returned as nil.
+func (n *Note) ToEvent() interface{ Key() string } {
+	if !n.System {
+		return n
+	}
+
+	switch n.Body {
+	case systemNoteApproved:
+		return &ReviewApprovedEvent{Note: *n}
+	case systemNoteUnapproved:
+		return &ReviewUnapprovedEvent{Note: *n}
+	case systemNoteClosed:
+		return &MergeRequestClosedEvent{Note: *n}
+	case systemNoteReopened:
+		return &MergeRequestReopenedEvent{Note: *n}
+	case systemNoteMerged:
+		return &MergeRequestMergedEvent{Note: *n}
+	}
+	return nil
+}
+
+// The following events are recorded by GitLab as system notes. Their keys are
+// derived from the ID of the note, which is stable. The merge request webhooks
+// sent for them don't include the note, so they only trigger syncing the notes
+// (see MergeRequestEvent.RecordedAsNote) rather than being events themselves.
+
+// ReviewApprovedEvent is the approval of a merge request.
+type ReviewApprovedEvent struct{ Note }
+
+// Key is a unique key identifying this event.
+func (e *ReviewApprovedEvent) Key() string { return eventKey("approved", &e.Note) }
+
+// ReviewUnapprovedEvent is the removal of a previous approval of a merge
+// request.
+type ReviewUnapprovedEvent struct{ Note }
+
+// Key is a unique key identifying this event.
+func (e *ReviewUnapprovedEvent) Key() string { return eventKey("unapproved", &e.Note) }
+
+// MergeRequestClosedEvent is the closing of a merge request.
+type MergeRequestClosedEvent struct{ Note }
+
+// Key is a unique key identifying this event.
+func (e *MergeRequestClosedEvent) Key() string { return eventKey("closed", &e.Note) }
+
+// MergeRequestReopenedEvent is the reopening of a closed merge request.
+type MergeRequestReopenedEvent struct{ Note }
+
+// Key is a unique key identifying this event.
+func (e *MergeRequestReopenedEvent) Key() string { return eventKey("reopened", &e.Note) }
+
+// MergeRequestMergedEvent is the merging of a merge request.
+type MergeRequestMergedEvent struct{ Note }
+
+// Key is a unique key identifying this event.
+func (e *MergeRequestMergedEvent) Key() string { return eventKey("merged", &e.Note) }
+
+func eventKey(kind string, n *Note) string {
+	return fmt.Sprintf("%s:%d", kind, n.ID)
+}
+
+// PipelineStatus is the status of a GitLab CI pipeline.
+type PipelineStatus string
+
+const (
+	PipelineStatusCreated            PipelineStatus = "created"
+	PipelineStatusWaitingForResource PipelineStatus = "waiting_for_resource"
+	PipelineStatusPreparing          PipelineStatus = "preparing"
+	PipelineStatusPending            PipelineStatus = "pending"
+	PipelineStatusRunning            PipelineStatus = "running"
+	PipelineStatusSuccess            PipelineStatus = "success"
+	PipelineStatusFailed             PipelineStatus = "failed"
+	PipelineStatusCanceled           PipelineStatus = "canceled"
+	PipelineStatusSkipped            PipelineStatus = "skipped"
+	PipelineStatusManual             PipelineStatus = "manual"
+	PipelineStatusScheduled          PipelineStatus = "scheduled"
+)
+
+// Pipeline is a GitLab CI pipeline run for a merge request.
+type Pipeline struct {
+	ID        int            `json:"id"`
+	SHA       string         `json:"sha"`
+	Ref       string         `json:"ref"`
+	Status    PipelineStatus `json:"status"`
+	WebURL    string         `json:"web_url"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// Key is a unique key identifying this pipeline.
+func (p *Pipeline) Key() string { return strconv.Itoa(p.ID) }
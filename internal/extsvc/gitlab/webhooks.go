@@ -0,0 +1,149 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	eventTypeHeader = "X-Gitlab-Event"
+	tokenHeader     = "X-Gitlab-Token"
+)
+
+// WebhookEventType returns the type of the GitLab webhook event in r, e.g.
+// "Merge Request Hook".
+func WebhookEventType(r *http.Request) string {
+	return r.Header.Get(eventTypeHeader)
+}
+
+// WebhookToken returns the secret token GitLab sent along with the webhook
+// event in r.
+func WebhookToken(r *http.Request) string {
+	return r.Header.Get(tokenHeader)
+}
+
+// ParseWebhookEvent parses the payload of a webhook event of the given type.
+// Events which are not about merge requests are returned as nil.
+func ParseWebhookEvent(eventType string, payload []byte) (e interface{}, err error) {
+	switch eventType {
+	case "Merge Request Hook":
+		e = &MergeRequestEvent{}
+	case "Note Hook":
+		e = &NoteEvent{}
+	case "Pipeline Hook":
+		e = &PipelineEvent{}
+	default:
+		return nil, nil
+	}
+	return e, json.Unmarshal(payload, e)
+}
+
+// MergeRequestEvent is sent when a merge request is created, updated, closed,
+// reopened, merged, approved or unapproved.
+type MergeRequestEvent struct {
+	User             User          `json:"user"`
+	Project          ProjectCommon `json:"project"`
+	ObjectAttributes struct {
+		IID       int    `json:"iid"`
+		Action    string `json:"action"`
+		UpdatedAt Time   `json:"updated_at"`
+	} `json:"object_attributes"`
+}
+
+// RecordedAsNote reports whether e is about a change of the merge request
+// which GitLab records as a system note we turn into an event (see
+// Note.ToEvent). The payload doesn't include the note, so the event can only
+// be recorded by syncing the notes of the merge request.
+func (e *MergeRequestEvent) RecordedAsNote() bool {
+	switch e.ObjectAttributes.Action {
+	case "approved", "unapproved", "close", "reopen", "merge":
+		return true
+	}
+	return false
+}
+
+// NoteEvent is sent when a note is added to a merge request, issue, commit
+// or snippet.
+type NoteEvent struct {
+	User             User          `json:"user"`
+	Project          ProjectCommon `json:"project"`
+	ObjectAttributes struct {
+		ID           int    `json:"id"`
+		Note         string `json:"note"`
+		NoteableType string `json:"noteable_type"`
+		System       bool   `json:"system"`
+		CreatedAt    Time   `json:"created_at"`
+		UpdatedAt    Time   `json:"updated_at"`
+	} `json:"object_attributes"`
+	MergeRequest *struct {
+		IID int `json:"iid"`
+	} `json:"merge_request"`
+}
+
+// Note returns the merge request note of the event, or nil if the note
+// isn't on a merge request.
+func (e *NoteEvent) Note() *Note {
+	if e.ObjectAttributes.NoteableType != "MergeRequest" || e.MergeRequest == nil {
+		return nil
+	}
+	return &Note{
+		ID:        e.ObjectAttributes.ID,
+		Body:      e.ObjectAttributes.Note,
+		Author:    e.User,
+		System:    e.ObjectAttributes.System,
+		CreatedAt: e.ObjectAttributes.CreatedAt.Time,
+		UpdatedAt: e.ObjectAttributes.UpdatedAt.Time,
+	}
+}
+
+// PipelineEvent is sent when the status of a pipeline changes.
+type PipelineEvent struct {
+	Project          ProjectCommon `json:"project"`
+	ObjectAttributes struct {
+		ID         int            `json:"id"`
+		Ref        string         `json:"ref"`
+		SHA        string         `json:"sha"`
+		Status     PipelineStatus `json:"status"`
+		CreatedAt  Time           `json:"created_at"`
+		FinishedAt *Time          `json:"finished_at"`
+	} `json:"object_attributes"`
+	// MergeRequest is only set for pipelines run for a merge request.
+	MergeRequest *struct {
+		IID int `json:"iid"`
+	} `json:"merge_request"`
+}
+
+// Time is a timestamp in a webhook payload. Unlike the API, GitLab doesn't
+// use RFC 3339 for timestamps in webhook payloads, e.g.
+// "2020-04-01 12:00:00 UTC".
+type Time struct {
+	time.Time
+}
+
+var webhookTimeLayouts = []string{
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05 -0700",
+	time.RFC3339,
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *Time) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		t.Time = time.Time{}
+		return nil
+	}
+	for _, layout := range webhookTimeLayouts {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			t.Time = parsed.UTC()
+			return nil
+		}
+	}
+	return errors.Errorf("unrecognized GitLab webhook timestamp %q", s)
+}
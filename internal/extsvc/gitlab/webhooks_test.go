@@ -0,0 +1,98 @@
+package gitlab
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWebhookEvent(t *testing.T) {
+	t.Run("merge request approved", func(t *testing.T) {
+		payload := `{
+			"object_kind": "merge_request",
+			"user": {"name": "Jane Doe", "username": "jane"},
+			"project": {"id": 1, "path_with_namespace": "a/b"},
+			"object_attributes": {"iid": 2, "action": "approved", "updated_at": "2020-04-01 12:00:00 UTC"}
+		}`
+		e, err := ParseWebhookEvent("Merge Request Hook", []byte(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		mre, ok := e.(*MergeRequestEvent)
+		if !ok {
+			t.Fatalf("got %T, want *MergeRequestEvent", e)
+		}
+		if mre.Project.ID != 1 || mre.ObjectAttributes.IID != 2 {
+			t.Errorf("unexpected event %+v", mre)
+		}
+
+		if !mre.RecordedAsNote() {
+			t.Error("expected approval to be recorded as a note")
+		}
+	})
+
+	t.Run("merge request updated", func(t *testing.T) {
+		e, err := ParseWebhookEvent("Merge Request Hook", []byte(`{"object_attributes": {"action": "update"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.(*MergeRequestEvent).RecordedAsNote() {
+			t.Error("expected update not to be recorded as a note")
+		}
+	})
+
+	t.Run("note on issue", func(t *testing.T) {
+		e, err := ParseWebhookEvent("Note Hook", []byte(`{"object_attributes": {"id": 1, "noteable_type": "Issue"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := e.(*NoteEvent).Note(); n != nil {
+			t.Errorf("expected no note, got %+v", n)
+		}
+	})
+
+	t.Run("system note keys", func(t *testing.T) {
+		// The same system note synced at different times, e.g. after it was
+		// edited, must result in the same event.
+		a := &Note{ID: 42, Author: User{Username: "jane"}, Body: "approved this merge request", System: true, CreatedAt: time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)}
+		b := *a
+		b.UpdatedAt = a.CreatedAt.Add(time.Minute)
+		if a.ToEvent().Key() != b.ToEvent().Key() {
+			t.Errorf("got different keys %q and %q", a.ToEvent().Key(), b.ToEvent().Key())
+		}
+		// Approving again is another event.
+		c := *a
+		c.ID, c.CreatedAt = 43, a.CreatedAt.Add(time.Hour)
+		if a.ToEvent().Key() == c.ToEvent().Key() {
+			t.Errorf("got the same key %q for different approvals", a.ToEvent().Key())
+		}
+	})
+
+	t.Run("note on merge request", func(t *testing.T) {
+		payload := `{
+			"user": {"username": "jane"},
+			"object_attributes": {"id": 3, "note": "lgtm", "noteable_type": "MergeRequest", "created_at": "2020-04-01T12:00:00Z"},
+			"merge_request": {"iid": 2}
+		}`
+		e, err := ParseWebhookEvent("Note Hook", []byte(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := e.(*NoteEvent).Note()
+		if n == nil || n.ID != 3 || n.Body != "lgtm" || n.Author.Username != "jane" || n.CreatedAt.IsZero() {
+			t.Errorf("unexpected note %+v", n)
+		}
+	})
+
+	t.Run("unsupported event", func(t *testing.T) {
+		e, err := ParseWebhookEvent("Push Hook", []byte(`{}`))
+		if err != nil || e != nil {
+			t.Errorf("got %v (err: %v), want nil", e, err)
+		}
+	})
+
+	t.Run("invalid timestamp", func(t *testing.T) {
+		if _, err := ParseWebhookEvent("Pipeline Hook", []byte(`{"object_attributes": {"created_at": "yesterday"}}`)); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
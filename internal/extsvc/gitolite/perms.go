@@ -0,0 +1,322 @@
+package gitolite
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+)
+
+// Perms are the read permissions of the users of a Gitolite server, as computed from the access
+// rules of its gitolite-admin repository.
+type Perms struct {
+	// Users are the names of all users that have a key in the keydir/ directory.
+	Users []string
+
+	// Repos are the users with read access to each repository.
+	Repos []*RepoPerms
+}
+
+// RepoPerms are the users with read access to a Gitolite repository.
+type RepoPerms struct {
+	// Name is the name of the repository as it is returned by `ssh git@GITOLITE_HOST info`
+	Name string
+
+	// Users are the names of the users with read access to the repository.
+	Users []string
+}
+
+// adminRepo is the name of the repository that holds the Gitolite access rules and user keys.
+const adminRepo = "gitolite-admin"
+
+// ListRepoPerms returns the read permissions of the users of the Gitolite server on the
+// repositories returned by ListRepos. It reads the access rules and user keys from the
+// gitolite-admin repository, so the client's SSH key must have read access to it.
+func (c *Client) ListRepoPerms(ctx context.Context) (*Perms, error) {
+	repos, err := c.ListRepos(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "archive", "--remote="+cloneURL(c.Host, adminRepo), "HEAD", "conf", "keydir")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		log15.Error("reading gitolite access rules failed", "error", err, "out", stderr.String())
+		return nil, errors.Wrapf(err, "reading %s", adminRepo)
+	}
+
+	files, err := readTar(bytes.NewReader(out))
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", adminRepo)
+	}
+	return decodePerms(repos, files)
+}
+
+// readTar returns the contents of the regular files in the tar archive r by path.
+func readTar(r io.Reader) (map[string]string, error) {
+	files := make(map[string]string)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[hdr.Name] = string(b)
+	}
+}
+
+// decodePerms computes the read permissions on the given repositories from the files of the
+// conf/ and keydir/ directories of the gitolite-admin repository (keyed by path).
+//
+// It supports group definitions, repository names, patterns and groups, include and subconf
+// statements, and deny rules (which only apply to read access if the "deny-rules" option is
+// set, as in Gitolite). Access granted through CREATOR, READERS and WRITERS of wild repos or
+// through external group definitions is not taken into account.
+func decodePerms(repos []*Repo, files map[string]string) (*Perms, error) {
+	users := keydirUsers(files)
+
+	c := &conf{groups: make(map[string][]string)}
+	if err := c.parse(files, "conf/gitolite.conf", make(map[string]bool)); err != nil {
+		return nil, err
+	}
+
+	// Expand the users of all rules once.
+	ruleUsers := make([]map[string]bool, len(c.rules))
+	for i, r := range c.rules {
+		ruleUsers[i] = make(map[string]bool)
+		for _, u := range c.expand(r.users, make(map[string]bool)) {
+			if u == "@all" {
+				for _, u := range users {
+					ruleUsers[i][u] = true
+				}
+			} else {
+				ruleUsers[i][u] = true
+			}
+		}
+	}
+
+	perms := &Perms{Users: users}
+	for _, repo := range repos {
+		var rules []int
+		denyRules := false
+		for i, r := range c.rules {
+			if !c.matchRepo(r.repos, repo.Name) {
+				continue
+			}
+			if r.option != "" {
+				if r.option == "deny-rules" {
+					denyRules = r.value == "1"
+				}
+				continue
+			}
+			rules = append(rules, i)
+		}
+
+		rp := &RepoPerms{Name: repo.Name}
+		for _, u := range users {
+			if canRead(c.rules, rules, ruleUsers, u, denyRules) {
+				rp.Users = append(rp.Users, u)
+			}
+		}
+		perms.Repos = append(perms.Repos, rp)
+	}
+	return perms, nil
+}
+
+// canRead reports whether the user can read a repository with the given applicable rules.
+func canRead(all []*rule, rules []int, ruleUsers []map[string]bool, user string, denyRules bool) bool {
+	for _, i := range rules {
+		if !ruleUsers[i][user] {
+			continue
+		}
+		switch perm := all[i].perm; {
+		case perm == "-" && denyRules:
+			return false
+		case strings.HasPrefix(perm, "R"):
+			return true
+		}
+	}
+	return false
+}
+
+// keydirUsers returns the names of the users that have a key in keydir/. As in Gitolite, a
+// suffix starting with "@" is stripped from the key file name if it contains no dot (so that
+// "alice@laptop.pub" is a key of "alice" while "alice@example.com.pub" is a key of
+// "alice@example.com").
+func keydirUsers(files map[string]string) []string {
+	seen := make(map[string]bool)
+	var users []string
+	for p := range files {
+		if !strings.HasPrefix(p, "keydir/") || !strings.HasSuffix(p, ".pub") {
+			continue
+		}
+		name := strings.TrimSuffix(path.Base(p), ".pub")
+		if i := strings.LastIndex(name, "@"); i > 0 && !strings.Contains(name[i:], ".") {
+			name = name[:i]
+		}
+		if name != "" && !seen[name] {
+			seen[name] = true
+			users = append(users, name)
+		}
+	}
+	sort.Strings(users)
+	return users
+}
+
+// conf is a parsed Gitolite configuration.
+type conf struct {
+	groups map[string][]string // group name (including "@") -> members
+	rules  []*rule             // rules and options in the order they appear
+}
+
+// rule is an access rule (or an option, if option is set) of a repo section.
+type rule struct {
+	repos []string // the names, patterns and groups of the repo section
+
+	perm  string
+	users []string
+
+	option, value string
+}
+
+// repoNamePattern matches plain repository names. Other names are patterns (as in Gitolite).
+var repoNamePattern = regexp.MustCompile(`^@?[0-9a-zA-Z][-0-9a-zA-Z._@/+]*$`)
+
+// parse parses the configuration file at the given path, including other files as requested.
+func (c *conf) parse(files map[string]string, file string, seen map[string]bool) error {
+	if seen[file] {
+		return errors.Errorf("%s: recursive include", file)
+	}
+	seen[file] = true
+	defer delete(seen, file)
+
+	content, ok := files[file]
+	if !ok {
+		return errors.Errorf("%s: not found", file)
+	}
+
+	var repos []string
+	for n, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case fields[0] == "include" || fields[0] == "subconf":
+			if len(fields) != 2 {
+				return errors.Errorf("%s:%d: invalid %s statement", file, n+1, fields[0])
+			}
+			pattern := path.Join("conf", strings.Trim(fields[1], `"'`))
+			var matches []string
+			for p := range files {
+				if ok, _ := path.Match(pattern, p); ok {
+					matches = append(matches, p)
+				}
+			}
+			sort.Strings(matches)
+			for _, p := range matches {
+				if err := c.parse(files, p, seen); err != nil {
+					return err
+				}
+			}
+
+		case fields[0] == "repo":
+			repos = fields[1:]
+
+		case strings.HasPrefix(fields[0], "@") && len(fields) >= 2 && fields[1] == "=":
+			c.groups[fields[0]] = append(c.groups[fields[0]], fields[2:]...)
+
+		case fields[0] == "option":
+			if repos == nil {
+				return errors.Errorf("%s:%d: option outside of a repo section", file, n+1)
+			}
+			// option <name> = <value>
+			kv := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "option")), "=", 2)
+			if len(kv) != 2 {
+				return errors.Errorf("%s:%d: invalid option", file, n+1)
+			}
+			c.rules = append(c.rules, &rule{repos: repos, option: strings.TrimSpace(kv[0]), value: strings.TrimSpace(kv[1])})
+
+		case fields[0] == "config":
+			// Git config settings don't affect permissions.
+
+		default:
+			// <perm> [<refex>...] = <user>...
+			i := indexOf(fields, "=")
+			if repos == nil || i < 1 {
+				return errors.Errorf("%s:%d: invalid rule %q", file, n+1, strings.TrimSpace(line))
+			}
+			c.rules = append(c.rules, &rule{repos: repos, perm: fields[0], users: fields[i+1:]})
+		}
+	}
+	return nil
+}
+
+// expand returns the given names with all groups replaced by their members. The special group
+// "@all" is kept as is.
+func (c *conf) expand(names []string, seen map[string]bool) []string {
+	var expanded []string
+	for _, name := range names {
+		members, ok := c.groups[name]
+		if !ok || name == "@all" {
+			expanded = append(expanded, name)
+			continue
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		expanded = append(expanded, c.expand(members, seen)...)
+	}
+	return expanded
+}
+
+// matchRepo reports whether the repository name is matched by any of the names, patterns and
+// groups of a repo section.
+func (c *conf) matchRepo(items []string, name string) bool {
+	for _, item := range c.expand(items, make(map[string]bool)) {
+		switch {
+		case item == "@all" || item == name:
+			return true
+		case !repoNamePattern.MatchString(item):
+			if re, err := regexp.Compile("^(?:" + item + ")$"); err == nil && re.MatchString(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func indexOf(ss []string, s string) int {
+	for i := range ss {
+		if ss[i] == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,149 @@
+package gitolite
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_decodePerms(t *testing.T) {
+	repos := []*Repo{
+		{Name: "gitolite-admin"},
+		{Name: "public"},
+		{Name: "secret"},
+		{Name: "team/backend"},
+		{Name: "team/frontend"},
+		{Name: "users/bob/scratch"},
+	}
+
+	files := map[string]string{
+		"keydir/admin.pub":                 "ssh-rsa AAAA",
+		"keydir/alice.pub":                 "ssh-rsa AAAA",
+		"keydir/alice@laptop.pub":          "ssh-rsa AAAA",
+		"keydir/team/bob.pub":              "ssh-rsa AAAA",
+		"keydir/carol@example.com.pub":     "ssh-rsa AAAA",
+		"keydir/dave.pub":                  "ssh-rsa AAAA",
+		"keydir/README":                    "not a key",
+		"conf/gitolite.conf":               confMain,
+		"conf/teams/backend.conf":          confBackend,
+		"conf/teams/frontend.conf":         confFrontend,
+		"conf/unused/ignored.conf":         "repo public\n    -   =   @all\n",
+		"conf/teams/not-a-conf-file.notes": "garbage",
+	}
+
+	perms, err := decodePerms(repos, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Perms{
+		Users: []string{"admin", "alice", "bob", "carol@example.com", "dave"},
+		Repos: []*RepoPerms{
+			{Name: "gitolite-admin", Users: []string{"admin"}},
+			{Name: "public", Users: []string{"admin", "alice", "bob", "carol@example.com", "dave"}},
+			{Name: "secret", Users: []string{"admin", "alice"}},
+			{Name: "team/backend", Users: []string{"admin", "alice", "bob"}},
+			{Name: "team/frontend", Users: []string{"admin", "alice", "carol@example.com"}},
+			{Name: "users/bob/scratch", Users: []string{"bob"}}, // CREATOR isn't substituted
+		},
+	}
+	if diff := cmp.Diff(want, perms); diff != "" {
+		t.Error(diff)
+	}
+}
+
+const confMain = `
+# Groups
+@admins   = admin
+@frontend = alice
+@frontend = carol@example.com
+@devs     = @frontend bob
+
+repo gitolite-admin
+    RW+     =   @admins
+
+repo public
+    R       =   @all
+
+repo secret
+    RW      =   alice       # alice only
+    R       =   @admins
+
+repo users/CREATOR/..*
+    C       =   @devs
+    RW+     =   CREATOR
+    R       =   @admins
+
+repo users/bob/..*
+    RW      =   bob
+
+include "teams/*.conf"
+`
+
+const confBackend = `
+repo team/backend
+    option deny-rules = 1
+    -       =   dave
+    -       =   carol@example.com
+    RW+     =   @devs
+    R       =   @admins
+`
+
+const confFrontend = `
+repo team/frontend
+    -   master  =   alice
+    RW+         =   @frontend
+    R           =   @admins
+`
+
+func Test_decodePerms_errors(t *testing.T) {
+	for name, files := range map[string]map[string]string{
+		"missing gitolite.conf": {},
+		"recursive include": {
+			"conf/gitolite.conf": `include "gitolite.conf"`,
+		},
+		"rule outside of a repo section": {
+			"conf/gitolite.conf": `R = alice`,
+		},
+		"invalid rule": {
+			"conf/gitolite.conf": "repo foo\n    RW+ alice\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := decodePerms(nil, files); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
+
+func Test_readTar(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range []*tar.Header{
+		{Name: "conf/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "conf/gitolite.conf", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len("repo foo"))},
+	} {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte("repo foo")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := readTar(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]string{"conf/gitolite.conf": "repo foo"}, files); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -53,21 +53,23 @@ func decodeRepos(host, gitoliteInfo string) []*Repo {
 		}
 		name := fields[len(fields)-1]
 		if len(fields) >= 2 && fields[0] == "R" {
-			repo := &Repo{Name: name}
-
-			// We support both URL and SCP formats
-			// url: ssh://git@github.com:22/tsenart/vegeta
-			// scp: git@github.com:tsenart/vegeta
-			if u, _ := url.Parse(host); u == nil || u.Scheme == "" {
-				repo.URL = host + ":" + name
-			} else if u.Scheme == "ssh" {
-				u.Path = name
-				repo.URL = u.String()
-			}
-
-			repos = append(repos, repo)
+			repos = append(repos, &Repo{Name: name, URL: cloneURL(host, name)})
 		}
 	}
 
 	return repos
 }
+
+// cloneURL returns the clone URL of the repository with the given name on the Gitolite host.
+func cloneURL(host, name string) string {
+	// We support both URL and SCP formats
+	// url: ssh://git@github.com:22/tsenart/vegeta
+	// scp: git@github.com:tsenart/vegeta
+	if u, _ := url.Parse(host); u == nil || u.Scheme == "" {
+		return host + ":" + name
+	} else if u.Scheme == "ssh" {
+		u.Path = name
+		return u.String()
+	}
+	return ""
+}
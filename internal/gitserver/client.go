@@ -13,6 +13,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -60,6 +61,12 @@ func NewClient(cli httpcli.Doer) *Client {
 		Addrs: func(ctx context.Context) []string {
 			return conf.Get().ServiceConnections.GitServers
 		},
+		Replicas: func(ctx context.Context) int {
+			return conf.Get().ServiceConnections.GitServerReplicas
+		},
+		Hashing: func(ctx context.Context) Hashing {
+			return Hashing(conf.Get().ServiceConnections.GitServerHashing)
+		},
 		HTTPClient:  cli,
 		HTTPLimiter: parallel.NewRun(500),
 		// Use the binary name for UserAgent. This should effectively identify
@@ -82,6 +89,15 @@ type Client struct {
 	// concurrent use. It may return different results at different times.
 	Addrs func(ctx context.Context) []string
 
+	// Replicas is a function which should return the number of gitservers
+	// each repository is cloned onto. If it is nil or returns less than 2,
+	// each repository lives on a single gitserver.
+	Replicas func(ctx context.Context) int
+
+	// Hashing returns the scheme used to assign repositories to the gitservers
+	// in Addrs. If it is nil, ModuloHashing is used.
+	Hashing func(ctx context.Context) Hashing
+
 	// UserAgent is a string identifing who the client is. It will be logged in
 	// the telemetry in gitserver.
 	UserAgent string
@@ -100,13 +116,97 @@ func (c *Client) addrForKey(ctx context.Context, key string) string {
 	if len(addrs) == 0 {
 		panic("unexpected state: no gitserver addresses")
 	}
-	return addrForKey(addrs, key)
+	return AddrsForKey(addrs, key, 1, c.hashing(ctx))[0]
+}
+
+// AddrsForRepo returns the addresses of the gitservers the given repo is
+// cloned onto. The first address is the primary (the one returned by
+// AddrForRepo), the others are its replicas in the order in which they should
+// be tried when the primary is unavailable.
+func (c *Client) AddrsForRepo(ctx context.Context, repo api.RepoName) []string {
+	repo = protocol.NormalizeRepo(repo) // in case the caller didn't already normalize it
+	addrs := c.Addrs(ctx)
+	if len(addrs) == 0 {
+		panic("unexpected state: no gitserver addresses")
+	}
+	return AddrsForKey(addrs, string(repo), c.replicas(ctx), c.hashing(ctx))
+}
+
+func (c *Client) replicas(ctx context.Context) int {
+	if c.Replicas == nil {
+		return 1
+	}
+	if n := c.Replicas(ctx); n > 1 {
+		return n
+	}
+	return 1
+}
+
+func (c *Client) hashing(ctx context.Context) Hashing {
+	if c.Hashing == nil {
+		return ModuloHashing
+	}
+	return c.Hashing(ctx)
+}
+
+// Hashing is a scheme for assigning keys to gitserver addresses.
+type Hashing string
+
+const (
+	// ModuloHashing assigns a key to the address at the index of its hash
+	// modulo the number of addresses, and its replicas to the addresses
+	// following it. Adding or removing an address assigns almost every key to
+	// another address. It is the default, because it is how repositories have
+	// always been assigned.
+	ModuloHashing Hashing = ""
+
+	// RendezvousHashing ranks every address by a hash of itself and the key.
+	// Adding or removing an address only moves the keys which rank it highest.
+	// Switching to it from ModuloHashing assigns most keys to another address
+	// once.
+	RendezvousHashing Hashing = "rendezvous"
+)
+
+// AddrsForKey returns the addresses of the replicas gitservers in addrs which
+// are responsible for key with the given hashing scheme, primary first.
+// gitserver relies on it to move repositories between instances when the set
+// of addresses changes.
+func AddrsForKey(addrs []string, key string, replicas int, hashing Hashing) []string {
+	if replicas < 1 {
+		replicas = 1
+	}
+	if replicas > len(addrs) {
+		replicas = len(addrs)
+	}
+
+	if hashing != RendezvousHashing {
+		sum := md5.Sum([]byte(key))
+		primary := binary.BigEndian.Uint64(sum[:]) % uint64(len(addrs))
+		owners := make([]string, replicas)
+		for i := range owners {
+			owners[i] = addrs[(primary+uint64(i))%uint64(len(addrs))]
+		}
+		return owners
+	}
+
+	ranked := make([]string, len(addrs))
+	copy(ranked, addrs)
+	scores := make(map[string]uint64, len(addrs))
+	for _, addr := range addrs {
+		scores[addr] = rendezvousScore(addr, key)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i]] > scores[ranked[j]]
+	})
+	return ranked[:replicas]
 }
 
-func addrForKey(addrs []string, key string) string {
-	sum := md5.Sum([]byte(key))
-	serverIndex := binary.BigEndian.Uint64(sum[:]) % uint64(len(addrs))
-	return addrs[serverIndex]
+func rendezvousScore(addr, key string) uint64 {
+	h := md5.New()
+	_, _ = io.WriteString(h, addr)
+	_, _ = h.Write([]byte{0})
+	_, _ = io.WriteString(h, key)
+	return binary.BigEndian.Uint64(h.Sum(nil))
 }
 
 // ArchiveOptions contains options for the Archive func.
@@ -142,8 +242,51 @@ func (a *archiveReader) Close() error {
 }
 
 // ArchiveURL returns a URL from which an archive of the given Git repository can
-// be downloaded from.
+// be downloaded from. Since the caller downloads it without failing over, the
+// URL points at the first gitserver of the repository which is available.
 func (c *Client) ArchiveURL(ctx context.Context, repo Repo, opt ArchiveOptions) *url.URL {
+	return &url.URL{
+		Scheme:   "http",
+		Host:     c.availableAddrForRepo(ctx, repo.Name),
+		Path:     "/archive",
+		RawQuery: archiveQuery(repo, opt).Encode(),
+	}
+}
+
+// availableAddrForRepo returns the address of the first gitserver of repo,
+// primary first, which responds to an is-repo-cloned request in a way which
+// would not make a read-only request fail over to the next replica. If none
+// does, or the repository is not replicated, the primary is returned.
+func (c *Client) availableAddrForRepo(ctx context.Context, repo api.RepoName) string {
+	addrs := c.AddrsForRepo(ctx, repo)
+	if len(addrs) == 1 {
+		return addrs[0]
+	}
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Client.availableAddrForRepo")
+	defer span.Finish()
+
+	reqBody, err := json.Marshal(&protocol.IsRepoClonedRequest{Repo: repo})
+	if err != nil {
+		return addrs[0]
+	}
+	for i, addr := range addrs {
+		if i > 0 {
+			replicaFailovers.Inc()
+			span.LogKV("event", "failing over to replica", "addr", addr)
+		}
+		resp, err := c.doOnce(ctx, span.Tracer(), "POST", "http://"+addr+"/is-repo-cloned", reqBody)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if !shouldFailover(ctx, resp, err) {
+			return addr
+		}
+	}
+	return addrs[0]
+}
+
+func archiveQuery(repo Repo, opt ArchiveOptions) url.Values {
 	q := url.Values{
 		"repo":    {string(repo.Name)},
 		"treeish": {opt.Treeish},
@@ -153,13 +296,7 @@ func (c *Client) ArchiveURL(ctx context.Context, repo Repo, opt ArchiveOptions)
 	for _, path := range opt.Paths {
 		q.Add("path", path)
 	}
-
-	return &url.URL{
-		Scheme:   "http",
-		Host:     c.AddrForRepo(ctx, repo.Name),
-		Path:     "/archive",
-		RawQuery: q.Encode(),
-	}
+	return q
 }
 
 // Archive produces an archive from a Git repository.
@@ -181,8 +318,7 @@ func (c *Client) Archive(ctx context.Context, repo Repo, opt ArchiveOptions) (_
 		return nil, err
 	}
 
-	u := c.ArchiveURL(ctx, repo, opt)
-	resp, err := c.do(ctx, repo.Name, "GET", u.String(), nil)
+	resp, err := c.do(ctx, repo.Name, "GET", "archive?"+archiveQuery(repo, opt).Encode(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -478,6 +614,33 @@ func (c *Client) ListGitolite(ctx context.Context, gitoliteHost string) (list []
 	return list, err
 }
 
+// ListGitolitePerms lists the read permissions of the users of a Gitolite server on its
+// repositories.
+func (c *Client) ListGitolitePerms(ctx context.Context, gitoliteHost string) (*gitolite.Perms, error) {
+	// The gitserver calls the shared Gitolite server in response to this request, so
+	// we need to only call a single gitserver.
+	addr := c.addrForKey(ctx, gitoliteHost)
+	req, err := http.NewRequest("GET", "http://"+addr+"/list-gitolite-perms?gitolite="+url.QueryEscape(gitoliteHost), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.HTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("list gitolite perms: bad HTTP response status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var perms gitolite.Perms
+	err = json.NewDecoder(resp.Body).Decode(&perms)
+	return &perms, err
+}
+
 // ListCloned lists all cloned repositories
 func (c *Client) ListCloned(ctx context.Context) ([]string, error) {
 	var (
@@ -485,20 +648,26 @@ func (c *Client) ListCloned(ctx context.Context) ([]string, error) {
 		mu    sync.Mutex
 		err   error
 		repos []string
+		seen  = map[string]bool{}
 	)
 	addrs := c.Addrs(ctx)
+	replicas, hashing := c.replicas(ctx), c.hashing(ctx)
 	for _, addr := range addrs {
 		wg.Add(1)
 		go func(addr string) {
 			defer wg.Done()
 			r, e := c.doListOne(ctx, "?cloned", addr)
 
-			// Only include repos that belong on addr.
+			// Only include repos that belong on addr, either as their
+			// primary or as one of their replicas.
 			if len(r) > 0 {
 				filtered := r[:0]
 				for _, repo := range r {
-					if addrForKey(addrs, repo) == addr {
-						filtered = append(filtered, repo)
+					for _, a := range AddrsForKey(addrs, repo, replicas, hashing) {
+						if a == addr {
+							filtered = append(filtered, repo)
+							break
+						}
 					}
 				}
 				r = filtered
@@ -507,7 +676,12 @@ func (c *Client) ListCloned(ctx context.Context) ([]string, error) {
 			if e != nil {
 				err = e
 			}
-			repos = append(repos, r...)
+			for _, repo := range r {
+				if !seen[repo] {
+					seen[repo] = true
+					repos = append(repos, repo)
+				}
+			}
 			mu.Unlock()
 		}(addr)
 	}
@@ -561,13 +735,41 @@ func (c *Client) doListOne(ctx context.Context, urlSuffix, addr string) ([]strin
 // Repo updates are not guaranteed to occur. If a repo has been updated
 // recently (within the Since duration specified in the request), the
 // update won't happen.
+//
+// If repositories are replicated, the update is sent to every replica, which
+// clones the repository if it doesn't have it yet. Only the response of the
+// primary is returned; failures to update a replica are logged.
 func (c *Client) RequestRepoUpdate(ctx context.Context, repo Repo, since time.Duration) (*protocol.RepoUpdateResponse, error) {
 	req := &protocol.RepoUpdateRequest{
 		Repo:  repo.Name,
 		URL:   repo.URL,
 		Since: since,
 	}
-	resp, err := c.httpPost(ctx, repo.Name, "repo-update", req)
+
+	addrs := c.AddrsForRepo(ctx, repo.Name)
+	var wg sync.WaitGroup
+	for _, addr := range addrs[1:] {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			info, err := c.requestRepoUpdate(ctx, addr, req)
+			if err == nil && info != nil && info.Error != "" {
+				err = errors.New(info.Error)
+			}
+			if err != nil {
+				replicaUpdateErrors.Inc()
+				log15.Warn("gitserver: failed to update replica", "repo", repo.Name, "addr", addr, "error", err)
+			}
+		}(addr)
+	}
+
+	info, err := c.requestRepoUpdate(ctx, addrs[0], req)
+	wg.Wait()
+	return info, err
+}
+
+func (c *Client) requestRepoUpdate(ctx context.Context, addr string, req *protocol.RepoUpdateRequest) (*protocol.RepoUpdateResponse, error) {
+	resp, err := c.httpPost(ctx, req.Repo, "http://"+addr+"/repo-update", req)
 	if err != nil {
 		return nil, err
 	}
@@ -671,12 +873,15 @@ func (c *Client) RepoInfo(ctx context.Context, repos ...api.RepoName) (*protocol
 	shards := make(map[string]*protocol.RepoInfoRequest, (len(repos)/numPossibleShards)*2) // 2x because it may not be a perfect division
 
 	for _, r := range repos {
-		addr := c.AddrForRepo(ctx, r)
-		shard := shards[addr]
+		// Shard by the whole list of replicas rather than just the primary,
+		// since a failed request fails over to the replicas of the shard's
+		// first repository, which must be the replicas of all its repositories.
+		key := strings.Join(c.AddrsForRepo(ctx, r), " ")
+		shard := shards[key]
 
 		if shard == nil {
 			shard = new(protocol.RepoInfoRequest)
-			shards[addr] = shard
+			shards[key] = shard
 		}
 
 		shard.Repos = append(shard.Repos, r)
@@ -736,12 +941,65 @@ func (c *Client) RepoInfo(ctx context.Context, repos ...api.RepoName) (*protocol
 	return &res, err.ErrorOrNil()
 }
 
-// Remove removes the repository clone from gitserver.
+// MockRepoHistory mocks (*Client).RepoHistory for tests.
+var MockRepoHistory func(api.RepoName) ([]protocol.RepoOperation, error)
+
+// RepoHistory returns the most recent clones and fetches of repo on its
+// gitserver, most recent first.
+func (c *Client) RepoHistory(ctx context.Context, repo api.RepoName) ([]protocol.RepoOperation, error) {
+	if MockRepoHistory != nil {
+		return MockRepoHistory(repo)
+	}
+
+	req := &protocol.RepoHistoryRequest{
+		Repo: repo,
+	}
+	resp, err := c.httpPost(ctx, repo, "repo-history", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		// best-effort inclusion of body in error message
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 200))
+		return nil, &url.Error{URL: resp.Request.URL.String(), Op: "RepoHistory", Err: fmt.Errorf("RepoHistory: http status %d: %s", resp.StatusCode, string(body))}
+	}
+
+	var res protocol.RepoHistoryResponse
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	return res.Operations, err
+}
+
+// Remove removes the repository clone from gitserver, including all of its
+// replicas.
 func (c *Client) Remove(ctx context.Context, repo api.RepoName) error {
 	req := &protocol.RepoDeleteRequest{
 		Repo: repo,
 	}
-	resp, err := c.httpPost(ctx, repo, "delete", req)
+
+	addrs := c.AddrsForRepo(ctx, repo)
+	errs := make([]error, len(addrs))
+	var wg sync.WaitGroup
+	for i, addr := range addrs {
+		wg.Add(1)
+		go func(i int, addr string) {
+			defer wg.Done()
+			errs[i] = c.remove(ctx, addr, req)
+		}(i, addr)
+	}
+	wg.Wait()
+
+	var err *multierror.Error
+	for _, e := range errs {
+		if e != nil {
+			err = multierror.Append(err, e)
+		}
+	}
+	return err.ErrorOrNil()
+}
+
+func (c *Client) remove(ctx context.Context, addr string, req *protocol.RepoDeleteRequest) error {
+	resp, err := c.httpPost(ctx, req.Repo, "http://"+addr+"/delete", req)
 	if err != nil {
 		return err
 	}
@@ -758,8 +1016,21 @@ func (c *Client) httpPost(ctx context.Context, repo api.RepoName, op string, pay
 	return c.do(ctx, repo, "POST", op, payload)
 }
 
+// readOnlyOps are the operations which any replica of a repository can
+// serve, so do retries them on the next replica when a gitserver is
+// unavailable.
+var readOnlyOps = map[string]bool{
+	"exec":           true,
+	"archive":        true,
+	"is-repo-cloned": true,
+	"repos":          true,
+}
+
 // do performs a request to a gitserver, sharding based on the given
 // repo name (the repo name is otherwise not used).
+//
+// Read-only operations fail over to the replicas of repo if the gitserver
+// can't be reached or responds with a gateway error.
 func (c *Client) do(ctx context.Context, repo api.RepoName, method, op string, payload interface{}) (resp *http.Response, err error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "Client.do")
 	defer func() {
@@ -776,11 +1047,42 @@ func (c *Client) do(ctx context.Context, repo api.RepoName, method, op string, p
 		return nil, err
 	}
 
-	uri := op
+	uris := []string{op}
 	if !strings.HasPrefix(op, "http") {
-		uri = "http://" + c.AddrForRepo(ctx, repo) + "/" + op
+		addrs := c.AddrsForRepo(ctx, repo)
+		if name := strings.SplitN(op, "?", 2)[0]; !readOnlyOps[name] {
+			addrs = addrs[:1]
+		}
+		uris = uris[:0]
+		for _, addr := range addrs {
+			uris = append(uris, "http://"+addr+"/"+op)
+		}
+	}
+
+	if c.HTTPLimiter != nil {
+		c.HTTPLimiter.Acquire()
+		defer c.HTTPLimiter.Release()
+		span.LogKV("event", "Acquired HTTP limiter")
 	}
 
+	for i, uri := range uris {
+		if i > 0 {
+			replicaFailovers.Inc()
+			span.LogKV("event", "failing over to replica", "uri", uri, "error", errOrStatus(err, resp))
+			if resp != nil {
+				resp.Body.Close()
+			}
+		}
+
+		resp, err = c.doOnce(ctx, span.Tracer(), method, uri, reqBody)
+		if !shouldFailover(ctx, resp, err) {
+			break
+		}
+	}
+	return resp, err
+}
+
+func (c *Client) doOnce(ctx context.Context, tracer opentracing.Tracer, method, uri string, reqBody []byte) (*http.Response, error) {
 	req, err := http.NewRequest(method, uri, bytes.NewReader(reqBody))
 	if err != nil {
 		return nil, err
@@ -790,13 +1092,7 @@ func (c *Client) do(ctx context.Context, repo api.RepoName, method, op string, p
 	req.Header.Set("User-Agent", c.UserAgent)
 	req = req.WithContext(ctx)
 
-	if c.HTTPLimiter != nil {
-		c.HTTPLimiter.Acquire()
-		defer c.HTTPLimiter.Release()
-		span.LogKV("event", "Acquired HTTP limiter")
-	}
-
-	req, ht := nethttp.TraceRequest(span.Tracer(), req,
+	req, ht := nethttp.TraceRequest(tracer, req,
 		nethttp.OperationName("Gitserver Client"),
 		nethttp.ClientTrace(false))
 	defer ht.Finish()
@@ -804,6 +1100,48 @@ func (c *Client) do(ctx context.Context, repo api.RepoName, method, op string, p
 	return c.HTTPClient.Do(req)
 }
 
+// shouldFailover reports whether a request which returned resp and err should
+// be retried on the next replica.
+func shouldFailover(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		// Errors caused by the caller giving up are not the fault of the
+		// gitserver.
+		return ctx.Err() == nil
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func errOrStatus(err error, resp *http.Response) string {
+	if err != nil {
+		return err.Error()
+	}
+	return resp.Status
+}
+
+var (
+	replicaFailovers = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "gitserver",
+		Name:      "client_replica_failovers",
+		Help:      "Times that a read-only request was retried on a replica because a gitserver was unavailable",
+	})
+	replicaUpdateErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "gitserver",
+		Name:      "client_replica_update_errors",
+		Help:      "Times that updating a replica of a repository failed",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(replicaFailovers)
+	prometheus.MustRegister(replicaUpdateErrors)
+}
+
 // CreateCommitFromPatch will attempt to create a commit from a patch
 // If possible, the error returned will be of type protocol.CreateCommitFromPatchError
 func (c *Client) CreateCommitFromPatch(ctx context.Context, req protocol.CreateCommitFromPatchRequest) (string, error) {
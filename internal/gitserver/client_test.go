@@ -4,7 +4,10 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"crypto/md5"
 	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -14,6 +17,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"sort"
+	"sync"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -22,6 +26,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/gitserver/server"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
 )
 
@@ -57,6 +62,254 @@ func TestClient_ListCloned(t *testing.T) {
 	}
 }
 
+func TestClient_ListCloned_Replicas(t *testing.T) {
+	addrs := []string{"gitserver-0", "gitserver-1", "gitserver-2"}
+	cli := &gitserver.Client{
+		Addrs:    func(ctx context.Context) []string { return addrs },
+		Replicas: func(ctx context.Context) int { return 2 },
+		HTTPClient: httpcli.DoerFunc(func(r *http.Request) (*http.Response, error) {
+			// Every repo is on two of the three gitservers, but must only
+			// be listed once.
+			return &http.Response{
+				Body: ioutil.NopCloser(bytes.NewBufferString(`["repo-a", "repo-b", "repo-c", "repo-d"]`)),
+			}, nil
+		}),
+	}
+
+	want := []string{"repo-a", "repo-b", "repo-c", "repo-d"}
+	got, err := cli.ListCloned(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	if !cmp.Equal(want, got, cmpopts.EquateEmpty()) {
+		t.Errorf("mismatch for (-want +got):\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestClient_AddrsForRepo(t *testing.T) {
+	addrs := []string{"gitserver-0", "gitserver-1", "gitserver-2"}
+	for _, hashing := range []gitserver.Hashing{gitserver.ModuloHashing, gitserver.RendezvousHashing} {
+		for _, tc := range []struct {
+			replicas int
+			want     int
+		}{
+			{replicas: 0, want: 1},
+			{replicas: 1, want: 1},
+			{replicas: 2, want: 2},
+			{replicas: 5, want: 3},
+		} {
+			replicas, hashing := tc.replicas, hashing
+			cli := &gitserver.Client{
+				Addrs:    func(ctx context.Context) []string { return addrs },
+				Replicas: func(ctx context.Context) int { return replicas },
+				Hashing:  func(ctx context.Context) gitserver.Hashing { return hashing },
+			}
+
+			for _, repo := range []api.RepoName{"github.com/foo/bar", "github.com/foo/baz", "example.com/qux"} {
+				got := cli.AddrsForRepo(context.Background(), repo)
+				if len(got) != tc.want {
+					t.Fatalf("hashing=%q replicas=%d: got %d addrs %v, want %d", hashing, tc.replicas, len(got), got, tc.want)
+				}
+				if got[0] != cli.AddrForRepo(context.Background(), repo) {
+					t.Errorf("hashing=%q replicas=%d: first addr %q is not the primary %q", hashing, tc.replicas, got[0], cli.AddrForRepo(context.Background(), repo))
+				}
+				seen := map[string]bool{}
+				for _, addr := range got {
+					if seen[addr] {
+						t.Errorf("hashing=%q replicas=%d: duplicate addr %q in %v", hashing, tc.replicas, addr, got)
+					}
+					seen[addr] = true
+				}
+			}
+		}
+	}
+}
+
+func TestAddrsForKey_Modulo(t *testing.T) {
+	addrs := []string{"gitserver-0", "gitserver-1", "gitserver-2"}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("github.com/foo/repo-%d", i)
+		// Repositories must stay where they were assigned before hashing
+		// schemes were configurable.
+		sum := md5.Sum([]byte(key))
+		want := addrs[binary.BigEndian.Uint64(sum[:])%uint64(len(addrs))]
+		if got := gitserver.AddrsForKey(addrs, key, 1, gitserver.ModuloHashing)[0]; got != want {
+			t.Fatalf("%s assigned to %s, want %s", key, got, want)
+		}
+	}
+}
+
+func TestAddrsForKey_Consistent(t *testing.T) {
+	addrs := []string{"gitserver-0", "gitserver-1", "gitserver-2"}
+	grown := append(addrs[:len(addrs):len(addrs)], "gitserver-3")
+
+	moved := 0
+	const n = 1000
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("github.com/foo/repo-%d", i)
+		before := gitserver.AddrsForKey(addrs, key, 1, gitserver.RendezvousHashing)[0]
+		after := gitserver.AddrsForKey(grown, key, 1, gitserver.RendezvousHashing)[0]
+		if before == after {
+			continue
+		}
+		// Adding a gitserver must only move repos onto the new gitserver.
+		if after != "gitserver-3" {
+			t.Fatalf("%s moved from %s to %s", key, before, after)
+		}
+		moved++
+	}
+	// About a quarter of the repos should move to the new gitserver.
+	if moved < n/8 || moved > n/2 {
+		t.Errorf("%d of %d repos moved, want about %d", moved, n, n/4)
+	}
+}
+
+func TestClient_ReplicaFailover(t *testing.T) {
+	const repo = "github.com/foo/bar"
+	addrs := []string{"gitserver-0", "gitserver-1"}
+
+	var requested []string
+	cli := &gitserver.Client{
+		Addrs:    func(ctx context.Context) []string { return addrs },
+		Replicas: func(ctx context.Context) int { return 2 },
+	}
+	primary := cli.AddrForRepo(context.Background(), repo)
+	cli.HTTPClient = httpcli.DoerFunc(func(r *http.Request) (*http.Response, error) {
+		requested = append(requested, r.URL.Host+r.URL.Path)
+		if r.URL.Host == primary {
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewBufferString("")),
+			Request:    r,
+		}, nil
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		requested = nil
+		cloned, err := cli.IsRepoCloned(context.Background(), repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cloned {
+			t.Error("expected repo to be reported as cloned by the replica")
+		}
+		if len(requested) != 2 || requested[1] == primary+"/is-repo-cloned" {
+			t.Errorf("expected request to fail over to the replica, got requests %v", requested)
+		}
+	})
+
+	t.Run("archive URL", func(t *testing.T) {
+		requested = nil
+		u := cli.ArchiveURL(context.Background(), gitserver.Repo{Name: repo}, gitserver.ArchiveOptions{Treeish: "HEAD", Format: "tar"})
+		if u.Host == primary {
+			t.Errorf("expected archive URL to point at the replica, got %s", u)
+		}
+	})
+
+	t.Run("write", func(t *testing.T) {
+		requested = nil
+		_, err := cli.CreateCommitFromPatch(context.Background(), protocol.CreateCommitFromPatchRequest{Repo: repo})
+		if err == nil {
+			t.Fatal("expected error when primary is unavailable")
+		}
+		if want := []string{primary + "/create-commit-from-patch"}; !cmp.Equal(want, requested) {
+			t.Errorf("mismatch for (-want +got):\n%s", cmp.Diff(want, requested))
+		}
+	})
+}
+
+func TestClient_RepoInfo_ReplicaFailover(t *testing.T) {
+	addrs := []string{"gitserver-0", "gitserver-1", "gitserver-2"}
+	cli := &gitserver.Client{
+		Addrs:    func(ctx context.Context) []string { return addrs },
+		Replicas: func(ctx context.Context) int { return 2 },
+		Hashing:  func(ctx context.Context) gitserver.Hashing { return gitserver.RendezvousHashing },
+	}
+	// Every gitserver only has the repos that belong on it.
+	cli.HTTPClient = httpcli.DoerFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Host == "gitserver-0" {
+			return nil, errors.New("connection refused")
+		}
+		var req protocol.RepoInfoRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, err
+		}
+		res := protocol.RepoInfoResponse{Results: map[api.RepoName]*protocol.RepoInfo{}}
+		for _, repo := range req.Repos {
+			info := &protocol.RepoInfo{}
+			for _, addr := range cli.AddrsForRepo(r.Context(), repo) {
+				info.Cloned = info.Cloned || addr == r.URL.Host
+			}
+			res.Results[repo] = info
+		}
+		body, _ := json.Marshal(res)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader(body)),
+			Request:    r,
+		}, nil
+	})
+
+	var repos []api.RepoName
+	for i := 0; i < 30; i++ {
+		repos = append(repos, api.RepoName(fmt.Sprintf("github.com/foo/repo-%d", i)))
+	}
+	res, err := cli.RepoInfo(context.Background(), repos...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, repo := range repos {
+		if info := res.Results[repo]; info == nil || !info.Cloned {
+			t.Errorf("%s not reported as cloned by its replica", repo)
+		}
+	}
+}
+
+func TestClient_RequestRepoUpdate_Replicas(t *testing.T) {
+	addrs := []string{"gitserver-0", "gitserver-1", "gitserver-2"}
+
+	var (
+		mu        sync.Mutex
+		requested []string
+	)
+	cli := &gitserver.Client{
+		Addrs:    func(ctx context.Context) []string { return addrs },
+		Replicas: func(ctx context.Context) int { return 2 },
+		HTTPClient: httpcli.DoerFunc(func(r *http.Request) (*http.Response, error) {
+			mu.Lock()
+			requested = append(requested, r.URL.Host+r.URL.Path)
+			mu.Unlock()
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`{"Cloned": true}`)),
+				Request:    r,
+			}, nil
+		}),
+	}
+
+	const repo = "github.com/foo/bar"
+	info, err := cli.RequestRepoUpdate(context.Background(), gitserver.Repo{Name: repo}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Cloned {
+		t.Errorf("unexpected response %+v", info)
+	}
+
+	var want []string
+	for _, addr := range cli.AddrsForRepo(context.Background(), repo) {
+		want = append(want, addr+"/repo-update")
+	}
+	sort.Strings(want)
+	sort.Strings(requested)
+	if !cmp.Equal(want, requested) {
+		t.Errorf("mismatch for (-want +got):\n%s", cmp.Diff(want, requested))
+	}
+}
+
 func TestClient_Archive(t *testing.T) {
 	root, err := ioutil.TempDir("", t.Name())
 	if err != nil {
@@ -131,6 +131,30 @@ type RepoInfoResponse struct {
 	Results map[api.RepoName]*RepoInfo
 }
 
+// RepoHistoryRequest is a request for the recent clones and fetches of a
+// repository on gitserver.
+type RepoHistoryRequest struct {
+	// Repo is the repository to get the history of.
+	Repo api.RepoName
+}
+
+// RepoHistoryResponse is the response type for the RepoHistoryRequest.
+type RepoHistoryResponse struct {
+	// Operations are the most recent clones and fetches of the repository,
+	// most recent first.
+	Operations []RepoOperation
+}
+
+// RepoOperation is a clone or fetch of a repository by gitserver.
+type RepoOperation struct {
+	Kind     string    // "clone" or "fetch"
+	Start    time.Time // when the git command started
+	End      time.Time // when the git command finished
+	Bytes    int64     // how much the repository's objects grew on disk
+	ExitCode int       // the exit code of the git command, -1 if it didn't run
+	Stderr   string    // the end of the output of the git command, with credentials redacted
+}
+
 // CreateCommitFromPatchRequest is the request information needed for creating
 // the simulated staging area git object for a repo.
 type CreateCommitFromPatchRequest struct {
@@ -148,6 +172,10 @@ type CreateCommitFromPatchRequest struct {
 	CommitInfo PatchCommitInfo
 	// Push specifies whether the target ref will be pushed to the code host
 	Push bool
+	// ExpectedHead, if set, is the commit that the target ref is expected to
+	// point at on the code host. The push fails instead of overwriting the
+	// ref if it points at another commit.
+	ExpectedHead api.CommitID
 	// GitApplyArgs are the arguments that will be passed to `git apply` along
 	// with `--cached`.
 	GitApplyArgs []string
@@ -161,6 +189,12 @@ type PatchCommitInfo struct {
 	CommitterName  string
 	CommitterEmail string
 	Date           time.Time
+
+	// SignOff adds a Signed-off-by trailer for the committer to the message.
+	SignOff bool
+	// Sign signs the commit with the GPG key configured on gitserver. The
+	// request fails if gitserver has no signing key.
+	Sign bool
 }
 
 // CreateCommitFromPatchResponse is the response type returned after creating
@@ -0,0 +1,211 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// Priority is the priority with which an operation draws from the rate limit
+// budget of an external service. Operations with a lower priority back off
+// earlier, so that the budget isn't exhausted when an operation with a higher
+// priority needs it.
+type Priority int
+
+const (
+	// PriorityInteractive is the priority of operations a user is waiting
+	// for. They never wait for the budget and may exhaust it completely.
+	// It's the priority of operations without a priority in their context.
+	PriorityInteractive Priority = iota
+	// PriorityPublish is the priority of operations that publish changes to
+	// the external service, such as creating campaign changesets.
+	PriorityPublish
+	// PriorityBackground is the priority of periodic background operations,
+	// such as syncing changesets or permissions.
+	PriorityBackground
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityPublish:
+		return "publish"
+	case PriorityBackground:
+		return "background"
+	default:
+		return "unknown"
+	}
+}
+
+// reserve returns the fraction of the rate limit that operations with the
+// priority p may not draw from.
+func (p Priority) reserve() float64 {
+	switch p {
+	case PriorityPublish:
+		return 0.1
+	case PriorityBackground:
+		return 0.3
+	default:
+		return 0
+	}
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context in which the operations drawing from a
+// Monitor's budget have the given priority.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority set with WithPriority, or
+// PriorityInteractive if there's none.
+func PriorityFromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityInteractive
+}
+
+// Wait blocks until an operation with the given rate limit cost may draw from
+// the budget of the Monitor, with the priority from the context, and then
+// deducts the cost from the budget. It returns early with the context's error
+// if the context is done.
+//
+// Interactive operations never wait. Publishing operations wait for the rate
+// limit to reset when the remaining budget falls below a small reserve.
+// Background operations keep a larger reserve and are additionally spaced out
+// as recommended by RecommendedWaitForBackgroundOp.
+func (c *Monitor) Wait(ctx context.Context, cost int) error {
+	p := PriorityFromContext(ctx)
+	for {
+		d := c.waitFor(p, cost)
+		if d <= 0 {
+			return nil
+		}
+
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// waitFor returns how long an operation with the given priority and cost has
+// to wait before drawing from the budget. If it doesn't have to wait, the cost
+// is deducted from the budget.
+func (c *Monitor) waitFor(p Priority, cost int) time.Duration {
+	if p == PriorityBackground {
+		if d := c.RecommendedWaitForBackgroundOp(cost); d > 0 {
+			return d
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if p != PriorityInteractive && c.retry.After(now) {
+		return c.retry.Sub(now)
+	}
+
+	if !c.known || now.After(c.reset) {
+		// Either we know nothing about the rate limit, or it has been
+		// reset since the last API response.
+		return 0
+	}
+
+	if p != PriorityInteractive && c.remaining-cost < int(float64(c.limit)*p.reserve()) {
+		// The small constant accounts for clock out-of-synchronization.
+		return c.reset.Sub(now) + 10*time.Second
+	}
+
+	// Deduct the cost until the next API response tells us the actual
+	// remaining budget, so that concurrent operations don't all assume the
+	// same budget.
+	c.remaining -= cost
+	return 0
+}
+
+// MonitorRegistry holds the Monitors of the external services a process talks
+// to, so that all API clients for the same external service and token share
+// the same rate limit budget.
+//
+// Monitors are registered for every token an API client is created with,
+// including tokens of individual users, so the registry removes Monitors
+// which know nothing about the current rate limit of their token as it grows.
+// An API client which still holds such a Monitor no longer shares it with new
+// API clients for the same token, but both learn the remaining budget from
+// the next API response.
+type MonitorRegistry struct {
+	mu       sync.Mutex
+	monitors map[string]*Monitor
+	// sweepAt is the number of Monitors at which stale Monitors are removed
+	// the next time a Monitor is registered.
+	sweepAt int
+}
+
+// minRegistrySweep is the smallest number of Monitors at which a
+// MonitorRegistry removes stale Monitors.
+const minRegistrySweep = 256
+
+// DefaultMonitorRegistry is the MonitorRegistry used by the API clients in
+// internal/extsvc.
+var DefaultMonitorRegistry = NewMonitorRegistry()
+
+// NewMonitorRegistry returns a new empty MonitorRegistry.
+func NewMonitorRegistry() *MonitorRegistry {
+	return &MonitorRegistry{monitors: make(map[string]*Monitor), sweepAt: minRegistrySweep}
+}
+
+// GetOrSet returns the Monitor for the external service with the given base
+// URL and token. If there's none yet, a new Monitor with the given header
+// prefix is registered.
+func (r *MonitorRegistry) GetOrSet(baseURL, token, headerPrefix string) *Monitor {
+	// We don't want to hold on to tokens for longer than the API clients do.
+	sum := sha256.Sum256([]byte(token + ":" + baseURL))
+	key := base64.URLEncoding.EncodeToString(sum[:])
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, ok := r.monitors[key]
+	if !ok {
+		if len(r.monitors) >= r.sweepAt {
+			r.sweep()
+		}
+		m = &Monitor{HeaderPrefix: headerPrefix}
+		r.monitors[key] = m
+	}
+	return m
+}
+
+// sweep removes stale Monitors. It doubles the number of Monitors at which
+// it runs next, relative to the Monitors left, so that registering Monitors
+// stays cheap on average. r.mu must be held.
+func (r *MonitorRegistry) sweep() {
+	for key, m := range r.monitors {
+		if m.stale() {
+			delete(r.monitors, key)
+		}
+	}
+	r.sweepAt = 2 * len(r.monitors)
+	if r.sweepAt < minRegistrySweep {
+		r.sweepAt = minRegistrySweep
+	}
+}
+
+// stale reports whether the Monitor knows nothing about the current rate
+// limit, i.e. it behaves like a new Monitor.
+func (c *Monitor) stale() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	return (!c.known || now.After(c.reset)) && !c.retry.After(now)
+}
@@ -0,0 +1,124 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMonitor_Wait(t *testing.T) {
+	now := time.Now()
+	reset := now.Add(30 * time.Minute)
+
+	tests := []struct {
+		name      string
+		priority  Priority
+		remaining int
+		retry     time.Time
+		wait      bool
+	}{
+		{name: "interactive, exhausted", priority: PriorityInteractive, remaining: 0},
+		{name: "interactive, retry after", priority: PriorityInteractive, remaining: 4000, retry: now.Add(time.Minute)},
+		{name: "publish, above reserve", priority: PriorityPublish, remaining: 1000},
+		{name: "publish, below reserve", priority: PriorityPublish, remaining: 400, wait: true},
+		{name: "publish, retry after", priority: PriorityPublish, remaining: 4000, retry: now.Add(time.Minute), wait: true},
+		{name: "background, above reserve", priority: PriorityBackground, remaining: 4000},
+		{name: "background, below reserve", priority: PriorityBackground, remaining: 1000, wait: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Monitor{
+				known:     true,
+				limit:     5000,
+				remaining: tc.remaining,
+				reset:     reset,
+				retry:     tc.retry,
+				clock:     func() time.Time { return now },
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			err := m.Wait(WithPriority(ctx, tc.priority), 1)
+			if waited := err == context.DeadlineExceeded; waited != tc.wait {
+				t.Fatalf("have waited %t (err: %v), want %t", waited, err, tc.wait)
+			}
+
+			want := tc.remaining
+			if !tc.wait {
+				want--
+			}
+			if m.remaining != want {
+				t.Fatalf("have remaining %d, want %d", m.remaining, want)
+			}
+		})
+	}
+}
+
+func TestMonitor_Wait_Reset(t *testing.T) {
+	now := time.Now()
+	m := &Monitor{
+		known:     true,
+		limit:     5000,
+		remaining: 0,
+		reset:     now.Add(-time.Second),
+		clock:     func() time.Time { return now },
+	}
+
+	// The rate limit has been reset since the last API response, so even
+	// background operations may proceed.
+	ctx := WithPriority(context.Background(), PriorityBackground)
+	if err := m.Wait(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMonitorRegistry_GetOrSet(t *testing.T) {
+	r := NewMonitorRegistry()
+
+	a := r.GetOrSet("https://api.github.com", "token-a", "X-")
+	if a.HeaderPrefix != "X-" {
+		t.Fatalf("have header prefix %q, want %q", a.HeaderPrefix, "X-")
+	}
+	if r.GetOrSet("https://api.github.com", "token-a", "X-") != a {
+		t.Fatal("same service and token returned a different monitor")
+	}
+	if r.GetOrSet("https://api.github.com", "token-b", "X-") == a {
+		t.Fatal("different tokens returned the same monitor")
+	}
+	if r.GetOrSet("https://ghe.example.com/api/v3", "token-a", "X-") == a {
+		t.Fatal("different services returned the same monitor")
+	}
+}
+
+func TestMonitorRegistry_Sweep(t *testing.T) {
+	r := NewMonitorRegistry()
+
+	now := time.Now()
+	active := r.GetOrSet("https://api.github.com", "active", "X-")
+	active.Update(http.Header{
+		"X-Ratelimit-Limit":     []string{"5000"},
+		"X-Ratelimit-Remaining": []string{"4000"},
+		"X-Ratelimit-Reset":     []string{strconv.FormatInt(now.Add(time.Hour).Unix(), 10)},
+	})
+	retrying := r.GetOrSet("https://api.github.com", "retrying", "X-")
+	retrying.Update(http.Header{"Retry-After": []string{"60"}})
+
+	for i := 0; i < 2*minRegistrySweep; i++ {
+		r.GetOrSet("https://api.github.com", fmt.Sprintf("token-%d", i), "X-")
+	}
+
+	if have := len(r.monitors); have >= minRegistrySweep+3 {
+		t.Fatalf("registry holds %d monitors, want stale monitors to be removed", have)
+	}
+	if r.GetOrSet("https://api.github.com", "active", "X-") != active {
+		t.Error("monitor which knows the rate limit was removed")
+	}
+	if r.GetOrSet("https://api.github.com", "retrying", "X-") != retrying {
+		t.Error("monitor which has to wait for a retry was removed")
+	}
+}
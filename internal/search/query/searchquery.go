@@ -33,6 +33,12 @@ const (
 	FieldCommitter = "committer"
 	FieldMessage   = "message"
 
+	// For symbol search only:
+	FieldSymbolKind     = "symbolkind"
+	FieldSymbolLang     = "symbollang"
+	FieldSymbolParent   = "symbolparent"
+	FieldSymbolExported = "symbolexported"
+
 	// Temporary experimental fields:
 	FieldIndex     = "index"
 	FieldCount     = "count" // Searches that specify `count:` will fetch at least that number of results, or the full result set
@@ -69,6 +75,11 @@ var (
 			FieldCommitter: regexpNegatableFieldType,
 			FieldMessage:   regexpNegatableFieldType,
 
+			FieldSymbolKind:     stringFieldType,
+			FieldSymbolLang:     stringFieldType,
+			FieldSymbolParent:   {Literal: types.RegexpType, Quoted: types.RegexpType, Singular: true},
+			FieldSymbolExported: {Literal: types.BoolType, Quoted: types.BoolType, Singular: true},
+
 			// Experimental fields:
 			FieldIndex:     {Literal: types.StringType, Quoted: types.StringType, Singular: true},
 			FieldCount:     {Literal: types.StringType, Quoted: types.StringType, Singular: true},
@@ -222,6 +222,17 @@ func valueToTypedValue(field, value string) []*types.Value {
 		FieldMessage, "m", "msg":
 		return []*types.Value{{Regexp: parseRegexpOrPanic(field, value)}}
 
+	case
+		FieldSymbolKind,
+		FieldSymbolLang:
+		return []*types.Value{{String: &value}}
+
+	case FieldSymbolParent:
+		return []*types.Value{{Regexp: parseRegexpOrPanic(field, value)}}
+
+	case FieldSymbolExported:
+		return []*types.Value{{Bool: parseBoolOrPanic(field, value)}}
+
 	case
 		FieldIndex,
 		FieldCount,
@@ -33,6 +33,25 @@ type SearchArgs struct {
 	// need to match to get included in the result
 	ExcludePattern string
 
+	// Kinds, if non-empty, restricts the result to symbols of one of these
+	// kinds (e.g. "function"). Kinds are matched case insensitively.
+	Kinds []string
+
+	// Languages, if non-empty, restricts the result to symbols in one of
+	// these languages, as reported by ctags (e.g. "Go"). Languages are
+	// matched case insensitively.
+	Languages []string
+
+	// Parent is an optional regex that the name of a symbol's parent (its
+	// container, e.g. the enclosing class) needs to match to get included in
+	// the result.
+	Parent string
+
+	// ExportedOnly if true excludes symbols which are only visible in the
+	// file they are defined in, and Go symbols which are only visible in
+	// their package.
+	ExportedOnly bool
+
 	// First indicates that only the first n symbols should be returned.
 	First int
 }
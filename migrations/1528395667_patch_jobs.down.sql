@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS patch_jobs;
+
+ALTER TABLE patch_sets DROP COLUMN IF EXISTS spec;
+
+COMMIT;
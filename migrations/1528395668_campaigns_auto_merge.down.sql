@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE campaigns DROP COLUMN IF EXISTS auto_merge;
+ALTER TABLE campaigns DROP COLUMN IF EXISTS merge_method;
+
+COMMIT;
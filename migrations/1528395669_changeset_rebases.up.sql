@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE changeset_jobs ADD COLUMN IF NOT EXISTS base_commit text;
+ALTER TABLE changesets ADD COLUMN IF NOT EXISTS conflicted boolean NOT NULL DEFAULT false;
+
+COMMIT;
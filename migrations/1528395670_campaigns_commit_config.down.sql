@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE campaigns DROP COLUMN IF EXISTS commit_message;
+ALTER TABLE campaigns DROP COLUMN IF EXISTS commit_author_name;
+ALTER TABLE campaigns DROP COLUMN IF EXISTS commit_author_email;
+ALTER TABLE campaigns DROP COLUMN IF EXISTS commit_sign_off;
+ALTER TABLE campaigns DROP COLUMN IF EXISTS commit_sign;
+
+COMMIT;
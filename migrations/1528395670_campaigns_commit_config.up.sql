@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS commit_message text;
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS commit_author_name text;
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS commit_author_email text;
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS commit_sign_off boolean NOT NULL DEFAULT false;
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS commit_sign boolean NOT NULL DEFAULT false;
+
+COMMIT;
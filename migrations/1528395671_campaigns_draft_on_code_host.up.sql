@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS draft_on_code_host boolean NOT NULL DEFAULT false;
+
+COMMIT;
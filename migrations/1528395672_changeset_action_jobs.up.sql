@@ -0,0 +1,21 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS changeset_action_jobs (
+  id bigserial PRIMARY KEY,
+  campaign_id bigint NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE DEFERRABLE,
+  changeset_id bigint NOT NULL REFERENCES changesets(id) ON DELETE CASCADE DEFERRABLE,
+  user_id integer REFERENCES users(id) ON DELETE SET NULL DEFERRABLE,
+  kind text NOT NULL CHECK (kind != ''),
+  payload jsonb NOT NULL DEFAULT '{}'::jsonb,
+  error text,
+  started_at timestamp with time zone,
+  finished_at timestamp with time zone,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  updated_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS changeset_action_jobs_campaign_id ON changeset_action_jobs(campaign_id);
+CREATE INDEX IF NOT EXISTS changeset_action_jobs_started_at ON changeset_action_jobs(started_at);
+CREATE INDEX IF NOT EXISTS changeset_action_jobs_finished_at ON changeset_action_jobs(finished_at);
+
+COMMIT;
@@ -0,0 +1,17 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS access_token_usage (
+  id bigserial PRIMARY KEY,
+  access_token_id bigint NOT NULL REFERENCES access_tokens(id) ON DELETE CASCADE DEFERRABLE,
+  remote_addr text NOT NULL,
+  forwarded_for text,
+  route text NOT NULL,
+  operation_name text,
+  status_code integer NOT NULL,
+  created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS access_token_usage_access_token_id_created_at ON access_token_usage(access_token_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS access_token_usage_created_at ON access_token_usage(created_at);
+
+COMMIT;
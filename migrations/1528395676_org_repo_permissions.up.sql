@@ -0,0 +1,13 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS org_repo_permissions (
+  repo_id integer NOT NULL,
+  permission text NOT NULL,
+  org_id integer NOT NULL REFERENCES orgs(id) ON DELETE CASCADE DEFERRABLE,
+  updated_at timestamp with time zone NOT NULL DEFAULT now(),
+  PRIMARY KEY (repo_id, permission, org_id)
+);
+
+CREATE INDEX IF NOT EXISTS org_repo_permissions_org_id ON org_repo_permissions (org_id);
+
+COMMIT;
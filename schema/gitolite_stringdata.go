@@ -75,6 +75,45 @@ const GitoliteSchemaJSON = `{
           "type": "string"
         }
       }
+    },
+    "authorization": {
+      "title": "GitoliteAuthorization",
+      "description": "If non-null, enforces Gitolite repository permissions. Permissions are computed from the access rules in the conf/ directory and the users in the keydir/ directory of the gitolite-admin repository, so the SSH key of gitserver must have read access to the gitolite-admin repository. This requires ` + "`" + `permissions.backgroundSync` + "`" + ` to be enabled in the site configuration to perform well.",
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["identityProvider"],
+      "properties": {
+        "identityProvider": {
+          "description": "The source of identity to use when computing permissions. This defines how to compute the Gitolite identity to use for a given Sourcegraph user. When 'username' is used, Sourcegraph assumes that the username of a Sourcegraph account is identical to the Gitolite user name (i.e., the name of the user's key file in keydir/) and ` + "`" + `auth.enableUsernameChanges` + "`" + ` must be set to false for security reasons.",
+          "title": "GitoliteIdentityProvider",
+          "type": "object",
+          "required": ["type"],
+          "properties": {
+            "type": {
+              "type": "string",
+              "enum": ["username"]
+            }
+          },
+          "oneOf": [{ "$ref": "#/definitions/UsernameIdentity" }],
+          "!go": {
+            "taggedUnionType": true
+          }
+        }
+      }
+    }
+  },
+  "definitions": {
+    "UsernameIdentity": {
+      "title": "GitoliteUsernameIdentity",
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["type"],
+      "properties": {
+        "type": {
+          "type": "string",
+          "const": "username"
+        }
+      }
     }
   }
 }
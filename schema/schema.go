@@ -111,12 +111,20 @@ func (v *AuthProviders) UnmarshalJSON(data []byte) error {
 	return fmt.Errorf("tagged union type must have a %q property whose value is one of %s", "type", []string{"builtin", "saml", "openidconnect", "http-header", "github", "gitlab"})
 }
 
+// BitbucketCloudAuthorization description: If non-null, enforces Bitbucket Cloud repository permissions. Permissions are read from the repository permissions of the workspaces of "username" and "teams", so "username" must be an administrator of these workspaces. This requires `permissions.backgroundSync` to be enabled in the site configuration to perform well.
+type BitbucketCloudAuthorization struct {
+	// IdentityProvider description: The source of identity to use when computing permissions. This defines how to compute the Bitbucket Cloud identity to use for a given Sourcegraph user. When 'username' is used, Sourcegraph assumes that the username of a Sourcegraph account is identical to the nickname of the Bitbucket Cloud account and `auth.enableUsernameChanges` must be set to false for security reasons.
+	IdentityProvider BitbucketCloudIdentityProvider `json:"identityProvider"`
+}
+
 // BitbucketCloudConnection description: Configuration for a connection to Bitbucket Cloud.
 type BitbucketCloudConnection struct {
 	// ApiURL description: The API URL of Bitbucket Cloud, such as https://api.bitbucket.org. Generally, admin should not modify the value of this option because Bitbucket Cloud is a public hosting platform.
 	ApiURL string `json:"apiURL,omitempty"`
 	// AppPassword description: The app password to use when authenticating to the Bitbucket Cloud. Also set the corresponding "username" field.
 	AppPassword string `json:"appPassword"`
+	// Authorization description: If non-null, enforces Bitbucket Cloud repository permissions. Permissions are read from the repository permissions of the workspaces of "username" and "teams", so "username" must be an administrator of these workspaces. This requires `permissions.backgroundSync` to be enabled in the site configuration to perform well.
+	Authorization *BitbucketCloudAuthorization `json:"authorization,omitempty"`
 	// Exclude description: A list of repositories to never mirror from Bitbucket Cloud. Takes precedence over "teams" configuration.
 	//
 	// Supports excluding by name ({"name": "myorg/myrepo"}) or by UUID ({"uuid": "{fceb73c7-cef6-4abe-956d-e471281126bd}"}).
@@ -143,6 +151,35 @@ type BitbucketCloudConnection struct {
 	Username string `json:"username"`
 }
 
+// BitbucketCloudIdentityProvider description: The source of identity to use when computing permissions. This defines how to compute the Bitbucket Cloud identity to use for a given Sourcegraph user. When 'username' is used, Sourcegraph assumes that the username of a Sourcegraph account is identical to the nickname of the Bitbucket Cloud account and `auth.enableUsernameChanges` must be set to false for security reasons.
+type BitbucketCloudIdentityProvider struct {
+	Username *BitbucketCloudUsernameIdentity
+}
+
+func (v BitbucketCloudIdentityProvider) MarshalJSON() ([]byte, error) {
+	if v.Username != nil {
+		return json.Marshal(v.Username)
+	}
+	return nil, errors.New("tagged union type must have exactly 1 non-nil field value")
+}
+func (v *BitbucketCloudIdentityProvider) UnmarshalJSON(data []byte) error {
+	var d struct {
+		DiscriminantProperty string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return err
+	}
+	switch d.DiscriminantProperty {
+	case "username":
+		return json.Unmarshal(data, &v.Username)
+	}
+	return fmt.Errorf("tagged union type must have a %q property whose value is one of %s", "type", []string{"username"})
+}
+
+type BitbucketCloudUsernameIdentity struct {
+	Type string `json:"type"`
+}
+
 // BitbucketServerAuthorization description: If non-null, enforces Bitbucket Server repository permissions.
 type BitbucketServerAuthorization struct {
 	// HardTTL description: Duration after which a user's cached permissions must be updated before authorizing any user actions. This is 3 days by default.
@@ -557,6 +594,8 @@ type GitLabConnection struct {
 	Token string `json:"token"`
 	// Url description: URL of a GitLab instance, such as https://gitlab.example.com or (for GitLab.com) https://gitlab.com.
 	Url string `json:"url"`
+	// Webhooks description: An array of configurations defining existing GitLab webhooks that send merge request updates back to Sourcegraph. The webhooks must be configured to send to /.api/gitlab-webhooks on your Sourcegraph instance.
+	Webhooks []*GitLabWebhook `json:"webhooks,omitempty"`
 }
 type GitLabNameTransformation struct {
 	// Regex description: The regex to match for the occurrences of its replacement.
@@ -570,9 +609,21 @@ type GitLabProject struct {
 	// Name description: The name of a GitLab project ("group/name") to mirror.
 	Name string `json:"name,omitempty"`
 }
+type GitLabWebhook struct {
+	// Secret description: The secret token used when creating the webhook
+	Secret string `json:"secret"`
+}
+
+// GitoliteAuthorization description: If non-null, enforces Gitolite repository permissions. Permissions are computed from the access rules in the conf/ directory and the users in the keydir/ directory of the gitolite-admin repository, so the SSH key of gitserver must have read access to the gitolite-admin repository. This requires `permissions.backgroundSync` to be enabled in the site configuration to perform well.
+type GitoliteAuthorization struct {
+	// IdentityProvider description: The source of identity to use when computing permissions. This defines how to compute the Gitolite identity to use for a given Sourcegraph user. When 'username' is used, Sourcegraph assumes that the username of a Sourcegraph account is identical to the Gitolite user name (i.e., the name of the user's key file in keydir/) and `auth.enableUsernameChanges` must be set to false for security reasons.
+	IdentityProvider GitoliteIdentityProvider `json:"identityProvider"`
+}
 
 // GitoliteConnection description: Configuration for a connection to Gitolite.
 type GitoliteConnection struct {
+	// Authorization description: If non-null, enforces Gitolite repository permissions. Permissions are computed from the access rules in the conf/ directory and the users in the keydir/ directory of the gitolite-admin repository, so the SSH key of gitserver must have read access to the gitolite-admin repository. This requires `permissions.backgroundSync` to be enabled in the site configuration to perform well.
+	Authorization *GitoliteAuthorization `json:"authorization,omitempty"`
 	// Blacklist description: Regular expression to filter repositories from auto-discovery, so they will not get cloned automatically.
 	Blacklist string `json:"blacklist,omitempty"`
 	// Exclude description: A list of repositories to never mirror from this Gitolite instance. Supports excluding by exact name ({"name": "foo"}).
@@ -589,6 +640,35 @@ type GitoliteConnection struct {
 	Prefix string `json:"prefix"`
 }
 
+// GitoliteIdentityProvider description: The source of identity to use when computing permissions. This defines how to compute the Gitolite identity to use for a given Sourcegraph user. When 'username' is used, Sourcegraph assumes that the username of a Sourcegraph account is identical to the Gitolite user name (i.e., the name of the user's key file in keydir/) and `auth.enableUsernameChanges` must be set to false for security reasons.
+type GitoliteIdentityProvider struct {
+	Username *GitoliteUsernameIdentity
+}
+
+func (v GitoliteIdentityProvider) MarshalJSON() ([]byte, error) {
+	if v.Username != nil {
+		return json.Marshal(v.Username)
+	}
+	return nil, errors.New("tagged union type must have exactly 1 non-nil field value")
+}
+func (v *GitoliteIdentityProvider) UnmarshalJSON(data []byte) error {
+	var d struct {
+		DiscriminantProperty string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return err
+	}
+	switch d.DiscriminantProperty {
+	case "username":
+		return json.Unmarshal(data, &v.Username)
+	}
+	return fmt.Errorf("tagged union type must have a %q property whose value is one of %s", "type", []string{"username"})
+}
+
+type GitoliteUsernameIdentity struct {
+	Type string `json:"type"`
+}
+
 // HTTPHeaderAuthProvider description: Configures the HTTP header authentication provider (which authenticates users by consulting an HTTP request header set by an authentication proxy such as https://github.com/bitly/oauth2_proxy).
 type HTTPHeaderAuthProvider struct {
 	// StripUsernameHeaderPrefix description: The prefix that precedes the username portion of the HTTP header specified in `usernameHeader`. If specified, the prefix will be stripped from the header value and the remainder will be used as the username. For example, if using Google Identity-Aware Proxy (IAP) with Google Sign-In, set this value to `accounts.google.com:`.
@@ -681,6 +761,8 @@ type OpenIDConnectAuthProvider struct {
 	// ConfigID description: An identifier that can be used to reference this authentication provider in other parts of the config. For example, in configuration for a code host, you may want to designate this authentication provider as the identity provider for the code host.
 	ConfigID    string `json:"configID,omitempty"`
 	DisplayName string `json:"displayName,omitempty"`
+	// GroupsClaimName description: The name of the ID token or UserInfo claim whose value is the list of groups that the user is a member of. Group memberships are updated on every sign-in and used by group-based repository permissions (`permissions.groups`).
+	GroupsClaimName string `json:"groupsClaimName,omitempty"`
 	// Issuer description: The URL of the OpenID Connect issuer.
 	//
 	// For Google Apps: https://accounts.google.com
@@ -712,12 +794,18 @@ type PermissionsBackgroundSync struct {
 	// Enabled description: Whether syncing permissions in the background is enabled.
 	Enabled bool `json:"enabled,omitempty"`
 }
+type PermissionsGroup struct {
+	// Name description: The name of the group as it appears in the group claims.
+	Name string `json:"name"`
+	// Repos description: Regular expressions that are matched against whole repository names, as if they were enclosed in `^(?:` and `)$`. Members of the group can read the repositories whose names match any of them.
+	Repos []string `json:"repos"`
+}
 
-// PermissionsUserMapping description: Settings for Sourcegraph permissions, which allow the site admin to explicitly manage repository permissions via the GraphQL API. This setting cannot be enabled if repository permissions for any specific external service are enabled (i.e., when the external service's `authorization` field is set).
+// PermissionsUserMapping description: Settings for Sourcegraph permissions, which allow the site admin to explicitly manage repository permissions via the GraphQL API. Explicit permissions apply to the repositories of all external services without repository permissions enabled (i.e., whose `authorization` field is not set).
 type PermissionsUserMapping struct {
 	// BindID description: The type of identifier to identify a user. The default is "email", which uses the email address to identify a user. Use "username" to identify a user by their username. Changing this setting will erase any permissions created for users that do not yet exist.
 	BindID string `json:"bindID,omitempty"`
-	// Enabled description: Whether permissions user mapping is enabled. There must be no `authorization` field in any external service configuration before enabling this.
+	// Enabled description: Whether permissions user mapping is enabled.
 	Enabled bool `json:"enabled,omitempty"`
 }
 
@@ -760,6 +848,8 @@ type SAMLAuthProvider struct {
 	// ConfigID description: An identifier that can be used to reference this authentication provider in other parts of the config. For example, in configuration for a code host, you may want to designate this authentication provider as the identity provider for the code host.
 	ConfigID    string `json:"configID,omitempty"`
 	DisplayName string `json:"displayName,omitempty"`
+	// GroupsAttributeName description: The name of the SAML assertion attribute whose values are the groups that the user is a member of. Group memberships are updated on every sign-in and used by group-based repository permissions (`permissions.groups`).
+	GroupsAttributeName string `json:"groupsAttributeName,omitempty"`
 	// IdentityProviderMetadata description: The SAML Identity Provider metadata XML contents (for static configuration of the SAML Service Provider). The value of this field should be an XML document whose root element is `<EntityDescriptor>` or `<EntityDescriptors>`. To escape the value into a JSON string, you may want to use a tool like https://json-escape-text.now.sh.
 	IdentityProviderMetadata string `json:"identityProviderMetadata,omitempty"`
 	// IdentityProviderMetadataURL description: The SAML Identity Provider metadata URL (for dynamic configuration of the SAML Service Provider).
@@ -973,7 +1063,9 @@ type SiteConfiguration struct {
 	ParentSourcegraph *ParentSourcegraph `json:"parentSourcegraph,omitempty"`
 	// PermissionsBackgroundSync description: Sync code host repository and user permissions in the background.
 	PermissionsBackgroundSync *PermissionsBackgroundSync `json:"permissions.backgroundSync,omitempty"`
-	// PermissionsUserMapping description: Settings for Sourcegraph permissions, which allow the site admin to explicitly manage repository permissions via the GraphQL API. This setting cannot be enabled if repository permissions for any specific external service are enabled (i.e., when the external service's `authorization` field is set).
+	// PermissionsGroups description: Group-based repository permissions for the repositories of external services of kind `OTHER`. Users are members of the groups listed in the group claims of their SAML or OpenID Connect sign-in (see `groupsAttributeName` and `groupsClaimName` of those authentication providers). When set, the repositories of `OTHER` external services with a `url` can only be read by members of a group with a matching repository pattern.
+	PermissionsGroups []*PermissionsGroup `json:"permissions.groups,omitempty"`
+	// PermissionsUserMapping description: Settings for Sourcegraph permissions, which allow the site admin to explicitly manage repository permissions via the GraphQL API. Explicit permissions apply to the repositories of all external services without repository permissions enabled (i.e., whose `authorization` field is not set).
 	PermissionsUserMapping *PermissionsUserMapping `json:"permissions.userMapping,omitempty"`
 	// RepoListUpdateInterval description: Interval (in minutes) for checking code hosts (such as GitHub, Gitolite, etc.) for new repositories.
 	RepoListUpdateInterval int `json:"repoListUpdateInterval,omitempty"`
@@ -297,12 +297,12 @@ const SiteSchemaJSON = `{
       "group": "Security"
     },
     "permissions.userMapping": {
-      "description": "Settings for Sourcegraph permissions, which allow the site admin to explicitly manage repository permissions via the GraphQL API. This setting cannot be enabled if repository permissions for any specific external service are enabled (i.e., when the external service's ` + "`" + `authorization` + "`" + ` field is set).",
+      "description": "Settings for Sourcegraph permissions, which allow the site admin to explicitly manage repository permissions via the GraphQL API. Explicit permissions apply to the repositories of all external services without repository permissions enabled (i.e., whose ` + "`" + `authorization` + "`" + ` field is not set).",
       "type": "object",
       "additionalProperties": false,
       "properties": {
         "enabled": {
-          "description": "Whether permissions user mapping is enabled. There must be no ` + "`" + `authorization` + "`" + ` field in any external service configuration before enabling this.",
+          "description": "Whether permissions user mapping is enabled.",
           "type": "boolean",
           "default": false
         },
@@ -320,6 +320,33 @@ const SiteSchemaJSON = `{
       "examples": [{ "bindID": "email" }, { "bindID": "username" }],
       "group": "Security"
     },
+    "permissions.groups": {
+      "description": "Group-based repository permissions for the repositories of external services of kind ` + "`" + `OTHER` + "`" + `. Users are members of the groups listed in the group claims of their SAML or OpenID Connect sign-in (see ` + "`" + `groupsAttributeName` + "`" + ` and ` + "`" + `groupsClaimName` + "`" + ` of those authentication providers). When set, the repositories of ` + "`" + `OTHER` + "`" + ` external services with a ` + "`" + `url` + "`" + ` can only be read by members of a group with a matching repository pattern.",
+      "type": "array",
+      "items": {
+        "title": "PermissionsGroup",
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["name", "repos"],
+        "properties": {
+          "name": {
+            "description": "The name of the group as it appears in the group claims.",
+            "type": "string",
+            "minLength": 1
+          },
+          "repos": {
+            "description": "Regular expressions that are matched against whole repository names, as if they were enclosed in ` + "`" + `^(?:` + "`" + ` and ` + "`" + `)$` + "`" + `. Members of the group can read the repositories whose names match any of them.",
+            "type": "array",
+            "items": {
+              "type": "string",
+              "format": "regex"
+            }
+          }
+        }
+      },
+      "examples": [[{ "name": "engineering", "repos": ["git\\.example\\.com/engineering/.*"] }]],
+      "group": "Security"
+    },
     "permissions.backgroundSync": {
       "description": "Sync code host repository and user permissions in the background.",
       "type": "object",
@@ -729,6 +756,11 @@ const SiteSchemaJSON = `{
           "description": "Only allow users to authenticate if their email domain is equal to this value (example: mycompany.com). Do not include a leading \"@\". If not set, all users on this OpenID Connect provider can authenticate to Sourcegraph.",
           "type": "string",
           "pattern": "^[^<@]"
+        },
+        "groupsClaimName": {
+          "description": "The name of the ID token or UserInfo claim whose value is the list of groups that the user is a member of. Group memberships are updated on every sign-in and used by group-based repository permissions (` + "`" + `permissions.groups` + "`" + `).",
+          "type": "string",
+          "default": "groups"
         }
       }
     },
@@ -804,6 +836,11 @@ const SiteSchemaJSON = `{
           "description": "Whether the Service Provider should (insecurely) accept assertions from the Identity Provider without a valid signature.",
           "type": "boolean",
           "default": false
+        },
+        "groupsAttributeName": {
+          "description": "The name of the SAML assertion attribute whose values are the groups that the user is a member of. Group memberships are updated on every sign-in and used by group-based repository permissions (` + "`" + `permissions.groups` + "`" + `).",
+          "type": "string",
+          "default": "groups"
         }
       }
     },